@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// apiClient sends authenticated requests to a JSON REST API.
+type apiClient struct {
+	http *http.Client
+	// header is added to every request (authentication, API version).
+	header http.Header
+}
+
+// upload sends a file as the raw request body and decodes a JSON response into out when
+// it is not nil.
+func (c *apiClient) upload(ctx context.Context, method, endpoint, path string, out any) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	return c.send(ctx, method, endpoint, f, info.Size(), "application/octet-stream", out)
+}
+
+// do sends an API request without a body and decodes a JSON response into out when it
+// is not nil.
+func (c *apiClient) do(ctx context.Context, method, endpoint string, out any) error {
+	return c.send(ctx, method, endpoint, nil, -1, "", out)
+}
+
+// send sends an API request with a body of the given size and content type.
+func (c *apiClient) send(ctx context.Context, method, endpoint string, body io.Reader, size int64, contentType string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	for key, values := range c.header {
+		req.Header[key] = values
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s returned status %d: %s", method, endpoint, resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// alpineArchitectures maps target architectures to Alpine architecture names.
+var alpineArchitectures = map[string]string{
+	"amd64":   "x86_64",
+	"386":     "x86",
+	"arm64":   "aarch64",
+	"arm":     "armhf",
+	"arm/v6":  "armhf",
+	"arm/v7":  "armv7",
+	"ppc64le": "ppc64le",
+	"s390x":   "s390x",
+	"riscv64": "riscv64",
+}
+
+// apkIndexName is the name of an Alpine repository index.
+const apkIndexName = "APKINDEX.tar.gz"
+
+// APKPublishConfig configures publishing apk packages to an Alpine repository directory.
+type APKPublishConfig struct {
+	// Repo is the repository directory; packages go into <repo>/<alpine arch>/.
+	Repo string
+	// Description is written into the index (e.g. a version or repository name).
+	Description string
+	// KeyPath is the abuild private key used to sign the index. Defaults to apk_key_path.
+	KeyPath string
+}
+
+// parseAPKPublish parses the publish.apk block. It returns nil when it is not set.
+// The signing key defaults to defaultKey.
+func parseAPKPublish(publish map[string]any, defaultKey string) *APKPublishConfig {
+	block := helpers.NewConfigParser(publish).GetMap("apk")
+	if block == nil {
+		return nil
+	}
+
+	parser := helpers.NewConfigParser(block)
+	return &APKPublishConfig{
+		Repo:        parser.GetString("repo", "", ""),
+		Description: parser.GetString("description", "", ""),
+		KeyPath:     parser.GetString("key_path", "", defaultKey),
+	}
+}
+
+// validate checks the apk publishing settings.
+func (c *APKPublishConfig) validate() error {
+	if c.Repo == "" {
+		return fmt.Errorf("repo is required")
+	}
+	if err := validatePath(c.Repo); err != nil {
+		return fmt.Errorf("invalid repo: %w", err)
+	}
+	if err := validatePath(c.KeyPath); err != nil {
+		return fmt.Errorf("invalid key_path: %w", err)
+	}
+	if strings.HasPrefix(c.Description, "-") {
+		return fmt.Errorf("description cannot start with '-'")
+	}
+	return nil
+}
+
+// alpineArch returns the Alpine architecture name for a target architecture.
+func alpineArch(arch string) string {
+	if name, ok := alpineArchitectures[arch]; ok {
+		return name
+	}
+	return arch
+}
+
+// publishAPK copies apk packages into <repo>/<arch>/, regenerates APKINDEX.tar.gz from
+// every package in that directory with `apk index`, and signs it with abuild-sign.
+func (p *LinuxPkgPlugin) publishAPK(ctx context.Context, executor CommandExecutor, c *APKPublishConfig, artifacts []map[string]any) (map[string]any, error) {
+	byArch := make(map[string][]string)
+	for _, artifact := range artifacts {
+		if artifact["format"] != "apk" {
+			continue
+		}
+		arch, _ := artifact["arch"].(string)
+		byArch[alpineArch(arch)] = append(byArch[alpineArch(arch)], artifact["path"].(string))
+	}
+
+	copied := make([]string, 0)
+	indexes := make([]string, 0, len(byArch))
+	for _, arch := range sortedKeys(byArch) {
+		archDir := filepath.Join(c.Repo, arch)
+		if err := os.MkdirAll(archDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create repository directory: %w", err)
+		}
+		for _, apk := range byArch[arch] {
+			dst := filepath.Join(archDir, filepath.Base(apk))
+			if err := copyFile(apk, dst); err != nil {
+				return nil, err
+			}
+			copied = append(copied, dst)
+		}
+
+		packages, err := filepath.Glob(filepath.Join(archDir, "*.apk"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list packages: %w", err)
+		}
+		sort.Strings(packages)
+
+		index := filepath.Join(archDir, apkIndexName)
+		args := []string{"index", "--allow-untrusted", "--output", index}
+		if c.Description != "" {
+			args = append(args, "--description", c.Description)
+		}
+		output, err := runCommand(ctx, executor, "apk", append(args, packages...)...)
+		if err != nil {
+			return nil, fmt.Errorf("apk index failed for %s: %w\nOutput: %s", arch, err, string(output))
+		}
+
+		if c.KeyPath != "" {
+			output, err := runCommand(ctx, executor, "abuild-sign", "-k", c.KeyPath, index)
+			if err != nil {
+				return nil, fmt.Errorf("failed to sign %s: %w\nOutput: %s", index, err, string(output))
+			}
+		}
+		indexes = append(indexes, index)
+	}
+
+	return map[string]any{
+		"repository": c.Repo,
+		"packages":   copied,
+		"indexes":    indexes,
+		"signed":     c.KeyPath != "",
+	}, nil
+}
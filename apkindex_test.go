@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestParseAPKPublishDefaultsKey tests that the index key defaults to apk_key_path.
+func TestParseAPKPublishDefaultsKey(t *testing.T) {
+	t.Parallel()
+
+	publish := parsePublish(map[string]any{
+		"apk_key_path": "keys/apk.rsa",
+		"publish":      map[string]any{"apk": map[string]any{"repo": "repo/alpine"}},
+	})
+	if publish == nil || publish.APK == nil {
+		t.Fatal("expected publish.apk to be parsed")
+	}
+	if publish.APK.KeyPath != "keys/apk.rsa" {
+		t.Errorf("expected key to default to apk_key_path, got %q", publish.APK.KeyPath)
+	}
+}
+
+// TestValidatePublishAPK tests validation of the publish.apk block.
+func TestValidatePublishAPK(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		apk         map[string]any
+		expectValid bool
+	}{
+		{name: "repo", apk: map[string]any{"repo": "repo/alpine"}, expectValid: true},
+		{name: "missing repo", apk: map[string]any{}, expectValid: false},
+		{name: "key path traversal", apk: map[string]any{"repo": "repo", "key_path": "../apk.rsa"}, expectValid: false},
+		{name: "option injection", apk: map[string]any{"repo": "repo", "description": "--keys-dir=/"}, expectValid: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &LinuxPkgPlugin{}
+			resp, err := p.Validate(context.Background(), map[string]any{"publish": map[string]any{"apk": tc.apk}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Valid != tc.expectValid {
+				t.Errorf("expected valid=%v, got %v: %v", tc.expectValid, resp.Valid, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestPublishAPK tests copying apks per architecture, indexing, and signing.
+func TestPublishAPK(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	repo := filepath.Join(dir, "repo")
+
+	// An apk published by an earlier release must stay in the index.
+	if err := os.MkdirAll(filepath.Join(repo, "x86_64"), 0755); err != nil {
+		t.Fatalf("failed to create repo: %v", err)
+	}
+	existing := filepath.Join(repo, "x86_64", "myapp-0.9.0-r0.apk")
+	if err := os.WriteFile(existing, []byte("old"), 0644); err != nil {
+		t.Fatalf("failed to write apk: %v", err)
+	}
+
+	var artifacts []map[string]any
+	for _, arch := range []string{"amd64", "arm64"} {
+		path := filepath.Join(dir, "myapp_1.0.0_"+arch+".apk")
+		if err := os.WriteFile(path, []byte(arch), 0644); err != nil {
+			t.Fatalf("failed to write apk: %v", err)
+		}
+		artifacts = append(artifacts, map[string]any{"path": path, "format": "apk", "arch": arch})
+	}
+	artifacts = append(artifacts, map[string]any{"path": filepath.Join(dir, "myapp.deb"), "format": "deb", "arch": "amd64"})
+
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return nil, nil
+		},
+	}
+
+	p := &LinuxPkgPlugin{}
+	cfg := &APKPublishConfig{Repo: repo, Description: "v1.0.0", KeyPath: "keys/apk.rsa"}
+	result, err := p.publishAPK(context.Background(), mock, cfg, artifacts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	x86Index := filepath.Join(repo, "x86_64", apkIndexName)
+	armIndex := filepath.Join(repo, "aarch64", apkIndexName)
+	expected := []string{
+		"apk index --allow-untrusted --output " + armIndex + " --description v1.0.0 " + filepath.Join(repo, "aarch64", "myapp_1.0.0_arm64.apk"),
+		"abuild-sign -k keys/apk.rsa " + armIndex,
+		"apk index --allow-untrusted --output " + x86Index + " --description v1.0.0 " + existing + " " + filepath.Join(repo, "x86_64", "myapp_1.0.0_amd64.apk"),
+		"abuild-sign -k keys/apk.rsa " + x86Index,
+	}
+	if len(mock.Calls) != len(expected) {
+		t.Fatalf("expected %d calls, got %v", len(expected), mock.Calls)
+	}
+	for i, call := range mock.Calls {
+		if got := call.Name + " " + strings.Join(call.Args, " "); got != expected[i] {
+			t.Errorf("call %d: expected %q, got %q", i, expected[i], got)
+		}
+	}
+
+	indexes := result["indexes"].([]string)
+	if len(indexes) != 2 || indexes[0] != armIndex || indexes[1] != x86Index {
+		t.Errorf("unexpected indexes: %v", indexes)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// Allowed APT repository tools.
+var allowedAPTTools = map[string]bool{
+	"reprepro": true,
+	"aptly":    true,
+}
+
+// debianArchitectures maps target architectures to Debian architecture names.
+var debianArchitectures = map[string]string{
+	"amd64":   "amd64",
+	"386":     "i386",
+	"arm64":   "arm64",
+	"arm":     "armhf",
+	"ppc64le": "ppc64el",
+	"s390x":   "s390x",
+	"riscv64": "riscv64",
+}
+
+// aptNamePattern validates APT distribution, component, and aptly repo names.
+var aptNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+-]*$`)
+
+// APTPublishConfig configures publishing debs to an APT repository.
+type APTPublishConfig struct {
+	// Tool manages the repository: reprepro (default) or aptly.
+	Tool string
+	// Repo is the reprepro base directory, or the aptly local repo name.
+	Repo string
+	// Remote is where the repository is published: an rsync destination for reprepro,
+	// or an aptly publish endpoint (e.g. s3:releases:). Empty keeps it local.
+	Remote string
+	// Distribution is the codename the packages are added to.
+	Distribution string
+	// Component is the repository component (e.g. main).
+	Component string
+	// GPGKey is the key used to sign Release/InRelease. Empty leaves the repository unsigned.
+	GPGKey string
+}
+
+// parseAPTPublish parses the publish.apt block. It returns nil when it is not set.
+func parseAPTPublish(publish map[string]any) *APTPublishConfig {
+	block := helpers.NewConfigParser(publish).GetMap("apt")
+	if block == nil {
+		return nil
+	}
+
+	parser := helpers.NewConfigParser(block)
+	return &APTPublishConfig{
+		Tool:         parser.GetString("tool", "", "reprepro"),
+		Repo:         parser.GetString("repo", "", ""),
+		Remote:       parser.GetString("remote", "", ""),
+		Distribution: parser.GetString("distribution", "", "stable"),
+		Component:    parser.GetString("component", "", "main"),
+		GPGKey:       parser.GetString("gpg_key", "", ""),
+	}
+}
+
+// validate checks the APT publishing settings.
+func (c *APTPublishConfig) validate() error {
+	if !allowedAPTTools[c.Tool] {
+		return fmt.Errorf("unsupported tool: %s (allowed: %s)", c.Tool, strings.Join(sortedKeys(allowedAPTTools), ", "))
+	}
+	if c.Repo == "" {
+		return fmt.Errorf("repo is required")
+	}
+
+	if c.Tool == "reprepro" {
+		if err := validatePath(c.Repo); err != nil {
+			return fmt.Errorf("invalid repo: %w", err)
+		}
+	} else if !aptNamePattern.MatchString(c.Repo) {
+		return fmt.Errorf("invalid repo name: %s", c.Repo)
+	}
+
+	if !aptNamePattern.MatchString(c.Distribution) {
+		return fmt.Errorf("invalid distribution: %s", c.Distribution)
+	}
+	if !aptNamePattern.MatchString(c.Component) {
+		return fmt.Errorf("invalid component: %s", c.Component)
+	}
+	if strings.HasPrefix(c.Remote, "-") || strings.HasPrefix(c.GPGKey, "-") {
+		return fmt.Errorf("remote and gpg_key cannot start with '-'")
+	}
+
+	return nil
+}
+
+// publishAPT adds debs to the APT repository, regenerating and signing its indices.
+func (p *LinuxPkgPlugin) publishAPT(ctx context.Context, executor CommandExecutor, c *APTPublishConfig, debs []string) (map[string]any, error) {
+	var err error
+	if c.Tool == "aptly" {
+		err = p.publishAptly(ctx, executor, c, debs)
+	} else {
+		err = p.publishReprepro(ctx, executor, c, debs)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]any{
+		"tool":         c.Tool,
+		"repository":   c.Repo,
+		"distribution": c.Distribution,
+		"component":    c.Component,
+		"packages":     debs,
+		"signed":       c.GPGKey != "",
+	}
+	if c.Remote != "" {
+		result["remote"] = c.Remote
+	}
+	return result, nil
+}
+
+// publishReprepro includes debs with reprepro, which regenerates Packages/Release and,
+// when the distribution has SignWith set, signs InRelease. The repository config is
+// created on first use and the repository is synced to Remote with rsync.
+func (p *LinuxPkgPlugin) publishReprepro(ctx context.Context, executor CommandExecutor, c *APTPublishConfig, debs []string) error {
+	if err := writeRepreproDistributions(c); err != nil {
+		return err
+	}
+
+	for _, deb := range debs {
+		output, err := runCommand(ctx, executor, "reprepro", c.repreproIncludeArgs(deb)...)
+		if err != nil {
+			return fmt.Errorf("reprepro failed to include %s: %w\nOutput: %s", deb, err, string(output))
+		}
+	}
+
+	if c.Remote != "" {
+		output, err := runCommand(ctx, executor, "rsync", c.rsyncArgs()...)
+		if err != nil {
+			return fmt.Errorf("failed to sync repository to %s: %w\nOutput: %s", c.Remote, err, string(output))
+		}
+	}
+
+	return nil
+}
+
+// repreproIncludeArgs returns the reprepro arguments that add deb to the repository.
+func (c *APTPublishConfig) repreproIncludeArgs(deb string) []string {
+	return []string{"-b", c.Repo, "-C", c.Component, "includedeb", c.Distribution, deb}
+}
+
+// rsyncArgs returns the rsync arguments that sync a reprepro repository to Remote,
+// leaving out its config and database.
+func (c *APTPublishConfig) rsyncArgs() []string {
+	return []string{"-a", "--exclude", "conf/", "--exclude", "db/", strings.TrimSuffix(c.Repo, "/") + "/", c.Remote}
+}
+
+// aptlySigning returns the aptly flag that signs the published distribution, or skips
+// signing without a GPG key.
+func (c *APTPublishConfig) aptlySigning() string {
+	if c.GPGKey != "" {
+		return "-gpg-key=" + c.GPGKey
+	}
+	return "-skip-signing"
+}
+
+// aptlyUpdateArgs returns the aptly arguments that update the published distribution.
+func (c *APTPublishConfig) aptlyUpdateArgs() []string {
+	update := []string{"publish", "update", c.aptlySigning(), c.Distribution}
+	if c.Remote != "" {
+		update = append(update, c.Remote)
+	}
+	return update
+}
+
+// writeRepreproDistributions creates conf/distributions for a new reprepro repository.
+// It accepts every supported architecture; an existing file is left untouched.
+func writeRepreproDistributions(c *APTPublishConfig) error {
+	path := filepath.Join(c.Repo, "conf", "distributions")
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	archs := map[string]bool{"all": true}
+	for _, arch := range debianArchitectures {
+		archs[arch] = true
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Codename: %s\n", c.Distribution)
+	fmt.Fprintf(&sb, "Components: %s\n", c.Component)
+	fmt.Fprintf(&sb, "Architectures: %s\n", strings.Join(sortedKeys(archs), " "))
+	if c.GPGKey != "" {
+		fmt.Fprintf(&sb, "SignWith: %s\n", c.GPGKey)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create reprepro config directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write reprepro distributions: %w", err)
+	}
+	return nil
+}
+
+// publishAptly adds debs to an aptly local repo (creating it if needed) and updates the
+// published distribution, publishing it for the first time if it does not exist yet.
+func (p *LinuxPkgPlugin) publishAptly(ctx context.Context, executor CommandExecutor, c *APTPublishConfig, debs []string) error {
+	if _, err := runCommand(ctx, executor, "aptly", "repo", "show", c.Repo); err != nil {
+		output, err := runCommand(ctx, executor, "aptly", "repo", "create",
+			"-distribution="+c.Distribution, "-component="+c.Component, c.Repo)
+		if err != nil {
+			return fmt.Errorf("aptly failed to create repo %s: %w\nOutput: %s", c.Repo, err, string(output))
+		}
+	}
+
+	output, err := runCommand(ctx, executor, "aptly", append([]string{"repo", "add", c.Repo}, debs...)...)
+	if err != nil {
+		return fmt.Errorf("aptly failed to add packages: %w\nOutput: %s", err, string(output))
+	}
+
+	if _, err := runCommand(ctx, executor, "aptly", c.aptlyUpdateArgs()...); err == nil {
+		return nil
+	}
+
+	publish := []string{"publish", "repo", c.aptlySigning(), "-distribution=" + c.Distribution, "-component=" + c.Component, c.Repo}
+	if c.Remote != "" {
+		publish = append(publish, c.Remote)
+	}
+	output, err = runCommand(ctx, executor, "aptly", publish...)
+	if err != nil {
+		return fmt.Errorf("aptly failed to publish %s: %w\nOutput: %s", c.Distribution, err, string(output))
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestValidatePublishAPT tests validation of the publish.apt block.
+func TestValidatePublishAPT(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		apt         map[string]any
+		expectValid bool
+	}{
+		{name: "reprepro", apt: map[string]any{"repo": "repo/apt", "gpg_key": "ABCD1234"}, expectValid: true},
+		{name: "aptly", apt: map[string]any{"tool": "aptly", "repo": "myapp-stable", "remote": "s3:releases:"}, expectValid: true},
+		{name: "missing repo", apt: map[string]any{}, expectValid: false},
+		{name: "unknown tool", apt: map[string]any{"tool": "dput", "repo": "repo"}, expectValid: false},
+		{name: "repo path traversal", apt: map[string]any{"repo": "../repo"}, expectValid: false},
+		{name: "aptly repo with slash", apt: map[string]any{"tool": "aptly", "repo": "a/b"}, expectValid: false},
+		{name: "invalid distribution", apt: map[string]any{"repo": "repo", "distribution": "stable main"}, expectValid: false},
+		{name: "option injection", apt: map[string]any{"repo": "repo", "remote": "--rsh=evil"}, expectValid: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &LinuxPkgPlugin{}
+			resp, err := p.Validate(context.Background(), map[string]any{"publish": map[string]any{"apt": tc.apt}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Valid != tc.expectValid {
+				t.Errorf("expected valid=%v, got %v: %v", tc.expectValid, resp.Valid, resp.Errors)
+			}
+			if !tc.expectValid && (len(resp.Errors) == 0 || resp.Errors[0].Field != "publish") {
+				t.Errorf("expected error on field publish, got %v", resp.Errors)
+			}
+		})
+	}
+}
+
+// TestPublishReprepro tests including debs with reprepro and syncing to a remote.
+func TestPublishReprepro(t *testing.T) {
+	t.Parallel()
+
+	repo := filepath.Join(t.TempDir(), "apt")
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return nil, nil
+		},
+	}
+
+	p := &LinuxPkgPlugin{}
+	cfg := &APTPublishConfig{
+		Tool:         "reprepro",
+		Repo:         repo,
+		Remote:       "deploy@apt.example.com:/srv/apt",
+		Distribution: "bookworm",
+		Component:    "main",
+		GPGKey:       "ABCD1234",
+	}
+	result, err := p.publishAPT(context.Background(), mock, cfg, []string{"dist/a_1.0_amd64.deb", "dist/a_1.0_arm64.deb"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"reprepro -b " + repo + " -C main includedeb bookworm dist/a_1.0_amd64.deb",
+		"reprepro -b " + repo + " -C main includedeb bookworm dist/a_1.0_arm64.deb",
+		"rsync -a --exclude conf/ --exclude db/ " + repo + "/ deploy@apt.example.com:/srv/apt",
+	}
+	if len(mock.Calls) != len(expected) {
+		t.Fatalf("expected %d calls, got %v", len(expected), mock.Calls)
+	}
+	for i, call := range mock.Calls {
+		if got := call.Name + " " + strings.Join(call.Args, " "); got != expected[i] {
+			t.Errorf("call %d: expected %q, got %q", i, expected[i], got)
+		}
+	}
+
+	distributions, err := os.ReadFile(filepath.Join(repo, "conf", "distributions"))
+	if err != nil {
+		t.Fatalf("expected conf/distributions to be created: %v", err)
+	}
+	for _, line := range []string{"Codename: bookworm", "Components: main", "SignWith: ABCD1234", "armhf"} {
+		if !strings.Contains(string(distributions), line) {
+			t.Errorf("expected %q in distributions, got %q", line, distributions)
+		}
+	}
+
+	if result["signed"] != true || result["remote"] != cfg.Remote {
+		t.Errorf("unexpected result: %v", result)
+	}
+}
+
+// TestPublishAptly tests adding debs with aptly and publishing a new distribution.
+func TestPublishAptly(t *testing.T) {
+	t.Parallel()
+
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			switch args[0] + " " + args[1] {
+			case "repo show", "publish update":
+				return []byte("not found"), errors.New("exit status 1")
+			}
+			return nil, nil
+		},
+	}
+
+	p := &LinuxPkgPlugin{}
+	cfg := &APTPublishConfig{
+		Tool:         "aptly",
+		Repo:         "myapp",
+		Remote:       "s3:releases:",
+		Distribution: "stable",
+		Component:    "main",
+	}
+	if _, err := p.publishAPT(context.Background(), mock, cfg, []string{"dist/a_1.0_amd64.deb"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"repo show myapp",
+		"repo create -distribution=stable -component=main myapp",
+		"repo add myapp dist/a_1.0_amd64.deb",
+		"publish update -skip-signing stable s3:releases:",
+		"publish repo -skip-signing -distribution=stable -component=main myapp s3:releases:",
+	}
+	if len(mock.Calls) != len(expected) {
+		t.Fatalf("expected %d calls, got %v", len(expected), mock.Calls)
+	}
+	for i, call := range mock.Calls {
+		if got := strings.Join(call.Args, " "); call.Name != "aptly" || got != expected[i] {
+			t.Errorf("call %d: expected %q, got %s %q", i, expected[i], call.Name, got)
+		}
+	}
+}
+
+// TestExecutePublishesToAPT tests that only debs are published after the build.
+func TestExecutePublishesToAPT(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if name == "reprepro" {
+				return nil, errors.New("exit status 254")
+			}
+			return nil, nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []any{"deb", "rpm"},
+			"publish":     map[string]any{"apt": map[string]any{"repo": "apt"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "failed to publish packages: apt: reprepro failed") {
+		t.Fatalf("expected publish failure, got %+v", resp)
+	}
+	if len(mock.Calls) != 1 || !strings.HasSuffix(mock.Calls[0].Args[len(mock.Calls[0].Args)-1], ".deb") {
+		t.Errorf("expected only the deb to be published, got %v", mock.Calls)
+	}
+	if packages, ok := resp.Outputs["packages"].([]string); !ok || len(packages) != 2 {
+		t.Errorf("expected built packages in outputs, got %v", resp.Outputs["packages"])
+	}
+}
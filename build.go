@@ -0,0 +1,409 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// buildJob is one format/architecture combination to build.
+type buildJob struct {
+	Format string
+	Target buildTarget
+	// Config is the configuration for the job's format, with any per-format overrides applied.
+	Config *Config
+	// ConfigPath is the prepared nfpm config for the job's target.
+	ConfigPath string
+	// Env holds the release variables (VERSION, RELEASE, ...) passed to nfpm.
+	Env []string
+	// Version is the release version the package is built for.
+	Version string
+	// Subpackage is the name of the subpackage the job builds, empty for the package.
+	Subpackage string
+	// Debug marks the job building the debug package split from the job before it.
+	Debug bool
+}
+
+// buildOutcome is the result of a build job.
+type buildOutcome struct {
+	// Artifact describes the built package. Nil when the job failed.
+	Artifact map[string]any
+	// Log is the persisted tool log, if any. It is set for failed jobs too.
+	Log string
+	// Err is the job's failure, ready to be reported.
+	Err error
+	// Inputs is the digest of the job's inputs when the build cache is enabled.
+	Inputs string
+	// Duration is how long the job took, including signing and checks.
+	Duration time.Duration
+}
+
+// successPolicies are the accepted values of success_policy.
+var successPolicies = []string{"any", "all"}
+
+// validateSuccessPolicy checks the policy deciding whether failed builds fail the run.
+func validateSuccessPolicy(policy string) error {
+	if !slices.Contains(successPolicies, policy) {
+		return fmt.Errorf("must be one of %s, got %q", strings.Join(successPolicies, ", "), policy)
+	}
+	return nil
+}
+
+// validateConcurrency checks the configured number of build workers.
+func validateConcurrency(concurrency int) error {
+	if concurrency < 0 {
+		return fmt.Errorf("concurrency must be 0 (one per CPU) or positive, got %d", concurrency)
+	}
+	return nil
+}
+
+// containerJobWeight is the number of build slots a job of the container packager takes.
+// Each one starts a container with its own nfpm, which costs a runner more CPU, memory,
+// and disk than an nfpm build on the host.
+const containerJobWeight = 2
+
+// buildSlots returns the number of build slots shared by every job of a run: the
+// configured concurrency, or the CPU count when it is 0.
+func buildSlots(concurrency int) int {
+	if concurrency == 0 {
+		return runtime.NumCPU()
+	}
+	return concurrency
+}
+
+// buildConcurrency returns the number of build workers: one per build slot, and never
+// more than the number of jobs.
+func buildConcurrency(concurrency, jobs int) int {
+	return max(1, min(buildSlots(concurrency), jobs))
+}
+
+// jobWeight returns the number of build slots a job takes while it builds.
+func jobWeight(job buildJob) int {
+	if job.Config.Packager == "container" {
+		return containerJobWeight
+	}
+	return 1
+}
+
+// slotPool hands out build slots, so the jobs building at once never take more slots
+// than the run has, whichever formats, targets, and packagers they combine.
+type slotPool struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+	size int
+	free int
+}
+
+// newSlotPool returns a pool of size slots.
+func newSlotPool(size int) *slotPool {
+	pool := &slotPool{size: size, free: size}
+	pool.cond = sync.NewCond(&pool.mu)
+	return pool
+}
+
+// acquire waits until weight slots are free and takes them. A weight above the pool
+// size takes every slot, so a heavy job still runs, alone. It returns the slots taken.
+func (s *slotPool) acquire(weight int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	weight = min(weight, s.size)
+	for s.free < weight {
+		s.cond.Wait()
+	}
+	s.free -= weight
+	return weight
+}
+
+// release returns slots taken by acquire.
+func (s *slotPool) release(slots int) {
+	s.mu.Lock()
+	s.free += slots
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+// runBuildJobs builds every job on a pool of workers and returns the outcomes in job
+// order. Workers share the run's build slots, those of every package in a monorepo
+// run, and each job takes as many as it weighs, so container builds leave room for
+// fewer jobs beside them. With fail_fast, jobs after a failed job are no longer
+// started; every job before it still runs, so the first failure in job order is the
+// same regardless of scheduling. Skipped jobs have a nil outcome.
+func (p *LinuxPkgPlugin) runBuildJobs(ctx context.Context, executor CommandExecutor, cfg *Config, jobs []buildJob, provenance *provenanceContext, cache *buildCache) []*buildOutcome {
+	outcomes := make([]*buildOutcome, len(jobs))
+
+	var mu sync.Mutex
+	firstFailure := len(jobs)
+	failedBefore := func(i int) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return cfg.FailFast && firstFailure < i
+	}
+
+	slots := cfg.Run.buildSlots()
+	if slots == nil {
+		slots = newSlotPool(buildSlots(cfg.Concurrency))
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range buildConcurrency(cfg.Concurrency, len(jobs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if failedBefore(i) {
+					continue
+				}
+				taken := slots.acquire(jobWeight(jobs[i]))
+				started := time.Now()
+				outcome := p.buildArtifact(ctx, executor, jobs[i], provenance, cache)
+				outcome.Duration = time.Since(started)
+				slots.release(taken)
+				outcomes[i] = outcome
+				if outcome.Err != nil {
+					mu.Lock()
+					firstFailure = min(firstFailure, i)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for i := range jobs {
+		if failedBefore(i) {
+			break
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return outcomes
+}
+
+// buildArtifact builds, signs, and describes a single package. With a build cache, a
+// package whose inputs are unchanged is reused instead.
+func (p *LinuxPkgPlugin) buildArtifact(ctx context.Context, executor CommandExecutor, job buildJob, provenance *provenanceContext, cache *buildCache) *buildOutcome {
+	cfg, format, target := job.Config, job.Format, job.Target
+	outcome := &buildOutcome{}
+
+	if cache != nil {
+		inputs, err := cache.inputsDigest(job)
+		if err != nil {
+			outcome.Err = fmt.Errorf("failed to hash build inputs: %w", err)
+			return outcome
+		}
+		outcome.Inputs = inputs
+		if artifact := cache.lookup(job, inputs); artifact != nil {
+			outcome.Artifact = artifact
+			return outcome
+		}
+	}
+
+	name, prefix := fmt.Sprintf("%s-%s", format, target.fileName()), format+"/"+target.Arch
+	if job.Subpackage != "" {
+		name += "-" + job.Subpackage
+		prefix += " " + job.Subpackage
+	}
+	if job.Debug {
+		name += "-debug"
+		prefix += " debug"
+	}
+	log := newLinePrefixWriter(p.getLogOutput(), "["+prefix+"] ")
+	result, output, err := p.runBuild(ctx, executor, cfg, job.ConfigPath, format, target, job.Env, log)
+	_ = log.Flush()
+	// The log holds the output of every step that ran, so it is written however the job
+	// ends. Failing to write it fails the job.
+	if cfg.PersistLogs {
+		defer func() {
+			logPath, logErr := writeToolLog(cfg.OutputDir, name, output, cfg.CompressLogs)
+			if logErr != nil {
+				if outcome.Err == nil {
+					outcome.Artifact, outcome.Err = nil, logErr
+				}
+				return
+			}
+			outcome.Log = logPath
+		}()
+	}
+	signed := err == nil && format == "rpm" && cfg.RPMSigning != nil
+	if signed {
+		var signOutput []byte
+		signOutput, err = p.signRPM(ctx, executor, cfg.RPMSigning, result.Path)
+		output = append(output, signOutput...)
+	}
+
+	if err != nil {
+		outcome.Err = fmt.Errorf("failed to build %s package for %s: %w\nOutput: %s", format, target.Arch, err, string(output))
+		return outcome
+	}
+
+	arch := result.Arch
+	if arch == "" {
+		arch = target.Arch
+	}
+	digest, size, err := artifactDigest(result.Path)
+	if err != nil {
+		outcome.Err = err
+		return outcome
+	}
+	artifact := map[string]any{
+		"path":   result.Path,
+		"format": format,
+		"arch":   arch,
+		"sha256": digest,
+		"size":   size,
+	}
+	if stats, err := readPackageStats(result.Path, format); err == nil {
+		artifact["installed_size"] = stats.InstalledSize
+		artifact["file_count"] = stats.FileCount
+	}
+	if distro := cfg.distroName(); distro != "" {
+		artifact["distro"] = distro
+	}
+	if job.Subpackage != "" {
+		artifact["subpackage"] = job.Subpackage
+	}
+	if job.Debug {
+		artifact["debug"] = true
+	}
+	if signed || (format == "apk" && cfg.APKKeyPath != "") {
+		artifact["signed"] = true
+	}
+	if cfg.Checks != nil && (cfg.Checks.Metadata || cfg.Checks.Version) {
+		meta, err := checkPackageMetadata(job, result.Path, cfg.Checks)
+		if err != nil {
+			outcome.Err = err
+			return outcome
+		}
+		artifact["metadata"] = meta.asMap()
+	}
+	if provenance != nil && digest != "" {
+		provenancePath, err := writeProvenance(provenance, result.Path, format, arch, digest)
+		if err != nil {
+			outcome.Err = err
+			return outcome
+		}
+		artifact["provenance"] = provenancePath
+	}
+	if cfg.Cosign != nil {
+		signResult, signOutput, err := p.cosignSign(ctx, executor, cfg.Cosign, result.Path)
+		output = append(output, signOutput...)
+		if err != nil {
+			outcome.Err = fmt.Errorf("%w\nOutput: %s", err, string(signOutput))
+			return outcome
+		}
+		artifact["signature"] = signResult.Signature
+		if signResult.Certificate != "" {
+			artifact["certificate"] = signResult.Certificate
+		}
+		if signResult.Bundle != "" {
+			artifact["bundle"] = signResult.Bundle
+			artifact["rekor_log_index"] = signResult.LogIndex
+			artifact["rekor_uuid"] = signResult.UUID
+		}
+	}
+
+	outcome.Artifact = artifact
+	return outcome
+}
+
+// buildErrors describes every failed job for the errors output. Failures of the nfpm
+// binary carry the command, its arguments, exit code, and stderr.
+func buildErrors(jobs []buildJob, outcomes []*buildOutcome) []map[string]any {
+	var entries []map[string]any
+	for i, outcome := range outcomes {
+		if outcome == nil || outcome.Err == nil {
+			continue
+		}
+		entry := map[string]any{
+			"format": jobs[i].Format,
+			"arch":   jobs[i].Target.Arch,
+			"error":  outcome.Err.Error(),
+		}
+		var cmdErr *CommandError
+		if errors.As(outcome.Err, &cmdErr) {
+			maps.Copy(entry, cmdErr.asMap())
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// buildResults reports the result of every job for the builds output. Jobs that were not
+// started after an earlier failure are marked as skipped; the others carry how long
+// they took in duration_ms.
+func buildResults(jobs []buildJob, outcomes []*buildOutcome) []map[string]any {
+	results := make([]map[string]any, len(jobs))
+	for i, job := range jobs {
+		result := map[string]any{"format": job.Format, "arch": job.Target.Arch}
+		switch outcome := outcomes[i]; {
+		case outcome == nil:
+			result["success"] = false
+			result["skipped"] = true
+		case outcome.Err != nil:
+			result["success"] = false
+			result["error"] = outcome.Err.Error()
+		default:
+			result["success"] = true
+			result["package"] = outcome.Artifact["path"]
+		}
+		if outcome := outcomes[i]; outcome != nil {
+			result["duration_ms"] = outcome.Duration.Milliseconds()
+		}
+		results[i] = result
+	}
+	return results
+}
+
+// buildFailureSummary describes the failed builds, one per line with the first line of
+// its error.
+func buildFailureSummary(jobs []buildJob, outcomes []*buildOutcome, failed int) string {
+	summary := fmt.Sprintf("%d of %d build(s) failed:", failed, len(jobs))
+	for _, outcome := range outcomes {
+		if outcome != nil && outcome.Err != nil {
+			line, _, _ := strings.Cut(outcome.Err.Error(), "\n")
+			summary += "\n  " + line
+		}
+	}
+	return summary
+}
+
+// partialOutputs describes what a failed run built, so the packages can be salvaged or
+// cleaned up: the packages and artifacts of the jobs that succeeded, the formats that
+// failed, and the result of every job.
+func partialOutputs(jobs []buildJob, outcomes []*buildOutcome) map[string]any {
+	packages := []string{}
+	logs := []string{}
+	artifacts := []map[string]any{}
+	failedFormats := []string{}
+	for i, outcome := range outcomes {
+		if outcome == nil {
+			continue
+		}
+		if outcome.Log != "" {
+			logs = append(logs, outcome.Log)
+		}
+		if outcome.Err != nil {
+			if !slices.Contains(failedFormats, jobs[i].Format) {
+				failedFormats = append(failedFormats, jobs[i].Format)
+			}
+			continue
+		}
+		packages = append(packages, outcome.Artifact["path"].(string))
+		artifacts = append(artifacts, outcome.Artifact)
+	}
+	return map[string]any{
+		"packages":       packages,
+		"artifacts":      artifacts,
+		"failed_formats": failedFormats,
+		"builds":         buildResults(jobs, outcomes),
+		"errors":         buildErrors(jobs, outcomes),
+		"logs":           logs,
+	}
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestBuildConcurrency tests the number of build workers.
+func TestBuildConcurrency(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		concurrency int
+		jobs        int
+		expected    int
+	}{
+		{name: "serial", concurrency: 1, jobs: 6, expected: 1},
+		{name: "capped by jobs", concurrency: 8, jobs: 3, expected: 3},
+		{name: "cpu count", concurrency: 0, jobs: 1000, expected: runtime.NumCPU()},
+		{name: "no jobs", concurrency: 4, jobs: 0, expected: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := buildConcurrency(tc.concurrency, tc.jobs); got != tc.expected {
+				t.Errorf("expected %d workers, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestExecuteConcurrencyWeights tests that container builds take more of the build slots
+// than builds on the host.
+func TestExecuteConcurrencyWeights(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		packager  string
+		expectMax int32
+	}{
+		{"nfpm-cli", "nfpm-cli", 4},
+		{"container", "container", 4 / containerJobWeight},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: test\nversion: 1.0.0"), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			var active, peak atomic.Int32
+			mock := &MockCommandExecutor{
+				RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+					n := active.Add(1)
+					defer active.Add(-1)
+					for {
+						old := peak.Load()
+						if n <= old || peak.CompareAndSwap(old, n) {
+							break
+						}
+					}
+					time.Sleep(20 * time.Millisecond)
+					return []byte("created package: " + args[len(args)-1] + "test.pkg"), nil
+				},
+			}
+			p := &LinuxPkgPlugin{
+				cmdExecutor: mock,
+				lookPath:    func(file string) (string, error) { return "/usr/bin/" + file, nil },
+			}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook: plugin.HookPostPublish,
+				Config: map[string]any{
+					"working_dir": dir,
+					"formats":     []string{"deb", "rpm", "apk"},
+					"targets":     []string{"amd64", "arm64"},
+					"packager":    tc.packager,
+					"concurrency": 4,
+				},
+				Context: plugin.ReleaseContext{Version: "1.0.0"},
+			})
+			if err != nil || !resp.Success {
+				t.Fatalf("expected success, got %v, %+v", err, resp)
+			}
+			if got := peak.Load(); got != tc.expectMax {
+				t.Errorf("expected at most %d builds at once, got %d", tc.expectMax, got)
+			}
+		})
+	}
+}
+
+// TestSlotPool tests that a job heavier than the pool still runs, alone.
+func TestSlotPool(t *testing.T) {
+	t.Parallel()
+
+	pool := newSlotPool(1)
+	taken := pool.acquire(containerJobWeight)
+	if taken != 1 || pool.free != 0 {
+		t.Fatalf("expected the only slot to be taken, got %d with %d free", taken, pool.free)
+	}
+	pool.release(taken)
+	if pool.free != 1 {
+		t.Errorf("expected the slot back, got %d free", pool.free)
+	}
+}
+
+// TestValidateConcurrency tests validation of the concurrency option.
+func TestValidateConcurrency(t *testing.T) {
+	t.Parallel()
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Validate(context.Background(), map[string]any{"concurrency": -1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Valid || len(resp.Errors) != 1 || resp.Errors[0].Field != "concurrency" {
+		t.Errorf("expected a concurrency error, got %v", resp.Errors)
+	}
+}
+
+// TestExecuteParallelEmbedded tests that parallel builds report artifacts in the same
+// order as a serial build.
+func TestExecuteParallelEmbedded(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "")
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb", "rpm", "apk"},
+			"targets":     []string{"amd64", "arm64"},
+			"concurrency": 4,
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	artifacts := resp.Outputs["artifacts"].([]map[string]any)
+	if len(artifacts) != 6 {
+		t.Fatalf("expected 6 artifacts, got %v", artifacts)
+	}
+	// Artifacts report the native arch name of each format.
+	expected := [][2]string{
+		{"deb", "amd64"}, {"rpm", "x86_64"}, {"apk", "x86_64"},
+		{"deb", "arm64"}, {"rpm", "aarch64"}, {"apk", "aarch64"},
+	}
+	for i, want := range expected {
+		if artifacts[i]["format"] != want[0] || artifacts[i]["arch"] != want[1] {
+			t.Errorf("artifact %d: expected %s/%s, got %v/%v", i, want[0], want[1], artifacts[i]["format"], artifacts[i]["arch"])
+		}
+		if _, err := os.Stat(artifacts[i]["path"].(string)); err != nil {
+			t.Errorf("expected package %v to exist: %v", artifacts[i]["path"], err)
+		}
+	}
+}
+
+// TestExecuteParallelFirstFailure tests that the first failing build in job order is
+// reported even when a later build fails first.
+func TestExecuteParallelFirstFailure(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: test\nversion: 1.0.0\narch: amd64\n"), 0644); err != nil {
+		t.Fatalf("failed to create test config: %v", err)
+	}
+
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			doc, err := loadNfpmConfig(args[2])
+			if err != nil {
+				return nil, err
+			}
+			arch, format := doc["arch"].(string), args[4]
+			if arch == "amd64" {
+				// Let the arm64 builds finish first.
+				time.Sleep(50 * time.Millisecond)
+			}
+			if format == "rpm" {
+				return []byte("rpmbuild exploded"), errors.New("exit status 1")
+			}
+			return []byte("created package: dist/test_" + arch + packageExtensions[format]), nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb", "rpm"},
+			"targets":     []string{"amd64", "arm64"},
+			"packager":    "nfpm-cli",
+			"concurrency": 4,
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success {
+		t.Fatal("expected failure")
+	}
+	if !strings.HasPrefix(resp.Error, "failed to build rpm package for amd64") {
+		t.Errorf("expected the amd64 rpm failure to be reported, got %q", resp.Error)
+	}
+}
+
+// TestExecuteCommandError tests that nfpm failures are reported with their command, exit
+// code, and stderr.
+func TestExecuteCommandError(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: test\nversion: 1.0.0"), 0644); err != nil {
+		t.Fatalf("failed to create test config: %v", err)
+	}
+	exitErr := exec.Command("sh", "-c", "exit 2").Run()
+	if exitErr == nil {
+		t.Fatal("expected sh to fail")
+	}
+
+	mock := &MockCommandExecutor{
+		ExecFunc: func(ctx context.Context, spec ExecSpec) (*ExecResult, error) {
+			if spec.Args[4] == "rpm" {
+				return &ExecResult{Stdout: []byte("using rpm packager\n"), Stderr: []byte("rpmbuild exploded\n"),
+					Combined: []byte("using rpm packager\nrpmbuild exploded\n")}, exitErr
+			}
+			output := []byte("created package: dist/test.deb")
+			return &ExecResult{Stdout: output, Combined: output}, nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb", "rpm"},
+			"target":      "amd64",
+			"packager":    "nfpm-cli",
+			"concurrency": 1,
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success || !strings.HasPrefix(resp.Error, "failed to build rpm package for amd64: nfpm exited with status 2") {
+		t.Fatalf("expected the rpm failure, got %+v", resp)
+	}
+
+	expected := []map[string]any{{
+		"format":    "rpm",
+		"arch":      "amd64",
+		"error":     resp.Error,
+		"cmd":       "nfpm",
+		"args":      mock.Calls[1].Args,
+		"exit_code": 2,
+		"stderr":    "rpmbuild exploded\n",
+	}}
+	if errs := resp.Outputs["errors"]; !reflect.DeepEqual(errs, expected) {
+		t.Errorf("expected errors %v, got %v", expected, errs)
+	}
+}
+
+// TestExecuteContinueOnError tests that without fail_fast every format is built and the
+// success policy decides the outcome.
+func TestExecuteContinueOnError(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: test\nversion: 1.0.0"), 0644); err != nil {
+		t.Fatalf("failed to create test config: %v", err)
+	}
+
+	execute := func(failing []string, policy string) *plugin.ExecuteResponse {
+		t.Helper()
+
+		mock := &MockCommandExecutor{
+			RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				format := args[4]
+				if slices.Contains(failing, format) {
+					return []byte(format + " exploded"), errors.New("exit status 1")
+				}
+				return []byte("created package: " + args[len(args)-1] + "test." + format), nil
+			},
+		}
+		p := &LinuxPkgPlugin{cmdExecutor: mock}
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook: plugin.HookPostPublish,
+			Config: map[string]any{
+				"working_dir":    dir,
+				"formats":        []string{"deb", "rpm", "apk"},
+				"target":         "amd64",
+				"packager":       "nfpm-cli",
+				"concurrency":    1,
+				"fail_fast":      false,
+				"success_policy": policy,
+			},
+			Context: plugin.ReleaseContext{Version: "1.0.0"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(mock.Calls) != 3 {
+			t.Errorf("expected every format to be built, got %d builds", len(mock.Calls))
+		}
+		return resp
+	}
+
+	resp := execute([]string{"deb"}, "any")
+	if !resp.Success || !strings.Contains(resp.Message, "; 1 build(s) failed") {
+		t.Fatalf("expected success with a failed build, got %+v", resp)
+	}
+	if packages := resp.Outputs["packages"].([]string); len(packages) != 2 {
+		t.Errorf("expected the rpm and apk packages, got %v", packages)
+	}
+	builds := resp.Outputs["builds"].([]map[string]any)
+	if builds[0]["success"] != false || !strings.HasPrefix(builds[0]["error"].(string), "failed to build deb package for amd64") {
+		t.Errorf("expected the deb build to fail, got %v", builds[0])
+	}
+	if builds[1]["success"] != true || builds[1]["package"] != filepath.Join(dir, "dist", "test.rpm") {
+		t.Errorf("expected the rpm build to succeed, got %v", builds[1])
+	}
+
+	resp = execute([]string{"deb"}, "all")
+	expected := "1 of 3 build(s) failed:\n  failed to build deb package for amd64: nfpm: exit status 1"
+	if resp.Success || resp.Error != expected {
+		t.Errorf("expected error %q, got %+v", expected, resp)
+	}
+
+	resp = execute([]string{"deb", "rpm", "apk"}, "any")
+	if resp.Success || !strings.HasPrefix(resp.Error, "3 of 3 build(s) failed:") {
+		t.Errorf("expected every build to fail, got %+v", resp)
+	}
+	if errs := resp.Outputs["errors"].([]map[string]any); len(errs) != 3 {
+		t.Errorf("expected 3 errors, got %v", errs)
+	}
+}
+
+// TestValidateSuccessPolicy tests validation of the success_policy option.
+func TestValidateSuccessPolicy(t *testing.T) {
+	t.Parallel()
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Validate(context.Background(), map[string]any{"success_policy": "most"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Valid || len(resp.Errors) != 1 || resp.Errors[0].Field != "success_policy" {
+		t.Errorf("expected a success_policy error, got %v", resp.Errors)
+	}
+}
+
+// TestExecutePartialOutputs tests that a failed run lists the packages built before the
+// failure.
+func TestExecutePartialOutputs(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: test\nversion: 1.0.0"), 0644); err != nil {
+		t.Fatalf("failed to create test config: %v", err)
+	}
+
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if format := args[4]; format != "rpm" {
+				return []byte("created package: " + args[len(args)-1] + "test." + format), nil
+			}
+			return []byte("rpmbuild exploded"), errors.New("exit status 1")
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb", "rpm", "apk"},
+			"target":      "amd64",
+			"packager":    "nfpm-cli",
+			"concurrency": 1,
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success {
+		t.Fatal("expected failure")
+	}
+
+	deb := filepath.Join(dir, "dist", "test.deb")
+	if packages := resp.Outputs["packages"].([]string); !reflect.DeepEqual(packages, []string{deb}) {
+		t.Errorf("expected the deb package, got %v", packages)
+	}
+	if artifacts := resp.Outputs["artifacts"].([]map[string]any); len(artifacts) != 1 || artifacts[0]["path"] != deb {
+		t.Errorf("expected the deb artifact, got %v", artifacts)
+	}
+	if failed := resp.Outputs["failed_formats"].([]string); !reflect.DeepEqual(failed, []string{"rpm"}) {
+		t.Errorf("expected rpm to fail, got %v", failed)
+	}
+	if builds := resp.Outputs["builds"].([]map[string]any); builds[2]["format"] != "apk" || builds[2]["skipped"] != true {
+		t.Errorf("expected the apk build to be skipped, got %v", builds)
+	}
+}
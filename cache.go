@@ -0,0 +1,306 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// cacheManifestName is the build cache manifest kept in the output directory.
+const cacheManifestName = ".linuxpkg-cache.json"
+
+// stagingDirPattern matches the temporary directories the plugin stages configs and
+// generated files in, such as linuxpkg-assets-123456, whose names change every run.
+var stagingDirPattern = regexp.MustCompile(regexp.QuoteMeta(filepath.Join(os.TempDir(), "linuxpkg-")) + `([a-z]+-)?[0-9]+`)
+
+// stablePaths replaces the staging directories in s with a placeholder for their kind,
+// so digests of the configs and files staged in them are the same on every run.
+func stablePaths(s string) string {
+	return stagingDirPattern.ReplaceAllString(s, "<tmp>/linuxpkg-${1}")
+}
+
+// cacheEntry records the inputs of a built package and what the build produced.
+type cacheEntry struct {
+	Inputs        string `json:"inputs"`
+	Path          string `json:"path"`
+	Arch          string `json:"arch"`
+	SHA256        string `json:"sha256"`
+	Size          int64  `json:"size"`
+	InstalledSize int64  `json:"installed_size,omitempty"`
+	FileCount     int    `json:"file_count,omitempty"`
+	Signed        bool   `json:"signed,omitempty"`
+	Provenance    string `json:"provenance,omitempty"`
+	Signature     string `json:"signature,omitempty"`
+	Certificate   string `json:"certificate,omitempty"`
+	Bundle        string `json:"bundle,omitempty"`
+	RekorLogIndex int64  `json:"rekor_log_index,omitempty"`
+	RekorUUID     string `json:"rekor_uuid,omitempty"`
+}
+
+// buildCache skips builds whose inputs match a package already in the output directory.
+// Entries are read before the build and only replaced after it, so workers can look
+// them up concurrently.
+type buildCache struct {
+	path string
+	// salt covers the inputs shared by every job: release version and commit, packager,
+	// and signing settings.
+	salt    string
+	entries map[string]cacheEntry
+}
+
+// loadBuildCache reads the cache manifest from the output directory. A missing or
+// unreadable manifest starts an empty cache.
+func loadBuildCache(cfg *Config, version, commit string) *buildCache {
+	cache := &buildCache{
+		path:    filepath.Join(cfg.OutputDir, cacheManifestName),
+		entries: make(map[string]cacheEntry),
+	}
+
+	salt := []string{pluginVersion, version, commit, cfg.Packager, cfg.APKKeyPath, cfg.APKKeyName}
+	if cfg.RPMSigning != nil {
+		salt = append(salt, cfg.RPMSigning.Method, cfg.RPMSigning.KeyFile, cfg.RPMSigning.KeyID)
+	}
+	if cfg.Cosign != nil {
+		salt = append(salt, cfg.Cosign.Mode, cfg.Cosign.Key, cfg.Cosign.RekorURL)
+	}
+	cache.salt = strings.Join(salt, "\x00")
+
+	if data, err := os.ReadFile(cache.path); err == nil {
+		// A corrupt manifest only costs a rebuild.
+		_ = json.Unmarshal(data, &cache.entries)
+	}
+	return cache
+}
+
+// cacheKey identifies a job in the manifest.
+func cacheKey(job buildJob) string {
+	key := job.Format + "-" + job.Target.Arch
+	if distro := job.Config.distroName(); distro != "" {
+		key = distro + "-" + key
+	}
+	if job.Subpackage != "" {
+		key += "-" + job.Subpackage
+	}
+	if job.Debug {
+		key += "-debug"
+	}
+	return key
+}
+
+// inputsDigest hashes everything that determines a job's package: the prepared nfpm
+// config, the files it references, the compiled binary, the job's format, target,
+// output directory, and file name template, and the cache salt. Staging directories are left out of the config
+// and file names, so generated scripts, changelogs, and other staged files are covered
+// by their contents alone.
+func (c *buildCache) inputsDigest(job buildJob) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%t\x00%s\x00%s\x00%s\x00", c.salt, job.Format, job.Target.Arch, job.Target.Override, job.Config.OutputDir, job.Config.Compression.setting(job.Format), job.Config.FilenameTemplate)
+
+	config, err := os.ReadFile(job.ConfigPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", job.ConfigPath, err)
+	}
+	io.WriteString(h, stablePaths(string(config)))
+	// A compiled binary is referenced through the environment rather than a literal path.
+	for _, kv := range job.Env {
+		if binary, ok := strings.CutPrefix(kv, goBinaryEnv+"="); ok {
+			if err := hashFile(h, binary); err != nil {
+				return "", err
+			}
+		}
+	}
+	doc, err := loadNfpmConfig(job.ConfigPath)
+	if err != nil {
+		return "", err
+	}
+	files, err := referencedFiles(doc)
+	if err != nil {
+		return "", err
+	}
+	for _, file := range files {
+		fmt.Fprintf(h, "\x00%s\x00", stablePaths(file))
+		if err := hashFile(h, file); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// lookup returns the cached artifact for a job when its inputs are unchanged and the
+// package is still in place with the recorded digest.
+func (c *buildCache) lookup(job buildJob, inputs string) map[string]any {
+	entry, ok := c.entries[cacheKey(job)]
+	if !ok || entry.Inputs != inputs {
+		return nil
+	}
+	if digest, _, err := artifactDigest(entry.Path); err != nil || digest == "" || digest != entry.SHA256 {
+		return nil
+	}
+
+	artifact := map[string]any{
+		"path":   entry.Path,
+		"format": job.Format,
+		"arch":   entry.Arch,
+		"sha256": entry.SHA256,
+		"size":   entry.Size,
+		"cached": true,
+	}
+	if distro := job.Config.distroName(); distro != "" {
+		artifact["distro"] = distro
+	}
+	if job.Subpackage != "" {
+		artifact["subpackage"] = job.Subpackage
+	}
+	if job.Debug {
+		artifact["debug"] = true
+	}
+	if entry.FileCount > 0 {
+		artifact["installed_size"] = entry.InstalledSize
+		artifact["file_count"] = entry.FileCount
+	}
+	if entry.Signed {
+		artifact["signed"] = true
+	}
+	for key, value := range map[string]string{
+		"provenance":  entry.Provenance,
+		"signature":   entry.Signature,
+		"certificate": entry.Certificate,
+	} {
+		if value != "" {
+			artifact[key] = value
+		}
+	}
+	if entry.Bundle != "" {
+		artifact["bundle"] = entry.Bundle
+		artifact["rekor_log_index"] = entry.RekorLogIndex
+		artifact["rekor_uuid"] = entry.RekorUUID
+	}
+	return artifact
+}
+
+// record stores the inputs and result of a job.
+func (c *buildCache) record(job buildJob, inputs string, artifact map[string]any) {
+	entry := cacheEntry{Inputs: inputs}
+	entry.Path, _ = artifact["path"].(string)
+	entry.Arch, _ = artifact["arch"].(string)
+	entry.SHA256, _ = artifact["sha256"].(string)
+	entry.Size, _ = artifact["size"].(int64)
+	entry.InstalledSize, _ = artifact["installed_size"].(int64)
+	entry.FileCount, _ = artifact["file_count"].(int)
+	entry.Signed, _ = artifact["signed"].(bool)
+	entry.Provenance, _ = artifact["provenance"].(string)
+	entry.Signature, _ = artifact["signature"].(string)
+	entry.Certificate, _ = artifact["certificate"].(string)
+	entry.Bundle, _ = artifact["bundle"].(string)
+	entry.RekorLogIndex, _ = artifact["rekor_log_index"].(int64)
+	entry.RekorUUID, _ = artifact["rekor_uuid"].(string)
+	c.entries[cacheKey(job)] = entry
+}
+
+// save writes the manifest.
+func (c *buildCache) save() error {
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode build cache: %w", err)
+	}
+	if err := os.WriteFile(c.path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write build cache: %w", err)
+	}
+	return nil
+}
+
+// referencedFiles returns the sorted local files an nfpm config pulls into a package:
+// contents sources (globs and directories expanded) and scripts, top-level, in the
+// format blocks, and in the per-format overrides, and the changelog. Missing files are
+// skipped; nfpm reports them when it builds.
+func referencedFiles(doc map[string]any) ([]string, error) {
+	sections := []map[string]any{doc}
+	for _, format := range nfpmFormatBlocks {
+		if block, ok := doc[format].(map[string]any); ok {
+			sections = append(sections, block)
+		}
+	}
+	if overrides, ok := doc["overrides"].(map[string]any); ok {
+		for _, format := range sortedKeys(overrides) {
+			if override, ok := overrides[format].(map[string]any); ok {
+				sections = append(sections, override)
+			}
+		}
+	}
+
+	var sources []string
+	for _, section := range sections {
+		for _, raw := range contentEntries(section) {
+			entry, ok := raw.(map[string]any)
+			if !ok {
+				continue
+			}
+			src, _ := entry["src"].(string)
+			entryType, _ := entry["type"].(string)
+			if src == "" || entryType == "symlink" || entryType == "dir" {
+				continue
+			}
+			sources = append(sources, src)
+		}
+		if scripts, ok := section["scripts"].(map[string]any); ok {
+			for _, script := range scripts {
+				if path, ok := script.(string); ok {
+					sources = append(sources, path)
+				}
+			}
+		}
+	}
+	if changelog, ok := doc["changelog"].(string); ok {
+		sources = append(sources, changelog)
+	}
+
+	seen := make(map[string]bool)
+	for _, src := range sources {
+		matches := []string{src}
+		if hasGlobMeta(src) {
+			globbed, _, err := globFiles(src, nil)
+			if err != nil {
+				return nil, err
+			}
+			matches = globbed
+		}
+		for _, match := range matches {
+			err := filepath.WalkDir(match, func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !d.IsDir() {
+					seen[p] = true
+				}
+				return nil
+			})
+			if err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to read %s: %w", match, err)
+			}
+		}
+	}
+
+	return sortedKeys(seen), nil
+}
+
+// hashFile writes a file's contents to h.
+func hashFile(h io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestReferencedFiles tests collecting content sources, scripts, and the changelog,
+// including those of format blocks and overrides.
+func TestReferencedFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, file := range []string{"bin/myapp", "etc/a.conf", "etc/sub/b.conf", "scripts/postinstall.sh", "changelog.yml", "rpm/extra.conf", "rpm/pretrans.sh", "deb/templates"} {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(file), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	doc := map[string]any{
+		"contents": []any{
+			map[string]any{"src": "bin/myapp", "dst": "/usr/bin/myapp"},
+			map[string]any{"src": "etc/**/*.conf", "dst": "/etc/myapp"},
+			map[string]any{"src": "/usr/bin/myapp", "dst": "/usr/local/bin/myapp", "type": "symlink"},
+			map[string]any{"src": "missing", "dst": "/opt/missing"},
+		},
+		"scripts":   map[string]any{"postinstall": "scripts/postinstall.sh"},
+		"changelog": "changelog.yml",
+		"deb":       map[string]any{"scripts": map[string]any{"templates": "deb/templates"}},
+		"overrides": map[string]any{
+			"rpm": map[string]any{
+				"contents": []any{map[string]any{"src": "rpm/extra.conf", "dst": "/etc/myapp/extra.conf"}},
+				"scripts":  map[string]any{"pretrans": "rpm/pretrans.sh"},
+			},
+		},
+	}
+	rebaseNfpmPaths(doc, dir)
+
+	files, err := referencedFiles(doc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var expected []string
+	for _, file := range []string{"bin/myapp", "changelog.yml", "deb/templates", "etc/a.conf", "etc/sub/b.conf", "rpm/extra.conf", "rpm/pretrans.sh", "scripts/postinstall.sh"} {
+		expected = append(expected, filepath.Join(dir, file))
+	}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected %v, got %v", expected, files)
+	}
+}
+
+// TestExecuteBuildCache tests that unchanged packages are reused and changed inputs
+// trigger a rebuild.
+func TestExecuteBuildCache(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+
+	build := func(version string) *plugin.ExecuteResponse {
+		t.Helper()
+		p := &LinuxPkgPlugin{}
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  map[string]any{"working_dir": dir, "formats": []string{"deb", "rpm"}, "cache": true},
+			Context: plugin.ReleaseContext{Version: version},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !resp.Success {
+			t.Fatalf("expected success, got failure: %s", resp.Error)
+		}
+		return resp
+	}
+	cachedFlags := func(resp *plugin.ExecuteResponse) []bool {
+		var flags []bool
+		for _, artifact := range resp.Outputs["artifacts"].([]map[string]any) {
+			flags = append(flags, artifact["cached"] == true)
+		}
+		return flags
+	}
+
+	first := build("1.2.3")
+	if flags := cachedFlags(first); !reflect.DeepEqual(flags, []bool{false, false}) {
+		t.Fatalf("expected a fresh build, got cached=%v", flags)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "dist", cacheManifestName)); err != nil {
+		t.Fatalf("expected cache manifest: %v", err)
+	}
+
+	second := build("1.2.3")
+	if flags := cachedFlags(second); !reflect.DeepEqual(flags, []bool{true, true}) {
+		t.Errorf("expected both packages to be reused, got cached=%v", flags)
+	}
+	if !reflect.DeepEqual(second.Outputs["packages"], first.Outputs["packages"]) {
+		t.Errorf("expected the same packages, got %v and %v", first.Outputs["packages"], second.Outputs["packages"])
+	}
+
+	// Changing a content file invalidates every package.
+	if err := os.WriteFile(filepath.Join(dir, "myapp"), []byte("#!/bin/sh\necho changed\n"), 0755); err != nil {
+		t.Fatalf("failed to update binary: %v", err)
+	}
+	if flags := cachedFlags(build("1.2.3")); !reflect.DeepEqual(flags, []bool{false, false}) {
+		t.Errorf("expected a rebuild after a content change, got cached=%v", flags)
+	}
+
+	// Removing a package forces just that one to be rebuilt.
+	rpm := build("1.2.3").Outputs["packages"].([]string)[1]
+	if err := os.Remove(rpm); err != nil {
+		t.Fatalf("failed to remove package: %v", err)
+	}
+	if flags := cachedFlags(build("1.2.3")); !reflect.DeepEqual(flags, []bool{true, false}) {
+		t.Errorf("expected only the removed package to be rebuilt, got cached=%v", flags)
+	}
+
+	// A new release version invalidates every package.
+	if flags := cachedFlags(build("1.2.4")); !reflect.DeepEqual(flags, []bool{false, false}) {
+		t.Errorf("expected a rebuild for a new version, got cached=%v", flags)
+	}
+}
+
+// TestExecuteBuildCacheGeneratedFiles tests that packages with rendered scripts and a
+// generated changelog, which are staged in new temporary directories every run, are
+// reused while their contents are unchanged.
+func TestExecuteBuildCacheGeneratedFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+	script := filepath.Join(dir, "postinstall.sh.tmpl")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho myapp {{.Version}}\n"), 0644); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+
+	build := func() []bool {
+		t.Helper()
+		p := &LinuxPkgPlugin{}
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook: plugin.HookPostPublish,
+			Config: map[string]any{
+				"working_dir": dir,
+				"formats":     []string{"deb", "rpm"},
+				"cache":       true,
+				"scripts":     map[string]any{"postinstall": "postinstall.sh.tmpl"},
+				"changelog":   true,
+			},
+			Context: plugin.ReleaseContext{Version: "1.2.3", ReleaseNotes: "- Fixed a crash"},
+		})
+		if err != nil || !resp.Success {
+			t.Fatalf("expected success, got %v, %+v", err, resp)
+		}
+		var flags []bool
+		for _, artifact := range resp.Outputs["artifacts"].([]map[string]any) {
+			flags = append(flags, artifact["cached"] == true)
+		}
+		return flags
+	}
+
+	if flags := build(); !reflect.DeepEqual(flags, []bool{false, false}) {
+		t.Fatalf("expected a fresh build, got cached=%v", flags)
+	}
+	if flags := build(); !reflect.DeepEqual(flags, []bool{true, true}) {
+		t.Errorf("expected both packages to be reused, got cached=%v", flags)
+	}
+
+	// Changing the script template invalidates every package.
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho myapp {{.Version}} installed\n"), 0644); err != nil {
+		t.Fatalf("failed to update script: %v", err)
+	}
+	if flags := build(); !reflect.DeepEqual(flags, []bool{false, false}) {
+		t.Errorf("expected a rebuild after a script change, got cached=%v", flags)
+	}
+}
+
+// TestExecuteBuildCacheOverridesAndFilename tests that a changed source of a per-format
+// override or a changed filename_template invalidates the cached packages.
+func TestExecuteBuildCacheOverridesAndFilename(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	overrides := "overrides:\n" +
+		"  rpm:\n" +
+		"    contents:\n" +
+		"      - src: extra.conf\n" +
+		"        dst: /etc/myapp/extra.conf\n"
+	writeTestNfpmConfig(t, dir, "arch: amd64\n"+overrides, map[string]string{"/usr/bin/myapp": "#!/bin/sh\necho hello\n"})
+	extra := filepath.Join(dir, "extra.conf")
+	if err := os.WriteFile(extra, []byte("key=value\n"), 0644); err != nil {
+		t.Fatalf("failed to write override source: %v", err)
+	}
+
+	build := func(filenameTemplate string) ([]bool, []string) {
+		t.Helper()
+		config := map[string]any{"working_dir": dir, "formats": []string{"deb", "rpm"}, "cache": true}
+		if filenameTemplate != "" {
+			config["filename_template"] = filenameTemplate
+		}
+		p := &LinuxPkgPlugin{}
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  config,
+			Context: plugin.ReleaseContext{Version: "1.2.3"},
+		})
+		if err != nil || !resp.Success {
+			t.Fatalf("expected success, got %v, %+v", err, resp)
+		}
+		var flags []bool
+		for _, artifact := range resp.Outputs["artifacts"].([]map[string]any) {
+			flags = append(flags, artifact["cached"] == true)
+		}
+		return flags, resp.Outputs["packages"].([]string)
+	}
+
+	if flags, _ := build(""); !reflect.DeepEqual(flags, []bool{false, false}) {
+		t.Fatalf("expected a fresh build, got cached=%v", flags)
+	}
+	if flags, _ := build(""); !reflect.DeepEqual(flags, []bool{true, true}) {
+		t.Fatalf("expected both packages to be reused, got cached=%v", flags)
+	}
+
+	// Changing a source only an override installs rebuilds the package it goes into.
+	if err := os.WriteFile(extra, []byte("key=changed\n"), 0644); err != nil {
+		t.Fatalf("failed to update override source: %v", err)
+	}
+	if flags, _ := build(""); flags[1] {
+		t.Errorf("expected the rpm to be rebuilt after an override source change, got cached=%v", flags)
+	}
+
+	// Changing the file name template rebuilds every package under its new name.
+	flags, packages := build("{name}-custom_{version}_{arch}.{ext}")
+	if !reflect.DeepEqual(flags, []bool{false, false}) {
+		t.Errorf("expected a rebuild after a filename_template change, got cached=%v", flags)
+	}
+	for _, pkg := range packages {
+		if !strings.HasPrefix(filepath.Base(pkg), "myapp-custom_") {
+			t.Errorf("expected %s to be named by the new template", pkg)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"os/exec"
+	"sort"
+)
+
+// capabilityHostTools are the host binaries probed when reporting capabilities.
+var capabilityHostTools = []string{"nfpm", "fpm", "dpkg-deb", "rpm", "rpmbuild", "rpmsign", "apk", "gpg", "cosign", "lintian", "rpmlint", "docker", "podman"}
+
+// Capabilities describes what this plugin build supports and which host tools were detected.
+type Capabilities struct {
+	// Formats are the package formats the plugin can build.
+	Formats []string `json:"formats"`
+	// Packagers are the accepted values for the packager option.
+	Packagers []string `json:"packagers"`
+	// Architectures are the accepted target architectures.
+	Architectures []string `json:"architectures"`
+	// Signers are the supported package signing mechanisms.
+	Signers []string `json:"signers"`
+	// PublishTargets are the supported publish destinations.
+	PublishTargets []string `json:"publish_targets"`
+	// HostTools reports which helper binaries were found on PATH.
+	HostTools map[string]bool `json:"host_tools"`
+}
+
+// getLookPath returns the binary lookup function, defaulting to exec.LookPath.
+func (p *LinuxPkgPlugin) getLookPath() func(string) (string, error) {
+	if p.lookPath != nil {
+		return p.lookPath
+	}
+	return exec.LookPath
+}
+
+// capabilities reports the formats, packagers, architectures, signers, and publish
+// targets supported by this build along with the host tools that are available.
+func (p *LinuxPkgPlugin) capabilities() Capabilities {
+	lookPath := p.getLookPath()
+	hostTools := make(map[string]bool, len(capabilityHostTools))
+	for _, tool := range capabilityHostTools {
+		_, err := lookPath(tool)
+		hostTools[tool] = err == nil
+	}
+
+	return Capabilities{
+		Formats:        sortedKeys(allowedFormats),
+		Packagers:      sortedKeys(allowedPackagers),
+		Architectures:  sortedKeys(allowedArchitectures),
+		Signers:        supportedSigners,
+		PublishTargets: supportedPublishTargets,
+		HostTools:      hostTools,
+	}
+}
+
+// withCapabilities adds an "x-capabilities" extension to a JSON schema document.
+// The schema is returned unchanged if it cannot be parsed.
+func withCapabilities(schema string, caps Capabilities) string {
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(schema), &doc); err != nil {
+		return schema
+	}
+
+	doc["x-capabilities"] = caps
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return schema
+	}
+	return string(data)
+}
+
+// sortedKeys returns the keys of a map in sorted order.
+func sortedKeys[V any](set map[string]V) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
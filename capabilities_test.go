@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestCapabilities tests capability reporting with a stubbed host.
+func TestCapabilities(t *testing.T) {
+	t.Parallel()
+
+	p := &LinuxPkgPlugin{
+		lookPath: func(file string) (string, error) {
+			if file == "nfpm" || file == "docker" {
+				return "/usr/bin/" + file, nil
+			}
+			return "", errors.New("not found")
+		},
+	}
+
+	caps := p.capabilities()
+
+	if !reflect.DeepEqual(caps.Formats, sortedKeys(allowedFormats)) {
+		t.Errorf("unexpected formats: %v", caps.Formats)
+	}
+	if !reflect.DeepEqual(caps.Packagers, []string{"container", "fpm", "native", "nfpm", "nfpm-cli"}) {
+		t.Errorf("unexpected packagers: %v", caps.Packagers)
+	}
+	if len(caps.Architectures) != len(allowedArchitectures) {
+		t.Errorf("expected %d architectures, got %v", len(allowedArchitectures), caps.Architectures)
+	}
+	if !caps.HostTools["nfpm"] || !caps.HostTools["docker"] {
+		t.Errorf("expected nfpm and docker to be detected, got %v", caps.HostTools)
+	}
+	if caps.HostTools["rpm"] {
+		t.Errorf("expected rpm to be missing, got %v", caps.HostTools)
+	}
+}
+
+// TestGetInfoReportsCapabilities tests that GetInfo embeds capabilities in the config schema.
+func TestGetInfoReportsCapabilities(t *testing.T) {
+	t.Parallel()
+
+	p := &LinuxPkgPlugin{
+		lookPath: func(file string) (string, error) {
+			return "", errors.New("not found")
+		},
+	}
+
+	var schema struct {
+		Type         string         `json:"type"`
+		Properties   map[string]any `json:"properties"`
+		Capabilities Capabilities   `json:"x-capabilities"`
+	}
+	if err := json.Unmarshal([]byte(p.GetInfo().ConfigSchema), &schema); err != nil {
+		t.Fatalf("config schema is not valid JSON: %v", err)
+	}
+
+	if schema.Type != "object" || schema.Properties["formats"] == nil {
+		t.Errorf("expected schema properties to be preserved, got %+v", schema)
+	}
+	if len(schema.Capabilities.Formats) == 0 {
+		t.Error("expected capabilities to list formats")
+	}
+	if schema.Capabilities.HostTools["nfpm"] {
+		t.Error("expected nfpm to be reported as missing")
+	}
+}
+
+// TestWithCapabilitiesInvalidSchema tests that an unparsable schema is returned unchanged.
+func TestWithCapabilitiesInvalidSchema(t *testing.T) {
+	t.Parallel()
+
+	if got := withCapabilities("{not json", Capabilities{}); got != "{not json" {
+		t.Errorf("expected schema to be unchanged, got %q", got)
+	}
+}
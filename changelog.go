@@ -0,0 +1,275 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/goreleaser/chglog"
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// defaultChangelogDistribution is the Debian changelog distribution used outside a
+// distribution build.
+const defaultChangelogDistribution = "unstable"
+
+// changelogUrgencies are the urgencies Debian policy allows.
+var changelogUrgencies = map[string]bool{
+	"low":       true,
+	"medium":    true,
+	"high":      true,
+	"emergency": true,
+	"critical":  true,
+}
+
+// changelogMaintainerPattern matches a "Full Name <email>" maintainer.
+var changelogMaintainerPattern = regexp.MustCompile(`^[^<>]+ <[^<>@\s]+@[^<>\s]+>$`)
+
+// changelogDistributionPattern matches a Debian distribution name such as "jammy".
+var changelogDistributionPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.+-]*$`)
+
+// changelogBulletPattern matches a Markdown list item in release notes.
+var changelogBulletPattern = regexp.MustCompile(`^\s*[-*+]\s+(.+)$`)
+
+// ChangelogConfig generates deb and rpm changelogs from the release.
+type ChangelogConfig struct {
+	// Maintainer signs the entry, as "Full Name <email>". Defaults to the nfpm maintainer.
+	Maintainer string
+	// Distribution is the Debian distribution of deb entries. Defaults to the distribution
+	// tag when building per distribution, otherwise "unstable".
+	Distribution string
+	// Urgency is the Debian upload urgency of deb entries.
+	Urgency string
+}
+
+// parseChangelog parses the changelog option, either true or an object. It returns nil
+// when no changelog is generated.
+func parseChangelog(raw map[string]any) *ChangelogConfig {
+	if enabled, ok := raw["changelog"].(bool); ok {
+		if !enabled {
+			return nil
+		}
+		return &ChangelogConfig{Urgency: "medium"}
+	}
+	block := helpers.NewConfigParser(raw).GetMap("changelog")
+	if block == nil {
+		return nil
+	}
+
+	parser := helpers.NewConfigParser(block)
+	return &ChangelogConfig{
+		Maintainer:   parser.GetString("maintainer", "", ""),
+		Distribution: parser.GetString("distribution", "", ""),
+		Urgency:      parser.GetString("urgency", "", "medium"),
+	}
+}
+
+// validateChangelog checks the changelog option.
+func validateChangelog(raw map[string]any) error {
+	value, ok := raw["changelog"]
+	if !ok || value == nil {
+		return nil
+	}
+	switch value.(type) {
+	case bool, map[string]any:
+	default:
+		return fmt.Errorf("changelog must be a boolean or an object")
+	}
+
+	if changelog := parseChangelog(raw); changelog != nil {
+		return changelog.validate()
+	}
+	return nil
+}
+
+// validate checks the changelog settings.
+func (c *ChangelogConfig) validate() error {
+	if c.Maintainer != "" && !changelogMaintainerPattern.MatchString(c.Maintainer) {
+		return fmt.Errorf("invalid maintainer %q: use \"Full Name <email>\"", c.Maintainer)
+	}
+	if c.Distribution != "" && !changelogDistributionPattern.MatchString(c.Distribution) {
+		return fmt.Errorf("invalid distribution %q", c.Distribution)
+	}
+	if !changelogUrgencies[c.Urgency] {
+		return fmt.Errorf("invalid urgency %q (allowed: %s)", c.Urgency, strings.Join(sortedKeys(changelogUrgencies), ", "))
+	}
+	return nil
+}
+
+// changelogNotes returns the entries of the release's changelog: the breaking changes,
+// features, fixes, and performance improvements of the release, or else the list items of
+// its release notes or changelog. A release without either gets a single note.
+func changelogNotes(release plugin.ReleaseContext) []string {
+	var notes []string
+	if changes := release.Changes; changes != nil {
+		seen := make(map[string]bool)
+		groups := [][]plugin.ConventionalCommit{changes.Breaking, changes.Features, changes.Fixes, changes.Performance}
+		for _, group := range groups {
+			for _, commit := range group {
+				if commit.Description == "" || (commit.Hash != "" && seen[commit.Hash]) {
+					continue
+				}
+				seen[commit.Hash] = true
+				note := commit.Description
+				if commit.Scope != "" {
+					note = commit.Scope + ": " + note
+				}
+				if commit.Breaking {
+					note = "BREAKING: " + note
+				}
+				notes = append(notes, note)
+			}
+		}
+	}
+
+	for _, text := range []string{release.ReleaseNotes, release.Changelog} {
+		if len(notes) > 0 {
+			break
+		}
+		for _, line := range strings.Split(text, "\n") {
+			if m := changelogBulletPattern.FindStringSubmatch(line); m != nil {
+				notes = append(notes, strings.TrimSpace(m[1]))
+			}
+		}
+	}
+
+	if len(notes) == 0 {
+		notes = []string{"Release " + release.Version}
+	}
+	return notes
+}
+
+// nfpmVersion holds the parts of the version nfpm gives a package.
+type nfpmVersion struct {
+	Epoch, Version, Prerelease, Metadata, Release string
+}
+
+// resolvePackageVersion returns the version parts of the package in doc, resolving
+// environment references with getenv and splitting semantic versions as nfpm does.
+func resolvePackageVersion(doc map[string]any, getenv func(string) string) nfpmVersion {
+	field := func(key string) string {
+		if value, ok := doc[key]; ok && value != nil {
+			return os.Expand(fmt.Sprint(value), getenv)
+		}
+		return ""
+	}
+
+	v := nfpmVersion{
+		Epoch:      field("epoch"),
+		Version:    field("version"),
+		Prerelease: field("prerelease"),
+		Metadata:   field("version_metadata"),
+		Release:    field("release"),
+	}
+	if field("version_schema") != "none" {
+		if sv, err := semver.NewVersion(v.Version); err == nil {
+			v.Version = fmt.Sprintf("%d.%d.%d", sv.Major(), sv.Minor(), sv.Patch())
+			if sv.Prerelease() != "" {
+				v.Prerelease = sv.Prerelease()
+			}
+			if sv.Metadata() != "" {
+				v.Metadata = sv.Metadata()
+			}
+		}
+	}
+	return v
+}
+
+// debianVersion returns the full Debian version nfpm gives the package in doc:
+// [epoch:]version[~prerelease][+metadata][-release].
+func debianVersion(doc map[string]any, getenv func(string) string) string {
+	v := resolvePackageVersion(doc, getenv)
+	version := v.Version
+	if v.Epoch != "" {
+		version = v.Epoch + ":" + version
+	}
+	if v.Prerelease != "" {
+		version += "~" + v.Prerelease
+	}
+	if v.Metadata != "" {
+		version += "+" + v.Metadata
+	}
+	if v.Release != "" {
+		version += "-" + v.Release
+	}
+	return version
+}
+
+// rpmChangelogVersion returns the version of the package in doc for an rpm changelog
+// entry. nfpm reads it as a semantic version and prints version-prerelease, so the
+// release is folded into the prerelease: "1.2.3-1", or "1.2.0-rc.1-1" for the rpm
+// version 1.2.0~rc.1.
+func rpmChangelogVersion(doc map[string]any, getenv func(string) string) (string, error) {
+	v := resolvePackageVersion(doc, getenv)
+	// Versions already normalized for rpm carry the prerelease after '~'. Build metadata
+	// is not shown, so it is dropped.
+	version, pre, _ := strings.Cut(v.Version, "~")
+	if v.Prerelease != "" {
+		pre = v.Prerelease
+	}
+	pre, _, _ = strings.Cut(pre, "+")
+
+	var suffix []string
+	for _, part := range []string{pre, v.Release} {
+		if part != "" {
+			suffix = append(suffix, strings.ReplaceAll(part, "_", "-"))
+		}
+	}
+	if len(suffix) > 0 {
+		version += "-" + strings.Join(suffix, "-")
+	}
+	// Loosely parsed versions would be printed differently, e.g. 2024.05 as 2024.5.0.
+	if _, err := semver.StrictNewVersion(version); err != nil {
+		return "", fmt.Errorf("cannot use version %q in an rpm changelog: %w", version, err)
+	}
+	return version, nil
+}
+
+// writeChangelog writes a chglog changelog with a single entry for the release into dir
+// and points doc's changelog at it. nfpm installs it as
+// /usr/share/doc/<name>/changelog.Debian.gz in deb packages and as the %changelog of rpm
+// packages. distribution is the Debian distribution used when cfg does not set one.
+func writeChangelog(doc map[string]any, format string, cfg *ChangelogConfig, distribution string, data *nfpmTemplateData, getenv func(string) string, dir string) error {
+	maintainer := cfg.Maintainer
+	if maintainer == "" {
+		maintainer, _ = doc["maintainer"].(string)
+		maintainer = os.Expand(maintainer, getenv)
+	}
+	if maintainer == "" {
+		return fmt.Errorf("changelog needs a maintainer: set maintainer in the nfpm config or changelog.maintainer")
+	}
+	date, err := time.Parse(time.RFC3339, data.Date)
+	if err != nil {
+		return fmt.Errorf("invalid changelog date %q: %w", data.Date, err)
+	}
+
+	entry := &chglog.ChangeLog{Date: date, Packager: maintainer}
+	switch format {
+	case "rpm":
+		if entry.Semver, err = rpmChangelogVersion(doc, getenv); err != nil {
+			return err
+		}
+	default:
+		if cfg.Distribution != "" {
+			distribution = cfg.Distribution
+		}
+		entry.Deb = &chglog.ChangelogDeb{Urgency: cfg.Urgency, Distributions: []string{distribution}}
+		entry.Semver = debianVersion(doc, getenv)
+	}
+	for _, note := range changelogNotes(data.ReleaseContext) {
+		entry.Changes = append(entry.Changes, &chglog.ChangeLogChange{Commit: data.CommitSHA, Note: note})
+	}
+
+	path := filepath.Join(dir, "changelog.yml")
+	entries := chglog.ChangeLogEntries{entry}
+	if err := entries.Save(path); err != nil {
+		return fmt.Errorf("failed to write changelog: %w", err)
+	}
+	doc["changelog"] = path
+	return nil
+}
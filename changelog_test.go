@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goreleaser/chglog"
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+	"gopkg.in/yaml.v3"
+)
+
+// TestValidateChangelog tests changelog validation.
+func TestValidateChangelog(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		changelog   any
+		expectError string
+	}{
+		{"enabled", true, ""},
+		{"disabled", false, ""},
+		{"object", map[string]any{"maintainer": "Relicta Team <team@example.com>", "distribution": "jammy", "urgency": "high"}, ""},
+		{"bad maintainer", map[string]any{"maintainer": "team@example.com"}, "invalid maintainer"},
+		{"bad distribution", map[string]any{"distribution": "Jammy Jellyfish"}, "invalid distribution"},
+		{"bad urgency", map[string]any{"urgency": "urgent"}, "invalid urgency"},
+		{"not an object", "yes", "must be a boolean or an object"},
+	}
+
+	p := &LinuxPkgPlugin{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := p.Validate(context.Background(), map[string]any{"changelog": tt.changelog})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectError == "" {
+				if !resp.Valid {
+					t.Errorf("expected valid config, got errors: %v", resp.Errors)
+				}
+				return
+			}
+			if resp.Valid || len(resp.Errors) == 0 || resp.Errors[0].Field != "changelog" ||
+				!strings.Contains(resp.Errors[0].Message, tt.expectError) {
+				t.Errorf("expected changelog error containing %q, got %v", tt.expectError, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestChangelogNotes tests deriving changelog entries from the release.
+func TestChangelogNotes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		release  plugin.ReleaseContext
+		expected []string
+	}{
+		{
+			name: "categorized changes",
+			release: plugin.ReleaseContext{
+				Changes: &plugin.CategorizedChanges{
+					Breaking: []plugin.ConventionalCommit{{Hash: "a1", Description: "drop v1 API", Breaking: true}},
+					Features: []plugin.ConventionalCommit{{Hash: "a1", Description: "drop v1 API", Breaking: true}, {Hash: "b2", Scope: "cli", Description: "add --json"}},
+					Fixes:    []plugin.ConventionalCommit{{Hash: "c3", Description: "handle empty input"}},
+					Docs:     []plugin.ConventionalCommit{{Hash: "d4", Description: "fix typo"}},
+				},
+				ReleaseNotes: "- ignored",
+			},
+			expected: []string{"BREAKING: drop v1 API", "cli: add --json", "handle empty input"},
+		},
+		{
+			name:     "release notes",
+			release:  plugin.ReleaseContext{ReleaseNotes: "## Features\n\n- add --json\n* handle empty input\n\nThanks!", Changelog: "- ignored"},
+			expected: []string{"add --json", "handle empty input"},
+		},
+		{
+			name:     "changelog",
+			release:  plugin.ReleaseContext{ReleaseNotes: "A quiet release.", Changelog: "## 1.2.3\n- handle empty input"},
+			expected: []string{"handle empty input"},
+		},
+		{
+			name:     "nothing",
+			release:  plugin.ReleaseContext{Version: "1.2.3"},
+			expected: []string{"Release 1.2.3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := changelogNotes(tt.release); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestDebianVersion tests the full version written into the changelog.
+func TestDebianVersion(t *testing.T) {
+	t.Parallel()
+
+	getenv := func(key string) string {
+		if key == "VERSION" {
+			return "1.2.3"
+		}
+		return ""
+	}
+	tests := []struct {
+		doc      map[string]any
+		expected string
+	}{
+		{map[string]any{"version": "1.2.3"}, "1.2.3"},
+		{map[string]any{"version": "${VERSION}", "release": "1"}, "1.2.3-1"},
+		{map[string]any{"version": "v1.2.3-rc.1", "release": "2", "epoch": 1}, "1:1.2.3~rc.1-2"},
+		{map[string]any{"version": "1.2.3", "prerelease": "beta1", "version_metadata": "git"}, "1.2.3~beta1+git"},
+		{map[string]any{"version": "1.2.0~rc.1", "version_schema": "none", "release": "1+jammy"}, "1.2.0~rc.1-1+jammy"},
+	}
+
+	for _, tt := range tests {
+		if got := debianVersion(tt.doc, getenv); got != tt.expected {
+			t.Errorf("debianVersion(%v) = %q, expected %q", tt.doc, got, tt.expected)
+		}
+	}
+}
+
+// TestRPMChangelogVersion tests the version written into rpm changelog entries.
+func TestRPMChangelogVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		doc         map[string]any
+		expected    string
+		expectError bool
+	}{
+		{map[string]any{"version": "1.2.3"}, "1.2.3", false},
+		{map[string]any{"version": "1.2.3", "release": "1.el8", "epoch": 2}, "1.2.3-1.el8", false},
+		{map[string]any{"version": "1.2.0-rc.1", "release": "1"}, "1.2.0-rc.1-1", false},
+		{map[string]any{"version": "1.2.0~rc.1+build_5", "version_schema": "none", "release": "1"}, "1.2.0-rc.1-1", false},
+		{map[string]any{"version": "1.2.0~rc_1", "version_schema": "none", "release": "1"}, "1.2.0-rc-1-1", false},
+		{map[string]any{"version": "2024.05", "version_schema": "none"}, "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := rpmChangelogVersion(tt.doc, os.Getenv)
+		if tt.expectError {
+			if err == nil {
+				t.Errorf("rpmChangelogVersion(%v) = %q, expected an error", tt.doc, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.expected {
+			t.Errorf("rpmChangelogVersion(%v) = %q, %v, expected %q", tt.doc, got, err, tt.expected)
+		}
+	}
+}
+
+// TestWriteChangelog tests the deb and rpm changelogs nfpm renders from the generated file.
+func TestWriteChangelog(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	doc := map[string]any{"name": "myapp", "version": "1.2.3", "release": "1", "maintainer": "Relicta Team <team@example.com>"}
+	data := newNfpmTemplateData(plugin.ReleaseContext{
+		Version:      "1.2.3",
+		CommitSHA:    "0123456789abcdef",
+		ReleaseNotes: "- add --json\n- handle empty input",
+	}, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+
+	cfg := &ChangelogConfig{Urgency: "medium"}
+	if err := writeChangelog(doc, "deb", cfg, "unstable", data, os.Getenv, dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries, err := chglog.Parse(doc["changelog"].(string))
+	if err != nil {
+		t.Fatalf("failed to parse changelog: %v", err)
+	}
+	tpl, err := chglog.DebTemplate()
+	if err != nil {
+		t.Fatalf("failed to load template: %v", err)
+	}
+	formatted, err := chglog.FormatChangelog(&chglog.PackageChangeLog{Name: "myapp", Entries: entries}, tpl)
+	if err != nil {
+		t.Fatalf("failed to format changelog: %v", err)
+	}
+
+	expected := "myapp (1.2.3-1) unstable; urgency=medium\n" +
+		"  * add --json\n" +
+		"  * handle empty input\n" +
+		"\n" +
+		" -- Relicta Team <team@example.com>  Wed, 01 May 2024 10:00:00 +0000"
+	if got := strings.TrimSpace(formatted); got != expected {
+		t.Errorf("expected changelog:\n%s\ngot:\n%s", expected, got)
+	}
+
+	rpmDoc := map[string]any{"name": "myapp", "version": "1.2.3", "release": "1", "maintainer": "Relicta Team <team@example.com>"}
+	if err := writeChangelog(rpmDoc, "rpm", cfg, "unstable", data, os.Getenv, t.TempDir()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entries, err = chglog.Parse(rpmDoc["changelog"].(string))
+	if err != nil {
+		t.Fatalf("failed to parse changelog: %v", err)
+	}
+	if tpl, err = chglog.RPMTemplate(); err != nil {
+		t.Fatalf("failed to load template: %v", err)
+	}
+	if formatted, err = chglog.FormatChangelog(&chglog.PackageChangeLog{Name: "myapp", Entries: entries}, tpl); err != nil {
+		t.Fatalf("failed to format changelog: %v", err)
+	}
+	expected = "* Wed May 1 2024 Relicta Team <team@example.com> - 1.2.3-1\n" +
+		"  - add --json\n" +
+		"  - handle empty input"
+	if got := strings.TrimSpace(formatted); got != expected {
+		t.Errorf("expected rpm changelog:\n%s\ngot:\n%s", expected, got)
+	}
+
+	// A maintainer is required.
+	delete(doc, "maintainer")
+	delete(doc, "changelog")
+	if err := writeChangelog(doc, "deb", cfg, "unstable", data, os.Getenv, dir); err == nil || !strings.Contains(err.Error(), "needs a maintainer") {
+		t.Errorf("expected a maintainer error, got %v", err)
+	}
+}
+
+// TestExecuteChangelog tests that deb and rpm packages get a changelog.
+func TestExecuteChangelog(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	config := "name: myapp\nversion: 1.2.3\nmaintainer: Relicta Team <team@example.com>\n"
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	changelogs := make(map[string]string)
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			content, err := os.ReadFile(args[2])
+			if err != nil {
+				return nil, err
+			}
+			var doc map[string]any
+			if err := yaml.Unmarshal(content, &doc); err != nil {
+				return nil, err
+			}
+			if path, ok := doc["changelog"].(string); ok {
+				entries, err := chglog.Parse(path)
+				if err != nil {
+					return nil, err
+				}
+				entry := entries[0]
+				changelogs[args[4]] = entry.Semver + " " + entry.Changes[0].Note
+				if entry.Deb != nil {
+					changelogs[args[4]] += " " + entry.Deb.Distributions[0]
+				}
+			}
+			return []byte("created package: " + args[len(args)-1] + "myapp." + args[4]), nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb", "rpm"},
+			"packager":    "nfpm-cli",
+			"changelog":   map[string]any{"distribution": "stable"},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3", ReleaseNotes: "- handle empty input"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	expected := map[string]string{"deb": "1.2.3 handle empty input stable", "rpm": "1.2.3 handle empty input"}
+	if !reflect.DeepEqual(changelogs, expected) {
+		t.Errorf("expected changelogs %v, got %v", expected, changelogs)
+	}
+}
+
+// TestExecuteChangelogEmbedded tests building packages with changelogs with the embedded library.
+func TestExecuteChangelogEmbedded(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"working_dir": dir, "formats": []string{"deb", "rpm"}, "changelog": true},
+		Context: plugin.ReleaseContext{Version: "1.2.3", CommitSHA: "0123456789abcdef", ReleaseNotes: "- handle empty input"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+	if packages := resp.Outputs["packages"].([]string); len(packages) != 2 {
+		t.Errorf("expected 2 packages, got %v", packages)
+	}
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// lintianSeverities ranks the lintian severities a threshold can be set to.
+var lintianSeverities = map[string]int{
+	"pedantic": 1,
+	"info":     2,
+	"warning":  3,
+	"error":    4,
+}
+
+// lintianCodes maps lintian's one-letter tag codes to severities. Experimental, overridden,
+// masked, and classification tags are not findings.
+var lintianCodes = map[string]string{
+	"E": "error",
+	"W": "warning",
+	"I": "info",
+	"P": "pedantic",
+}
+
+// lintianTagPattern matches lintian tag lines such as
+// "E: myapp: binary-without-manpage usr/bin/myapp".
+var lintianTagPattern = regexp.MustCompile(`^([A-Z]): ([^:\s]+)(?: [a-z]+)?: (\S+)\s*(.*)$`)
+
+// rpmlintSeverities ranks the rpmlint findings a fail_on threshold can be set to.
+var rpmlintSeverities = map[string]int{
+	"warning": 1,
+	"error":   2,
+}
+
+// rpmlintCodes maps rpmlint's one-letter codes to severities. Info lines are not findings.
+var rpmlintCodes = map[string]string{
+	"E": "error",
+	"W": "warning",
+}
+
+// rpmlintFindingPattern matches rpmlint finding lines such as
+// "myapp.x86_64: E: statically-linked-binary /usr/bin/myapp".
+var rpmlintFindingPattern = regexp.MustCompile(`^(\S+): ([A-Z]): (\S+)\s*(.*)$`)
+
+// rpmlintSummaryPattern matches the summary rpmlint prints after a completed run.
+var rpmlintSummaryPattern = regexp.MustCompile(`(?m)^\d+ packages? and \d+ specfiles? checked`)
+
+// Allowed actions when a check finds violations.
+var allowedCheckActions = map[string]bool{
+	"fail": true,
+	"warn": true,
+}
+
+// ChecksConfig configures the policy checks run on built packages.
+type ChecksConfig struct {
+	// Lintian runs lintian on every deb package.
+	Lintian *LintianConfig
+	// Rpmlint runs rpmlint on every rpm package.
+	Rpmlint *RpmlintConfig
+	// Install installs every package in containers of the distributions using its format.
+	Install *InstallCheckConfig
+	// Metadata reads every package's name, version, and architecture back and checks them
+	// against its nfpm config.
+	Metadata bool
+	// Version checks that every package carries the release version.
+	Version bool
+}
+
+// LintianConfig configures the lintian check.
+type LintianConfig struct {
+	// Severity is the lowest severity reported: error, warning, info, or pedantic.
+	Severity string
+	// Ignore lists lintian tags that are never reported.
+	Ignore []string
+	// OnViolation is what reported tags do: fail the hook or only warn.
+	OnViolation string
+}
+
+// RpmlintConfig configures the rpmlint check.
+type RpmlintConfig struct {
+	// Rpmlintrc is an rpmlintrc file with filters and settings passed to rpmlint.
+	Rpmlintrc string
+	// FailOn is the lowest severity, error or warning, that fails the hook. Empty only
+	// reports findings.
+	FailOn string
+}
+
+// parseChecks parses the checks block. It returns nil when no check is configured.
+func parseChecks(raw map[string]any) *ChecksConfig {
+	block := helpers.NewConfigParser(raw).GetMap("checks")
+	if block == nil {
+		return nil
+	}
+
+	checks := &ChecksConfig{
+		Lintian:  parseLintian(block),
+		Rpmlint:  parseRpmlint(block),
+		Install:  parseInstallCheck(block),
+		Metadata: helpers.NewConfigParser(block).GetBool("metadata", false),
+		Version:  helpers.NewConfigParser(block).GetBool("version", false),
+	}
+	if checks.Lintian == nil && checks.Rpmlint == nil && checks.Install == nil && !checks.Metadata && !checks.Version {
+		return nil
+	}
+	return checks
+}
+
+// parseLintian parses the lintian check, given as true or an object.
+func parseLintian(raw map[string]any) *LintianConfig {
+	if enabled, ok := raw["lintian"].(bool); ok {
+		if !enabled {
+			return nil
+		}
+		return &LintianConfig{Severity: "error", OnViolation: "fail"}
+	}
+	block := helpers.NewConfigParser(raw).GetMap("lintian")
+	if block == nil {
+		return nil
+	}
+
+	parser := helpers.NewConfigParser(block)
+	return &LintianConfig{
+		Severity:    parser.GetString("severity", "", "error"),
+		Ignore:      parser.GetStringSlice("ignore", nil),
+		OnViolation: parser.GetString("on_violation", "", "fail"),
+	}
+}
+
+// parseRpmlint parses the rpmlint check, given as true or an object.
+func parseRpmlint(raw map[string]any) *RpmlintConfig {
+	if enabled, ok := raw["rpmlint"].(bool); ok {
+		if !enabled {
+			return nil
+		}
+		return &RpmlintConfig{}
+	}
+	block := helpers.NewConfigParser(raw).GetMap("rpmlint")
+	if block == nil {
+		return nil
+	}
+
+	parser := helpers.NewConfigParser(block)
+	return &RpmlintConfig{
+		Rpmlintrc: parser.GetString("rpmlintrc", "", ""),
+		FailOn:    parser.GetString("fail_on", "", ""),
+	}
+}
+
+// validateChecks checks the checks option.
+func validateChecks(raw map[string]any) error {
+	value, ok := raw["checks"]
+	if !ok || value == nil {
+		return nil
+	}
+	block, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("checks must be an object")
+	}
+	for _, name := range []string{"lintian", "rpmlint", "install"} {
+		switch block[name].(type) {
+		case nil, bool, map[string]any:
+		default:
+			return fmt.Errorf("%s must be a boolean or an object", name)
+		}
+	}
+	for _, name := range []string{"metadata", "version"} {
+		if _, ok := block[name].(bool); !ok && block[name] != nil {
+			return fmt.Errorf("%s must be a boolean", name)
+		}
+	}
+
+	if checks := parseChecks(raw); checks != nil {
+		return checks.validate()
+	}
+	return nil
+}
+
+// validate checks the settings of each configured check.
+func (c *ChecksConfig) validate() error {
+	if c.Lintian != nil {
+		if err := c.Lintian.validate(); err != nil {
+			return fmt.Errorf("lintian: %w", err)
+		}
+	}
+	if c.Rpmlint != nil {
+		if err := c.Rpmlint.validate(); err != nil {
+			return fmt.Errorf("rpmlint: %w", err)
+		}
+	}
+	if c.Install != nil {
+		if err := c.Install.validate(); err != nil {
+			return fmt.Errorf("install: %w", err)
+		}
+	}
+	return nil
+}
+
+// validate checks the lintian settings.
+func (l *LintianConfig) validate() error {
+	if _, ok := lintianSeverities[l.Severity]; !ok {
+		return fmt.Errorf("invalid severity %q (allowed: error, warning, info, pedantic)", l.Severity)
+	}
+	if !allowedCheckActions[l.OnViolation] {
+		return fmt.Errorf("invalid on_violation %q (allowed: fail, warn)", l.OnViolation)
+	}
+	for _, tag := range l.Ignore {
+		if tag == "" || strings.ContainsAny(tag, ", \t\n") {
+			return fmt.Errorf("invalid ignored tag %q", tag)
+		}
+	}
+	return nil
+}
+
+// lintianArgs returns the lintian arguments for checking path.
+func (l *LintianConfig) lintianArgs(path string) []string {
+	args := []string{"--no-tag-display-limit"}
+	if lintianSeverities[l.Severity] <= lintianSeverities["info"] {
+		args = append(args, "--display-info")
+	}
+	if l.Severity == "pedantic" {
+		args = append(args, "--pedantic")
+	}
+	if len(l.Ignore) > 0 {
+		args = append(args, "--suppress-tags", strings.Join(l.Ignore, ","))
+	}
+	return append(args, path)
+}
+
+// parseLintianOutput returns the tags in lintian output at or above the configured
+// severity that are not ignored, and whether the output held any tag lines.
+func (l *LintianConfig) parseLintianOutput(output []byte, path string) ([]map[string]any, bool) {
+	threshold := lintianSeverities[l.Severity]
+	var findings []map[string]any
+	tagged := false
+	for _, line := range strings.Split(string(output), "\n") {
+		m := lintianTagPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		tagged = true
+		severity, ok := lintianCodes[m[1]]
+		if !ok || lintianSeverities[severity] < threshold || slices.Contains(l.Ignore, m[3]) {
+			continue
+		}
+		finding := map[string]any{
+			"package":  path,
+			"severity": severity,
+			"tag":      m[3],
+		}
+		if m[4] != "" {
+			finding["detail"] = m[4]
+		}
+		findings = append(findings, finding)
+	}
+	return findings, tagged
+}
+
+// runLintian runs lintian on a deb package and returns the reported tags.
+func (p *LinuxPkgPlugin) runLintian(ctx context.Context, executor CommandExecutor, l *LintianConfig, path string) ([]map[string]any, error) {
+	out, err := runCommand(ctx, executor, "lintian", l.lintianArgs(path)...)
+	findings, tagged := l.parseLintianOutput(out, path)
+	// lintian exits non-zero when it reports errors; only a run without any tags failed.
+	if err != nil && !tagged {
+		return nil, fmt.Errorf("lintian failed on %s: %w: %s", path, err, strings.TrimSpace(string(out)))
+	}
+	return findings, nil
+}
+
+// validate checks the rpmlint settings. The rpmlintrc file is checked for existence when
+// building.
+func (r *RpmlintConfig) validate() error {
+	if r.FailOn != "" {
+		if _, ok := rpmlintSeverities[r.FailOn]; !ok {
+			return fmt.Errorf("invalid fail_on %q (allowed: error, warning)", r.FailOn)
+		}
+	}
+	if err := validatePath(r.Rpmlintrc); err != nil {
+		return fmt.Errorf("invalid rpmlintrc: %w", err)
+	}
+	return nil
+}
+
+// rpmlintArgs returns the rpmlint arguments for checking path.
+func (r *RpmlintConfig) rpmlintArgs(path string) []string {
+	if r.Rpmlintrc != "" {
+		return []string{"--rpmlintrc", r.Rpmlintrc, path}
+	}
+	return []string{path}
+}
+
+// parseRpmlintOutput returns the errors and warnings in rpmlint output.
+func parseRpmlintOutput(output []byte, path string) []map[string]any {
+	var findings []map[string]any
+	for _, line := range strings.Split(string(output), "\n") {
+		m := rpmlintFindingPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		severity, ok := rpmlintCodes[m[2]]
+		if !ok {
+			continue
+		}
+		finding := map[string]any{
+			"package":  path,
+			"severity": severity,
+			"tag":      m[3],
+		}
+		if m[4] != "" {
+			finding["detail"] = m[4]
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// runRpmlint runs rpmlint on an rpm package and returns its errors and warnings.
+func (p *LinuxPkgPlugin) runRpmlint(ctx context.Context, executor CommandExecutor, r *RpmlintConfig, path string) ([]map[string]any, error) {
+	out, err := runCommand(ctx, executor, "rpmlint", r.rpmlintArgs(path)...)
+	findings := parseRpmlintOutput(out, path)
+	// rpmlint exits non-zero when it reports errors; only a run that did not finish failed.
+	if err != nil && len(findings) == 0 && !rpmlintSummaryPattern.Match(out) {
+		return nil, fmt.Errorf("rpmlint failed on %s: %w: %s", path, err, strings.TrimSpace(string(out)))
+	}
+	return findings, nil
+}
+
+// formatFinding renders a check finding for messages, e.g.
+// "dist/myapp.deb: error: binary-without-manpage usr/bin/myapp".
+func formatFinding(finding map[string]any) string {
+	line := fmt.Sprintf("%s: %s: %s", finding["package"], finding["severity"], finding["tag"])
+	if detail, ok := finding["detail"].(string); ok {
+		line += " " + detail
+	}
+	return line
+}
+
+// formatFindings renders check findings one per line, indented for messages.
+func formatFindings(findings []map[string]any) string {
+	lines := make([]string, len(findings))
+	for i, finding := range findings {
+		lines[i] = formatFinding(finding)
+	}
+	return strings.Join(lines, "\n  ")
+}
+
+// runChecks runs the configured checks on the built packages. It returns the findings by
+// check for the outputs and message suffixes reporting findings that do not fail the
+// hook. Findings of a check that fails on them are returned as an error.
+func (p *LinuxPkgPlugin) runChecks(ctx context.Context, executor CommandExecutor, checks *ChecksConfig, formats []string, artifacts []map[string]any) (map[string]any, []string, error) {
+	results := make(map[string]any)
+	var warnings []string
+	if checks == nil {
+		return results, nil, nil
+	}
+
+	if checks.Lintian != nil {
+		var findings []map[string]any
+		for _, artifact := range artifacts {
+			if artifact["format"] != "deb" {
+				continue
+			}
+			found, err := p.runLintian(ctx, executor, checks.Lintian, artifact["path"].(string))
+			if err != nil {
+				return results, nil, err
+			}
+			findings = append(findings, found...)
+		}
+		results["lintian"] = findings
+
+		if len(findings) > 0 {
+			if checks.Lintian.OnViolation == "fail" {
+				return results, nil, fmt.Errorf("lintian reported %d tag(s) at or above %s:\n  %s",
+					len(findings), checks.Lintian.Severity, formatFindings(findings))
+			}
+			warnings = append(warnings, fmt.Sprintf("%d lintian tag(s) at or above %s", len(findings), checks.Lintian.Severity))
+		}
+	}
+
+	if checks.Rpmlint != nil {
+		var findings []map[string]any
+		for _, artifact := range artifacts {
+			if artifact["format"] != "rpm" {
+				continue
+			}
+			found, err := p.runRpmlint(ctx, executor, checks.Rpmlint, artifact["path"].(string))
+			if err != nil {
+				return results, nil, err
+			}
+			findings = append(findings, found...)
+		}
+		results["rpmlint"] = findings
+
+		var failing []map[string]any
+		counts := make(map[string]int, len(rpmlintSeverities))
+		for _, finding := range findings {
+			severity := finding["severity"].(string)
+			counts[severity]++
+			if checks.Rpmlint.FailOn != "" && rpmlintSeverities[severity] >= rpmlintSeverities[checks.Rpmlint.FailOn] {
+				failing = append(failing, finding)
+			}
+		}
+		if len(failing) > 0 {
+			return results, nil, fmt.Errorf("rpmlint reported %d finding(s) at or above %s:\n  %s",
+				len(failing), checks.Rpmlint.FailOn, formatFindings(failing))
+		}
+		if len(findings) > 0 {
+			warnings = append(warnings, fmt.Sprintf("rpmlint reported %d error(s) and %d warning(s):\n  %s",
+				counts["error"], counts["warning"], formatFindings(findings)))
+		}
+	}
+
+	if checks.Install != nil {
+		installs, err := p.runInstallCheck(ctx, executor, checks.Install, formats, artifacts)
+		if err != nil {
+			return results, nil, err
+		}
+		results["install"] = installs
+
+		var failed []string
+		for _, install := range installs {
+			if install["success"] != true {
+				failed = append(failed, fmt.Sprintf("%s: %s: %s", install["image"], install["package"], install["error"]))
+			}
+		}
+		if len(failed) > 0 {
+			if checks.Install.OnFailure == "fail" {
+				return results, nil, fmt.Errorf("%d of %d package installation(s) failed:\n  %s",
+					len(failed), len(installs), strings.Join(failed, "\n  "))
+			}
+			warnings = append(warnings, fmt.Sprintf("%d of %d package installation(s) failed:\n  %s",
+				len(failed), len(installs), strings.Join(failed, "\n  ")))
+		}
+	}
+
+	return results, warnings, nil
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestValidateChecks tests checks validation.
+func TestValidateChecks(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		checks      any
+		expectError string
+	}{
+		{"lintian enabled", map[string]any{"lintian": true}, ""},
+		{"lintian disabled", map[string]any{"lintian": false}, ""},
+		{"lintian object", map[string]any{"lintian": map[string]any{"severity": "warning", "ignore": []any{"no-copyright-file"}, "on_violation": "warn"}}, ""},
+		{"bad severity", map[string]any{"lintian": map[string]any{"severity": "fatal"}}, "invalid severity"},
+		{"bad action", map[string]any{"lintian": map[string]any{"on_violation": "ignore"}}, "invalid on_violation"},
+		{"bad tag", map[string]any{"lintian": map[string]any{"ignore": []any{"a,b"}}}, "invalid ignored tag"},
+		{"lintian string", map[string]any{"lintian": "yes"}, "lintian must be a boolean or an object"},
+		{"rpmlint enabled", map[string]any{"rpmlint": true}, ""},
+		{"rpmlint object", map[string]any{"rpmlint": map[string]any{"rpmlintrc": ".rpmlintrc", "fail_on": "warning"}}, ""},
+		{"bad fail_on", map[string]any{"rpmlint": map[string]any{"fail_on": "info"}}, "invalid fail_on"},
+		{"rpmlintrc traversal", map[string]any{"rpmlint": map[string]any{"rpmlintrc": "../.rpmlintrc"}}, "invalid rpmlintrc"},
+		{"rpmlint list", map[string]any{"rpmlint": []any{"error"}}, "rpmlint must be a boolean or an object"},
+		{"install enabled", map[string]any{"install": true}, ""},
+		{"install object", map[string]any{"install": map[string]any{"runtime": "podman", "images": []any{"debian:bookworm", "registry.example.com/library/fedora:40", "alpine@sha256:abc"}, "on_failure": "warn"}}, ""},
+		{"bad runtime", map[string]any{"install": map[string]any{"runtime": "lxc"}}, "invalid runtime"},
+		{"unknown image", map[string]any{"install": map[string]any{"images": []any{"busybox:latest"}}}, "unsupported image"},
+		{"bad image", map[string]any{"install": map[string]any{"images": []any{"Debian; rm -rf /"}}}, "invalid image"},
+		{"metadata", map[string]any{"metadata": true}, ""},
+		{"metadata object", map[string]any{"metadata": map[string]any{}}, "metadata must be a boolean"},
+		{"not an object", true, "checks must be an object"},
+	}
+
+	p := &LinuxPkgPlugin{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := p.Validate(context.Background(), map[string]any{"checks": tt.checks})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectError == "" {
+				if !resp.Valid {
+					t.Errorf("expected valid config, got errors: %v", resp.Errors)
+				}
+				return
+			}
+			if resp.Valid || len(resp.Errors) == 0 || resp.Errors[0].Field != "checks" ||
+				!strings.Contains(resp.Errors[0].Message, tt.expectError) {
+				t.Errorf("expected checks error containing %q, got %v", tt.expectError, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestLintian tests lintian arguments and parsing its output.
+func TestLintian(t *testing.T) {
+	t.Parallel()
+
+	output := []byte(`E: myapp: binary-without-manpage usr/bin/myapp
+W: myapp: no-copyright-file
+I: myapp: description-synopsis-might-not-be-phrased-properly
+P: myapp: no-homepage-field
+X: myapp: experimental-tag
+O: myapp: overridden-tag
+N: 1 tag overridden
+`)
+
+	tests := []struct {
+		lintian *LintianConfig
+		args    []string
+		tags    []string
+	}{
+		{
+			&LintianConfig{Severity: "error"},
+			[]string{"--no-tag-display-limit", "myapp.deb"},
+			[]string{"error binary-without-manpage usr/bin/myapp"},
+		},
+		{
+			&LintianConfig{Severity: "warning", Ignore: []string{"binary-without-manpage"}},
+			[]string{"--no-tag-display-limit", "--suppress-tags", "binary-without-manpage", "myapp.deb"},
+			[]string{"warning no-copyright-file"},
+		},
+		{
+			&LintianConfig{Severity: "pedantic"},
+			[]string{"--no-tag-display-limit", "--display-info", "--pedantic", "myapp.deb"},
+			[]string{
+				"error binary-without-manpage usr/bin/myapp",
+				"warning no-copyright-file",
+				"info description-synopsis-might-not-be-phrased-properly",
+				"pedantic no-homepage-field",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		if args := tt.lintian.lintianArgs("myapp.deb"); !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%s: expected args %q, got %q", tt.lintian.Severity, tt.args, args)
+		}
+		findings, tagged := tt.lintian.parseLintianOutput(output, "myapp.deb")
+		var tags []string
+		for _, finding := range findings {
+			tag := finding["severity"].(string) + " " + finding["tag"].(string)
+			if detail, ok := finding["detail"].(string); ok {
+				tag += " " + detail
+			}
+			tags = append(tags, tag)
+		}
+		if !tagged || !reflect.DeepEqual(tags, tt.tags) {
+			t.Errorf("%s: expected tags %q, got %q", tt.lintian.Severity, tt.tags, tags)
+		}
+	}
+
+	if _, tagged := (&LintianConfig{Severity: "error"}).parseLintianOutput([]byte("lintian: cannot open myapp.deb\n"), "myapp.deb"); tagged {
+		t.Errorf("expected no tag lines in an error message")
+	}
+}
+
+// TestExecuteLintian tests running lintian on built deb packages.
+func TestExecuteLintian(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: myapp\nversion: 1.2.3\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	lintianOutput, lintianErr := "W: myapp: no-copyright-file\n", error(nil)
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if name == "lintian" {
+				return []byte(lintianOutput), lintianErr
+			}
+			return []byte("created package: " + args[len(args)-1] + "myapp." + args[4]), nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	execute := func(lintian any) *plugin.ExecuteResponse {
+		t.Helper()
+		mock.Calls = nil
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  map[string]any{"working_dir": dir, "formats": []string{"deb", "rpm"}, "packager": "nfpm-cli", "checks": map[string]any{"lintian": lintian}},
+			Context: plugin.ReleaseContext{Version: "1.2.3"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return resp
+	}
+	lintianCalls := func() []string {
+		var calls []string
+		for _, call := range mock.Calls {
+			if call.Name == "lintian" {
+				calls = append(calls, call.Args[len(call.Args)-1])
+			}
+		}
+		return calls
+	}
+
+	// Warnings are below the default error threshold.
+	resp := execute(true)
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+	if calls := lintianCalls(); !reflect.DeepEqual(calls, []string{filepath.Join(dir, "dist", "myapp.deb")}) {
+		t.Errorf("expected lintian to check only the deb package, got %v", calls)
+	}
+	if strings.Contains(resp.Message, "lintian") {
+		t.Errorf("expected no lintian warning in message, got %q", resp.Message)
+	}
+
+	// Warnings at the threshold fail the hook.
+	resp = execute(map[string]any{"severity": "warning"})
+	if resp.Success || !strings.Contains(resp.Error, "lintian reported 1 tag(s) at or above warning:\n  "+filepath.Join(dir, "dist", "myapp.deb")+": warning: no-copyright-file") {
+		t.Errorf("expected a lintian failure, got %+v", resp)
+	}
+
+	// With on_violation: warn, the build succeeds and reports the tags.
+	resp = execute(map[string]any{"severity": "warning", "on_violation": "warn"})
+	if !resp.Success || !strings.Contains(resp.Message, "1 lintian tag(s) at or above warning") {
+		t.Errorf("expected a lintian warning, got %+v", resp)
+	}
+	findings := resp.Outputs["checks"].(map[string]any)["lintian"].([]map[string]any)
+	if len(findings) != 1 || findings[0]["tag"] != "no-copyright-file" || findings[0]["package"] != filepath.Join(dir, "dist", "myapp.deb") {
+		t.Errorf("unexpected lintian findings: %v", findings)
+	}
+
+	// Ignored tags are not reported.
+	resp = execute(map[string]any{"severity": "warning", "ignore": []string{"no-copyright-file"}})
+	if !resp.Success {
+		t.Errorf("expected ignored tags to pass, got failure: %s", resp.Error)
+	}
+
+	// lintian failing without reporting tags fails the hook.
+	lintianOutput, lintianErr = "sh: lintian: not found\n", errors.New("exit status 127")
+	resp = execute(true)
+	if resp.Success || !strings.Contains(resp.Error, "lintian failed on "+filepath.Join(dir, "dist", "myapp.deb")) {
+		t.Errorf("expected a lintian run failure, got %+v", resp)
+	}
+}
+
+// TestParseRpmlintOutput tests parsing rpmlint findings.
+func TestParseRpmlintOutput(t *testing.T) {
+	t.Parallel()
+
+	output := []byte(`============================ rpmlint session starts ============================
+rpmlint: 2.5.0
+myapp.x86_64: E: statically-linked-binary /usr/bin/myapp
+myapp.x86_64: W: no-manual-page-for-binary myapp
+myapp.x86_64: I: some-info
+1 packages and 0 specfiles checked; 1 errors, 1 warnings, 0 badness; has taken 0.1 s
+`)
+	expected := []map[string]any{
+		{"package": "myapp.rpm", "severity": "error", "tag": "statically-linked-binary", "detail": "/usr/bin/myapp"},
+		{"package": "myapp.rpm", "severity": "warning", "tag": "no-manual-page-for-binary", "detail": "myapp"},
+	}
+	if findings := parseRpmlintOutput(output, "myapp.rpm"); !reflect.DeepEqual(findings, expected) {
+		t.Errorf("expected findings %v, got %v", expected, findings)
+	}
+	if !rpmlintSummaryPattern.Match(output) {
+		t.Errorf("expected the summary line to match")
+	}
+}
+
+// TestExecuteRpmlint tests running rpmlint on built rpm packages.
+func TestExecuteRpmlint(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: myapp\nversion: 1.2.3\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".rpmlintrc"), []byte("addFilter(\"no-documentation\")\n"), 0644); err != nil {
+		t.Fatalf("failed to write rpmlintrc: %v", err)
+	}
+
+	rpmlintOutput, rpmlintErr := "myapp.x86_64: W: no-manual-page-for-binary myapp\n1 packages and 0 specfiles checked; 0 errors, 1 warnings\n", error(nil)
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if name == "rpmlint" {
+				return []byte(rpmlintOutput), rpmlintErr
+			}
+			return []byte("created package: " + args[len(args)-1] + "myapp." + args[4]), nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	execute := func(rpmlint any) *plugin.ExecuteResponse {
+		t.Helper()
+		mock.Calls = nil
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  map[string]any{"working_dir": dir, "formats": []string{"deb", "rpm"}, "packager": "nfpm-cli", "checks": map[string]any{"rpmlint": rpmlint}},
+			Context: plugin.ReleaseContext{Version: "1.2.3"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return resp
+	}
+
+	// Without fail_on, findings are reported in the message.
+	resp := execute(map[string]any{"rpmlintrc": ".rpmlintrc"})
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+	var calls [][]string
+	for _, call := range mock.Calls {
+		if call.Name == "rpmlint" {
+			calls = append(calls, call.Args)
+		}
+	}
+	if expected := [][]string{{"--rpmlintrc", filepath.Join(dir, ".rpmlintrc"), filepath.Join(dir, "dist", "myapp.rpm")}}; !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected rpmlint calls %v, got %v", expected, calls)
+	}
+	if !strings.HasSuffix(resp.Message, "; rpmlint reported 0 error(s) and 1 warning(s):\n  "+filepath.Join(dir, "dist", "myapp.rpm")+": warning: no-manual-page-for-binary myapp") {
+		t.Errorf("expected rpmlint findings in message, got %q", resp.Message)
+	}
+	if findings := resp.Outputs["checks"].(map[string]any)["rpmlint"].([]map[string]any); len(findings) != 1 {
+		t.Errorf("expected 1 rpmlint finding, got %v", findings)
+	}
+
+	// Warnings stay below an error threshold but fail a warning threshold.
+	if resp = execute(map[string]any{"fail_on": "error"}); !resp.Success {
+		t.Errorf("expected warnings to pass fail_on: error, got failure: %s", resp.Error)
+	}
+	resp = execute(map[string]any{"fail_on": "warning"})
+	if resp.Success || !strings.Contains(resp.Error, "rpmlint reported 1 finding(s) at or above warning") {
+		t.Errorf("expected an rpmlint failure, got %+v", resp)
+	}
+
+	// rpmlint exits non-zero on errors, which are findings rather than a failed run.
+	rpmlintOutput, rpmlintErr = "myapp.x86_64: E: statically-linked-binary /usr/bin/myapp\n", errors.New("exit status 64")
+	if resp = execute(true); !resp.Success || !strings.Contains(resp.Message, "1 error(s)") {
+		t.Errorf("expected rpmlint errors to be reported, got %+v", resp)
+	}
+	rpmlintOutput = "rpmlint: error: unrecognized arguments\n"
+	if resp = execute(true); resp.Success || !strings.Contains(resp.Error, "rpmlint failed on "+filepath.Join(dir, "dist", "myapp.rpm")) {
+		t.Errorf("expected an rpmlint run failure, got %+v", resp)
+	}
+
+	// A missing rpmlintrc fails before packaging.
+	mock.Calls = nil
+	resp = execute(map[string]any{"rpmlintrc": "missing.rpmlintrc"})
+	if resp.Success || !strings.Contains(resp.Error, "invalid checks: rpmlint") || len(mock.Calls) != 0 {
+		t.Errorf("expected a missing rpmlintrc error before packaging, got %+v", resp)
+	}
+}
+
+// TestExecuteInstallCheck tests installing built packages in containers.
+func TestExecuteInstallCheck(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: myapp\nversion: 1.2.3\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if name == "podman" {
+				if slices.Contains(args, "fedora:40") {
+					return []byte("Error:\n Problem: nothing provides libfoo needed by myapp\n"), errors.New("exit status 1")
+				}
+				return []byte("Setting up myapp (1.2.3) ...\n"), nil
+			}
+			return []byte("created package: " + args[len(args)-1] + "myapp." + args[4]), nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock, lookPath: func(name string) (string, error) {
+		if name == "podman" {
+			return "/usr/bin/podman", nil
+		}
+		return "", errors.New("not found")
+	}}
+	execute := func(install any) *plugin.ExecuteResponse {
+		t.Helper()
+		mock.Calls = nil
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  map[string]any{"working_dir": dir, "formats": []string{"deb", "rpm"}, "packager": "nfpm-cli", "checks": map[string]any{"install": install}},
+			Context: plugin.ReleaseContext{Version: "1.2.3"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return resp
+	}
+
+	// Default images install each format with its package manager.
+	resp := execute(true)
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+	var images []string
+	for _, call := range mock.Calls {
+		if call.Name != "podman" {
+			continue
+		}
+		image := call.Args[len(call.Args)-4]
+		images = append(images, image)
+		if image == "debian:stable-slim" {
+			expected := []string{"run", "--rm", "-e", "DEBIAN_FRONTEND=noninteractive",
+				"-v", filepath.Join(dir, "dist", "myapp.deb") + ":/tmp/linuxpkg/myapp.deb:ro", "--platform", "linux/" + runtime.GOARCH,
+				"debian:stable-slim", "sh", "-c", "apt-get update -qq && apt-get install -y -qq '/tmp/linuxpkg/myapp.deb'"}
+			if !reflect.DeepEqual(call.Args, expected) {
+				t.Errorf("expected args %q, got %q", expected, call.Args)
+			}
+		}
+	}
+	if expected := []string{"debian:stable-slim", "ubuntu:latest", "fedora:latest"}; !reflect.DeepEqual(images, expected) {
+		t.Errorf("expected images %v, got %v", expected, images)
+	}
+	if installs := resp.Outputs["checks"].(map[string]any)["install"].([]map[string]any); len(installs) != 3 || installs[2]["package"] != filepath.Join(dir, "dist", "myapp.rpm") {
+		t.Errorf("unexpected install results: %v", installs)
+	}
+
+	// A failed installation fails the hook with the package manager's last line.
+	resp = execute(map[string]any{"images": []string{"ubuntu:24.04", "fedora:40"}})
+	if resp.Success || !strings.Contains(resp.Error, "1 of 2 package installation(s) failed:\n  fedora:40: "+filepath.Join(dir, "dist", "myapp.rpm")+": Problem: nothing provides libfoo needed by myapp") {
+		t.Errorf("expected an install failure, got %+v", resp)
+	}
+
+	// With on_failure: warn, the build succeeds and reports the failure.
+	resp = execute(map[string]any{"images": []string{"fedora:40"}, "on_failure": "warn"})
+	if !resp.Success || !strings.Contains(resp.Message, "1 of 1 package installation(s) failed") {
+		t.Errorf("expected an install warning, got %+v", resp)
+	}
+
+	// Without a container runtime the check cannot run.
+	p.lookPath = func(string) (string, error) { return "", errors.New("not found") }
+	if resp = execute(true); resp.Success || !strings.Contains(resp.Error, "needs docker or podman") {
+		t.Errorf("expected a missing runtime error, got %+v", resp)
+	}
+}
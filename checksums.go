@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// checksumAlgorithms maps supported checksum algorithms to their hash constructors.
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// validateChecksumAlgorithm validates that a checksum algorithm is supported.
+func validateChecksumAlgorithm(algorithm string) error {
+	if _, ok := checksumAlgorithms[algorithm]; !ok {
+		return fmt.Errorf("unsupported checksum algorithm: %s (allowed: %s)", algorithm, strings.Join(sortedKeys(checksumAlgorithms), ", "))
+	}
+	return nil
+}
+
+// checksumFileName returns the name of the checksum file for an algorithm (e.g. SHA256SUMS).
+func checksumFileName(algorithm string) string {
+	return strings.ToUpper(algorithm) + "SUMS"
+}
+
+// fileDigest returns the hex-encoded digest of a file.
+func fileDigest(path, algorithm string) (string, error) {
+	newHash, ok := checksumAlgorithms[algorithm]
+	if !ok {
+		return "", validateChecksumAlgorithm(algorithm)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	h := newHash()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// artifactDigest returns the sha256 digest and size of a built package. A package that
+// does not exist yields an empty digest and zero size.
+func artifactDigest(path string) (string, int64, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to stat artifact %s: %w", path, err)
+	}
+
+	digest, err := fileDigest(path, "sha256")
+	if err != nil {
+		return "", 0, err
+	}
+	return digest, info.Size(), nil
+}
+
+// writeChecksumFiles writes one sha256sum-compatible checksum file per algorithm into
+// outputDir, covering every package. Entries are relative to outputDir and sorted by name.
+// Packages that do not exist are skipped.
+func writeChecksumFiles(outputDir string, packages []string, algorithms []string) ([]string, error) {
+	names := make([]string, 0, len(packages))
+	paths := make(map[string]string, len(packages))
+	for _, pkg := range packages {
+		if _, err := os.Stat(pkg); os.IsNotExist(err) {
+			continue
+		}
+		name, err := filepath.Rel(outputDir, pkg)
+		if err != nil || strings.HasPrefix(name, "..") {
+			name = filepath.Base(pkg)
+		}
+		name = filepath.ToSlash(name)
+		if _, seen := paths[name]; seen {
+			continue
+		}
+		paths[name] = pkg
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	files := make([]string, 0, len(algorithms))
+	for _, algorithm := range algorithms {
+		var sb strings.Builder
+		for _, name := range names {
+			digest, err := fileDigest(paths[name], algorithm)
+			if err != nil {
+				return files, err
+			}
+			fmt.Fprintf(&sb, "%s  %s\n", digest, name)
+		}
+
+		path := filepath.Join(outputDir, checksumFileName(algorithm))
+		if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+			return files, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		files = append(files, path)
+	}
+
+	return files, nil
+}
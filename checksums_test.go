@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestWriteChecksumFiles tests writing sha256sum-compatible checksum files.
+func TestWriteChecksumFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	packages := []string{
+		filepath.Join(dir, "myapp_1.0.0_amd64.deb"),
+		filepath.Join(dir, "myapp-1.0.0.x86_64.rpm"),
+	}
+	for _, pkg := range packages {
+		if err := os.WriteFile(pkg, []byte(filepath.Base(pkg)), 0644); err != nil {
+			t.Fatalf("failed to write package: %v", err)
+		}
+	}
+	missing := filepath.Join(dir, "missing.apk")
+
+	files, err := writeChecksumFiles(dir, append(packages, missing), []string{"sha256", "sha512"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(files) != 2 || filepath.Base(files[0]) != "SHA256SUMS" || filepath.Base(files[1]) != "SHA512SUMS" {
+		t.Fatalf("unexpected checksum files: %v", files)
+	}
+
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("failed to read SHA256SUMS: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 entries, got %q", data)
+	}
+
+	sum := sha256.Sum256([]byte("myapp-1.0.0.x86_64.rpm"))
+	expected := hex.EncodeToString(sum[:]) + "  myapp-1.0.0.x86_64.rpm"
+	if lines[0] != expected {
+		t.Errorf("expected sorted entry %q, got %q", expected, lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "  myapp_1.0.0_amd64.deb") {
+		t.Errorf("unexpected second entry %q", lines[1])
+	}
+
+	data, err = os.ReadFile(files[1])
+	if err != nil {
+		t.Fatalf("failed to read SHA512SUMS: %v", err)
+	}
+	if digest := strings.Fields(string(data))[0]; len(digest) != 128 {
+		t.Errorf("expected a sha512 digest, got %q", digest)
+	}
+}
+
+// TestValidateChecksums tests validation of the checksums option.
+func TestValidateChecksums(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		checksums   []any
+		expectValid bool
+	}{
+		{name: "sha256", checksums: []any{"sha256"}, expectValid: true},
+		{name: "sha256 and sha512", checksums: []any{"sha256", "sha512"}, expectValid: true},
+		{name: "disabled", checksums: []any{}, expectValid: true},
+		{name: "md5", checksums: []any{"md5"}, expectValid: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &LinuxPkgPlugin{}
+			resp, err := p.Validate(context.Background(), map[string]any{"checksums": tc.checksums})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Valid != tc.expectValid {
+				t.Errorf("expected valid=%v, got %v: %v", tc.expectValid, resp.Valid, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestExecuteWritesChecksums tests that a SHA256SUMS file is written by default.
+func TestExecuteWritesChecksums(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:   plugin.HookPostPublish,
+		Config: map[string]any{"working_dir": dir, "formats": []any{"deb", "rpm"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	files, ok := resp.Outputs["checksum_files"].([]string)
+	if !ok || len(files) != 1 || files[0] != filepath.Join(dir, "dist", "SHA256SUMS") {
+		t.Fatalf("unexpected checksum_files output: %v", resp.Outputs["checksum_files"])
+	}
+
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("failed to read checksum file: %v", err)
+	}
+	for _, pkg := range resp.Outputs["packages"].([]string) {
+		digest, err := fileDigest(pkg, "sha256")
+		if err != nil {
+			t.Fatalf("failed to hash package: %v", err)
+		}
+		if !strings.Contains(string(data), digest+"  "+filepath.Base(pkg)+"\n") {
+			t.Errorf("expected an entry for %s in %q", pkg, data)
+		}
+	}
+}
+
+// TestArtifactDigest tests digesting a package and skipping missing ones.
+func TestArtifactDigest(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "myapp.deb")
+	if err := os.WriteFile(path, []byte("package"), 0644); err != nil {
+		t.Fatalf("failed to write package: %v", err)
+	}
+
+	digest, size, err := artifactDigest(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sum := sha256.Sum256([]byte("package"))
+	if digest != hex.EncodeToString(sum[:]) || size != 7 {
+		t.Errorf("unexpected digest %q and size %d", digest, size)
+	}
+
+	digest, size, err = artifactDigest(filepath.Join(t.TempDir(), "missing.deb"))
+	if err != nil || digest != "" || size != 0 {
+		t.Errorf("expected missing package to be skipped, got %q, %d, %v", digest, size, err)
+	}
+}
+
+// TestExecuteReportsArtifactDigests tests that each artifact carries its sha256 and size.
+func TestExecuteReportsArtifactDigests(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:   plugin.HookPostPublish,
+		Config: map[string]any{"working_dir": dir, "formats": []any{"deb", "apk"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	artifacts := resp.Outputs["artifacts"].([]map[string]any)
+	if len(artifacts) != 2 {
+		t.Fatalf("expected 2 artifacts, got %v", artifacts)
+	}
+	for _, artifact := range artifacts {
+		path := artifact["path"].(string)
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("failed to stat %s: %v", path, err)
+		}
+		digest, err := fileDigest(path, "sha256")
+		if err != nil {
+			t.Fatalf("failed to hash %s: %v", path, err)
+		}
+		if artifact["sha256"] != digest {
+			t.Errorf("%s: expected sha256 %s, got %v", path, digest, artifact["sha256"])
+		}
+		if artifact["size"] != info.Size() {
+			t.Errorf("%s: expected size %d, got %v", path, info.Size(), artifact["size"])
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// attestationExtensions are appended to a package's name by the files written next to
+// it: cosign signatures, certificates, and bundles, and provenance statements.
+var attestationExtensions = []string{".sig", ".pem", ".bundle", provenanceExtension}
+
+// isArtifactName reports whether a file name is one the plugin writes to an output
+// directory: a package, a file written next to one, or a checksum file.
+func isArtifactName(name string) bool {
+	for algorithm := range checksumAlgorithms {
+		if name == checksumFileName(algorithm) {
+			return true
+		}
+	}
+	for _, ext := range attestationExtensions {
+		name = strings.TrimSuffix(name, ext)
+	}
+	for _, ext := range packageExtensions {
+		if strings.HasSuffix(name, ext) && len(name) > len(ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkInsideWorkspace checks that dir is strictly inside workspace once symlinks are
+// resolved, so clean_output never removes files from the workspace root or elsewhere.
+func checkInsideWorkspace(workspace, dir string) error {
+	root, err := filepath.EvalSymlinks(workspace)
+	if err != nil {
+		return fmt.Errorf("failed to resolve workspace %s: %w", workspace, err)
+	}
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s is not inside the workspace %s", dir, workspace)
+	}
+	return nil
+}
+
+// staleArtifacts returns the artifacts in outputDirs that the release record of version
+// does not list. Packages built for version are kept, so the build cache can reuse
+// them; everything left over from other versions is stale. Directories that do not
+// exist yet have nothing to clean. Each directory must be inside the working directory,
+// or the process directory without one.
+func (cfg *Config) staleArtifacts(version string, outputDirs []string) ([]string, error) {
+	workspace := cfg.WorkingDir
+	if workspace == "" {
+		dir, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get working directory: %w", err)
+		}
+		workspace = dir
+	}
+
+	keep := make(map[string]bool)
+	if record, err := readReleaseRecord(outputDirs[0]); err == nil && record.Version == version {
+		for _, artifact := range record.Artifacts {
+			for _, key := range []string{"path", "signature", "certificate", "bundle", "provenance"} {
+				if path, ok := artifact[key].(string); ok && path != "" {
+					keep[filepath.Clean(path)] = true
+				}
+			}
+		}
+		for _, path := range record.ChecksumFiles {
+			keep[filepath.Clean(path)] = true
+		}
+	}
+
+	var stale []string
+	seen := make(map[string]bool, len(outputDirs))
+	for _, dir := range outputDirs {
+		dir = filepath.Clean(dir)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+		}
+		if err := checkInsideWorkspace(workspace, dir); err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if entry.Type().IsRegular() && isArtifactName(entry.Name()) && !keep[path] {
+				stale = append(stale, path)
+			}
+		}
+	}
+	slices.Sort(stale)
+	return stale, nil
+}
+
+// removeFiles removes files and returns those it removed before any failure.
+func removeFiles(files []string) ([]string, error) {
+	removed := make([]string, 0, len(files))
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return removed, fmt.Errorf("failed to remove %s: %w", file, err)
+		}
+		removed = append(removed, file)
+	}
+	return removed, nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestIsArtifactName tests recognizing the files the plugin writes to output directories.
+func TestIsArtifactName(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]bool{
+		"myapp_1.0.0_amd64.deb":              true,
+		"myapp-1.0.0-1.x86_64.rpm":           true,
+		"myapp-1.0.0-1-x86_64.pkg.tar.zst":   true,
+		"myapp_1.0.0_amd64.deb.sig":          true,
+		"myapp_1.0.0_amd64.deb.intoto.json":  true,
+		"SHA256SUMS":                         true,
+		"SHA512SUMS":                         true,
+		"notes.txt":                          false,
+		".deb":                               false,
+		releaseRecordName:                    false,
+		"myapp_1.0.0_amd64.deb.sig.download": false,
+	}
+
+	for name, expected := range tests {
+		if got := isArtifactName(name); got != expected {
+			t.Errorf("isArtifactName(%q) = %v, expected %v", name, got, expected)
+		}
+	}
+}
+
+// TestExecuteCleanOutput tests that clean_output removes artifacts of other versions
+// before building and keeps other files.
+func TestExecuteCleanOutput(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+	dist := filepath.Join(dir, "dist")
+	if err := os.MkdirAll(dist, 0755); err != nil {
+		t.Fatalf("failed to create dist: %v", err)
+	}
+	stale := []string{"myapp_0.9.0_amd64.deb", "myapp_0.9.0_amd64.deb.sig", "myapp-0.9.0-1.x86_64.rpm"}
+	for _, name := range append([]string{"notes.txt"}, stale...) {
+		if err := os.WriteFile(filepath.Join(dist, name), []byte("old"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	p := &LinuxPkgPlugin{}
+	req := plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"working_dir": dir, "formats": []any{"deb"}, "clean_output": true},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+		DryRun:  true,
+	}
+	resp, err := p.Execute(context.Background(), req)
+	if err != nil || !resp.Success {
+		t.Fatalf("expected the dry run to succeed, got %v, %+v", err, resp)
+	}
+	expected := []string{filepath.Join(dist, stale[2]), filepath.Join(dist, stale[0]), filepath.Join(dist, stale[1])}
+	if !reflect.DeepEqual(resp.Outputs["stale_files"], expected) {
+		t.Errorf("expected stale files %v, got %v", expected, resp.Outputs["stale_files"])
+	}
+	if _, err := os.Stat(expected[0]); err != nil {
+		t.Errorf("expected the dry run to keep %s: %v", expected[0], err)
+	}
+
+	req.DryRun = false
+	resp, err = p.Execute(context.Background(), req)
+	if err != nil || !resp.Success {
+		t.Fatalf("expected the build to succeed, got %v, %+v", err, resp)
+	}
+	if !strings.Contains(resp.Message, "removed 3 stale file(s)") {
+		t.Errorf("unexpected message: %s", resp.Message)
+	}
+	for _, path := range expected {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed, got %v", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dist, "notes.txt")); err != nil {
+		t.Errorf("expected notes.txt to be kept: %v", err)
+	}
+
+	// Rebuilding the same version keeps its packages.
+	resp, err = p.Execute(context.Background(), req)
+	if err != nil || !resp.Success {
+		t.Fatalf("expected the rebuild to succeed, got %v, %+v", err, resp)
+	}
+	if cleaned := resp.Outputs["cleaned"].([]string); len(cleaned) != 0 {
+		t.Errorf("expected nothing to be cleaned, got %v", cleaned)
+	}
+}
+
+// TestExecuteCleanOutputWorkspace tests that clean_output refuses to clean the workspace
+// root.
+func TestExecuteCleanOutputWorkspace(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"working_dir": dir, "output_dir": ".", "clean_output": true},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "is not inside the workspace") {
+		t.Errorf("expected the workspace root to be refused, got %+v", resp)
+	}
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/blakesmith/ar"
+	"github.com/goreleaser/nfpm/v2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressionAlgorithms are the algorithms nfpm supports for deb data tarballs and rpm
+// payloads.
+var compressionAlgorithms = map[string][]string{
+	"deb": {"gzip", "xz", "zstd", "none"},
+	"rpm": {"gzip", "lzma", "xz", "zstd"},
+}
+
+// compressionLevels are the levels of the algorithms that take one.
+var compressionLevels = map[string][2]int{
+	"gzip": {1, 9},
+	"zstd": {1, 22},
+}
+
+// debDataExtensions are the extensions of deb data tarballs by compression algorithm.
+var debDataExtensions = map[string]string{
+	"gzip": ".gz",
+	"xz":   ".xz",
+	"zstd": ".zst",
+	"none": "",
+}
+
+// fpmCompressions are fpm's names of the algorithms, by format. fpm has no lzma or zstd
+// rpm payloads.
+var fpmCompressions = map[string]map[string]string{
+	"deb": {"gzip": "gz", "xz": "xz", "zstd": "zst", "none": "none"},
+	"rpm": {"gzip": "gzip", "xz": "xz"},
+}
+
+// CompressionConfig sets the compression of deb and rpm packages, as an algorithm with an
+// optional level, such as "zstd:19".
+type CompressionConfig struct {
+	// Deb compresses the data tarball of deb packages.
+	Deb string
+	// RPM compresses the payload of rpm packages.
+	RPM string
+}
+
+// parseCompression reads the compression object of raw, the top-level config or the
+// settings of a distribution. It returns nil when it is not set.
+func parseCompression(raw map[string]any) *CompressionConfig {
+	block, ok := raw["compression"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	deb, _ := block["deb"].(string)
+	rpm, _ := block["rpm"].(string)
+	return &CompressionConfig{Deb: deb, RPM: rpm}
+}
+
+// validateCompressionObject checks that the compression of raw is an object with only
+// deb and rpm strings.
+func validateCompressionObject(raw map[string]any) error {
+	value, ok := raw["compression"]
+	if !ok || value == nil {
+		return nil
+	}
+	block, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("compression must be an object")
+	}
+	for _, key := range sortedKeys(block) {
+		if _, ok := compressionAlgorithms[key]; !ok {
+			return fmt.Errorf("unknown format %q (allowed: deb, rpm)", key)
+		}
+		if _, ok := block[key].(string); !ok {
+			return fmt.Errorf("%s must be a string", key)
+		}
+	}
+	return nil
+}
+
+// parseCompressionSetting splits an "algorithm[:level]" setting. The level is 0 when
+// the setting has none.
+func parseCompressionSetting(setting string) (string, int, error) {
+	algorithm, rawLevel, hasLevel := strings.Cut(setting, ":")
+	if !hasLevel {
+		return algorithm, 0, nil
+	}
+	level, err := strconv.Atoi(rawLevel)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid level %q", rawLevel)
+	}
+	return algorithm, level, nil
+}
+
+// validate checks the algorithm and level of each format, and that packager supports them.
+func (c *CompressionConfig) validate(packager string) error {
+	if c == nil {
+		return nil
+	}
+	for _, format := range []string{"deb", "rpm"} {
+		setting := c.setting(format)
+		if setting == "" {
+			continue
+		}
+		algorithm, level, err := parseCompressionSetting(setting)
+		if err != nil {
+			return fmt.Errorf("%s: %w", format, err)
+		}
+		if !slices.Contains(compressionAlgorithms[format], algorithm) {
+			return fmt.Errorf("%s: unsupported algorithm %q (allowed: %s)", format, algorithm, strings.Join(compressionAlgorithms[format], ", "))
+		}
+		if strings.Contains(setting, ":") {
+			bounds, ok := compressionLevels[algorithm]
+			if !ok {
+				return fmt.Errorf("%s: %s takes no level", format, algorithm)
+			}
+			if level < bounds[0] || level > bounds[1] {
+				return fmt.Errorf("%s: %s level must be between %d and %d", format, algorithm, bounds[0], bounds[1])
+			}
+		}
+		if _, ok := fpmCompressions[format][algorithm]; packager == "fpm" && !ok {
+			return fmt.Errorf("%s: packager fpm does not support %s", format, algorithm)
+		}
+	}
+	return nil
+}
+
+// merge returns c with the formats override sets replaced.
+func (c *CompressionConfig) merge(override *CompressionConfig) *CompressionConfig {
+	if override == nil {
+		return c
+	}
+	merged := CompressionConfig{}
+	if c != nil {
+		merged = *c
+	}
+	if override.Deb != "" {
+		merged.Deb = override.Deb
+	}
+	if override.RPM != "" {
+		merged.RPM = override.RPM
+	}
+	return &merged
+}
+
+// setting returns the compression setting of format, or "" to keep nfpm's.
+func (c *CompressionConfig) setting(format string) string {
+	if c == nil {
+		return ""
+	}
+	switch format {
+	case "deb":
+		return c.Deb
+	case "rpm":
+		return c.RPM
+	}
+	return ""
+}
+
+// applyCompression sets the compression of format in doc. nfpm takes rpm levels but not
+// deb ones, which recompressDeb applies after the build. It reports whether doc changed.
+func applyCompression(doc map[string]any, format string, c *CompressionConfig) bool {
+	setting := c.setting(format)
+	if setting == "" {
+		return false
+	}
+	if format == "deb" {
+		setting, _, _ = strings.Cut(setting, ":")
+	}
+	block, ok := doc[format].(map[string]any)
+	if !ok {
+		block = make(map[string]any)
+		doc[format] = block
+	}
+	block["compression"] = setting
+	return true
+}
+
+// fpmCompressionArgs returns the fpm arguments compressing a package of format as info
+// sets. Deb levels are applied by recompressDeb.
+func fpmCompressionArgs(info *nfpm.Info, format string) []string {
+	switch format {
+	case "deb":
+		if name, ok := fpmCompressions["deb"][info.Deb.Compression]; ok {
+			return []string{"--deb-compression", name}
+		}
+	case "rpm":
+		algorithm, level, err := parseCompressionSetting(info.RPM.Compression)
+		name, ok := fpmCompressions["rpm"][algorithm]
+		if err != nil || !ok {
+			return nil
+		}
+		args := []string{"--rpm-compression", name}
+		if level > 0 {
+			args = append(args, "--rpm-compression-level", strconv.Itoa(level))
+		}
+		return args
+	}
+	return nil
+}
+
+// recompressDeb compresses the data tarball of a built deb with the algorithm and level
+// of setting. Signed debs cannot be recompressed, since the signature covers the tarball.
+func recompressDeb(pkg, setting string) error {
+	algorithm, level, err := parseCompressionSetting(setting)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(pkg)
+	if err != nil {
+		return fmt.Errorf("failed to recompress %s: %w", pkg, err)
+	}
+	f, err := os.Open(pkg)
+	if err != nil {
+		return fmt.Errorf("failed to recompress %s: %w", pkg, err)
+	}
+	defer f.Close()
+
+	var out bytes.Buffer
+	writer := ar.NewWriter(&out)
+	if err := writer.WriteGlobalHeader(); err != nil {
+		return err
+	}
+	reader := ar.NewReader(f)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to recompress %s: %w", pkg, err)
+		}
+		name := strings.TrimSuffix(header.Name, "/")
+		if strings.HasPrefix(name, "_gpg") {
+			return fmt.Errorf("cannot set the compression level of signed deb %s", pkg)
+		}
+
+		var content []byte
+		if strings.HasPrefix(name, "data.tar") {
+			content, err = recompressTarball(reader, name, algorithm, level)
+			header.Name = "data.tar" + debDataExtensions[algorithm]
+		} else {
+			content, err = io.ReadAll(reader)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to recompress %s: %w", pkg, err)
+		}
+		header.Size = int64(len(content))
+		if err := writer.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := writer.Write(content); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(pkg, out.Bytes(), info.Mode().Perm())
+}
+
+// recompressTarball decompresses the tarball member name and compresses it with
+// algorithm at level.
+func recompressTarball(r io.Reader, name, algorithm string, level int) ([]byte, error) {
+	data, err := decompress(r, name)
+	if err != nil {
+		return nil, err
+	}
+	defer data.Close()
+
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch algorithm {
+	case "gzip":
+		w, err = gzip.NewWriterLevel(&buf, level)
+	case "zstd":
+		// A single encoder goroutine keeps the output reproducible.
+		w, err = zstd.NewWriter(&buf, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)), zstd.WithEncoderConcurrency(1))
+	default:
+		err = errors.New(algorithm + " takes no level")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(w, data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// validateCompressions checks the global compression and that of every distribution.
+func validateCompressions(compression *CompressionConfig, distros []*DistroConfig, packager string) error {
+	if err := compression.validate(packager); err != nil {
+		return err
+	}
+	for _, distro := range distros {
+		if err := distro.Compression.validate(packager); err != nil {
+			return fmt.Errorf("distro %s: %w", distro.Name, err)
+		}
+	}
+	return nil
+}
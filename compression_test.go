@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/blakesmith/ar"
+	"github.com/goreleaser/nfpm/v2"
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// debDataHead returns the name and first bytes of a deb's data tarball.
+func debDataHead(t *testing.T, pkg string) (string, []byte) {
+	t.Helper()
+
+	name, member := debMember(t, pkg, "data.tar")
+	head := make([]byte, 16)
+	if _, err := io.ReadFull(member, head); err != nil {
+		t.Fatalf("failed to read %s: %v", name, err)
+	}
+	return name, head
+}
+
+// TestExecuteCompression tests compressing debs and rpms as configured, globally and per
+// distribution.
+func TestExecuteCompression(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+
+	p := &LinuxPkgPlugin{cmdExecutor: &MockCommandExecutor{}}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []any{"deb", "rpm"},
+			"compression": map[string]any{"deb": "gzip:1", "rpm": "xz"},
+			"distros": map[string]any{
+				"debian-bookworm": map[string]any{"compression": map[string]any{"deb": "zstd:19"}},
+				"ubuntu-bionic":   nil,
+				"el9":             nil,
+			},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected success, got %v, %+v", err, resp)
+	}
+
+	artifacts := resp.Outputs["artifacts"].([]map[string]any)
+	if len(artifacts) != 3 {
+		t.Fatalf("expected 3 artifacts, got %v", artifacts)
+	}
+	for _, artifact := range artifacts {
+		path := artifact["path"].(string)
+		switch artifact["distro"] {
+		case "ubuntu-bionic":
+			// The gzip header's extra flags are 4 for the fastest level.
+			if name, head := debDataHead(t, path); name != "data.tar.gz" || head[8] != 4 {
+				t.Errorf("expected a gzip level 1 data tarball, got %s with % x", name, head)
+			}
+		case "debian-bookworm":
+			if name, head := debDataHead(t, path); name != "data.tar.zst" || string(head[:4]) != "\x28\xb5\x2f\xfd" {
+				t.Errorf("expected a zstd data tarball, got %s with % x", name, head)
+			}
+		case "el9":
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("failed to open %s: %v", path, err)
+			}
+			h, err := readRPMMainHeader(f)
+			f.Close()
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", path, err)
+			}
+			if compressor, _ := h.value(1125); compressor != "xz" {
+				t.Errorf("expected an xz payload, got %q", compressor)
+			}
+		default:
+			t.Errorf("unexpected artifact %v", artifact)
+		}
+		if _, err := readPackageContents(path, artifact["format"].(string), true); err != nil {
+			t.Errorf("failed to read %s: %v", path, err)
+		}
+	}
+}
+
+// TestRecompressDebSigned tests that signed debs are not recompressed.
+func TestRecompressDebSigned(t *testing.T) {
+	t.Parallel()
+
+	pkg := filepath.Join(t.TempDir(), "test.deb")
+	f, err := os.Create(pkg)
+	if err != nil {
+		t.Fatalf("failed to create deb: %v", err)
+	}
+	writer := ar.NewWriter(f)
+	_ = writer.WriteGlobalHeader()
+	for _, name := range []string{"debian-binary", "_gpgorigin"} {
+		_ = writer.WriteHeader(&ar.Header{Name: name, Mode: 0644, Size: 4})
+		_, _ = writer.Write([]byte("2.0\n"))
+	}
+	f.Close()
+
+	if err := recompressDeb(pkg, "gzip:9"); err == nil || !strings.Contains(err.Error(), "signed deb") {
+		t.Errorf("expected a signed deb error, got %v", err)
+	}
+}
+
+// TestValidateCompression tests the compression option.
+func TestValidateCompression(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		config      map[string]any
+		expectError string
+	}{
+		{"valid", map[string]any{"compression": map[string]any{"deb": "zstd:19", "rpm": "gzip:9"}}, ""},
+		{"algorithm only", map[string]any{"compression": map[string]any{"deb": "none", "rpm": "lzma"}}, ""},
+		{"not an object", map[string]any{"compression": "zstd"}, "must be an object"},
+		{"unknown format", map[string]any{"compression": map[string]any{"apk": "gzip"}}, "unknown format"},
+		{"unknown algorithm", map[string]any{"compression": map[string]any{"deb": "bzip2"}}, "unsupported algorithm"},
+		{"level without levels", map[string]any{"compression": map[string]any{"rpm": "xz:6"}}, "xz takes no level"},
+		{"level out of range", map[string]any{"compression": map[string]any{"deb": "gzip:10"}}, "between 1 and 9"},
+		{"invalid level", map[string]any{"compression": map[string]any{"deb": "zstd:max"}}, "invalid level"},
+		{"fpm", map[string]any{"packager": "fpm", "compression": map[string]any{"rpm": "zstd"}}, "fpm does not support zstd"},
+		{"distro", map[string]any{"distros": map[string]any{"el9": map[string]any{"compression": map[string]any{"rpm": "gzip:0"}}}}, "distro el9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			expectFieldError(t, tt.config, "compression", tt.expectError)
+		})
+	}
+}
+
+// TestFpmCompressionArgs tests passing the compression to fpm.
+func TestFpmCompressionArgs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		format string
+		info   nfpm.Info
+		expect []string
+	}{
+		{"deb", nfpm.Info{Overridables: nfpm.Overridables{Deb: nfpm.Deb{Compression: "zstd"}}}, []string{"--deb-compression", "zst"}},
+		{"rpm", nfpm.Info{Overridables: nfpm.Overridables{RPM: nfpm.RPM{Compression: "gzip:9"}}}, []string{"--rpm-compression", "gzip", "--rpm-compression-level", "9"}},
+		{"rpm", nfpm.Info{}, nil},
+	}
+
+	for _, tt := range tests {
+		if got := fpmCompressionArgs(&tt.info, tt.format); !slices.Equal(got, tt.expect) {
+			t.Errorf("expected %v for %s, got %v", tt.expect, tt.format, got)
+		}
+	}
+}
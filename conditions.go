@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// releaseTypes are the release types only_release_types can select.
+var releaseTypes = []string{"major", "minor", "patch", "prerelease"}
+
+// validateReleaseTypes checks the release types of only_release_types.
+func validateReleaseTypes(types []string) error {
+	for _, releaseType := range types {
+		if !slices.Contains(releaseTypes, releaseType) {
+			return fmt.Errorf("unsupported release type: %s (allowed: %s)", releaseType, strings.Join(releaseTypes, ", "))
+		}
+	}
+	return nil
+}
+
+// isRegexpPattern reports whether a branch or tag pattern is a regular expression,
+// written between slashes as in /^v[0-9]+/. Other patterns are globs.
+func isRegexpPattern(pattern string) bool {
+	return len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/")
+}
+
+// validateRefPatterns checks the globs and regular expressions of only_branches or
+// only_tags.
+func validateRefPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if pattern == "" {
+			return fmt.Errorf("pattern cannot be empty")
+		}
+		if isRegexpPattern(pattern) {
+			if _, err := regexp.Compile(pattern[1 : len(pattern)-1]); err != nil {
+				return fmt.Errorf("invalid regular expression %s: %w", pattern, err)
+			}
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid glob %s: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchRef reports whether ref matches any of patterns. Globs match with path.Match, so
+// release/* matches release/1.2 but not release/1.2/hotfix.
+func matchRef(patterns []string, ref string) bool {
+	for _, pattern := range patterns {
+		if isRegexpPattern(pattern) {
+			if re, err := regexp.Compile(pattern[1 : len(pattern)-1]); err == nil && re.MatchString(ref) {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(pattern, ref); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isPrerelease reports whether release is a prerelease: its release type says so or its
+// version has a prerelease part, as in 1.2.0-rc.1.
+func isPrerelease(release plugin.ReleaseContext) bool {
+	if release.ReleaseType == "prerelease" {
+		return true
+	}
+	v, err := semver.NewVersion(release.Version)
+	return err == nil && v.Prerelease() != ""
+}
+
+// skipReason returns why the plugin does not run for release under the configured
+// conditions, or "" when it runs.
+func skipReason(cfg *Config, release plugin.ReleaseContext) string {
+	if cfg.SkipPrerelease && isPrerelease(release) {
+		return fmt.Sprintf("release %s is a prerelease (skip_prerelease)", release.Version)
+	}
+	if len(cfg.OnlyReleaseTypes) > 0 && !slices.Contains(cfg.OnlyReleaseTypes, release.ReleaseType) {
+		if release.ReleaseType == "" {
+			return fmt.Sprintf("release %s has no release type (only_release_types: %s)", release.Version, strings.Join(cfg.OnlyReleaseTypes, ", "))
+		}
+		return fmt.Sprintf("release type %s is not selected (only_release_types: %s)", release.ReleaseType, strings.Join(cfg.OnlyReleaseTypes, ", "))
+	}
+	if len(cfg.OnlyBranches) > 0 && !matchRef(cfg.OnlyBranches, release.Branch) {
+		if release.Branch == "" {
+			return fmt.Sprintf("release %s has no branch (only_branches: %s)", release.Version, strings.Join(cfg.OnlyBranches, ", "))
+		}
+		return fmt.Sprintf("branch %s does not match only_branches (%s)", release.Branch, strings.Join(cfg.OnlyBranches, ", "))
+	}
+	if len(cfg.OnlyTags) > 0 && !matchRef(cfg.OnlyTags, release.TagName) {
+		if release.TagName == "" {
+			return fmt.Sprintf("release %s has no tag (only_tags: %s)", release.Version, strings.Join(cfg.OnlyTags, ", "))
+		}
+		return fmt.Sprintf("tag %s does not match only_tags (%s)", release.TagName, strings.Join(cfg.OnlyTags, ", "))
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestSkipReason tests skipping releases by release type, branch, and tag.
+func TestSkipReason(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		cfg        *Config
+		release    plugin.ReleaseContext
+		expectSkip string
+	}{
+		{"no conditions", &Config{}, plugin.ReleaseContext{Version: "1.0.0-rc.1"}, ""},
+		{"release", &Config{SkipPrerelease: true}, plugin.ReleaseContext{Version: "1.0.0", ReleaseType: "minor"}, ""},
+		{"prerelease version", &Config{SkipPrerelease: true}, plugin.ReleaseContext{Version: "1.0.0-rc.1"}, "release 1.0.0-rc.1 is a prerelease"},
+		{"prerelease type", &Config{SkipPrerelease: true}, plugin.ReleaseContext{Version: "1.0.0", ReleaseType: "prerelease"}, "is a prerelease"},
+		{"selected type", &Config{OnlyReleaseTypes: []string{"major", "minor"}}, plugin.ReleaseContext{Version: "2.0.0", ReleaseType: "major"}, ""},
+		{"other type", &Config{OnlyReleaseTypes: []string{"major", "minor"}}, plugin.ReleaseContext{Version: "2.0.1", ReleaseType: "patch"}, "release type patch is not selected"},
+		{"no type", &Config{OnlyReleaseTypes: []string{"major"}}, plugin.ReleaseContext{Version: "2.0.1"}, "has no release type"},
+		{"branch glob", &Config{OnlyBranches: []string{"main", "release/*"}}, plugin.ReleaseContext{Branch: "release/1.2"}, ""},
+		{"nested branch", &Config{OnlyBranches: []string{"release/*"}}, plugin.ReleaseContext{Branch: "release/1.2/hotfix"}, "branch release/1.2/hotfix does not match only_branches (release/*)"},
+		{"no branch", &Config{OnlyBranches: []string{"main"}}, plugin.ReleaseContext{Version: "1.0.0"}, "has no branch"},
+		{"tag regexp", &Config{OnlyTags: []string{`/^v[0-9]+\.[0-9]+\.[0-9]+$/`}}, plugin.ReleaseContext{TagName: "v1.2.3"}, ""},
+		{"tag regexp mismatch", &Config{OnlyTags: []string{`/^v[0-9]+\.[0-9]+\.[0-9]+$/`}}, plugin.ReleaseContext{TagName: "v1.2.3-rc.1"}, "tag v1.2.3-rc.1 does not match only_tags"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			reason := skipReason(tt.cfg, tt.release)
+			if tt.expectSkip == "" {
+				if reason != "" {
+					t.Errorf("expected no skip, got %q", reason)
+				}
+				return
+			}
+			if !strings.Contains(reason, tt.expectSkip) {
+				t.Errorf("expected a skip reason containing %q, got %q", tt.expectSkip, reason)
+			}
+		})
+	}
+}
+
+// TestExecuteSkipsRelease tests that a skipped release builds nothing and reports why.
+func TestExecuteSkipsRelease(t *testing.T) {
+	t.Parallel()
+
+	mock := &MockCommandExecutor{}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"packager": "nfpm-cli", "skip_prerelease": true},
+		Context: plugin.ReleaseContext{Version: "1.0.0-beta.2"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success || resp.Message != "Skipped: release 1.0.0-beta.2 is a prerelease (skip_prerelease)" {
+		t.Errorf("expected a skipped release, got %+v", resp)
+	}
+	if resp.Outputs["skipped"] != true || len(mock.Calls) != 0 {
+		t.Errorf("expected nothing to be built, got outputs %v and calls %v", resp.Outputs, mock.Calls)
+	}
+
+	resp, err = p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:   plugin.HookPostPublish,
+		Config: map[string]any{"only_release_types": []any{"major", "hotfix"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success || !strings.HasPrefix(resp.Error, "invalid only_release_types: unsupported release type: hotfix") {
+		t.Errorf("expected an invalid release type, got %+v", resp)
+	}
+}
+
+// TestValidateRefPatterns tests validating branch and tag patterns.
+func TestValidateRefPatterns(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		patterns    []string
+		expectError bool
+	}{
+		{[]string{"main", "release/*", "/^hotfix-.+$/"}, false},
+		{[]string{"release/["}, true},
+		{[]string{"/(unclosed/"}, true},
+		{[]string{""}, true},
+	}
+
+	for _, tt := range tests {
+		if err := validateRefPatterns(tt.patterns); (err != nil) != tt.expectError {
+			t.Errorf("validateRefPatterns(%q) error = %v, expectError %v", tt.patterns, err, tt.expectError)
+		}
+	}
+}
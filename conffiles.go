@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// configFileType is the nfpm content type of configuration files that survive upgrades:
+// deb conffiles and rpm %config(noreplace) files.
+const configFileType = "config|noreplace"
+
+// validateConfigFiles checks that every config_files glob is an absolute destination.
+func validateConfigFiles(globs []string) error {
+	for _, glob := range globs {
+		if !strings.HasPrefix(glob, "/") {
+			return fmt.Errorf("config_files glob must be an absolute destination: %s", glob)
+		}
+		if _, err := regexp.Compile(globToRegexp(glob)); err != nil {
+			return fmt.Errorf("invalid config_files glob %q: %w", glob, err)
+		}
+	}
+	return nil
+}
+
+// applyConfigFiles marks the regular files of doc installed at a destination matching one
+// of globs as configuration files, in the top-level contents and nfpm's own overrides for
+// format. Entries with a globbed source are marked as a whole when their destination
+// directory matches, and entries that already are configuration files keep their type.
+// It reports whether doc changed.
+func applyConfigFiles(doc map[string]any, format string, globs []string, getenv func(string) string) bool {
+	if len(globs) == 0 {
+		return false
+	}
+	patterns := make([]*regexp.Regexp, len(globs))
+	for i, glob := range globs {
+		patterns[i] = regexp.MustCompile(globToRegexp(glob))
+	}
+
+	changed := false
+	for _, entries := range formatContentEntries(doc, format) {
+		for _, raw := range entries {
+			entry, ok := raw.(map[string]any)
+			if !ok {
+				continue
+			}
+			if entryType, _ := entry["type"].(string); entryType != "" && entryType != "file" {
+				continue
+			}
+			for _, pattern := range patterns {
+				if contentDestinationMatches(pattern, entry, getenv) {
+					entry["type"] = configFileType
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return changed
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// debControlFile returns the content of a file in a deb's control tarball.
+func debControlFile(t *testing.T, pkg, name string) string {
+	t.Helper()
+
+	tr := readDebMember(t, pkg, "control.tar")
+	for {
+		entry, err := tr.Next()
+		if err == io.EOF {
+			return ""
+		}
+		if err != nil {
+			t.Fatalf("failed to read the control tarball of %s: %v", pkg, err)
+		}
+		if strings.TrimPrefix(entry.Name, "./") == name {
+			content, err := io.ReadAll(tr)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", name, err)
+			}
+			return string(content)
+		}
+	}
+}
+
+// TestApplyConfigFiles tests marking contents as configuration files.
+func TestApplyConfigFiles(t *testing.T) {
+	t.Parallel()
+
+	doc := map[string]any{
+		"contents": []any{
+			map[string]any{"src": "myapp", "dst": "/usr/bin/myapp"},
+			map[string]any{"src": "myapp.conf", "dst": "/etc/myapp/myapp.conf", "type": "file"},
+			map[string]any{"src": "conf.d/*", "dst": "/etc/myapp/conf.d"},
+			map[string]any{"src": "defaults", "dst": "/etc/${APP}/defaults", "expand": true},
+			map[string]any{"src": "legacy.conf", "dst": "/etc/myapp/legacy.conf", "type": "config"},
+			map[string]any{"dst": "/etc/myapp/empty", "type": "dir"},
+		},
+		"overrides": map[string]any{
+			"rpm": map[string]any{"contents": []any{map[string]any{"src": "rpm.conf", "dst": "/etc/myapp/rpm.conf"}}},
+		},
+	}
+	getenv := func(key string) string { return map[string]string{"APP": "myapp"}[key] }
+
+	if !applyConfigFiles(doc, "rpm", []string{"/etc/myapp/**"}, getenv) {
+		t.Fatal("expected the config to change")
+	}
+	expected := []string{"", configFileType, configFileType, configFileType, "config", "dir"}
+	for i, raw := range contentEntries(doc) {
+		if entryType, _ := raw.(map[string]any)["type"].(string); entryType != expected[i] {
+			t.Errorf("expected type %q for entry %d, got %q", expected[i], i, entryType)
+		}
+	}
+	override := doc["overrides"].(map[string]any)["rpm"].(map[string]any)
+	if entryType := contentEntries(override)[0].(map[string]any)["type"]; entryType != configFileType {
+		t.Errorf("expected the rpm override to be a config file, got %v", entryType)
+	}
+
+	if applyConfigFiles(doc, "deb", []string{"/usr/share/**"}, getenv) {
+		t.Error("expected no change without matches")
+	}
+}
+
+// TestExecuteConfigFiles tests building debs with conffiles.
+func TestExecuteConfigFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeTestNfpmConfig(t, dir, "arch: amd64\n", map[string]string{"/etc/myapp/myapp.conf": "key=value\n"})
+
+	p := &LinuxPkgPlugin{cmdExecutor: &MockCommandExecutor{}}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir":  dir,
+			"formats":      []any{"deb"},
+			"config_files": []any{"/etc/myapp/*.conf"},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected success, got %v, %+v", err, resp)
+	}
+
+	pkg := resp.Outputs["artifacts"].([]map[string]any)[0]["path"].(string)
+	if conffiles := debControlFile(t, pkg, "conffiles"); conffiles != "/etc/myapp/myapp.conf\n" {
+		t.Errorf("expected /etc/myapp/myapp.conf in conffiles, got %q", conffiles)
+	}
+}
+
+// TestValidateConfigFiles tests the config_files option.
+func TestValidateConfigFiles(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		globs       []any
+		expectError string
+	}{
+		{"valid", []any{"/etc/myapp/**", "/etc/default/myapp"}, ""},
+		{"relative", []any{"etc/myapp/*.conf"}, "absolute destination"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			expectFieldError(t, map[string]any{"config_files": tt.globs}, "config_files", tt.expectError)
+		})
+	}
+}
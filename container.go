@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// defaultContainerImage is the nfpm image the container packager runs.
+const defaultContainerImage = "goreleaser/nfpm"
+
+// containerNetworkPattern matches container network names, such as none, host, or a
+// user-defined network.
+var containerNetworkPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// containerUserPattern matches a numeric uid with an optional gid.
+var containerUserPattern = regexp.MustCompile(`^[0-9]+(:[0-9]+)?$`)
+
+// imageDigestPattern matches an image digest.
+var imageDigestPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// ContainerConfig configures the container packager, which runs nfpm in a container so
+// hosts without nfpm or native rpm tooling can build packages.
+type ContainerConfig struct {
+	// Image is the container image, whose entrypoint must be nfpm.
+	Image string
+	// Digest pins Image to a sha256 digest, e.g. "sha256:<hex>".
+	Digest string
+	// RequireDigest refuses images not pinned by digest, in Image or Digest.
+	RequireDigest bool
+	// Runtime is the container runtime, docker or podman. Empty uses whichever is installed.
+	Runtime string
+	// Mounts are extra volumes as host[:container][:ro|rw]. Relative host paths are
+	// resolved against the working directory; the container path defaults to the host path.
+	Mounts []string
+	// Network is the network the container joins, e.g. none. Empty uses the runtime's default.
+	Network string
+	// User runs nfpm as uid[:gid], or as the current user with "host". Empty uses the
+	// image's user.
+	User string
+}
+
+// parseContainer parses the container block, with defaults when it is not set.
+func parseContainer(raw map[string]any) *ContainerConfig {
+	parser := helpers.NewConfigParser(helpers.NewConfigParser(raw).GetMap("container"))
+	return &ContainerConfig{
+		Image:         parser.GetString("image", "", defaultContainerImage),
+		Digest:        parser.GetString("digest", "", ""),
+		RequireDigest: parser.GetBool("require_digest", false),
+		Runtime:       parser.GetString("runtime", "", ""),
+		Mounts:        parser.GetStringSlice("mounts", nil),
+		Network:       parser.GetString("network", "", ""),
+		User:          parser.GetString("user", "", ""),
+	}
+}
+
+// validate checks the container image, runtime, and engine options.
+func (c *ContainerConfig) validate() error {
+	if c.Runtime != "" && !allowedContainerRuntimes[c.Runtime] {
+		return fmt.Errorf("invalid runtime %q (allowed: docker, podman)", c.Runtime)
+	}
+	if !installImagePattern.MatchString(c.Image) {
+		return fmt.Errorf("invalid image %q", c.Image)
+	}
+	if c.Digest != "" {
+		if !imageDigestPattern.MatchString(c.Digest) {
+			return fmt.Errorf("invalid digest %q: must be sha256:<64 hex characters>", c.Digest)
+		}
+		if strings.Contains(c.Image, "@") {
+			return fmt.Errorf("image %q is already pinned by digest", c.Image)
+		}
+	}
+	if c.RequireDigest && !strings.Contains(c.imageRef(), "@sha256:") {
+		return fmt.Errorf("image %q is not pinned by digest (require_digest is set)", c.Image)
+	}
+	for _, mount := range c.Mounts {
+		if _, _, _, err := parseContainerMount(mount); err != nil {
+			return fmt.Errorf("invalid mount %q: %w", mount, err)
+		}
+	}
+	if c.Network != "" && !containerNetworkPattern.MatchString(c.Network) {
+		return fmt.Errorf("invalid network %q", c.Network)
+	}
+	if c.User != "" && c.User != "host" && !containerUserPattern.MatchString(c.User) {
+		return fmt.Errorf("invalid user %q: must be uid[:gid] or host", c.User)
+	}
+	return nil
+}
+
+// imageRef returns the image reference to run, pinned to Digest when it is set.
+func (c *ContainerConfig) imageRef() string {
+	if c.Digest != "" {
+		return c.Image + "@" + c.Digest
+	}
+	return c.Image
+}
+
+// parseContainerMount splits a host[:container][:ro|rw] mount. The container path
+// defaults to the host path.
+func parseContainerMount(mount string) (host, target string, readOnly bool, err error) {
+	parts := strings.Split(mount, ":")
+	if n := len(parts); n > 1 && (parts[n-1] == "ro" || parts[n-1] == "rw") {
+		readOnly = parts[n-1] == "ro"
+		parts = parts[:n-1]
+	}
+	switch len(parts) {
+	case 1:
+		host, target = parts[0], parts[0]
+	case 2:
+		host, target = parts[0], parts[1]
+	default:
+		return "", "", false, fmt.Errorf("must be host[:container][:ro|rw]")
+	}
+	if host == "" {
+		return "", "", false, fmt.Errorf("host path cannot be empty")
+	}
+	if !filepath.IsAbs(host) {
+		if err := validatePath(host); err != nil {
+			return "", "", false, err
+		}
+	}
+	if len(parts) == 2 && !filepath.IsAbs(target) {
+		return "", "", false, fmt.Errorf("container path must be absolute: %s", target)
+	}
+	return host, target, readOnly, nil
+}
+
+// volumes returns the -v values of the extra mounts, with relative host paths resolved
+// against workingDir and the current directory.
+func (c *ContainerConfig) volumes(workingDir string) []string {
+	var volumes []string
+	for _, mount := range c.Mounts {
+		host, target, readOnly, err := parseContainerMount(mount)
+		if err != nil {
+			continue
+		}
+		relative := target == host && !filepath.IsAbs(host)
+		if abs, err := filepath.Abs(inWorkingDir(workingDir, host)); err == nil {
+			host = abs
+		}
+		if relative {
+			target = host
+		}
+		volume := host + ":" + target
+		if readOnly {
+			volume += ":ro"
+		}
+		volumes = append(volumes, volume)
+	}
+	return volumes
+}
+
+// runUser returns the --user value for User: the current uid:gid for "host".
+func (c *ContainerConfig) runUser() string {
+	if c.User == "host" {
+		return fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid())
+	}
+	return c.User
+}
+
+// resolveRuntime returns the runtime the container packager runs, which must be on PATH.
+func (p *LinuxPkgPlugin) resolveRuntime(c *ContainerConfig) (string, error) {
+	runtime, err := p.containerRuntime(c.Runtime, "the container packager")
+	if err != nil {
+		return "", err
+	}
+	if _, err := p.getLookPath()(runtime); err != nil {
+		return "", fmt.Errorf("the container packager needs %s on PATH", runtime)
+	}
+	return runtime, nil
+}
+
+// runtimeName returns the runtime shown for the container packager before one is
+// resolved.
+func (c *ContainerConfig) runtimeName() string {
+	if c.Runtime == "" {
+		return "docker"
+	}
+	return c.Runtime
+}
+
+// containerMounts returns the directories a containerized build of configPath reads or
+// writes: the current directory, which nfpm resolves relative contents against, the
+// working directory, the output directory, the directory of the rendered config, and
+// the directory of every absolute file the config references, such as generated
+// scripts and changelogs. Each is mounted at the same path, so paths need no
+// translation. Directories inside another mount are left out.
+func containerMounts(cfg *Config, configPath string) []string {
+	var dirs []string
+	if cwd, err := os.Getwd(); err == nil {
+		dirs = append(dirs, cwd)
+	}
+	for _, dir := range []string{cfg.WorkingDir, cfg.OutputDir, filepath.Dir(configPath)} {
+		if dir == "" {
+			continue
+		}
+		if abs, err := filepath.Abs(dir); err == nil {
+			dirs = append(dirs, abs)
+		}
+	}
+	// A config that cannot be read fails the build in nfpm itself.
+	if doc, err := loadNfpmConfig(configPath); err == nil {
+		files, _ := referencedFiles(doc)
+		seen := make(map[string]bool)
+		for _, file := range files {
+			if dir := filepath.Dir(file); filepath.IsAbs(file) && !seen[dir] {
+				seen[dir] = true
+				dirs = append(dirs, dir)
+			}
+		}
+	}
+	sort.Strings(dirs)
+
+	var mounts []string
+	for _, dir := range dirs {
+		nested := false
+		for _, mount := range mounts {
+			if dir == mount || strings.HasPrefix(dir, strings.TrimSuffix(mount, "/")+"/") {
+				nested = true
+				break
+			}
+		}
+		if !nested {
+			mounts = append(mounts, dir)
+		}
+	}
+	return mounts
+}
+
+// containerEnvNames returns the names of the variables passed into the container: the
+// build environment and nfpm's signing passphrases. They are passed by name, so their
+// values never appear on the command line.
+func containerEnvNames(env []string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, kv := range env {
+		name, _, _ := strings.Cut(kv, "=")
+		add(name)
+	}
+	for _, kv := range os.Environ() {
+		if name, _, _ := strings.Cut(kv, "="); strings.HasPrefix(name, "NFPM_") {
+			add(name)
+		}
+	}
+	return names
+}
+
+// containerRunArgs returns the runtime arguments that run nfpm with args in a container
+// with mounts and the extra mounts of c, working in workdir.
+func containerRunArgs(c *ContainerConfig, mounts []string, workingDir, workdir string, envNames, args []string) []string {
+	run := []string{"run", "--rm", "-w", workdir}
+	for _, mount := range mounts {
+		run = append(run, "-v", mount+":"+mount)
+	}
+	for _, volume := range c.volumes(workingDir) {
+		run = append(run, "-v", volume)
+	}
+	if c.Network != "" {
+		run = append(run, "--network", c.Network)
+	}
+	if user := c.runUser(); user != "" {
+		run = append(run, "--user", user)
+	}
+	for _, name := range envNames {
+		run = append(run, "-e", name)
+	}
+	run = append(run, c.imageRef())
+	return append(run, args...)
+}
+
+// buildPackageContainer builds a single package by running nfpm in a container, the
+// way buildPackage runs the nfpm binary.
+func (p *LinuxPkgPlugin) buildPackageContainer(ctx context.Context, executor CommandExecutor, cfg *Config, configPath, format, target string, env []string, log io.Writer) ([]byte, error) {
+	workdir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current directory: %w", err)
+	}
+	spec := ExecSpec{
+		Name:   cfg.ContainerRuntime,
+		Args:   containerRunArgs(cfg.Container, containerMounts(cfg, configPath), cfg.WorkingDir, workdir, containerEnvNames(env), nfpmPackageArgs(configPath, format, target)),
+		Env:    env,
+		Output: log,
+	}
+	return runBuildCommand(ctx, executor, spec)
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestContainerMounts tests that nested directories share the mount of their parent.
+func TestContainerMounts(t *testing.T) {
+	t.Parallel()
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get current directory: %v", err)
+	}
+	cfg := &Config{WorkingDir: "/work", OutputDir: "/work/dist"}
+
+	mounts := containerMounts(cfg, "/tmp/linuxpkg-1/nfpm.yaml")
+	for _, want := range []string{"/work", "/tmp/linuxpkg-1", cwd} {
+		if !slices.Contains(mounts, want) {
+			t.Errorf("expected %s to be mounted, got %v", want, mounts)
+		}
+	}
+	if slices.Contains(mounts, "/work/dist") {
+		t.Errorf("expected the output directory to share the working directory mount, got %v", mounts)
+	}
+}
+
+// TestContainerRunArgs tests the runtime arguments of a containerized nfpm build.
+func TestContainerRunArgs(t *testing.T) {
+	t.Parallel()
+
+	c := &ContainerConfig{Image: "goreleaser/nfpm:v2.41.1"}
+	args := containerRunArgs(c, []string{"/work"}, "/work", "/work", []string{"VERSION"}, []string{"package", "--packager", "deb"})
+	expected := []string{"run", "--rm", "-w", "/work", "-v", "/work:/work", "-e", "VERSION", "goreleaser/nfpm:v2.41.1", "package", "--packager", "deb"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected %q, got %q", expected, args)
+	}
+}
+
+// TestExecuteContainer tests building packages with nfpm in a container.
+func TestExecuteContainer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		container       map[string]any
+		installed       []string
+		expectedRuntime string
+		expectError     string
+	}{
+		{"auto docker", nil, []string{"docker", "podman"}, "docker", ""},
+		{"auto podman", nil, []string{"podman"}, "podman", ""},
+		{"configured", map[string]any{"runtime": "podman", "image": "ghcr.io/goreleaser/nfpm:v2.41.1"}, []string{"docker", "podman"}, "podman", ""},
+		{"missing", nil, nil, "", "the container packager needs docker or podman"},
+		{"configured missing", map[string]any{"runtime": "podman"}, []string{"docker"}, "", "the container packager needs podman on PATH"},
+		{"invalid runtime", map[string]any{"runtime": "lxc"}, []string{"docker"}, "", `invalid container: invalid runtime "lxc"`},
+		{"invalid image", map[string]any{"image": "--privileged"}, []string{"docker"}, "", `invalid container: invalid image "--privileged"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: test\nversion: 1.0.0"), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+			config := map[string]any{"working_dir": dir, "formats": []string{"rpm"}, "packager": "container"}
+			if tt.container != nil {
+				config["container"] = tt.container
+			}
+
+			mock := &MockCommandExecutor{
+				RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+					return []byte("created package: " + args[len(args)-1] + "test.rpm"), nil
+				},
+			}
+			p := &LinuxPkgPlugin{
+				cmdExecutor: mock,
+				lookPath: func(file string) (string, error) {
+					if slices.Contains(tt.installed, file) {
+						return "/usr/bin/" + file, nil
+					}
+					return "", errors.New("not found")
+				},
+			}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook:    plugin.HookPostPublish,
+				Config:  config,
+				Context: plugin.ReleaseContext{Version: "1.0.0"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tt.expectError != "" {
+				if resp.Success || !strings.HasPrefix(resp.Error, tt.expectError) {
+					t.Errorf("expected error starting with %q, got %+v", tt.expectError, resp)
+				}
+				return
+			}
+			if !resp.Success {
+				t.Fatalf("expected success, got failure: %s", resp.Error)
+			}
+			if len(mock.Calls) != 1 || mock.Calls[0].Name != tt.expectedRuntime {
+				t.Fatalf("expected one %s run, got %v", tt.expectedRuntime, mock.Calls)
+			}
+			args := mock.Calls[0].Args
+			if !slices.Contains(args, dir+":"+dir) {
+				t.Errorf("expected the working directory to be mounted, got %q", args)
+			}
+			image := defaultContainerImage
+			if tt.container != nil && tt.container["image"] != nil {
+				image = tt.container["image"].(string)
+			}
+			if i := slices.Index(args, image); i < 0 || args[i+1] != "package" {
+				t.Errorf("expected nfpm package to run in %s, got %q", image, args)
+			}
+			expected := filepath.Join(dir, "dist") + "/test.rpm"
+			if packages := resp.Outputs["packages"].([]string); len(packages) != 1 || packages[0] != expected {
+				t.Errorf("expected package %s, got %v", expected, packages)
+			}
+		})
+	}
+}
+
+// TestExecuteContainerGeneratedFiles tests that rendered scripts and generated
+// changelogs, which live outside the working directory, are mounted into the container.
+func TestExecuteContainerGeneratedFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+	if err := os.WriteFile(filepath.Join(dir, "postinstall.sh.tmpl"), []byte("#!/bin/sh\necho myapp {{.Version}}\n"), 0644); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+
+	var referenced, mounts []string
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			for i, arg := range args {
+				if arg == "-v" {
+					host, _, _ := strings.Cut(args[i+1], ":")
+					mounts = append(mounts, host)
+				}
+			}
+			doc, err := loadNfpmConfig(args[slices.Index(args, "--config")+1])
+			if err != nil {
+				return nil, err
+			}
+			if referenced, err = referencedFiles(doc); err != nil {
+				return nil, err
+			}
+			return []byte("created package: " + args[len(args)-1] + "myapp.deb"), nil
+		},
+	}
+	p := &LinuxPkgPlugin{
+		cmdExecutor: mock,
+		lookPath: func(file string) (string, error) {
+			return "/usr/bin/" + file, nil
+		},
+	}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb"},
+			"packager":    "container",
+			"scripts":     map[string]any{"postinstall": "postinstall.sh.tmpl"},
+			"changelog":   true,
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3", ReleaseNotes: "- Fixed a crash"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected success, got %v, %+v", err, resp)
+	}
+
+	var generated int
+	for _, file := range referenced {
+		if strings.HasPrefix(file, dir+"/") {
+			continue
+		}
+		generated++
+		if !slices.ContainsFunc(mounts, func(mount string) bool { return strings.HasPrefix(file, mount+"/") }) {
+			t.Errorf("expected %s to be mounted, got %v", file, mounts)
+		}
+	}
+	if generated < 2 {
+		t.Errorf("expected a generated script and changelog, got %v", referenced)
+	}
+}
+
+// TestValidateContainer tests validating the container block and runtime.
+func TestValidateContainer(t *testing.T) {
+	t.Parallel()
+
+	p := &LinuxPkgPlugin{
+		lookPath:  func(string) (string, error) { return "", errors.New("not found") },
+		logOutput: io.Discard,
+	}
+
+	tests := []struct {
+		name   string
+		config map[string]any
+		fields []string
+	}{
+		{"defaults", map[string]any{"packager": "container"}, nil},
+		{"invalid runtime", map[string]any{"packager": "container", "container": map[string]any{"runtime": "lxc"}}, []string{"container"}},
+		{"not an object", map[string]any{"packager": "container", "container": "docker"}, []string{"container"}},
+		{"strict without runtime", map[string]any{"packager": "container", "strict": true}, []string{"packager", "config_path"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := p.Validate(context.Background(), tt.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(resp.Errors) != len(tt.fields) {
+				t.Fatalf("expected errors for %v, got %v", tt.fields, resp.Errors)
+			}
+			for i, field := range tt.fields {
+				if resp.Errors[i].Field != field {
+					t.Errorf("expected an error for %s, got %v", field, resp.Errors[i])
+				}
+			}
+		})
+	}
+}
+
+// TestContainerEngineOptions tests pinning the image by digest, extra mounts, the
+// network, and the user of containerized builds.
+func TestContainerEngineOptions(t *testing.T) {
+	t.Parallel()
+
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	c := parseContainer(map[string]any{"container": map[string]any{
+		"image":          "goreleaser/nfpm:v2.41.1",
+		"digest":         digest,
+		"require_digest": true,
+		"mounts":         []any{"vendor", "cache:/cache:ro", "/opt/assets"},
+		"network":        "none",
+		"user":           "1000:1000",
+	}})
+	if err := c.validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	args := containerRunArgs(c, []string{"/work"}, "/work", "/work", nil, []string{"package"})
+	expected := []string{"run", "--rm", "-w", "/work", "-v", "/work:/work",
+		"-v", "/work/vendor:/work/vendor", "-v", "/work/cache:/cache:ro", "-v", "/opt/assets:/opt/assets",
+		"--network", "none", "--user", "1000:1000",
+		"goreleaser/nfpm:v2.41.1@" + digest, "package"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected %q, got %q", expected, args)
+	}
+
+	c.User = "host"
+	if user := c.runUser(); user != strconv.Itoa(os.Getuid())+":"+strconv.Itoa(os.Getgid()) {
+		t.Errorf("expected the current uid:gid, got %q", user)
+	}
+}
+
+// TestContainerConfigValidate tests rejecting invalid container engine options.
+func TestContainerConfigValidate(t *testing.T) {
+	t.Parallel()
+
+	digest := "sha256:" + strings.Repeat("0", 64)
+	tests := []struct {
+		name        string
+		container   map[string]any
+		expectError string
+	}{
+		{"defaults", map[string]any{}, ""},
+		{"pinned image", map[string]any{"image": "goreleaser/nfpm@" + digest, "require_digest": true}, ""},
+		{"invalid digest", map[string]any{"digest": "sha256:abc"}, "invalid digest"},
+		{"digest twice", map[string]any{"image": "goreleaser/nfpm@" + digest, "digest": digest}, "already pinned by digest"},
+		{"unpinned", map[string]any{"require_digest": true}, "not pinned by digest"},
+		{"mount escapes working dir", map[string]any{"mounts": []any{"../secrets"}}, "invalid mount"},
+		{"relative container path", map[string]any{"mounts": []any{"cache:cache"}}, "container path must be absolute"},
+		{"invalid mount mode", map[string]any{"mounts": []any{"a:/a:ro:z"}}, "must be host[:container][:ro|rw]"},
+		{"invalid network", map[string]any{"network": "--privileged"}, "invalid network"},
+		{"invalid user", map[string]any{"user": "root"}, "invalid user"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := parseContainer(map[string]any{"container": tt.container}).validate()
+			if tt.expectError == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+				t.Errorf("expected error containing %q, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
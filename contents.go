@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// globbedContentTypes are nfpm content types whose src may be a glob of regular files.
+var globbedContentTypes = map[string]bool{
+	"":                 true,
+	"file":             true,
+	"config":           true,
+	"config|noreplace": true,
+}
+
+// contentEntries returns the nfpm contents list of a config document.
+func contentEntries(doc map[string]any) []any {
+	entries, _ := doc["contents"].([]any)
+	return entries
+}
+
+// formatContentEntries returns the contents lists nfpm installs for format: the top-level
+// contents and those of nfpm's own overrides for format.
+func formatContentEntries(doc map[string]any, format string) [][]any {
+	lists := [][]any{contentEntries(doc)}
+	if overrides, ok := doc["overrides"].(map[string]any); ok {
+		if override, ok := overrides[format].(map[string]any); ok {
+			lists = append(lists, contentEntries(override))
+		}
+	}
+	return lists
+}
+
+// contentDestinationMatches reports whether pattern matches the destination of a contents
+// entry, with environment references of expanded entries resolved with getenv. An entry
+// with a globbed source matches as a whole when its destination directory does, such as
+// /etc/myapp for /etc/myapp/**.
+func contentDestinationMatches(pattern *regexp.Regexp, entry map[string]any, getenv func(string) string) bool {
+	dst, _ := entry["dst"].(string)
+	if dst == "" {
+		return false
+	}
+	if expand, _ := entry["expand"].(bool); expand {
+		dst = os.Expand(dst, getenv)
+	}
+	dst = path.Clean("/" + dst)
+	src, _ := entry["src"].(string)
+	return pattern.MatchString(dst) || (hasGlobMeta(src) && pattern.MatchString(dst+"/"))
+}
+
+// expandContentGlobs replaces globbed contents entries with one entry per matched file,
+// dropping files excluded by the ignore matcher. Destinations mirror nfpm's own glob
+// handling: each file keeps its path relative to the static prefix of the pattern.
+func expandContentGlobs(doc map[string]any, ignore *ignoreMatcher) error {
+	entries := contentEntries(doc)
+	if entries == nil {
+		return nil
+	}
+
+	expanded := make([]any, 0, len(entries))
+	for _, raw := range entries {
+		entry, ok := raw.(map[string]any)
+		if !ok {
+			expanded = append(expanded, raw)
+			continue
+		}
+
+		src, _ := entry["src"].(string)
+		dst, _ := entry["dst"].(string)
+		entryType, _ := entry["type"].(string)
+		if src == "" || isRemoteSource(src) || !hasGlobMeta(src) || !globbedContentTypes[entryType] {
+			expanded = append(expanded, entry)
+			continue
+		}
+
+		matches, base, err := globFiles(src, ignore)
+		if err != nil {
+			return err
+		}
+
+		for _, match := range matches {
+			rel, err := filepath.Rel(base, match)
+			if err != nil {
+				return fmt.Errorf("failed to resolve content path %s: %w", match, err)
+			}
+
+			file := make(map[string]any, len(entry))
+			for k, v := range entry {
+				file[k] = v
+			}
+			file["src"] = filepath.ToSlash(match)
+			file["dst"] = path.Join(dst, filepath.ToSlash(rel))
+			expanded = append(expanded, file)
+		}
+	}
+
+	doc["contents"] = expanded
+	return nil
+}
+
+// globFiles returns the regular files matching a `**`-aware glob, excluding ignored paths,
+// along with the static directory prefix of the pattern.
+func globFiles(pattern string, ignore *ignoreMatcher) ([]string, string, error) {
+	pattern = filepath.ToSlash(filepath.Clean(pattern))
+
+	// The walk root is the longest leading run of path segments without glob metacharacters.
+	segments := strings.Split(pattern, "/")
+	static := make([]string, 0, len(segments))
+	for _, segment := range segments {
+		if hasGlobMeta(segment) {
+			break
+		}
+		static = append(static, segment)
+	}
+	base := "."
+	if len(static) > 0 {
+		base = strings.Join(static, "/")
+	}
+
+	re, err := regexp.Compile(globToRegexp(pattern))
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid content glob %q: %w", pattern, err)
+	}
+
+	var matches []string
+	err = filepath.WalkDir(filepath.FromSlash(base), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel := filepath.ToSlash(p)
+		if d.IsDir() {
+			if d.Name() == ".git" || (rel != base && ignore.Match(rel, true)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if re.MatchString(rel) && !ignore.Match(rel, false) {
+			matches = append(matches, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to expand content glob %q: %w", pattern, err)
+	}
+
+	sort.Strings(matches)
+	return matches, filepath.FromSlash(base), nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// defaultCOPRTimeout is how long to wait for COPR builds to finish.
+const defaultCOPRTimeout = "1h"
+
+// coprPollInterval is the delay between build status checks.
+var coprPollInterval = 30 * time.Second
+
+// coprProjectPattern validates COPR projects given as "owner/project" (or "@group/project").
+var coprProjectPattern = regexp.MustCompile(`^@?[A-Za-z0-9][A-Za-z0-9_.-]*/[A-Za-z0-9][A-Za-z0-9_.+-]*$`)
+
+// coprChrootPattern validates COPR chroot names such as "fedora-40-x86_64".
+var coprChrootPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.+-]*$`)
+
+// coprBuildIDPattern extracts build IDs from copr-cli output ("Created builds: 123 124").
+var coprBuildIDPattern = regexp.MustCompile(`Created builds: ([0-9 ]+)`)
+
+// coprFinalStates are the COPR build states that end polling, mapped to whether they
+// count as success.
+var coprFinalStates = map[string]bool{
+	"succeeded": true,
+	"skipped":   true,
+	"forked":    true,
+	"failed":    false,
+	"canceled":  false,
+}
+
+// COPRPublishConfig configures submitting builds to Fedora COPR with copr-cli.
+type COPRPublishConfig struct {
+	// Project is the COPR project as "owner/project".
+	Project string
+	// Chroots restricts the build to these chroots. Empty builds all project chroots.
+	Chroots []string
+	// SRPM is a source RPM to submit. Empty builds from the release tag instead.
+	SRPM string
+	// Timeout bounds how long to wait for builds to finish. "0" submits without waiting.
+	Timeout string
+}
+
+// parseCOPRPublish parses the publish.copr block. It returns nil when it is not set.
+func parseCOPRPublish(publish map[string]any) *COPRPublishConfig {
+	block := helpers.NewConfigParser(publish).GetMap("copr")
+	if block == nil {
+		return nil
+	}
+
+	parser := helpers.NewConfigParser(block)
+	return &COPRPublishConfig{
+		Project: parser.GetString("project", "", ""),
+		Chroots: parser.GetStringSlice("chroots", nil),
+		SRPM:    parser.GetString("srpm", "", ""),
+		Timeout: parser.GetString("timeout", "", defaultCOPRTimeout),
+	}
+}
+
+// validate checks the COPR publishing settings.
+func (c *COPRPublishConfig) validate() error {
+	if !coprProjectPattern.MatchString(c.Project) {
+		return fmt.Errorf("project must be in the form owner/project: %q", c.Project)
+	}
+	for _, chroot := range c.Chroots {
+		if !coprChrootPattern.MatchString(chroot) {
+			return fmt.Errorf("invalid chroot: %q", chroot)
+		}
+	}
+	if c.SRPM != "" {
+		if err := validatePath(c.SRPM); err != nil {
+			return fmt.Errorf("invalid srpm: %w", err)
+		}
+		if !strings.HasSuffix(c.SRPM, ".src.rpm") {
+			return fmt.Errorf("srpm must be a .src.rpm file: %s", c.SRPM)
+		}
+	}
+	if timeout, err := time.ParseDuration(c.Timeout); err != nil || timeout < 0 {
+		return fmt.Errorf("invalid timeout: %q", c.Timeout)
+	}
+	return nil
+}
+
+// buildArgs returns the copr-cli arguments that submit the build.
+func (c *COPRPublishConfig) buildArgs(release plugin.ReleaseContext) ([]string, error) {
+	var args []string
+	if c.SRPM != "" {
+		args = []string{"build", "--nowait"}
+	} else {
+		if release.RepositoryURL == "" || release.TagName == "" {
+			return nil, fmt.Errorf("building from the release tag requires a repository URL and tag name")
+		}
+		args = []string{"buildscm", "--nowait", "--clone-url", release.RepositoryURL, "--commit", release.TagName}
+	}
+	for _, chroot := range c.Chroots {
+		args = append(args, "-r", chroot)
+	}
+	args = append(args, c.Project)
+	if c.SRPM != "" {
+		args = append(args, c.SRPM)
+	}
+	return args, nil
+}
+
+// publishCOPR submits a COPR build, either from the configured SRPM or from the release
+// tag of the repository, and waits for it to finish unless the timeout is zero.
+func (p *LinuxPkgPlugin) publishCOPR(ctx context.Context, executor CommandExecutor, c *COPRPublishConfig, release plugin.ReleaseContext) (map[string]any, error) {
+	args, err := c.buildArgs(release)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := runCommand(ctx, executor, "copr-cli", args...)
+	if err != nil {
+		return nil, fmt.Errorf("copr-cli %s failed: %w\nOutput: %s", args[0], err, string(output))
+	}
+	match := coprBuildIDPattern.FindStringSubmatch(string(output))
+	if match == nil {
+		return nil, fmt.Errorf("could not find build ID in copr-cli output: %s", strings.TrimSpace(string(output)))
+	}
+	buildIDs := strings.Fields(match[1])
+
+	result := map[string]any{
+		"project": c.Project,
+		"builds":  buildIDs,
+	}
+
+	timeout, _ := time.ParseDuration(c.Timeout)
+	if timeout == 0 {
+		return result, nil
+	}
+
+	states, err := p.waitForCOPRBuilds(ctx, executor, buildIDs, timeout)
+	result["states"] = states
+	return result, err
+}
+
+// waitForCOPRBuilds polls `copr-cli status` until every build reaches a final state or
+// the timeout expires. It fails if any build did not succeed.
+func (p *LinuxPkgPlugin) waitForCOPRBuilds(ctx context.Context, executor CommandExecutor, buildIDs []string, timeout time.Duration) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	states := make(map[string]string, len(buildIDs))
+	for {
+		pending := 0
+		for _, id := range buildIDs {
+			if _, done := coprFinalStates[states[id]]; done {
+				continue
+			}
+			output, err := runCommand(ctx, executor, "copr-cli", "status", id)
+			if err != nil {
+				if ctx.Err() != nil {
+					break
+				}
+				return states, fmt.Errorf("copr-cli status %s failed: %w\nOutput: %s", id, err, string(output))
+			}
+			states[id] = strings.TrimSpace(string(output))
+			if _, done := coprFinalStates[states[id]]; !done {
+				pending++
+			}
+		}
+
+		if ctx.Err() == nil && pending == 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return states, fmt.Errorf("timed out after %s waiting for COPR builds %s", timeout, strings.Join(buildIDs, ", "))
+		case <-time.After(coprPollInterval):
+		}
+	}
+
+	for _, id := range buildIDs {
+		if !coprFinalStates[states[id]] {
+			return states, fmt.Errorf("COPR build %s %s", id, states[id])
+		}
+	}
+	return states, nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestValidatePublishCOPR tests validation of the publish.copr block.
+func TestValidatePublishCOPR(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		copr        map[string]any
+		expectValid bool
+	}{
+		{name: "project", copr: map[string]any{"project": "me/myapp"}, expectValid: true},
+		{name: "group project with srpm", copr: map[string]any{"project": "@team/myapp", "srpm": "dist/myapp-1.0.0-1.src.rpm"}, expectValid: true},
+		{name: "missing owner", copr: map[string]any{"project": "myapp"}, expectValid: false},
+		{name: "invalid chroot", copr: map[string]any{"project": "me/myapp", "chroots": []any{"--all"}}, expectValid: false},
+		{name: "srpm not a source rpm", copr: map[string]any{"project": "me/myapp", "srpm": "dist/myapp.rpm"}, expectValid: false},
+		{name: "invalid timeout", copr: map[string]any{"project": "me/myapp", "timeout": "soon"}, expectValid: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &LinuxPkgPlugin{}
+			resp, err := p.Validate(context.Background(), map[string]any{"publish": map[string]any{"copr": tc.copr}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Valid != tc.expectValid {
+				t.Errorf("expected valid=%v, got %v: %v", tc.expectValid, resp.Valid, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestPublishCOPR tests submitting builds and polling their status.
+// Note: This test cannot run in parallel because it shortens coprPollInterval.
+func TestPublishCOPR(t *testing.T) {
+	interval := coprPollInterval
+	coprPollInterval = time.Millisecond
+	t.Cleanup(func() { coprPollInterval = interval })
+
+	release := plugin.ReleaseContext{RepositoryURL: "https://github.com/example/myapp", TagName: "v1.0.0"}
+
+	tests := []struct {
+		name         string
+		cfg          *COPRPublishConfig
+		statuses     []string
+		expectSubmit string
+		expectError  string
+	}{
+		{
+			name:         "build from tag",
+			cfg:          &COPRPublishConfig{Project: "me/myapp", Chroots: []string{"fedora-40-x86_64"}, Timeout: "1m"},
+			statuses:     []string{"pending", "running", "succeeded"},
+			expectSubmit: "buildscm --nowait --clone-url https://github.com/example/myapp --commit v1.0.0 -r fedora-40-x86_64 me/myapp",
+		},
+		{
+			name:         "srpm",
+			cfg:          &COPRPublishConfig{Project: "me/myapp", SRPM: "dist/myapp-1.0.0-1.src.rpm", Timeout: "1m"},
+			statuses:     []string{"failed"},
+			expectSubmit: "build --nowait me/myapp dist/myapp-1.0.0-1.src.rpm",
+			expectError:  "COPR build 123 failed",
+		},
+		{
+			name:         "no wait",
+			cfg:          &COPRPublishConfig{Project: "me/myapp", Timeout: "0"},
+			expectSubmit: "buildscm --nowait --clone-url https://github.com/example/myapp --commit v1.0.0 me/myapp",
+		},
+		{
+			name:         "timeout",
+			cfg:          &COPRPublishConfig{Project: "me/myapp", Timeout: "20ms"},
+			statuses:     []string{"running"},
+			expectSubmit: "buildscm --nowait --clone-url https://github.com/example/myapp --commit v1.0.0 me/myapp",
+			expectError:  "timed out",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			polls := 0
+			mock := &MockCommandExecutor{
+				RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+					if args[0] != "status" {
+						return []byte("Uploading package...\nCreated builds: 123\n"), nil
+					}
+					if err := ctx.Err(); err != nil {
+						return nil, err
+					}
+					status := tc.statuses[min(polls, len(tc.statuses)-1)]
+					polls++
+					if status == "running" && tc.expectError != "" {
+						time.Sleep(5 * time.Millisecond)
+					}
+					return []byte(status + "\n"), nil
+				},
+			}
+
+			p := &LinuxPkgPlugin{}
+			result, err := p.publishCOPR(context.Background(), mock, tc.cfg, release)
+			if got := strings.Join(mock.Calls[0].Args, " "); got != tc.expectSubmit {
+				t.Errorf("expected submit %q, got %q", tc.expectSubmit, got)
+			}
+
+			if tc.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectError) {
+					t.Fatalf("expected error containing %q, got %v", tc.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if builds := result["builds"].([]string); len(builds) != 1 || builds[0] != "123" {
+				t.Errorf("unexpected builds: %v", builds)
+			}
+			if len(tc.statuses) > 0 {
+				if polls != len(tc.statuses) {
+					t.Errorf("expected %d status polls, got %d", len(tc.statuses), polls)
+				}
+				if states := result["states"].(map[string]string); states["123"] != "succeeded" {
+					t.Errorf("unexpected states: %v", states)
+				}
+			}
+		})
+	}
+}
+
+// TestPublishCOPRSubmitFailure tests that a failed submission is reported.
+func TestPublishCOPRSubmitFailure(t *testing.T) {
+	t.Parallel()
+
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("Error: Login invalid/expired"), errors.New("exit status 1")
+		},
+	}
+
+	p := &LinuxPkgPlugin{}
+	cfg := &COPRPublishConfig{Project: "me/myapp", Timeout: "1m"}
+	_, err := p.publishCOPR(context.Background(), mock, cfg, plugin.ReleaseContext{RepositoryURL: "https://github.com/example/myapp", TagName: "v1.0.0"})
+	if err == nil || !strings.Contains(err.Error(), "Login invalid") {
+		t.Errorf("expected submit error with output, got %v", err)
+	}
+}
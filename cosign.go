@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// Allowed cosign signing modes.
+var allowedCosignModes = map[string]bool{
+	"keyless": true,
+	"key":     true,
+}
+
+// CosignConfig configures signing package files with `cosign sign-blob`.
+type CosignConfig struct {
+	// Mode is keyless (OIDC identity, Fulcio certificate) or key (a cosign private key).
+	Mode string
+	// Key is the private key path or KMS/env reference used in key mode.
+	// The key password is read by cosign from COSIGN_PASSWORD.
+	Key string
+	// TlogUpload records each signature in a Rekor transparency log. Keyless mode requires it.
+	TlogUpload bool
+	// RekorURL overrides the Rekor instance, e.g. for air-gapped setups. Empty uses cosign's default.
+	RekorURL string
+}
+
+// cosignResult lists the files written when signing one package.
+type cosignResult struct {
+	// Signature is the path of the base64 signature (<package>.sig).
+	Signature string
+	// Certificate is the path of the signing certificate (<package>.pem), keyless mode only.
+	Certificate string
+	// Bundle is the path of the cosign bundle (<package>.bundle), written when uploading to Rekor.
+	Bundle string
+	// LogIndex is the Rekor log index of the signature entry.
+	LogIndex int64
+	// UUID is the Rekor entry UUID (the hex leaf hash of the entry).
+	UUID string
+}
+
+// parseCosign parses the cosign block. It returns nil when cosign signing is not configured.
+func parseCosign(raw map[string]any) *CosignConfig {
+	block := helpers.NewConfigParser(raw).GetMap("cosign")
+	if block == nil {
+		return nil
+	}
+
+	parser := helpers.NewConfigParser(block)
+	return &CosignConfig{
+		Mode:       parser.GetString("mode", "", "keyless"),
+		Key:        parser.GetString("key", "", ""),
+		TlogUpload: parser.GetBool("tlog_upload", true),
+		RekorURL:   parser.GetString("rekor_url", "", ""),
+	}
+}
+
+// isKeyReference reports whether a cosign key is a provider reference (e.g. awskms://, env://)
+// rather than a file path.
+func isKeyReference(key string) bool {
+	return strings.Contains(key, "://")
+}
+
+// validate checks that the cosign settings are complete for the selected mode.
+func (c *CosignConfig) validate() error {
+	if !allowedCosignModes[c.Mode] {
+		return fmt.Errorf("unsupported cosign mode: %s (allowed: %s)", c.Mode, strings.Join(sortedKeys(allowedCosignModes), ", "))
+	}
+
+	switch c.Mode {
+	case "key":
+		if c.Key == "" {
+			return fmt.Errorf("key is required for key mode")
+		}
+		if !isKeyReference(c.Key) {
+			if err := validatePath(c.Key); err != nil {
+				return fmt.Errorf("invalid key: %w", err)
+			}
+		}
+	case "keyless":
+		if c.Key != "" {
+			return fmt.Errorf("key cannot be used in keyless mode")
+		}
+		if !c.TlogUpload {
+			return fmt.Errorf("keyless mode requires tlog_upload")
+		}
+	}
+
+	if c.RekorURL != "" {
+		u, err := url.Parse(c.RekorURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("rekor_url must be an http(s) URL: %s", c.RekorURL)
+		}
+	}
+
+	return nil
+}
+
+// signBlobArgs returns the cosign arguments that sign path, along with the files the
+// signature, certificate, and bundle are written to.
+func (c *CosignConfig) signBlobArgs(path string) (*cosignResult, []string) {
+	result := &cosignResult{Signature: path + ".sig"}
+
+	args := []string{"sign-blob", "--yes", "--output-signature", result.Signature}
+	if c.Mode == "key" {
+		args = append(args, "--key", c.Key)
+	} else {
+		result.Certificate = path + ".pem"
+		args = append(args, "--output-certificate", result.Certificate)
+	}
+	if c.TlogUpload {
+		result.Bundle = path + ".bundle"
+		args = append(args, "--bundle", result.Bundle)
+		if c.RekorURL != "" {
+			args = append(args, "--rekor-url", c.RekorURL)
+		}
+	} else {
+		args = append(args, "--tlog-upload=false")
+	}
+	return result, append(args, path)
+}
+
+// cosignSign signs a package with `cosign sign-blob`, writing the signature (and in
+// keyless mode the certificate) next to it. When uploading to Rekor, the bundle is kept
+// next to the package and the log entry is reported. The tool output is returned for logging.
+func (p *LinuxPkgPlugin) cosignSign(ctx context.Context, executor CommandExecutor, c *CosignConfig, path string) (*cosignResult, []byte, error) {
+	result, args := c.signBlobArgs(path)
+	output, err := runCommand(ctx, executor, "cosign", args...)
+	if err != nil {
+		return nil, output, fmt.Errorf("cosign failed to sign %s: %w", path, err)
+	}
+
+	if result.Bundle != "" {
+		data, err := os.ReadFile(result.Bundle)
+		if err != nil {
+			return nil, output, fmt.Errorf("failed to read cosign bundle: %w", err)
+		}
+		if result.LogIndex, result.UUID, err = parseRekorEntry(data); err != nil {
+			return nil, output, err
+		}
+	}
+
+	return result, output, nil
+}
+
+// cosignBundle covers the fields read from both the legacy cosign bundle and the
+// Sigstore bundle format.
+type cosignBundle struct {
+	RekorBundle *struct {
+		Payload struct {
+			Body     string `json:"body"`
+			LogIndex int64  `json:"logIndex"`
+		} `json:"Payload"`
+	} `json:"rekorBundle"`
+	VerificationMaterial *struct {
+		TlogEntries []struct {
+			LogIndex          string `json:"logIndex"`
+			CanonicalizedBody string `json:"canonicalizedBody"`
+		} `json:"tlogEntries"`
+	} `json:"verificationMaterial"`
+}
+
+// parseRekorEntry extracts the Rekor log index and entry UUID from a cosign bundle.
+func parseRekorEntry(data []byte) (int64, string, error) {
+	var bundle cosignBundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return 0, "", fmt.Errorf("failed to parse cosign bundle: %w", err)
+	}
+
+	var (
+		logIndex int64
+		body     string
+	)
+	switch {
+	case bundle.RekorBundle != nil:
+		logIndex = bundle.RekorBundle.Payload.LogIndex
+		body = bundle.RekorBundle.Payload.Body
+	case bundle.VerificationMaterial != nil && len(bundle.VerificationMaterial.TlogEntries) > 0:
+		entry := bundle.VerificationMaterial.TlogEntries[0]
+		index, err := strconv.ParseInt(entry.LogIndex, 10, 64)
+		if err != nil {
+			return 0, "", fmt.Errorf("invalid log index in cosign bundle: %w", err)
+		}
+		logIndex = index
+		body = entry.CanonicalizedBody
+	default:
+		return 0, "", fmt.Errorf("cosign bundle has no transparency log entry")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid entry body in cosign bundle: %w", err)
+	}
+
+	// Rekor entry UUIDs are the RFC 6962 leaf hash of the canonicalized entry body.
+	leaf := sha256.Sum256(append([]byte{0}, decoded...))
+	return logIndex, hex.EncodeToString(leaf[:]), nil
+}
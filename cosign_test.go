@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// testRekorBody is the canonicalized Rekor entry body used by fake cosign bundles.
+const testRekorBody = `{"apiVersion":"0.0.1","kind":"hashedrekord"}`
+
+// fakeCosign mimics cosign sign-blob by writing a legacy bundle when --bundle is passed.
+func fakeCosign(ctx context.Context, name string, args ...string) ([]byte, error) {
+	for i, arg := range args {
+		if arg == "--bundle" && i+1 < len(args) {
+			bundle := `{"rekorBundle": {"Payload": {"body": "` + base64.StdEncoding.EncodeToString([]byte(testRekorBody)) + `", "logIndex": 4242}}}`
+			if err := os.WriteFile(args[i+1], []byte(bundle), 0644); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, nil
+}
+
+// testRekorUUID returns the expected entry UUID for testRekorBody.
+func testRekorUUID() string {
+	leaf := sha256.Sum256(append([]byte{0}, testRekorBody...))
+	return hex.EncodeToString(leaf[:])
+}
+
+// TestValidateCosign tests validation of the cosign block.
+func TestValidateCosign(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		cosign      any
+		expectValid bool
+	}{
+		{name: "keyless default", cosign: map[string]any{}, expectValid: true},
+		{name: "key file", cosign: map[string]any{"mode": "key", "key": "cosign.key"}, expectValid: true},
+		{name: "kms key", cosign: map[string]any{"mode": "key", "key": "awskms:///alias/release"}, expectValid: true},
+		{name: "key mode without key", cosign: map[string]any{"mode": "key"}, expectValid: false},
+		{name: "key path traversal", cosign: map[string]any{"mode": "key", "key": "../cosign.key"}, expectValid: false},
+		{name: "key in keyless mode", cosign: map[string]any{"key": "cosign.key"}, expectValid: false},
+		{name: "unknown mode", cosign: map[string]any{"mode": "pgp"}, expectValid: false},
+		{name: "key without tlog", cosign: map[string]any{"mode": "key", "key": "cosign.key", "tlog_upload": false}, expectValid: true},
+		{name: "keyless without tlog", cosign: map[string]any{"tlog_upload": false}, expectValid: false},
+		{name: "custom rekor", cosign: map[string]any{"rekor_url": "https://rekor.internal.example.com"}, expectValid: true},
+		{name: "invalid rekor url", cosign: map[string]any{"rekor_url": "rekor.internal"}, expectValid: false},
+		{name: "not an object", cosign: true, expectValid: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &LinuxPkgPlugin{}
+			resp, err := p.Validate(context.Background(), map[string]any{"cosign": tc.cosign})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Valid != tc.expectValid {
+				t.Errorf("expected valid=%v, got %v: %v", tc.expectValid, resp.Valid, resp.Errors)
+			}
+			if !tc.expectValid && (len(resp.Errors) == 0 || resp.Errors[0].Field != "cosign") {
+				t.Errorf("expected error on field cosign, got %v", resp.Errors)
+			}
+		})
+	}
+}
+
+// TestCosignSign tests the cosign sign-blob invocation for each mode.
+func TestCosignSign(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		cosign         *CosignConfig
+		expectedArgs   string
+		expectedCert   string
+		expectedBundle bool
+	}{
+		{
+			name:           "keyless",
+			cosign:         &CosignConfig{Mode: "keyless", TlogUpload: true},
+			expectedArgs:   "sign-blob --yes --output-signature {dir}/myapp.deb.sig --output-certificate {dir}/myapp.deb.pem --bundle {dir}/myapp.deb.bundle {dir}/myapp.deb",
+			expectedCert:   "{dir}/myapp.deb.pem",
+			expectedBundle: true,
+		},
+		{
+			name:         "key without tlog",
+			cosign:       &CosignConfig{Mode: "key", Key: "cosign.key"},
+			expectedArgs: "sign-blob --yes --output-signature {dir}/myapp.deb.sig --key cosign.key --tlog-upload=false {dir}/myapp.deb",
+		},
+		{
+			name:           "key with custom rekor",
+			cosign:         &CosignConfig{Mode: "key", Key: "cosign.key", TlogUpload: true, RekorURL: "https://rekor.example.com"},
+			expectedArgs:   "sign-blob --yes --output-signature {dir}/myapp.deb.sig --key cosign.key --bundle {dir}/myapp.deb.bundle --rekor-url https://rekor.example.com {dir}/myapp.deb",
+			expectedBundle: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			expand := func(s string) string { return strings.ReplaceAll(s, "{dir}", dir) }
+			mock := &MockCommandExecutor{RunFunc: fakeCosign}
+
+			p := &LinuxPkgPlugin{}
+			result, _, err := p.cosignSign(context.Background(), mock, tc.cosign, filepath.Join(dir, "myapp.deb"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(mock.Calls) != 1 || mock.Calls[0].Name != "cosign" {
+				t.Fatalf("expected one cosign call, got %v", mock.Calls)
+			}
+			if args := strings.Join(mock.Calls[0].Args, " "); args != expand(tc.expectedArgs) {
+				t.Errorf("expected args %q, got %q", expand(tc.expectedArgs), args)
+			}
+			if result.Signature != expand("{dir}/myapp.deb.sig") || result.Certificate != expand(tc.expectedCert) {
+				t.Errorf("unexpected result: %+v", result)
+			}
+			if tc.expectedBundle {
+				if result.LogIndex != 4242 || result.UUID != testRekorUUID() {
+					t.Errorf("unexpected rekor entry: %d %s", result.LogIndex, result.UUID)
+				}
+			} else if result.Bundle != "" {
+				t.Errorf("expected no bundle, got %s", result.Bundle)
+			}
+		})
+	}
+}
+
+// TestParseRekorEntry tests reading log entries from both cosign bundle formats.
+func TestParseRekorEntry(t *testing.T) {
+	t.Parallel()
+
+	body := base64.StdEncoding.EncodeToString([]byte(testRekorBody))
+	tests := []struct {
+		name      string
+		bundle    string
+		expectErr bool
+	}{
+		{
+			name:   "legacy bundle",
+			bundle: `{"base64Signature": "c2ln", "rekorBundle": {"Payload": {"body": "` + body + `", "logIndex": 4242}}}`,
+		},
+		{
+			name:   "sigstore bundle",
+			bundle: `{"verificationMaterial": {"tlogEntries": [{"logIndex": "4242", "canonicalizedBody": "` + body + `"}]}}`,
+		},
+		{name: "no tlog entry", bundle: `{"base64Signature": "c2ln"}`, expectErr: true},
+		{name: "invalid json", bundle: `{`, expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			logIndex, uuid, err := parseRekorEntry([]byte(tc.bundle))
+			if tc.expectErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if logIndex != 4242 || uuid != testRekorUUID() {
+				t.Errorf("unexpected entry: %d %s", logIndex, uuid)
+			}
+		})
+	}
+}
+
+// TestExecuteWithCosign tests that every package is signed and recorded in outputs.
+func TestExecuteWithCosign(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+
+	mock := &MockCommandExecutor{RunFunc: fakeCosign}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []any{"deb", "rpm"},
+			"cosign":      map[string]any{},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	signatures := resp.Outputs["signatures"].([]string)
+	if len(signatures) != 2 || len(mock.Calls) != 2 {
+		t.Fatalf("expected both packages to be signed, got %v", signatures)
+	}
+	for _, artifact := range resp.Outputs["artifacts"].([]map[string]any) {
+		path := artifact["path"].(string)
+		if artifact["signature"] != path+".sig" || artifact["certificate"] != path+".pem" {
+			t.Errorf("unexpected signature files on %v", artifact)
+		}
+		if artifact["rekor_log_index"] != int64(4242) || artifact["rekor_uuid"] != testRekorUUID() {
+			t.Errorf("expected rekor entry on %v", artifact)
+		}
+	}
+
+	t.Run("signing failure fails the build", func(t *testing.T) {
+		mock := &MockCommandExecutor{
+			RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				return []byte("error: no identity token"), errors.New("exit status 1")
+			},
+		}
+		p := &LinuxPkgPlugin{cmdExecutor: mock}
+
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook: plugin.HookPostPublish,
+			Config: map[string]any{
+				"working_dir": dir,
+				"formats":     []any{"deb"},
+				"cosign":      map[string]any{},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Success || !strings.Contains(resp.Error, "no identity token") {
+			t.Errorf("expected cosign failure, got %+v", resp)
+		}
+	})
+}
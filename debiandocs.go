@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// debianCopyrightFormat is the URI of the machine-readable copyright format (DEP-5).
+const debianCopyrightFormat = "https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/"
+
+// debianCommonLicenses maps SPDX identifiers of the licenses Debian ships in
+// /usr/share/common-licenses to their DEP-5 short names.
+var debianCommonLicenses = map[string]string{
+	"Apache-2.0":        "Apache-2.0",
+	"CC0-1.0":           "CC0-1.0",
+	"GPL-2.0":           "GPL-2",
+	"GPL-2.0-only":      "GPL-2",
+	"GPL-2.0-or-later":  "GPL-2+",
+	"GPL-3.0":           "GPL-3",
+	"GPL-3.0-only":      "GPL-3",
+	"GPL-3.0-or-later":  "GPL-3+",
+	"LGPL-2.1":          "LGPL-2.1",
+	"LGPL-2.1-only":     "LGPL-2.1",
+	"LGPL-2.1-or-later": "LGPL-2.1+",
+	"LGPL-3.0":          "LGPL-3",
+	"LGPL-3.0-only":     "LGPL-3",
+	"LGPL-3.0-or-later": "LGPL-3+",
+	"MPL-2.0":           "MPL-2.0",
+}
+
+// DebianDocsConfig installs the changelog and copyright files Debian policy requires
+// under /usr/share/doc/<name> in deb packages.
+type DebianDocsConfig struct {
+	// Copyright is the copyright holder. Defaults to the nfpm vendor, else the maintainer.
+	Copyright string
+	// LicenseFile holds the full license text, required for licenses Debian does not ship
+	// in /usr/share/common-licenses.
+	LicenseFile string
+}
+
+// parseDebianDocs parses the debian_docs option, either true or an object. It returns nil
+// when the files are not generated.
+func parseDebianDocs(raw map[string]any) *DebianDocsConfig {
+	if enabled, ok := raw["debian_docs"].(bool); ok {
+		if !enabled {
+			return nil
+		}
+		return &DebianDocsConfig{}
+	}
+	block := helpers.NewConfigParser(raw).GetMap("debian_docs")
+	if block == nil {
+		return nil
+	}
+
+	parser := helpers.NewConfigParser(block)
+	return &DebianDocsConfig{
+		Copyright:   parser.GetString("copyright", "", ""),
+		LicenseFile: parser.GetString("license_file", "", ""),
+	}
+}
+
+// validateDebianDocs checks the debian_docs option against the formats.
+func validateDebianDocs(raw map[string]any, formats []string) error {
+	value, ok := raw["debian_docs"]
+	if !ok || value == nil {
+		return nil
+	}
+	switch value.(type) {
+	case bool, map[string]any:
+	default:
+		return fmt.Errorf("debian_docs must be a boolean or an object")
+	}
+
+	if docs := parseDebianDocs(raw); docs != nil {
+		return docs.validate(formats)
+	}
+	return nil
+}
+
+// validate checks that deb packages are built and the license file stays in the working
+// directory.
+func (d *DebianDocsConfig) validate(formats []string) error {
+	if !slices.Contains(formats, "deb") {
+		return fmt.Errorf("requires the deb format")
+	}
+	if strings.ContainsAny(d.Copyright, "\n") {
+		return fmt.Errorf("copyright must be a single line")
+	}
+	return validatePath(d.LicenseFile)
+}
+
+// debianCopyright returns the DEP-5 copyright file of the package described by the nfpm
+// fields, for a release on date. The holder is prefixed with the release year unless it
+// starts with years of its own.
+func (d *DebianDocsConfig) debianCopyright(name, license, vendor, maintainer, homepage string, date time.Time) (string, error) {
+	if license == "" {
+		return "", fmt.Errorf("debian_docs needs a license in the nfpm config")
+	}
+	holder := d.Copyright
+	if holder == "" {
+		holder = vendor
+	}
+	if holder == "" {
+		holder = maintainer
+	}
+	if holder == "" {
+		return "", fmt.Errorf("debian_docs needs a copyright holder: set vendor or maintainer in the nfpm config, or debian_docs.copyright")
+	}
+	if holder[0] < '0' || holder[0] > '9' {
+		holder = fmt.Sprintf("%d %s", date.Year(), holder)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Format: %s\n", debianCopyrightFormat)
+	fmt.Fprintf(&b, "Upstream-Name: %s\n", name)
+	if maintainer != "" {
+		fmt.Fprintf(&b, "Upstream-Contact: %s\n", maintainer)
+	}
+	if homepage != "" {
+		fmt.Fprintf(&b, "Source: %s\n", homepage)
+	}
+	fmt.Fprintf(&b, "\nFiles: *\nCopyright: %s\n", holder)
+
+	shortName, common := debianCommonLicenses[license]
+	switch {
+	case d.LicenseFile != "":
+		text, err := os.ReadFile(d.LicenseFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read license file: %w", err)
+		}
+		fmt.Fprintf(&b, "License: %s\n", license)
+		// Continuation lines are indented, and empty lines are written as " .".
+		for _, line := range strings.Split(strings.TrimRight(strings.ReplaceAll(string(text), "\r\n", "\n"), "\n"), "\n") {
+			if strings.TrimSpace(line) == "" {
+				b.WriteString(" .\n")
+				continue
+			}
+			fmt.Fprintf(&b, " %s\n", strings.TrimRight(line, " \t"))
+		}
+	case common:
+		fmt.Fprintf(&b, "License: %s\n", shortName)
+		fmt.Fprintf(&b, " On Debian systems, the complete text of this license can be found in\n /usr/share/common-licenses/%s.\n", strings.TrimSuffix(shortName, "+"))
+	default:
+		return "", fmt.Errorf("license %s is not in /usr/share/common-licenses: set debian_docs.license_file to its text", license)
+	}
+	return b.String(), nil
+}
+
+// addDebianCopyright installs a DEP-5 copyright file at /usr/share/doc/<name>/copyright
+// into the package a deb job builds, unless its contents already install one. The
+// returned cleanup function removes the file and staged config.
+func addDebianCopyright(job buildJob, docs *DebianDocsConfig, data *nfpmTemplateData) (buildJob, func(), error) {
+	noop := func() {}
+	doc, err := loadNfpmConfig(job.ConfigPath)
+	if err != nil {
+		return job, noop, err
+	}
+	getenv := envLookup(job.Env, os.Getenv)
+	info, _, err := resolvePackageInfo(job.ConfigPath, job.Format, "", getenv)
+	if err != nil {
+		return job, noop, err
+	}
+
+	dst := path.Join("/usr/share/doc", info.Name, "copyright")
+	for _, content := range info.Contents {
+		if path.Clean(content.Destination) == dst {
+			return job, noop, nil
+		}
+	}
+
+	date, err := time.Parse(time.RFC3339, data.Date)
+	if err != nil {
+		return job, noop, fmt.Errorf("invalid copyright date %q: %w", data.Date, err)
+	}
+	copyright, err := docs.debianCopyright(info.Name, info.License, info.Vendor, info.Maintainer, info.Homepage, date)
+	if err != nil {
+		return job, noop, err
+	}
+
+	dir, err := os.MkdirTemp("", "linuxpkg-copyright-")
+	if err != nil {
+		return job, noop, fmt.Errorf("failed to create copyright directory: %w", err)
+	}
+	removeDir := func() { _ = os.RemoveAll(dir) }
+	src := filepath.Join(dir, "copyright")
+	if err := os.WriteFile(src, []byte(copyright), 0644); err != nil {
+		removeDir()
+		return job, noop, fmt.Errorf("failed to write copyright file: %w", err)
+	}
+
+	doc["contents"] = append(contentEntries(doc), map[string]any{
+		"src":       src,
+		"dst":       dst,
+		"type":      "file",
+		"file_info": map[string]any{"mode": 0644},
+	})
+	staged, cleanup, err := stageNfpmConfig(doc)
+	if err != nil {
+		removeDir()
+		return job, noop, err
+	}
+	job.ConfigPath = staged
+	return job, func() { cleanup(); removeDir() }, nil
+}
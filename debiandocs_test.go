@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestExecuteDebianDocs tests installing a changelog and copyright file into every deb.
+func TestExecuteDebianDocs(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeTestNfpmConfig(t, dir, "arch: amd64\nlicense: MIT\n", map[string]string{
+		"/usr/bin/myapp":              "#!/bin/sh\necho hello\n",
+		"/usr/share/doc/myapp/README": "docs\n",
+	})
+	if err := os.WriteFile(filepath.Join(dir, "LICENSE"), []byte("MIT License\n\nPermission is hereby granted.\n"), 0644); err != nil {
+		t.Fatalf("failed to write LICENSE: %v", err)
+	}
+
+	p := &LinuxPkgPlugin{cmdExecutor: &MockCommandExecutor{}}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []any{"deb", "rpm"},
+			"debian_docs": map[string]any{"license_file": "LICENSE"},
+			"subpackages": []any{
+				map[string]any{"name": "myapp-doc", "contents": []any{"/usr/share/doc/**"}},
+			},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected success, got %v, %+v", err, resp)
+	}
+
+	artifacts := resp.Outputs["artifacts"].([]map[string]any)
+	if len(artifacts) != 4 {
+		t.Fatalf("expected 4 artifacts, got %v", artifacts)
+	}
+	for _, artifact := range artifacts {
+		path, format := artifact["path"].(string), artifact["format"].(string)
+		name := "myapp"
+		if subpackage, _ := artifact["subpackage"].(string); subpackage != "" {
+			name = subpackage
+		}
+		contents, err := readPackageContents(path, format, false)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", path, err)
+		}
+		for _, file := range []string{"changelog.Debian.gz", "copyright"} {
+			_, ok := contents.Files["/usr/share/doc/"+name+"/"+file]
+			if format == "deb" && !ok {
+				t.Errorf("expected %s in %s, got %v", file, path, contents.Files)
+			}
+			if format == "rpm" && ok {
+				t.Errorf("expected no %s in %s", file, path)
+			}
+		}
+	}
+}
+
+// TestDebianCopyright tests the generated DEP-5 copyright file.
+func TestDebianCopyright(t *testing.T) {
+	t.Parallel()
+
+	licenseFile := filepath.Join(t.TempDir(), "LICENSE")
+	if err := os.WriteFile(licenseFile, []byte("MIT License\r\n\r\nPermission is hereby granted.\r\n"), 0644); err != nil {
+		t.Fatalf("failed to write license: %v", err)
+	}
+	date := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		docs        DebianDocsConfig
+		license     string
+		vendor      string
+		expect      []string
+		expectError string
+	}{
+		{
+			name:    "common license",
+			license: "GPL-2.0-or-later",
+			vendor:  "Relicta",
+			expect: []string{
+				"Format: " + debianCopyrightFormat + "\n",
+				"Upstream-Name: myapp\nUpstream-Contact: Relicta Team <team@example.com>\nSource: https://example.com\n",
+				"\nFiles: *\nCopyright: 2024 Relicta\nLicense: GPL-2+\n",
+				"/usr/share/common-licenses/GPL-2.\n",
+			},
+		},
+		{
+			name:    "license file",
+			docs:    DebianDocsConfig{Copyright: "2019-2024 Relicta Authors", LicenseFile: licenseFile},
+			license: "MIT",
+			expect:  []string{"Copyright: 2019-2024 Relicta Authors\nLicense: MIT\n MIT License\n .\n Permission is hereby granted.\n"},
+		},
+		{
+			name:    "maintainer holder",
+			license: "Apache-2.0",
+			expect:  []string{"Copyright: 2024 Relicta Team <team@example.com>\n"},
+		},
+		{name: "no license", expectError: "needs a license"},
+		{name: "uncommon license", license: "MIT", expectError: "set debian_docs.license_file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			copyright, err := tt.docs.debianCopyright("myapp", tt.license, tt.vendor, "Relicta Team <team@example.com>", "https://example.com", date)
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Errorf("expected error %q, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, expect := range tt.expect {
+				if !strings.Contains(copyright, expect) {
+					t.Errorf("expected %q in:\n%s", expect, copyright)
+				}
+			}
+		})
+	}
+}
+
+// TestValidateDebianDocs tests the debian_docs option.
+func TestValidateDebianDocs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		config      map[string]any
+		expectError string
+	}{
+		{"enabled", map[string]any{"debian_docs": true}, ""},
+		{"object", map[string]any{"debian_docs": map[string]any{"copyright": "Relicta", "license_file": "LICENSE"}}, ""},
+		{"invalid type", map[string]any{"debian_docs": "yes"}, "must be a boolean or an object"},
+		{"no deb", map[string]any{"debian_docs": true, "formats": []any{"rpm"}}, "requires the deb format"},
+		{"license file escape", map[string]any{"debian_docs": map[string]any{"license_file": "../LICENSE"}}, "path traversal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			expectFieldError(t, tt.config, "debian_docs", tt.expectError)
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"debug/elf"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// debugPackageSuffixes are the suffixes debug packages add to the package name, by the
+// formats that get them.
+var debugPackageSuffixes = map[string]string{
+	"deb": "-dbgsym",
+	"rpm": "-debuginfo",
+}
+
+// DebugSymbolsConfig splits the debug symbols of packaged ELF files into companion
+// -dbgsym debs and -debuginfo rpms.
+type DebugSymbolsConfig struct {
+	// Objcopy is the objcopy binary, such as llvm-objcopy for binaries of other
+	// architectures.
+	Objcopy string
+}
+
+// parseDebugSymbols parses the debug_symbols option, either true or an object. It returns
+// nil when debug symbols are left in place.
+func parseDebugSymbols(raw map[string]any) *DebugSymbolsConfig {
+	if enabled, ok := raw["debug_symbols"].(bool); ok {
+		if !enabled {
+			return nil
+		}
+		return &DebugSymbolsConfig{Objcopy: "objcopy"}
+	}
+	block := helpers.NewConfigParser(raw).GetMap("debug_symbols")
+	if block == nil {
+		return nil
+	}
+
+	parser := helpers.NewConfigParser(block)
+	return &DebugSymbolsConfig{
+		Objcopy: parser.GetString("objcopy", "", "objcopy"),
+	}
+}
+
+// validateDebugSymbols checks the debug_symbols option against the formats and filename
+// template.
+func validateDebugSymbols(raw map[string]any, formats []string, filenameTemplate string) error {
+	value, ok := raw["debug_symbols"]
+	if !ok || value == nil {
+		return nil
+	}
+	switch value.(type) {
+	case bool, map[string]any:
+	default:
+		return fmt.Errorf("debug_symbols must be a boolean or an object")
+	}
+
+	if debug := parseDebugSymbols(raw); debug != nil {
+		return debug.validate(formats, filenameTemplate)
+	}
+	return nil
+}
+
+// validate checks that debug packages can be built and told apart by their file names.
+func (d *DebugSymbolsConfig) validate(formats []string, filenameTemplate string) error {
+	if !slices.ContainsFunc(formats, func(format string) bool { return debugPackageSuffixes[format] != "" }) {
+		return fmt.Errorf("requires the deb or rpm format")
+	}
+	if filenameTemplate != "" && !strings.Contains(filenameTemplate, "{name}") {
+		return fmt.Errorf("filename_template must contain {name} to tell debug packages apart")
+	}
+	if d.Objcopy == "" {
+		return fmt.Errorf("objcopy must not be empty")
+	}
+	return nil
+}
+
+// elfDebugInfo reports whether path is an ELF file with debug sections, and returns its
+// GNU build ID, empty when it has none.
+func elfDebugInfo(path string) (string, bool) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	if !slices.ContainsFunc(f.Sections, func(section *elf.Section) bool {
+		return strings.HasPrefix(section.Name, ".debug_") || strings.HasPrefix(section.Name, ".zdebug_")
+	}) {
+		return "", false
+	}
+	return elfBuildID(f), true
+}
+
+// elfBuildID returns the hex-encoded GNU build ID note of an ELF file, or "".
+func elfBuildID(f *elf.File) string {
+	section := f.Section(".note.gnu.build-id")
+	if section == nil {
+		return ""
+	}
+	data, err := section.Data()
+	if err != nil || len(data) < 12 {
+		return ""
+	}
+	// The note is the name and descriptor sizes, the type, the 4-byte aligned name, and
+	// the descriptor holding the ID.
+	nameSize, descSize := uint64(f.ByteOrder.Uint32(data[0:4])), uint64(f.ByteOrder.Uint32(data[4:8]))
+	offset := 12 + (nameSize+3)&^3
+	if offset+descSize > uint64(len(data)) {
+		return ""
+	}
+	return hex.EncodeToString(data[offset : offset+descSize])
+}
+
+// debugFilePath returns where the debug file of the ELF file installed at dst goes:
+// under /usr/lib/debug/.build-id by its build ID, or else next to its path under
+// /usr/lib/debug, where debuggers look for it.
+func debugFilePath(dst, buildID string) string {
+	if len(buildID) > 2 {
+		return path.Join("/usr/lib/debug/.build-id", buildID[:2], buildID[2:]+".debug")
+	}
+	return path.Join("/usr/lib/debug", dst) + ".debug"
+}
+
+// splitDebugSymbols splits the debug symbols of the ELF files a deb or rpm job packages
+// with objcopy. The job is returned with its config pointing at the stripped files,
+// followed by a job building the debug package, which installs the debug files and
+// depends on the exact version of the package. A job without such files is returned
+// as-is. Only literal contents sources are split: globs are left alone. The returned
+// cleanup function removes the split files and staged configs.
+func (p *LinuxPkgPlugin) splitDebugSymbols(ctx context.Context, executor CommandExecutor, debug *DebugSymbolsConfig, job buildJob) ([]buildJob, func(), error) {
+	noop := func() {}
+	doc, err := loadNfpmConfig(job.ConfigPath)
+	if err != nil {
+		return nil, noop, err
+	}
+	getenv := envLookup(job.Env, os.Getenv)
+
+	dir, err := os.MkdirTemp("", "linuxpkg-debug-")
+	if err != nil {
+		return nil, noop, fmt.Errorf("failed to create debug symbols directory: %w", err)
+	}
+	cleanups := []func(){func() { _ = os.RemoveAll(dir) }}
+	cleanup := func() {
+		for _, cleanup := range cleanups {
+			cleanup()
+		}
+	}
+
+	lists := [][]any{contentEntries(doc)}
+	if overrides, ok := doc["overrides"].(map[string]any); ok {
+		if override, ok := overrides[job.Format].(map[string]any); ok {
+			lists = append(lists, contentEntries(override))
+		}
+	}
+	var debugContents []any
+	var buildIDs []string
+	for _, entries := range lists {
+		for _, raw := range entries {
+			entry, ok := raw.(map[string]any)
+			if !ok {
+				continue
+			}
+			src, _ := entry["src"].(string)
+			dst, _ := entry["dst"].(string)
+			entryType, _ := entry["type"].(string)
+			if expand, _ := entry["expand"].(bool); expand {
+				src, dst = os.Expand(src, getenv), os.Expand(dst, getenv)
+			}
+			if (entryType != "" && entryType != "file") || src == "" || isRemoteSource(src) || hasGlobMeta(src) {
+				continue
+			}
+			buildID, ok := elfDebugInfo(src)
+			if !ok {
+				continue
+			}
+			if strings.HasSuffix(dst, "/") {
+				dst = path.Join(dst, filepath.Base(src))
+			}
+
+			// Each file is split in its own directory so both halves keep its name; the
+			// stripped file links to the debug file by name.
+			splitDir := filepath.Join(dir, strconv.Itoa(len(debugContents)))
+			if err := os.MkdirAll(splitDir, 0755); err != nil {
+				cleanup()
+				return nil, noop, fmt.Errorf("failed to create debug symbols directory: %w", err)
+			}
+			debugFile := filepath.Join(splitDir, path.Base(dst)+".debug")
+			stripped := filepath.Join(splitDir, path.Base(dst))
+			if output, err := runCommand(ctx, executor, debug.Objcopy, "--only-keep-debug", src, debugFile); err != nil {
+				cleanup()
+				return nil, noop, fmt.Errorf("failed to extract debug symbols of %s: %w\nOutput: %s", src, err, string(output))
+			}
+			if output, err := runCommand(ctx, executor, debug.Objcopy, "--strip-unneeded", "--add-gnu-debuglink="+debugFile, src, stripped); err != nil {
+				cleanup()
+				return nil, noop, fmt.Errorf("failed to strip %s: %w\nOutput: %s", src, err, string(output))
+			}
+
+			entry["src"] = stripped
+			debugContents = append(debugContents, map[string]any{
+				"src":       debugFile,
+				"dst":       debugFilePath(dst, buildID),
+				"type":      "file",
+				"file_info": map[string]any{"mode": 0o644},
+			})
+			if buildID != "" {
+				buildIDs = append(buildIDs, buildID)
+			}
+		}
+	}
+	if len(debugContents) == 0 {
+		cleanup()
+		return []buildJob{job}, noop, nil
+	}
+
+	mainPath, mainCleanup, err := stageNfpmConfig(doc)
+	if err != nil {
+		cleanup()
+		return nil, noop, err
+	}
+	cleanups = append(cleanups, mainCleanup)
+
+	// The debug package depends on the exact version nfpm writes into the package.
+	arch := ""
+	if job.Target.Override {
+		arch = job.Target.nfpmArch()
+	}
+	info, packager, err := resolvePackageInfo(mainPath, job.Format, arch, getenv)
+	if err != nil {
+		cleanup()
+		return nil, noop, err
+	}
+	version := expectedPackageMetadata(job.Format, info, packager).Version
+
+	debugDoc := companionPackage(doc, job.Format, info.Name+debugPackageSuffixes[job.Format], fmt.Sprintf("Debug symbols for %s.", info.Name), debugContents)
+	block := debugDoc[job.Format].(map[string]any)
+	switch job.Format {
+	case "deb":
+		debugDoc["section"] = "debug"
+		debugDoc["priority"] = "optional"
+		debugDoc["depends"] = []any{fmt.Sprintf("%s (= %s)", info.Name, version)}
+		fields := map[string]any{"Auto-Built-Package": "debug-symbols"}
+		if len(buildIDs) > 0 {
+			fields["Build-Ids"] = strings.Join(buildIDs, " ")
+		}
+		block["fields"] = fields
+	case "rpm":
+		debugDoc["depends"] = []any{fmt.Sprintf("%s = %s", info.Name, version)}
+	}
+
+	debugPath, debugCleanup, err := stageNfpmConfig(debugDoc)
+	if err != nil {
+		cleanup()
+		return nil, noop, err
+	}
+	cleanups = append(cleanups, debugCleanup)
+
+	mainJob, debugJob := job, job
+	mainJob.ConfigPath = mainPath
+	debugJob.ConfigPath = debugPath
+	debugJob.Debug = true
+	return []buildJob{mainJob, debugJob}, cleanup, nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestExecuteDebugSymbols tests splitting the debug symbols of packaged ELF files into
+// debug packages.
+func TestExecuteDebugSymbols(t *testing.T) {
+	t.Parallel()
+
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatalf("failed to find the test binary: %v", err)
+	}
+	buildID, ok := elfDebugInfo(executable)
+	if !ok {
+		t.Skip("the test binary has no debug sections")
+	}
+
+	tests := []struct {
+		name         string
+		elf          bool
+		expectSplits int
+		expectDebug  map[string]string
+	}{
+		{"elf", true, 2, map[string]string{"deb": "myapp (= 1.2.3)", "rpm": "myapp = 1.2.3-1"}},
+		{"script", false, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			writeEmbeddedTestConfig(t, dir, "amd64")
+			if tt.elf {
+				binary := filepath.Join(dir, "myapp")
+				if err := os.Remove(binary); err != nil {
+					t.Fatalf("failed to remove binary: %v", err)
+				}
+				if err := os.Symlink(executable, binary); err != nil {
+					t.Fatalf("failed to link binary: %v", err)
+				}
+			}
+
+			// The mock objcopy writes the debug and stripped halves with their name.
+			mock := &MockCommandExecutor{
+				RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+					switch {
+					case name == "objcopy" && args[0] == "--only-keep-debug":
+						return nil, os.WriteFile(args[2], []byte("debug"), 0644)
+					case name == "objcopy" && args[0] == "--strip-unneeded":
+						return nil, os.WriteFile(args[3], []byte("stripped"), 0755)
+					}
+					return nil, errors.New("unexpected command " + name)
+				},
+			}
+			p := &LinuxPkgPlugin{cmdExecutor: mock}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook: plugin.HookPostPublish,
+				Config: map[string]any{
+					"working_dir":   dir,
+					"formats":       []any{"deb", "rpm"},
+					"debug_symbols": true,
+				},
+				Context: plugin.ReleaseContext{Version: "1.2.3"},
+			})
+			if err != nil || !resp.Success {
+				t.Fatalf("expected success, got %v, %+v", err, resp)
+			}
+			if len(mock.Calls) != 2*tt.expectSplits {
+				t.Errorf("expected %d objcopy run(s), got %+v", 2*tt.expectSplits, mock.Calls)
+			}
+
+			artifacts := resp.Outputs["artifacts"].([]map[string]any)
+			if len(artifacts) != 2+tt.expectSplits {
+				t.Fatalf("expected %d artifacts, got %v", 2+tt.expectSplits, artifacts)
+			}
+			for _, artifact := range artifacts {
+				path, format := artifact["path"].(string), artifact["format"].(string)
+				contents, err := readPackageContents(path, format, false)
+				if err != nil {
+					t.Fatalf("failed to read %s: %v", path, err)
+				}
+				if artifact["debug"] != true {
+					if tt.elf && contents.Files["/usr/bin/myapp"].Size != int64(len("stripped")) {
+						t.Errorf("expected the stripped binary in %s, got %v", path, contents.Files)
+					}
+					continue
+				}
+
+				meta, err := readPackageMetadata(path, format)
+				if err != nil {
+					t.Fatalf("failed to read %s: %v", path, err)
+				}
+				if want := "myapp" + debugPackageSuffixes[format]; meta.Name != want {
+					t.Errorf("expected debug package %s, got %s", want, meta.Name)
+				}
+				if debugFile := debugFilePath("/usr/bin/myapp", buildID); contents.Files[debugFile].Size != int64(len("debug")) {
+					t.Errorf("expected %s in %s, got %v", debugFile, path, contents.Files)
+				}
+				if !slices.Equal(contents.Depends, []string{tt.expectDebug[format]}) {
+					t.Errorf("expected %s to depend on %q, got %v", path, tt.expectDebug[format], contents.Depends)
+				}
+			}
+		})
+	}
+}
+
+// TestDebugFilePath tests where debug files are installed.
+func TestDebugFilePath(t *testing.T) {
+	t.Parallel()
+
+	if got := debugFilePath("/usr/bin/myapp", "0a1b2c3d"); got != "/usr/lib/debug/.build-id/0a/1b2c3d.debug" {
+		t.Errorf("unexpected build ID path %s", got)
+	}
+	if got := debugFilePath("/usr/bin/myapp", ""); got != "/usr/lib/debug/usr/bin/myapp.debug" {
+		t.Errorf("unexpected path %s", got)
+	}
+}
+
+// TestValidateDebugSymbols tests the debug_symbols option.
+func TestValidateDebugSymbols(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		config      map[string]any
+		expectError string
+	}{
+		{"enabled", map[string]any{"formats": []any{"deb"}, "debug_symbols": true}, ""},
+		{"objcopy", map[string]any{"formats": []any{"rpm"}, "debug_symbols": map[string]any{"objcopy": "llvm-objcopy"}}, ""},
+		{"disabled", map[string]any{"formats": []any{"apk"}, "debug_symbols": false}, ""},
+		{"no deb or rpm", map[string]any{"formats": []any{"apk"}, "debug_symbols": true}, "requires the deb or rpm format"},
+		{"filename without name", map[string]any{"formats": []any{"deb"}, "debug_symbols": true, "filename_template": "pkg_{version}.{ext}"}, "must contain {name}"},
+		{"invalid", map[string]any{"formats": []any{"deb"}, "debug_symbols": "yes"}, "must be a boolean or an object"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			expectFieldError(t, tt.config, "debug_symbols", tt.expectError)
+		})
+	}
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// desktopIDPattern matches reverse-DNS application IDs such as "com.example.MyApp".
+var desktopIDPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*(\.[A-Za-z0-9_-]+)+$`)
+
+// desktopKeyPattern matches desktop entry keys, optionally localized ("Name[de]").
+var desktopKeyPattern = regexp.MustCompile(`^[A-Za-z0-9-]+(\[[A-Za-z0-9_.@-]+\])?$`)
+
+// metainfoReleasesPattern and metainfoEmptyReleasesPattern find the <releases> element
+// of AppStream metadata.
+var (
+	metainfoReleasesPattern      = regexp.MustCompile(`<releases\s*>`)
+	metainfoEmptyReleasesPattern = regexp.MustCompile(`<releases\s*/>`)
+)
+
+// desktopBooleanKeys are the desktop entry keys that take true or false.
+var desktopBooleanKeys = map[string]bool{
+	"Terminal":             true,
+	"NoDisplay":            true,
+	"Hidden":               true,
+	"StartupNotify":        true,
+	"DBusActivatable":      true,
+	"PrefersNonDefaultGPU": true,
+	"SingleMainWindow":     true,
+}
+
+// desktopIconTypes are the icon file types installed into the hicolor theme.
+var desktopIconTypes = map[string]bool{
+	".png":  true,
+	".svg":  true,
+	".svgz": true,
+}
+
+// DesktopConfig describes the desktop integration of a GUI application: its desktop
+// entry, AppStream metadata, and icons.
+type DesktopConfig struct {
+	// ID is the reverse-DNS application ID naming the installed files.
+	ID string
+	// Name, Comment, Exec, Categories, and Terminal fill a generated desktop entry.
+	Name       string
+	Comment    string
+	Exec       string
+	Categories []string
+	Terminal   bool
+	// DesktopFile is an existing desktop entry shipped instead of a generated one.
+	DesktopFile string
+	// Metainfo is an existing AppStream metainfo file the release is added to. Without
+	// it, one is generated.
+	Metainfo string
+	// Icons are PNG or SVG application icons.
+	Icons []string
+}
+
+// parseDesktop parses the desktop block. It returns nil when it is not configured.
+func parseDesktop(raw map[string]any) *DesktopConfig {
+	block := helpers.NewConfigParser(raw).GetMap("desktop")
+	if block == nil {
+		return nil
+	}
+
+	parser := helpers.NewConfigParser(block)
+	return &DesktopConfig{
+		ID:          parser.GetString("id", "", ""),
+		Name:        parser.GetString("name", "", ""),
+		Comment:     parser.GetString("comment", "", ""),
+		Exec:        parser.GetString("exec", "", ""),
+		Categories:  parser.GetStringSlice("categories", nil),
+		Terminal:    parser.GetBool("terminal", false),
+		DesktopFile: parser.GetString("desktop_file", "", ""),
+		Metainfo:    parser.GetString("metainfo", "", ""),
+		Icons:       parser.GetStringSlice("icons", nil),
+	}
+}
+
+// validate checks the desktop settings. Existing desktop entries are checked when the
+// files exist, so a missing file is reported when building.
+func (d *DesktopConfig) validate() error {
+	if !desktopIDPattern.MatchString(d.ID) {
+		return fmt.Errorf("invalid id %q: use a reverse-DNS ID such as com.example.MyApp", d.ID)
+	}
+	if d.DesktopFile == "" && (d.Name == "" || d.Exec == "") {
+		return fmt.Errorf("name and exec are required unless desktop_file is set")
+	}
+	for _, category := range d.Categories {
+		if category == "" || strings.ContainsAny(category, ";\n") {
+			return fmt.Errorf("invalid category %q", category)
+		}
+	}
+	for _, file := range append([]string{d.DesktopFile, d.Metainfo}, d.Icons...) {
+		if file == "" {
+			continue
+		}
+		if err := validatePath(file); err != nil {
+			return err
+		}
+	}
+	for _, icon := range d.Icons {
+		if !desktopIconTypes[strings.ToLower(filepath.Ext(icon))] {
+			return fmt.Errorf("unsupported icon %s: use PNG or SVG", icon)
+		}
+	}
+	if d.DesktopFile != "" {
+		if content, err := os.ReadFile(d.DesktopFile); err == nil {
+			if errs := verifyDesktopEntry(d.DesktopFile, content); len(errs) > 0 {
+				return fmt.Errorf("%s", strings.Join(errs, "; "))
+			}
+		}
+	}
+	return nil
+}
+
+// files returns the local files the desktop settings reference.
+func (d *DesktopConfig) files() []string {
+	var files []string
+	for _, file := range append([]string{d.DesktopFile, d.Metainfo}, d.Icons...) {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// escapeDesktopValue escapes a desktop entry string value.
+func escapeDesktopValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\t", `\t`, "\r", `\r`).Replace(value)
+}
+
+// desktopEntry returns the generated desktop entry.
+func (d *DesktopConfig) desktopEntry() string {
+	var b strings.Builder
+	b.WriteString("[Desktop Entry]\nType=Application\n")
+	fmt.Fprintf(&b, "Name=%s\n", escapeDesktopValue(d.Name))
+	if d.Comment != "" {
+		fmt.Fprintf(&b, "Comment=%s\n", escapeDesktopValue(d.Comment))
+	}
+	fmt.Fprintf(&b, "Exec=%s\n", d.Exec)
+	if len(d.Icons) > 0 {
+		fmt.Fprintf(&b, "Icon=%s\n", d.ID)
+	}
+	fmt.Fprintf(&b, "Terminal=%t\n", d.Terminal)
+	if len(d.Categories) > 0 {
+		fmt.Fprintf(&b, "Categories=%s;\n", strings.Join(d.Categories, ";"))
+	}
+	return b.String()
+}
+
+// verifyDesktopEntry checks a desktop entry against the Desktop Entry Specification: its
+// syntax, the [Desktop Entry] group, and the keys its type requires. Errors are reported
+// as "file:line: message".
+func verifyDesktopEntry(name string, content []byte) []string {
+	var errs []string
+	group := ""
+	entry := make(map[string]string)
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") || len(line) < 3 {
+				errs = append(errs, fmt.Sprintf("%s:%d: invalid group header %q", name, lineNo, line))
+				continue
+			}
+			group = line[1 : len(line)-1]
+			if len(seen) == 0 && group != "Desktop Entry" {
+				errs = append(errs, fmt.Sprintf("%s:%d: the first group must be [Desktop Entry], got [%s]", name, lineNo, group))
+			}
+			seen[group] = true
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Sprintf("%s:%d: expected Key=Value, got %q", name, lineNo, line))
+		case group == "":
+			errs = append(errs, fmt.Sprintf("%s:%d: %s= is outside of any group", name, lineNo, key))
+		case !desktopKeyPattern.MatchString(key):
+			errs = append(errs, fmt.Sprintf("%s:%d: invalid key %q", name, lineNo, key))
+		case group != "Desktop Entry":
+		case seen[group+"\x00"+key]:
+			errs = append(errs, fmt.Sprintf("%s:%d: duplicate key %s", name, lineNo, key))
+		default:
+			seen[group+"\x00"+key] = true
+			entry[key] = value
+			if desktopBooleanKeys[key] && value != "true" && value != "false" {
+				errs = append(errs, fmt.Sprintf("%s:%d: %s must be true or false, got %q", name, lineNo, key, value))
+			}
+			if key == "Categories" && !strings.HasSuffix(value, ";") {
+				errs = append(errs, fmt.Sprintf("%s:%d: Categories must end with ';'", name, lineNo))
+			}
+		}
+	}
+
+	if !seen["Desktop Entry"] {
+		return append(errs, fmt.Sprintf("%s: missing [Desktop Entry] group", name))
+	}
+	for _, key := range []string{"Type", "Name"} {
+		if entry[key] == "" {
+			errs = append(errs, fmt.Sprintf("%s: missing required key %s", name, key))
+		}
+	}
+	switch entry["Type"] {
+	case "", "Directory":
+	case "Application":
+		if entry["Exec"] == "" && entry["DBusActivatable"] != "true" {
+			errs = append(errs, fmt.Sprintf("%s: an Application needs Exec", name))
+		}
+	case "Link":
+		if entry["URL"] == "" {
+			errs = append(errs, fmt.Sprintf("%s: a Link needs URL", name))
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("%s: invalid Type %q (allowed: Application, Link, Directory)", name, entry["Type"]))
+	}
+	return errs
+}
+
+// metainfoRelease returns the AppStream release element for version on date.
+func metainfoRelease(version string, date time.Time) string {
+	var escaped bytes.Buffer
+	_ = xml.EscapeText(&escaped, []byte(version))
+	return fmt.Sprintf(`<release version="%s" date="%s"/>`, escaped.String(), date.Format("2006-01-02"))
+}
+
+// generateMetainfo returns AppStream metadata for the application. The license and
+// homepage come from the nfpm config.
+func (d *DesktopConfig) generateMetainfo(doc map[string]any, version string, date time.Time) string {
+	text := func(s string) string {
+		var b bytes.Buffer
+		_ = xml.EscapeText(&b, []byte(s))
+		return b.String()
+	}
+	license, _ := doc["license"].(string)
+	homepage, _ := doc["homepage"].(string)
+	summary := d.Comment
+	if summary == "" {
+		summary = d.Name
+	}
+
+	var b strings.Builder
+	b.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	b.WriteString("<component type=\"desktop-application\">\n")
+	fmt.Fprintf(&b, "  <id>%s</id>\n", text(d.ID))
+	b.WriteString("  <metadata_license>CC0-1.0</metadata_license>\n")
+	if license != "" {
+		fmt.Fprintf(&b, "  <project_license>%s</project_license>\n", text(license))
+	}
+	fmt.Fprintf(&b, "  <name>%s</name>\n", text(d.Name))
+	fmt.Fprintf(&b, "  <summary>%s</summary>\n", text(summary))
+	fmt.Fprintf(&b, "  <description>\n    <p>%s</p>\n  </description>\n", text(summary))
+	fmt.Fprintf(&b, "  <launchable type=\"desktop-id\">%s.desktop</launchable>\n", text(d.ID))
+	if homepage != "" {
+		fmt.Fprintf(&b, "  <url type=\"homepage\">%s</url>\n", text(homepage))
+	}
+	fmt.Fprintf(&b, "  <releases>\n    %s\n  </releases>\n", metainfoRelease(version, date))
+	b.WriteString("</component>\n")
+	return b.String()
+}
+
+// verifyMetainfo checks that content is well-formed AppStream metadata: a <component>
+// root with an <id>.
+func verifyMetainfo(name string, content []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	depth, root, hasID := 0, "", false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%s: invalid XML: %w", name, err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				root = t.Name.Local
+			} else if depth == 1 && t.Name.Local == "id" {
+				hasID = true
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	if root != "component" {
+		return fmt.Errorf("%s: the root element must be <component>, got <%s>", name, root)
+	}
+	if !hasID {
+		return fmt.Errorf("%s: missing <id>", name)
+	}
+	return nil
+}
+
+// addMetainfoRelease adds the release to existing AppStream metadata as the newest
+// entry of <releases>, creating the element when needed. Metadata that already lists
+// the version is returned unchanged.
+func addMetainfoRelease(content []byte, version string, date time.Time) []byte {
+	s := string(content)
+	if regexp.MustCompile(`<release\s[^>]*version="` + regexp.QuoteMeta(version) + `"`).MatchString(s) {
+		return content
+	}
+	release := metainfoRelease(version, date)
+	if loc := metainfoReleasesPattern.FindStringIndex(s); loc != nil {
+		return []byte(s[:loc[1]] + "\n    " + release + s[loc[1]:])
+	}
+	if loc := metainfoEmptyReleasesPattern.FindStringIndex(s); loc != nil {
+		return []byte(s[:loc[0]] + "<releases>\n    " + release + "\n  </releases>" + s[loc[1]:])
+	}
+	if i := strings.LastIndex(s, "</component>"); i >= 0 {
+		return []byte(s[:i] + "  <releases>\n    " + release + "\n  </releases>\n" + s[i:])
+	}
+	return content
+}
+
+// iconDir returns the hicolor theme directory for icon: "scalable" for SVG, or the pixel
+// size of a square PNG.
+func iconDir(icon string) (string, error) {
+	if ext := strings.ToLower(filepath.Ext(icon)); ext != ".png" {
+		return "scalable", nil
+	}
+	file, err := os.Open(icon)
+	if err != nil {
+		return "", fmt.Errorf("failed to read icon: %w", err)
+	}
+	defer file.Close()
+	cfg, err := png.DecodeConfig(file)
+	if err != nil {
+		return "", fmt.Errorf("invalid icon %s: %w", icon, err)
+	}
+	if cfg.Width != cfg.Height {
+		return "", fmt.Errorf("icon %s must be square, got %dx%d", icon, cfg.Width, cfg.Height)
+	}
+	return fmt.Sprintf("%dx%d", cfg.Width, cfg.Height), nil
+}
+
+// addDesktop writes the desktop entry and AppStream metadata for the release in data into
+// dir, checks them, and adds them and the icons to doc's contents:
+//
+//	/usr/share/applications/<id>.desktop
+//	/usr/share/metainfo/<id>.metainfo.xml
+//	/usr/share/icons/hicolor/<size>/apps/<id>.<ext>
+func addDesktop(doc map[string]any, desktop *DesktopConfig, data *nfpmTemplateData, dir string) error {
+	date, err := time.Parse(time.RFC3339, data.Date)
+	if err != nil {
+		return fmt.Errorf("invalid release date %q: %w", data.Date, err)
+	}
+
+	entry := []byte(desktop.desktopEntry())
+	entryName := desktop.ID + ".desktop"
+	if desktop.DesktopFile != "" {
+		if entry, err = os.ReadFile(desktop.DesktopFile); err != nil {
+			return fmt.Errorf("failed to read desktop file: %w", err)
+		}
+		entryName = desktop.DesktopFile
+	}
+	if errs := verifyDesktopEntry(entryName, entry); len(errs) > 0 {
+		return fmt.Errorf("invalid desktop entry:\n  %s", strings.Join(errs, "\n  "))
+	}
+
+	metainfo := []byte(desktop.generateMetainfo(doc, data.Version, date))
+	if desktop.Metainfo != "" {
+		existing, err := os.ReadFile(desktop.Metainfo)
+		if err != nil {
+			return fmt.Errorf("failed to read metainfo: %w", err)
+		}
+		if err := verifyMetainfo(desktop.Metainfo, existing); err != nil {
+			return fmt.Errorf("invalid metainfo: %w", err)
+		}
+		metainfo = addMetainfoRelease(existing, data.Version, date)
+	}
+
+	files := []struct {
+		name, dst string
+		content   []byte
+	}{
+		{"app.desktop", "/usr/share/applications/" + desktop.ID + ".desktop", entry},
+		{"app.metainfo.xml", "/usr/share/metainfo/" + desktop.ID + ".metainfo.xml", metainfo},
+	}
+	contents := contentEntries(doc)
+	for _, file := range files {
+		src := filepath.Join(dir, file.name)
+		if err := os.WriteFile(src, file.content, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+		contents = append(contents, map[string]any{"src": src, "dst": file.dst, "file_info": map[string]any{"mode": 0644}})
+	}
+	for _, icon := range desktop.Icons {
+		size, err := iconDir(icon)
+		if err != nil {
+			return err
+		}
+		dst := fmt.Sprintf("/usr/share/icons/hicolor/%s/apps/%s%s", size, desktop.ID, strings.ToLower(filepath.Ext(icon)))
+		contents = append(contents, map[string]any{"src": icon, "dst": dst, "file_info": map[string]any{"mode": 0644}})
+	}
+	doc["contents"] = contents
+	return nil
+}
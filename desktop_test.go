@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+	"gopkg.in/yaml.v3"
+)
+
+// writeTestIcon writes a blank PNG icon of the given size.
+func writeTestIcon(t *testing.T, path string, width, height int) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create icon: %v", err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("failed to write icon: %v", err)
+	}
+}
+
+// TestValidateDesktop tests desktop validation.
+func TestValidateDesktop(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		desktop     any
+		expectError string
+	}{
+		{"valid", map[string]any{"id": "com.example.MyApp", "name": "My App", "exec": "myapp %U", "icons": []any{"myapp.png", "myapp.svg"}}, ""},
+		{"existing files", map[string]any{"id": "com.example.MyApp", "desktop_file": "myapp.desktop", "metainfo": "myapp.metainfo.xml"}, ""},
+		{"missing id", map[string]any{"name": "My App", "exec": "myapp"}, "invalid id"},
+		{"not reverse-DNS", map[string]any{"id": "myapp", "name": "My App", "exec": "myapp"}, "invalid id"},
+		{"missing exec", map[string]any{"id": "com.example.MyApp", "name": "My App"}, "name and exec are required"},
+		{"bad category", map[string]any{"id": "com.example.MyApp", "name": "My App", "exec": "myapp", "categories": []any{"Utility;Game"}}, "invalid category"},
+		{"bad icon", map[string]any{"id": "com.example.MyApp", "name": "My App", "exec": "myapp", "icons": []any{"myapp.ico"}}, "unsupported icon"},
+		{"traversal", map[string]any{"id": "com.example.MyApp", "desktop_file": "../myapp.desktop"}, "path traversal"},
+		{"not an object", "com.example.MyApp", "must be an object"},
+	}
+
+	p := &LinuxPkgPlugin{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := p.Validate(context.Background(), map[string]any{"desktop": tt.desktop})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectError == "" {
+				if !resp.Valid {
+					t.Errorf("expected valid config, got errors: %v", resp.Errors)
+				}
+				return
+			}
+			if resp.Valid || len(resp.Errors) == 0 || resp.Errors[0].Field != "desktop" ||
+				!strings.Contains(resp.Errors[0].Message, tt.expectError) {
+				t.Errorf("expected desktop error containing %q, got %v", tt.expectError, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestVerifyDesktopEntry tests checking desktop entries.
+func TestVerifyDesktopEntry(t *testing.T) {
+	t.Parallel()
+
+	generated := (&DesktopConfig{ID: "com.example.MyApp", Name: "My App", Exec: "myapp %U", Categories: []string{"Utility"}, Icons: []string{"myapp.svg"}}).desktopEntry()
+	tests := []struct {
+		name    string
+		content string
+		errors  []string
+	}{
+		{"generated", generated, nil},
+		{"link", "# comment\n[Desktop Entry]\nType=Link\nName=Docs\nName[de]=Doku\nURL=https://example.com\n\n[Desktop Action new]\nName=New\n", nil},
+		{"dbus activatable", "[Desktop Entry]\nType=Application\nName=My App\nDBusActivatable=true\n", nil},
+		{"wrong first group", "[Settings]\nType=Application\n", []string{
+			"app.desktop:1: the first group must be [Desktop Entry], got [Settings]",
+			"app.desktop: missing [Desktop Entry] group",
+		}},
+		{"missing keys", "[Desktop Entry]\nType=Application\n", []string{
+			"app.desktop: missing required key Name",
+			"app.desktop: an Application needs Exec",
+		}},
+		{"bad values", "[Desktop Entry]\nType=Application\nName=My App\nExec=myapp\nTerminal=yes\nCategories=Utility\nName=Other\n", []string{
+			"app.desktop:5: Terminal must be true or false, got \"yes\"",
+			"app.desktop:6: Categories must end with ';'",
+			"app.desktop:7: duplicate key Name",
+		}},
+		{"bad syntax", "Name=Orphan\n[Desktop Entry]\nType=Service\nName=My App\njust text\nBad Key=1\n", []string{
+			"app.desktop:1: Name= is outside of any group",
+			"app.desktop:5: expected Key=Value, got \"just text\"",
+			"app.desktop:6: invalid key \"Bad Key\"",
+			"app.desktop: invalid Type \"Service\" (allowed: Application, Link, Directory)",
+		}},
+	}
+
+	for _, tt := range tests {
+		if errs := verifyDesktopEntry("app.desktop", []byte(tt.content)); !reflect.DeepEqual(errs, tt.errors) {
+			t.Errorf("%s: expected errors %q, got %q", tt.name, tt.errors, errs)
+		}
+	}
+}
+
+// TestMetainfo tests generating AppStream metadata and adding releases to it.
+func TestMetainfo(t *testing.T) {
+	t.Parallel()
+
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	desktop := &DesktopConfig{ID: "com.example.MyApp", Name: "My & App", Comment: "Does things"}
+	generated := desktop.generateMetainfo(map[string]any{"license": "MIT", "homepage": "https://example.com"}, "1.2.3", date)
+	if err := verifyMetainfo("generated", []byte(generated)); err != nil {
+		t.Fatalf("expected valid metainfo, got %v\n%s", err, generated)
+	}
+	for _, want := range []string{
+		"<name>My &amp; App</name>",
+		"<project_license>MIT</project_license>",
+		`<url type="homepage">https://example.com</url>`,
+		`<release version="1.2.3" date="2024-05-01"/>`,
+	} {
+		if !strings.Contains(generated, want) {
+			t.Errorf("expected %q in:\n%s", want, generated)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			"releases",
+			"<component>\n  <id>x.y</id>\n  <releases>\n    <release version=\"1.0.0\"/>\n  </releases>\n</component>\n",
+			"<component>\n  <id>x.y</id>\n  <releases>\n    <release version=\"1.2.3\" date=\"2024-05-01\"/>\n    <release version=\"1.0.0\"/>\n  </releases>\n</component>\n",
+		},
+		{
+			"empty releases",
+			"<component>\n  <id>x.y</id>\n  <releases/>\n</component>\n",
+			"<component>\n  <id>x.y</id>\n  <releases>\n    <release version=\"1.2.3\" date=\"2024-05-01\"/>\n  </releases>\n</component>\n",
+		},
+		{
+			"no releases",
+			"<component>\n  <id>x.y</id>\n</component>\n",
+			"<component>\n  <id>x.y</id>\n  <releases>\n    <release version=\"1.2.3\" date=\"2024-05-01\"/>\n  </releases>\n</component>\n",
+		},
+		{
+			"already listed",
+			"<component>\n  <id>x.y</id>\n  <releases>\n    <release date=\"2024-04-30\" version=\"1.2.3\"/>\n  </releases>\n</component>\n",
+			"<component>\n  <id>x.y</id>\n  <releases>\n    <release date=\"2024-04-30\" version=\"1.2.3\"/>\n  </releases>\n</component>\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := string(addMetainfoRelease([]byte(tt.content), "1.2.3", date)); got != tt.expected {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", tt.name, tt.expected, got)
+		}
+	}
+
+	for content, want := range map[string]string{
+		"<component><id>x.y</id>":                 "invalid XML",
+		"<application><id>x.y</id></application>": "root element must be <component>",
+		"<component><name>X</name></component>":   "missing <id>",
+	} {
+		if err := verifyMetainfo("app.xml", []byte(content)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected error containing %q, got %v", content, want, err)
+		}
+	}
+}
+
+// TestExecuteDesktop tests that the desktop entry, metainfo, and icons reach nfpm.
+func TestExecuteDesktop(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: myapp\nversion: 1.2.3\nlicense: MIT\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	writeTestIcon(t, filepath.Join(dir, "myapp.png"), 128, 128)
+	if err := os.WriteFile(filepath.Join(dir, "myapp.svg"), []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"/>\n"), 0644); err != nil {
+		t.Fatalf("failed to write icon: %v", err)
+	}
+
+	var dsts []string
+	var metainfo string
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			content, err := os.ReadFile(args[2])
+			if err != nil {
+				return nil, err
+			}
+			var doc map[string]any
+			if err := yaml.Unmarshal(content, &doc); err != nil {
+				return nil, err
+			}
+			for _, raw := range contentEntries(doc) {
+				entry := raw.(map[string]any)
+				dsts = append(dsts, entry["dst"].(string))
+				if strings.HasSuffix(entry["dst"].(string), ".metainfo.xml") {
+					data, err := os.ReadFile(entry["src"].(string))
+					if err != nil {
+						return nil, err
+					}
+					metainfo = string(data)
+				}
+			}
+			return []byte("created package: " + args[len(args)-1] + "myapp.deb"), nil
+		},
+	}
+	desktop := map[string]any{"id": "com.example.MyApp", "name": "My App", "exec": "myapp", "icons": []string{"myapp.png", "myapp.svg"}}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"working_dir": dir, "formats": []string{"deb"}, "packager": "nfpm-cli", "desktop": desktop},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	expected := []string{
+		"/usr/share/applications/com.example.MyApp.desktop",
+		"/usr/share/metainfo/com.example.MyApp.metainfo.xml",
+		"/usr/share/icons/hicolor/128x128/apps/com.example.MyApp.png",
+		"/usr/share/icons/hicolor/scalable/apps/com.example.MyApp.svg",
+	}
+	if !reflect.DeepEqual(dsts, expected) {
+		t.Errorf("expected contents %q, got %q", expected, dsts)
+	}
+	if !strings.Contains(metainfo, `<release version="1.2.3" date="`) || !strings.Contains(metainfo, "<project_license>MIT</project_license>") {
+		t.Errorf("unexpected metainfo:\n%s", metainfo)
+	}
+
+	// A broken desktop file or a non-square icon fails before packaging.
+	if err := os.WriteFile(filepath.Join(dir, "myapp.desktop"), []byte("[Desktop Entry]\nType=Application\nName=My App\n"), 0644); err != nil {
+		t.Fatalf("failed to write desktop file: %v", err)
+	}
+	writeTestIcon(t, filepath.Join(dir, "wide.png"), 128, 64)
+	for _, tt := range []struct {
+		desktop map[string]any
+		want    string
+	}{
+		{map[string]any{"id": "com.example.MyApp", "desktop_file": "myapp.desktop"}, "an Application needs Exec"},
+		{map[string]any{"id": "com.example.MyApp", "name": "My App", "exec": "myapp", "icons": []string{"wide.png"}}, "must be square"},
+	} {
+		mock.Calls = nil
+		resp, err = p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  map[string]any{"working_dir": dir, "formats": []string{"deb"}, "packager": "nfpm-cli", "desktop": tt.desktop},
+			Context: plugin.ReleaseContext{Version: "1.2.3"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Success || !strings.Contains(resp.Error, tt.want) || len(mock.Calls) != 0 {
+			t.Errorf("expected an error containing %q before packaging, got %+v", tt.want, resp)
+		}
+	}
+}
+
+// TestExecuteDesktopEmbedded tests building packages with desktop files with the embedded
+// library.
+func TestExecuteDesktopEmbedded(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+	writeTestIcon(t, filepath.Join(dir, "myapp.png"), 64, 64)
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb", "rpm"},
+			"desktop":     map[string]any{"id": "com.example.MyApp", "name": "My App", "exec": "myapp", "icons": []string{"myapp.png"}},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+	if packages := resp.Outputs["packages"].([]string); len(packages) != 2 {
+		t.Errorf("expected 2 packages, got %v", packages)
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// packageDiff compares a built package with the same package of the previous release.
+type packageDiff struct {
+	Package string `json:"package"`
+	Format  string `json:"format"`
+	Arch    string `json:"arch"`
+	// Previous is the previous release's package, empty for a package it did not have.
+	Previous           string   `json:"previous,omitempty"`
+	FilesAdded         []string `json:"files_added,omitempty"`
+	FilesRemoved       []string `json:"files_removed,omitempty"`
+	FilesChanged       []string `json:"files_changed,omitempty"`
+	DependsAdded       []string `json:"depends_added,omitempty"`
+	DependsRemoved     []string `json:"depends_removed,omitempty"`
+	SizeDelta          int64    `json:"size_delta"`
+	InstalledSizeDelta int64    `json:"installed_size_delta"`
+}
+
+// validateDiffAgainst checks diff_against: a template of an https URL or of a relative
+// directory.
+func validateDiffAgainst(location string) error {
+	if location == "" {
+		return nil
+	}
+	if _, err := template.New("diff_against").Parse(location); err != nil {
+		return err
+	}
+	if strings.Contains(location, "://") {
+		if !strings.HasPrefix(location, "https://") {
+			return fmt.Errorf("only https URLs are supported: %s", location)
+		}
+		return nil
+	}
+	return validatePath(location)
+}
+
+// renderDiffAgainst renders diff_against with the release's template data and resolves
+// a directory against the working directory.
+func renderDiffAgainst(location, workingDir string, data *nfpmTemplateData) (string, error) {
+	tmpl, err := template.New("diff_against").Option("missingkey=error").Parse(location)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	rendered := buf.String()
+	if strings.HasPrefix(rendered, "https://") {
+		return rendered, nil
+	}
+	if err := validatePath(rendered); err != nil {
+		return "", err
+	}
+	return inWorkingDir(workingDir, rendered), nil
+}
+
+// packageFormat returns the format of a package file by its extension, or "" when it
+// is not a package.
+func packageFormat(name string) string {
+	for format, ext := range packageExtensions {
+		if strings.HasSuffix(name, ext) && len(name) > len(ext) {
+			return format
+		}
+	}
+	return ""
+}
+
+// fetchPreviousPackages downloads the packages listed in the SHA256SUMS file at baseURL,
+// verifying each digest. It returns the URL of each downloaded package by its path.
+func (p *LinuxPkgPlugin) fetchPreviousPackages(ctx context.Context, fetcher *remoteFetcher, baseURL string) (map[string]string, error) {
+	baseURL = strings.TrimSuffix(baseURL, "/") + "/"
+	checksums, err := p.download(ctx, baseURL+checksumFileName("sha256"))
+	if err != nil {
+		return nil, err
+	}
+
+	packages := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		name := strings.TrimPrefix(fields[1], "*")
+		if packageFormat(name) == "" || name != path.Base(name) {
+			continue
+		}
+		local, err := fetcher.fetch(ctx, baseURL+name, fields[0])
+		if err != nil {
+			return nil, err
+		}
+		packages[local] = baseURL + name
+	}
+	return packages, nil
+}
+
+// listPreviousPackages returns the packages in dir, each named by its own path.
+func listPreviousPackages(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read previous packages: %w", err)
+	}
+	packages := make(map[string]string)
+	for _, entry := range entries {
+		if !entry.IsDir() && packageFormat(entry.Name()) != "" {
+			local := filepath.Join(dir, entry.Name())
+			packages[local] = local
+		}
+	}
+	return packages, nil
+}
+
+// packageKey identifies a package across releases by format, name, and architecture.
+func packageKey(pkg, format string) (string, error) {
+	meta, err := readPackageMetadata(pkg, format)
+	if err != nil {
+		return "", err
+	}
+	return format + "/" + meta.Name + "/" + meta.Arch, nil
+}
+
+// diffPackages compares each built package with the package of the same format, name,
+// and architecture among the previous release's packages, found in a local directory or
+// listed in the SHA256SUMS file at the https URL of location. Formats whose contents
+// cannot be read are left out.
+func (p *LinuxPkgPlugin) diffPackages(ctx context.Context, location string, artifacts []map[string]any) ([]packageDiff, error) {
+	var packages map[string]string
+	var err error
+	if strings.HasPrefix(location, "https://") {
+		fetcher := newRemoteFetcher(p.getHTTPClient())
+		defer fetcher.cleanup()
+		packages, err = p.fetchPreviousPackages(ctx, fetcher, location)
+	} else {
+		packages, err = listPreviousPackages(location)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	previous := make(map[string]string, len(packages))
+	for local := range packages {
+		if key, err := packageKey(local, packageFormat(local)); err == nil {
+			previous[key] = local
+		}
+	}
+
+	diffs := make([]packageDiff, 0, len(artifacts))
+	for _, artifact := range artifacts {
+		pkg, _ := artifact["path"].(string)
+		format, _ := artifact["format"].(string)
+		arch, _ := artifact["arch"].(string)
+		current, err := readPackageContents(pkg, format, true)
+		if err != nil {
+			continue
+		}
+		diff := packageDiff{Package: pkg, Format: format, Arch: arch}
+		key, err := packageKey(pkg, format)
+		if err != nil {
+			return nil, err
+		}
+		local, ok := previous[key]
+		if !ok {
+			diffs = append(diffs, diff)
+			continue
+		}
+		old, err := readPackageContents(local, format, true)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(local)
+		if err != nil {
+			return nil, err
+		}
+		diff.Previous = packages[local]
+		diff.compare(old, current)
+		size, _ := artifact["size"].(int64)
+		diff.SizeDelta = size - info.Size()
+		diffs = append(diffs, diff)
+	}
+	return diffs, nil
+}
+
+// compare fills in the file, dependency, and installed size changes from old to current.
+func (d *packageDiff) compare(old, current *packageContents) {
+	for name, file := range current.Files {
+		d.InstalledSizeDelta += file.Size
+		previous, ok := old.Files[name]
+		switch {
+		case !ok:
+			d.FilesAdded = append(d.FilesAdded, name)
+		case previous != file:
+			d.FilesChanged = append(d.FilesChanged, name)
+		}
+	}
+	for name, file := range old.Files {
+		d.InstalledSizeDelta -= file.Size
+		if _, ok := current.Files[name]; !ok {
+			d.FilesRemoved = append(d.FilesRemoved, name)
+		}
+	}
+	slices.Sort(d.FilesAdded)
+	slices.Sort(d.FilesRemoved)
+	slices.Sort(d.FilesChanged)
+
+	for _, depend := range current.Depends {
+		if !slices.Contains(old.Depends, depend) {
+			d.DependsAdded = append(d.DependsAdded, depend)
+		}
+	}
+	for _, depend := range old.Depends {
+		if !slices.Contains(current.Depends, depend) {
+			d.DependsRemoved = append(d.DependsRemoved, depend)
+		}
+	}
+}
+
+// summary describes the changes in one line, or "new" for a package the previous
+// release did not have.
+func (d *packageDiff) summary() string {
+	if d.Previous == "" {
+		return "new"
+	}
+	return fmt.Sprintf("%d file(s) added, %d removed, %d changed; %d dependency(ies) added, %d removed; size %s, installed size %s",
+		len(d.FilesAdded), len(d.FilesRemoved), len(d.FilesChanged), len(d.DependsAdded), len(d.DependsRemoved),
+		formatSizeDelta(d.SizeDelta), formatSizeDelta(d.InstalledSizeDelta))
+}
+
+// formatSizeDelta renders a signed byte count for messages.
+func formatSizeDelta(delta int64) string {
+	if delta < 0 {
+		return "-" + formatSize(-delta)
+	}
+	return "+" + formatSize(delta)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestExecuteDiffAgainst tests comparing packages with those of the previous release,
+// kept in a directory or downloaded from the URL of its checksums.
+func TestExecuteDiffAgainst(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		remote bool
+	}{
+		{"directory", false},
+		{"url", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			configPath := writeEmbeddedTestConfig(t, dir, "amd64")
+			formats := []any{"deb", "rpm", "apk", "archlinux", "ipk"}
+
+			p := &LinuxPkgPlugin{}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook:    plugin.HookPostPublish,
+				Config:  map[string]any{"working_dir": dir, "output_dir": "previous", "formats": formats},
+				Context: plugin.ReleaseContext{Version: "1.0.0"},
+			})
+			if err != nil || !resp.Success {
+				t.Fatalf("expected the previous build to succeed, got %v, %+v", err, resp)
+			}
+
+			// The next release changes the binary, adds a file, and depends on bash.
+			if err := os.WriteFile(filepath.Join(dir, "myapp"), []byte("#!/bin/sh\necho hello world\n"), 0755); err != nil {
+				t.Fatalf("failed to write binary: %v", err)
+			}
+			readme := filepath.Join(dir, "README")
+			if err := os.WriteFile(readme, []byte("myapp\n"), 0644); err != nil {
+				t.Fatalf("failed to write readme: %v", err)
+			}
+			config, err := os.ReadFile(configPath)
+			if err != nil {
+				t.Fatalf("failed to read config: %v", err)
+			}
+			config = append(config, "depends:\n  - bash\n"...)
+			config = []byte(strings.Replace(string(config), "contents:\n",
+				"contents:\n  - src: "+readme+"\n    dst: /usr/share/doc/myapp/README\n", 1))
+			if err := os.WriteFile(configPath, config, 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			diffAgainst := "previous"
+			if tt.remote {
+				server := httptest.NewTLSServer(http.StripPrefix("/releases/1.0.0/", http.FileServer(http.Dir(filepath.Join(dir, "previous")))))
+				defer server.Close()
+				p.httpClient = server.Client()
+				diffAgainst = server.URL + "/releases/{{.PreviousVersion}}/"
+			}
+			resp, err = p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook:    plugin.HookPostPublish,
+				Config:  map[string]any{"working_dir": dir, "formats": formats, "diff_against": diffAgainst},
+				Context: plugin.ReleaseContext{Version: "1.1.0", PreviousVersion: "1.0.0"},
+			})
+			if err != nil || !resp.Success {
+				t.Fatalf("expected success, got %v, %+v", err, resp)
+			}
+			if strings.Contains(resp.Message, "failed to diff") {
+				t.Fatalf("unexpected warning: %s", resp.Message)
+			}
+
+			diffs := resp.Outputs["package_diff"].([]packageDiff)
+			if len(diffs) != len(formats) {
+				t.Fatalf("expected a diff per format, got %+v", diffs)
+			}
+			for _, diff := range diffs {
+				if diff.Previous == "" || tt.remote != strings.HasPrefix(diff.Previous, "https://") {
+					t.Errorf("%s: unexpected previous package %q", diff.Format, diff.Previous)
+				}
+				if !reflect.DeepEqual(diff.FilesAdded, []string{"/usr/share/doc/myapp/README"}) ||
+					!reflect.DeepEqual(diff.FilesChanged, []string{"/usr/bin/myapp"}) || len(diff.FilesRemoved) != 0 {
+					t.Errorf("%s: unexpected file changes %+v", diff.Format, diff)
+				}
+				if !reflect.DeepEqual(diff.DependsAdded, []string{"bash"}) || len(diff.DependsRemoved) != 0 {
+					t.Errorf("%s: unexpected dependency changes %v, %v", diff.Format, diff.DependsAdded, diff.DependsRemoved)
+				}
+				if diff.InstalledSizeDelta != 12 {
+					t.Errorf("%s: expected installed size to grow by 12 bytes, got %d", diff.Format, diff.InstalledSizeDelta)
+				}
+			}
+		})
+	}
+}
+
+// TestExecuteDiffAgainstFirstRelease tests that a release without a previous version is
+// not compared, and that a missing previous release only warns.
+func TestExecuteDiffAgainstFirstRelease(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+
+	p := &LinuxPkgPlugin{}
+	for _, tc := range []struct {
+		previous string
+		warning  bool
+	}{
+		{"", false},
+		{"0.9.0", true},
+	} {
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  map[string]any{"working_dir": dir, "formats": []any{"deb"}, "diff_against": "releases/{{.PreviousVersion}}"},
+			Context: plugin.ReleaseContext{Version: "1.0.0", PreviousVersion: tc.previous},
+		})
+		if err != nil || !resp.Success {
+			t.Fatalf("expected success, got %v, %+v", err, resp)
+		}
+		if strings.Contains(resp.Message, "failed to diff against the previous release") != tc.warning {
+			t.Errorf("previous %q: unexpected message %q", tc.previous, resp.Message)
+		}
+	}
+}
+
+// TestValidateDiffAgainst tests validation of diff_against.
+func TestValidateDiffAgainst(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		location string
+		valid    bool
+	}{
+		{"", true},
+		{"dist/{{.PreviousVersion}}", true},
+		{"https://downloads.example.com/myapp/{{.PreviousVersion}}/", true},
+		{"http://downloads.example.com/myapp/", false},
+		{"/var/cache/myapp", false},
+		{"../previous", false},
+		{"dist/{{.PreviousVersion", false},
+	}
+
+	for _, tt := range tests {
+		if err := validateDiffAgainst(tt.location); (err == nil) != tt.valid {
+			t.Errorf("%q: expected valid %v, got %v", tt.location, tt.valid, err)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// DistroConfig describes a distribution packages are built for.
+type DistroConfig struct {
+	// Name identifies the distribution, e.g. "el9" or "ubuntu-jammy".
+	Name string
+	// Tag is appended to the package release ("1.el9", "1+jammy"). Defaults to the last
+	// '-' separated part of Name.
+	Tag string
+	// Formats are the formats built for the distribution. Defaults to the format its
+	// family uses.
+	Formats []string
+	// ConfigOverlays are merged after the global config_overlays for this distribution.
+	ConfigOverlays []string
+	// Compression replaces the global compression of the formats it sets.
+	Compression *CompressionConfig
+}
+
+// distroFamilyPattern infers a distribution's package format from its name.
+var distroFamilyPattern = regexp.MustCompile(`^(el|rhel|centos|rocky|alma|almalinux|fedora|fc|amzn|opensuse|sles|ubuntu|debian|alpine|arch|archlinux|openwrt)([0-9.-]|$)`)
+
+// distroFamilyFormats maps distribution families to their package format.
+var distroFamilyFormats = map[string]string{
+	"el":        "rpm",
+	"rhel":      "rpm",
+	"centos":    "rpm",
+	"rocky":     "rpm",
+	"alma":      "rpm",
+	"almalinux": "rpm",
+	"fedora":    "rpm",
+	"fc":        "rpm",
+	"amzn":      "rpm",
+	"opensuse":  "rpm",
+	"sles":      "rpm",
+	"ubuntu":    "deb",
+	"debian":    "deb",
+	"alpine":    "apk",
+	"arch":      "archlinux",
+	"archlinux": "archlinux",
+	"openwrt":   "ipk",
+}
+
+// distroNamePattern matches valid distribution names; they are used as directory names.
+var distroNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]*$`)
+
+// distroTagPattern matches tags that are valid in both deb revisions and rpm releases.
+var distroTagPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.]*$`)
+
+// parseDistros reads the distros option, which is either a list of distribution names or
+// an object mapping names to settings. In the object form distributions are built in
+// sorted order. Formats and tags that are not configured are inferred from the name.
+func parseDistros(raw map[string]any) []*DistroConfig {
+	var distros []*DistroConfig
+	if block, ok := raw["distros"].(map[string]any); ok {
+		for _, name := range sortedKeys(block) {
+			distro := &DistroConfig{Name: name}
+			if settings, ok := block[name].(map[string]any); ok {
+				parser := helpers.NewConfigParser(settings)
+				distro.Tag = parser.GetString("tag", "", "")
+				distro.Formats = parser.GetStringSlice("formats", nil)
+				distro.ConfigOverlays = parser.GetStringSlice("config_overlays", nil)
+				distro.Compression = parseCompression(settings)
+			}
+			distros = append(distros, distro)
+		}
+	} else {
+		for _, name := range helpers.NewConfigParser(raw).GetStringSlice("distros", nil) {
+			distros = append(distros, &DistroConfig{Name: name})
+		}
+	}
+
+	for _, distro := range distros {
+		if distro.Tag == "" {
+			distro.Tag = distro.Name[strings.LastIndex(distro.Name, "-")+1:]
+		}
+		if len(distro.Formats) == 0 {
+			if m := distroFamilyPattern.FindStringSubmatch(distro.Name); m != nil {
+				distro.Formats = []string{distroFamilyFormats[m[1]]}
+			}
+		}
+	}
+	return distros
+}
+
+// validate checks a distribution's name, tag, formats, and overlays.
+func (d *DistroConfig) validate() error {
+	if !distroNamePattern.MatchString(d.Name) {
+		return fmt.Errorf("invalid name %q: use lowercase letters, digits, '.' and '-'", d.Name)
+	}
+	if !distroTagPattern.MatchString(d.Tag) {
+		return fmt.Errorf("%s: invalid tag %q: use letters, digits, and '.'", d.Name, d.Tag)
+	}
+	if len(d.Formats) == 0 {
+		return fmt.Errorf("%s: cannot infer the package format, set formats", d.Name)
+	}
+	for _, format := range d.Formats {
+		if err := validateFormat(format); err != nil {
+			return fmt.Errorf("%s: %w", d.Name, err)
+		}
+	}
+	for _, overlay := range d.ConfigOverlays {
+		if err := validatePath(overlay); err != nil {
+			return fmt.Errorf("%s: invalid config_overlays: %w", d.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateDistros checks every distribution and rejects duplicate names.
+func validateDistros(distros []*DistroConfig) error {
+	seen := make(map[string]bool, len(distros))
+	for _, distro := range distros {
+		if err := distro.validate(); err != nil {
+			return err
+		}
+		if seen[distro.Name] {
+			return fmt.Errorf("duplicate distribution %q", distro.Name)
+		}
+		seen[distro.Name] = true
+	}
+	return nil
+}
+
+// validateDistrosObject checks the values of the object form of distros. Each value must
+// be null or an object with only tag, formats, and config_overlays.
+func validateDistrosObject(raw map[string]any) error {
+	block, ok := raw["distros"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	for _, name := range sortedKeys(block) {
+		if block[name] == nil {
+			continue
+		}
+		settings, ok := block[name].(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: settings must be an object", name)
+		}
+		for _, key := range sortedKeys(settings) {
+			if key != "tag" && key != "formats" && key != "config_overlays" && key != "compression" {
+				return fmt.Errorf("%s: unknown option %q (allowed: compression, config_overlays, formats, tag)", name, key)
+			}
+		}
+		if err := validateCompressionObject(settings); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// buildUnit is one format to build for every target, optionally for a distribution.
+type buildUnit struct {
+	Format string
+	Distro *DistroConfig
+}
+
+// buildUnits returns what to build for each target: every format once per distribution
+// that uses it, and once without a distribution when none does.
+func buildUnits(cfg *Config) []buildUnit {
+	units := make([]buildUnit, 0, len(cfg.Formats))
+	for _, format := range cfg.Formats {
+		matched := false
+		for _, distro := range cfg.Distros {
+			if slices.Contains(distro.Formats, format) {
+				units = append(units, buildUnit{Format: format, Distro: distro})
+				matched = true
+			}
+		}
+		if !matched {
+			units = append(units, buildUnit{Format: format})
+		}
+	}
+	return units
+}
+
+// forUnit returns the configuration used to build a unit: the format's configuration, with
+// the distribution's output directory, overlays, and compression applied.
+func (cfg *Config) forUnit(unit buildUnit) *Config {
+	formatCfg := cfg.forFormat(unit.Format)
+	if unit.Distro == nil {
+		return formatCfg
+	}
+
+	distroCfg := *formatCfg
+	distroCfg.Distro = unit.Distro
+	distroCfg.OutputDir = filepath.Join(formatCfg.OutputDir, unit.Distro.Name)
+	distroCfg.ConfigOverlays = append(slices.Clip(formatCfg.ConfigOverlays), unit.Distro.ConfigOverlays...)
+	distroCfg.Compression = formatCfg.Compression.merge(unit.Distro.Compression)
+	return &distroCfg
+}
+
+// distroName returns the name of the distribution cfg builds for, or "".
+func (cfg *Config) distroName() string {
+	if cfg.Distro == nil {
+		return ""
+	}
+	return cfg.Distro.Name
+}
+
+// tagRelease appends a distribution tag to the release in doc for formats whose release
+// may carry one: "1.el9" for rpm, "1+jammy" for deb and ipk. apk and archlinux releases
+// must be numeric, so their packages are only told apart by directory. Environment
+// references in the release are resolved with getenv first, as nfpm would. It reports
+// whether doc changed.
+func tagRelease(doc map[string]any, format, tag string, getenv func(string) string) bool {
+	separator := ""
+	switch format {
+	case "rpm":
+		separator = "."
+	case "deb", "ipk":
+		separator = "+"
+	default:
+		return false
+	}
+
+	release := defaultPackageRelease
+	if value, ok := doc["release"]; ok && value != nil {
+		if expanded := os.Expand(fmt.Sprint(value), getenv); expanded != "" {
+			release = expanded
+		}
+	}
+	doc["release"] = release + separator + tag
+	return true
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestParseDistros tests parsing distros as a list and as an object of settings.
+func TestParseDistros(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		raw      map[string]any
+		expected []*DistroConfig
+	}{
+		{
+			name: "unset",
+			raw:  map[string]any{},
+		},
+		{
+			name: "list",
+			raw:  map[string]any{"distros": []any{"el9", "ubuntu-jammy", "alpine3.20", "myos"}},
+			expected: []*DistroConfig{
+				{Name: "el9", Tag: "el9", Formats: []string{"rpm"}},
+				{Name: "ubuntu-jammy", Tag: "jammy", Formats: []string{"deb"}},
+				{Name: "alpine3.20", Tag: "alpine3.20", Formats: []string{"apk"}},
+				{Name: "myos", Tag: "myos"},
+			},
+		},
+		{
+			name: "object",
+			raw: map[string]any{"distros": map[string]any{
+				"el8":  nil,
+				"myos": map[string]any{"tag": "my1", "formats": []any{"deb", "rpm"}, "config_overlays": []any{"myos.yaml"}},
+			}},
+			expected: []*DistroConfig{
+				{Name: "el8", Tag: "el8", Formats: []string{"rpm"}},
+				{Name: "myos", Tag: "my1", Formats: []string{"deb", "rpm"}, ConfigOverlays: []string{"myos.yaml"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := parseDistros(tt.raw); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestValidateDistros tests distros validation.
+func TestValidateDistros(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		distros     any
+		expectError string
+	}{
+		{"list", []any{"el8", "el9", "debian-bookworm"}, ""},
+		{"uninferable", []any{"myos"}, "cannot infer the package format"},
+		{"bad name", []any{"EL9"}, "invalid name"},
+		{"duplicate", []any{"el9", "el9"}, "duplicate"},
+		{"bad tag", map[string]any{"el9": map[string]any{"tag": "el-9"}}, "invalid tag"},
+		{"bad format", map[string]any{"myos": map[string]any{"formats": []any{"msi"}}}, "unsupported format"},
+		{"unknown option", map[string]any{"el9": map[string]any{"depends": []any{"libc"}}}, "unknown option"},
+		{"traversal", map[string]any{"el9": map[string]any{"config_overlays": []any{"../el9.yaml"}}}, "config_overlays"},
+	}
+
+	p := &LinuxPkgPlugin{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := p.Validate(context.Background(), map[string]any{"distros": tt.distros})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectError == "" {
+				if !resp.Valid {
+					t.Errorf("expected valid config, got errors: %v", resp.Errors)
+				}
+				return
+			}
+			if resp.Valid || len(resp.Errors) == 0 || resp.Errors[0].Field != "distros" ||
+				!strings.Contains(resp.Errors[0].Message, tt.expectError) {
+				t.Errorf("expected distros error containing %q, got %v", tt.expectError, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestBuildUnits tests pairing formats with distributions.
+func TestBuildUnits(t *testing.T) {
+	t.Parallel()
+
+	el9 := &DistroConfig{Name: "el9", Tag: "el9", Formats: []string{"rpm"}}
+	jammy := &DistroConfig{Name: "ubuntu-jammy", Tag: "jammy", Formats: []string{"deb"}}
+	cfg := &Config{Formats: []string{"deb", "rpm", "apk"}, Distros: []*DistroConfig{el9, jammy}}
+
+	expected := []buildUnit{
+		{Format: "deb", Distro: jammy},
+		{Format: "rpm", Distro: el9},
+		{Format: "apk"},
+	}
+	if got := buildUnits(cfg); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+// TestTagRelease tests appending distribution tags to package releases.
+func TestTagRelease(t *testing.T) {
+	t.Parallel()
+
+	getenv := func(key string) string {
+		if key == "RELEASE" {
+			return "4"
+		}
+		return ""
+	}
+
+	tests := []struct {
+		format   string
+		release  any
+		expected any
+	}{
+		{"rpm", nil, "1.el9"},
+		{"rpm", "2", "2.el9"},
+		{"deb", 3, "3+el9"},
+		{"ipk", "${RELEASE}", "4+el9"},
+		{"apk", "2", "2"},
+		{"archlinux", nil, nil},
+	}
+
+	for _, tt := range tests {
+		doc := map[string]any{}
+		if tt.release != nil {
+			doc["release"] = tt.release
+		}
+		changed := tagRelease(doc, tt.format, "el9", getenv)
+		if doc["release"] != tt.expected || changed != (tt.release != tt.expected) {
+			t.Errorf("%s release %v: expected %v, got %v (changed=%v)", tt.format, tt.release, tt.expected, doc["release"], changed)
+		}
+	}
+}
+
+// TestExecuteDistros tests building per-distro packages with the embedded library.
+func TestExecuteDistros(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+	if err := os.WriteFile(filepath.Join(dir, "el8.yaml"), []byte("depends:\n  - openssl-libs\n"), 0644); err != nil {
+		t.Fatalf("failed to write overlay: %v", err)
+	}
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb", "rpm"},
+			"distros": map[string]any{
+				"el8":          map[string]any{"config_overlays": []string{"el8.yaml"}},
+				"el9":          nil,
+				"ubuntu-jammy": nil,
+			},
+			"filename_template": "{name}-{version}-{release}.{distro}.{arch}.{ext}",
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	expected := []string{
+		filepath.Join(dir, "dist", "ubuntu-jammy", "myapp-1.2.3-1+jammy.ubuntu-jammy.amd64.deb"),
+		filepath.Join(dir, "dist", "el8", "myapp-1.2.3-1.el8.el8.x86_64.rpm"),
+		filepath.Join(dir, "dist", "el9", "myapp-1.2.3-1.el9.el9.x86_64.rpm"),
+	}
+	if !reflect.DeepEqual(resp.Outputs["packages"], expected) {
+		t.Fatalf("expected packages %v, got %v", expected, resp.Outputs["packages"])
+	}
+	for _, path := range expected {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s to exist: %v", path, err)
+		}
+	}
+
+	artifacts := resp.Outputs["artifacts"].([]map[string]any)
+	if artifacts[0]["distro"] != "ubuntu-jammy" || artifacts[1]["distro"] != "el8" {
+		t.Errorf("expected artifacts to record their distribution, got %v", artifacts)
+	}
+	if !strings.Contains(resp.Message, "3 distribution(s)") {
+		t.Errorf("expected the message to mention distributions, got %q", resp.Message)
+	}
+}
+
+// TestExecuteDistrosOverlay tests that distribution overlays only apply to their builds.
+func TestExecuteDistrosOverlay(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: myapp\nversion: 1.2.3\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "el8.yaml"), []byte("depends:\n  - openssl-libs\n"), 0644); err != nil {
+		t.Fatalf("failed to write overlay: %v", err)
+	}
+
+	docs := make(map[string]map[string]any)
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			doc, err := loadNfpmConfig(args[2])
+			if err != nil {
+				return nil, err
+			}
+			target := args[len(args)-1]
+			docs[target] = doc
+			return []byte("created package: " + target + "myapp.rpm"), nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"rpm"},
+			"packager":    "nfpm-cli",
+			"distros": map[string]any{
+				"el8": map[string]any{"config_overlays": []string{"el8.yaml"}},
+				"el9": nil,
+			},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	el8, el9 := docs[filepath.Join(dir, "dist", "el8")+"/"], docs[filepath.Join(dir, "dist", "el9")+"/"]
+	if el8 == nil || el9 == nil {
+		t.Fatalf("expected builds into dist/el8 and dist/el9, got %v", docs)
+	}
+	if el8["release"] != "1.el8" || el9["release"] != "1.el9" {
+		t.Errorf("expected tagged releases, got %v and %v", el8["release"], el9["release"])
+	}
+	if el8["depends"] == nil || el9["depends"] != nil {
+		t.Errorf("expected only el8 to get the overlay, got %v and %v", el8["depends"], el9["depends"])
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// dryRunPassphraseFile stands in for the temporary file rpmsign reads the key passphrase
+// from, which only exists while signing.
+const dryRunPassphraseFile = "<passphrase file>"
+
+// dryRunCommand is a command a run would execute.
+type dryRunCommand struct {
+	// Step is what the command does: build, package, sign, verify, cosign, or publish.
+	Step      string
+	Format    string
+	Arch      string
+	Publisher string
+	Argv      []string
+	// Env holds the variables added to the command's environment.
+	Env []string
+}
+
+// asMap returns the command as it appears in the commands output.
+func (c dryRunCommand) asMap() map[string]any {
+	command := map[string]any{"step": c.Step, "command": c.Argv}
+	if c.Format != "" {
+		command["format"] = c.Format
+	}
+	if c.Arch != "" {
+		command["arch"] = c.Arch
+	}
+	if c.Publisher != "" {
+		command["publisher"] = c.Publisher
+	}
+	if len(c.Env) > 0 {
+		command["env"] = c.Env
+	}
+	return command
+}
+
+// dryRunCommands lists the commands a run would execute, in order: the Go build of every
+// target, and the nfpm or fpm build, signing, and verification of every package, followed by
+// the APT, YUM, and COPR publishers. The embedded packager builds in-process, so its
+// packages have no nfpm command. Configs are shown by their source path, although they
+// are rendered to a temporary file when overlays, templates, or conversions apply.
+func dryRunCommands(cfg *Config, units []buildUnit, targets []buildTarget, ldflags string, releaseEnv []string, release plugin.ReleaseContext) []map[string]any {
+	var commands []dryRunCommand
+	var debs, rpms []string
+
+	for _, target := range targets {
+		env := releaseEnv
+		if cfg.Build != nil {
+			binary := cfg.Build.binaryPath(cfg.OutputDir, target)
+			commands = append(commands, dryRunCommand{
+				Step: "build",
+				Arch: target.Arch,
+				Argv: append([]string{"go"}, cfg.Build.goBuildArgs(cfg.WorkingDir, binary, ldflags)...),
+				Env:  cfg.Build.goEnv(target),
+			})
+			env = append(slices.Clip(releaseEnv), goBinaryEnv+"="+binary)
+		}
+
+		arch := ""
+		if target.Override {
+			arch = target.nfpmArch()
+		}
+		for _, unit := range units {
+			format, unitCfg := unit.Format, cfg.forUnit(unit)
+			pkg := predictPackagePath(unitCfg, format, arch, env)
+
+			if cfg.Packager == "fpm" {
+				commands = append(commands, dryRunCommand{
+					Step:   "package",
+					Format: format,
+					Arch:   target.Arch,
+					Argv:   append([]string{"fpm"}, dryRunFpmArgs(unitCfg, format, arch, env)...),
+					Env:    env,
+				})
+			} else if !usesEmbeddedNfpm(cfg.Packager) {
+				packageTarget, err := nfpmTarget(unitCfg, unitCfg.ConfigPath, format, arch, target, env)
+				if err != nil {
+					packageTarget = pkg
+				}
+				argv := append([]string{unitCfg.nfpmBinary()}, nfpmPackageArgs(unitCfg.ConfigPath, format, packageTarget)...)
+				if cfg.Packager == "container" {
+					workdir, _ := os.Getwd()
+					argv = append([]string{cfg.Container.runtimeName()}, containerRunArgs(cfg.Container,
+						containerMounts(unitCfg, unitCfg.ConfigPath), cfg.WorkingDir, workdir, containerEnvNames(env), argv[1:])...)
+				}
+				commands = append(commands, dryRunCommand{
+					Step:   "package",
+					Format: format,
+					Arch:   target.Arch,
+					Argv:   argv,
+					Env:    env,
+				})
+			}
+
+			if signing := unitCfg.RPMSigning; format == "rpm" && signing != nil {
+				if signing.Method == "rpmsign" {
+					passphraseFile := ""
+					if os.Getenv(signing.PassphraseEnv) != "" {
+						passphraseFile = dryRunPassphraseFile
+					}
+					commands = append(commands, dryRunCommand{Step: "sign", Format: format, Arch: target.Arch,
+						Argv: append([]string{"rpmsign"}, signing.rpmsignArgs(passphraseFile, pkg)...)})
+				}
+				if signing.Verify {
+					commands = append(commands, dryRunCommand{Step: "verify", Format: format, Arch: target.Arch,
+						Argv: []string{"rpm", "--checksig", pkg}})
+				}
+			}
+
+			if unitCfg.Cosign != nil {
+				_, args := unitCfg.Cosign.signBlobArgs(pkg)
+				commands = append(commands, dryRunCommand{Step: "cosign", Format: format, Arch: target.Arch,
+					Argv: append([]string{"cosign"}, args...)})
+			}
+
+			switch format {
+			case "deb":
+				debs = append(debs, pkg)
+			case "rpm":
+				rpms = append(rpms, pkg)
+			}
+		}
+	}
+
+	if cfg.Publish != nil {
+		commands = append(commands, dryRunPublishCommands(cfg.Publish, debs, rpms, release)...)
+	}
+
+	result := make([]map[string]any, len(commands))
+	for i, command := range commands {
+		result[i] = command.asMap()
+	}
+	return result
+}
+
+// dryRunFpmArgs returns the fpm arguments that would build a package of format, with
+// directories and symlinks staged in a placeholder directory, or only the output type
+// and directory when the config cannot be read as is.
+func dryRunFpmArgs(cfg *Config, format, arch string, env []string) []string {
+	info, err := resolveFpmInfo(cfg.ConfigPath, format, arch, envLookup(env, signingEnv(cfg)))
+	if err == nil {
+		if args, err := fpmArgs(info, format, fpmTarget(cfg, format, info), "<staging>"); err == nil {
+			return args
+		}
+	}
+	return []string{"-s", "dir", "-t", fpmTypes[format], "-p", cfg.OutputDir + "/"}
+}
+
+// dryRunPublishCommands lists the commands of the APT, YUM, and COPR publishers. aptly is
+// shown updating an already published distribution.
+func dryRunPublishCommands(publish *PublishConfig, debs, rpms []string, release plugin.ReleaseContext) []dryRunCommand {
+	var commands []dryRunCommand
+	add := func(publisher string, argv ...string) {
+		commands = append(commands, dryRunCommand{Step: "publish", Publisher: publisher, Argv: argv})
+	}
+
+	if c := publish.APT; c != nil && len(debs) > 0 {
+		if c.Tool == "aptly" {
+			add("apt", append([]string{"aptly", "repo", "add", c.Repo}, debs...)...)
+			add("apt", append([]string{"aptly"}, c.aptlyUpdateArgs()...)...)
+		} else {
+			for _, deb := range debs {
+				add("apt", append([]string{"reprepro"}, c.repreproIncludeArgs(deb)...)...)
+			}
+			if c.Remote != "" {
+				add("apt", append([]string{"rsync"}, c.rsyncArgs()...)...)
+			}
+		}
+	}
+
+	if c := publish.YUM; c != nil && len(rpms) > 0 {
+		add("yum", "createrepo_c", "--update", c.Repo)
+		if c.GPGKey != "" {
+			add("yum", append([]string{"gpg"}, c.signArgs(filepath.Join(c.Repo, "repodata", "repomd.xml"))...)...)
+		}
+	}
+
+	if c := publish.COPR; c != nil {
+		if args, err := c.buildArgs(release); err == nil {
+			add("copr", append([]string{"copr-cli"}, args...)...)
+		}
+	}
+
+	return commands
+}
+
+// predictPackagePath returns the path a package will be built at, read from the nfpm
+// config, or a placeholder in the output directory when the config cannot be read as is.
+func predictPackagePath(cfg *Config, format, arch string, env []string) string {
+	info, packager, err := resolvePackageInfo(cfg.ConfigPath, format, arch, envLookup(env, signingEnv(cfg)))
+	if err != nil {
+		return filepath.Join(cfg.OutputDir, "<"+format+" package>")
+	}
+	return filepath.Join(cfg.OutputDir, packageFilename(cfg.filenameTemplate(), format, packager, info))
+}
+
+// previewNfpmConfigs renders the nfpm config every format, distribution, and target would
+// be built from, with overlays, templates, overrides, and version normalization applied.
+// Remote contents are not downloaded, and files the plugin generates next to a rendered
+// config are removed again, so their paths only show where they would be written.
+func previewNfpmConfigs(ctx context.Context, cfg *Config, units []buildUnit, targets []buildTarget, data *nfpmTemplateData, releaseEnv []string) ([]map[string]any, error) {
+	var previews []map[string]any
+	for _, target := range targets {
+		env := releaseEnv
+		if cfg.Build != nil {
+			env = append(slices.Clip(releaseEnv), goBinaryEnv+"="+cfg.Build.binaryPath(cfg.OutputDir, target))
+		}
+		configArch := ""
+		if target.Override && !usesEmbeddedNfpm(cfg.Packager) {
+			configArch = target.nfpmArch()
+		}
+
+		for _, unit := range units {
+			content, err := renderNfpmPreview(ctx, cfg.forUnit(unit), unit.Format, configArch, data, env)
+			if err != nil {
+				return nil, err
+			}
+			preview := map[string]any{"format": unit.Format, "arch": target.Arch, "config": content}
+			if unit.Distro != nil {
+				preview["distro"] = unit.Distro.Name
+			}
+			previews = append(previews, preview)
+		}
+	}
+	return previews, nil
+}
+
+// renderNfpmPreview returns the nfpm config format would be built from.
+func renderNfpmPreview(ctx context.Context, cfg *Config, format, arch string, data *nfpmTemplateData, env []string) (string, error) {
+	prepared, cleanup, err := prepareNfpmConfig(cfg, arch, data)
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	final, finalCleanup, err := finalizeNfpmConfig(ctx, prepared, format, cfg, data, envLookup(env, os.Getenv), nil)
+	if err != nil {
+		return "", err
+	}
+	defer finalCleanup()
+
+	content, err := os.ReadFile(final)
+	if err != nil {
+		return "", fmt.Errorf("failed to read rendered config: %w", err)
+	}
+	return string(content), nil
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestExecuteDryRunCommands tests that a dry run lists the commands a release would run
+// without running any of them.
+func TestExecuteDryRunCommands(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: myapp\nversion: ${VERSION}\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	mock := &MockCommandExecutor{}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb", "rpm"},
+			"packager":    "nfpm-cli",
+			"target":      "amd64",
+			"build":       map[string]any{"main": "./cmd/myapp"},
+			"rpm_signing": map[string]any{"method": "rpmsign", "key_id": "ABCD1234"},
+			"publish": map[string]any{
+				"apt":  map[string]any{"repo": "apt"},
+				"yum":  map[string]any{"repo": "yum"},
+				"copr": map[string]any{"project": "me/myapp"},
+			},
+		},
+		Context: plugin.ReleaseContext{
+			Version:       "1.2.3",
+			TagName:       "v1.2.3",
+			RepositoryURL: "https://github.com/example/myapp",
+		},
+		DryRun: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+	if len(mock.Calls) != 0 {
+		t.Errorf("expected no commands to run, got %v", mock.Calls)
+	}
+
+	dist := filepath.Join(dir, "dist")
+	binary := filepath.Join(dist, "bin", "amd64", "myapp")
+	deb := filepath.Join(dist, "myapp_1.2.3_amd64.deb")
+	rpm := filepath.Join(dist, "myapp-1.2.3-1.x86_64.rpm")
+	config := filepath.Join(dir, "nfpm.yaml")
+	expected := [][]string{
+		{"go", "-C", dir, "build", "-trimpath", "-o", binary, "./cmd/myapp"},
+		{"nfpm", "package", "--config", config, "--packager", "deb", "--target", dist + "/"},
+		{"nfpm", "package", "--config", config, "--packager", "rpm", "--target", dist + "/"},
+		{"rpmsign", "--addsign", "--define", "_gpg_name ABCD1234", rpm},
+		{"rpm", "--checksig", rpm},
+		{"reprepro", "-b", filepath.Join(dir, "apt"), "-C", "main", "includedeb", "stable", deb},
+		{"createrepo_c", "--update", filepath.Join(dir, "yum")},
+		{"copr-cli", "buildscm", "--nowait", "--clone-url", "https://github.com/example/myapp", "--commit", "v1.2.3", "me/myapp"},
+	}
+
+	commands := resp.Outputs["commands"].([]map[string]any)
+	argvs := make([][]string, len(commands))
+	for i, command := range commands {
+		argvs[i] = command["command"].([]string)
+	}
+	if !reflect.DeepEqual(argvs, expected) {
+		t.Fatalf("expected commands:\n%v\ngot:\n%v", expected, argvs)
+	}
+
+	if commands[0]["step"] != "build" || commands[0]["arch"] != "amd64" {
+		t.Errorf("expected the go build first, got %v", commands[0])
+	}
+	if commands[2]["step"] != "package" || commands[2]["format"] != "rpm" {
+		t.Errorf("expected the rpm build, got %v", commands[2])
+	}
+	if env := commands[2]["env"].([]string); !slices.Contains(env, "VERSION=1.2.3") || !slices.Contains(env, "BINARY="+binary) {
+		t.Errorf("expected the release variables and binary in the nfpm environment, got %v", env)
+	}
+	if commands[5]["step"] != "publish" || commands[5]["publisher"] != "apt" {
+		t.Errorf("expected the apt publisher, got %v", commands[5])
+	}
+}
+
+// TestExecuteDryRunNfpmConfigs tests that a dry run returns the rendered nfpm config of
+// every format and target.
+func TestExecuteDryRunNfpmConfigs(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"nfpm.yaml":    "name: myapp\nversion: ${VERSION}\ndescription: Built from {{ .TagName }}\n",
+		"overlay.yaml": "maintainer: Relicta Team <team@example.com>\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	p := &LinuxPkgPlugin{cmdExecutor: &MockCommandExecutor{}}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir":     dir,
+			"formats":         []string{"deb", "rpm"},
+			"packager":        "nfpm-cli",
+			"targets":         []string{"amd64", "arm64"},
+			"template_config": true,
+			"config_overlays": []string{"overlay.yaml"},
+			"overrides":       map[string]any{"rpm": map[string]any{"depends": []any{"glibc"}}},
+		},
+		Context: plugin.ReleaseContext{Version: "1.3.0-rc.1", TagName: "v1.3.0-rc.1"},
+		DryRun:  true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	previews := resp.Outputs["nfpm_configs"].([]map[string]any)
+	if len(previews) != 4 {
+		t.Fatalf("expected 4 configs, got %v", previews)
+	}
+	for i, want := range [][2]string{{"deb", "amd64"}, {"rpm", "amd64"}, {"deb", "arm64"}, {"rpm", "arm64"}} {
+		preview := previews[i]
+		if preview["format"] != want[0] || preview["arch"] != want[1] {
+			t.Errorf("config %d: expected %s/%s, got %v/%v", i, want[0], want[1], preview["format"], preview["arch"])
+			continue
+		}
+		config := preview["config"].(string)
+		for _, line := range []string{
+			"arch: " + want[1],
+			"description: Built from v1.3.0-rc.1",
+			"maintainer: Relicta Team <team@example.com>",
+			"version: 1.3.0~rc.1",
+		} {
+			if !strings.Contains(config, line+"\n") {
+				t.Errorf("%s/%s: expected %q in config:\n%s", want[0], want[1], line, config)
+			}
+		}
+		if hasDepends := strings.Contains(config, "- glibc"); hasDepends != (want[0] == "rpm") {
+			t.Errorf("%s/%s: expected the glibc override only for rpm:\n%s", want[0], want[1], config)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/goreleaser/nfpm/v2"
+	_ "github.com/goreleaser/nfpm/v2/apk"  // Registers the apk packager.
+	_ "github.com/goreleaser/nfpm/v2/arch" // Registers the archlinux packager.
+	_ "github.com/goreleaser/nfpm/v2/deb"  // Registers the deb packager.
+	_ "github.com/goreleaser/nfpm/v2/ipk"  // Registers the ipk packager.
+	_ "github.com/goreleaser/nfpm/v2/rpm"  // Registers the rpm packager.
+)
+
+// packageResult describes a package produced by a build.
+type packageResult struct {
+	// Path is the location of the built package.
+	Path string
+	// Name is the package name from the nfpm config, when known.
+	Name string
+	// Version is the package version from the nfpm config, when known.
+	Version string
+	// Arch is the package architecture, when known.
+	Arch string
+}
+
+// usesEmbeddedNfpm reports whether a packager builds with the embedded nfpm library.
+func usesEmbeddedNfpm(packager string) bool {
+	return packager == "nfpm"
+}
+
+// parsedNfpmConfig is an nfpm config parsed by resolvePackageInfo.
+type parsedNfpmConfig struct {
+	config  nfpm.Config
+	modTime time.Time
+	size    int64
+	// env holds the environment variables the config references, with the values it was
+	// parsed with.
+	env map[string]string
+}
+
+// parsedNfpmConfigs keeps parsed nfpm configs by path, so the jobs of a matrix and the
+// steps of each job parse a staged config once. Staged configs are dropped when they are
+// cleaned up.
+var (
+	parsedNfpmConfigsMu sync.Mutex
+	parsedNfpmConfigs   = make(map[string]*parsedNfpmConfig)
+)
+
+// parseNfpmConfig parses configPath the way nfpm does, with environment references
+// resolved with getenv. The config parsed before is reused while the file and the
+// environment variables it references are unchanged.
+func parseNfpmConfig(configPath string, getenv func(string) string) (nfpm.Config, error) {
+	stat, err := os.Stat(configPath)
+	if err != nil {
+		return nfpm.Config{}, err
+	}
+	parsedNfpmConfigsMu.Lock()
+	parsed := parsedNfpmConfigs[configPath]
+	parsedNfpmConfigsMu.Unlock()
+	if parsed != nil && parsed.matches(stat, getenv) {
+		return parsed.config, nil
+	}
+
+	env := make(map[string]string)
+	config, err := nfpm.ParseFileWithEnvMapping(configPath, func(name string) string {
+		value := getenv(name)
+		env[name] = value
+		return value
+	})
+	if err != nil {
+		return nfpm.Config{}, err
+	}
+	parsedNfpmConfigsMu.Lock()
+	parsedNfpmConfigs[configPath] = &parsedNfpmConfig{config: config, modTime: stat.ModTime(), size: stat.Size(), env: env}
+	parsedNfpmConfigsMu.Unlock()
+	return config, nil
+}
+
+// matches reports whether a parsed config is still what parsing its file with getenv
+// would return.
+func (c *parsedNfpmConfig) matches(stat os.FileInfo, getenv func(string) string) bool {
+	if !stat.ModTime().Equal(c.modTime) || stat.Size() != c.size {
+		return false
+	}
+	for name, value := range c.env {
+		if getenv(name) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// forgetNfpmConfig drops the parsed config of configPath.
+func forgetNfpmConfig(configPath string) {
+	parsedNfpmConfigsMu.Lock()
+	delete(parsedNfpmConfigs, configPath)
+	parsedNfpmConfigsMu.Unlock()
+}
+
+// resolvePackageInfo parses an nfpm config for format the way nfpm does before it
+// builds, with environment references resolved with getenv. When arch is non-empty it
+// overrides the architecture from the config.
+func resolvePackageInfo(configPath, format, arch string, getenv func(string) string) (*nfpm.Info, nfpm.Packager, error) {
+	config, err := parseNfpmConfig(configPath, getenv)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse nfpm config: %w", err)
+	}
+
+	info, err := config.Get(format)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to resolve %s config: %w", format, err)
+	}
+	if arch != "" {
+		info.Arch = arch
+	}
+	info = nfpm.WithDefaults(info)
+
+	if err := nfpm.Validate(info); err != nil {
+		return nil, nil, fmt.Errorf("invalid nfpm config: %w", err)
+	}
+
+	packager, err := nfpm.Get(format)
+	if err != nil {
+		return nil, nil, err
+	}
+	return info, packager, nil
+}
+
+// buildPackageEmbedded builds a single package with the embedded nfpm library. When
+// arch is non-empty it overrides the architecture from the nfpm config. The package is
+// named after filenameTemplate, or nfpm's conventional name when it is empty. The
+// returned output mirrors what the nfpm CLI prints so logs look the same for both
+// backends. Environment references in the config, including signing passphrases, are
+// resolved with getenv.
+func buildPackageEmbedded(ctx context.Context, configPath, format, arch, outputDir, filenameTemplate string, getenv func(string) string) (*packageResult, []byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	info, packager, err := resolvePackageInfo(configPath, format, arch, getenv)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	output := []byte(fmt.Sprintf("using %s packager...\n", format))
+	target := filepath.Join(outputDir, packageFilename(filenameTemplate, format, packager, info))
+	info.Target = target
+
+	f, err := os.Create(target)
+	if err != nil {
+		return nil, output, fmt.Errorf("failed to create package file: %w", err)
+	}
+
+	if err := packager.Package(info, f); err != nil {
+		f.Close()
+		os.Remove(target)
+		return nil, output, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, output, fmt.Errorf("failed to write package file: %w", err)
+	}
+
+	output = append(output, fmt.Sprintf("created package: %s\n", target)...)
+	return &packageResult{
+		Path:    target,
+		Name:    info.Name,
+		Version: info.Version,
+		Arch:    info.Arch,
+	}, output, nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// writeEmbeddedTestConfig writes a minimal nfpm config with one file to dir.
+func writeEmbeddedTestConfig(t *testing.T, dir, arch string) string {
+	t.Helper()
+
+	extra := ""
+	if arch != "" {
+		extra = "arch: " + arch + "\n"
+	}
+	return writeTestNfpmConfig(t, dir, extra, map[string]string{"/usr/bin/myapp": "#!/bin/sh\necho hello\n"})
+}
+
+// writeTestNfpmConfig writes an nfpm config for myapp 1.2.3 to dir, with the YAML in
+// extra and contents installing each file of files, keyed by destination, from a source
+// in dir named after the destination's base name.
+func writeTestNfpmConfig(t *testing.T, dir, extra string, files map[string]string) string {
+	t.Helper()
+
+	config := "name: myapp\n" +
+		"version: 1.2.3\n" +
+		"maintainer: Relicta Team <team@example.com>\n" +
+		"description: test package\n" +
+		extra +
+		"contents:\n"
+	for _, dst := range sortedKeys(files) {
+		src := filepath.Join(dir, filepath.Base(dst))
+		if err := os.WriteFile(src, []byte(files[dst]), 0755); err != nil {
+			t.Fatalf("failed to write %s: %v", src, err)
+		}
+		config += "  - src: " + src + "\n" +
+			"    dst: " + dst + "\n"
+	}
+
+	path := filepath.Join(dir, "nfpm.yaml")
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+// TestBuildPackageEmbedded tests building every supported format with the embedded library.
+func TestBuildPackageEmbedded(t *testing.T) {
+	t.Parallel()
+
+	for format, ext := range packageExtensions {
+		if fpmOnlyFormats[format] {
+			continue
+		}
+		t.Run(format, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			configPath := writeEmbeddedTestConfig(t, dir, "amd64")
+
+			result, output, err := buildPackageEmbedded(context.Background(), configPath, format, "", dir, "", os.Getenv)
+			if err != nil {
+				t.Fatalf("unexpected error: %v\n%s", err, output)
+			}
+
+			if !strings.HasSuffix(result.Path, ext) {
+				t.Errorf("expected package path ending in %s, got %q", ext, result.Path)
+			}
+			if info, err := os.Stat(result.Path); err != nil || info.Size() == 0 {
+				t.Errorf("expected non-empty package at %s: %v", result.Path, err)
+			}
+			if result.Name != "myapp" || result.Version != "1.2.3" {
+				t.Errorf("unexpected package info: %+v", result)
+			}
+			if !strings.Contains(string(output), "created package: "+result.Path) {
+				t.Errorf("expected output to mention the package, got %q", output)
+			}
+		})
+	}
+}
+
+// TestBuildPackageEmbeddedArchOverride tests that an explicit arch overrides the nfpm config.
+func TestBuildPackageEmbeddedArchOverride(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	configPath := writeEmbeddedTestConfig(t, dir, "amd64")
+
+	result, _, err := buildPackageEmbedded(context.Background(), configPath, "deb", "arm64", dir, "", os.Getenv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Arch != "arm64" {
+		t.Errorf("expected arch arm64, got %q", result.Arch)
+	}
+	if !strings.Contains(filepath.Base(result.Path), "arm64") {
+		t.Errorf("expected arm64 in package filename, got %q", result.Path)
+	}
+}
+
+// TestBuildPackageEmbeddedErrors tests error reporting from the embedded library.
+func TestBuildPackageEmbeddedErrors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("missing name", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		configPath := filepath.Join(dir, "nfpm.yaml")
+		if err := os.WriteFile(configPath, []byte("version: 1.0.0\n"), 0644); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+
+		_, _, err := buildPackageEmbedded(context.Background(), configPath, "deb", "", dir, "", os.Getenv)
+		if err == nil || !strings.Contains(err.Error(), "name") {
+			t.Errorf("expected missing name error, got %v", err)
+		}
+	})
+
+	t.Run("missing content source", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		configPath := filepath.Join(dir, "nfpm.yaml")
+		config := "name: myapp\nversion: 1.0.0\ncontents:\n  - src: " + filepath.Join(dir, "missing") + "\n    dst: /usr/bin/myapp\n"
+		if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+
+		if _, _, err := buildPackageEmbedded(context.Background(), configPath, "rpm", "", dir, "", os.Getenv); err == nil {
+			t.Error("expected error for missing content source")
+		}
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if _, _, err := buildPackageEmbedded(ctx, "nfpm.yaml", "deb", "", t.TempDir(), "", os.Getenv); err == nil {
+			t.Error("expected error for cancelled context")
+		}
+	})
+}
+
+// TestResolvePackageInfoReusesParse tests that a config is parsed once while the file
+// and the environment variables it references are unchanged.
+func TestResolvePackageInfoReusesParse(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "nfpm.yaml")
+	if err := os.WriteFile(configPath, []byte("name: myapp\nversion: ${VERSION}\narch: amd64\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	t.Cleanup(func() { forgetNfpmConfig(configPath) })
+
+	resolve := func(version string) (*parsedNfpmConfig, string) {
+		t.Helper()
+		getenv := func(name string) string {
+			if name == "VERSION" {
+				return version
+			}
+			return ""
+		}
+		info, _, err := resolvePackageInfo(configPath, "deb", "arm64", getenv)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		parsedNfpmConfigsMu.Lock()
+		defer parsedNfpmConfigsMu.Unlock()
+		return parsedNfpmConfigs[configPath], info.Version + "/" + info.Arch
+	}
+
+	first, resolved := resolve("1.2.3")
+	if resolved != "1.2.3/arm64" {
+		t.Fatalf("expected 1.2.3/arm64, got %s", resolved)
+	}
+	if again, _ := resolve("1.2.3"); again != first {
+		t.Error("expected the parsed config to be reused")
+	}
+	if changed, resolved := resolve("1.2.4"); changed == first || resolved != "1.2.4/arm64" {
+		t.Errorf("expected a new parse for a new VERSION, got %s", resolved)
+	}
+
+	forgetNfpmConfig(configPath)
+	parsedNfpmConfigsMu.Lock()
+	_, ok := parsedNfpmConfigs[configPath]
+	parsedNfpmConfigsMu.Unlock()
+	if ok {
+		t.Error("expected the parsed config to be forgotten")
+	}
+}
+
+// TestExecuteEmbeddedBackend tests that the default packager builds without the nfpm binary.
+func TestExecuteEmbeddedBackend(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "")
+
+	mock := &MockCommandExecutor{}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb", "rpm"},
+			"target":      "arm64",
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	if len(mock.Calls) != 0 {
+		t.Errorf("expected no nfpm executions, got %v", mock.Calls)
+	}
+
+	packages, ok := resp.Outputs["packages"].([]string)
+	if !ok || len(packages) != 2 {
+		t.Fatalf("expected 2 packages, got %v", resp.Outputs["packages"])
+	}
+	for _, pkg := range packages {
+		if _, err := os.Stat(pkg); err != nil {
+			t.Errorf("expected package %s to exist: %v", pkg, err)
+		}
+	}
+	if !strings.HasSuffix(packages[0], "_arm64.deb") {
+		t.Errorf("expected arm64 deb, got %q", packages[0])
+	}
+}
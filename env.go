@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// envReferencePattern matches ${VAR} references in config values.
+var envReferencePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// envExpandedFields are the config values in which ${VAR} references are expanded,
+// as paths of keys into the raw config. "*" matches every key of an object or every
+// element of a list.
+var envExpandedFields = [][]string{
+	{"config_path"},
+	{"output_dir"},
+	{"formats", "*", "config_path"},
+	{"formats", "*", "output_dir"},
+	{"packages", "*", "config_path"},
+	{"packages", "*", "output_dir"},
+	{"packages", "*", "formats", "*", "config_path"},
+	{"packages", "*", "formats", "*", "output_dir"},
+	{"apk_key_path"},
+	{"rpm_signing", "key_file"},
+	{"rpm_signing", "key_id"},
+	{"cosign", "key"},
+	{"publish", "apt", "gpg_key"},
+	{"publish", "yum", "gpg_key"},
+	{"publish", "apk", "key_path"},
+	{"publish", "s3", "sse_kms_key_id"},
+}
+
+// expandEnv replaces ${VAR} references in value with environment variables. Unset
+// variables expand to an empty string, or are an error when strict is set.
+func expandEnv(value string, strict bool) (string, error) {
+	var missing []string
+	expanded := envReferencePattern.ReplaceAllStringFunc(value, func(ref string) string {
+		name := envReferencePattern.FindStringSubmatch(ref)[1]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if strict && len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// expandConfigEnv returns a copy of the raw config with ${VAR} references expanded in
+// envExpandedFields. With strict_env, a reference to an unset variable is an error
+// naming the field.
+func expandConfigEnv(raw map[string]any) (map[string]any, error) {
+	strict := helpers.NewConfigParser(raw).GetBool("strict_env", false)
+
+	expanded := raw
+	for _, field := range envExpandedFields {
+		var err error
+		if expanded, err = expandEnvField(expanded, field, "", strict); err != nil {
+			return nil, err
+		}
+	}
+	return expanded, nil
+}
+
+// expandEnvField expands the string at path within obj. Objects along the path are
+// copied before they are changed, so the caller's config is left untouched.
+func expandEnvField(obj map[string]any, path []string, prefix string, strict bool) (map[string]any, error) {
+	keys := []string{path[0]}
+	if path[0] == "*" {
+		keys = sortedKeys(obj)
+	}
+
+	result, copied := obj, false
+	for _, key := range keys {
+		name := key
+		if prefix != "" {
+			name = prefix + "." + key
+		}
+
+		var value any
+		switch v := obj[key].(type) {
+		case string:
+			if len(path) > 1 || !envReferencePattern.MatchString(v) {
+				continue
+			}
+			s, err := expandEnv(v, strict)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			value = s
+		case map[string]any:
+			if len(path) == 1 {
+				continue
+			}
+			nested, err := expandEnvField(v, path[1:], name, strict)
+			if err != nil {
+				return nil, err
+			}
+			value = nested
+		case []any:
+			if len(path) < 3 || path[1] != "*" {
+				continue
+			}
+			nested, err := expandEnvList(v, path[2:], name, strict)
+			if err != nil {
+				return nil, err
+			}
+			value = nested
+		default:
+			continue
+		}
+
+		if !copied {
+			result = make(map[string]any, len(obj))
+			for k, v := range obj {
+				result[k] = v
+			}
+			copied = true
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+// expandEnvList expands the strings at path within every object of list. The list is
+// copied, so the caller's config is left untouched.
+func expandEnvList(list []any, path []string, prefix string, strict bool) ([]any, error) {
+	result := slices.Clone(list)
+	for i, item := range list {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		nested, err := expandEnvField(obj, path, fmt.Sprintf("%s[%d]", prefix, i), strict)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = nested
+	}
+	return result, nil
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestExpandEnv tests expanding ${VAR} references.
+// Note: This test cannot run in parallel due to t.Setenv usage.
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("LINUXPKG_TEST_STAGE", "staging")
+	t.Setenv("LINUXPKG_TEST_EMPTY", "")
+
+	tests := []struct {
+		name        string
+		value       string
+		strict      bool
+		expected    string
+		expectError string
+	}{
+		{name: "no references", value: "dist", expected: "dist"},
+		{name: "reference", value: "dist/${LINUXPKG_TEST_STAGE}", expected: "dist/staging"},
+		{name: "bare dollar is kept", value: "$LINUXPKG_TEST_STAGE/${LINUXPKG_TEST_STAGE}", expected: "$LINUXPKG_TEST_STAGE/staging"},
+		{name: "set but empty", value: "a${LINUXPKG_TEST_EMPTY}b", strict: true, expected: "ab"},
+		{name: "unset", value: "nfpm-${LINUXPKG_TEST_UNSET}.yaml", expected: "nfpm-.yaml"},
+		{name: "unset strict", value: "nfpm-${LINUXPKG_TEST_UNSET}.yaml", strict: true, expectError: "LINUXPKG_TEST_UNSET is not set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expandEnv(tt.value, tt.strict)
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Fatalf("expected error containing %q, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestExpandConfigEnv tests that only the supported fields are expanded and the caller's
+// config is left untouched.
+// Note: This test cannot run in parallel due to t.Setenv usage.
+func TestExpandConfigEnv(t *testing.T) {
+	t.Setenv("LINUXPKG_TEST_STAGE", "staging")
+	t.Setenv("LINUXPKG_TEST_KEY", "ABCD1234")
+
+	raw := map[string]any{
+		"config_path": "nfpm.${LINUXPKG_TEST_STAGE}.yaml",
+		"output_dir":  "dist/${LINUXPKG_TEST_STAGE}",
+		"packager":    "${LINUXPKG_TEST_STAGE}",
+		"formats": map[string]any{
+			"deb": map[string]any{"output_dir": "dist/${LINUXPKG_TEST_STAGE}/deb"},
+			"rpm": nil,
+		},
+		"packages": []any{
+			map[string]any{
+				"name":        "api",
+				"config_path": "api/nfpm.${LINUXPKG_TEST_STAGE}.yaml",
+				"output_dir":  "dist/${LINUXPKG_TEST_STAGE}/api",
+				"formats": map[string]any{
+					"rpm": map[string]any{"config_path": "api/rpm.${LINUXPKG_TEST_STAGE}.yaml"},
+				},
+			},
+			map[string]any{"name": "${LINUXPKG_TEST_STAGE}", "config_path": "cli/nfpm.yaml"},
+		},
+		"publish": map[string]any{
+			"apt": map[string]any{"repo": "repo/${LINUXPKG_TEST_STAGE}", "gpg_key": "${LINUXPKG_TEST_KEY}"},
+		},
+	}
+
+	got, err := expandConfigEnv(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]any{
+		"config_path": "nfpm.staging.yaml",
+		"output_dir":  "dist/staging",
+		"packager":    "${LINUXPKG_TEST_STAGE}",
+		"formats": map[string]any{
+			"deb": map[string]any{"output_dir": "dist/staging/deb"},
+			"rpm": nil,
+		},
+		"packages": []any{
+			map[string]any{
+				"name":        "api",
+				"config_path": "api/nfpm.staging.yaml",
+				"output_dir":  "dist/staging/api",
+				"formats": map[string]any{
+					"rpm": map[string]any{"config_path": "api/rpm.staging.yaml"},
+				},
+			},
+			map[string]any{"name": "${LINUXPKG_TEST_STAGE}", "config_path": "cli/nfpm.yaml"},
+		},
+		"publish": map[string]any{
+			"apt": map[string]any{"repo": "repo/${LINUXPKG_TEST_STAGE}", "gpg_key": "ABCD1234"},
+		},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if raw["config_path"] != "nfpm.${LINUXPKG_TEST_STAGE}.yaml" || raw["publish"].(map[string]any)["apt"].(map[string]any)["gpg_key"] != "${LINUXPKG_TEST_KEY}" {
+		t.Errorf("expected the caller's config to be unchanged, got %v", raw)
+	}
+	if raw["packages"].([]any)[0].(map[string]any)["config_path"] != "api/nfpm.${LINUXPKG_TEST_STAGE}.yaml" {
+		t.Errorf("expected the caller's packages to be unchanged, got %v", raw["packages"])
+	}
+
+	raw["strict_env"] = true
+	raw["packages"] = []any{map[string]any{"name": "api", "config_path": "${LINUXPKG_TEST_UNSET}/nfpm.yaml"}}
+	if _, err := expandConfigEnv(raw); err == nil || !strings.Contains(err.Error(), "packages[0].config_path: environment variable LINUXPKG_TEST_UNSET is not set") {
+		t.Errorf("expected an error naming packages[0].config_path, got %v", err)
+	}
+	delete(raw, "packages")
+
+	raw["strict_env"] = true
+	raw["cosign"] = map[string]any{"mode": "key", "key": "${LINUXPKG_TEST_UNSET}"}
+	if _, err := expandConfigEnv(raw); err == nil || !strings.Contains(err.Error(), "cosign.key: environment variable LINUXPKG_TEST_UNSET is not set") {
+		t.Errorf("expected an error naming cosign.key, got %v", err)
+	}
+}
+
+// TestValidateStrictEnv tests that Validate checks expanded values and strict mode.
+// Note: This test cannot run in parallel due to t.Setenv usage.
+func TestValidateStrictEnv(t *testing.T) {
+	t.Setenv("LINUXPKG_TEST_ABS", "/etc")
+
+	tests := []struct {
+		name        string
+		config      map[string]any
+		expectValid bool
+	}{
+		{"unset lenient", map[string]any{"output_dir": "dist${LINUXPKG_TEST_UNSET}"}, true},
+		{"unset strict", map[string]any{"output_dir": "dist${LINUXPKG_TEST_UNSET}", "strict_env": true}, false},
+		{"expands to absolute path", map[string]any{"config_path": "${LINUXPKG_TEST_ABS}/nfpm.yaml"}, false},
+	}
+
+	p := &LinuxPkgPlugin{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := p.Validate(context.Background(), tt.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Valid != tt.expectValid {
+				t.Errorf("expected valid=%v, got valid=%v, errors=%v", tt.expectValid, resp.Valid, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestExecuteStrictEnv tests that Execute expands paths and fails on unset variables in
+// strict mode.
+// Note: This test cannot run in parallel due to t.Setenv usage.
+func TestExecuteStrictEnv(t *testing.T) {
+	t.Setenv("LINUXPKG_TEST_STAGE", "staging")
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"output_dir": "dist/${LINUXPKG_TEST_STAGE}", "strict_env": true},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+		DryRun:  true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success || resp.Outputs["output_dir"] != "dist/staging" {
+		t.Errorf("expected output_dir dist/staging, got success=%v outputs=%v error=%s", resp.Success, resp.Outputs, resp.Error)
+	}
+
+	resp, err = p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"config_path": "nfpm-${LINUXPKG_TEST_UNSET}.yaml", "strict_env": true},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+		DryRun:  true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "config_path: environment variable LINUXPKG_TEST_UNSET is not set") {
+		t.Errorf("expected a strict_env failure, got success=%v error=%s", resp.Success, resp.Error)
+	}
+}
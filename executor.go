@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+)
+
+// ExecSpec describes a command for a CommandExecutor to run.
+type ExecSpec struct {
+	Name string
+	Args []string
+	// Env holds "KEY=value" variables added to the inherited environment, e.g. to hand
+	// credentials to a CLI without exposing them in arguments.
+	Env []string
+	// Dir is the directory the command runs in. Empty means the current directory.
+	Dir string
+	// Stdin, when set, is fed to the command's standard input, e.g. for passphrases.
+	Stdin io.Reader
+	// Output, when set, receives stdout and stderr as they are written, in addition to
+	// them being collected in the ExecResult.
+	Output io.Writer
+}
+
+// ExecResult holds the output of a command.
+type ExecResult struct {
+	Stdout []byte
+	Stderr []byte
+	// Combined interleaves stdout and stderr in the order they were written.
+	Combined []byte
+}
+
+// CommandExecutor abstracts command execution for testability. Exec returns the output
+// collected so far along with any error, so failures can be reported with it.
+type CommandExecutor interface {
+	Exec(ctx context.Context, spec ExecSpec) (*ExecResult, error)
+}
+
+// RealCommandExecutor executes real shell commands.
+type RealCommandExecutor struct{}
+
+// Exec runs the command described by spec.
+func (e *RealCommandExecutor) Exec(ctx context.Context, spec ExecSpec) (*ExecResult, error) {
+	cmd := exec.CommandContext(ctx, spec.Name, spec.Args...)
+	if len(spec.Env) > 0 {
+		cmd.Env = append(os.Environ(), spec.Env...)
+	}
+	cmd.Dir = spec.Dir
+	cmd.Stdin = spec.Stdin
+
+	var stdout, stderr bytes.Buffer
+	combined := &lockedBuffer{tee: spec.Output}
+	cmd.Stdout = io.MultiWriter(&stdout, combined)
+	cmd.Stderr = io.MultiWriter(&stderr, combined)
+	err := cmd.Run()
+	return &ExecResult{Stdout: stdout.Bytes(), Stderr: stderr.Bytes(), Combined: combined.Bytes()}, err
+}
+
+// lockedBuffer is a bytes.Buffer that stdout and stderr can be copied into concurrently.
+// Writes are also copied to tee, if set; failing to do so does not fail the command.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+	tee io.Writer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tee != nil {
+		_, _ = b.tee.Write(p)
+	}
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Bytes()
+}
+
+// runCommand runs a command and returns its combined output.
+func runCommand(ctx context.Context, executor CommandExecutor, name string, args ...string) ([]byte, error) {
+	return runWithEnv(ctx, executor, nil, name, args...)
+}
+
+// runWithEnv runs a command with extra environment variables and returns its combined
+// output.
+func runWithEnv(ctx context.Context, executor CommandExecutor, env []string, name string, args ...string) ([]byte, error) {
+	result, err := executor.Exec(ctx, ExecSpec{Name: name, Args: args, Env: env})
+	if result == nil {
+		return nil, err
+	}
+	return result.Combined, err
+}
+
+// CommandError is returned when a command fails, with what orchestrators need to
+// classify the failure.
+type CommandError struct {
+	Cmd  string
+	Args []string
+	// ExitCode is the command's exit status, or -1 if it did not exit normally, e.g.
+	// because it could not be started or was killed.
+	ExitCode int
+	Stderr   string
+	// Err is the underlying error from running the command.
+	Err error
+}
+
+// newCommandError describes the failure err of the command run from spec.
+func newCommandError(spec ExecSpec, result *ExecResult, err error) *CommandError {
+	cmdErr := &CommandError{Cmd: spec.Name, Args: spec.Args, ExitCode: -1, Err: err}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		cmdErr.ExitCode = exitErr.ExitCode()
+	}
+	if result != nil {
+		cmdErr.Stderr = string(result.Stderr)
+	}
+	return cmdErr
+}
+
+func (e *CommandError) Error() string {
+	if e.ExitCode >= 0 {
+		return fmt.Sprintf("%s exited with status %d", e.Cmd, e.ExitCode)
+	}
+	return fmt.Sprintf("%s: %v", e.Cmd, e.Err)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// asMap returns the error's fields as they appear in the errors output.
+func (e *CommandError) asMap() map[string]any {
+	return map[string]any{
+		"cmd":       e.Cmd,
+		"args":      e.Args,
+		"exit_code": e.ExitCode,
+		"stderr":    e.Stderr,
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestRealCommandExecutorExec tests running a command with an environment, working
+// directory, and stdin, and collecting its output streams.
+func TestRealCommandExecutorExec(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not installed")
+	}
+
+	dir := t.TempDir()
+	e := &RealCommandExecutor{}
+	result, err := e.Exec(context.Background(), ExecSpec{
+		Name:  "sh",
+		Args:  []string{"-c", `read line; echo "$line $GREETING $(pwd)"; echo oops >&2`},
+		Env:   []string{"GREETING=world"},
+		Dir:   dir,
+		Stdin: strings.NewReader("hello\n"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := "hello world " + dir + "\n"; string(result.Stdout) != expected {
+		t.Errorf("expected stdout %q, got %q", expected, result.Stdout)
+	}
+	if string(result.Stderr) != "oops\n" {
+		t.Errorf("expected stderr %q, got %q", "oops\n", result.Stderr)
+	}
+	// The streams are read concurrently, so only the order within each is kept.
+	if combined := string(result.Combined); len(combined) != len(result.Stdout)+len(result.Stderr) ||
+		!strings.Contains(combined, "hello world "+dir+"\n") || !strings.Contains(combined, "oops\n") {
+		t.Errorf("expected combined output of both streams, got %q", combined)
+	}
+
+	result, err = e.Exec(context.Background(), ExecSpec{Name: "sh", Args: []string{"-c", "echo failed >&2; exit 3"}})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if result == nil || string(result.Combined) != "failed\n" {
+		t.Errorf("expected output with the error, got %+v", result)
+	}
+}
+
+// TestRunWithEnv tests that the helpers hand the environment to the executor and return
+// the combined output.
+func TestRunWithEnv(t *testing.T) {
+	t.Parallel()
+
+	mock := &MockCommandExecutor{
+		ExecFunc: func(ctx context.Context, spec ExecSpec) (*ExecResult, error) {
+			return &ExecResult{Stdout: []byte("out"), Stderr: []byte("err"), Combined: []byte("outerr")}, nil
+		},
+	}
+	output, err := runWithEnv(context.Background(), mock, []string{"TOKEN=secret"}, "aws", "s3", "cp")
+	if err != nil || string(output) != "outerr" {
+		t.Errorf("expected combined output, got %q (%v)", output, err)
+	}
+	if _, err := runCommand(context.Background(), mock, "nfpm", "--version"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mock.Calls[0].Env[0] != "TOKEN=secret" || mock.Calls[1].Env != nil {
+		t.Errorf("expected env only on the first call, got %v", mock.Calls)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/goreleaser/nfpm/v2"
+)
+
+// filenamePlaceholders are the placeholders a filename template may use.
+var filenamePlaceholders = map[string]bool{
+	"{name}":    true,
+	"{version}": true,
+	"{release}": true,
+	"{arch}":    true,
+	"{format}":  true,
+	"{ext}":     true,
+	"{distro}":  true,
+	"{variant}": true,
+}
+
+// filenamePlaceholderPattern matches anything that looks like a placeholder.
+var filenamePlaceholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+// validateFilenameTemplate checks a package filename template. It must only use known
+// placeholders and must not contain path separators.
+func validateFilenameTemplate(template string) error {
+	if template == "" {
+		return nil
+	}
+	for _, placeholder := range filenamePlaceholderPattern.FindAllString(template, -1) {
+		if !filenamePlaceholders[placeholder] {
+			return fmt.Errorf("unknown placeholder %s (allowed: %s)", placeholder, strings.Join(sortedKeys(filenamePlaceholders), ", "))
+		}
+	}
+	if strings.ContainsAny(template, `/\`) || template == "." || template == ".." {
+		return fmt.Errorf("must be a file name, not a path: %s", template)
+	}
+	return nil
+}
+
+// validateFilenameMatrix checks that a filename template tells the packages of a build
+// matrix apart.
+func validateFilenameMatrix(template string, formats, targets, variants int) error {
+	if template == "" {
+		return nil
+	}
+	if formats > 1 && !strings.Contains(template, "{format}") && !strings.Contains(template, "{ext}") {
+		return fmt.Errorf("must contain {format} or {ext} when building several formats")
+	}
+	if targets > 1 && !strings.Contains(template, "{arch}") {
+		return fmt.Errorf("must contain {arch} when building several targets")
+	}
+	// deb and ipk use armhf for both arm/v6 and arm/v7.
+	if variants > 1 && !strings.Contains(template, "{variant}") {
+		return fmt.Errorf("must contain {variant} when building several ARM variants")
+	}
+	return nil
+}
+
+// filenameTemplate returns cfg's filename template with {distro} replaced by the
+// distribution the build targets, or removed for builds without one.
+func (cfg *Config) filenameTemplate() string {
+	return strings.ReplaceAll(cfg.FilenameTemplate, "{distro}", cfg.distroName())
+}
+
+// sharedARMArchs are the native architectures a format uses for several ARM variants.
+var sharedARMArchs = map[string]string{
+	"deb": "armhf",
+	"ipk": "armhf",
+}
+
+// packageFilename returns the file name of a package: filenameTemplate with its
+// placeholders replaced, or nfpm's conventional name when the template is empty. The
+// architecture is the format's native name (x86_64 for rpm, amd64 for deb). Conventional
+// names whose architecture does not identify the ARM variant get it appended
+// (myapp_1.2.3_armhf-v7.deb).
+func packageFilename(filenameTemplate, format string, packager nfpm.Packager, info *nfpm.Info) string {
+	variant := armVariant(info.Arch)
+	// ConventionalFileName translates info.Arch into the format's native name.
+	conventional := packager.ConventionalFileName(info)
+	if filenameTemplate == "" {
+		if variant != "" && sharedARMArchs[format] == info.Arch {
+			if i := strings.LastIndex(conventional, info.Arch); i >= 0 {
+				i += len(info.Arch)
+				conventional = conventional[:i] + "-" + variant + conventional[i:]
+			}
+		}
+		return conventional
+	}
+
+	return strings.NewReplacer(
+		"{name}", info.Name,
+		"{version}", packageVersion(format, info),
+		"{release}", info.Release,
+		"{arch}", info.Arch,
+		"{format}", format,
+		"{ext}", strings.TrimPrefix(packageExtensions[format], "."),
+		"{variant}", variant,
+	).Replace(filenameTemplate)
+}
+
+// packageVersion returns the version a package of format carries, including any
+// prerelease and build metadata nfpm split off, without the release.
+func packageVersion(format string, info *nfpm.Info) string {
+	if info.Prerelease == "" && info.VersionMetadata == "" {
+		return info.Version
+	}
+	version := info.Version
+	if info.Prerelease != "" {
+		version += "-" + info.Prerelease
+	}
+	if info.VersionMetadata != "" {
+		version += "+" + info.VersionMetadata
+	}
+	if native, ok := nativeVersion(format, version); ok {
+		return native
+	}
+	return info.Version
+}
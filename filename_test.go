@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestValidateFilenameTemplate tests filename template validation.
+func TestValidateFilenameTemplate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		template    string
+		expectError string
+	}{
+		{"", ""},
+		{"{name}_{version}_{arch}.{format}", ""},
+		{"{name}-{version}-{release}.{arch}.{ext}", ""},
+		{"{name}_{commit}.deb", "unknown placeholder {commit}"},
+		{"pkgs/{name}.{ext}", "must be a file name"},
+		{"..", "must be a file name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateFilenameTemplate(tt.template)
+			if tt.expectError == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+				t.Errorf("expected error containing %q, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+// TestValidateFilenameMatrix tests that templates must tell matrix packages apart.
+func TestValidateFilenameMatrix(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		template    string
+		formats     int
+		targets     int
+		variants    int
+		expectError bool
+	}{
+		{"", 2, 2, 2, false},
+		{"{name}.deb", 1, 1, 0, false},
+		{"{name}.{ext}", 2, 1, 0, false},
+		{"{name}_{arch}.deb", 2, 1, 0, true},
+		{"{name}.{format}", 1, 2, 0, true},
+		{"{name}_{arch}.{format}", 2, 2, 1, false},
+		{"{name}_{arch}.{format}", 1, 2, 2, true},
+		{"{name}_{arch}{variant}.{format}", 1, 2, 2, false},
+	}
+
+	for _, tt := range tests {
+		if err := validateFilenameMatrix(tt.template, tt.formats, tt.targets, tt.variants); (err != nil) != tt.expectError {
+			t.Errorf("%q with %d format(s) x %d target(s) (%d ARM variant(s)): expected error=%v, got %v", tt.template, tt.formats, tt.targets, tt.variants, tt.expectError, err)
+		}
+	}
+}
+
+// TestExecuteFilenameTemplateEmbedded tests naming packages built with the embedded library.
+func TestExecuteFilenameTemplateEmbedded(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir":       dir,
+			"formats":           []string{"deb", "rpm", "archlinux"},
+			"filename_template": "{name}_{version}_{arch}.{ext}",
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	expected := []string{
+		filepath.Join(dir, "dist", "myapp_1.2.3_amd64.deb"),
+		filepath.Join(dir, "dist", "myapp_1.2.3_x86_64.rpm"),
+		filepath.Join(dir, "dist", "myapp_1.2.3_x86_64.pkg.tar.zst"),
+	}
+	if !reflect.DeepEqual(resp.Outputs["packages"], expected) {
+		t.Errorf("expected packages %v, got %v", expected, resp.Outputs["packages"])
+	}
+	for _, path := range expected {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s to exist: %v", path, err)
+		}
+	}
+}
+
+// TestExecuteFilenameTemplateCLI tests that nfpm-cli is given the package file as its target.
+func TestExecuteFilenameTemplateCLI(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: myapp\nversion: ${VERSION}\nrelease: \"3\"\narch: arm64\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("using rpm packager...\n"), nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir":       dir,
+			"formats":           []string{"rpm"},
+			"packager":          "nfpm-cli",
+			"filename_template": "{name}-{version}-{release}.{arch}.{format}",
+		},
+		Context: plugin.ReleaseContext{Version: "2.0.0-rc.1"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	target := filepath.Join(dir, "dist", "myapp-2.0.0~rc.1-3.aarch64.rpm")
+	args := mock.Calls[0].Args
+	if args[len(args)-2] != "--target" || args[len(args)-1] != target {
+		t.Errorf("expected --target %s, got %v", target, args)
+	}
+	if packages := resp.Outputs["packages"].([]string); len(packages) != 1 || packages[0] != target {
+		t.Errorf("expected package %s, got %v", target, packages)
+	}
+}
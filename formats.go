@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// defaultFormats are built when no formats are configured.
+var defaultFormats = []string{"deb", "rpm"}
+
+// FormatConfig overrides settings for a single package format.
+type FormatConfig struct {
+	// ConfigPath is the nfpm config used for this format. Empty uses config_path.
+	ConfigPath string
+	// OutputDir is where this format's packages are written. Empty uses output_dir.
+	OutputDir string
+}
+
+// parseFormats reads the formats option, which is either a list of format names or an
+// object mapping format names to per-format overrides. In the object form formats are
+// built in sorted order, and only formats with overrides appear in the returned map.
+func parseFormats(raw map[string]any) ([]string, map[string]*FormatConfig) {
+	block, ok := raw["formats"].(map[string]any)
+	if !ok {
+		formats := helpers.NewConfigParser(raw).GetStringSlice("formats", defaultFormats)
+		if len(formats) == 0 {
+			formats = defaultFormats
+		}
+		return formats, nil
+	}
+
+	formats := sortedKeys(block)
+	if len(formats) == 0 {
+		return defaultFormats, nil
+	}
+
+	overrides := make(map[string]*FormatConfig)
+	for _, format := range formats {
+		settings, ok := block[format].(map[string]any)
+		if !ok {
+			continue
+		}
+		parser := helpers.NewConfigParser(settings)
+		override := &FormatConfig{
+			ConfigPath: parser.GetString("config_path", "", ""),
+			OutputDir:  parser.GetString("output_dir", "", ""),
+		}
+		if *override != (FormatConfig{}) {
+			overrides[format] = override
+		}
+	}
+	return formats, overrides
+}
+
+// validateFormatsObject checks the values of the object form of formats. Each value
+// must be null or an object with only config_path and output_dir.
+func validateFormatsObject(raw map[string]any) error {
+	block, ok := raw["formats"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	for _, format := range sortedKeys(block) {
+		if block[format] == nil {
+			continue
+		}
+		settings, ok := block[format].(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: overrides must be an object", format)
+		}
+		for _, key := range sortedKeys(settings) {
+			value, isString := settings[key].(string)
+			switch {
+			case key != "config_path" && key != "output_dir":
+				return fmt.Errorf("%s: unknown option %q (allowed: config_path, output_dir)", format, key)
+			case !isString:
+				return fmt.Errorf("%s: %s must be a string", format, key)
+			}
+			if err := validatePath(value); err != nil {
+				return fmt.Errorf("%s: invalid %s: %w", format, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// forFormat returns the configuration used to build one format: cfg itself, or a copy
+// with the format's config path and output directory applied.
+func (cfg *Config) forFormat(format string) *Config {
+	override, ok := cfg.FormatConfigs[format]
+	if !ok {
+		return cfg
+	}
+
+	formatCfg := *cfg
+	if override.ConfigPath != "" {
+		formatCfg.ConfigPath = override.ConfigPath
+	}
+	if override.OutputDir != "" {
+		formatCfg.OutputDir = override.OutputDir
+	}
+	return &formatCfg
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestParseFormats tests parsing formats as a list and as an object of overrides.
+func TestParseFormats(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name              string
+		raw               map[string]any
+		expectedFormats   []string
+		expectedOverrides map[string]*FormatConfig
+	}{
+		{
+			name:            "default",
+			raw:             map[string]any{},
+			expectedFormats: []string{"deb", "rpm"},
+		},
+		{
+			name:            "list",
+			raw:             map[string]any{"formats": []any{"apk", "deb"}},
+			expectedFormats: []string{"apk", "deb"},
+		},
+		{
+			name:            "empty object",
+			raw:             map[string]any{"formats": map[string]any{}},
+			expectedFormats: []string{"deb", "rpm"},
+		},
+		{
+			name: "object",
+			raw: map[string]any{"formats": map[string]any{
+				"rpm": map[string]any{"config_path": "nfpm-rpm.yaml", "output_dir": "dist/rpm"},
+				"deb": map[string]any{"config_path": "nfpm-deb.yaml"},
+				"apk": nil,
+			}},
+			expectedFormats: []string{"apk", "deb", "rpm"},
+			expectedOverrides: map[string]*FormatConfig{
+				"deb": {ConfigPath: "nfpm-deb.yaml"},
+				"rpm": {ConfigPath: "nfpm-rpm.yaml", OutputDir: "dist/rpm"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			formats, overrides := parseFormats(tt.raw)
+			if !reflect.DeepEqual(formats, tt.expectedFormats) {
+				t.Errorf("expected formats %v, got %v", tt.expectedFormats, formats)
+			}
+			if len(overrides) != len(tt.expectedOverrides) {
+				t.Fatalf("expected %d overrides, got %d", len(tt.expectedOverrides), len(overrides))
+			}
+			for format, expected := range tt.expectedOverrides {
+				if !reflect.DeepEqual(overrides[format], expected) {
+					t.Errorf("expected %s override %+v, got %+v", format, expected, overrides[format])
+				}
+			}
+		})
+	}
+}
+
+// TestConfigForFormat tests applying per-format overrides to the configuration.
+func TestConfigForFormat(t *testing.T) {
+	t.Parallel()
+
+	cfg := &Config{
+		ConfigPath: "nfpm.yaml",
+		OutputDir:  "dist",
+		FormatConfigs: map[string]*FormatConfig{
+			"rpm": {ConfigPath: "nfpm-rpm.yaml"},
+		},
+	}
+
+	if got := cfg.forFormat("deb"); got != cfg {
+		t.Errorf("expected formats without overrides to use the base config")
+	}
+	rpm := cfg.forFormat("rpm")
+	if rpm.ConfigPath != "nfpm-rpm.yaml" || rpm.OutputDir != "dist" {
+		t.Errorf("expected nfpm-rpm.yaml in dist, got %s in %s", rpm.ConfigPath, rpm.OutputDir)
+	}
+	if cfg.ConfigPath != "nfpm.yaml" {
+		t.Errorf("expected the base config to be unchanged, got %s", cfg.ConfigPath)
+	}
+}
+
+// TestValidateFormatsObject tests validation of per-format overrides.
+func TestValidateFormatsObject(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		formats     any
+		expectValid bool
+	}{
+		{"list", []any{"deb", "rpm"}, true},
+		{"overrides", map[string]any{"deb": map[string]any{"config_path": "nfpm-deb.yaml", "output_dir": "dist/deb"}}, true},
+		{"null override", map[string]any{"deb": nil}, true},
+		{"unsupported format", map[string]any{"msi": nil}, false},
+		{"override not an object", map[string]any{"deb": "nfpm-deb.yaml"}, false},
+		{"unknown option", map[string]any{"deb": map[string]any{"packager": "native"}}, false},
+		{"non-string option", map[string]any{"deb": map[string]any{"output_dir": 1}}, false},
+		{"path traversal", map[string]any{"rpm": map[string]any{"config_path": "../nfpm.yaml"}}, false},
+		{"absolute output dir", map[string]any{"rpm": map[string]any{"output_dir": "/tmp/rpm"}}, false},
+	}
+
+	p := &LinuxPkgPlugin{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := p.Validate(context.Background(), map[string]any{"formats": tt.formats})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Valid != tt.expectValid {
+				t.Errorf("expected valid=%v, got valid=%v, errors=%v", tt.expectValid, resp.Valid, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestExecutePerFormatConfig tests building each format from its own nfpm config into
+// its own output directory.
+func TestExecutePerFormatConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	configPath := writeEmbeddedTestConfig(t, dir, "amd64")
+
+	base, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	rpmConfig := strings.Replace(string(base), "name: myapp", "name: myapp-rpm", 1)
+	if err := os.WriteFile(filepath.Join(dir, "nfpm-rpm.yaml"), []byte(rpmConfig), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats": map[string]any{
+				"deb": nil,
+				"rpm": map[string]any{"config_path": "nfpm-rpm.yaml", "output_dir": "dist/rpm"},
+			},
+			"checksums": []string{"sha256"},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	packages := resp.Outputs["packages"].([]string)
+	if len(packages) != 2 {
+		t.Fatalf("expected 2 packages, got %v", packages)
+	}
+	if pkgDir, name := filepath.Split(packages[0]); filepath.Clean(pkgDir) != filepath.Join(dir, "dist") || !strings.HasPrefix(name, "myapp_") {
+		t.Errorf("expected the deb package from nfpm.yaml in dist, got %s", packages[0])
+	}
+	if pkgDir, name := filepath.Split(packages[1]); filepath.Clean(pkgDir) != filepath.Join(dir, "dist", "rpm") || !strings.HasPrefix(name, "myapp-rpm-") {
+		t.Errorf("expected the rpm package from nfpm-rpm.yaml in dist/rpm, got %s", packages[1])
+	}
+
+	sums, err := os.ReadFile(filepath.Join(dir, "dist", "SHA256SUMS"))
+	if err != nil {
+		t.Fatalf("failed to read checksums: %v", err)
+	}
+	if !strings.Contains(string(sums), "rpm/"+filepath.Base(packages[1])) {
+		t.Errorf("expected checksums to list the rpm package under rpm/, got:\n%s", sums)
+	}
+}
+
+// TestExecuteDryRunFormatConfigs tests that a dry run reports per-format overrides.
+func TestExecuteDryRunFormatConfigs(t *testing.T) {
+	t.Parallel()
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"formats": map[string]any{
+				"deb": nil,
+				"rpm": map[string]any{"output_dir": "dist/rpm"},
+			},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+		DryRun:  true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	expected := map[string]any{
+		"deb": map[string]any{"config_path": "nfpm.yaml", "output_dir": "dist"},
+		"rpm": map[string]any{"config_path": "nfpm.yaml", "output_dir": "dist/rpm"},
+	}
+	if !reflect.DeepEqual(resp.Outputs["format_configs"], expected) {
+		t.Errorf("expected format_configs %v, got %v", expected, resp.Outputs["format_configs"])
+	}
+}
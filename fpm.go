@@ -0,0 +1,322 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/goreleaser/nfpm/v2"
+	"github.com/goreleaser/nfpm/v2/files"
+)
+
+// fpmTypes maps package formats to the fpm output types that build them.
+var fpmTypes = map[string]string{
+	"deb":       "deb",
+	"rpm":       "rpm",
+	"apk":       "apk",
+	"archlinux": "pacman",
+	"sh":        "sh",
+	"tar":       "tar",
+}
+
+// fpmOnlyFormats are the formats only the fpm packager builds: a self-extracting shell
+// script and a plain tarball of the package contents.
+var fpmOnlyFormats = map[string]bool{
+	"sh":  true,
+	"tar": true,
+}
+
+// fpmArchitectures maps nfpm architectures to the names fpm output types use, where
+// fpm does not translate them itself.
+var fpmArchitectures = map[string]map[string]string{
+	"deb":    {"arm5": "armel", "arm6": "armhf", "arm7": "armhf"},
+	"rpm":    {"amd64": "x86_64", "arm64": "aarch64", "386": "i386", "arm6": "armv6hl", "arm7": "armv7hl"},
+	"apk":    {"amd64": "x86_64", "arm64": "aarch64", "386": "x86", "arm6": "armhf", "arm7": "armv7"},
+	"pacman": {"amd64": "x86_64", "arm64": "aarch64", "386": "i686", "arm6": "armv6h", "arm7": "armv7h"},
+}
+
+// fpmCreatedPattern matches the package fpm reports creating, e.g.
+// `Created package {:path=>"dist/myapp_1.0.0_amd64.deb"}`.
+var fpmCreatedPattern = regexp.MustCompile(`Created package.*:path=>"([^"]+)"`)
+
+// fpmInstallHint tells users where to get fpm.
+const fpmInstallHint = "install it with `gem install fpm`, see https://fpm.readthedocs.io/"
+
+// validatePackagerFormat checks that packager can build format. fpm builds no ipk
+// packages, and only fpm builds the formats in fpmOnlyFormats.
+func validatePackagerFormat(packager, format string) error {
+	if packager == "fpm" {
+		if _, ok := fpmTypes[format]; !ok {
+			return fmt.Errorf("packager fpm cannot build %s packages", format)
+		}
+		return nil
+	}
+	if fpmOnlyFormats[format] {
+		return fmt.Errorf("%s packages require packager: fpm", format)
+	}
+	return nil
+}
+
+// checkFpmBinary checks that the fpm binary the fpm packager runs is on PATH.
+func (p *LinuxPkgPlugin) checkFpmBinary() error {
+	if _, err := p.getLookPath()("fpm"); err != nil {
+		return fmt.Errorf("fpm requires the fpm binary on PATH: %s", fpmInstallHint)
+	}
+	return nil
+}
+
+// resolveFpmInfo parses an nfpm config for format the way resolvePackageInfo does, for
+// formats nfpm may have no packager for. Contents are expanded as nfpm would for format.
+func resolveFpmInfo(configPath, format, arch string, getenv func(string) string) (*nfpm.Info, error) {
+	config, err := nfpm.ParseFileWithEnvMapping(configPath, getenv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse nfpm config: %w", err)
+	}
+	info, err := config.Get(format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s config: %w", format, err)
+	}
+	if arch != "" {
+		info.Arch = arch
+	}
+	info = nfpm.WithDefaults(info)
+	if err := nfpm.Validate(info); err != nil {
+		return nil, fmt.Errorf("invalid nfpm config: %w", err)
+	}
+	info.Contents, err = files.PrepareForPackager(info.Contents, info.Umask, format, info.DisableGlobbing, info.MTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid nfpm config: %w", err)
+	}
+	return info, nil
+}
+
+// fpmArgs returns the fpm arguments that build a package of format from info into
+// target, the output directory, with a trailing slash, or the package file. Directories
+// and symlinks are packaged from staging, where stageFpmContents creates them. Owners
+// and groups other than root are set with --rpm-attr, which only rpm packages have.
+func fpmArgs(info *nfpm.Info, format, target, staging string) ([]string, error) {
+	outputType := fpmTypes[format]
+	args := []string{
+		"-s", "dir",
+		"-t", outputType,
+		"-n", info.Name,
+		"-v", packageVersion(format, info),
+		"-a", fpmArch(format, info.Arch),
+		"--force",
+		"-p", target,
+	}
+	args = append(args, fpmCompressionArgs(info, format)...)
+	optional := []struct{ flag, value string }{
+		{"--iteration", info.Release},
+		{"--epoch", info.Epoch},
+		{"--description", info.Description},
+		{"--maintainer", info.Maintainer},
+		{"--vendor", info.Vendor},
+		{"--url", info.Homepage},
+		{"--license", info.License},
+		{"--before-install", info.Scripts.PreInstall},
+		{"--after-install", info.Scripts.PostInstall},
+		{"--before-remove", info.Scripts.PreRemove},
+		{"--after-remove", info.Scripts.PostRemove},
+	}
+	for _, option := range optional {
+		if option.value != "" {
+			args = append(args, option.flag, option.value)
+		}
+	}
+	type relation struct {
+		flag   string
+		values []string
+	}
+	relations := []relation{
+		{"--depends", info.Depends},
+		{"--conflicts", info.Conflicts},
+		{"--provides", info.Provides},
+		{"--replaces", info.Replaces},
+	}
+	if outputType == "deb" {
+		relations = append(relations, relation{"--deb-recommends", info.Recommends}, relation{"--deb-suggests", info.Suggests})
+	}
+	for _, relation := range relations {
+		for _, value := range relation.values {
+			args = append(args, relation.flag, value)
+		}
+	}
+
+	var mappings []string
+	for i, content := range info.Contents {
+		if content.Type != files.TypeImplicitDir && content.FileInfo != nil && !rootOwned(content.FileInfo.Owner, content.FileInfo.Group) {
+			if outputType != "rpm" {
+				return nil, fmt.Errorf("packager fpm sets owners and groups only in rpm packages: %s", content.Destination)
+			}
+			args = append(args, "--rpm-attr", fmt.Sprintf("-,%s,%s:%s", cmp.Or(content.FileInfo.Owner, "root"), cmp.Or(content.FileInfo.Group, "root"), content.Destination))
+		}
+		switch content.Type {
+		case files.TypeImplicitDir:
+			// fpm creates the parents of packaged files itself.
+		case files.TypeDir:
+			mappings = append(mappings, fpmStagedPath(staging, "dir", i)+"/="+content.Destination)
+		case files.TypeSymlink:
+			mappings = append(mappings, fpmStagedPath(staging, "link", i)+"="+content.Destination)
+		case files.TypeConfig, files.TypeConfigNoReplace, files.TypeConfigMissingOK:
+			args = append(args, "--config-files", content.Destination)
+			mappings = append(mappings, content.Source+"="+content.Destination)
+		case files.TypeRPMGhost:
+			return nil, fmt.Errorf("packager fpm does not support ghost contents: %s", content.Destination)
+		default:
+			mappings = append(mappings, content.Source+"="+content.Destination)
+		}
+	}
+	return append(args, mappings...), nil
+}
+
+// fpmArch returns the name packages of format use for the nfpm architecture arch.
+func fpmArch(format, arch string) string {
+	if native, ok := fpmArchitectures[fpmTypes[format]][arch]; ok {
+		return native
+	}
+	return arch
+}
+
+// fpmStagedPath returns where the kind of staged copy of contents[i] is staged.
+func fpmStagedPath(staging, kind string, i int) string {
+	return filepath.Join(staging, kind+"-"+strconv.Itoa(i))
+}
+
+// stageFpmContents creates what fpm packages contents from in staging, since fpm takes
+// each file's mode from its source: an empty directory with the mode of each directory,
+// a copy of each symlink, and a copy of each file whose source has another mode than its
+// file_info, which becomes the file's source.
+func stageFpmContents(contents files.Contents, staging string) error {
+	for i, content := range contents {
+		mode := fpmFileMode(content.FileInfo)
+		switch content.Type {
+		case files.TypeDir:
+			path := fpmStagedPath(staging, "dir", i)
+			if err := os.Mkdir(path, 0700); err != nil {
+				return err
+			}
+			if err := os.Chmod(path, mode); err != nil {
+				return err
+			}
+		case files.TypeSymlink:
+			if err := os.Symlink(content.Source, fpmStagedPath(staging, "link", i)); err != nil {
+				return err
+			}
+		case files.TypeFile, files.TypeConfig, files.TypeConfigNoReplace, files.TypeConfigMissingOK:
+			stat, err := os.Stat(content.Source)
+			if err != nil {
+				return err
+			}
+			if content.FileInfo == nil || content.FileInfo.Mode == 0 || stat.Mode()&fpmModeBits == mode {
+				continue
+			}
+			path := fpmStagedPath(staging, "file", i)
+			if err := copyFile(content.Source, path); err != nil {
+				return err
+			}
+			if err := os.Chmod(path, mode); err != nil {
+				return err
+			}
+			content.Source = path
+		}
+	}
+	return nil
+}
+
+// fpmModeBits are the bits of an os.FileMode fpm copies from a source.
+const fpmModeBits = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+
+// fpmFileMode returns the mode of info as an os.FileMode. nfpm configs give modes as Unix
+// permission bits, such as 04755, while modes nfpm reads from sources carry Go's flags.
+// Directories without a mode get 0755.
+func fpmFileMode(info *files.ContentFileInfo) os.FileMode {
+	if info == nil || info.Mode == 0 {
+		return 0755
+	}
+	mode := info.Mode & fpmModeBits
+	for bit, flag := range map[os.FileMode]os.FileMode{0o4000: os.ModeSetuid, 0o2000: os.ModeSetgid, 0o1000: os.ModeSticky} {
+		if info.Mode&bit != 0 {
+			mode |= flag
+		}
+	}
+	return mode
+}
+
+// fpmTarget returns the -p of an fpm build: the output directory, with a trailing slash,
+// which fpm names the package in itself, or the file filename_template names, with the
+// architecture's native name as {arch}.
+func fpmTarget(cfg *Config, format string, info *nfpm.Info) string {
+	filenameTemplate := cfg.filenameTemplate()
+	if filenameTemplate == "" {
+		return cfg.OutputDir + "/"
+	}
+	named := *info
+	named.Arch = fpmArch(format, info.Arch)
+	return filepath.Join(cfg.OutputDir, packageFilename(filenameTemplate, format, fpmNamer{}, &named))
+}
+
+// fpmNamer stands in for an nfpm packager when packageFilename names fpm packages,
+// which always have a filename template.
+type fpmNamer struct{ nfpm.Packager }
+
+// ConventionalFileName is not used for templated names.
+func (fpmNamer) ConventionalFileName(*nfpm.Info) string { return "" }
+
+// buildPackageFpm builds a single package with fpm. The nfpm config stays the source of
+// the package metadata and contents, which are translated into fpm arguments. fpm's
+// output is streamed to log as it runs. A failed build returns a *CommandError along
+// with fpm's output.
+func (p *LinuxPkgPlugin) buildPackageFpm(ctx context.Context, executor CommandExecutor, cfg *Config, configPath, format, arch string, env []string, log io.Writer) (*packageResult, []byte, error) {
+	info, err := resolveFpmInfo(configPath, format, arch, envLookup(env, signingEnv(cfg)))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	staging, err := os.MkdirTemp("", "linuxpkg-fpm-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+	if err := stageFpmContents(info.Contents, staging); err != nil {
+		return nil, nil, fmt.Errorf("failed to stage contents: %w", err)
+	}
+
+	target := fpmTarget(cfg, format, info)
+	args, err := fpmArgs(info, format, target, staging)
+	if err != nil {
+		return nil, nil, err
+	}
+	spec := ExecSpec{
+		Name:   "fpm",
+		Args:   args,
+		Env:    env,
+		Output: log,
+	}
+	output, err := runBuildCommand(ctx, executor, spec)
+	if err != nil {
+		return nil, output, err
+	}
+
+	path := parseFpmPackagePath(output)
+	if path == "" && target != cfg.OutputDir+"/" {
+		path = target
+	}
+	if path == "" {
+		return nil, output, fmt.Errorf("fpm did not report the package it created")
+	}
+	return &packageResult{Path: path, Name: info.Name, Version: info.Version}, output, nil
+}
+
+// parseFpmPackagePath returns the path of the package fpm reports creating, or "".
+func parseFpmPackagePath(output []byte) string {
+	if match := fpmCreatedPattern.FindSubmatch(output); match != nil {
+		return string(match[1])
+	}
+	return ""
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestFpmArgs tests that the nfpm config is translated into fpm arguments.
+func TestFpmArgs(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "myapp")
+	conf := filepath.Join(dir, "myapp.conf")
+	for _, path := range []string{binary, conf} {
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+	config := "name: myapp\n" +
+		"version: 1.2.3\n" +
+		"release: 2\n" +
+		"arch: arm64\n" +
+		"maintainer: Dev <dev@example.com>\n" +
+		"description: test package\n" +
+		"license: MIT\n" +
+		"depends: [libc6]\n" +
+		"recommends: [curl]\n" +
+		"scripts:\n" +
+		"  postinstall: " + filepath.Join(dir, "postinstall.sh") + "\n" +
+		"contents:\n" +
+		"  - src: " + binary + "\n" +
+		"    dst: /usr/bin/myapp\n" +
+		"  - src: " + conf + "\n" +
+		"    dst: /etc/myapp.conf\n" +
+		"    type: config\n" +
+		"  - dst: /var/lib/myapp\n" +
+		"    type: dir\n" +
+		"  - src: /usr/bin/myapp\n" +
+		"    dst: /usr/local/bin/myapp\n" +
+		"    type: symlink\n"
+	configPath := filepath.Join(dir, "nfpm.yaml")
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	tests := []struct {
+		format   string
+		expected []string
+	}{
+		{"deb", []string{"-s", "dir", "-t", "deb", "-n", "myapp", "-v", "1.2.3", "-a", "arm64", "--force", "-p", "dist/",
+			"--iteration", "2", "--description", "test package", "--maintainer", "Dev <dev@example.com>", "--license", "MIT",
+			"--after-install", filepath.Join(dir, "postinstall.sh"), "--depends", "libc6", "--deb-recommends", "curl"}},
+		{"rpm", []string{"-s", "dir", "-t", "rpm", "-n", "myapp", "-v", "1.2.3", "-a", "aarch64", "--force", "-p", "dist/",
+			"--iteration", "2", "--description", "test package", "--maintainer", "Dev <dev@example.com>", "--license", "MIT",
+			"--after-install", filepath.Join(dir, "postinstall.sh"), "--depends", "libc6"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			t.Parallel()
+
+			info, err := resolveFpmInfo(configPath, tt.format, "", os.Getenv)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			args, err := fpmArgs(info, tt.format, "dist/", "/staging")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(args[:len(tt.expected)], tt.expected) {
+				t.Errorf("expected options %q, got %q", tt.expected, args)
+			}
+			for _, want := range []string{
+				binary + "=/usr/bin/myapp",
+				conf + "=/etc/myapp.conf",
+			} {
+				if !slices.Contains(args, want) {
+					t.Errorf("expected mapping %q, got %q", want, args)
+				}
+			}
+			if !slices.ContainsFunc(args, func(arg string) bool {
+				return strings.HasPrefix(arg, "/staging/dir-") && strings.HasSuffix(arg, "/=/var/lib/myapp/")
+			}) {
+				t.Errorf("expected a staged directory, got %q", args)
+			}
+			if i := slices.Index(args, "--config-files"); i < 0 || args[i+1] != "/etc/myapp.conf" {
+				t.Errorf("expected /etc/myapp.conf as a config file, got %q", args)
+			}
+			if !slices.ContainsFunc(args, func(arg string) bool {
+				return strings.HasPrefix(arg, "/staging/link-") && strings.HasSuffix(arg, "=/usr/local/bin/myapp")
+			}) {
+				t.Errorf("expected a staged symlink, got %q", args)
+			}
+		})
+	}
+}
+
+// TestFpmFileInfo tests that the modes, owners, and groups of contents reach fpm.
+func TestFpmFileInfo(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "myapp.conf")
+	if err := os.WriteFile(conf, []byte("data"), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", conf, err)
+	}
+	config := "name: myapp\n" +
+		"version: 1.2.3\n" +
+		"arch: amd64\n" +
+		"contents:\n" +
+		"  - src: " + conf + "\n" +
+		"    dst: /etc/myapp.conf\n" +
+		"    file_info: {owner: myapp, mode: 0600}\n" +
+		"  - dst: /var/lib/myapp\n" +
+		"    type: dir\n" +
+		"    file_info: {mode: 0750}\n"
+	configPath := filepath.Join(dir, "nfpm.yaml")
+	if err := os.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	info, err := resolveFpmInfo(configPath, "rpm", "", os.Getenv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	staging := t.TempDir()
+	if err := stageFpmContents(info.Contents, staging); err != nil {
+		t.Fatalf("failed to stage contents: %v", err)
+	}
+	args, err := fpmArgs(info, "rpm", "dist/", staging)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if i := slices.Index(args, "--rpm-attr"); i < 0 || args[i+1] != "-,myapp,root:/etc/myapp.conf" {
+		t.Errorf("expected the owner of /etc/myapp.conf as an rpm attribute, got %q", args)
+	}
+	for _, want := range []struct {
+		dst  string
+		mode os.FileMode
+	}{
+		{"/etc/myapp.conf", 0600},
+		{"/var/lib/myapp/", os.ModeDir | 0750},
+	} {
+		i := slices.IndexFunc(args, func(arg string) bool { return strings.HasSuffix(arg, "="+want.dst) })
+		if i < 0 {
+			t.Fatalf("expected a mapping to %s, got %q", want.dst, args)
+		}
+		src := strings.TrimSuffix(strings.TrimSuffix(args[i], "="+want.dst), "/")
+		if !strings.HasPrefix(src, staging) {
+			t.Errorf("expected %s to be packaged from staging, got %s", want.dst, src)
+		}
+		if stat, err := os.Stat(src); err != nil || stat.Mode() != want.mode {
+			t.Errorf("expected %s staged with mode %v, got %v (%v)", want.dst, want.mode, stat, err)
+		}
+	}
+
+	if _, err := fpmArgs(info, "deb", "dist/", staging); err == nil || !strings.Contains(err.Error(), "only in rpm packages") {
+		t.Errorf("expected deb packages to reject owners, got %v", err)
+	}
+}
+
+// TestValidatePackagerFormat tests which formats each packager builds.
+func TestValidatePackagerFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		packager    string
+		format      string
+		expectError bool
+	}{
+		{"fpm", "deb", false},
+		{"fpm", "archlinux", false},
+		{"fpm", "sh", false},
+		{"fpm", "tar", false},
+		{"fpm", "ipk", true},
+		{"nfpm", "ipk", false},
+		{"nfpm", "sh", true},
+		{"nfpm-cli", "tar", true},
+	}
+
+	for _, tt := range tests {
+		err := validatePackagerFormat(tt.packager, tt.format)
+		if (err != nil) != tt.expectError {
+			t.Errorf("validatePackagerFormat(%q, %q) error = %v, expectError %v", tt.packager, tt.format, err, tt.expectError)
+		}
+	}
+}
+
+// TestExecuteFpm tests building packages with the fpm packager.
+func TestExecuteFpm(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	config := "name: myapp\nversion: ${VERSION}\narch: amd64\n"
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			outputType := args[slices.Index(args, "-t")+1]
+			path := filepath.Join(args[slices.Index(args, "-p")+1], "myapp."+outputType)
+			return []byte(`Created package {:path=>"` + path + `"}`), nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"packager":    "fpm",
+			"formats":     []string{"rpm", "sh"},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	expected := []string{filepath.Join(dir, "dist", "myapp.rpm"), filepath.Join(dir, "dist", "myapp.sh")}
+	if packages := resp.Outputs["packages"].([]string); !reflect.DeepEqual(packages, expected) {
+		t.Errorf("expected packages %v, got %v", expected, packages)
+	}
+	if len(mock.Calls) != 2 || mock.Calls[0].Name != "fpm" {
+		t.Fatalf("expected two fpm builds, got %v", mock.Calls)
+	}
+	if args := mock.Calls[0].Args; !slices.Contains(args, "x86_64") || !slices.Contains(args, "1.2.3") {
+		t.Errorf("expected the rpm arch and release version, got %q", args)
+	}
+}
+
+// TestParseFpmPackagePath tests reading the package path from fpm's output.
+func TestParseFpmPackagePath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		output   string
+		expected string
+	}{
+		{`Created package {:path=>"dist/myapp_1.0.0_amd64.deb"}`, "dist/myapp_1.0.0_amd64.deb"},
+		{`{:timestamp=>"2024-01-01T00:00:00", :message=>"Created package", :path=>"dist/myapp.rpm"}`, "dist/myapp.rpm"},
+		{"Force flag given. Overwriting package at dist/myapp.deb", ""},
+	}
+
+	for _, tt := range tests {
+		if got := parseFpmPackagePath([]byte(tt.output)); got != tt.expected {
+			t.Errorf("parseFpmPackagePath(%q) = %q, want %q", tt.output, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	// defaultGCSEndpoint is the Cloud Storage JSON API endpoint.
+	defaultGCSEndpoint = "https://storage.googleapis.com"
+	// gcsWriteScope is the OAuth scope needed to upload objects.
+	gcsWriteScope = "https://www.googleapis.com/auth/devstorage.read_write"
+)
+
+// gcsBucketPattern validates Cloud Storage bucket names.
+var gcsBucketPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{1,220}[a-z0-9]$`)
+
+// GCSPublishConfig configures uploading packages and checksum files to Google Cloud Storage.
+type GCSPublishConfig struct {
+	// Bucket is the destination bucket.
+	Bucket string
+	// Prefix is the object name prefix; see expandPublishTemplate for placeholders.
+	Prefix string
+	// PublicRead makes uploaded objects readable by everyone (fine-grained ACL buckets).
+	PublicRead bool
+	// Endpoint overrides the API endpoint (emulators, private service connect).
+	Endpoint string
+}
+
+// gcsObject is the part of an uploaded object resource the publisher reports.
+type gcsObject struct {
+	Generation string `json:"generation"`
+}
+
+// parseGCSPublish parses the publish.gcs block. It returns nil when it is not set.
+func parseGCSPublish(publish map[string]any) *GCSPublishConfig {
+	block := helpers.NewConfigParser(publish).GetMap("gcs")
+	if block == nil {
+		return nil
+	}
+
+	parser := helpers.NewConfigParser(block)
+	return &GCSPublishConfig{
+		Bucket:     parser.GetString("bucket", "", ""),
+		Prefix:     parser.GetString("prefix", "", defaultObjectPrefix),
+		PublicRead: parser.GetBool("public_read", false),
+		Endpoint:   parser.GetString("endpoint", "", defaultGCSEndpoint),
+	}
+}
+
+// validate checks the GCS publishing settings.
+func (c *GCSPublishConfig) validate() error {
+	if !gcsBucketPattern.MatchString(c.Bucket) {
+		return fmt.Errorf("invalid bucket: %q", c.Bucket)
+	}
+	if err := validateObjectPrefix(c.Prefix); err != nil {
+		return err
+	}
+	u, err := url.Parse(c.Endpoint)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("endpoint must be an http(s) URL: %s", c.Endpoint)
+	}
+	return nil
+}
+
+// publishGCS uploads every file with the Cloud Storage JSON API, authenticating with
+// Application Default Credentials, and returns gs:// and HTTPS URLs.
+func (p *LinuxPkgPlugin) publishGCS(ctx context.Context, c *GCSPublishConfig, files []string, prefix string) (map[string]any, error) {
+	// The OAuth token exchange uses the plugin's HTTP client as well.
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.getHTTPClient())
+	credentials, err := google.FindDefaultCredentials(ctx, gcsWriteScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find application default credentials: %w", err)
+	}
+	client := &apiClient{http: oauth2.NewClient(ctx, credentials.TokenSource)}
+
+	endpoint := strings.TrimSuffix(c.Endpoint, "/")
+	objects := make([]map[string]any, 0, len(files))
+	for _, file := range files {
+		name := path.Join(prefix, filepath.Base(file))
+		query := url.Values{"uploadType": {"media"}, "name": {name}}
+		if c.PublicRead {
+			query.Set("predefinedAcl", "publicRead")
+		}
+		uploadURL := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?%s", endpoint, c.Bucket, query.Encode())
+
+		var object gcsObject
+		if err := client.upload(ctx, http.MethodPost, uploadURL, file, &object); err != nil {
+			return nil, fmt.Errorf("failed to upload %s: %w", file, err)
+		}
+		objects = append(objects, map[string]any{
+			"name":       filepath.Base(file),
+			"uri":        "gs://" + c.Bucket + "/" + name,
+			"url":        endpoint + "/" + c.Bucket + "/" + (&url.URL{Path: name}).EscapedPath(),
+			"generation": object.Generation,
+		})
+	}
+
+	return map[string]any{
+		"bucket":  c.Bucket,
+		"prefix":  prefix,
+		"objects": objects,
+	}, nil
+}
+
+// yankGCS deletes the objects a publish uploaded, as recorded in published, with the
+// Cloud Storage JSON API. Objects already gone are skipped.
+func (p *LinuxPkgPlugin) yankGCS(ctx context.Context, c *GCSPublishConfig, published map[string]any) ([]string, error) {
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.getHTTPClient())
+	credentials, err := google.FindDefaultCredentials(ctx, gcsWriteScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find application default credentials: %w", err)
+	}
+	client := &apiClient{http: oauth2.NewClient(ctx, credentials.TokenSource)}
+
+	endpoint := strings.TrimSuffix(c.Endpoint, "/")
+	var yanked []string
+	for _, uri := range publishedValues(published["objects"], "uri") {
+		name, ok := strings.CutPrefix(uri, "gs://"+c.Bucket+"/")
+		if !ok {
+			continue
+		}
+		objectURL := fmt.Sprintf("%s/storage/v1/b/%s/o/%s", endpoint, c.Bucket, url.PathEscape(name))
+		if err := client.do(ctx, http.MethodDelete, objectURL, nil); err != nil {
+			if strings.Contains(err.Error(), "returned status 404") {
+				continue
+			}
+			return yanked, fmt.Errorf("failed to delete %s: %w", uri, err)
+		}
+		yanked = append(yanked, uri)
+	}
+	return yanked, nil
+}
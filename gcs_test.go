@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestValidatePublishGCS tests validation of the publish.gcs block.
+func TestValidatePublishGCS(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		gcs         map[string]any
+		expectValid bool
+	}{
+		{name: "bucket", gcs: map[string]any{"bucket": "my-packages", "public_read": true}, expectValid: true},
+		{name: "missing bucket", gcs: map[string]any{}, expectValid: false},
+		{name: "prefix traversal", gcs: map[string]any{"bucket": "my-packages", "prefix": "a/../../b"}, expectValid: false},
+		{name: "invalid endpoint", gcs: map[string]any{"bucket": "my-packages", "endpoint": "storage.googleapis.com"}, expectValid: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &LinuxPkgPlugin{}
+			resp, err := p.Validate(context.Background(), map[string]any{"publish": map[string]any{"gcs": tc.gcs}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Valid != tc.expectValid {
+				t.Errorf("expected valid=%v, got %v: %v", tc.expectValid, resp.Valid, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestPublishGCS tests uploading objects with service account credentials found through
+// Application Default Credentials.
+// Note: This test cannot run in parallel due to t.Setenv usage.
+func TestPublishGCS(t *testing.T) {
+	var uploads []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token": "gcs-token", "token_type": "Bearer", "expires_in": 3600}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer gcs-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/upload/storage/v1/b/my-packages/o" || r.URL.Query().Get("uploadType") != "media" {
+			http.NotFound(w, r)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		uploads = append(uploads, r.URL.Query().Get("name")+" "+r.URL.Query().Get("predefinedAcl")+" "+string(data))
+		_ = json.NewEncoder(w).Encode(map[string]any{"name": r.URL.Query().Get("name"), "generation": "1700000000000000"})
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	credentials, _ := json.Marshal(map[string]any{
+		"type":           "service_account",
+		"project_id":     "test",
+		"private_key_id": "1",
+		"private_key":    string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		"client_email":   "publisher@test.iam.gserviceaccount.com",
+		"token_uri":      server.URL + "/token",
+	})
+	credentialsPath := filepath.Join(dir, "credentials.json")
+	if err := os.WriteFile(credentialsPath, credentials, 0600); err != nil {
+		t.Fatalf("failed to write credentials: %v", err)
+	}
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", credentialsPath)
+
+	var files []string
+	for _, name := range []string{"myapp.deb", "SHA256SUMS"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		files = append(files, path)
+	}
+
+	p := &LinuxPkgPlugin{httpClient: server.Client()}
+	cfg := &GCSPublishConfig{Bucket: "my-packages", PublicRead: true, Endpoint: server.URL}
+	result, err := p.publishGCS(context.Background(), cfg, files, "myapp/1.0.0/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"myapp/1.0.0/myapp.deb publicRead myapp.deb",
+		"myapp/1.0.0/SHA256SUMS publicRead SHA256SUMS",
+	}
+	if strings.Join(uploads, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected uploads:\n%s", strings.Join(uploads, "\n"))
+	}
+
+	objects := result["objects"].([]map[string]any)
+	if objects[0]["uri"] != "gs://my-packages/myapp/1.0.0/myapp.deb" {
+		t.Errorf("unexpected uri: %v", objects[0]["uri"])
+	}
+	if objects[0]["url"] != server.URL+"/my-packages/myapp/1.0.0/myapp.deb" {
+		t.Errorf("unexpected url: %v", objects[0]["url"])
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+const (
+	// defaultGemfuryPushURL is Gemfury's push endpoint.
+	defaultGemfuryPushURL = "https://push.fury.io"
+	// defaultGemfuryTokenEnv is the environment variable holding the Gemfury push token.
+	defaultGemfuryTokenEnv = "FURY_PUSH_TOKEN"
+)
+
+// gemfuryFormats are the package formats Gemfury accepts.
+var gemfuryFormats = map[string]bool{
+	"deb": true,
+	"rpm": true,
+}
+
+// gemfuryAccountPattern validates Gemfury account names.
+var gemfuryAccountPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// GemfuryPublishConfig configures uploading packages to Gemfury.
+type GemfuryPublishConfig struct {
+	// Account is the Gemfury account (user or organization) to push to.
+	Account string
+	// TokenEnv names the environment variable holding the push token.
+	TokenEnv string
+	// PushURL overrides the push endpoint.
+	PushURL string
+}
+
+// parseGemfuryPublish parses the publish.gemfury block. It returns nil when it is not set.
+func parseGemfuryPublish(publish map[string]any) *GemfuryPublishConfig {
+	block := helpers.NewConfigParser(publish).GetMap("gemfury")
+	if block == nil {
+		return nil
+	}
+
+	parser := helpers.NewConfigParser(block)
+	return &GemfuryPublishConfig{
+		Account:  parser.GetString("account", "", ""),
+		TokenEnv: parser.GetString("token_env", "", defaultGemfuryTokenEnv),
+		PushURL:  parser.GetString("push_url", "", defaultGemfuryPushURL),
+	}
+}
+
+// validate checks the Gemfury publishing settings.
+func (c *GemfuryPublishConfig) validate() error {
+	if !gemfuryAccountPattern.MatchString(c.Account) {
+		return fmt.Errorf("account is required and must contain only letters, digits, '-' and '_'")
+	}
+	if c.TokenEnv == "" {
+		return fmt.Errorf("token_env cannot be empty")
+	}
+	u, err := url.Parse(c.PushURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("push_url must be an http(s) URL: %s", c.PushURL)
+	}
+	return nil
+}
+
+// publishGemfury uploads every deb and rpm to Gemfury. A failed upload is recorded and the
+// remaining packages are still pushed; only a missing token fails the publisher.
+func (p *LinuxPkgPlugin) publishGemfury(ctx context.Context, c *GemfuryPublishConfig, artifacts []map[string]any) (map[string]any, error) {
+	token := os.Getenv(c.TokenEnv)
+	if token == "" {
+		return nil, fmt.Errorf("push token not set: %s is empty", c.TokenEnv)
+	}
+
+	endpoint := strings.TrimSuffix(c.PushURL, "/") + "/" + c.Account + "/"
+	uploads := make([]map[string]any, 0)
+	failed := 0
+	for _, artifact := range artifacts {
+		format, _ := artifact["format"].(string)
+		if !gemfuryFormats[format] {
+			continue
+		}
+
+		path := artifact["path"].(string)
+		upload := map[string]any{"path": path, "success": true}
+		if err := p.gemfuryUpload(ctx, endpoint, token, path); err != nil {
+			upload["success"] = false
+			upload["error"] = err.Error()
+			failed++
+		}
+		uploads = append(uploads, upload)
+	}
+
+	return map[string]any{
+		"account": c.Account,
+		"uploads": uploads,
+		"failed":  failed,
+	}, nil
+}
+
+// gemfuryFailures returns the number of failed Gemfury uploads in the publish results.
+func gemfuryFailures(published map[string]any) int {
+	result, ok := published["gemfury"].(map[string]any)
+	if !ok {
+		return 0
+	}
+	failed, _ := result["failed"].(int)
+	return failed
+}
+
+// gemfuryUpload pushes one package as a multipart form, authenticating with the token.
+func (p *LinuxPkgPlugin) gemfuryUpload(ctx context.Context, endpoint, token, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	body, contentType := multipartFile("package", filepath.Base(path), f)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.SetBasicAuth(token, "")
+
+	resp, err := p.getHTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("upload failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
+
+// multipartFile streams a single file as a multipart form body and returns the body
+// together with its content type.
+func multipartFile(field, name string, r io.Reader) (io.Reader, string) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+
+	go func() {
+		part, err := mw.CreateFormFile(field, name)
+		if err == nil {
+			_, err = io.Copy(part, r)
+		}
+		if err == nil {
+			err = mw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return pr, mw.FormDataContentType()
+}
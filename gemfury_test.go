@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestValidatePublishGemfury tests validation of the publish.gemfury block.
+func TestValidatePublishGemfury(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		gemfury     map[string]any
+		expectValid bool
+	}{
+		{name: "account", gemfury: map[string]any{"account": "my-org"}, expectValid: true},
+		{name: "missing account", gemfury: map[string]any{}, expectValid: false},
+		{name: "account with path", gemfury: map[string]any{"account": "my-org/../x"}, expectValid: false},
+		{name: "invalid push url", gemfury: map[string]any{"account": "my-org", "push_url": "ftp://push.fury.io"}, expectValid: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &LinuxPkgPlugin{}
+			resp, err := p.Validate(context.Background(), map[string]any{"publish": map[string]any{"gemfury": tc.gemfury}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Valid != tc.expectValid {
+				t.Errorf("expected valid=%v, got %v: %v", tc.expectValid, resp.Valid, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestPublishGemfury tests that every deb and rpm is uploaded and a failed upload does
+// not stop the remaining ones.
+// Note: This test cannot run in parallel due to t.Setenv usage.
+func TestPublishGemfury(t *testing.T) {
+	t.Setenv("TEST_FURY_TOKEN", "secret")
+
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, _, ok := r.BasicAuth(); !ok || user != "secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/my-org/" {
+			http.NotFound(w, r)
+			return
+		}
+		file, header, err := r.FormFile("package")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		received = append(received, header.Filename+":"+string(data))
+		if header.Filename == "myapp.rpm" {
+			http.Error(w, "version already exists", http.StatusConflict)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	var artifacts []map[string]any
+	for _, name := range []string{"myapp.deb", "myapp.rpm", "myapp.apk"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("failed to write package: %v", err)
+		}
+		artifacts = append(artifacts, map[string]any{"path": path, "format": filepath.Ext(name)[1:]})
+	}
+
+	p := &LinuxPkgPlugin{httpClient: server.Client()}
+	cfg := &GemfuryPublishConfig{Account: "my-org", TokenEnv: "TEST_FURY_TOKEN", PushURL: server.URL}
+	result, err := p.publishGemfury(context.Background(), cfg, artifacts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(received) != 2 || received[0] != "myapp.deb:myapp.deb" || received[1] != "myapp.rpm:myapp.rpm" {
+		t.Errorf("unexpected uploads: %v", received)
+	}
+
+	uploads := result["uploads"].([]map[string]any)
+	if len(uploads) != 2 {
+		t.Fatalf("expected 2 upload results, got %v", uploads)
+	}
+	if uploads[0]["success"] != true {
+		t.Errorf("expected deb upload to succeed: %v", uploads[0])
+	}
+	if uploads[1]["success"] != false || uploads[1]["error"] == nil {
+		t.Errorf("expected rpm upload to fail: %v", uploads[1])
+	}
+	if gemfuryFailures(map[string]any{"gemfury": result}) != 1 {
+		t.Errorf("expected 1 failed upload, got %v", result["failed"])
+	}
+}
+
+// TestPublishGemfuryMissingToken tests that a missing token fails the publisher.
+// Note: This test cannot run in parallel due to t.Setenv usage.
+func TestPublishGemfuryMissingToken(t *testing.T) {
+	t.Setenv("TEST_FURY_TOKEN", "")
+
+	p := &LinuxPkgPlugin{}
+	cfg := &GemfuryPublishConfig{Account: "my-org", TokenEnv: "TEST_FURY_TOKEN", PushURL: defaultGemfuryPushURL}
+	if _, err := p.publishGemfury(context.Background(), cfg, nil); err == nil {
+		t.Error("expected error for missing token")
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+const (
+	// defaultGitHubAPIURL is the GitHub REST API endpoint.
+	defaultGitHubAPIURL = "https://api.github.com"
+	// defaultGitHubTokenEnv is the environment variable holding the GitHub token.
+	defaultGitHubTokenEnv = "GITHUB_TOKEN"
+)
+
+// githubRepositoryPattern validates repositories given as "owner/name".
+var githubRepositoryPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*/[A-Za-z0-9_.-]+$`)
+
+// GitHubPublishConfig configures uploading packages as assets of the GitHub release.
+type GitHubPublishConfig struct {
+	// Repository is the "owner/name" repository. Defaults to the release context.
+	Repository string
+	// TokenEnv names the environment variable holding the API token.
+	TokenEnv string
+	// APIURL overrides the API endpoint (GitHub Enterprise Server).
+	APIURL string
+	// Checksums also uploads the checksum files.
+	Checksums bool
+}
+
+// githubRelease is the part of a GitHub release the uploader needs.
+type githubRelease struct {
+	ID        int64         `json:"id"`
+	UploadURL string        `json:"upload_url"`
+	Assets    []githubAsset `json:"assets"`
+}
+
+// githubAsset is a release asset.
+type githubAsset struct {
+	Name               string `json:"name"`
+	URL                string `json:"url"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// parseGitHubPublish parses the publish.github block. It returns nil when it is not set.
+func parseGitHubPublish(publish map[string]any) *GitHubPublishConfig {
+	block := helpers.NewConfigParser(publish).GetMap("github")
+	if block == nil {
+		return nil
+	}
+
+	parser := helpers.NewConfigParser(block)
+	return &GitHubPublishConfig{
+		Repository: parser.GetString("repository", "", ""),
+		TokenEnv:   parser.GetString("token_env", "", defaultGitHubTokenEnv),
+		APIURL:     parser.GetString("api_url", "", defaultGitHubAPIURL),
+		Checksums:  parser.GetBool("checksums", true),
+	}
+}
+
+// validate checks the GitHub publishing settings.
+func (c *GitHubPublishConfig) validate() error {
+	if c.Repository != "" && !githubRepositoryPattern.MatchString(c.Repository) {
+		return fmt.Errorf("repository must be in the form owner/name: %q", c.Repository)
+	}
+	if c.TokenEnv == "" {
+		return fmt.Errorf("token_env cannot be empty")
+	}
+	u, err := url.Parse(c.APIURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("api_url must be an http(s) URL: %s", c.APIURL)
+	}
+	return nil
+}
+
+// publishGitHub uploads the packages (and checksum files) as assets of the release for
+// the current tag. An asset that already exists with the same name is replaced, so
+// re-running a release uploads the rebuilt files instead of failing.
+func (p *LinuxPkgPlugin) publishGitHub(ctx context.Context, c *GitHubPublishConfig, files []string, release plugin.ReleaseContext) (map[string]any, error) {
+	token := os.Getenv(c.TokenEnv)
+	if token == "" {
+		return nil, fmt.Errorf("token not set: %s is empty", c.TokenEnv)
+	}
+	repository := c.Repository
+	if repository == "" {
+		if release.RepositoryOwner == "" || release.RepositoryName == "" {
+			return nil, fmt.Errorf("repository is not set and the release context has no repository")
+		}
+		repository = release.RepositoryOwner + "/" + release.RepositoryName
+	}
+	if release.TagName == "" {
+		return nil, fmt.Errorf("release context has no tag name")
+	}
+
+	client := p.githubClient(token)
+	rel, err := findGitHubRelease(ctx, client, c.APIURL, repository, release.TagName)
+	if err != nil {
+		return nil, err
+	}
+	uploadURL, _, _ := strings.Cut(rel.UploadURL, "{")
+
+	existing := make(map[string]githubAsset, len(rel.Assets))
+	for _, asset := range rel.Assets {
+		existing[asset.Name] = asset
+	}
+
+	assets := make([]map[string]any, 0, len(files))
+	for _, file := range files {
+		name := filepath.Base(file)
+		replaced := false
+		if asset, ok := existing[name]; ok {
+			if err := client.do(ctx, http.MethodDelete, asset.URL, nil); err != nil {
+				return nil, fmt.Errorf("failed to replace asset %s: %w", name, err)
+			}
+			replaced = true
+		}
+
+		var uploaded githubAsset
+		err := client.upload(ctx, http.MethodPost, uploadURL+"?name="+url.QueryEscape(name), file, &uploaded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload %s: %w", name, err)
+		}
+		assets = append(assets, map[string]any{
+			"name":     name,
+			"url":      uploaded.BrowserDownloadURL,
+			"replaced": replaced,
+		})
+	}
+
+	return map[string]any{
+		"repository": repository,
+		"tag":        release.TagName,
+		"release_id": rel.ID,
+		"assets":     assets,
+	}, nil
+}
+
+// githubClient returns an API client authenticated with token.
+func (p *LinuxPkgPlugin) githubClient(token string) *apiClient {
+	return &apiClient{http: p.getHTTPClient(), header: http.Header{
+		"Accept":               {"application/vnd.github+json"},
+		"Authorization":        {"Bearer " + token},
+		"X-Github-Api-Version": {"2022-11-28"},
+	}}
+}
+
+// findGitHubRelease returns the release of repository for tag.
+func findGitHubRelease(ctx context.Context, client *apiClient, apiURL, repository, tag string) (*githubRelease, error) {
+	var rel githubRelease
+	endpoint := fmt.Sprintf("%s/repos/%s/releases/tags/%s", strings.TrimSuffix(apiURL, "/"), repository, url.PathEscape(tag))
+	if err := client.do(ctx, http.MethodGet, endpoint, &rel); err != nil {
+		return nil, fmt.Errorf("failed to find release %s: %w", tag, err)
+	}
+	return &rel, nil
+}
+
+// yankGitHub deletes the assets a publish uploaded, as recorded in published, from the
+// GitHub release. Assets already gone are skipped.
+func (p *LinuxPkgPlugin) yankGitHub(ctx context.Context, c *GitHubPublishConfig, published map[string]any) ([]string, error) {
+	token := os.Getenv(c.TokenEnv)
+	if token == "" {
+		return nil, fmt.Errorf("token not set: %s is empty", c.TokenEnv)
+	}
+	repository, _ := published["repository"].(string)
+	tag, _ := published["tag"].(string)
+
+	client := p.githubClient(token)
+	rel, err := findGitHubRelease(ctx, client, c.APIURL, repository, tag)
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]githubAsset, len(rel.Assets))
+	for _, asset := range rel.Assets {
+		existing[asset.Name] = asset
+	}
+
+	var yanked []string
+	for _, name := range publishedValues(published["assets"], "name") {
+		asset, ok := existing[name]
+		if !ok {
+			continue
+		}
+		if err := client.do(ctx, http.MethodDelete, asset.URL, nil); err != nil {
+			return yanked, fmt.Errorf("failed to delete asset %s: %w", name, err)
+		}
+		yanked = append(yanked, name)
+	}
+	return yanked, nil
+}
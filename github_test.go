@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestValidatePublishGitHub tests validation of the publish.github block.
+func TestValidatePublishGitHub(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		github      map[string]any
+		expectValid bool
+	}{
+		{name: "defaults", github: map[string]any{}, expectValid: true},
+		{name: "repository", github: map[string]any{"repository": "owner/name"}, expectValid: true},
+		{name: "invalid repository", github: map[string]any{"repository": "owner"}, expectValid: false},
+		{name: "invalid api url", github: map[string]any{"api_url": "api.github.com"}, expectValid: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &LinuxPkgPlugin{}
+			resp, err := p.Validate(context.Background(), map[string]any{"publish": map[string]any{"github": tc.github}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Valid != tc.expectValid {
+				t.Errorf("expected valid=%v, got %v: %v", tc.expectValid, resp.Valid, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestPublishGitHub tests uploading release assets and replacing existing ones.
+// Note: This test cannot run in parallel due to t.Setenv usage.
+func TestPublishGitHub(t *testing.T) {
+	t.Setenv("TEST_GITHUB_TOKEN", "secret")
+
+	var requests []string
+	uploaded := make(map[string]string)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/owner/myapp/releases/tags/v1.0.0":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id":         42,
+				"upload_url": server.URL + "/uploads/repos/owner/myapp/releases/42/assets{?name,label}",
+				"assets": []map[string]any{
+					{"id": 7, "name": "myapp.deb", "url": server.URL + "/repos/owner/myapp/releases/assets/7"},
+				},
+			})
+		case r.Method == http.MethodDelete && r.URL.Path == "/repos/owner/myapp/releases/assets/7":
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == http.MethodPost && r.URL.Path == "/uploads/repos/owner/myapp/releases/42/assets":
+			name := r.URL.Query().Get("name")
+			data, _ := io.ReadAll(r.Body)
+			uploaded[name] = string(data)
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"name":                 name,
+				"browser_download_url": "https://github.com/owner/myapp/releases/download/v1.0.0/" + name,
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	var files []string
+	for _, name := range []string{"myapp.deb", "myapp.rpm", "SHA256SUMS"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		files = append(files, path)
+	}
+
+	p := &LinuxPkgPlugin{httpClient: server.Client()}
+	cfg := &GitHubPublishConfig{TokenEnv: "TEST_GITHUB_TOKEN", APIURL: server.URL}
+	release := plugin.ReleaseContext{RepositoryOwner: "owner", RepositoryName: "myapp", TagName: "v1.0.0"}
+	result, err := p.publishGitHub(context.Background(), cfg, files, release)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"GET /repos/owner/myapp/releases/tags/v1.0.0",
+		"DELETE /repos/owner/myapp/releases/assets/7",
+		"POST /uploads/repos/owner/myapp/releases/42/assets",
+		"POST /uploads/repos/owner/myapp/releases/42/assets",
+		"POST /uploads/repos/owner/myapp/releases/42/assets",
+	}
+	if strings.Join(requests, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected requests:\n%s", strings.Join(requests, "\n"))
+	}
+	for _, name := range []string{"myapp.deb", "myapp.rpm", "SHA256SUMS"} {
+		if uploaded[name] != name {
+			t.Errorf("expected %s to be uploaded, got %q", name, uploaded[name])
+		}
+	}
+
+	assets := result["assets"].([]map[string]any)
+	if len(assets) != 3 || assets[0]["replaced"] != true || assets[1]["replaced"] != false {
+		t.Errorf("unexpected assets: %v", assets)
+	}
+	if assets[1]["url"] != "https://github.com/owner/myapp/releases/download/v1.0.0/myapp.rpm" {
+		t.Errorf("unexpected download url: %v", assets[1]["url"])
+	}
+}
+
+// TestPublishGitHubMissingRelease tests that a missing release is reported.
+// Note: This test cannot run in parallel due to t.Setenv usage.
+func TestPublishGitHubMissingRelease(t *testing.T) {
+	t.Setenv("TEST_GITHUB_TOKEN", "secret")
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	p := &LinuxPkgPlugin{httpClient: server.Client()}
+	cfg := &GitHubPublishConfig{Repository: "owner/myapp", TokenEnv: "TEST_GITHUB_TOKEN", APIURL: server.URL}
+	_, err := p.publishGitHub(context.Background(), cfg, nil, plugin.ReleaseContext{TagName: "v1.0.0"})
+	if err == nil || !strings.Contains(err.Error(), "failed to find release v1.0.0") {
+		t.Errorf("expected missing release error, got %v", err)
+	}
+}
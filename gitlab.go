@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+const (
+	// defaultGitLabURL is the GitLab instance used when neither url nor CI_SERVER_URL is set.
+	defaultGitLabURL = "https://gitlab.com"
+	// defaultGitLabTokenEnv is the environment variable holding a GitLab access token.
+	defaultGitLabTokenEnv = "GITLAB_TOKEN"
+	// gitlabJobTokenEnv holds the CI job token inside GitLab CI.
+	gitlabJobTokenEnv = "CI_JOB_TOKEN"
+)
+
+// allowedGitLabModes are the supported GitLab publishing modes.
+var allowedGitLabModes = map[string]bool{
+	"registry": true,
+	"release":  true,
+}
+
+// gitlabPackageNamePattern matches names accepted by the generic package registry.
+var gitlabPackageNamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// GitLabPublishConfig configures uploading packages to GitLab.
+type GitLabPublishConfig struct {
+	// Mode is "registry" (generic package registry) or "release" (registry upload linked
+	// from the release for the tag).
+	Mode string
+	// URL is the GitLab instance. Defaults to CI_SERVER_URL, then gitlab.com.
+	URL string
+	// Project is the numeric project ID or "group/project" path. Defaults to CI_PROJECT_ID.
+	Project string
+	// PackageName is the generic package name. Defaults to the repository name.
+	PackageName string
+	// TokenEnv names the environment variable holding an access token. CI_JOB_TOKEN is
+	// used when it is empty.
+	TokenEnv string
+}
+
+// gitlabLink is a release asset link.
+type gitlabLink struct {
+	Name string `json:"name"`
+}
+
+// parseGitLabPublish parses the publish.gitlab block. It returns nil when it is not set.
+func parseGitLabPublish(publish map[string]any) *GitLabPublishConfig {
+	block := helpers.NewConfigParser(publish).GetMap("gitlab")
+	if block == nil {
+		return nil
+	}
+
+	parser := helpers.NewConfigParser(block)
+	return &GitLabPublishConfig{
+		Mode:        parser.GetString("mode", "", "registry"),
+		URL:         parser.GetString("url", "CI_SERVER_URL", defaultGitLabURL),
+		Project:     parser.GetString("project", "CI_PROJECT_ID", ""),
+		PackageName: parser.GetString("package_name", "", ""),
+		TokenEnv:    parser.GetString("token_env", "", defaultGitLabTokenEnv),
+	}
+}
+
+// validate checks the GitLab publishing settings.
+func (c *GitLabPublishConfig) validate() error {
+	if !allowedGitLabModes[c.Mode] {
+		return fmt.Errorf("invalid mode: %s (allowed: registry, release)", c.Mode)
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("url must be an http(s) URL: %s", c.URL)
+	}
+	if c.PackageName != "" && !gitlabPackageNamePattern.MatchString(c.PackageName) {
+		return fmt.Errorf("invalid package_name: %q", c.PackageName)
+	}
+	if strings.Contains(c.Project, "..") {
+		return fmt.Errorf("invalid project: %q", c.Project)
+	}
+	return nil
+}
+
+// authHeader returns the authentication header for the configured token, falling
+// back to the CI job token.
+func (c *GitLabPublishConfig) authHeader() (http.Header, error) {
+	if token := os.Getenv(c.TokenEnv); token != "" {
+		return http.Header{"Private-Token": {token}}, nil
+	}
+	if token := os.Getenv(gitlabJobTokenEnv); token != "" {
+		return http.Header{"Job-Token": {token}}, nil
+	}
+	return nil, fmt.Errorf("token not set: %s and %s are empty", c.TokenEnv, gitlabJobTokenEnv)
+}
+
+// publishGitLab uploads every file to the project's generic package registry under
+// <package>/<version>/. In release mode each file is also linked from the release for the
+// tag; files that already have a link are not linked again.
+func (p *LinuxPkgPlugin) publishGitLab(ctx context.Context, c *GitLabPublishConfig, files []string, release plugin.ReleaseContext) (map[string]any, error) {
+	if c.Project == "" {
+		return nil, fmt.Errorf("project is not set and CI_PROJECT_ID is empty")
+	}
+	packageName := c.PackageName
+	if packageName == "" {
+		packageName = release.RepositoryName
+	}
+	if !gitlabPackageNamePattern.MatchString(packageName) {
+		return nil, fmt.Errorf("package name %q is invalid; set package_name", packageName)
+	}
+	if release.Version == "" {
+		return nil, fmt.Errorf("release context has no version")
+	}
+	if c.Mode == "release" && release.TagName == "" {
+		return nil, fmt.Errorf("release context has no tag name")
+	}
+
+	header, err := c.authHeader()
+	if err != nil {
+		return nil, err
+	}
+	client := &apiClient{http: p.getHTTPClient(), header: header}
+	project := strings.TrimSuffix(c.URL, "/") + "/api/v4/projects/" + url.PathEscape(c.Project)
+
+	var linked map[string]bool
+	var linksEndpoint string
+	if c.Mode == "release" {
+		linksEndpoint = project + "/releases/" + url.PathEscape(release.TagName) + "/assets/links"
+		var links []gitlabLink
+		if err := client.do(ctx, http.MethodGet, linksEndpoint, &links); err != nil {
+			return nil, fmt.Errorf("failed to list release links for %s: %w", release.TagName, err)
+		}
+		linked = make(map[string]bool, len(links))
+		for _, link := range links {
+			linked[link.Name] = true
+		}
+	}
+
+	packages := make([]map[string]any, 0, len(files))
+	for _, file := range files {
+		name := filepath.Base(file)
+		fileURL := fmt.Sprintf("%s/packages/generic/%s/%s/%s", project, packageName, url.PathEscape(release.Version), url.PathEscape(name))
+		if err := client.upload(ctx, http.MethodPut, fileURL, file, nil); err != nil {
+			return nil, fmt.Errorf("failed to upload %s: %w", name, err)
+		}
+		entry := map[string]any{"name": name, "url": fileURL}
+
+		if c.Mode == "release" {
+			if !linked[name] {
+				form := url.Values{"name": {name}, "url": {fileURL}, "link_type": {"package"}}
+				if err := client.send(ctx, http.MethodPost, linksEndpoint, strings.NewReader(form.Encode()), -1, "application/x-www-form-urlencoded", nil); err != nil {
+					return nil, fmt.Errorf("failed to link %s to release %s: %w", name, release.TagName, err)
+				}
+			}
+			entry["linked"] = true
+		}
+		packages = append(packages, entry)
+	}
+
+	return map[string]any{
+		"project": c.Project,
+		"package": packageName,
+		"version": release.Version,
+		"files":   packages,
+	}, nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestValidatePublishGitLab tests validation of the publish.gitlab block.
+func TestValidatePublishGitLab(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		gitlab      map[string]any
+		expectValid bool
+	}{
+		{name: "defaults", gitlab: map[string]any{}, expectValid: true},
+		{name: "self-hosted release", gitlab: map[string]any{"mode": "release", "url": "https://gitlab.example.com", "project": "group/myapp"}, expectValid: true},
+		{name: "invalid mode", gitlab: map[string]any{"mode": "pages"}, expectValid: false},
+		{name: "invalid url", gitlab: map[string]any{"url": "gitlab.example.com"}, expectValid: false},
+		{name: "invalid package name", gitlab: map[string]any{"package_name": "my app"}, expectValid: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &LinuxPkgPlugin{}
+			resp, err := p.Validate(context.Background(), map[string]any{"publish": map[string]any{"gitlab": tc.gitlab}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Valid != tc.expectValid {
+				t.Errorf("expected valid=%v, got %v: %v", tc.expectValid, resp.Valid, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestPublishGitLab tests registry uploads with a job token and release links with an
+// access token.
+// Note: This test cannot run in parallel due to t.Setenv usage.
+func TestPublishGitLab(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for _, name := range []string{"myapp.deb", "SHA256SUMS"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		files = append(files, path)
+	}
+	release := plugin.ReleaseContext{RepositoryName: "myapp", Version: "1.0.0", TagName: "v1.0.0"}
+
+	tests := []struct {
+		name           string
+		mode           string
+		token          string
+		jobToken       string
+		expectAuth     string
+		expectRequests []string
+	}{
+		{
+			name:       "registry with job token",
+			mode:       "registry",
+			jobToken:   "job",
+			expectAuth: "Job-Token job",
+			expectRequests: []string{
+				"PUT /api/v4/projects/group%2Fmyapp/packages/generic/myapp/1.0.0/myapp.deb myapp.deb",
+				"PUT /api/v4/projects/group%2Fmyapp/packages/generic/myapp/1.0.0/SHA256SUMS SHA256SUMS",
+			},
+		},
+		{
+			name:       "release with access token",
+			mode:       "release",
+			token:      "secret",
+			jobToken:   "job",
+			expectAuth: "Private-Token secret",
+			expectRequests: []string{
+				"GET /api/v4/projects/group%2Fmyapp/releases/v1.0.0/assets/links ",
+				"PUT /api/v4/projects/group%2Fmyapp/packages/generic/myapp/1.0.0/myapp.deb myapp.deb",
+				"PUT /api/v4/projects/group%2Fmyapp/packages/generic/myapp/1.0.0/SHA256SUMS SHA256SUMS",
+				"POST /api/v4/projects/group%2Fmyapp/releases/v1.0.0/assets/links SHA256SUMS",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("TEST_GITLAB_TOKEN", tc.token)
+			t.Setenv(gitlabJobTokenEnv, tc.jobToken)
+
+			var requests []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth := "Job-Token " + r.Header.Get("Job-Token")
+				if token := r.Header.Get("Private-Token"); token != "" {
+					auth = "Private-Token " + token
+				}
+				if auth != tc.expectAuth {
+					http.Error(w, "unauthorized: "+auth, http.StatusUnauthorized)
+					return
+				}
+
+				body := ""
+				if r.Method == http.MethodPost {
+					_ = r.ParseForm()
+					body = r.PostForm.Get("name")
+				} else {
+					data, _ := io.ReadAll(r.Body)
+					body = string(data)
+				}
+				requests = append(requests, r.Method+" "+r.URL.EscapedPath()+" "+body)
+
+				if r.Method == http.MethodGet {
+					// myapp.deb was linked by an earlier run.
+					_ = json.NewEncoder(w).Encode([]map[string]any{{"name": "myapp.deb"}})
+					return
+				}
+				w.WriteHeader(http.StatusCreated)
+			}))
+			defer server.Close()
+
+			p := &LinuxPkgPlugin{httpClient: server.Client()}
+			cfg := &GitLabPublishConfig{Mode: tc.mode, URL: server.URL, Project: "group/myapp", TokenEnv: "TEST_GITLAB_TOKEN"}
+			result, err := p.publishGitLab(context.Background(), cfg, files, release)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if strings.Join(requests, "\n") != strings.Join(tc.expectRequests, "\n") {
+				t.Errorf("unexpected requests:\n%s", strings.Join(requests, "\n"))
+			}
+			uploaded := result["files"].([]map[string]any)
+			expectedURL := server.URL + "/api/v4/projects/group%2Fmyapp/packages/generic/myapp/1.0.0/myapp.deb"
+			if len(uploaded) != 2 || uploaded[0]["url"] != expectedURL {
+				t.Errorf("unexpected files: %v", uploaded)
+			}
+		})
+	}
+}
+
+// TestPublishGitLabMissingToken tests that a missing token fails the publisher.
+// Note: This test cannot run in parallel due to t.Setenv usage.
+func TestPublishGitLabMissingToken(t *testing.T) {
+	t.Setenv("TEST_GITLAB_TOKEN", "")
+	t.Setenv(gitlabJobTokenEnv, "")
+
+	p := &LinuxPkgPlugin{}
+	cfg := &GitLabPublishConfig{Mode: "registry", URL: defaultGitLabURL, Project: "1", TokenEnv: "TEST_GITLAB_TOKEN"}
+	_, err := p.publishGitLab(context.Background(), cfg, nil, plugin.ReleaseContext{RepositoryName: "myapp", Version: "1.0.0"})
+	if err == nil || !strings.Contains(err.Error(), "token not set") {
+		t.Errorf("expected missing token error, got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// goBinaryEnv is the variable nfpm configs use to reference the compiled binary.
+const goBinaryEnv = "BINARY"
+
+// GoBuildConfig compiles a Go binary for each target right before packaging.
+type GoBuildConfig struct {
+	// Main is the main package to build.
+	Main string
+	// Binary is the name of the compiled binary. Defaults to the last element of Main.
+	Binary string
+	// Ldflags is a Go template over the release context, e.g. "-X main.version={{.Version}}".
+	Ldflags string
+	// Flags are extra go build flags.
+	Flags []string
+	// Env lists extra KEY=value variables for go build, such as CGO_ENABLED=0.
+	Env []string
+}
+
+// goBinaryNamePattern matches valid binary names.
+var goBinaryNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// parseGoBuild parses the build block. It returns nil when no build step is configured.
+func parseGoBuild(raw map[string]any) *GoBuildConfig {
+	block := helpers.NewConfigParser(raw).GetMap("build")
+	if block == nil {
+		return nil
+	}
+
+	parser := helpers.NewConfigParser(block)
+	build := &GoBuildConfig{
+		Main:    parser.GetString("main", "", "."),
+		Binary:  parser.GetString("binary", "", ""),
+		Ldflags: parser.GetString("ldflags", "", ""),
+		Flags:   parser.GetStringSlice("flags", []string{"-trimpath"}),
+		Env:     parser.GetStringSlice("env", nil),
+	}
+	if build.Binary == "" {
+		build.Binary = path.Base(filepath.ToSlash(build.Main))
+	}
+	return build
+}
+
+// validate checks the build settings.
+func (b *GoBuildConfig) validate() error {
+	if err := validatePath(b.Main); err != nil {
+		return fmt.Errorf("invalid main: %w", err)
+	}
+	if !goBinaryNamePattern.MatchString(b.Binary) {
+		return fmt.Errorf("invalid binary name %q: set binary", b.Binary)
+	}
+	if _, err := template.New("ldflags").Parse(b.Ldflags); err != nil {
+		return fmt.Errorf("invalid ldflags: %w", err)
+	}
+	for _, flag := range b.Flags {
+		if !strings.HasPrefix(flag, "-") {
+			return fmt.Errorf("invalid flag %q: flags must start with '-'", flag)
+		}
+		if name, _, _ := strings.Cut(strings.TrimLeft(flag, "-"), "="); name == "o" || name == "ldflags" {
+			return fmt.Errorf("flag %s is set by the plugin", flag)
+		}
+	}
+	for _, kv := range b.Env {
+		if key, _, ok := strings.Cut(kv, "="); !ok || key == "" {
+			return fmt.Errorf("invalid env entry %q: use KEY=value", kv)
+		}
+	}
+	return nil
+}
+
+// renderLdflags renders the ldflags template with the release data.
+func (b *GoBuildConfig) renderLdflags(data *nfpmTemplateData) (string, error) {
+	tmpl, err := template.New("ldflags").Option("missingkey=error").Parse(b.Ldflags)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// binaryPath returns where the binary for target is written: output_dir/bin/<arch>/<binary>.
+func (b *GoBuildConfig) binaryPath(outputDir string, target buildTarget) string {
+	return filepath.Join(outputDir, "bin", target.fileName(), b.Binary)
+}
+
+// goEnv returns the go build environment for target: GOOS=linux, GOARCH, and GOARM for
+// ARM variants, followed by the configured variables.
+func (b *GoBuildConfig) goEnv(target buildTarget) []string {
+	arch, _, _ := strings.Cut(target.Arch, "/")
+	env := []string{"GOOS=linux", "GOARCH=" + arch}
+	if variant := target.variant(); variant != "" {
+		env = append(env, "GOARM="+strings.TrimPrefix(variant, "v"))
+	}
+	return append(env, b.Env...)
+}
+
+// goBuildArgs returns the go arguments that compile the binary to binary, run in
+// workingDir when set.
+func (b *GoBuildConfig) goBuildArgs(workingDir, binary, ldflags string) []string {
+	var args []string
+	if workingDir != "" {
+		// Main is a package path within the working directory's module.
+		args = append(args, "-C", workingDir)
+	}
+	args = append(args, "build")
+	args = append(args, b.Flags...)
+	if ldflags != "" {
+		args = append(args, "-ldflags", ldflags)
+	}
+	return append(args, "-o", binary, b.Main)
+}
+
+// buildGoBinary compiles the binary for target with go build, run in workingDir when set,
+// and returns its path and the tool output.
+func buildGoBinary(ctx context.Context, executor CommandExecutor, build *GoBuildConfig, workingDir, outputDir string, target buildTarget, ldflags string) (string, []byte, error) {
+	binary := build.binaryPath(outputDir, target)
+	if err := os.MkdirAll(filepath.Dir(binary), 0755); err != nil {
+		return "", nil, fmt.Errorf("failed to create binary directory: %w", err)
+	}
+	output, err := runWithEnv(ctx, executor, build.goEnv(target), "go", build.goBuildArgs(workingDir, binary, ldflags)...)
+	if err != nil {
+		return "", output, err
+	}
+	return binary, output, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestParseGoBuild tests parsing the build block.
+func TestParseGoBuild(t *testing.T) {
+	t.Parallel()
+
+	if build := parseGoBuild(map[string]any{}); build != nil {
+		t.Errorf("expected nil without a build block, got %+v", build)
+	}
+
+	build := parseGoBuild(map[string]any{"build": map[string]any{"main": "./cmd/myapp"}})
+	expected := &GoBuildConfig{Main: "./cmd/myapp", Binary: "myapp", Flags: []string{"-trimpath"}}
+	if !reflect.DeepEqual(build, expected) {
+		t.Errorf("expected %+v, got %+v", expected, build)
+	}
+}
+
+// TestValidateGoBuild tests build block validation.
+func TestValidateGoBuild(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		build       any
+		expectError string
+	}{
+		{"valid", map[string]any{"main": "./cmd/myapp", "ldflags": "-X main.version={{.Version}}"}, ""},
+		{"no binary name", map[string]any{}, "set binary"},
+		{"traversal", map[string]any{"main": "../other/cmd"}, "invalid main"},
+		{"bad ldflags", map[string]any{"main": "./cmd/myapp", "ldflags": "{{.Version"}, "invalid ldflags"},
+		{"output flag", map[string]any{"main": "./cmd/myapp", "flags": []any{"-o=bin/myapp"}}, "set by the plugin"},
+		{"bad env", map[string]any{"main": "./cmd/myapp", "env": []any{"CGO_ENABLED"}}, "KEY=value"},
+		{"not an object", "./cmd/myapp", "must be an object"},
+	}
+
+	p := &LinuxPkgPlugin{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := p.Validate(context.Background(), map[string]any{"build": tt.build})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectError == "" {
+				if !resp.Valid {
+					t.Errorf("expected valid config, got errors: %v", resp.Errors)
+				}
+				return
+			}
+			if resp.Valid || len(resp.Errors) == 0 || resp.Errors[0].Field != "build" ||
+				!strings.Contains(resp.Errors[0].Message, tt.expectError) {
+				t.Errorf("expected build error containing %q, got %v", tt.expectError, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestExecuteGoBuild tests compiling a binary per target before packaging it.
+func TestExecuteGoBuild(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	config := "name: myapp\nversion: 1.2.3\ncontents:\n  - src: ${BINARY}\n    dst: /usr/bin/myapp\n    expand: true\n"
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if name == "go" {
+				// Stand in for the compiler by writing the -o file.
+				output := args[len(args)-2]
+				return nil, os.WriteFile(output, []byte("binary"), 0755)
+			}
+			return []byte("created package: " + args[len(args)-1] + "myapp.deb"), nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb"},
+			"packager":    "nfpm-cli",
+			"targets":     []string{"amd64", "arm/v7"},
+			"build": map[string]any{
+				"main":    "./cmd/myapp",
+				"ldflags": "-s -w -X main.version={{.Version}} -X main.commit={{.ShortCommit}}",
+				"env":     []string{"CGO_ENABLED=0"},
+			},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3", CommitSHA: "0123456789abcdef"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	if len(mock.Calls) != 4 {
+		t.Fatalf("expected a go build and an nfpm call per target, got %v", mock.Calls)
+	}
+	tests := []struct {
+		call   MockCall
+		env    []string
+		binary string
+	}{
+		{mock.Calls[0], []string{"GOOS=linux", "GOARCH=amd64", "CGO_ENABLED=0"}, filepath.Join(dir, "dist", "bin", "amd64", "myapp")},
+		{mock.Calls[1], []string{"GOOS=linux", "GOARCH=arm", "GOARM=7", "CGO_ENABLED=0"}, filepath.Join(dir, "dist", "bin", "armv7", "myapp")},
+	}
+	for _, tt := range tests {
+		expectedArgs := []string{"-C", dir, "build", "-trimpath", "-ldflags", "-s -w -X main.version=1.2.3 -X main.commit=0123456", "-o", tt.binary, "./cmd/myapp"}
+		if tt.call.Name != "go" || !reflect.DeepEqual(tt.call.Args, expectedArgs) {
+			t.Errorf("expected go %v, got %s %v", expectedArgs, tt.call.Name, tt.call.Args)
+		}
+		if !reflect.DeepEqual(tt.call.Env, tt.env) {
+			t.Errorf("expected go build env %v, got %v", tt.env, tt.call.Env)
+		}
+	}
+	if env := mock.Calls[3].Env; env[len(env)-1] != "BINARY="+tests[1].binary {
+		t.Errorf("expected nfpm to get BINARY=%s, got %v", tests[1].binary, env)
+	}
+}
+
+// TestExecuteGoBuildFailure tests that a failed compile stops the release.
+func TestExecuteGoBuildFailure(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: myapp\nversion: 1.2.3\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("main.go:3:1: syntax error"), os.ErrInvalid
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"working_dir": dir, "formats": []string{"deb"}, "packager": "nfpm-cli", "target": "amd64", "build": map[string]any{"binary": "myapp"}},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "failed to build Go binary for amd64") || !strings.Contains(resp.Error, "syntax error") {
+		t.Errorf("expected a build failure with the compiler output, got %+v", resp)
+	}
+	if len(mock.Calls) != 1 {
+		t.Errorf("expected nfpm not to run, got %v", mock.Calls)
+	}
+}
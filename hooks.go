@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// lifecycleActions are the actions hooks can run, in the order one hook runs them:
+// build packages, publish the packages a build recorded, report on them, and remove them.
+var lifecycleActions = []string{"build", "publish", "report", "cleanup"}
+
+// actionHookOrder maps the hooks actions can run in to their place in a release.
+// on-success and on-error both run after publishing.
+var actionHookOrder = map[string]int{
+	"pre-publish":  0,
+	"post-publish": 1,
+	"on-success":   2,
+	"on-error":     2,
+}
+
+// releaseRecordName is the record of the last build kept in the output directory.
+const releaseRecordName = ".linuxpkg-release.json"
+
+// releaseRecord lists what a build produced for a release and where it was published,
+// so later hooks can publish or remove it.
+type releaseRecord struct {
+	Version       string           `json:"version"`
+	Artifacts     []map[string]any `json:"artifacts"`
+	ChecksumFiles []string         `json:"checksum_files,omitempty"`
+	Published     map[string]any   `json:"published,omitempty"`
+	// Delivered maps publish targets to the digests of the packages they received, for
+	// skip_existing.
+	Delivered map[string][]string `json:"delivered,omitempty"`
+	// Diff compares the packages with those of the previous release, for diff_against.
+	Diff []packageDiff `json:"diff,omitempty"`
+	// Builds is the result of every build job, as in the builds output.
+	Builds []map[string]any `json:"builds,omitempty"`
+	// BuildDurationMS and PublishDurationMS are how long building and publishing took.
+	BuildDurationMS   int64 `json:"build_duration_ms,omitempty"`
+	PublishDurationMS int64 `json:"publish_duration_ms,omitempty"`
+}
+
+// parseHooks parses the hooks block, which maps hook names to an action or a list of
+// actions. It returns nil when the block is not set.
+func parseHooks(raw map[string]any) map[string][]string {
+	block := helpers.NewConfigParser(raw).GetMap("hooks")
+	if block == nil {
+		return nil
+	}
+
+	hooks := make(map[string][]string, len(block))
+	for name, value := range block {
+		if action, ok := value.(string); ok {
+			hooks[name] = []string{action}
+			continue
+		}
+		hooks[name] = helpers.NewConfigParser(block).GetStringSlice(name, nil)
+	}
+	return hooks
+}
+
+// resolveHookActions returns the actions each hook runs. Every action runs in at most
+// one hook; build must run in pre-publish or post-publish, publish and report no
+// earlier than build, and cleanup in a later hook. Underscores may stand in for dashes
+// in hook names, as in on_error.
+func resolveHookActions(hooks map[string][]string, publish bool) (map[plugin.Hook][]string, error) {
+	mapped := make(map[string]string)
+	for _, name := range sortedKeys(hooks) {
+		hook := strings.ReplaceAll(name, "_", "-")
+		if _, ok := actionHookOrder[hook]; !ok {
+			return nil, fmt.Errorf("unsupported hook: %s (allowed: %s)", name, strings.Join(sortedKeys(actionHookOrder), ", "))
+		}
+		for _, action := range hooks[name] {
+			if !slices.Contains(lifecycleActions, action) {
+				return nil, fmt.Errorf("%s: unsupported action: %s (allowed: %s)", name, action, strings.Join(lifecycleActions, ", "))
+			}
+			if other, ok := mapped[action]; ok && other != hook {
+				return nil, fmt.Errorf("action %s is mapped to both %s and %s", action, other, hook)
+			}
+			mapped[action] = hook
+		}
+	}
+
+	build, ok := mapped["build"]
+	if !ok {
+		return nil, fmt.Errorf("no hook runs the build action")
+	}
+	if _, ok := runOnHooks[build]; !ok {
+		return nil, fmt.Errorf("build must run in pre-publish or post-publish, not %s", build)
+	}
+	if hook, ok := mapped["publish"]; ok {
+		if !publish {
+			return nil, fmt.Errorf("the publish action needs a publish block")
+		}
+		if actionHookOrder[hook] < actionHookOrder[build] {
+			return nil, fmt.Errorf("publish runs in %s, before build in %s", hook, build)
+		}
+	}
+	if hook, ok := mapped["report"]; ok && actionHookOrder[hook] < actionHookOrder[build] {
+		return nil, fmt.Errorf("report runs in %s, before build in %s", hook, build)
+	}
+	if hook, ok := mapped["cleanup"]; ok && actionHookOrder[hook] <= actionHookOrder[build] {
+		return nil, fmt.Errorf("cleanup must run in a hook after build in %s, not %s", build, hook)
+	}
+
+	actions := make(map[plugin.Hook][]string)
+	for _, action := range lifecycleActions {
+		if hook, ok := mapped[action]; ok {
+			actions[plugin.Hook(hook)] = append(actions[plugin.Hook(hook)], action)
+		}
+	}
+	return actions, nil
+}
+
+// runHookActions runs the actions of one hook in order. The run stops at the first
+// failed action; the outputs and messages of those that ran are combined.
+func (p *LinuxPkgPlugin) runHookActions(ctx context.Context, cfg *Config, actions []string, req plugin.ExecuteRequest) (*plugin.ExecuteResponse, error) {
+	outputs := make(map[string]any)
+	messages := make([]string, 0, len(actions))
+	for _, action := range actions {
+		var resp *plugin.ExecuteResponse
+		var err error
+		switch action {
+		case "build":
+			resp, err = p.buildPackages(ctx, cfg, req.Context, req.DryRun)
+		case "publish":
+			resp, err = p.publishRecorded(ctx, cfg, req.Context, req.DryRun)
+		case "report":
+			resp, err = reportRecorded(cfg, req.Context, req.DryRun)
+		case "cleanup":
+			resp, err = p.cleanupRecorded(ctx, cfg, req.Context, req.DryRun)
+		}
+		if err != nil || !resp.Success || len(actions) == 1 {
+			return resp, err
+		}
+		maps.Copy(outputs, resp.Outputs)
+		messages = append(messages, resp.Message)
+	}
+
+	return &plugin.ExecuteResponse{
+		Success: true,
+		Message: strings.Join(messages, "; "),
+		Outputs: outputs,
+	}, nil
+}
+
+// resolveRecordDir validates the output directory and resolves the paths of cfg against
+// the working directory, as buildPackages does, for actions that only read a record.
+func resolveRecordDir(cfg *Config) error {
+	if err := validatePath(cfg.OutputDir); err != nil {
+		return fmt.Errorf("invalid output_dir: %w", err)
+	}
+	if cfg.WorkingDir != "" {
+		dir, err := resolveWorkingDir(cfg.WorkingDir)
+		if err != nil {
+			return fmt.Errorf("invalid working_dir: %w", err)
+		}
+		cfg.WorkingDir = dir
+		cfg.applyWorkingDir()
+	}
+	return nil
+}
+
+// writeReleaseRecord writes record to outputDir, replacing the record of an earlier build.
+func writeReleaseRecord(outputDir string, record releaseRecord) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode release record: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, releaseRecordName), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write release record: %w", err)
+	}
+	return nil
+}
+
+// readReleaseRecord reads the record of the last build in outputDir. A missing record
+// returns an error wrapping fs.ErrNotExist.
+func readReleaseRecord(outputDir string) (*releaseRecord, error) {
+	path := filepath.Join(outputDir, releaseRecordName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("no packages were recorded in %s: %w", outputDir, err)
+	}
+	var record releaseRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to read release record %s: %w", path, err)
+	}
+	return &record, nil
+}
+
+// previousDeliveries returns what an earlier run of the release published, from the
+// release record in outputDir. A record of another version published nothing of it.
+func previousDeliveries(outputDir, version string) (map[string]any, map[string][]string) {
+	record, err := readReleaseRecord(outputDir)
+	if err != nil || record.Version != version {
+		return nil, make(map[string][]string)
+	}
+	if record.Delivered == nil {
+		record.Delivered = make(map[string][]string)
+	}
+	return record.Published, record.Delivered
+}
+
+// publishRecorded publishes the packages an earlier build recorded for the release.
+func (p *LinuxPkgPlugin) publishRecorded(ctx context.Context, cfg *Config, releaseCtx plugin.ReleaseContext, dryRun bool) (*plugin.ExecuteResponse, error) {
+	if err := cfg.Publish.validate(); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid publish: %v", err),
+		}, nil
+	}
+	if err := resolveRecordDir(cfg); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	record, err := readReleaseRecord(cfg.OutputDir)
+	if err == nil && record.Version != releaseCtx.Version {
+		err = fmt.Errorf("the packages recorded in %s were built for %s, not %s", cfg.OutputDir, record.Version, releaseCtx.Version)
+	}
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+	packages := artifactPaths(record.Artifacts)
+
+	if dryRun {
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: fmt.Sprintf("Would publish %d package(s)", len(packages)),
+			Outputs: map[string]any{
+				"packages": packages,
+				"version":  releaseCtx.Version,
+			},
+		}, nil
+	}
+
+	executor := p.getExecutor()
+	var publishLog *loggingExecutor
+	if cfg.PersistLogs {
+		publishLog = &loggingExecutor{executor: executor}
+		executor = publishLog
+	}
+	started := time.Now()
+	var published map[string]any
+	if cfg.SkipExisting {
+		if record.Delivered == nil {
+			record.Delivered = make(map[string][]string)
+		}
+		published, err = p.publishNew(ctx, executor, cfg.Publish, record.Artifacts, record.ChecksumFiles, releaseCtx, record.Published, record.Delivered)
+	} else {
+		published, err = p.publishPackages(ctx, executor, cfg.Publish, record.Artifacts, record.ChecksumFiles, releaseCtx)
+	}
+	// Record what was uploaded, even by a failed publish, so cleanup can yank it.
+	record.Published = published
+	record.PublishDurationMS = time.Since(started).Milliseconds()
+	if recordErr := writeReleaseRecord(cfg.OutputDir, *record); err == nil {
+		err = recordErr
+	}
+	logs := []string{}
+	if publishLog != nil {
+		logPath, logErr := writeToolLog(cfg.OutputDir, publishLogName, publishLog.output(), cfg.CompressLogs)
+		if logErr == nil {
+			logs = append(logs, logPath)
+			logErr = cfg.applyOutputModes(nil, []string{logPath})
+		}
+		if err == nil {
+			err = logErr
+		}
+	}
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to publish packages: %v", err),
+			Outputs: map[string]any{
+				"published": published,
+				"logs":      logs,
+			},
+		}, nil
+	}
+
+	message := fmt.Sprintf("Published %d Linux package(s)", len(packages))
+	if failed := gemfuryFailures(published); failed > 0 {
+		message += fmt.Sprintf("; %d Gemfury upload(s) failed", failed)
+	}
+	return &plugin.ExecuteResponse{
+		Success: true,
+		Message: message,
+		Outputs: map[string]any{
+			"packages":  packages,
+			"published": published,
+			"logs":      logs,
+			"version":   releaseCtx.Version,
+		},
+	}, nil
+}
+
+// cleanupRecorded removes the packages an earlier build recorded for the release, with
+// their signatures, provenance, and checksum files, and then the record. With Yank, the
+// packages it published are deleted first. Without a record for the release there is
+// nothing to remove.
+func (p *LinuxPkgPlugin) cleanupRecorded(ctx context.Context, cfg *Config, releaseCtx plugin.ReleaseContext, dryRun bool) (*plugin.ExecuteResponse, error) {
+	if err := resolveRecordDir(cfg); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	record, err := readReleaseRecord(cfg.OutputDir)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && record.Version != releaseCtx.Version) {
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: fmt.Sprintf("No packages recorded for %s", releaseCtx.Version),
+		}, nil
+	}
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	var files []string
+	for _, artifact := range record.Artifacts {
+		for _, key := range []string{"path", "signature", "certificate", "bundle", "provenance"} {
+			if path, ok := artifact[key].(string); ok && path != "" {
+				files = append(files, path)
+			}
+		}
+	}
+	files = append(files, record.ChecksumFiles...)
+	yank := cfg.Yank && cfg.Publish != nil && len(record.Published) > 0
+
+	if dryRun {
+		outputs := map[string]any{
+			"removed": files,
+			"version": releaseCtx.Version,
+		}
+		if yank {
+			outputs["yank"] = sortedKeys(record.Published)
+		}
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: fmt.Sprintf("Would remove %d file(s) built for %s", len(files), releaseCtx.Version),
+			Outputs: outputs,
+		}, nil
+	}
+
+	// Yank first, so a failure leaves the record in place to retry.
+	yanked := make(map[string]any)
+	if yank {
+		if yanked, err = p.yankPublished(ctx, p.getExecutor(), cfg.Publish, record.Published); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to yank packages: %v", err),
+				Outputs: map[string]any{
+					"yanked": yanked,
+				},
+			}, nil
+		}
+	}
+
+	removed := make([]string, 0, len(files))
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to remove %s: %v", file, err),
+				Outputs: map[string]any{
+					"removed": removed,
+					"yanked":  yanked,
+				},
+			}, nil
+		}
+		removed = append(removed, file)
+	}
+	if err := os.Remove(filepath.Join(cfg.OutputDir, releaseRecordName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("failed to remove release record: %v", err),
+			Outputs: map[string]any{
+				"removed": removed,
+				"yanked":  yanked,
+			},
+		}, nil
+	}
+
+	message := fmt.Sprintf("Removed %d file(s) built for %s", len(removed), releaseCtx.Version)
+	if len(yanked) > 0 {
+		message += fmt.Sprintf("; yanked packages from %s", strings.Join(sortedKeys(yanked), ", "))
+	}
+	return &plugin.ExecuteResponse{
+		Success: true,
+		Message: message,
+		Outputs: map[string]any{
+			"removed": removed,
+			"yanked":  yanked,
+			"version": releaseCtx.Version,
+		},
+	}, nil
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestResolveHookActions tests mapping hooks to the actions they run.
+func TestResolveHookActions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		hooks       map[string][]string
+		publish     bool
+		expected    map[plugin.Hook][]string
+		expectError string
+	}{
+		{
+			"lifecycle",
+			map[string][]string{"pre_publish": {"build"}, "post_publish": {"publish"}, "on_error": {"cleanup"}},
+			true,
+			map[plugin.Hook][]string{plugin.HookPrePublish: {"build"}, plugin.HookPostPublish: {"publish"}, plugin.HookOnError: {"cleanup"}},
+			"",
+		},
+		{
+			"one hook in action order",
+			map[string][]string{"post-publish": {"publish", "build"}},
+			true,
+			map[plugin.Hook][]string{plugin.HookPostPublish: {"build", "publish"}},
+			"",
+		},
+		{"unknown hook", map[string][]string{"pre-init": {"build"}}, false, nil, "unsupported hook: pre-init"},
+		{"unknown action", map[string][]string{"pre-publish": {"deploy"}}, false, nil, "pre-publish: unsupported action: deploy"},
+		{"no build", map[string][]string{"on-error": {"cleanup"}}, false, nil, "no hook runs the build action"},
+		{"build twice", map[string][]string{"pre-publish": {"build"}, "post-publish": {"build"}}, false, nil, "action build is mapped to both"},
+		{"build on error", map[string][]string{"on-error": {"build"}}, false, nil, "build must run in pre-publish or post-publish"},
+		{"publish without block", map[string][]string{"pre-publish": {"build"}, "post-publish": {"publish"}}, false, nil, "needs a publish block"},
+		{"publish before build", map[string][]string{"pre-publish": {"publish"}, "post-publish": {"build"}}, true, nil, "publish runs in pre-publish, before build"},
+		{"report before build", map[string][]string{"pre-publish": {"report"}, "post-publish": {"build"}}, false, nil, "report runs in pre-publish, before build"},
+		{"cleanup with build", map[string][]string{"post-publish": {"build", "cleanup"}}, false, nil, "cleanup must run in a hook after build"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			actions, err := resolveHookActions(tt.hooks, tt.publish)
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Errorf("expected error containing %q, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(actions, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, actions)
+			}
+		})
+	}
+}
+
+// TestExecuteHooks tests building, publishing, and cleaning up packages in separate
+// hooks of one release.
+func TestExecuteHooks(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return nil, nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	config := map[string]any{
+		"working_dir": dir,
+		"formats":     []any{"deb"},
+		"publish":     map[string]any{"apt": map[string]any{"repo": "apt"}},
+		"hooks": map[string]any{
+			"pre_publish":  "build",
+			"post_publish": []any{"publish"},
+			"on_error":     "cleanup",
+		},
+	}
+	execute := func(hook plugin.Hook, version string) *plugin.ExecuteResponse {
+		t.Helper()
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    hook,
+			Config:  config,
+			Context: plugin.ReleaseContext{Version: version},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return resp
+	}
+
+	resp := execute(plugin.HookPrePublish, "1.0.0")
+	if !resp.Success {
+		t.Fatalf("expected the build to succeed, got %s", resp.Error)
+	}
+	if len(mock.Calls) != 0 {
+		t.Errorf("expected publishing to wait for post-publish, got %v", mock.Calls)
+	}
+	packages := resp.Outputs["packages"].([]string)
+	if _, err := os.Stat(filepath.Join(dir, "dist", releaseRecordName)); err != nil {
+		t.Fatalf("expected a release record: %v", err)
+	}
+
+	if resp := execute(plugin.HookPostPublish, "1.1.0"); resp.Success || !strings.Contains(resp.Error, "were built for 1.0.0, not 1.1.0") {
+		t.Errorf("expected a version mismatch, got %+v", resp)
+	}
+
+	resp = execute(plugin.HookPostPublish, "1.0.0")
+	if !resp.Success {
+		t.Fatalf("expected publishing to succeed, got %s", resp.Error)
+	}
+	if len(mock.Calls) != 1 || mock.Calls[0].Name != "reprepro" || mock.Calls[0].Args[len(mock.Calls[0].Args)-1] != packages[0] {
+		t.Errorf("expected the recorded deb to be published, got %v", mock.Calls)
+	}
+
+	resp = execute(plugin.HookOnError, "1.0.0")
+	if !resp.Success {
+		t.Fatalf("expected cleanup to succeed, got %s", resp.Error)
+	}
+	for _, path := range append(packages, filepath.Join(dir, "dist", releaseRecordName)) {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed, got %v", path, err)
+		}
+	}
+
+	if resp := execute(plugin.HookOnError, "1.0.0"); !resp.Success || !strings.HasPrefix(resp.Message, "No packages recorded") {
+		t.Errorf("expected nothing to clean up, got %+v", resp)
+	}
+}
+
+// TestValidateHooks tests validating the hooks block.
+func TestValidateHooks(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		config      map[string]any
+		expectError string
+	}{
+		{"build and cleanup", map[string]any{"hooks": map[string]any{"pre_publish": "build", "on_error": "cleanup"}}, ""},
+		{"not an object", map[string]any{"hooks": "build"}, "hooks must be an object"},
+		{"invalid action", map[string]any{"hooks": map[string]any{"post_publish": "deploy"}}, "unsupported action: deploy"},
+		{"with run_on", map[string]any{"run_on": "pre-publish", "hooks": map[string]any{"pre_publish": "build"}}, "hooks cannot be combined with run_on"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			expectFieldError(t, tt.config, "hooks", tt.expectError)
+		})
+	}
+}
+
+// TestExecuteOnErrorCleanup tests that the on-error hook removes the packages built for
+// the failed release when cleanup_on_error is set.
+func TestExecuteOnErrorCleanup(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		config        map[string]any
+		version       string
+		expectRemoved bool
+	}{
+		{"enabled", map[string]any{"cleanup_on_error": true}, "1.0.0", true},
+		{"other release", map[string]any{"cleanup_on_error": true}, "2.0.0", false},
+		{"default", nil, "1.0.0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			writeEmbeddedTestConfig(t, dir, "amd64")
+			config := map[string]any{"working_dir": dir, "formats": []any{"deb"}}
+			for key, value := range tt.config {
+				config[key] = value
+			}
+
+			p := &LinuxPkgPlugin{}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook:    plugin.HookPostPublish,
+				Config:  config,
+				Context: plugin.ReleaseContext{Version: "1.0.0"},
+			})
+			if err != nil || !resp.Success {
+				t.Fatalf("expected the build to succeed, got %v, %+v", err, resp)
+			}
+			packages := resp.Outputs["packages"].([]string)
+			checksums := resp.Outputs["checksum_files"].([]string)
+
+			resp, err = p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook:    plugin.HookOnError,
+				Config:  config,
+				Context: plugin.ReleaseContext{Version: tt.version},
+			})
+			if err != nil || !resp.Success {
+				t.Fatalf("expected on-error to succeed, got %v, %+v", err, resp)
+			}
+			for _, path := range append(packages, checksums...) {
+				_, err := os.Stat(path)
+				if removed := os.IsNotExist(err); removed != tt.expectRemoved {
+					t.Errorf("expected %s removed %v, got %v (%s)", path, tt.expectRemoved, err, resp.Message)
+				}
+			}
+		})
+	}
+}
+
+// TestCleanupYank tests that cleanup deletes published release assets and S3 objects
+// before removing the packages.
+// Note: This test cannot run in parallel due to t.Setenv usage.
+func TestCleanupYank(t *testing.T) {
+	t.Setenv("TEST_GITHUB_TOKEN", "secret")
+
+	var requests []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/owner/myapp/releases/tags/v1.0.0":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id": 42,
+				"assets": []map[string]any{
+					{"name": "myapp.deb", "url": server.URL + "/repos/owner/myapp/releases/assets/7"},
+					{"name": "notes.txt", "url": server.URL + "/repos/owner/myapp/releases/assets/8"},
+				},
+			})
+		case r.Method == http.MethodDelete && r.URL.Path == "/repos/owner/myapp/releases/assets/7":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	outputDir := filepath.Join(dir, "dist")
+	if err := os.Mkdir(outputDir, 0755); err != nil {
+		t.Fatalf("failed to create output directory: %v", err)
+	}
+	pkg := filepath.Join(outputDir, "myapp.deb")
+	if err := os.WriteFile(pkg, []byte("deb"), 0644); err != nil {
+		t.Fatalf("failed to write package: %v", err)
+	}
+	record := releaseRecord{
+		Version:   "1.0.0",
+		Artifacts: []map[string]any{{"path": pkg, "format": "deb"}},
+		Published: map[string]any{
+			"github": map[string]any{"repository": "owner/myapp", "tag": "v1.0.0", "assets": []map[string]any{{"name": "myapp.deb"}, {"name": "SHA256SUMS"}}},
+			"s3":     map[string]any{"bucket": "pkgs", "objects": []map[string]any{{"uri": "s3://pkgs/myapp/1.0.0/myapp.deb"}}},
+			"apt":    map[string]any{"repo": "apt"},
+		},
+	}
+	if err := writeReleaseRecord(outputDir, record); err != nil {
+		t.Fatalf("failed to write record: %v", err)
+	}
+
+	mock := &MockCommandExecutor{}
+	p := &LinuxPkgPlugin{cmdExecutor: mock, httpClient: server.Client()}
+	cfg := &Config{
+		WorkingDir: dir,
+		OutputDir:  "dist",
+		Yank:       true,
+		Publish: &PublishConfig{
+			GitHub: &GitHubPublishConfig{TokenEnv: "TEST_GITHUB_TOKEN", APIURL: server.URL},
+			S3:     &S3PublishConfig{Bucket: "pkgs", Region: "eu-west-1"},
+		},
+	}
+	resp, err := p.cleanupRecorded(context.Background(), cfg, plugin.ReleaseContext{Version: "1.0.0"}, false)
+	if err != nil || !resp.Success {
+		t.Fatalf("expected cleanup to succeed, got %v, %+v", err, resp)
+	}
+
+	expected := []string{"GET /repos/owner/myapp/releases/tags/v1.0.0", "DELETE /repos/owner/myapp/releases/assets/7"}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("expected requests %v, got %v", expected, requests)
+	}
+	expectedArgs := []string{"s3", "rm", "--only-show-errors", "--region", "eu-west-1", "s3://pkgs/myapp/1.0.0/myapp.deb"}
+	if len(mock.Calls) != 1 || mock.Calls[0].Name != "aws" || !reflect.DeepEqual(mock.Calls[0].Args, expectedArgs) {
+		t.Errorf("expected aws %q, got %v", expectedArgs, mock.Calls)
+	}
+	if _, err := os.Stat(pkg); !os.IsNotExist(err) {
+		t.Errorf("expected the package to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// defaultIgnoreFiles are the ignore files honored when respect_ignore_files is enabled.
+var defaultIgnoreFiles = []string{".gitignore", ".nfpmignore"}
+
+// ignoreRule is a single compiled line of a gitignore-style file.
+type ignoreRule struct {
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+	// anchored rules match against the full relative path rather than the base name.
+	anchored bool
+}
+
+// ignoreMatcher decides whether workspace-relative paths are excluded by gitignore-style rules.
+type ignoreMatcher struct {
+	rules []ignoreRule
+	// root is the workspace absolute paths are made relative to. Empty leaves them as-is.
+	root string
+}
+
+// loadIgnoreMatcher reads the given ignore files in root, or the working directory when
+// root is empty, skipping ones that do not exist.
+func loadIgnoreMatcher(root string, files ...string) (*ignoreMatcher, error) {
+	m := &ignoreMatcher{root: root}
+	for _, file := range files {
+		f, err := os.Open(inWorkingDir(root, file))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ignore file %s: %w", file, err)
+		}
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			m.add(scanner.Text())
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ignore file %s: %w", file, err)
+		}
+	}
+	return m, nil
+}
+
+// add compiles a single gitignore-style line and appends it to the matcher.
+func (m *ignoreMatcher) add(line string) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+
+	var rule ignoreRule
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	}
+	line = strings.TrimPrefix(line, `\`)
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	// A slash anywhere but the end anchors the pattern to the workspace root.
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return
+	}
+
+	re, err := regexp.Compile(globToRegexp(line))
+	if err != nil {
+		return
+	}
+	rule.pattern = re
+	m.rules = append(m.rules, rule)
+}
+
+// Match reports whether a slash-separated relative path is ignored. A path is
+// also ignored when any of its parent directories is ignored. Absolute paths within the
+// matcher's root are matched relative to it.
+func (m *ignoreMatcher) Match(rel string, isDir bool) bool {
+	if m == nil || len(m.rules) == 0 {
+		return false
+	}
+
+	if m.root != "" && path.IsAbs(rel) {
+		if r, err := filepath.Rel(m.root, filepath.FromSlash(rel)); err == nil {
+			rel = filepath.ToSlash(r)
+		}
+	}
+
+	rel = strings.TrimPrefix(path.Clean(rel), "./")
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.matchOne(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.matchOne(rel, isDir)
+}
+
+// matchOne applies the rules to a single path; the last matching rule wins.
+func (m *ignoreMatcher) matchOne(rel string, isDir bool) bool {
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		target := rel
+		if !rule.anchored {
+			target = path.Base(rel)
+		}
+		if rule.pattern.MatchString(target) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// globToRegexp converts a slash-separated glob with `*`, `?`, `[...]`, and `**` into an
+// anchored regular expression.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	b.WriteString("$")
+	return b.String()
+}
+
+// hasGlobMeta reports whether a path contains glob metacharacters.
+func hasGlobMeta(p string) bool {
+	return strings.ContainsAny(p, "*?[")
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestIgnoreMatcher tests gitignore-style pattern matching.
+func TestIgnoreMatcher(t *testing.T) {
+	t.Parallel()
+
+	m := &ignoreMatcher{}
+	for _, line := range []string{
+		"# build byproducts",
+		"*.o",
+		"*.log",
+		"!keep.log",
+		"tmp/",
+		"/coverage.out",
+		"docs/**/*.draft.md",
+		"",
+	} {
+		m.add(line)
+	}
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{path: "main.o", ignored: true},
+		{path: "build/deep/main.o", ignored: true},
+		{path: "build/myapp", ignored: false},
+		{path: "debug.log", ignored: true},
+		{path: "logs/keep.log", ignored: false},
+		{path: "tmp", isDir: true, ignored: true},
+		{path: "tmp/cache/data.bin", ignored: true},
+		{path: "src/tmp", ignored: false},
+		{path: "coverage.out", ignored: true},
+		{path: "pkg/coverage.out", ignored: false},
+		{path: "docs/guide.draft.md", ignored: true},
+		{path: "docs/a/b/guide.draft.md", ignored: true},
+		{path: "docs/guide.md", ignored: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.path, func(t *testing.T) {
+			t.Parallel()
+
+			if got := m.Match(tc.path, tc.isDir); got != tc.ignored {
+				t.Errorf("Match(%q): expected %v, got %v", tc.path, tc.ignored, got)
+			}
+		})
+	}
+
+	t.Run("nil matcher ignores nothing", func(t *testing.T) {
+		t.Parallel()
+
+		var nilMatcher *ignoreMatcher
+		if nilMatcher.Match("main.o", false) {
+			t.Error("expected nil matcher to ignore nothing")
+		}
+	})
+}
+
+// TestGlobToRegexp tests glob translation.
+func TestGlobToRegexp(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		glob    string
+		path    string
+		matches bool
+	}{
+		{glob: "build/*", path: "build/myapp", matches: true},
+		{glob: "build/*", path: "build/sub/myapp", matches: false},
+		{glob: "build/**", path: "build/sub/myapp", matches: true},
+		{glob: "build/**/*.so", path: "build/lib.so", matches: true},
+		{glob: "build/**/*.so", path: "build/a/b/lib.so", matches: true},
+		{glob: "bin/app-?", path: "bin/app-1", matches: true},
+		{glob: "bin/app-[ab]", path: "bin/app-c", matches: false},
+		{glob: "bin/app-[!ab]", path: "bin/app-c", matches: true},
+		{glob: "conf/app.yaml", path: "conf/appXyaml", matches: false},
+	}
+
+	for _, tc := range tests {
+		m := &ignoreMatcher{}
+		m.add("/" + tc.glob)
+		if got := m.rules[0].pattern.MatchString(tc.path); got != tc.matches {
+			t.Errorf("glob %q against %q: expected %v, got %v", tc.glob, tc.path, tc.matches, got)
+		}
+	}
+}
+
+// TestExpandContentGlobs tests that globbed contents honor ignore files in the working
+// directory.
+func TestExpandContentGlobs(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		".gitignore":                 "*.tmp\n",
+		".nfpmignore":                "share/drafts/\n",
+		"build/myapp":                "binary",
+		"build/myapp.tmp":            "scratch",
+		"share/docs/README.md":       "docs",
+		"share/docs/api/index.md":    "api",
+		"share/drafts/unreleased.md": "draft",
+		"config/myapp.yaml":          "config",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	ignore, err := loadIgnoreMatcher(dir, defaultIgnoreFiles...)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	doc := map[string]any{
+		"contents": []any{
+			map[string]any{"src": "build/*", "dst": "/usr/bin"},
+			map[string]any{"src": "share/**/*.md", "dst": "/usr/share/myapp"},
+			map[string]any{"src": "config/myapp.yaml", "dst": "/etc/myapp/myapp.yaml", "type": "config"},
+			map[string]any{"dst": "/var/lib/myapp", "type": "dir"},
+		},
+	}
+
+	rebaseNfpmPaths(doc, dir)
+	if err := expandContentGlobs(doc, ignore); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, raw := range contentEntries(doc) {
+		entry := raw.(map[string]any)
+		src, _ := entry["src"].(string)
+		got[entry["dst"].(string)] = src
+	}
+
+	root := filepath.ToSlash(dir) + "/"
+	expected := map[string]string{
+		"/usr/bin/myapp":                     root + "build/myapp",
+		"/usr/share/myapp/docs/README.md":    root + "share/docs/README.md",
+		"/usr/share/myapp/docs/api/index.md": root + "share/docs/api/index.md",
+		"/etc/myapp/myapp.yaml":              root + "config/myapp.yaml",
+		"/var/lib/myapp":                     "",
+	}
+	if len(got) != len(expected) {
+		t.Errorf("expected %d entries, got %d: %v", len(expected), len(got), got)
+	}
+	for dst, src := range expected {
+		if got[dst] != src {
+			t.Errorf("%s: expected src %q, got %q", dst, src, got[dst])
+		}
+	}
+}
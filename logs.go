@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// logsDirName is the subdirectory of output_dir where tool logs are persisted.
+const logsDirName = "logs"
+
+// writeToolLog persists the full output of a tool invocation to output_dir/logs/<name>.log,
+// gzip-compressing it when compress is set. It returns the path of the written log.
+func writeToolLog(outputDir, name string, output []byte, compress bool) (string, error) {
+	logsDir := filepath.Join(outputDir, logsDirName)
+	if err := os.MkdirAll(logsDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create logs directory: %w", err)
+	}
+
+	path := filepath.Join(logsDir, name+".log")
+	if !compress {
+		if err := os.WriteFile(path, output, 0644); err != nil {
+			return "", fmt.Errorf("failed to write log %s: %w", path, err)
+		}
+		return path, nil
+	}
+
+	path += ".gz"
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to create log %s: %w", path, err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	if _, err := gz.Write(output); err != nil {
+		return "", fmt.Errorf("failed to write log %s: %w", path, err)
+	}
+	if err := gz.Close(); err != nil {
+		return "", fmt.Errorf("failed to write log %s: %w", path, err)
+	}
+
+	return path, nil
+}
+
+// publishLogName is the name of the persisted log of the publish step.
+const publishLogName = "publish"
+
+// loggingExecutor runs commands with executor and keeps a transcript of each command line
+// and its combined output, so a step running several tools can persist one log. The
+// environment is left out, as it carries credentials.
+type loggingExecutor struct {
+	executor CommandExecutor
+	mu       sync.Mutex
+	log      bytes.Buffer
+}
+
+// Exec implements CommandExecutor.
+func (e *loggingExecutor) Exec(ctx context.Context, spec ExecSpec) (*ExecResult, error) {
+	result, err := e.executor.Exec(ctx, spec)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fmt.Fprintf(&e.log, "$ %s\n", strings.Join(append([]string{spec.Name}, spec.Args...), " "))
+	if result != nil && len(result.Combined) > 0 {
+		e.log.Write(result.Combined)
+		if !bytes.HasSuffix(result.Combined, []byte("\n")) {
+			e.log.WriteByte('\n')
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(&e.log, "error: %v\n", err)
+	}
+	return result, err
+}
+
+// output returns the transcript so far.
+func (e *loggingExecutor) output() []byte {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return bytes.Clone(e.log.Bytes())
+}
+
+// logMu serializes lines written to the log output, so the output of concurrent builds
+// is interleaved line by line rather than mid-line.
+var logMu sync.Mutex
+
+// linePrefixWriter writes complete lines to an underlying writer, each prefixed with the
+// job it belongs to, e.g. "[deb/amd64] ". A trailing partial line is held back until it
+// is completed or Flush is called.
+type linePrefixWriter struct {
+	w       io.Writer
+	prefix  string
+	mu      sync.Mutex
+	partial []byte
+}
+
+// newLinePrefixWriter returns a linePrefixWriter writing to w.
+func newLinePrefixWriter(w io.Writer, prefix string) *linePrefixWriter {
+	return &linePrefixWriter{w: w, prefix: prefix}
+}
+
+func (l *linePrefixWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.partial = append(l.partial, p...)
+	for {
+		i := bytes.IndexByte(l.partial, '\n')
+		if i < 0 {
+			break
+		}
+		if err := l.writeLine(l.partial[:i+1]); err != nil {
+			return 0, err
+		}
+		l.partial = l.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes a trailing partial line, terminated with a newline.
+func (l *linePrefixWriter) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.partial) == 0 {
+		return nil
+	}
+	line := append(l.partial, '\n')
+	l.partial = nil
+	return l.writeLine(line)
+}
+
+// writeLine writes a single prefixed line.
+func (l *linePrefixWriter) writeLine(line []byte) error {
+	logMu.Lock()
+	defer logMu.Unlock()
+
+	_, err := l.w.Write(append([]byte(l.prefix), line...))
+	return err
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestWriteToolLog tests persisting tool output as plain and gzipped logs.
+func TestWriteToolLog(t *testing.T) {
+	t.Parallel()
+
+	output := []byte("using deb packager...\ncreated package: dist/myapp_1.0.0_amd64.deb\n")
+
+	t.Run("plain", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		path, err := writeToolLog(dir, "deb-amd64", output, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if expected := filepath.Join(dir, "logs", "deb-amd64.log"); path != expected {
+			t.Errorf("expected %q, got %q", expected, path)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read log: %v", err)
+		}
+		if string(data) != string(output) {
+			t.Errorf("expected %q, got %q", output, data)
+		}
+	})
+
+	t.Run("gzipped", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		path, err := writeToolLog(dir, "rpm-arm64", output, true)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if expected := filepath.Join(dir, "logs", "rpm-arm64.log.gz"); path != expected {
+			t.Errorf("expected %q, got %q", expected, path)
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("failed to open log: %v", err)
+		}
+		defer f.Close()
+
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("failed to read gzip: %v", err)
+		}
+		data, err := io.ReadAll(gz)
+		if err != nil {
+			t.Fatalf("failed to decompress log: %v", err)
+		}
+		if string(data) != string(output) {
+			t.Errorf("expected %q, got %q", output, data)
+		}
+	})
+}
+
+// TestExecutePersistsLogs tests that logs are written and reported for successful and failed builds.
+func TestExecutePersistsLogs(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: test\nversion: 1.0.0"), 0644); err != nil {
+		t.Fatalf("failed to create test config: %v", err)
+	}
+
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			for i, arg := range args {
+				if arg == "--packager" && args[i+1] == "rpm" {
+					return []byte("error: rpmbuild exploded"), errors.New("exit status 1")
+				}
+			}
+			return []byte("created package: dist/test_1.0.0_amd64.deb"), nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir":  dir,
+			"formats":      []string{"deb", "rpm"},
+			"target":       "amd64",
+			"persist_logs": true,
+			"packager":     "nfpm-cli",
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success {
+		t.Fatal("expected rpm build failure")
+	}
+
+	logs, ok := resp.Outputs["logs"].([]string)
+	if !ok || len(logs) != 2 {
+		t.Fatalf("expected 2 logs in outputs, got %v", resp.Outputs["logs"])
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "dist", "logs", "rpm-amd64.log"))
+	if err != nil {
+		t.Fatalf("expected failed build log to be persisted: %v", err)
+	}
+	if string(data) != "error: rpmbuild exploded" {
+		t.Errorf("unexpected log content %q", data)
+	}
+}
+
+// TestLinePrefixWriter tests that output is written line by line with a prefix.
+func TestLinePrefixWriter(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	w := newLinePrefixWriter(&out, "[deb/amd64] ")
+	for _, chunk := range []string{"using deb", " packager\ncreated", " package\n", "done"} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if expected := "[deb/amd64] using deb packager\n[deb/amd64] created package\n"; out.String() != expected {
+		t.Errorf("expected %q before flushing, got %q", expected, out.String())
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasSuffix(out.String(), "\n[deb/amd64] done\n") {
+		t.Errorf("expected the partial line after flushing, got %q", out.String())
+	}
+}
+
+// TestExecuteStreamsOutput tests that nfpm output reaches the log while it is still
+// captured for the package path.
+func TestExecuteStreamsOutput(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: test\nversion: 1.0.0"), 0644); err != nil {
+		t.Fatalf("failed to create test config: %v", err)
+	}
+
+	mock := &MockCommandExecutor{
+		ExecFunc: func(ctx context.Context, spec ExecSpec) (*ExecResult, error) {
+			format, target := spec.Args[4], spec.Args[len(spec.Args)-1]
+			output := []byte("using " + format + " packager...\ncreated package: " + target + "test." + format + "\n")
+			if spec.Output == nil {
+				t.Error("expected an output to stream to")
+			} else {
+				_, _ = spec.Output.Write(output)
+			}
+			return &ExecResult{Stdout: output, Combined: output}, nil
+		},
+	}
+	var log bytes.Buffer
+	p := &LinuxPkgPlugin{cmdExecutor: mock, logOutput: &log}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb", "rpm"},
+			"target":      "amd64",
+			"packager":    "nfpm-cli",
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	for _, line := range []string{
+		"[deb/amd64] using deb packager...\n",
+		"[deb/amd64] created package: " + filepath.Join(dir, "dist", "test.deb") + "\n",
+		"[rpm/amd64] using rpm packager...\n",
+		"[rpm/amd64] created package: " + filepath.Join(dir, "dist", "test.rpm") + "\n",
+	} {
+		if !strings.Contains(log.String(), line) {
+			t.Errorf("expected %q in the log, got %q", line, log.String())
+		}
+	}
+	expected := []string{filepath.Join(dir, "dist", "test.deb"), filepath.Join(dir, "dist", "test.rpm")}
+	if packages := resp.Outputs["packages"].([]string); !reflect.DeepEqual(packages, expected) {
+		t.Errorf("expected packages %v, got %v", expected, packages)
+	}
+}
+
+// TestExecutePersistsSigningAndPublishLogs tests that cosign output is kept in the log of
+// the package it signed, and the output of publishing in the publish log.
+func TestExecutePersistsSigningAndPublishLogs(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			switch name {
+			case "cosign":
+				if _, err := fakeCosign(ctx, name, args...); err != nil {
+					return nil, err
+				}
+				return []byte("tlog entry created with index: 4242\n"), nil
+			case "createrepo_c":
+				return []byte("Directory walk done - 1 packages\n"), nil
+			}
+			return nil, nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir":  dir,
+			"formats":      []string{"rpm"},
+			"persist_logs": true,
+			"cosign":       map[string]any{},
+			"publish":      map[string]any{"yum": map[string]any{"repo": "yum"}},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected success, got %v, %+v", err, resp)
+	}
+
+	logs := resp.Outputs["logs"].([]string)
+	expected := []string{filepath.Join(dir, "dist", "logs", "rpm-amd64.log"), filepath.Join(dir, "dist", "logs", "publish.log")}
+	if !reflect.DeepEqual(logs, expected) {
+		t.Fatalf("expected logs %v, got %v", expected, logs)
+	}
+	for path, want := range map[string][]string{
+		logs[0]: {"created package: ", "tlog entry created with index: 4242"},
+		logs[1]: {"$ createrepo_c --update " + filepath.Join(dir, "yum") + "\n", "Directory walk done - 1 packages"},
+	} {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read log: %v", err)
+		}
+		for _, line := range want {
+			if !strings.Contains(string(data), line) {
+				t.Errorf("expected %s to contain %q, got %q", filepath.Base(path), line, data)
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/cpuguy83/go-md2man/v2/md2man"
+)
+
+// manpagePattern splits a man page source name like "myapp.1.md" or "myapp.conf.5" into
+// its name, section, and Markdown suffix.
+var manpagePattern = regexp.MustCompile(`^(.+)\.([1-9][a-z]*)(\.md)?$`)
+
+// manpageContentTypes are the formats that get man pages and the nfpm content type used
+// for them. rpm marks them as documentation so --excludedocs skips them; OpenWrt packages
+// do not ship man pages.
+var manpageContentTypes = map[string]string{
+	"deb":       "",
+	"rpm":       "doc",
+	"apk":       "",
+	"archlinux": "",
+}
+
+// parseManpage returns the page name and section of a man page source, and whether it is
+// Markdown.
+func parseManpage(src string) (name, section string, markdown bool, err error) {
+	m := manpagePattern.FindStringSubmatch(filepath.Base(src))
+	if m == nil {
+		return "", "", false, fmt.Errorf("cannot tell the section of %s: name it <page>.<section>[.md], e.g. myapp.1.md", src)
+	}
+	return m[1], m[2], m[3] != "", nil
+}
+
+// validateManpages checks the man page sources.
+func validateManpages(manpages []string) error {
+	seen := make(map[string]bool, len(manpages))
+	for _, src := range manpages {
+		if err := validatePath(src); err != nil {
+			return err
+		}
+		name, section, _, err := parseManpage(src)
+		if err != nil {
+			return err
+		}
+		page := name + "." + section
+		if seen[page] {
+			return fmt.Errorf("duplicate man page %s", page)
+		}
+		seen[page] = true
+	}
+	return nil
+}
+
+// renderManpage returns the gzipped roff man page for src. Markdown sources are converted
+// with go-md2man; those without a "% NAME SECTION" title line get one from the file name.
+func renderManpage(src string) ([]byte, error) {
+	name, section, markdown, err := parseManpage(src)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read man page: %w", err)
+	}
+	if markdown {
+		if !bytes.HasPrefix(content, []byte("%")) {
+			title := fmt.Sprintf("%% %s %s\n\n", strings.ToUpper(name), section)
+			content = append([]byte(title), content...)
+		}
+		content = md2man.Render(content)
+	}
+
+	// The gzip header carries no name or time, so unchanged pages compress identically.
+	var buf bytes.Buffer
+	zw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := zw.Write(content); err != nil {
+		return nil, fmt.Errorf("failed to compress man page %s: %w", src, err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress man page %s: %w", src, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// addManpages renders the man pages into dir and adds them to doc's contents under
+// /usr/share/man/man<N>/, with one entry per format that ships man pages.
+func addManpages(doc map[string]any, manpages []string, dir string) error {
+	contents := contentEntries(doc)
+	for _, src := range manpages {
+		name, section, _, err := parseManpage(src)
+		if err != nil {
+			return err
+		}
+		page, err := renderManpage(src)
+		if err != nil {
+			return err
+		}
+
+		file := name + "." + section + ".gz"
+		rendered := filepath.Join(dir, "man", file)
+		if err := os.MkdirAll(filepath.Dir(rendered), 0755); err != nil {
+			return fmt.Errorf("failed to create man page directory: %w", err)
+		}
+		if err := os.WriteFile(rendered, page, 0644); err != nil {
+			return fmt.Errorf("failed to write man page %s: %w", file, err)
+		}
+
+		dst := fmt.Sprintf("/usr/share/man/man%s/%s", section[:1], file)
+		for _, format := range sortedKeys(manpageContentTypes) {
+			entry := map[string]any{
+				"src":       rendered,
+				"dst":       dst,
+				"packager":  format,
+				"file_info": map[string]any{"mode": 0644},
+			}
+			if contentType := manpageContentTypes[format]; contentType != "" {
+				entry["type"] = contentType
+			}
+			contents = append(contents, entry)
+		}
+	}
+	doc["contents"] = contents
+	return nil
+}
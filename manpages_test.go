@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+	"gopkg.in/yaml.v3"
+)
+
+// gunzipString decompresses data.
+func gunzipString(t *testing.T, data []byte) string {
+	t.Helper()
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to open gzip data: %v", err)
+	}
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("failed to read gzip data: %v", err)
+	}
+	return string(content)
+}
+
+// TestValidateManpages tests manpages validation.
+func TestValidateManpages(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		manpages    []string
+		expectError string
+	}{
+		{"valid", []string{"docs/myapp.1.md", "docs/myapp.conf.5", "docs/myapp-admin.8.md", "docs/MyApp.3p"}, ""},
+		{"no section", []string{"docs/myapp.md"}, "cannot tell the section"},
+		{"section zero", []string{"docs/myapp.0"}, "cannot tell the section"},
+		{"duplicate", []string{"docs/myapp.1.md", "man/myapp.1"}, "duplicate man page myapp.1"},
+		{"traversal", []string{"../docs/myapp.1"}, "path traversal"},
+	}
+
+	p := &LinuxPkgPlugin{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := p.Validate(context.Background(), map[string]any{"manpages": tt.manpages})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectError == "" {
+				if !resp.Valid {
+					t.Errorf("expected valid config, got errors: %v", resp.Errors)
+				}
+				return
+			}
+			if resp.Valid || len(resp.Errors) == 0 || resp.Errors[0].Field != "manpages" ||
+				!strings.Contains(resp.Errors[0].Message, tt.expectError) {
+				t.Errorf("expected manpages error containing %q, got %v", tt.expectError, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestRenderManpage tests converting and compressing man pages.
+func TestRenderManpage(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"myapp.1.md":       "# NAME\n\nmyapp - does things\n\n# SYNOPSIS\n\n**myapp** [*options*]\n",
+		"myapp-admin.8.md": "% MYAPP-ADMIN 8 \"May 2024\"\n\n# NAME\n\nmyapp-admin - manages things\n",
+		"myapp.conf.5":     ".TH MYAPP.CONF 5\n.SH NAME\nmyapp.conf\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		file     string
+		contains []string
+	}{
+		{"myapp.1.md", []string{".TH MYAPP 1", ".SH NAME", `\fBmyapp\fP`}},
+		{"myapp-admin.8.md", []string{`.TH MYAPP-ADMIN 8 "May 2024"`, ".SH NAME"}},
+		{"myapp.conf.5", []string{files["myapp.conf.5"]}},
+	}
+	for _, tt := range tests {
+		page, err := renderManpage(filepath.Join(dir, tt.file))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.file, err)
+		}
+		roff := gunzipString(t, page)
+		for _, want := range tt.contains {
+			if !strings.Contains(roff, want) {
+				t.Errorf("%s: expected %q in:\n%s", tt.file, want, roff)
+			}
+		}
+
+		again, err := renderManpage(filepath.Join(dir, tt.file))
+		if err != nil || !bytes.Equal(page, again) {
+			t.Errorf("%s: expected identical output for identical input", tt.file)
+		}
+	}
+}
+
+// TestExecuteManpages tests that man pages reach nfpm for each format that ships them.
+func TestExecuteManpages(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: myapp\nversion: 1.2.3\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "docs"), 0755); err != nil {
+		t.Fatalf("failed to create docs: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "docs/myapp.1.md"), []byte("# NAME\n\nmyapp - does things\n"), 0644); err != nil {
+		t.Fatalf("failed to write man page: %v", err)
+	}
+
+	var entries []string
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			content, err := os.ReadFile(args[2])
+			if err != nil {
+				return nil, err
+			}
+			var doc map[string]any
+			if err := yaml.Unmarshal(content, &doc); err != nil {
+				return nil, err
+			}
+			for _, raw := range contentEntries(doc) {
+				entry := raw.(map[string]any)
+				entryType, _ := entry["type"].(string)
+				entries = append(entries, entry["packager"].(string)+" "+entry["dst"].(string)+" "+entryType)
+				if _, err := os.Stat(entry["src"].(string)); err != nil {
+					return nil, err
+				}
+			}
+			return []byte("created package: " + args[len(args)-1] + "myapp.deb"), nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"working_dir": dir, "formats": []string{"deb"}, "packager": "nfpm-cli", "manpages": []string{"docs/myapp.1.md"}},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	expected := []string{
+		"apk /usr/share/man/man1/myapp.1.gz ",
+		"archlinux /usr/share/man/man1/myapp.1.gz ",
+		"deb /usr/share/man/man1/myapp.1.gz ",
+		"rpm /usr/share/man/man1/myapp.1.gz doc",
+	}
+	if !reflect.DeepEqual(entries, expected) {
+		t.Errorf("expected contents %q, got %q", expected, entries)
+	}
+
+	// A missing page fails before packaging.
+	mock.Calls = nil
+	resp, err = p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"working_dir": dir, "formats": []string{"deb"}, "packager": "nfpm-cli", "manpages": []string{"docs/myapp.8.md"}},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "invalid manpages") || len(mock.Calls) != 0 {
+		t.Errorf("expected a missing man page error before packaging, got %+v", resp)
+	}
+}
+
+// TestExecuteManpagesEmbedded tests building packages with man pages with the embedded
+// library.
+func TestExecuteManpagesEmbedded(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+	if err := os.WriteFile(filepath.Join(dir, "myapp.1.md"), []byte("# NAME\n\nmyapp - does things\n"), 0644); err != nil {
+		t.Fatalf("failed to write man page: %v", err)
+	}
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"working_dir": dir, "formats": []string{"deb", "rpm", "ipk"}, "manpages": []string{"myapp.1.md"}},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+	if packages := resp.Outputs["packages"].([]string); len(packages) != 3 {
+		t.Errorf("expected 3 packages, got %v", packages)
+	}
+}
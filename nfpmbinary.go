@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// minNfpmVersion is the oldest nfpm release the nfpm-cli packager supports by default.
+// Older releases ignore config keys such as zstd compression or rpm prefixes.
+const minNfpmVersion = "2.35.0"
+
+// nfpmInstallHint tells users where to get the nfpm binary.
+const nfpmInstallHint = "install it from https://nfpm.goreleaser.com/install/, e.g. with " +
+	"`go install github.com/goreleaser/nfpm/v2/cmd/nfpm@latest`, or use packager: nfpm, which needs no binary"
+
+// checkNfpmBinary checks that the nfpm binary the nfpm-cli packager runs, the one at
+// nfpmPath or else the one on PATH, exists and is not older than minimum.
+func (p *LinuxPkgPlugin) checkNfpmBinary(ctx context.Context, nfpmPath, minimum string) error {
+	if nfpmPath != "" {
+		binary, err := checkNfpmPath(nfpmPath)
+		if err != nil {
+			return err
+		}
+		return checkNfpmVersion(ctx, p.getExecutor(), binary, minimum)
+	}
+	if _, err := p.getLookPath()("nfpm"); err != nil {
+		return fmt.Errorf("nfpm-cli requires the nfpm binary on PATH: %s", nfpmInstallHint)
+	}
+	return checkNfpmVersion(ctx, p.getExecutor(), "nfpm", minimum)
+}
+
+// checkNfpmVersion runs `binary --version` and returns an error if the nfpm version it
+// reports is older than minimum. A version that cannot be read from the output is not
+// held against the binary.
+func checkNfpmVersion(ctx context.Context, executor CommandExecutor, binary, minimum string) error {
+	output, err := runCommand(ctx, executor, binary, "--version")
+	if err != nil {
+		return fmt.Errorf("failed to run nfpm --version: %w", err)
+	}
+	version := parseNfpmVersion(output)
+	if version == "" {
+		return nil
+	}
+	return checkMinNfpmVersion(version, minimum)
+}
+
+// validateMinNfpmVersion checks min_nfpm_version.
+func validateMinNfpmVersion(minimum string) error {
+	if _, err := semver.NewVersion(strings.TrimPrefix(minimum, "v")); err != nil {
+		return fmt.Errorf("must be a version such as 2.35.0, got %q", minimum)
+	}
+	return nil
+}
+
+// checkMinNfpmVersion returns an error if version is older than minimum.
+func checkMinNfpmVersion(version, minimum string) error {
+	v, err := semver.NewVersion(strings.TrimPrefix(version, "v"))
+	if err != nil {
+		return nil
+	}
+	least, err := semver.NewVersion(strings.TrimPrefix(minimum, "v"))
+	if err != nil {
+		return validateMinNfpmVersion(minimum)
+	}
+	if v.LessThan(least) {
+		return fmt.Errorf("nfpm %s is older than the minimum supported version %s; %s", version, minimum, nfpmInstallHint)
+	}
+	return nil
+}
+
+// validateNfpmPath checks nfpm_path, a vendored nfpm binary the nfpm-cli packager runs
+// in place of the one on PATH. It must be inside the working directory unless absolute
+// paths are allowed, and cannot be combined with a pinned nfpm_version.
+func validateNfpmPath(path string, allowAbsolute bool, packager, version string) error {
+	if path == "" {
+		return nil
+	}
+	if !allowAbsolute || !filepath.IsAbs(path) {
+		if err := validatePath(path); err != nil {
+			if filepath.IsAbs(path) {
+				return fmt.Errorf("%w (set allow_absolute_nfpm_path to allow one)", err)
+			}
+			return err
+		}
+	}
+	if packager != "nfpm-cli" {
+		return fmt.Errorf("requires packager: nfpm-cli")
+	}
+	if version != "" {
+		return fmt.Errorf("cannot be combined with nfpm_version")
+	}
+	return nil
+}
+
+// checkNfpmPath checks that path is an executable file and returns it as an absolute
+// path, so running it never searches PATH.
+func checkNfpmPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	info, err := os.Stat(abs)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("nfpm binary does not exist: %s", path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+		return "", fmt.Errorf("nfpm binary is not an executable file: %s", path)
+	}
+	return abs, nil
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestValidateNfpmBinary tests that Validate checks the nfpm binary the nfpm-cli
+// packager runs, warning outside strict mode and failing in it.
+func TestValidateNfpmBinary(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		found       bool
+		version     string
+		expectError string
+	}{
+		{"supported", true, "GitVersion:    v2.41.1\n", ""},
+		{"bare version", true, "2.35.0\n", ""},
+		{"unknown version", true, "nfpm built from source\n", ""},
+		{"missing", false, "", "requires the nfpm binary on PATH: install it from https://nfpm.goreleaser.com/install/"},
+		{"too old", true, "GitVersion:    v2.20.0\n", "nfpm v2.20.0 is older than the minimum supported version 2.35.0"},
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: myapp\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var log bytes.Buffer
+			p := &LinuxPkgPlugin{
+				cmdExecutor: &MockCommandExecutor{
+					RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+						return []byte(tt.version), nil
+					},
+				},
+				lookPath: func(file string) (string, error) {
+					if !tt.found {
+						return "", errors.New("not found")
+					}
+					return "/usr/bin/" + file, nil
+				},
+				logOutput: &log,
+			}
+
+			resp, err := p.Validate(context.Background(), map[string]any{"working_dir": dir, "packager": "nfpm-cli"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !resp.Valid {
+				t.Errorf("expected valid without strict, got errors=%v", resp.Errors)
+			}
+			if warned := strings.HasPrefix(log.String(), "warning: "); warned != (tt.expectError != "") {
+				t.Errorf("expected a warning only for a problem, got %q", log.String())
+			}
+
+			resp, err = p.Validate(context.Background(), map[string]any{"working_dir": dir, "packager": "nfpm-cli", "strict": true})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectError == "" {
+				if !resp.Valid {
+					t.Errorf("expected valid, got errors=%v", resp.Errors)
+				}
+				return
+			}
+			if len(resp.Errors) != 1 || resp.Errors[0].Field != "packager" || !strings.Contains(resp.Errors[0].Message, tt.expectError) {
+				t.Errorf("expected packager error containing %q, got %v", tt.expectError, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestExecuteMinNfpmVersion tests that min_nfpm_version refuses to build with an older
+// nfpm.
+func TestExecuteMinNfpmVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		version     string
+		expectError string
+	}{
+		{"newer", "GitVersion:    v2.41.1\n", ""},
+		{"same", "GitVersion:    v2.38.0\n", ""},
+		{"older", "GitVersion:    v2.30.1\n", "nfpm v2.30.1 is older than the minimum supported version v2.38.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: test\nversion: 1.0.0"), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+			mock := &MockCommandExecutor{
+				RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+					if args[0] == "--version" {
+						return []byte(tt.version), nil
+					}
+					return []byte("created package: " + args[len(args)-1] + "test.deb"), nil
+				},
+			}
+			p := &LinuxPkgPlugin{cmdExecutor: mock}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook: plugin.HookPostPublish,
+				Config: map[string]any{
+					"working_dir":      dir,
+					"formats":          []string{"deb"},
+					"packager":         "nfpm-cli",
+					"min_nfpm_version": "v2.38.0",
+				},
+				Context: plugin.ReleaseContext{Version: "1.0.0"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tt.expectError != "" {
+				if resp.Success || !strings.HasPrefix(resp.Error, tt.expectError) {
+					t.Errorf("expected error starting with %q, got %+v", tt.expectError, resp)
+				}
+				if len(mock.Calls) != 1 {
+					t.Errorf("expected no build, got %v", mock.Calls)
+				}
+				return
+			}
+			if !resp.Success {
+				t.Fatalf("expected success, got failure: %s", resp.Error)
+			}
+			if len(mock.Calls) != 2 || mock.Calls[0].Args[0] != "--version" {
+				t.Errorf("expected the version check before the build, got %v", mock.Calls)
+			}
+		})
+	}
+}
+
+// TestExecuteNfpmPath tests that nfpm_path runs a vendored nfpm binary from the working
+// directory, and that absolute paths need allow_absolute_nfpm_path.
+func TestExecuteNfpmPath(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: test\nversion: 1.0.0"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "tools"), 0755); err != nil {
+		t.Fatalf("failed to create tools dir: %v", err)
+	}
+	vendored := filepath.Join(dir, "tools", "nfpm")
+	if err := os.WriteFile(vendored, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("failed to write nfpm: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tools", "readme"), nil, 0644); err != nil {
+		t.Fatalf("failed to write readme: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		config      map[string]any
+		expectError string
+	}{
+		{"relative", map[string]any{"nfpm_path": "tools/nfpm"}, ""},
+		{"absolute allowed", map[string]any{"nfpm_path": vendored, "allow_absolute_nfpm_path": true}, ""},
+		{"absolute", map[string]any{"nfpm_path": vendored}, "invalid nfpm_path: absolute paths are not allowed"},
+		{"escapes working dir", map[string]any{"nfpm_path": "../nfpm"}, "invalid nfpm_path: path traversal detected"},
+		{"missing", map[string]any{"nfpm_path": "tools/missing"}, "invalid nfpm_path: nfpm binary does not exist"},
+		{"not executable", map[string]any{"nfpm_path": "tools/readme"}, "invalid nfpm_path: nfpm binary is not an executable file"},
+		{"with nfpm_version", map[string]any{"nfpm_path": "tools/nfpm", "nfpm_version": "v2.41.1"}, "invalid nfpm_path: cannot be combined with nfpm_version"},
+		{"wrong packager", map[string]any{"nfpm_path": "tools/nfpm", "packager": "nfpm"}, "invalid nfpm_path: requires packager: nfpm-cli"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := map[string]any{"working_dir": dir, "formats": []string{"deb"}, "packager": "nfpm-cli"}
+			for key, value := range tt.config {
+				config[key] = value
+			}
+			mock := &MockCommandExecutor{}
+			p := &LinuxPkgPlugin{
+				cmdExecutor: mock,
+				lookPath:    func(string) (string, error) { return "", errors.New("not found") },
+			}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook:    plugin.HookPostPublish,
+				Config:  config,
+				Context: plugin.ReleaseContext{Version: "1.0.0"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tt.expectError != "" {
+				if resp.Success || !strings.HasPrefix(resp.Error, tt.expectError) {
+					t.Errorf("expected error starting with %q, got %+v", tt.expectError, resp)
+				}
+				return
+			}
+			if !resp.Success {
+				t.Fatalf("expected success, got failure: %s", resp.Error)
+			}
+			if len(mock.Calls) == 0 || mock.Calls[0].Name != vendored {
+				t.Errorf("expected %s to build the package, got %v", vendored, mock.Calls)
+			}
+		})
+	}
+}
+
+// TestValidateNfpmPath tests that Validate checks the binary at nfpm_path in place of
+// the one on PATH.
+func TestValidateNfpmPath(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: myapp\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "nfpm"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("failed to write nfpm: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		fields []string
+	}{
+		{"vendored", "nfpm", nil},
+		{"missing", "tools/nfpm", []string{"packager"}},
+		{"absolute", filepath.Join(dir, "nfpm"), []string{"nfpm_path"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &LinuxPkgPlugin{
+				cmdExecutor: &MockCommandExecutor{
+					RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+						return []byte("GitVersion:    v2.41.1\n"), nil
+					},
+				},
+				lookPath:  func(string) (string, error) { return "", errors.New("not found") },
+				logOutput: io.Discard,
+			}
+			resp, err := p.Validate(context.Background(), map[string]any{
+				"working_dir": dir,
+				"packager":    "nfpm-cli",
+				"nfpm_path":   tt.path,
+				"strict":      true,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(resp.Errors) != len(tt.fields) {
+				t.Fatalf("expected errors for %v, got %v", tt.fields, resp.Errors)
+			}
+			for i, field := range tt.fields {
+				if resp.Errors[i].Field != field {
+					t.Errorf("expected an error for %s, got %v", field, resp.Errors[i])
+				}
+			}
+		})
+	}
+}
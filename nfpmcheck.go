@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/goreleaser/nfpm/v2"
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// nfpmCheckRelease stands in for the release during validation, so configs that take
+// their version from ${VERSION} or templates can be checked before a release exists.
+var nfpmCheckRelease = plugin.ReleaseContext{Version: "0.0.0", TagName: "v0.0.0"}
+
+// checkNfpmConfigs renders the nfpm config of every format and distribution the way a
+// build would, with placeholder release values, and parses it with the nfpm library.
+// This catches YAML syntax errors, unknown keys, and a missing package name. Content
+// files are not checked, as they may only exist once the release has built them.
+func checkNfpmConfigs(cfg *Config) []plugin.ValidationError {
+	data := newNfpmTemplateData(nfpmCheckRelease, time.Now())
+	env := data.env()
+	if cfg.Build != nil {
+		if targets, err := resolveTargets(cfg); err == nil {
+			env = append(slices.Clip(env), goBinaryEnv+"="+cfg.Build.binaryPath(cfg.OutputDir, targets[0]))
+		}
+	}
+
+	var problems []plugin.ValidationError
+	for _, unit := range buildUnits(cfg) {
+		unitCfg := cfg.forUnit(unit)
+		if err := checkNfpmConfig(unitCfg, unit.Format, data, env); err != nil {
+			field := "config_path"
+			if unitCfg.ConfigPath != cfg.ConfigPath {
+				field = "formats"
+			}
+			name := unit.Format
+			if unit.Distro != nil {
+				name = unit.Distro.Name
+			}
+			problems = append(problems, plugin.ValidationError{Field: field, Message: fmt.Sprintf("%s: %v", name, err)})
+		}
+	}
+	return problems
+}
+
+// checkNfpmConfig renders the nfpm config format is built from and parses it.
+func checkNfpmConfig(cfg *Config, format string, data *nfpmTemplateData, env []string) error {
+	prepared, cleanup, err := prepareNfpmConfig(cfg, "", data)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	getenv := envLookup(env, os.Getenv)
+	final, finalCleanup, err := finalizeNfpmConfig(context.Background(), prepared, format, cfg, data, getenv, nil)
+	if err != nil {
+		return err
+	}
+	defer finalCleanup()
+
+	config, err := nfpm.ParseFileWithEnvMapping(final, getenv)
+	if err != nil {
+		return fmt.Errorf("failed to parse nfpm config: %w", err)
+	}
+	info, err := config.Get(format)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s config: %w", format, err)
+	}
+	info = nfpm.WithDefaults(info)
+	info.Contents = nil
+	if err := nfpm.Validate(info); err != nil {
+		return fmt.Errorf("invalid nfpm config: %w", err)
+	}
+	return nil
+}
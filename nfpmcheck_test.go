@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestValidateCheckNfpmConfig tests that check_nfpm_config parses the nfpm config of
+// every format during validation.
+func TestValidateCheckNfpmConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		config      string
+		expectError string
+	}{
+		{"valid", "name: myapp\nversion: ${VERSION}\ncontents:\n  - src: ./bin/myapp\n    dst: /usr/bin/myapp\n", ""},
+		{"templated", "name: myapp\nversion: \"{{ .Version }}\"\n", ""},
+		{"syntax error", "name: myapp\nversion: [1.0.0\n", "failed to parse nfpm config"},
+		{"unknown key", "name: myapp\nversion: 1.0.0\nmaintainr: Relicta Team\n", "field maintainr not found"},
+		{"missing name", "version: 1.0.0\n", "package name must be provided"},
+	}
+
+	p := &LinuxPkgPlugin{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte(tt.config), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+			config := map[string]any{"working_dir": dir, "formats": []string{"deb", "rpm"}}
+			if strings.Contains(tt.config, "{{") {
+				config["template_config"] = true
+			}
+
+			resp, err := p.Validate(context.Background(), config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !resp.Valid {
+				t.Fatalf("expected valid without check_nfpm_config, got errors=%v", resp.Errors)
+			}
+
+			config["check_nfpm_config"] = true
+			resp, err = p.Validate(context.Background(), config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectError == "" {
+				if !resp.Valid {
+					t.Errorf("expected valid config, got errors=%v", resp.Errors)
+				}
+				return
+			}
+			if len(resp.Errors) != 2 {
+				t.Fatalf("expected an error per format, got %v", resp.Errors)
+			}
+			for i, format := range []string{"deb", "rpm"} {
+				e := resp.Errors[i]
+				if e.Field != "config_path" || !strings.HasPrefix(e.Message, format+": ") || !strings.Contains(e.Message, tt.expectError) {
+					t.Errorf("expected %s error containing %q, got %v", format, tt.expectError, e)
+				}
+			}
+		})
+	}
+}
+
+// TestValidateCheckNfpmConfigPerFormat tests that problems in a per-format config are
+// reported under formats.
+func TestValidateCheckNfpmConfigPerFormat(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"nfpm.yaml": "name: myapp\nversion: 1.0.0\n",
+		"rpm.yaml":  "version: 1.0.0\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Validate(context.Background(), map[string]any{
+		"working_dir":       dir,
+		"formats":           map[string]any{"deb": nil, "rpm": map[string]any{"config_path": "rpm.yaml"}},
+		"check_nfpm_config": true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Errors) != 1 || resp.Errors[0].Field != "formats" || !strings.HasPrefix(resp.Errors[0].Message, "rpm: ") {
+		t.Errorf("expected an rpm error, got %v", resp.Errors)
+	}
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// nfpmConfigDecoders maps nfpm config file extensions to their decoders.
+// Files with any other extension are treated as YAML, which is what nfpm reads natively.
+var nfpmConfigDecoders = map[string]func(data []byte, out *map[string]any) error{
+	".yaml": func(data []byte, out *map[string]any) error { return yaml.Unmarshal(data, out) },
+	".yml":  func(data []byte, out *map[string]any) error { return yaml.Unmarshal(data, out) },
+	".json": func(data []byte, out *map[string]any) error { return json.Unmarshal(data, out) },
+	".toml": func(data []byte, out *map[string]any) error { return toml.Unmarshal(data, out) },
+}
+
+// nfpmConfigFormat returns the normalized config file extension for a path.
+func nfpmConfigFormat(path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if _, ok := nfpmConfigDecoders[ext]; ok {
+		return ext
+	}
+	return ".yaml"
+}
+
+// isNativeNfpmConfig reports whether nfpm can read the config file as-is.
+func isNativeNfpmConfig(path string) bool {
+	ext := nfpmConfigFormat(path)
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// loadNfpmConfig reads an nfpm config file in YAML, JSON, or TOML format.
+func loadNfpmConfig(path string) (map[string]any, error) {
+	return loadNfpmTemplate(path, nil)
+}
+
+// loadNfpmTemplate reads an nfpm config file like loadNfpmConfig. When data is not nil,
+// the file is rendered as a template with it before being parsed.
+func loadNfpmTemplate(path string, data *nfpmTemplateData) (map[string]any, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read nfpm config: %w", err)
+	}
+	if data != nil {
+		if content, err = executeNfpmTemplate(path, content, data); err != nil {
+			return nil, err
+		}
+	}
+
+	doc := make(map[string]any)
+	decode := nfpmConfigDecoders[nfpmConfigFormat(path)]
+	if err := decode(content, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse nfpm config %s: %w", path, err)
+	}
+	if doc == nil {
+		doc = make(map[string]any)
+	}
+
+	return doc, nil
+}
+
+// renderNfpmConfig serializes an nfpm config document to YAML.
+func renderNfpmConfig(doc map[string]any) ([]byte, error) {
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render nfpm config: %w", err)
+	}
+	return data, nil
+}
+
+// Allowed strategies for merging lists when applying config overlays.
+var allowedListStrategies = map[string]bool{
+	"replace": true,
+	"append":  true,
+	"unique":  true,
+}
+
+// validateListStrategy validates an overlay list merge strategy.
+func validateListStrategy(strategy string) error {
+	if !allowedListStrategies[strategy] {
+		return fmt.Errorf("unsupported list strategy: %s (allowed: replace, append, unique)", strategy)
+	}
+	return nil
+}
+
+// mergeNfpmConfig deep-merges overlay into base and returns the result.
+// Maps are merged recursively, scalars in overlay win, and lists are combined
+// according to listStrategy. Neither input is modified.
+func mergeNfpmConfig(base, overlay map[string]any, listStrategy string) map[string]any {
+	merged := make(map[string]any, len(base)+len(overlay))
+	for k, v := range base {
+		merged[k] = v
+	}
+
+	for k, ov := range overlay {
+		bv, exists := merged[k]
+		if !exists {
+			merged[k] = ov
+			continue
+		}
+
+		switch o := ov.(type) {
+		case map[string]any:
+			if b, ok := bv.(map[string]any); ok {
+				merged[k] = mergeNfpmConfig(b, o, listStrategy)
+				continue
+			}
+		case []any:
+			if b, ok := bv.([]any); ok {
+				merged[k] = mergeLists(b, o, listStrategy)
+				continue
+			}
+		}
+		merged[k] = ov
+	}
+
+	return merged
+}
+
+// mergeLists combines two lists according to the given strategy.
+func mergeLists(base, overlay []any, strategy string) []any {
+	switch strategy {
+	case "append":
+		result := make([]any, 0, len(base)+len(overlay))
+		result = append(result, base...)
+		return append(result, overlay...)
+	case "unique":
+		result := make([]any, 0, len(base)+len(overlay))
+		seen := make(map[string]bool, len(base)+len(overlay))
+		for _, item := range append(append([]any{}, base...), overlay...) {
+			key := fmt.Sprintf("%#v", item)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			result = append(result, item)
+		}
+		return result
+	default:
+		return overlay
+	}
+}
+
+// needsRendering reports whether the nfpm config must be rewritten before nfpm can use it.
+func needsRendering(cfg *Config) bool {
+	return !isNativeNfpmConfig(cfg.ConfigPath) || cfg.WorkingDir != "" || len(cfg.ConfigOverlays) > 0 || cfg.RespectIgnoreFiles || cfg.TemplateConfig || cfg.Release != "" || cfg.Epoch > 0 || cfg.Reproducible || len(cfg.Scripts) > 0 || cfg.SystemUser != nil || len(cfg.Manpages) > 0 || cfg.Desktop != nil ||
+		(cfg.RPMSigning != nil && cfg.RPMSigning.Method == "nfpm") || cfg.APKKeyPath != ""
+}
+
+// resolveNfpmConfig loads the base nfpm config, applies all configured overlays in order,
+// resolves the files it references against the working directory, expands content globs
+// when ignore files are honored, and adds package signing settings.
+// With template_config, the base config and overlays are rendered with data first. A
+// configured release is set from data, and a configured epoch replaces the config's.
+// Reproducible builds set the config's mtime to the build time unless it has one.
+func resolveNfpmConfig(cfg *Config, data *nfpmTemplateData) (map[string]any, error) {
+	var templateData *nfpmTemplateData
+	if cfg.TemplateConfig {
+		templateData = data
+	}
+
+	doc, err := loadNfpmTemplate(cfg.ConfigPath, templateData)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, overlayPath := range cfg.ConfigOverlays {
+		overlay, err := loadNfpmTemplate(overlayPath, templateData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config overlay: %w", err)
+		}
+		doc = mergeNfpmConfig(doc, overlay, cfg.OverlayListStrategy)
+	}
+
+	// nfpm resolves the files a config references against its own working directory.
+	if cfg.WorkingDir != "" {
+		rebaseNfpmPaths(doc, cfg.WorkingDir)
+	}
+
+	if cfg.RespectIgnoreFiles {
+		ignore, err := loadIgnoreMatcher(cfg.WorkingDir, defaultIgnoreFiles...)
+		if err != nil {
+			return nil, err
+		}
+		if err := expandContentGlobs(doc, ignore); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Release != "" && data != nil {
+		doc["release"] = data.Release
+	}
+	if cfg.Epoch > 0 {
+		doc["epoch"] = strconv.Itoa(cfg.Epoch)
+	}
+	// Files and generated entries without their own mtime get the build time.
+	if cfg.Reproducible && data != nil {
+		if _, ok := doc["mtime"]; !ok {
+			doc["mtime"] = data.Date
+		}
+	}
+
+	applyRPMSigning(doc, cfg.RPMSigning)
+	applyAPKSigning(doc, cfg)
+
+	return doc, nil
+}
+
+// prepareNfpmConfig returns the path of an nfpm config that nfpm can consume directly.
+// A non-empty arch is written into the config, and data is used for templated configs
+// and script templates. Plain YAML configs are used in place; otherwise the resolved
+// config, rendered scripts, system user files, man pages, and desktop files are written to temporary
+// files which are removed by the returned cleanup function.
+func prepareNfpmConfig(cfg *Config, arch string, data *nfpmTemplateData) (string, func(), error) {
+	noop := func() {}
+	if !needsRendering(cfg) && arch == "" {
+		return cfg.ConfigPath, noop, nil
+	}
+
+	doc, err := resolveNfpmConfig(cfg, data)
+	if err != nil {
+		return "", noop, err
+	}
+	if arch != "" {
+		doc["arch"] = arch
+	}
+	if len(cfg.Scripts) == 0 && cfg.SystemUser == nil && len(cfg.Manpages) == 0 && cfg.Desktop == nil {
+		return stageNfpmConfig(doc)
+	}
+
+	// Rendered scripts and generated files live next to the staged config.
+	assetsDir, err := os.MkdirTemp("", "linuxpkg-assets-")
+	if err != nil {
+		return "", noop, fmt.Errorf("failed to create assets directory: %w", err)
+	}
+	removeAssets := func() { _ = os.RemoveAll(assetsDir) }
+	if len(cfg.Scripts) > 0 {
+		if err := renderScripts(doc, cfg.Scripts, data, assetsDir); err != nil {
+			removeAssets()
+			return "", noop, err
+		}
+	}
+	if cfg.SystemUser != nil {
+		if err := addSystemUser(doc, cfg.SystemUser, assetsDir); err != nil {
+			removeAssets()
+			return "", noop, err
+		}
+	}
+	if len(cfg.Manpages) > 0 {
+		if err := addManpages(doc, cfg.Manpages, assetsDir); err != nil {
+			removeAssets()
+			return "", noop, err
+		}
+	}
+	if cfg.Desktop != nil {
+		if err := addDesktop(doc, cfg.Desktop, data, assetsDir); err != nil {
+			removeAssets()
+			return "", noop, err
+		}
+	}
+	path, cleanup, err := stageNfpmConfig(doc)
+	if err != nil {
+		removeAssets()
+		return "", noop, err
+	}
+	return path, func() { cleanup(); removeAssets() }, nil
+}
+
+// finalizeNfpmConfig returns the nfpm config used to build format from a prepared config:
+// the version normalized for the format, the release tagged with cfg's distribution,
+// dependency overrides and compression for the format and distribution applied,
+// config_files and ownership applied to the contents, remote contents downloaded with
+// fetcher, and, for deb and rpm, a changelog generated from the release in data.
+// Environment references are resolved with getenv. A nil fetcher leaves remote contents
+// as they are. The prepared config is returned as-is when nothing changes; otherwise a
+// rewritten copy is staged and removed by the returned cleanup function.
+func finalizeNfpmConfig(ctx context.Context, path, format string, cfg *Config, data *nfpmTemplateData, getenv func(string) string, fetcher *remoteFetcher) (string, func(), error) {
+	noop := func() {}
+	doc, err := loadNfpmConfig(path)
+	if err != nil {
+		return "", noop, err
+	}
+
+	changed := false
+	if cfg.NormalizeVersion && normalizeVersion(doc, format, getenv) {
+		changed = true
+	}
+	if cfg.Distro != nil && tagRelease(doc, format, cfg.Distro.Tag, getenv) {
+		changed = true
+	}
+	if applyOverrides(doc, format, cfg.distroName(), cfg.Overrides) {
+		changed = true
+	}
+	if applyCompression(doc, format, cfg.Compression) {
+		changed = true
+	}
+	if applyConfigFiles(doc, format, cfg.ConfigFiles, getenv) {
+		changed = true
+	}
+	if applyOwnership(doc, format, cfg.Ownership, getenv) {
+		changed = true
+	}
+	fetched := false
+	if fetcher != nil {
+		if fetched, err = fetcher.resolveRemoteContents(ctx, doc, getenv); err != nil {
+			return "", noop, err
+		}
+	}
+	if !needsChangelog(doc, format, cfg) {
+		if !changed && !fetched {
+			return path, noop, nil
+		}
+		return stageNfpmConfig(doc)
+	}
+
+	changelogDir, err := os.MkdirTemp("", "linuxpkg-changelog-")
+	if err != nil {
+		return "", noop, fmt.Errorf("failed to create changelog directory: %w", err)
+	}
+	removeChangelog := func() { _ = os.RemoveAll(changelogDir) }
+	distribution := defaultChangelogDistribution
+	if cfg.Distro != nil {
+		distribution = cfg.Distro.Tag
+	}
+	changelog := cfg.Changelog
+	if changelog == nil {
+		// debian_docs generates deb changelogs with the defaults.
+		changelog = &ChangelogConfig{Urgency: "medium"}
+	}
+	if err := writeChangelog(doc, format, changelog, distribution, data, getenv, changelogDir); err != nil {
+		removeChangelog()
+		return "", noop, err
+	}
+	staged, cleanup, err := stageNfpmConfig(doc)
+	if err != nil {
+		removeChangelog()
+		return "", noop, err
+	}
+	return staged, func() { cleanup(); removeChangelog() }, nil
+}
+
+// needsChangelog reports whether a changelog is generated for format: deb and rpm
+// packages get one when the changelog option is set, and deb packages when debian_docs
+// is, and the nfpm config has none.
+func needsChangelog(doc map[string]any, format string, cfg *Config) bool {
+	switch {
+	case cfg.Changelog != nil && (format == "deb" || format == "rpm"):
+	case cfg.DebianDocs != nil && format == "deb":
+	default:
+		return false
+	}
+	changelog, _ := doc["changelog"].(string)
+	return changelog == ""
+}
+
+// stageNfpmConfig renders doc into a temporary YAML file and returns its path and a
+// cleanup function that removes it and forgets its parsed config.
+func stageNfpmConfig(doc map[string]any) (string, func(), error) {
+	noop := func() {}
+	rendered, err := renderNfpmConfig(doc)
+	if err != nil {
+		return "", noop, err
+	}
+
+	stagingDir, err := os.MkdirTemp("", "linuxpkg-")
+	if err != nil {
+		return "", noop, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	renderedPath := filepath.Join(stagingDir, "nfpm.yaml")
+	cleanup := func() {
+		forgetNfpmConfig(renderedPath)
+		_ = os.RemoveAll(stagingDir)
+	}
+
+	if err := os.WriteFile(renderedPath, rendered, 0600); err != nil {
+		cleanup()
+		return "", noop, fmt.Errorf("failed to write rendered nfpm config: %w", err)
+	}
+
+	return renderedPath, cleanup, nil
+}
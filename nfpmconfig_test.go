@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestLoadNfpmConfig tests loading nfpm configs in each supported format.
+func TestLoadNfpmConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		filename string
+		content  string
+	}{
+		{
+			name:     "yaml",
+			filename: "nfpm.yaml",
+			content:  "name: myapp\nversion: 1.0.0\narch: amd64\n",
+		},
+		{
+			name:     "yml",
+			filename: "nfpm.yml",
+			content:  "name: myapp\nversion: 1.0.0\narch: amd64\n",
+		},
+		{
+			name:     "json",
+			filename: "nfpm.json",
+			content:  `{"name": "myapp", "version": "1.0.0", "arch": "amd64"}`,
+		},
+		{
+			name:     "toml",
+			filename: "nfpm.toml",
+			content:  "name = \"myapp\"\nversion = \"1.0.0\"\narch = \"amd64\"\n",
+		},
+		{
+			name:     "unknown extension is read as yaml",
+			filename: "nfpm.conf",
+			content:  "name: myapp\nversion: 1.0.0\narch: amd64\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), tc.filename)
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			doc, err := loadNfpmConfig(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for key, expected := range map[string]string{"name": "myapp", "version": "1.0.0", "arch": "amd64"} {
+				if doc[key] != expected {
+					t.Errorf("%s: expected %q, got %v", key, expected, doc[key])
+				}
+			}
+		})
+	}
+
+	t.Run("invalid json", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "nfpm.json")
+		if err := os.WriteFile(path, []byte(`{"name": `), 0644); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+
+		if _, err := loadNfpmConfig(path); err == nil || !strings.Contains(err.Error(), "failed to parse nfpm config") {
+			t.Errorf("expected parse error, got %v", err)
+		}
+	})
+}
+
+// TestPrepareNfpmConfig tests that only non-YAML configs are converted.
+func TestPrepareNfpmConfig(t *testing.T) {
+	t.Parallel()
+
+	t.Run("yaml is used in place", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "nfpm.yaml")
+		if err := os.WriteFile(path, []byte("name: myapp\n"), 0644); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+
+		got, cleanup, err := prepareNfpmConfig(&Config{ConfigPath: path, OverlayListStrategy: "replace"}, "", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer cleanup()
+
+		if got != path {
+			t.Errorf("expected %q, got %q", path, got)
+		}
+	})
+
+	t.Run("toml is converted to yaml", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "nfpm.toml")
+		content := "name = \"myapp\"\n\n[[contents]]\nsrc = \"bin/myapp\"\ndst = \"/usr/bin/myapp\"\n"
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+
+		got, cleanup, err := prepareNfpmConfig(&Config{ConfigPath: path, OverlayListStrategy: "replace"}, "", nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if filepath.Ext(got) != ".yaml" {
+			t.Errorf("expected a .yaml file, got %q", got)
+		}
+
+		doc, err := loadNfpmConfig(got)
+		if err != nil {
+			t.Fatalf("failed to read converted config: %v", err)
+		}
+		if doc["name"] != "myapp" {
+			t.Errorf("expected name myapp, got %v", doc["name"])
+		}
+		contents, ok := doc["contents"].([]any)
+		if !ok || len(contents) != 1 {
+			t.Fatalf("expected 1 contents entry, got %v", doc["contents"])
+		}
+
+		cleanup()
+		if _, err := os.Stat(got); !os.IsNotExist(err) {
+			t.Error("expected converted config to be removed by cleanup")
+		}
+	})
+}
+
+// TestMergeNfpmConfig tests deep-merging overlays with each list strategy.
+func TestMergeNfpmConfig(t *testing.T) {
+	t.Parallel()
+
+	base := map[string]any{
+		"name":    "myapp",
+		"version": "1.0.0",
+		"depends": []any{"libc6", "openssl"},
+		"overrides": map[string]any{
+			"deb": map[string]any{"depends": []any{"libssl3"}},
+			"rpm": map[string]any{"depends": []any{"openssl-libs"}},
+		},
+	}
+	overlay := map[string]any{
+		"version": "1.0.0-staging",
+		"depends": []any{"openssl", "curl"},
+		"overrides": map[string]any{
+			"deb": map[string]any{"recommends": []any{"ca-certificates"}},
+		},
+	}
+
+	tests := []struct {
+		strategy       string
+		expectedDepend []any
+	}{
+		{strategy: "replace", expectedDepend: []any{"openssl", "curl"}},
+		{strategy: "append", expectedDepend: []any{"libc6", "openssl", "openssl", "curl"}},
+		{strategy: "unique", expectedDepend: []any{"libc6", "openssl", "curl"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.strategy, func(t *testing.T) {
+			t.Parallel()
+
+			merged := mergeNfpmConfig(base, overlay, tc.strategy)
+
+			if merged["name"] != "myapp" {
+				t.Errorf("expected name to be preserved, got %v", merged["name"])
+			}
+			if merged["version"] != "1.0.0-staging" {
+				t.Errorf("expected overlay version, got %v", merged["version"])
+			}
+			if !reflect.DeepEqual(merged["depends"], tc.expectedDepend) {
+				t.Errorf("depends: expected %v, got %v", tc.expectedDepend, merged["depends"])
+			}
+
+			overrides := merged["overrides"].(map[string]any)
+			deb := overrides["deb"].(map[string]any)
+			if deb["depends"] == nil || deb["recommends"] == nil {
+				t.Errorf("expected nested maps to be merged, got %v", deb)
+			}
+			if overrides["rpm"] == nil {
+				t.Error("expected untouched nested keys to be preserved")
+			}
+		})
+	}
+
+	t.Run("inputs are not modified", func(t *testing.T) {
+		t.Parallel()
+
+		_ = mergeNfpmConfig(base, overlay, "append")
+		if base["version"] != "1.0.0" {
+			t.Errorf("expected base to be unchanged, got version %v", base["version"])
+		}
+		if len(base["depends"].([]any)) != 2 {
+			t.Errorf("expected base depends to be unchanged, got %v", base["depends"])
+		}
+	})
+}
+
+// TestPrepareNfpmConfigWithOverlays tests that overlays are applied in order.
+func TestPrepareNfpmConfigWithOverlays(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"nfpm.yaml":       "name: myapp\nversion: 1.0.0\nmaintainer: team@example.com\n",
+		"staging.yaml":    "version: 1.0.0-staging\nrelease: \"2\"\n",
+		"production.json": `{"version": "1.0.0"}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	cfg := &Config{
+		ConfigPath:          filepath.Join(dir, "nfpm.yaml"),
+		ConfigOverlays:      []string{filepath.Join(dir, "staging.yaml"), filepath.Join(dir, "production.json")},
+		OverlayListStrategy: "replace",
+	}
+
+	got, cleanup, err := prepareNfpmConfig(cfg, "", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cleanup()
+
+	if got == cfg.ConfigPath {
+		t.Fatal("expected a rendered config when overlays are set")
+	}
+
+	doc, err := loadNfpmConfig(got)
+	if err != nil {
+		t.Fatalf("failed to read rendered config: %v", err)
+	}
+
+	expected := map[string]any{
+		"name":       "myapp",
+		"version":    "1.0.0",
+		"release":    "2",
+		"maintainer": "team@example.com",
+	}
+	for key, value := range expected {
+		if doc[key] != value {
+			t.Errorf("%s: expected %v, got %v", key, value, doc[key])
+		}
+	}
+}
+
+// TestValidateConfigOverlays tests validation of overlay settings.
+func TestValidateConfigOverlays(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		config      map[string]any
+		expectValid bool
+		errField    string
+	}{
+		{
+			name:        "valid overlays",
+			config:      map[string]any{"config_overlays": []any{"nfpm.staging.yaml"}, "overlay_list_strategy": "append"},
+			expectValid: true,
+		},
+		{
+			name:        "overlay path traversal",
+			config:      map[string]any{"config_overlays": []any{"../secrets.yaml"}},
+			expectValid: false,
+			errField:    "config_overlays",
+		},
+		{
+			name:        "unknown list strategy",
+			config:      map[string]any{"overlay_list_strategy": "zip"},
+			expectValid: false,
+			errField:    "overlay_list_strategy",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &LinuxPkgPlugin{}
+			resp, err := p.Validate(context.Background(), tc.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Valid != tc.expectValid {
+				t.Errorf("expected valid=%v, got %v: %v", tc.expectValid, resp.Valid, resp.Errors)
+			}
+			if tc.errField != "" && (len(resp.Errors) == 0 || resp.Errors[0].Field != tc.errField) {
+				t.Errorf("expected error on field %q, got %v", tc.errField, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestExecuteWithJSONConfig tests that a JSON config is converted before invoking nfpm.
+func TestExecuteWithJSONConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.json"), []byte(`{"name": "myapp", "version": "1.0.0"}`), 0644); err != nil {
+		t.Fatalf("failed to create test config: %v", err)
+	}
+
+	var renderedConfig string
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			for i, arg := range args {
+				if arg == "--config" && i+1 < len(args) {
+					data, err := os.ReadFile(args[i+1])
+					if err != nil {
+						return nil, err
+					}
+					renderedConfig = string(data)
+				}
+			}
+			return []byte("created package: dist/myapp-1.0.0.deb"), nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"config_path": "nfpm.json",
+			"formats":     []string{"deb"},
+			"packager":    "nfpm-cli",
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	if !strings.Contains(renderedConfig, "name: myapp") {
+		t.Errorf("expected nfpm to receive a YAML config, got %q", renderedConfig)
+	}
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+const (
+	// defaultNfpmDownloadURL is where nfpm releases are published, one directory per tag.
+	defaultNfpmDownloadURL = "https://github.com/goreleaser/nfpm/releases/download"
+	// nfpmChecksumsFile lists the SHA-256 digest of every asset of an nfpm release.
+	nfpmChecksumsFile = "checksums.txt"
+	// maxChecksumsSize bounds the checksums and signature files read into memory.
+	maxChecksumsSize = 1 << 20
+	// nfpmSignatureIdentity matches the certificate identity of nfpm's release workflow.
+	nfpmSignatureIdentity = `^https://github\.com/goreleaser/nfpm/`
+	// githubActionsIssuer is the OIDC issuer of certificates for GitHub Actions workflows.
+	githubActionsIssuer = "https://token.actions.githubusercontent.com"
+)
+
+// nfpmVersionPattern matches a pinned nfpm release, with or without the tag's "v".
+var nfpmVersionPattern = regexp.MustCompile(`^v?[0-9]+\.[0-9]+\.[0-9]+$`)
+
+// nfpmReleaseOS and nfpmReleaseArch name the platforms in nfpm's release archives.
+var (
+	nfpmReleaseOS   = map[string]string{"linux": "Linux", "darwin": "Darwin"}
+	nfpmReleaseArch = map[string]string{"amd64": "x86_64", "arm64": "arm64", "386": "i386"}
+)
+
+// validateNfpmVersion checks nfpm_version, which only the nfpm-cli packager runs.
+func validateNfpmVersion(version, packager string) error {
+	if version == "" {
+		return nil
+	}
+	if !nfpmVersionPattern.MatchString(version) {
+		return fmt.Errorf("must be a release version such as v2.41.1, got %q", version)
+	}
+	if packager != "nfpm-cli" {
+		return fmt.Errorf("requires packager: nfpm-cli")
+	}
+	return nil
+}
+
+// validateNfpmDownloadURL checks that nfpm releases are downloaded over https.
+func validateNfpmDownloadURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("must be an https URL: %s", rawURL)
+	}
+	return nil
+}
+
+// validateAllowUnverifiedNfpm checks that allow_unverified_nfpm is only set for a
+// downloaded nfpm, the only binary it applies to.
+func validateAllowUnverifiedNfpm(allow bool, version string) error {
+	if allow && version == "" {
+		return fmt.Errorf("requires nfpm_version")
+	}
+	return nil
+}
+
+// defaultToolCacheDir returns the directory downloaded tools are kept in between runs.
+func defaultToolCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "relicta-linuxpkg")
+}
+
+// nfpmAssetName returns the name of the nfpm release archive for a platform.
+func nfpmAssetName(version, goos, goarch string) (string, error) {
+	osName, ok := nfpmReleaseOS[goos]
+	if !ok {
+		return "", fmt.Errorf("no nfpm release for %s/%s", goos, goarch)
+	}
+	arch := "all" // macOS releases are universal binaries.
+	if goos != "darwin" {
+		if arch, ok = nfpmReleaseArch[goarch]; !ok {
+			return "", fmt.Errorf("no nfpm release for %s/%s", goos, goarch)
+		}
+	}
+	return fmt.Sprintf("nfpm_%s_%s_%s.tar.gz", strings.TrimPrefix(version, "v"), osName, arch), nil
+}
+
+// ensureNfpm returns the nfpm binary to run for cfg.NfpmVersion: "nfpm" when the one on
+// PATH is that version, or otherwise the release for the host platform, downloaded into
+// the tool cache on first use. The download is verified against the release's checksums,
+// whose cosign signature must verify unless cfg.AllowUnverifiedNfpm overrides it with a
+// warning in the log, and the cached binary against the digest recorded next to it
+// before every use. With a tool lock, the archive must also match its locked digest,
+// and a cached binary must have been extracted from that archive. Any mismatch fails
+// the run.
+func (p *LinuxPkgPlugin) ensureNfpm(ctx context.Context, executor CommandExecutor, cfg *Config) (string, error) {
+	version := "v" + strings.TrimPrefix(cfg.NfpmVersion, "v")
+	if _, err := p.getLookPath()("nfpm"); err == nil {
+		if output, err := runCommand(ctx, executor, "nfpm", "--version"); err == nil &&
+			strings.TrimPrefix(parseNfpmVersion(output), "v") == version[1:] {
+			return "nfpm", nil
+		}
+	}
+
+	cacheDir := cfg.ToolCacheDir
+	if cacheDir == "" {
+		cacheDir = defaultToolCacheDir()
+	}
+	platform := runtime.GOOS + "_" + runtime.GOARCH
+	binary := filepath.Join(cacheDir, "nfpm", version, platform, "nfpm")
+	locked := ""
+	if cfg.ToolLock != nil {
+		var err error
+		if locked, err = cfg.ToolLock.nfpmDigest(version, platform); err != nil {
+			return "", err
+		}
+	}
+	if _, err := os.Stat(binary); err == nil {
+		if err := verifyCachedTool(binary); err != nil {
+			return "", err
+		}
+		if locked != "" {
+			if err := verifyCachedSource(binary, locked); err != nil {
+				return "", err
+			}
+		}
+		return binary, nil
+	}
+
+	asset, err := nfpmAssetName(version, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return "", err
+	}
+	releaseURL := strings.TrimSuffix(cfg.NfpmDownloadURL, "/") + "/" + version
+	checksums, err := p.download(ctx, releaseURL+"/"+nfpmChecksumsFile)
+	if err != nil {
+		return "", err
+	}
+	if err := p.verifyNfpmChecksums(ctx, executor, releaseURL, checksums); err != nil {
+		if !errors.Is(err, errUnverifiable) {
+			return "", err
+		}
+		if !cfg.AllowUnverifiedNfpm {
+			return "", fmt.Errorf("refusing to run nfpm %s: %w (set allow_unverified_nfpm to trust its checksums alone)", version, err)
+		}
+		fmt.Fprintf(p.getLogOutput(), "warning: %v; trusting its checksums alone (allow_unverified_nfpm)\n", err)
+	}
+	digest, err := checksumFor(checksums, asset)
+	if err != nil {
+		return "", fmt.Errorf("%s/%s: %w", releaseURL, nfpmChecksumsFile, err)
+	}
+	if locked != "" && digest != locked {
+		return "", fmt.Errorf("%s/%s lists sha256 %s for %s, but tool_lock pins %s", releaseURL, nfpmChecksumsFile, digest, asset, locked)
+	}
+
+	fetcher := newRemoteFetcher(p.getHTTPClient())
+	defer fetcher.cleanup()
+	archive, err := fetcher.fetch(ctx, releaseURL+"/"+asset, digest)
+	if err != nil {
+		return "", err
+	}
+	if err := extractNfpm(archive, binary); err != nil {
+		return "", fmt.Errorf("failed to extract nfpm from %s: %w", asset, err)
+	}
+	if err := os.WriteFile(binary+".source.sha256", []byte(digest+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to record the digest of %s: %w", asset, err)
+	}
+	return binary, nil
+}
+
+// errNotPublished is returned by download for files the server does not have.
+var errNotPublished = errors.New("not published")
+
+// download fetches a small file, such as a checksums file or signature, into memory.
+func (p *LinuxPkgPlugin) download(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	resp, err := p.getHTTPClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("failed to download %s: %w", rawURL, errNotPublished)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", rawURL, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxChecksumsSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	return data, nil
+}
+
+// checksumFor returns the SHA-256 digest a checksums file in sha256sum format lists for
+// name.
+func checksumFor(checksums []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			if !sha256Pattern.MatchString(fields[0]) {
+				return "", fmt.Errorf("invalid sha256 for %s: %s", name, fields[0])
+			}
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("no checksum listed for %s", name)
+}
+
+// errUnverifiable is returned by verifyNfpmChecksums when a release's checksums cannot
+// be verified: the release publishes no signature, or cosign is not installed.
+var errUnverifiable = errors.New("cannot be verified")
+
+// verifyNfpmChecksums verifies the cosign signature nfpm releases carry for their
+// checksums file, keyless with a certificate issued to the nfpm release workflow. A
+// release without a signature or certificate, or a host without cosign on PATH, fails
+// with errUnverifiable rather than trusting the checksums alone.
+func (p *LinuxPkgPlugin) verifyNfpmChecksums(ctx context.Context, executor CommandExecutor, releaseURL string, checksums []byte) error {
+	signature, err := p.download(ctx, releaseURL+"/"+nfpmChecksumsFile+".sig")
+	if errors.Is(err, errNotPublished) {
+		return fmt.Errorf("%s/%s %w: the release publishes no %s.sig", releaseURL, nfpmChecksumsFile, errUnverifiable, nfpmChecksumsFile)
+	}
+	if err != nil {
+		return err
+	}
+	certificate, err := p.download(ctx, releaseURL+"/"+nfpmChecksumsFile+".pem")
+	if errors.Is(err, errNotPublished) {
+		return fmt.Errorf("%s/%s %w: the release publishes no %s.pem", releaseURL, nfpmChecksumsFile, errUnverifiable, nfpmChecksumsFile)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := p.getLookPath()("cosign"); err != nil {
+		return fmt.Errorf("%s/%s %w: cosign is not installed", releaseURL, nfpmChecksumsFile, errUnverifiable)
+	}
+
+	dir, err := os.MkdirTemp("", "linuxpkg-nfpm-")
+	if err != nil {
+		return fmt.Errorf("failed to create verification directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string][]byte{nfpmChecksumsFile: checksums, "checksums.sig": signature, "checksums.pem": certificate}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	output, err := runCommand(ctx, executor, "cosign", "verify-blob",
+		"--certificate", filepath.Join(dir, "checksums.pem"),
+		"--signature", filepath.Join(dir, "checksums.sig"),
+		"--certificate-identity-regexp", nfpmSignatureIdentity,
+		"--certificate-oidc-issuer", githubActionsIssuer,
+		filepath.Join(dir, nfpmChecksumsFile))
+	if err != nil {
+		return fmt.Errorf("signature verification of %s/%s failed: %w: %s", releaseURL, nfpmChecksumsFile, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// verifyCachedTool checks a cached tool against the digest recorded when it was
+// downloaded, so a binary changed in the cache is never run.
+func verifyCachedTool(path string) error {
+	recorded, err := os.ReadFile(path + ".sha256")
+	if err != nil {
+		return fmt.Errorf("cached %s has no recorded digest; remove it to download it again", path)
+	}
+	expected := strings.TrimSpace(string(recorded))
+	actual, err := fileDigest(path, "sha256")
+	if err != nil {
+		return err
+	}
+	if actual != expected {
+		return fmt.Errorf("cached %s does not match its recorded digest: expected sha256 %s, got %s", path, expected, actual)
+	}
+	return nil
+}
+
+// verifyCachedSource checks that a cached tool was extracted from the archive with the
+// locked digest, as recorded next to it when it was downloaded.
+func verifyCachedSource(path, locked string) error {
+	recorded, err := os.ReadFile(path + ".source.sha256")
+	if err != nil {
+		return fmt.Errorf("cached %s has no recorded archive digest; remove it to download it again", path)
+	}
+	if source := strings.TrimSpace(string(recorded)); source != locked {
+		return fmt.Errorf("cached %s was extracted from an archive with sha256 %s, but tool_lock pins %s", path, source, locked)
+	}
+	return nil
+}
+
+// extractNfpm writes the nfpm binary from a release archive to dest, and its digest to
+// dest with a .sha256 suffix. The binary is written next to dest first, so an
+// interrupted run never leaves a partial binary in the cache.
+func extractNfpm(archive, dest string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return fmt.Errorf("archive has no nfpm binary")
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg || filepath.Base(header.Name) != "nfpm" {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		tmp, err := os.CreateTemp(filepath.Dir(dest), ".nfpm-*")
+		if err != nil {
+			return err
+		}
+		_, copyErr := io.Copy(tmp, tr)
+		if err := tmp.Close(); copyErr == nil {
+			copyErr = err
+		}
+		if copyErr == nil {
+			copyErr = os.Chmod(tmp.Name(), 0755)
+		}
+		if copyErr == nil {
+			copyErr = recordDigest(tmp.Name(), dest+".sha256")
+		}
+		if copyErr == nil {
+			copyErr = os.Rename(tmp.Name(), dest)
+		}
+		if copyErr != nil {
+			_ = os.Remove(tmp.Name())
+		}
+		return copyErr
+	}
+}
+
+// recordDigest writes the SHA-256 digest of path to digestPath.
+func recordDigest(path, digestPath string) error {
+	digest, err := fileDigest(path, "sha256")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(digestPath, []byte(digest+"\n"), 0644)
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// nfpmReleaseServer serves a fake nfpm release for the host platform, with the checksum
+// listed for the archive replaced by digest when it is non-empty, and a signature of the
+// checksums when signed is set.
+func nfpmReleaseServer(t *testing.T, version, digest string, signed bool) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	asset, err := nfpmAssetName(version, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		t.Skip(err)
+	}
+
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	binary := []byte("#!/bin/sh\necho fake nfpm\n")
+	for _, file := range []struct {
+		name    string
+		content []byte
+	}{{"README.md", []byte("nfpm")}, {"nfpm", binary}} {
+		if err := tw.WriteHeader(&tar.Header{Name: file.name, Mode: 0755, Size: int64(len(file.content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatalf("failed to write archive: %v", err)
+		}
+		if _, err := tw.Write(file.content); err != nil {
+			t.Fatalf("failed to write archive: %v", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+
+	if digest == "" {
+		sum := sha256.Sum256(archive.Bytes())
+		digest = hex.EncodeToString(sum[:])
+	}
+	checksums := strings.Repeat("0", 64) + "  nfpm_amd64.deb\n" + digest + "  " + asset + "\n"
+
+	var downloads atomic.Int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/" + version + "/checksums.txt":
+			_, _ = w.Write([]byte(checksums))
+		case "/" + version + "/" + asset:
+			downloads.Add(1)
+			_, _ = w.Write(archive.Bytes())
+		case "/" + version + "/checksums.txt.sig", "/" + version + "/checksums.txt.pem":
+			if !signed {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write([]byte("signature"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &downloads
+}
+
+// cosignOnly is a lookPath that finds only cosign.
+func cosignOnly(file string) (string, error) {
+	if file == "cosign" {
+		return "/usr/bin/cosign", nil
+	}
+	return "", errors.New("not found")
+}
+
+// TestExecuteDownloadsNfpm tests that a pinned nfpm release is downloaded, verified,
+// cached, and used to build when nfpm is not on PATH.
+func TestExecuteDownloadsNfpm(t *testing.T) {
+	t.Parallel()
+
+	server, downloads := nfpmReleaseServer(t, "v2.41.1", "", true)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: test\nversion: 1.0.0"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	cacheDir := t.TempDir()
+
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("created package: " + args[len(args)-1] + "test.deb"), nil
+		},
+	}
+	p := &LinuxPkgPlugin{
+		cmdExecutor: mock,
+		lookPath:    cosignOnly,
+		httpClient:  server.Client(),
+	}
+	for run := 0; run < 2; run++ {
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook: plugin.HookPostPublish,
+			Config: map[string]any{
+				"working_dir":       dir,
+				"formats":           []string{"deb"},
+				"packager":          "nfpm-cli",
+				"nfpm_version":      "2.41.1",
+				"nfpm_download_url": server.URL,
+				"tool_cache_dir":    cacheDir,
+			},
+			Context: plugin.ReleaseContext{Version: "1.0.0"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !resp.Success {
+			t.Fatalf("expected success, got failure: %s", resp.Error)
+		}
+	}
+
+	binary := filepath.Join(cacheDir, "nfpm", "v2.41.1", runtime.GOOS+"_"+runtime.GOARCH, "nfpm")
+	if info, err := os.Stat(binary); err != nil || info.Mode().Perm() != 0755 {
+		t.Fatalf("expected an executable nfpm in the cache, got %v (%v)", info, err)
+	}
+	if n := downloads.Load(); n != 1 {
+		t.Errorf("expected one download, got %d", n)
+	}
+	if len(mock.Calls) != 3 || mock.Calls[0].Name != "cosign" || mock.Calls[1].Name != binary || mock.Calls[2].Name != binary {
+		t.Errorf("expected a verified download and both builds to run it, got %v", mock.Calls)
+	}
+}
+
+// TestEnsureNfpm tests when a pinned nfpm release is downloaded.
+func TestEnsureNfpm(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		pathVersion string
+		digest      string
+		expectError string
+		expectPath  bool
+	}{
+		{"on path", "GitVersion:    v2.41.1\n", "", "", true},
+		{"other version on path", "GitVersion:    v2.40.0\n", "", "", false},
+		{"checksum mismatch", "", strings.Repeat("a", 64), "checksum mismatch", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server, downloads := nfpmReleaseServer(t, "v2.41.1", tt.digest, true)
+			p := &LinuxPkgPlugin{
+				lookPath: func(file string) (string, error) {
+					if tt.pathVersion == "" && file != "cosign" {
+						return "", errors.New("not found")
+					}
+					return "/usr/bin/" + file, nil
+				},
+				httpClient: server.Client(),
+			}
+			mock := &MockCommandExecutor{
+				RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+					return []byte(tt.pathVersion), nil
+				},
+			}
+			cacheDir := t.TempDir()
+			binary, err := p.ensureNfpm(context.Background(), mock, &Config{
+				NfpmVersion:     "v2.41.1",
+				NfpmDownloadURL: server.URL,
+				ToolCacheDir:    cacheDir,
+			})
+
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Fatalf("expected error containing %q, got %v", tt.expectError, err)
+				}
+				if entries, _ := os.ReadDir(cacheDir); len(entries) != 0 {
+					t.Errorf("expected nothing cached, got %v", entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectPath {
+				if binary != "nfpm" || downloads.Load() != 0 {
+					t.Errorf("expected nfpm from PATH without a download, got %s after %d downloads", binary, downloads.Load())
+				}
+				return
+			}
+			if !strings.HasPrefix(binary, cacheDir) || downloads.Load() != 1 {
+				t.Errorf("expected a downloaded nfpm, got %s after %d downloads", binary, downloads.Load())
+			}
+		})
+	}
+}
+
+// TestEnsureNfpmSignature tests that the signature of a release's checksums must verify
+// with cosign, and that a release that cannot be verified is refused unless
+// allow_unverified_nfpm is set.
+func TestEnsureNfpmSignature(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		signed          bool
+		cosign          bool
+		cosignFails     bool
+		allowUnverified bool
+		expectError     string
+		expectVerify    bool
+		expectWarn      bool
+	}{
+		{"verified", true, true, false, false, "", true, false},
+		{"bad signature", true, true, true, false, "none of the expected identities matched", true, false},
+		{"bad signature allowed unverified", true, true, true, true, "none of the expected identities matched", true, false},
+		{"unsigned", false, true, false, false, "the release publishes no checksums.txt.sig", false, false},
+		{"no cosign", true, false, false, false, "cosign is not installed", false, false},
+		{"unsigned allowed unverified", false, true, false, true, "", false, true},
+		{"no cosign allowed unverified", true, false, false, true, "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server, downloads := nfpmReleaseServer(t, "v2.41.1", "", tt.signed)
+			var log bytes.Buffer
+			p := &LinuxPkgPlugin{
+				lookPath: func(file string) (string, error) {
+					if file == "cosign" && tt.cosign {
+						return "/usr/bin/cosign", nil
+					}
+					return "", errors.New("not found")
+				},
+				httpClient: server.Client(),
+				logOutput:  &log,
+			}
+			mock := &MockCommandExecutor{
+				RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+					if tt.cosignFails {
+						return []byte("Error: none of the expected identities matched"), errors.New("exit status 1")
+					}
+					return []byte("Verified OK"), nil
+				},
+			}
+			cacheDir := t.TempDir()
+			_, err := p.ensureNfpm(context.Background(), mock, &Config{
+				NfpmVersion:         "v2.41.1",
+				NfpmDownloadURL:     server.URL,
+				ToolCacheDir:        cacheDir,
+				AllowUnverifiedNfpm: tt.allowUnverified,
+			})
+
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Fatalf("expected error containing %q, got %v", tt.expectError, err)
+				}
+				if downloads.Load() != 0 {
+					t.Errorf("expected the archive not to be downloaded, got %d downloads", downloads.Load())
+				}
+				if entries, _ := os.ReadDir(cacheDir); len(entries) != 0 {
+					t.Errorf("expected nothing cached, got %v", entries)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			verified := len(mock.Calls) == 1 && mock.Calls[0].Name == "cosign" && mock.Calls[0].Args[0] == "verify-blob"
+			if verified != tt.expectVerify {
+				t.Errorf("expected verification %v, got calls %v", tt.expectVerify, mock.Calls)
+			}
+			if warned := strings.Contains(log.String(), "trusting its checksums alone"); warned != tt.expectWarn {
+				t.Errorf("expected warning %v, got %q", tt.expectWarn, log.String())
+			}
+		})
+	}
+}
+
+// TestEnsureNfpmTamperedCache tests that a cached nfpm that no longer matches its
+// recorded digest is not run.
+func TestEnsureNfpmTamperedCache(t *testing.T) {
+	t.Parallel()
+
+	server, _ := nfpmReleaseServer(t, "v2.41.1", "", true)
+	p := &LinuxPkgPlugin{
+		lookPath:   cosignOnly,
+		httpClient: server.Client(),
+	}
+	cfg := &Config{NfpmVersion: "v2.41.1", NfpmDownloadURL: server.URL, ToolCacheDir: t.TempDir()}
+	binary, err := p.ensureNfpm(context.Background(), &MockCommandExecutor{}, cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\necho tampered\n"), 0755); err != nil {
+		t.Fatalf("failed to modify binary: %v", err)
+	}
+
+	_, err = p.ensureNfpm(context.Background(), &MockCommandExecutor{}, cfg)
+	if err == nil || !strings.Contains(err.Error(), "does not match its recorded digest: expected sha256 ") || !strings.Contains(err.Error(), ", got ") {
+		t.Errorf("expected a digest mismatch, got %v", err)
+	}
+}
+
+// TestValidateNfpmVersion tests validation of nfpm_version and nfpm_download_url.
+func TestValidateNfpmVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		config map[string]any
+		field  string
+	}{
+		{"pinned", map[string]any{"packager": "nfpm-cli", "nfpm_version": "v2.41.1"}, ""},
+		{"without v", map[string]any{"packager": "nfpm-cli", "nfpm_version": "2.41.1"}, ""},
+		{"not a release", map[string]any{"packager": "nfpm-cli", "nfpm_version": "latest"}, "nfpm_version"},
+		{"embedded packager", map[string]any{"nfpm_version": "v2.41.1"}, "nfpm_version"},
+		{"min version", map[string]any{"packager": "nfpm-cli", "nfpm_version": "v2.41.1", "min_nfpm_version": "2.38"}, ""},
+		{"bad min version", map[string]any{"packager": "nfpm-cli", "nfpm_version": "v2.41.1", "min_nfpm_version": "recent"}, "min_nfpm_version"},
+		{"http mirror", map[string]any{"packager": "nfpm-cli", "nfpm_version": "v2.41.1", "nfpm_download_url": "http://mirror.example.com/nfpm"}, "nfpm_download_url"},
+		{"allow unverified", map[string]any{"packager": "nfpm-cli", "nfpm_version": "v2.41.1", "allow_unverified_nfpm": true}, ""},
+		{"allow unverified without download", map[string]any{"packager": "nfpm-cli", "allow_unverified_nfpm": true}, "allow_unverified_nfpm"},
+	}
+
+	p := &LinuxPkgPlugin{lookPath: func(string) (string, error) { return "", errors.New("not found") }}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := p.Validate(context.Background(), tt.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.field == "" {
+				if !resp.Valid {
+					t.Errorf("expected valid config, got errors=%v", resp.Errors)
+				}
+				return
+			}
+			if len(resp.Errors) != 1 || resp.Errors[0].Field != tt.field {
+				t.Errorf("expected an error for %s, got %v", tt.field, resp.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// defaultORASArtifactType is the artifact type of pushed package manifests.
+const defaultORASArtifactType = "application/vnd.relicta.linuxpkg.v1"
+
+// orasMediaTypes maps artifact formats to layer media types.
+var orasMediaTypes = map[string]string{
+	"deb":       "application/vnd.debian.binary-package",
+	"rpm":       "application/x-rpm",
+	"apk":       "application/vnd.alpine.package",
+	"archlinux": "application/x-zstd",
+	"ipk":       "application/vnd.openwrt.package",
+}
+
+// orasSidecarMediaTypes maps artifact sidecar files (signatures, provenance) to layer
+// media types.
+var orasSidecarMediaTypes = map[string]string{
+	"signature":   "application/vnd.dev.cosign.signature",
+	"certificate": "application/pem-certificate-chain",
+	"bundle":      "application/vnd.dev.sigstore.bundle+json",
+	"provenance":  "application/vnd.in-toto+json",
+}
+
+// orasRepositoryPattern validates OCI repository references without tag or digest.
+var orasRepositoryPattern = regexp.MustCompile(`^[a-z0-9.-]+(:[0-9]+)?(/[a-z0-9._-]+)+$`)
+
+// orasTagPattern validates OCI tags.
+var orasTagPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+
+// orasDigestPattern extracts the manifest digest from `oras push` output.
+var orasDigestPattern = regexp.MustCompile(`Digest: (sha256:[a-f0-9]{64})`)
+
+// ORASPublishConfig configures pushing packages to an OCI registry with ORAS.
+type ORASPublishConfig struct {
+	// Repository is the OCI repository, e.g. "ghcr.io/org/myapp-packages".
+	Repository string
+	// Tag is the manifest tag. Defaults to the release version.
+	Tag string
+	// ArtifactType is the manifest artifact type.
+	ArtifactType string
+	// Signatures also pushes signatures, certificates, bundles, and provenance.
+	Signatures bool
+}
+
+// parseORASPublish parses the publish.oras block. It returns nil when it is not set.
+func parseORASPublish(publish map[string]any) *ORASPublishConfig {
+	block := helpers.NewConfigParser(publish).GetMap("oras")
+	if block == nil {
+		return nil
+	}
+
+	parser := helpers.NewConfigParser(block)
+	return &ORASPublishConfig{
+		Repository:   parser.GetString("repository", "", ""),
+		Tag:          parser.GetString("tag", "", ""),
+		ArtifactType: parser.GetString("artifact_type", "", defaultORASArtifactType),
+		Signatures:   parser.GetBool("signatures", true),
+	}
+}
+
+// validate checks the ORAS publishing settings.
+func (c *ORASPublishConfig) validate() error {
+	if !orasRepositoryPattern.MatchString(c.Repository) {
+		return fmt.Errorf("repository must be an OCI repository without tag, e.g. ghcr.io/org/packages: %q", c.Repository)
+	}
+	if c.Tag != "" && !orasTagPattern.MatchString(c.Tag) {
+		return fmt.Errorf("invalid tag: %q", c.Tag)
+	}
+	if !strings.Contains(c.ArtifactType, "/") || strings.HasPrefix(c.ArtifactType, "-") {
+		return fmt.Errorf("artifact_type must be a media type: %q", c.ArtifactType)
+	}
+	return nil
+}
+
+// orasTag converts a release version into a valid OCI tag ('+' is not allowed in tags).
+func orasTag(version string) string {
+	return strings.ReplaceAll(version, "+", "_")
+}
+
+// publishORAS pushes the packages, and their signatures and provenance when enabled, as
+// the layers of one OCI artifact tagged with the release version.
+func (p *LinuxPkgPlugin) publishORAS(ctx context.Context, executor CommandExecutor, c *ORASPublishConfig, artifacts []map[string]any, version string) (map[string]any, error) {
+	tag := c.Tag
+	if tag == "" {
+		tag = orasTag(version)
+	}
+	if !orasTagPattern.MatchString(tag) {
+		return nil, fmt.Errorf("invalid tag %q; set tag", tag)
+	}
+	reference := c.Repository + ":" + tag
+
+	var files []string
+	for _, artifact := range artifacts {
+		format, _ := artifact["format"].(string)
+		mediaType, ok := orasMediaTypes[format]
+		if !ok {
+			mediaType = "application/octet-stream"
+		}
+		files = append(files, artifact["path"].(string)+":"+mediaType)
+
+		if !c.Signatures {
+			continue
+		}
+		for _, key := range sortedKeys(orasSidecarMediaTypes) {
+			if path, ok := artifact[key].(string); ok && path != "" {
+				files = append(files, path+":"+orasSidecarMediaTypes[key])
+			}
+		}
+	}
+
+	args := append([]string{"push", "--disable-path-validation", "--artifact-type", c.ArtifactType, reference}, files...)
+	output, err := runCommand(ctx, executor, "oras", args...)
+	if err != nil {
+		return nil, fmt.Errorf("oras push failed: %w\nOutput: %s", err, string(output))
+	}
+
+	result := map[string]any{
+		"reference": reference,
+		"files":     len(files),
+	}
+	if match := orasDigestPattern.FindStringSubmatch(string(output)); match != nil {
+		result["digest"] = match[1]
+		result["reference_digest"] = c.Repository + "@" + match[1]
+	}
+	return result, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestValidatePublishORAS tests validation of the publish.oras block.
+func TestValidatePublishORAS(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		oras        map[string]any
+		expectValid bool
+	}{
+		{name: "repository", oras: map[string]any{"repository": "ghcr.io/org/myapp-packages"}, expectValid: true},
+		{name: "registry with port", oras: map[string]any{"repository": "localhost:5000/packages", "tag": "latest"}, expectValid: true},
+		{name: "missing repository", oras: map[string]any{}, expectValid: false},
+		{name: "repository with tag", oras: map[string]any{"repository": "ghcr.io/org/packages:1.0.0"}, expectValid: false},
+		{name: "invalid tag", oras: map[string]any{"repository": "ghcr.io/org/packages", "tag": "1.0.0+build"}, expectValid: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &LinuxPkgPlugin{}
+			resp, err := p.Validate(context.Background(), map[string]any{"publish": map[string]any{"oras": tc.oras}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Valid != tc.expectValid {
+				t.Errorf("expected valid=%v, got %v: %v", tc.expectValid, resp.Valid, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestPublishORAS tests pushing packages and their signatures as one artifact.
+func TestPublishORAS(t *testing.T) {
+	t.Parallel()
+
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("Pushed [registry] ghcr.io/org/packages:1.0.0_build.1\nDigest: " + digest + "\n"), nil
+		},
+	}
+
+	artifacts := []map[string]any{
+		{"path": "dist/myapp.deb", "format": "deb", "signature": "dist/myapp.deb.sig", "certificate": "dist/myapp.deb.pem"},
+		{"path": "dist/myapp.rpm", "format": "rpm", "provenance": "dist/myapp.rpm.intoto.json"},
+	}
+
+	tests := []struct {
+		name       string
+		signatures bool
+		expected   string
+	}{
+		{
+			name:       "with signatures",
+			signatures: true,
+			expected: "push --disable-path-validation --artifact-type application/vnd.relicta.linuxpkg.v1 ghcr.io/org/packages:1.0.0_build.1 " +
+				"dist/myapp.deb:application/vnd.debian.binary-package " +
+				"dist/myapp.deb.pem:application/pem-certificate-chain " +
+				"dist/myapp.deb.sig:application/vnd.dev.cosign.signature " +
+				"dist/myapp.rpm:application/x-rpm " +
+				"dist/myapp.rpm.intoto.json:application/vnd.in-toto+json",
+		},
+		{
+			name: "packages only",
+			expected: "push --disable-path-validation --artifact-type application/vnd.relicta.linuxpkg.v1 ghcr.io/org/packages:1.0.0_build.1 " +
+				"dist/myapp.deb:application/vnd.debian.binary-package " +
+				"dist/myapp.rpm:application/x-rpm",
+		},
+	}
+
+	for _, tc := range tests {
+		p := &LinuxPkgPlugin{}
+		cfg := &ORASPublishConfig{Repository: "ghcr.io/org/packages", ArtifactType: defaultORASArtifactType, Signatures: tc.signatures}
+		result, err := p.publishORAS(context.Background(), mock, cfg, artifacts, "1.0.0+build.1")
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+
+		call := mock.Calls[len(mock.Calls)-1]
+		if got := strings.Join(call.Args, " "); call.Name != "oras" || got != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.expected, got)
+		}
+		if result["reference_digest"] != "ghcr.io/org/packages@"+digest {
+			t.Errorf("%s: unexpected result: %v", tc.name, result)
+		}
+	}
+}
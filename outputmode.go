@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// validateOutputModes checks the output_mode and umask options. Files must stay readable
+// and directories usable by their owner, since later steps read them back.
+func validateOutputModes(outputMode, umask string) error {
+	if outputMode != "" {
+		mode, err := parseFileMode(outputMode)
+		if err != nil {
+			return fmt.Errorf("output_mode: %w", err)
+		}
+		if mode > 0o777 {
+			return fmt.Errorf("output_mode %s must not set setuid, setgid, or sticky bits", outputMode)
+		}
+		if mode&0o600 != 0o600 {
+			return fmt.Errorf("output_mode %s must let the owner read and write files", outputMode)
+		}
+	}
+	if umask != "" {
+		mask, err := parseFileMode(umask)
+		if err != nil {
+			return fmt.Errorf("umask: %w", err)
+		}
+		if mask > 0o777 {
+			return fmt.Errorf("umask %s must only mask permission bits", umask)
+		}
+		if mask&0o700 != 0 {
+			return fmt.Errorf("umask %s must not mask the owner's permissions", umask)
+		}
+	}
+	return nil
+}
+
+// outputModes returns the modes of the files and directories the plugin writes to the
+// output directories: output_mode, else 0666 without the umask bits, for files, and 0777
+// without the umask bits for directories. A mode of 0 leaves them as they are created.
+func (cfg *Config) outputModes() (os.FileMode, os.FileMode) {
+	var fileMode, dirMode os.FileMode
+	if cfg.Umask != "" {
+		if mask, err := parseFileMode(cfg.Umask); err == nil {
+			fileMode, dirMode = 0o666&^mask, 0o777&^mask
+		}
+	}
+	if cfg.OutputMode != "" {
+		if mode, err := parseFileMode(cfg.OutputMode); err == nil {
+			fileMode = mode
+		}
+	}
+	return fileMode, dirMode
+}
+
+// applyOutputModes sets the configured modes of files and of dirs, and of the directories
+// holding the files.
+func (cfg *Config) applyOutputModes(dirs, files []string) error {
+	fileMode, dirMode := cfg.outputModes()
+	if dirMode != 0 {
+		dirs = slices.Clone(dirs)
+		for _, file := range files {
+			if dir := filepath.Dir(file); !slices.Contains(dirs, dir) {
+				dirs = append(dirs, dir)
+			}
+		}
+		for _, dir := range dirs {
+			if err := os.Chmod(dir, dirMode); err != nil {
+				return fmt.Errorf("failed to set the mode of %s: %w", dir, err)
+			}
+		}
+	}
+	if fileMode != 0 {
+		for _, file := range files {
+			if err := os.Chmod(file, fileMode); err != nil {
+				return fmt.Errorf("failed to set the mode of %s: %w", file, err)
+			}
+		}
+	}
+	return nil
+}
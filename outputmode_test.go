@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestExecuteOutputModes tests the modes of packages, checksum files, and output
+// directories.
+func TestExecuteOutputModes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		config     map[string]any
+		expectFile os.FileMode
+		expectDir  os.FileMode
+	}{
+		{"umask", map[string]any{"umask": "0077"}, 0o600, 0o700},
+		{"output mode", map[string]any{"output_mode": "0664", "umask": "0002"}, 0o664, 0o775},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			writeEmbeddedTestConfig(t, dir, "amd64")
+			config := map[string]any{
+				"working_dir":  dir,
+				"formats":      map[string]any{"deb": map[string]any{"output_dir": "dist/deb"}},
+				"persist_logs": true,
+			}
+			for key, value := range tt.config {
+				config[key] = value
+			}
+
+			p := &LinuxPkgPlugin{cmdExecutor: &MockCommandExecutor{}}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook:    plugin.HookPostPublish,
+				Config:  config,
+				Context: plugin.ReleaseContext{Version: "1.2.3"},
+			})
+			if err != nil || !resp.Success {
+				t.Fatalf("expected success, got %v, %+v", err, resp)
+			}
+
+			files := append(resp.Outputs["packages"].([]string), resp.Outputs["checksum_files"].([]string)...)
+			files = append(files, resp.Outputs["logs"].([]string)...)
+			files = append(files, filepath.Join(dir, "dist", releaseRecordName))
+			for _, file := range files {
+				info, err := os.Stat(file)
+				if err != nil {
+					t.Fatalf("failed to stat %s: %v", file, err)
+				}
+				if info.Mode().Perm() != tt.expectFile {
+					t.Errorf("expected mode %o for %s, got %o", tt.expectFile, file, info.Mode().Perm())
+				}
+			}
+			for _, sub := range []string{"dist", "dist/deb", "dist/deb/logs"} {
+				info, err := os.Stat(filepath.Join(dir, sub))
+				if err != nil {
+					t.Fatalf("failed to stat %s: %v", sub, err)
+				}
+				if info.Mode().Perm() != tt.expectDir {
+					t.Errorf("expected mode %o for %s, got %o", tt.expectDir, sub, info.Mode().Perm())
+				}
+			}
+		})
+	}
+}
+
+// TestValidateOutputModes tests the output_mode and umask options.
+func TestValidateOutputModes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		config      map[string]any
+		field       string
+		expectError string
+	}{
+		{"valid", map[string]any{"output_mode": "0644", "umask": "022"}, "", ""},
+		{"invalid mode", map[string]any{"output_mode": "rw-r--r--"}, "output_mode", "invalid mode"},
+		{"setuid", map[string]any{"output_mode": "4755"}, "output_mode", "must not set setuid"},
+		{"unreadable", map[string]any{"output_mode": "0044"}, "output_mode", "owner read and write"},
+		{"invalid umask", map[string]any{"umask": "0o22"}, "umask", "invalid mode"},
+		{"owner umask", map[string]any{"umask": "0277"}, "umask", "must not mask the owner"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			for _, field := range []string{"output_mode", "umask"} {
+				expectError := ""
+				if field == tt.field {
+					expectError = tt.expectError
+				}
+				expectFieldError(t, tt.config, field, expectError)
+			}
+		})
+	}
+}
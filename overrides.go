@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// dependencyFields are the nfpm lists an override may patch.
+var dependencyFields = []string{"depends", "recommends", "conflicts"}
+
+// DependencyPatch changes one nfpm dependency list.
+type DependencyPatch struct {
+	// Replace is the new list when Replaced is set; Add and Remove apply on top of it.
+	Replace  []string
+	Replaced bool
+	// Add lists entries to append. An entry replaces an existing one for the same package,
+	// so "openssl (>= 3.0)" updates a constraint.
+	Add []string
+	// Remove lists package names whose entries are dropped, whatever their constraints.
+	Remove []string
+}
+
+// DependencyOverride patches the dependency lists of the packages built for one format or
+// distribution, keyed by nfpm field.
+type DependencyOverride map[string]*DependencyPatch
+
+// parseOverrides reads the overrides option: an object keyed by format or distribution
+// name whose values map dependency fields to a list, which replaces the field, or to an
+// object with add and remove lists.
+func parseOverrides(raw map[string]any) map[string]DependencyOverride {
+	block, ok := raw["overrides"].(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	overrides := make(map[string]DependencyOverride, len(block))
+	for _, name := range sortedKeys(block) {
+		fields, ok := block[name].(map[string]any)
+		if !ok {
+			continue
+		}
+		override := make(DependencyOverride)
+		for _, field := range dependencyFields {
+			switch value := fields[field].(type) {
+			case []any, []string:
+				override[field] = &DependencyPatch{
+					Replace:  helpers.NewConfigParser(fields).GetStringSlice(field, nil),
+					Replaced: true,
+				}
+			case map[string]any:
+				parser := helpers.NewConfigParser(value)
+				override[field] = &DependencyPatch{
+					Add:    parser.GetStringSlice("add", nil),
+					Remove: parser.GetStringSlice("remove", nil),
+				}
+			}
+		}
+		overrides[name] = override
+	}
+	return overrides
+}
+
+// validateOverrides checks the overrides option. Keys must be supported formats or
+// configured distributions, and only dependency fields may be patched.
+func validateOverrides(raw map[string]any, distros []*DistroConfig) error {
+	value, ok := raw["overrides"]
+	if !ok || value == nil {
+		return nil
+	}
+	block, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("overrides must be an object keyed by format or distribution")
+	}
+
+	for _, name := range sortedKeys(block) {
+		if err := validateOverrideTarget(name, distros); err != nil {
+			return err
+		}
+		fields, ok := block[name].(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: override must be an object", name)
+		}
+		for _, field := range sortedKeys(fields) {
+			if !slices.Contains(dependencyFields, field) {
+				return fmt.Errorf("%s: unknown field %q (allowed: %s)", name, field, strings.Join(dependencyFields, ", "))
+			}
+			switch patch := fields[field].(type) {
+			case []any, []string:
+				if err := validateDependencyList(fields, field); err != nil {
+					return fmt.Errorf("%s: %w", name, err)
+				}
+			case map[string]any:
+				for _, key := range sortedKeys(patch) {
+					if key != "add" && key != "remove" {
+						return fmt.Errorf("%s: %s: unknown option %q (allowed: add, remove)", name, field, key)
+					}
+					if err := validateDependencyList(patch, key); err != nil {
+						return fmt.Errorf("%s: %s: %w", name, field, err)
+					}
+				}
+			default:
+				return fmt.Errorf("%s: %s must be a list or an object with add and remove", name, field)
+			}
+		}
+	}
+	return nil
+}
+
+// validateOverrideTarget checks that an override key names a supported format or a
+// configured distribution.
+func validateOverrideTarget(name string, distros []*DistroConfig) error {
+	if validateFormat(name) == nil || slices.ContainsFunc(distros, func(d *DistroConfig) bool { return d.Name == name }) {
+		return nil
+	}
+	return fmt.Errorf("%s: not a supported format or configured distribution", name)
+}
+
+// validateDependencyList checks that raw[key] is a list of non-empty strings.
+func validateDependencyList(raw map[string]any, key string) error {
+	items, ok := raw[key].([]any)
+	if !ok {
+		if _, ok := raw[key].([]string); ok {
+			return nil
+		}
+		return fmt.Errorf("%s must be a list of strings", key)
+	}
+	for _, item := range items {
+		if s, ok := item.(string); !ok || strings.TrimSpace(s) == "" {
+			return fmt.Errorf("%s must be a list of non-empty strings", key)
+		}
+	}
+	return nil
+}
+
+// applyOverrides patches the dependency lists in doc with the overrides for format and
+// then for distro. When the nfpm config has its own overrides for format, nfpm uses
+// those lists instead of the top-level ones, so they are patched as well. It reports
+// whether doc changed.
+func applyOverrides(doc map[string]any, format, distro string, overrides map[string]DependencyOverride) bool {
+	changed := false
+	for _, name := range []string{format, distro} {
+		override, ok := overrides[name]
+		if !ok || name == "" {
+			continue
+		}
+		// nfpm's own per-format overrides are only patched where they set the field.
+		var formatOverrides map[string]any
+		if nfpmOverrides, ok := doc["overrides"].(map[string]any); ok {
+			formatOverrides, _ = nfpmOverrides[format].(map[string]any)
+		}
+		for _, field := range dependencyFields {
+			patch, ok := override[field]
+			if !ok {
+				continue
+			}
+			doc[field] = patch.apply(stringList(doc[field]))
+			if _, ok := formatOverrides[field]; ok {
+				formatOverrides[field] = patch.apply(stringList(formatOverrides[field]))
+			}
+			changed = true
+		}
+	}
+	return changed
+}
+
+// apply returns list with the patch applied.
+func (patch *DependencyPatch) apply(list []string) []any {
+	if patch.Replaced {
+		list = slices.Clone(patch.Replace)
+	}
+	list = slices.DeleteFunc(list, func(entry string) bool {
+		return slices.Contains(patch.Remove, dependencyName(entry))
+	})
+	for _, entry := range patch.Add {
+		name := dependencyName(entry)
+		if i := slices.IndexFunc(list, func(existing string) bool { return dependencyName(existing) == name }); i >= 0 {
+			list[i] = entry
+		} else {
+			list = append(list, entry)
+		}
+	}
+
+	result := make([]any, len(list))
+	for i, entry := range list {
+		result[i] = entry
+	}
+	return result
+}
+
+// dependencyName returns the package name of a dependency entry such as
+// "libc6 (>= 2.31)" or "openssl>=3.0".
+func dependencyName(entry string) string {
+	entry = strings.TrimSpace(entry)
+	if i := strings.IndexAny(entry, " (<>=|"); i >= 0 {
+		return entry[:i]
+	}
+	return entry
+}
+
+// stringList converts a decoded YAML list into strings, skipping non-string items.
+func stringList(value any) []string {
+	items, _ := value.([]any)
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
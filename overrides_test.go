@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestApplyOverrides tests patching dependency lists per format and distribution.
+func TestApplyOverrides(t *testing.T) {
+	t.Parallel()
+
+	overrides := parseOverrides(map[string]any{"overrides": map[string]any{
+		"rpm": map[string]any{
+			"depends":   map[string]any{"add": []any{"openssl-libs (>= 3.0)"}, "remove": []any{"libssl3"}},
+			"conflicts": []any{"myapp-legacy"},
+		},
+		"el8": map[string]any{
+			"depends": map[string]any{"add": []any{"openssl-libs (>= 1.1)", "compat-openssl10"}},
+		},
+	}})
+
+	newDoc := func() map[string]any {
+		return map[string]any{
+			"depends":    []any{"libc", "libssl3 (>= 3.0)", "openssl-libs"},
+			"recommends": []any{"bash-completion"},
+			"overrides": map[string]any{
+				"rpm": map[string]any{"depends": []any{"glibc", "libssl3"}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name               string
+		format             string
+		distro             string
+		expectedDepends    []any
+		expectedConflicts  any
+		expectedRPMDepends []any
+	}{
+		{
+			name:               "format",
+			format:             "rpm",
+			expectedDepends:    []any{"libc", "openssl-libs (>= 3.0)"},
+			expectedConflicts:  []any{"myapp-legacy"},
+			expectedRPMDepends: []any{"glibc", "openssl-libs (>= 3.0)"},
+		},
+		{
+			name:               "format and distro",
+			format:             "rpm",
+			distro:             "el8",
+			expectedDepends:    []any{"libc", "openssl-libs (>= 1.1)", "compat-openssl10"},
+			expectedConflicts:  []any{"myapp-legacy"},
+			expectedRPMDepends: []any{"glibc", "openssl-libs (>= 1.1)", "compat-openssl10"},
+		},
+		{
+			name:               "other format",
+			format:             "deb",
+			expectedDepends:    []any{"libc", "libssl3 (>= 3.0)", "openssl-libs"},
+			expectedRPMDepends: []any{"glibc", "libssl3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			doc := newDoc()
+			changed := applyOverrides(doc, tt.format, tt.distro, overrides)
+			if changed != (tt.format == "rpm") {
+				t.Errorf("expected changed=%v, got %v", tt.format == "rpm", changed)
+			}
+			if !reflect.DeepEqual(doc["depends"], tt.expectedDepends) {
+				t.Errorf("expected depends %v, got %v", tt.expectedDepends, doc["depends"])
+			}
+			if !reflect.DeepEqual(doc["conflicts"], tt.expectedConflicts) {
+				t.Errorf("expected conflicts %v, got %v", tt.expectedConflicts, doc["conflicts"])
+			}
+			rpmDepends := doc["overrides"].(map[string]any)["rpm"].(map[string]any)["depends"]
+			if !reflect.DeepEqual(rpmDepends, tt.expectedRPMDepends) {
+				t.Errorf("expected nfpm rpm override depends %v, got %v", tt.expectedRPMDepends, rpmDepends)
+			}
+			if !reflect.DeepEqual(doc["recommends"], []any{"bash-completion"}) {
+				t.Errorf("expected recommends to be untouched, got %v", doc["recommends"])
+			}
+		})
+	}
+}
+
+// TestValidateOverrides tests overrides validation.
+func TestValidateOverrides(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		overrides   any
+		expectError string
+	}{
+		{"format", map[string]any{"deb": map[string]any{"depends": []any{"libc6"}}}, ""},
+		{"distro", map[string]any{"el9": map[string]any{"conflicts": map[string]any{"remove": []any{"foo"}}}}, ""},
+		{"not an object", []any{"deb"}, "must be an object"},
+		{"unknown target", map[string]any{"el7": map[string]any{}}, "not a supported format or configured distribution"},
+		{"unknown field", map[string]any{"deb": map[string]any{"suggests": []any{"foo"}}}, "unknown field"},
+		{"unknown option", map[string]any{"deb": map[string]any{"depends": map[string]any{"set": []any{"foo"}}}}, "unknown option"},
+		{"not a list", map[string]any{"deb": map[string]any{"depends": "libc6"}}, "must be a list"},
+		{"empty entry", map[string]any{"deb": map[string]any{"depends": []any{""}}}, "non-empty"},
+	}
+
+	p := &LinuxPkgPlugin{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := p.Validate(context.Background(), map[string]any{
+				"distros":   []any{"el9"},
+				"overrides": tt.overrides,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectError == "" {
+				if !resp.Valid {
+					t.Errorf("expected valid config, got errors: %v", resp.Errors)
+				}
+				return
+			}
+			if resp.Valid || len(resp.Errors) == 0 || resp.Errors[0].Field != "overrides" ||
+				!strings.Contains(resp.Errors[0].Message, tt.expectError) {
+				t.Errorf("expected overrides error containing %q, got %v", tt.expectError, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestExecuteOverrides tests that each build gets its patched dependency lists.
+func TestExecuteOverrides(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: myapp\nversion: 1.2.3\ndepends:\n  - libssl\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	depends := make(map[string]any)
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			doc, err := loadNfpmConfig(args[2])
+			if err != nil {
+				return nil, err
+			}
+			target := args[len(args)-1]
+			depends[target+args[4]] = doc["depends"]
+			return []byte("created package: " + target + "myapp" + packageExtensions[args[4]]), nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb", "rpm"},
+			"packager":    "nfpm-cli",
+			"distros":     []string{"el8", "el9"},
+			"overrides": map[string]any{
+				"deb": map[string]any{"depends": []any{"libssl3"}},
+				"el8": map[string]any{"depends": map[string]any{"add": []any{"compat-openssl10"}}},
+			},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	expected := map[string]any{
+		filepath.Join(dir, "dist", "deb"):        []any{"libssl3"},
+		filepath.Join(dir, "dist", "el8", "rpm"): []any{"libssl", "compat-openssl10"},
+		filepath.Join(dir, "dist", "el9", "rpm"): []any{"libssl"},
+	}
+	if !reflect.DeepEqual(depends, expected) {
+		t.Errorf("expected depends %v, got %v", expected, depends)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ownershipOptions are the options of an ownership rule.
+var ownershipOptions = []string{"owner", "group", "mode"}
+
+// OwnershipRule sets the owner, group, and mode of the contents installed at destinations
+// matching Glob. Empty fields are left as the nfpm config sets them.
+type OwnershipRule struct {
+	// Glob matches the destinations, such as "/etc/myapp/**".
+	Glob string
+	// Owner and Group are user and group names, which the package manager resolves on
+	// the installing system.
+	Owner string
+	Group string
+	// Mode is the octal permission bits, such as "0640".
+	Mode string
+}
+
+// parseOwnership reads the ownership option, an object of rules keyed by glob. Rules are
+// ordered from the least to the most specific glob, by length, so more specific rules are
+// applied last.
+func parseOwnership(raw map[string]any) []*OwnershipRule {
+	block, _ := raw["ownership"].(map[string]any)
+	rules := make([]*OwnershipRule, 0, len(block))
+	for _, glob := range sortedKeys(block) {
+		settings, _ := block[glob].(map[string]any)
+		owner, _ := settings["owner"].(string)
+		group, _ := settings["group"].(string)
+		mode, _ := settings["mode"].(string)
+		rules = append(rules, &OwnershipRule{Glob: glob, Owner: owner, Group: group, Mode: mode})
+	}
+	slices.SortStableFunc(rules, func(a, b *OwnershipRule) int {
+		return cmp.Compare(len(a.Glob), len(b.Glob))
+	})
+	return rules
+}
+
+// validateOwnershipObject checks that ownership is an object of rule objects with only
+// the supported options, all strings.
+func validateOwnershipObject(raw map[string]any) error {
+	value, ok := raw["ownership"]
+	if !ok || value == nil {
+		return nil
+	}
+	block, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("ownership must be an object of globs")
+	}
+	for _, glob := range sortedKeys(block) {
+		settings, ok := block[glob].(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s must be an object", glob)
+		}
+		for _, key := range sortedKeys(settings) {
+			if !slices.Contains(ownershipOptions, key) {
+				return fmt.Errorf("%s: unsupported option: %s (allowed: %s)", glob, key, strings.Join(ownershipOptions, ", "))
+			}
+			if _, ok := settings[key].(string); !ok {
+				return fmt.Errorf("%s: %s must be a string", glob, key)
+			}
+		}
+	}
+	return nil
+}
+
+// validateOwnership checks every ownership rule, and that packager can set its owner and
+// group in each of formats. fpm sets owners and groups other than root only in rpm
+// packages.
+func validateOwnership(rules []*OwnershipRule, packager string, formats []string) error {
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return err
+		}
+		if packager != "fpm" || rootOwned(rule.Owner, rule.Group) {
+			continue
+		}
+		for _, format := range formats {
+			if format != "rpm" {
+				return fmt.Errorf("%s: packager fpm sets owners and groups only in rpm packages, not %s", rule.Glob, format)
+			}
+		}
+	}
+	return nil
+}
+
+// rootOwned reports whether owner and group, either of which may be empty, leave a file
+// owned by root.
+func rootOwned(owner, group string) bool {
+	return (owner == "" || owner == "root") && (group == "" || group == "root")
+}
+
+// validate checks a rule's glob, names, and mode.
+func (r *OwnershipRule) validate() error {
+	if !strings.HasPrefix(r.Glob, "/") {
+		return fmt.Errorf("glob must be an absolute destination: %s", r.Glob)
+	}
+	if _, err := regexp.Compile(globToRegexp(r.Glob)); err != nil {
+		return fmt.Errorf("invalid glob %q: %w", r.Glob, err)
+	}
+	if r.Owner == "" && r.Group == "" && r.Mode == "" {
+		return fmt.Errorf("%s: set owner, group, or mode", r.Glob)
+	}
+	for _, name := range []string{r.Owner, r.Group} {
+		if name != "" && !systemUserNamePattern.MatchString(name) {
+			return fmt.Errorf("%s: invalid user or group name %q", r.Glob, name)
+		}
+	}
+	if r.Mode != "" {
+		if _, err := parseFileMode(r.Mode); err != nil {
+			return fmt.Errorf("%s: %w", r.Glob, err)
+		}
+	}
+	return nil
+}
+
+// parseFileMode parses octal permission bits, such as "0640" or "750".
+func parseFileMode(mode string) (os.FileMode, error) {
+	bits, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || bits > 0o7777 {
+		return 0, fmt.Errorf("invalid mode %q: use octal permission bits such as \"0640\"", mode)
+	}
+	return os.FileMode(bits), nil
+}
+
+// applyOwnership sets the owner, group, and mode of the contents entries of doc whose
+// destination matches a rule, in the top-level contents and nfpm's own overrides for
+// format. Every matching rule is applied in order, so a more specific rule overrides the
+// fields it sets. Entries with a globbed source are set as a whole when their destination
+// directory matches, and symlinks are left alone. It reports whether doc changed.
+func applyOwnership(doc map[string]any, format string, rules []*OwnershipRule, getenv func(string) string) bool {
+	if len(rules) == 0 {
+		return false
+	}
+	patterns := make([]*regexp.Regexp, len(rules))
+	for i, rule := range rules {
+		patterns[i] = regexp.MustCompile(globToRegexp(rule.Glob))
+	}
+
+	changed := false
+	for _, entries := range formatContentEntries(doc, format) {
+		for _, raw := range entries {
+			entry, ok := raw.(map[string]any)
+			if !ok {
+				continue
+			}
+			if entryType, _ := entry["type"].(string); entryType == "symlink" {
+				continue
+			}
+			for i, rule := range rules {
+				if !contentDestinationMatches(patterns[i], entry, getenv) {
+					continue
+				}
+				info, ok := entry["file_info"].(map[string]any)
+				if !ok {
+					info = make(map[string]any)
+					entry["file_info"] = info
+				}
+				if rule.Owner != "" {
+					info["owner"] = rule.Owner
+				}
+				if rule.Group != "" {
+					info["group"] = rule.Group
+				}
+				if mode, err := parseFileMode(rule.Mode); err == nil && rule.Mode != "" {
+					info["mode"] = int(mode)
+				}
+				changed = true
+			}
+		}
+	}
+	return changed
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// debDataHeaders returns the tar headers of a deb's data tarball, keyed by absolute path.
+func debDataHeaders(t *testing.T, pkg string) map[string]*tar.Header {
+	t.Helper()
+
+	headers := make(map[string]*tar.Header)
+	tr := readDebMember(t, pkg, "data.tar")
+	for {
+		entry, err := tr.Next()
+		if err == io.EOF {
+			return headers
+		}
+		if err != nil {
+			t.Fatalf("failed to read the data tarball of %s: %v", pkg, err)
+		}
+		headers["/"+strings.TrimSuffix(strings.TrimPrefix(entry.Name, "./"), "/")] = entry
+	}
+}
+
+// TestApplyOwnership tests setting the owner, group, and mode of contents.
+func TestApplyOwnership(t *testing.T) {
+	t.Parallel()
+
+	rules := parseOwnership(map[string]any{"ownership": map[string]any{
+		"/etc/myapp/secret.conf": map[string]any{"mode": "0600"},
+		"/etc/myapp/**":          map[string]any{"owner": "myapp", "group": "myapp", "mode": "0640"},
+		"/var/lib/myapp":         map[string]any{"owner": "myapp", "mode": "750"},
+	}})
+	doc := map[string]any{
+		"contents": []any{
+			map[string]any{"src": "myapp", "dst": "/usr/bin/myapp"},
+			map[string]any{"src": "myapp.conf", "dst": "/etc/myapp/myapp.conf", "file_info": map[string]any{"mtime": "2024-05-01T10:00:00Z"}},
+			map[string]any{"src": "secret.conf", "dst": "/etc/myapp/secret.conf"},
+			map[string]any{"src": "conf.d/*", "dst": "/etc/myapp/conf.d"},
+			map[string]any{"dst": "/var/lib/myapp", "type": "dir"},
+			map[string]any{"src": "/etc/myapp/myapp.conf", "dst": "/etc/myapp/current.conf", "type": "symlink"},
+		},
+	}
+
+	if !applyOwnership(doc, "deb", rules, os.Getenv) {
+		t.Fatal("expected the config to change")
+	}
+	expected := []any{
+		nil,
+		map[string]any{"mtime": "2024-05-01T10:00:00Z", "owner": "myapp", "group": "myapp", "mode": 0o640},
+		map[string]any{"owner": "myapp", "group": "myapp", "mode": 0o600},
+		map[string]any{"owner": "myapp", "group": "myapp", "mode": 0o640},
+		map[string]any{"owner": "myapp", "mode": 0o750},
+		nil,
+	}
+	for i, raw := range contentEntries(doc) {
+		if info := raw.(map[string]any)["file_info"]; !reflect.DeepEqual(info, expected[i]) {
+			t.Errorf("expected file_info %v for entry %d, got %v", expected[i], i, info)
+		}
+	}
+}
+
+// TestExecuteOwnership tests building a deb with files owned by a service user.
+func TestExecuteOwnership(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeTestNfpmConfig(t, dir, "arch: amd64\n", map[string]string{"/etc/myapp/myapp.conf": "key=value\n"})
+
+	p := &LinuxPkgPlugin{cmdExecutor: &MockCommandExecutor{}}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []any{"deb"},
+			"ownership": map[string]any{
+				"/etc/myapp/*.conf": map[string]any{"owner": "myapp", "group": "adm", "mode": "0640"},
+			},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected success, got %v, %+v", err, resp)
+	}
+
+	pkg := resp.Outputs["artifacts"].([]map[string]any)[0]["path"].(string)
+	header, ok := debDataHeaders(t, pkg)["/etc/myapp/myapp.conf"]
+	if !ok {
+		t.Fatalf("expected /etc/myapp/myapp.conf in %s", pkg)
+	}
+	if header.Uname != "myapp" || header.Gname != "adm" || header.Mode&0o7777 != 0o640 {
+		t.Errorf("expected myapp:adm 0640, got %s:%s %o", header.Uname, header.Gname, header.Mode)
+	}
+}
+
+// TestValidateOwnership tests the ownership option.
+func TestValidateOwnership(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		ownership   any
+		packager    string
+		expectError string
+	}{
+		{"valid", map[string]any{"/etc/myapp/**": map[string]any{"owner": "myapp", "group": "myapp", "mode": "0640"}}, "nfpm", ""},
+		{"not an object", []any{"/etc/myapp/**"}, "nfpm", "must be an object of globs"},
+		{"rule not an object", map[string]any{"/etc/myapp/**": "myapp"}, "nfpm", "must be an object"},
+		{"unknown option", map[string]any{"/etc/myapp/**": map[string]any{"uid": "1000"}}, "nfpm", "unsupported option: uid"},
+		{"numeric mode", map[string]any{"/etc/myapp/**": map[string]any{"mode": 640}}, "nfpm", "mode must be a string"},
+		{"relative glob", map[string]any{"etc/myapp/**": map[string]any{"owner": "myapp"}}, "nfpm", "absolute destination"},
+		{"empty rule", map[string]any{"/etc/myapp/**": map[string]any{}}, "nfpm", "set owner, group, or mode"},
+		{"invalid owner", map[string]any{"/etc/myapp/**": map[string]any{"owner": "My App"}}, "nfpm", "invalid user or group name"},
+		{"invalid mode", map[string]any{"/etc/myapp/**": map[string]any{"mode": "0999"}}, "nfpm", "invalid mode"},
+		{"fpm mode", map[string]any{"/etc/myapp/**": map[string]any{"owner": "root", "mode": "0640"}}, "fpm", ""},
+		{"fpm owner", map[string]any{"/etc/myapp/**": map[string]any{"owner": "myapp"}}, "fpm", "only in rpm packages, not deb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			expectFieldError(t, map[string]any{"ownership": tt.ownership, "packager": tt.packager}, "ownership", tt.expectError)
+		})
+	}
+}
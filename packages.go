@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// packageOptions are the options an entry of packages sets for its package. Every other
+// option is shared by all packages.
+var packageOptions = []string{"name", "config_path", "formats", "output_dir"}
+
+// packageNamePattern matches package names, which also name the default output directory.
+var packageNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// packageSpec is one package of a monorepo and the raw config it is built with.
+type packageSpec struct {
+	Name   string
+	Config map[string]any
+}
+
+// parsePackages returns the packages of the packages block, each with the top-level
+// options overridden by its entry. A package without output_dir writes to a directory
+// named after it in the top-level output_dir. It returns nil when the block is not set.
+func parsePackages(raw map[string]any) ([]packageSpec, error) {
+	value, ok := raw["packages"]
+	if !ok || value == nil {
+		return nil, nil
+	}
+	entries, ok := value.([]any)
+	if !ok || len(entries) == 0 {
+		return nil, fmt.Errorf("packages must be a non-empty list")
+	}
+
+	outputDir := helpers.NewConfigParser(raw).GetString("output_dir", "", "dist")
+	specs := make([]packageSpec, 0, len(entries))
+	names := make(map[string]bool, len(entries))
+	outputDirs := make(map[string]string, len(entries))
+	for i, value := range entries {
+		entry, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("packages[%d] must be an object", i)
+		}
+		for _, key := range sortedKeys(entry) {
+			if !slices.Contains(packageOptions, key) {
+				return nil, fmt.Errorf("packages[%d]: unsupported option: %s (allowed: %s)", i, key, strings.Join(packageOptions, ", "))
+			}
+		}
+
+		parser := helpers.NewConfigParser(entry)
+		name := parser.GetString("name", "", "")
+		if !packageNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("packages[%d]: name must be letters, digits, dots, dashes, and underscores, got %q", i, name)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("packages[%d]: duplicate name: %s", i, name)
+		}
+		names[name] = true
+		if parser.GetString("config_path", "", "") == "" {
+			return nil, fmt.Errorf("package %s: config_path is required", name)
+		}
+
+		config := maps.Clone(raw)
+		delete(config, "packages")
+		config["output_dir"] = path.Join(outputDir, name)
+		for _, key := range packageOptions[1:] {
+			if value, ok := entry[key]; ok {
+				config[key] = value
+			}
+		}
+
+		dir := filepath.Clean(helpers.NewConfigParser(config).GetString("output_dir", "", ""))
+		if other, ok := outputDirs[dir]; ok {
+			return nil, fmt.Errorf("packages %s and %s both write to %s", other, name, dir)
+		}
+		outputDirs[dir] = name
+		specs = append(specs, packageSpec{Name: name, Config: config})
+	}
+	return specs, nil
+}
+
+// packageListOutputs are the outputs whose lists are combined across packages, so
+// consumers of a single-package run keep working.
+var packageListOutputs = []string{"packages", "artifacts"}
+
+// packageRun coordinates one package with the others of a monorepo run. Their builds
+// run together and share one pool of build slots; everything after the builds runs one
+// package at a time, in package order.
+type packageRun struct {
+	slots *slotPool
+	// previous is closed when the package before this one has finished, with its
+	// success in previousOK. It is nil for the first package.
+	previous   <-chan struct{}
+	previousOK *bool
+}
+
+// buildSlots returns the build slots shared by the run, or nil outside a monorepo run.
+func (r *packageRun) buildSlots() *slotPool {
+	if r == nil {
+		return nil
+	}
+	return r.slots
+}
+
+// awaitTurn waits until the packages before this one have finished and reports whether
+// they all succeeded. Outside a monorepo run it returns true at once.
+func (r *packageRun) awaitTurn() bool {
+	if r == nil || r.previous == nil {
+		return true
+	}
+	<-r.previous
+	return *r.previousOK
+}
+
+// packageRunStopped is the response of a package whose turn never comes because a
+// package before it failed. executePackages reports that failure instead.
+func packageRunStopped() *plugin.ExecuteResponse {
+	return &plugin.ExecuteResponse{
+		Success: false,
+		Error:   "stopped: an earlier package failed",
+	}
+}
+
+// executePackages runs the hook for every package and stops at the first that fails.
+// Packages build at the same time, drawing on one pool of build slots sized by the
+// shared concurrency; the rest of each package runs in package order once the package
+// before it has succeeded. Each package's outputs are kept under package_outputs.
+func (p *LinuxPkgPlugin) executePackages(ctx context.Context, specs []packageSpec, req plugin.ExecuteRequest) (*plugin.ExecuteResponse, error) {
+	slots := newSlotPool(buildSlots(helpers.NewConfigParser(specs[0].Config).GetInt("concurrency", 0)))
+	resps := make([]*plugin.ExecuteResponse, len(specs))
+	errs := make([]error, len(specs))
+	ok := make([]bool, len(specs))
+	finished := make([]chan struct{}, len(specs))
+	var wg sync.WaitGroup
+	for i, spec := range specs {
+		finished[i] = make(chan struct{})
+		run := &packageRun{slots: slots}
+		if i > 0 {
+			run.previous, run.previousOK = finished[i-1], &ok[i-1]
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(finished[i])
+			resps[i], errs[i] = p.executeConfig(ctx, spec.Config, req, run)
+			ok[i] = errs[i] == nil && resps[i].Success && run.awaitTurn()
+		}()
+	}
+	wg.Wait()
+
+	perPackage := make(map[string]any, len(specs))
+	messages := make([]string, 0, len(specs))
+	lists := make(map[string][]any)
+	for i, spec := range specs {
+		resp, err := resps[i], errs[i]
+		if err != nil {
+			return nil, fmt.Errorf("package %s: %w", spec.Name, err)
+		}
+		perPackage[spec.Name] = resp.Outputs
+		if !resp.Success {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("package %s: %s", spec.Name, resp.Error),
+				Outputs: map[string]any{"package_outputs": perPackage},
+			}, nil
+		}
+
+		messages = append(messages, fmt.Sprintf("%s: %s", spec.Name, resp.Message))
+		for _, key := range packageListOutputs {
+			switch values := resp.Outputs[key].(type) {
+			case []string:
+				for _, value := range values {
+					lists[key] = append(lists[key], value)
+				}
+			case []map[string]any:
+				for _, value := range values {
+					lists[key] = append(lists[key], value)
+				}
+			}
+		}
+	}
+
+	outputs := map[string]any{
+		"package_outputs": perPackage,
+		"version":         req.Context.Version,
+	}
+	for key, values := range lists {
+		outputs[key] = values
+	}
+	return &plugin.ExecuteResponse{
+		Success: true,
+		Message: strings.Join(messages, "; "),
+		Outputs: outputs,
+	}, nil
+}
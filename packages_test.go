@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestParsePackages tests parsing the packages block into per-package configs.
+func TestParsePackages(t *testing.T) {
+	t.Parallel()
+
+	specs, err := parsePackages(map[string]any{
+		"formats": []any{"deb"},
+		"packages": []any{
+			map[string]any{"name": "api", "config_path": "services/api/nfpm.yaml"},
+			map[string]any{"name": "worker", "config_path": "services/worker/nfpm.yaml", "formats": []any{"rpm"}, "output_dir": "out/worker"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(specs) != 2 {
+		t.Fatalf("expected 2 packages, got %+v", specs)
+	}
+	api, worker := specs[0].Config, specs[1].Config
+	if api["output_dir"] != "dist/api" || api["config_path"] != "services/api/nfpm.yaml" || api["packages"] != nil {
+		t.Errorf("unexpected api config: %v", api)
+	}
+	if worker["output_dir"] != "out/worker" || worker["formats"].([]any)[0] != "rpm" {
+		t.Errorf("unexpected worker config: %v", worker)
+	}
+
+	tests := []struct {
+		name     string
+		packages any
+		expected string
+	}{
+		{"not a list", "api", "packages must be a non-empty list"},
+		{"empty", []any{}, "packages must be a non-empty list"},
+		{"not an object", []any{"api"}, "packages[0] must be an object"},
+		{"unknown option", []any{map[string]any{"name": "api", "config_path": "a.yaml", "target": "arm64"}}, "unsupported option: target"},
+		{"bad name", []any{map[string]any{"name": "../api", "config_path": "a.yaml"}}, "name must be"},
+		{"no config", []any{map[string]any{"name": "api"}}, "package api: config_path is required"},
+		{"duplicate", []any{
+			map[string]any{"name": "api", "config_path": "a.yaml"},
+			map[string]any{"name": "api", "config_path": "b.yaml"},
+		}, "duplicate name: api"},
+		{"shared output", []any{
+			map[string]any{"name": "api", "config_path": "a.yaml", "output_dir": "dist"},
+			map[string]any{"name": "worker", "config_path": "b.yaml", "output_dir": "dist/"},
+		}, "packages api and worker both write to dist"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := parsePackages(map[string]any{"packages": tt.packages})
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("expected an error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+// TestExecutePackages tests building every package of a monorepo in one run.
+func TestExecutePackages(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, name := range []string{"api", "worker"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", name, err)
+		}
+		writeEmbeddedTestConfig(t, filepath.Join(dir, name), "amd64")
+	}
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []any{"deb"},
+			"packages": []any{
+				map[string]any{"name": "api", "config_path": "api/nfpm.yaml"},
+				map[string]any{"name": "worker", "config_path": "worker/nfpm.yaml", "formats": []any{"deb", "rpm"}},
+			},
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got %s", resp.Error)
+	}
+	if !strings.HasPrefix(resp.Message, "api: Built 1 Linux package(s)") || !strings.Contains(resp.Message, "; worker: Built 2 Linux package(s)") {
+		t.Errorf("unexpected message: %s", resp.Message)
+	}
+	if packages := resp.Outputs["packages"].([]any); len(packages) != 3 {
+		t.Errorf("expected 3 packages, got %v", packages)
+	}
+
+	perPackage := resp.Outputs["package_outputs"].(map[string]any)
+	worker := perPackage["worker"].(map[string]any)
+	if worker["output_dir"] != filepath.Join(dir, "dist", "worker") {
+		t.Errorf("expected the worker packages in dist/worker, got %v", worker["output_dir"])
+	}
+	for _, pkg := range worker["packages"].([]string) {
+		if filepath.Dir(pkg) != filepath.Join(dir, "dist", "worker") {
+			t.Errorf("expected %s in dist/worker", pkg)
+		}
+	}
+
+	// A failing package fails the run and names the package.
+	resp, err = p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"packages": []any{
+				map[string]any{"name": "api", "config_path": "api/nfpm.yaml"},
+				map[string]any{"name": "web", "config_path": "web/nfpm.yaml"},
+			},
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success || !strings.HasPrefix(resp.Error, "package web: ") {
+		t.Errorf("expected the web package to fail, got %+v", resp)
+	}
+}
+
+// TestExecutePackagesSharedSlots tests that the builds of every package share one pool
+// of build slots, and that a failed package stops the packages after it.
+func TestExecutePackagesSharedSlots(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		concurrency int
+		failDeb     bool
+		expectMax   int32
+		expectError string
+	}{
+		{"serial", 1, false, 1, ""},
+		{"shared", 2, false, 2, ""},
+		{"first fails", 2, true, 2, "package api: "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			for _, name := range []string{"api", "worker"} {
+				if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+					t.Fatalf("failed to create %s: %v", name, err)
+				}
+				writeEmbeddedTestConfig(t, filepath.Join(dir, name), "amd64")
+			}
+
+			var active, peak atomic.Int32
+			mock := &MockCommandExecutor{
+				RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+					n := active.Add(1)
+					defer active.Add(-1)
+					for {
+						old := peak.Load()
+						if n <= old || peak.CompareAndSwap(old, n) {
+							break
+						}
+					}
+					time.Sleep(50 * time.Millisecond)
+					if tt.failDeb && slices.Contains(args, "deb") {
+						return []byte("error: broken"), errors.New("exit status 1")
+					}
+					return []byte("created package: " + args[len(args)-1] + "myapp.pkg"), nil
+				},
+			}
+			p := &LinuxPkgPlugin{
+				cmdExecutor: mock,
+				lookPath:    func(file string) (string, error) { return "/usr/bin/" + file, nil },
+			}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook: plugin.HookPostPublish,
+				Config: map[string]any{
+					"working_dir": dir,
+					"packager":    "nfpm-cli",
+					"concurrency": tt.concurrency,
+					"packages": []any{
+						map[string]any{"name": "api", "config_path": "api/nfpm.yaml", "formats": []any{"deb"}},
+						map[string]any{"name": "worker", "config_path": "worker/nfpm.yaml", "formats": []any{"rpm"}},
+					},
+				},
+				Context: plugin.ReleaseContext{Version: "1.2.3"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := peak.Load(); got != tt.expectMax {
+				t.Errorf("expected at most %d builds at once, got %d", tt.expectMax, got)
+			}
+			if tt.expectError != "" {
+				if resp.Success || !strings.HasPrefix(resp.Error, tt.expectError) {
+					t.Errorf("expected error starting with %q, got %+v", tt.expectError, resp)
+				}
+				if _, ok := resp.Outputs["package_outputs"].(map[string]any)["worker"]; ok {
+					t.Errorf("expected no outputs for the package after the failed one, got %v", resp.Outputs)
+				}
+				return
+			}
+			if !resp.Success {
+				t.Fatalf("expected success, got %s", resp.Error)
+			}
+		})
+	}
+}
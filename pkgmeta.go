@@ -0,0 +1,544 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/blakesmith/ar"
+	"github.com/goreleaser/nfpm/v2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// packageMetadata is the identity a package declares in its own metadata. Version is
+// shown the way the format's tools show it: the deb Version field, the rpm
+// [epoch:]version-release, or the apk and Arch pkgver.
+type packageMetadata struct {
+	Name    string
+	Version string
+	Arch    string
+}
+
+// asMap returns the metadata for outputs.
+func (m *packageMetadata) asMap() map[string]any {
+	return map[string]any{"name": m.Name, "version": m.Version, "arch": m.Arch}
+}
+
+// readPackageMetadata reads the name, version, and architecture from a built package
+// without external tools.
+func readPackageMetadata(pkg, format string) (*packageMetadata, error) {
+	f, err := os.Open(pkg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open package: %w", err)
+	}
+	defer f.Close()
+
+	var meta *packageMetadata
+	switch format {
+	case "deb":
+		meta, err = readDebMetadata(f)
+	case "ipk":
+		meta, err = readIPKMetadata(f)
+	case "rpm":
+		meta, err = readRPMMetadata(f)
+	case "apk":
+		meta, err = readAPKMetadata(f)
+	case "archlinux":
+		meta, err = readArchMetadata(f)
+	default:
+		return nil, fmt.Errorf("cannot read %s package metadata", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package metadata of %s: %w", pkg, err)
+	}
+	return meta, nil
+}
+
+// parseControl parses a Debian control file's Package, Version, and Architecture fields.
+func parseControl(content []byte) *packageMetadata {
+	meta := &packageMetadata{}
+	for _, line := range strings.Split(string(content), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || strings.HasPrefix(line, " ") {
+			continue
+		}
+		switch value = strings.TrimSpace(value); key {
+		case "Package":
+			meta.Name = value
+		case "Version":
+			meta.Version = value
+		case "Architecture":
+			meta.Arch = value
+		}
+	}
+	return meta
+}
+
+// tarFile returns the content of the first entry of a tar stream whose cleaned name is
+// name, or nil if there is none.
+func tarFile(r io.Reader, name string) ([]byte, error) {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if path.Clean(strings.TrimPrefix(header.Name, "./")) == name {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// controlFromTarGz returns the control file of a gzipped control tarball.
+func controlFromTarGz(r io.Reader) (*packageMetadata, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	content, err := tarFile(zr, "control")
+	if err != nil {
+		return nil, err
+	}
+	if content == nil {
+		return nil, errors.New("control.tar.gz has no control file")
+	}
+	return parseControl(content), nil
+}
+
+// readDebMetadata reads the control file from the control.tar.gz member of a deb.
+func readDebMetadata(r io.Reader) (*packageMetadata, error) {
+	reader := ar.NewReader(r)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil, errors.New("no control.tar.gz member")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.TrimSuffix(header.Name, "/") == "control.tar.gz" {
+			return controlFromTarGz(reader)
+		}
+	}
+}
+
+// readIPKMetadata reads the control file from the control.tar.gz inside an ipk, which
+// nfpm writes as a gzipped tarball.
+func readIPKMetadata(r io.Reader) (*packageMetadata, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	control, err := tarFile(zr, "control.tar.gz")
+	if err != nil {
+		return nil, err
+	}
+	if control == nil {
+		return nil, errors.New("no control.tar.gz entry")
+	}
+	return controlFromTarGz(bytes.NewReader(control))
+}
+
+// parsePKGINFO parses the pkgname, pkgver, and arch of an apk or Arch .PKGINFO file.
+func parsePKGINFO(content []byte) *packageMetadata {
+	meta := &packageMetadata{}
+	for _, line := range strings.Split(string(content), "\n") {
+		key, value, ok := strings.Cut(line, " = ")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "pkgname":
+			meta.Name = strings.TrimSpace(value)
+		case "pkgver":
+			meta.Version = strings.TrimSpace(value)
+		case "arch":
+			meta.Arch = strings.TrimSpace(value)
+		}
+	}
+	return meta
+}
+
+// readAPKMetadata reads .PKGINFO from an apk: concatenated gzip streams holding the
+// signature, the control tarball, and the data tarball.
+func readAPKMetadata(r io.Reader) (*packageMetadata, error) {
+	br := bufio.NewReader(r)
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		zr.Multistream(false)
+		content, err := tarFile(zr, ".PKGINFO")
+		if err != nil {
+			return nil, err
+		}
+		if content != nil {
+			return parsePKGINFO(content), nil
+		}
+		// Skip what is left of this stream before moving to the next one.
+		if _, err := io.Copy(io.Discard, zr); err != nil {
+			return nil, err
+		}
+		if err := zr.Reset(br); err == io.EOF {
+			return nil, errors.New("no .PKGINFO file")
+		} else if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readArchMetadata reads .PKGINFO from a zstd-compressed Arch Linux package.
+func readArchMetadata(r io.Reader) (*packageMetadata, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	content, err := tarFile(zr, ".PKGINFO")
+	if err != nil {
+		return nil, err
+	}
+	if content == nil {
+		return nil, errors.New("no .PKGINFO file")
+	}
+	return parsePKGINFO(content), nil
+}
+
+// rpm header tags and types read from the main header.
+const (
+	rpmTagName    = 1000
+	rpmTagVersion = 1001
+	rpmTagRelease = 1002
+	rpmTagEpoch   = 1003
+	rpmTagArch    = 1022
+
+	rpmTypeInt32  = 4
+	rpmTypeString = 6
+)
+
+// rpmHeader is a parsed rpm header structure: its index entries and data store.
+type rpmHeader struct {
+	entries map[uint32][3]uint32 // tag -> type, offset, count
+	store   []byte
+}
+
+// readRPMHeader reads a header structure; with pad, the header is followed by padding
+// to an 8-byte boundary, as the signature header is.
+func readRPMHeader(r io.Reader, pad bool) (*rpmHeader, error) {
+	var intro struct {
+		Magic    [4]byte
+		Reserved [4]byte
+		Count    uint32
+		Size     uint32
+	}
+	if err := binary.Read(r, binary.BigEndian, &intro); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(intro.Magic[:3], []byte{0x8e, 0xad, 0xe8}) {
+		return nil, errors.New("invalid header magic")
+	}
+	if intro.Count > 1<<16 || intro.Size > 1<<28 {
+		return nil, errors.New("header too large")
+	}
+
+	index := make([]uint32, 4*intro.Count)
+	if err := binary.Read(r, binary.BigEndian, index); err != nil {
+		return nil, err
+	}
+	store := make([]byte, intro.Size)
+	if _, err := io.ReadFull(r, store); err != nil {
+		return nil, err
+	}
+	if pad {
+		if padding := (8 - intro.Size%8) % 8; padding > 0 {
+			if _, err := io.CopyN(io.Discard, r, int64(padding)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	h := &rpmHeader{entries: make(map[uint32][3]uint32, intro.Count), store: store}
+	for i := 0; i < len(index); i += 4 {
+		h.entries[index[i]] = [3]uint32{index[i+1], index[i+2], index[i+3]}
+	}
+	return h, nil
+}
+
+// value returns a string or int32 tag as a string, and whether the tag is present.
+func (h *rpmHeader) value(tag uint32) (string, bool) {
+	entry, ok := h.entries[tag]
+	if !ok || int(entry[1]) >= len(h.store) {
+		return "", false
+	}
+	data := h.store[entry[1]:]
+	switch entry[0] {
+	case rpmTypeString:
+		if end := bytes.IndexByte(data, 0); end >= 0 {
+			return string(data[:end]), true
+		}
+	case rpmTypeInt32:
+		if len(data) >= 4 {
+			return strconv.FormatUint(uint64(binary.BigEndian.Uint32(data)), 10), true
+		}
+	}
+	return "", false
+}
+
+// int16s returns the values of an int16 array tag, and whether the tag is present.
+func (h *rpmHeader) int16s(tag uint32) ([]uint16, bool) {
+	entry, ok := h.entries[tag]
+	if !ok || entry[0] != rpmTypeInt16 || uint64(entry[1])+2*uint64(entry[2]) > uint64(len(h.store)) {
+		return nil, false
+	}
+	values := make([]uint16, entry[2])
+	for i := range values {
+		values[i] = binary.BigEndian.Uint16(h.store[int(entry[1])+2*i:])
+	}
+	return values, true
+}
+
+// int32s returns the values of an int32 array tag, and whether the tag is present.
+func (h *rpmHeader) int32s(tag uint32) ([]uint32, bool) {
+	entry, ok := h.entries[tag]
+	if !ok || entry[0] != rpmTypeInt32 || uint64(entry[1])+4*uint64(entry[2]) > uint64(len(h.store)) {
+		return nil, false
+	}
+	values := make([]uint32, entry[2])
+	for i := range values {
+		values[i] = binary.BigEndian.Uint32(h.store[int(entry[1])+4*i:])
+	}
+	return values, true
+}
+
+// strings returns the values of a string array tag, and whether the tag is present.
+func (h *rpmHeader) strings(tag uint32) ([]string, bool) {
+	entry, ok := h.entries[tag]
+	if !ok || entry[0] != rpmTypeStringArray || int(entry[1]) > len(h.store) {
+		return nil, false
+	}
+	data := h.store[entry[1]:]
+	values := make([]string, 0, entry[2])
+	for range entry[2] {
+		end := bytes.IndexByte(data, 0)
+		if end < 0 {
+			return nil, false
+		}
+		values = append(values, string(data[:end]))
+		data = data[end+1:]
+	}
+	return values, true
+}
+
+// readRPMMainHeader reads an rpm's main header, after its lead and signature header.
+func readRPMMainHeader(r io.Reader) (*rpmHeader, error) {
+	lead := make([]byte, 96)
+	if _, err := io.ReadFull(r, lead); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(lead[:4], []byte{0xed, 0xab, 0xee, 0xdb}) {
+		return nil, errors.New("not an rpm package")
+	}
+	if _, err := readRPMHeader(r, true); err != nil {
+		return nil, fmt.Errorf("invalid signature header: %w", err)
+	}
+	h, err := readRPMHeader(r, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid header: %w", err)
+	}
+	return h, nil
+}
+
+// readRPMMetadata reads the name, [epoch:]version-release, and arch from an rpm's main
+// header.
+func readRPMMetadata(r io.Reader) (*packageMetadata, error) {
+	h, err := readRPMMainHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	name, _ := h.value(rpmTagName)
+	version, _ := h.value(rpmTagVersion)
+	release, _ := h.value(rpmTagRelease)
+	arch, _ := h.value(rpmTagArch)
+	version += "-" + release
+	if epoch, ok := h.value(rpmTagEpoch); ok {
+		version = epoch + ":" + version
+	}
+	return &packageMetadata{Name: name, Version: version, Arch: arch}, nil
+}
+
+// expectedPackageMetadata returns the metadata nfpm writes for info into a package of
+// format. The packager translates info.Arch into the format's native name.
+func expectedPackageMetadata(format string, info *nfpm.Info, packager nfpm.Packager) *packageMetadata {
+	packager.ConventionalFileName(info)
+	meta := &packageMetadata{Name: info.Name, Arch: info.Arch}
+
+	epoch := ""
+	if n, err := strconv.ParseUint(info.Epoch, 10, 64); err == nil {
+		epoch = fmt.Sprintf("%d:", n)
+	}
+	switch format {
+	case "rpm":
+		version := info.Version
+		if info.Prerelease != "" {
+			version += "~" + strings.ReplaceAll(info.Prerelease, "-", "_")
+		}
+		if info.VersionMetadata != "" {
+			version += "+" + info.VersionMetadata
+		}
+		release := info.Release
+		if release == "" {
+			release = "1"
+		}
+		meta.Version = epoch + version + "-" + release
+	case "apk":
+		// apk has no epoch; the release gets an "r" and build metadata a "p" prefix.
+		version := info.Version
+		if info.Prerelease != "" {
+			version += "_" + info.Prerelease
+		}
+		if release := info.Release; release != "" {
+			if !strings.HasPrefix(release, "r") {
+				release = "r" + release
+			}
+			version += "-" + release
+		}
+		if metadata := info.VersionMetadata; metadata != "" {
+			if !strings.HasPrefix(metadata, "p") && !strings.HasPrefix(metadata, "cvs") && !strings.HasPrefix(metadata, "svn") &&
+				!strings.HasPrefix(metadata, "git") && !strings.HasPrefix(metadata, "hg") {
+				metadata = "p" + metadata
+			}
+			version += "-" + metadata
+		}
+		meta.Version = version
+	case "archlinux":
+		// nfpm only keeps the prerelease of Arch packages with an epoch.
+		pkgrel, err := strconv.Atoi(info.Release)
+		if err != nil {
+			pkgrel = 1
+		}
+		meta.Version = fmt.Sprintf("%s-%d", info.Version, pkgrel)
+		if epoch != "" {
+			meta.Version = fmt.Sprintf("%s%s%s-%d", epoch, info.Version, strings.ReplaceAll(info.Prerelease, "-", "_"), pkgrel)
+		}
+	default:
+		version := info.Version
+		if info.Epoch != "" {
+			version = info.Epoch + ":" + version
+		}
+		if info.Prerelease != "" {
+			version += "~" + info.Prerelease
+		}
+		if info.VersionMetadata != "" {
+			version += "+" + info.VersionMetadata
+		}
+		if info.Release != "" {
+			version += "-" + info.Release
+		}
+		meta.Version = version
+	}
+	return meta
+}
+
+// diff returns one "field: expected ..., got ..." line per field that differs.
+func (m *packageMetadata) diff(actual *packageMetadata) []string {
+	var diffs []string
+	for _, field := range []struct{ name, expected, actual string }{
+		{"name", m.Name, actual.Name},
+		{"version", m.Version, actual.Version},
+		{"arch", m.Arch, actual.Arch},
+	} {
+		if field.expected != field.actual {
+			diffs = append(diffs, fmt.Sprintf("%s: expected %q, got %q", field.name, field.expected, field.actual))
+		}
+	}
+	return diffs
+}
+
+// verifyPackageMetadata reads the metadata of the package built for job and checks it
+// against what nfpm derives from the job's config.
+func verifyPackageMetadata(job buildJob, pkg string) (*packageMetadata, error) {
+	arch := ""
+	if job.Target.Override {
+		arch = job.Target.nfpmArch()
+	}
+	info, packager, err := resolvePackageInfo(job.ConfigPath, job.Format, arch, envLookup(job.Env, os.Getenv))
+	if err != nil {
+		return nil, err
+	}
+	expected := expectedPackageMetadata(job.Format, info, packager)
+
+	actual, err := readPackageMetadata(pkg, job.Format)
+	if err != nil {
+		return nil, err
+	}
+	if diffs := expected.diff(actual); len(diffs) > 0 {
+		return actual, fmt.Errorf("package metadata of %s does not match its config:\n  %s", pkg, strings.Join(diffs, "\n  "))
+	}
+	return actual, nil
+}
+
+// releasePackageVersion returns the version a package of format built from info carries
+// when its version is the release version: the release version in the format's native
+// syntax, with the epoch and release of info.
+func releasePackageVersion(format, version string, info *nfpm.Info, packager nfpm.Packager) string {
+	native, ok := nativeVersion(format, version)
+	if !ok {
+		native = version
+	}
+	release := *info
+	release.Version, release.Prerelease, release.VersionMetadata = native, "", ""
+	return expectedPackageMetadata(format, &release, packager).Version
+}
+
+// verifyReleaseVersion checks that meta, read from the package built for job, carries the
+// job's release version.
+func verifyReleaseVersion(job buildJob, pkg string, meta *packageMetadata) error {
+	arch := ""
+	if job.Target.Override {
+		arch = job.Target.nfpmArch()
+	}
+	info, packager, err := resolvePackageInfo(job.ConfigPath, job.Format, arch, envLookup(job.Env, os.Getenv))
+	if err != nil {
+		return err
+	}
+	if expected := releasePackageVersion(job.Format, job.Version, info, packager); expected != meta.Version {
+		return fmt.Errorf("package version of %s does not match release version %s:\n  version: expected %q, got %q",
+			pkg, job.Version, expected, meta.Version)
+	}
+	return nil
+}
+
+// checkPackageMetadata runs the metadata and release version checks enabled in checks on
+// the package built for job and returns the package's metadata.
+func checkPackageMetadata(job buildJob, pkg string, checks *ChecksConfig) (*packageMetadata, error) {
+	var meta *packageMetadata
+	var err error
+	if checks.Metadata {
+		meta, err = verifyPackageMetadata(job, pkg)
+	} else {
+		meta, err = readPackageMetadata(pkg, job.Format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if checks.Version && job.Version != "" {
+		if err := verifyReleaseVersion(job, pkg, meta); err != nil {
+			return nil, err
+		}
+	}
+	return meta, nil
+}
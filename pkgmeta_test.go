@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/goreleaser/nfpm/v2"
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestExecuteMetadataCheck tests reading back the metadata of every format built with the
+// embedded library.
+func TestExecuteMetadataCheck(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb", "rpm", "apk", "archlinux", "ipk"},
+			"release":     "2",
+			"epoch":       1,
+			"checks":      map[string]any{"metadata": true},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	expected := map[string]map[string]any{
+		"deb":       {"name": "myapp", "version": "1:1.2.3-2", "arch": "amd64"},
+		"rpm":       {"name": "myapp", "version": "1:1.2.3-2", "arch": "x86_64"},
+		"apk":       {"name": "myapp", "version": "1.2.3-r2", "arch": "x86_64"},
+		"archlinux": {"name": "myapp", "version": "1:1.2.3-2", "arch": "x86_64"},
+		"ipk":       {"name": "myapp", "version": "1:1.2.3-2", "arch": "x86_64"},
+	}
+	for _, artifact := range resp.Outputs["artifacts"].([]map[string]any) {
+		format := artifact["format"].(string)
+		if !reflect.DeepEqual(artifact["metadata"], expected[format]) {
+			t.Errorf("%s: expected metadata %v, got %v", format, expected[format], artifact["metadata"])
+		}
+	}
+}
+
+// TestVerifyPackageMetadataMismatch tests that a package that does not match its config
+// is reported field by field.
+func TestVerifyPackageMetadataMismatch(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	config := writeEmbeddedTestConfig(t, dir, "amd64")
+
+	for _, format := range []string{"deb", "rpm", "apk", "archlinux", "ipk"} {
+		result, _, err := buildPackageEmbedded(context.Background(), config, format, "", dir, "", os.Getenv)
+		if err != nil {
+			t.Fatalf("%s: failed to build: %v", format, err)
+		}
+
+		job := buildJob{Format: format, ConfigPath: config}
+		if _, err := verifyPackageMetadata(job, result.Path); err != nil {
+			t.Errorf("%s: expected matching metadata, got %v", format, err)
+		}
+
+		// The config moved on since the package was built.
+		stale := strings.Replace(config, "nfpm.yaml", "stale.yaml", 1)
+		content, err := os.ReadFile(config)
+		if err != nil {
+			t.Fatalf("failed to read config: %v", err)
+		}
+		content = []byte(strings.NewReplacer("name: myapp", "name: otherapp", "version: 1.2.3", "version: 1.2.4").Replace(string(content)))
+		if err := os.WriteFile(stale, content, 0644); err != nil {
+			t.Fatalf("failed to write config: %v", err)
+		}
+		_, err = verifyPackageMetadata(buildJob{Format: format, ConfigPath: stale}, result.Path)
+		if err == nil || !strings.Contains(err.Error(), `name: expected "otherapp", got "myapp"`) || !strings.Contains(err.Error(), `version: expected "1.2.4`) {
+			t.Errorf("%s: expected a name and version mismatch, got %v", format, err)
+		}
+	}
+
+	if _, err := readPackageMetadata(config, "rpm"); err == nil || !strings.Contains(err.Error(), "not an rpm package") {
+		t.Errorf("expected an invalid rpm error, got %v", err)
+	}
+}
+
+// TestExecuteVersionCheck tests that packages whose version differs from the release
+// version fail the version check.
+func TestExecuteVersionCheck(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	configPath := writeEmbeddedTestConfig(t, dir, "amd64")
+
+	// The nfpm config hardcodes version 1.2.3.
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb"},
+			"checks":      map[string]any{"version": true},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.4"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success {
+		t.Fatal("expected a stale version to fail")
+	}
+	if !strings.Contains(resp.Error, "does not match release version 1.2.4") || !strings.Contains(resp.Error, `version: expected "1.2.4", got "1.2.3"`) {
+		t.Errorf("expected a version diff, got %s", resp.Error)
+	}
+
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	if err := os.WriteFile(configPath, []byte(strings.Replace(string(content), "version: 1.2.3", "version: ${VERSION}", 1)), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	resp, err = p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb", "rpm", "apk", "archlinux"},
+			"epoch":       2,
+			"checks":      map[string]any{"version": true},
+		},
+		Context: plugin.ReleaseContext{Version: "v1.3.0-rc.1"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+}
+
+// TestReleasePackageVersion tests the version packages of each format carry for a release.
+func TestReleasePackageVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		format   string
+		version  string
+		epoch    string
+		release  string
+		expected string
+	}{
+		{"deb", "1.2.3", "", "", "1.2.3"},
+		{"deb", "v1.2.3-rc.1", "1", "2", "1:1.2.3~rc.1-2"},
+		{"rpm", "1.2.3", "", "", "1.2.3-1"},
+		{"rpm", "1.2.3-rc.1+build-5", "3", "2", "3:1.2.3~rc.1+build_5-2"},
+		{"apk", "1.2.3-rc.1", "", "2", "1.2.3_rc1-r2"},
+		{"archlinux", "1.2.3-beta.2", "", "", "1.2.3beta.2-1"},
+		{"ipk", "2024.05", "", "", "2024.5.0"},
+		{"ipk", "1.2.3.4", "", "", "1.2.3.4"},
+	}
+	for _, tt := range tests {
+		info := &nfpm.Info{Name: "myapp", Arch: "amd64", Epoch: tt.epoch, Release: tt.release}
+		packager, err := nfpm.Get(tt.format)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.format, err)
+		}
+		if got := releasePackageVersion(tt.format, tt.version, info, packager); got != tt.expected {
+			t.Errorf("%s %s: expected %q, got %q", tt.format, tt.version, tt.expected, got)
+		}
+	}
+}
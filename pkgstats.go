@@ -0,0 +1,363 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/blakesmith/ar"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// packageStats describes what a package installs: the number of regular files and
+// their combined size in bytes.
+type packageStats struct {
+	InstalledSize int64
+	FileCount     int
+}
+
+// packageFile is a regular file a package installs.
+type packageFile struct {
+	Size int64
+	Mode int64
+	// Digest is the hex-encoded content digest, empty unless digests were read.
+	Digest string
+}
+
+// packageContents is what a package installs, keyed by absolute path, and the
+// dependencies it declares.
+type packageContents struct {
+	Files   map[string]packageFile
+	Depends []string
+}
+
+// readPackageStats reads the installed size and file count of a built package without
+// external tools.
+func readPackageStats(pkg, format string) (*packageStats, error) {
+	contents, err := readPackageContents(pkg, format, false)
+	if err != nil {
+		return nil, err
+	}
+	stats := &packageStats{FileCount: len(contents.Files)}
+	for _, file := range contents.Files {
+		stats.InstalledSize += file.Size
+	}
+	return stats, nil
+}
+
+// readPackageContents reads the regular files and dependencies of a built package
+// without external tools. With digests, the content of every file is hashed; rpms carry
+// their file digests in the header either way.
+func readPackageContents(pkg, format string, digests bool) (*packageContents, error) {
+	f, err := os.Open(pkg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open package: %w", err)
+	}
+	defer f.Close()
+
+	contents := &packageContents{Files: make(map[string]packageFile)}
+	switch format {
+	case "deb":
+		err = contents.addDeb(f, digests)
+	case "ipk":
+		err = contents.addIPK(f, digests)
+	case "rpm":
+		err = contents.addRPM(f)
+	case "apk":
+		err = contents.addAPK(f, digests)
+	case "archlinux":
+		err = contents.addArch(f, digests)
+	default:
+		return nil, fmt.Errorf("cannot read %s package contents", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read package contents of %s: %w", pkg, err)
+	}
+	sort.Strings(contents.Depends)
+	return contents, nil
+}
+
+// addTar adds the regular files of a tar stream. With pkginfo, entries whose top-level
+// name starts with a dot are package metadata, and the dependencies are read from
+// .PKGINFO.
+func (c *packageContents) addTar(r io.Reader, digests, pkginfo bool) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		if pkginfo && name == ".PKGINFO" {
+			content, err := io.ReadAll(tr)
+			if err != nil {
+				return err
+			}
+			c.Depends = append(c.Depends, pkginfoDepends(content)...)
+			continue
+		}
+		if header.Typeflag != tar.TypeReg || (pkginfo && strings.HasPrefix(name, ".")) {
+			continue
+		}
+
+		file := packageFile{Size: header.Size, Mode: header.Mode & 0o7777}
+		if digests {
+			h := sha256.New()
+			if _, err := io.Copy(h, tr); err != nil {
+				return err
+			}
+			file.Digest = hex.EncodeToString(h.Sum(nil))
+		}
+		c.Files["/"+strings.TrimPrefix(name, "/")] = file
+	}
+}
+
+// pkginfoDepends returns the depend entries of an apk or Arch .PKGINFO file.
+func pkginfoDepends(content []byte) []string {
+	var depends []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if key, value, ok := strings.Cut(line, " = "); ok && strings.TrimSpace(key) == "depend" {
+			depends = append(depends, strings.TrimSpace(value))
+		}
+	}
+	return depends
+}
+
+// controlDepends returns the Depends entries of a Debian control file.
+func controlDepends(content []byte) []string {
+	var depends []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if value, ok := strings.CutPrefix(line, "Depends:"); ok {
+			for _, depend := range strings.Split(value, ",") {
+				if depend = strings.TrimSpace(depend); depend != "" {
+					depends = append(depends, depend)
+				}
+			}
+		}
+	}
+	return depends
+}
+
+// addControlTarGz adds the dependencies from a gzipped control tarball.
+func (c *packageContents) addControlTarGz(r io.Reader) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	content, err := tarFile(zr, "control")
+	if err != nil {
+		return err
+	}
+	c.Depends = append(c.Depends, controlDepends(content)...)
+	return nil
+}
+
+// decompress returns a reader of a data tarball member, decompressed by its extension.
+func decompress(r io.Reader, name string) (io.ReadCloser, error) {
+	switch path.Ext(name) {
+	case ".tar":
+		return io.NopCloser(r), nil
+	case ".gz":
+		return gzip.NewReader(r)
+	case ".xz":
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(xr), nil
+	case ".zst":
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	case ".bz2":
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	}
+	return nil, fmt.Errorf("unsupported data member %s", name)
+}
+
+// addDeb adds the control and data.tar members of a deb.
+func (c *packageContents) addDeb(r io.Reader, digests bool) error {
+	reader := ar.NewReader(r)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return errors.New("no data.tar member")
+		}
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(header.Name, "/")
+		if name == "control.tar.gz" {
+			if err := c.addControlTarGz(reader); err != nil {
+				return err
+			}
+		}
+		if strings.HasPrefix(name, "data.tar") {
+			data, err := decompress(reader, name)
+			if err != nil {
+				return err
+			}
+			defer data.Close()
+			return c.addTar(data, digests, false)
+		}
+	}
+}
+
+// addIPK adds the control.tar.gz and data.tar.gz inside an ipk's gzipped tarball.
+func (c *packageContents) addIPK(r io.Reader, digests bool) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(zr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return errors.New("no data.tar.gz entry")
+		}
+		if err != nil {
+			return err
+		}
+		switch path.Clean(strings.TrimPrefix(header.Name, "./")) {
+		case "control.tar.gz":
+			if err := c.addControlTarGz(tr); err != nil {
+				return err
+			}
+		case "data.tar.gz":
+			data, err := gzip.NewReader(tr)
+			if err != nil {
+				return err
+			}
+			return c.addTar(data, digests, false)
+		}
+	}
+}
+
+// addAPK adds the files of an apk's data tarball and the dependencies of its control
+// tarball, gzip streams after the signature stream.
+func (c *packageContents) addAPK(r io.Reader, digests bool) error {
+	br := bufio.NewReader(r)
+	zr, err := gzip.NewReader(br)
+	if err != nil {
+		return err
+	}
+	for {
+		zr.Multistream(false)
+		if err := c.addTar(zr, digests, true); err != nil {
+			return err
+		}
+		if _, err := io.Copy(io.Discard, zr); err != nil {
+			return err
+		}
+		if err := zr.Reset(br); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// addArch adds the files and dependencies of a zstd-compressed Arch Linux package.
+func (c *packageContents) addArch(r io.Reader, digests bool) error {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	return c.addTar(zr, digests, true)
+}
+
+// rpm header tags, types, and dependency flags read for package contents.
+const (
+	rpmTagFileSizes      = 1028
+	rpmTagFileModes      = 1030
+	rpmTagFileDigests    = 1035
+	rpmTagRequireFlags   = 1048
+	rpmTagRequireName    = 1049
+	rpmTagRequireVersion = 1050
+	rpmTagDirIndexes     = 1116
+	rpmTagBaseNames      = 1117
+	rpmTagDirNames       = 1118
+
+	rpmTypeInt16       = 3
+	rpmTypeStringArray = 8
+
+	rpmSenseLess    = 1 << 1
+	rpmSenseGreater = 1 << 2
+	rpmSenseEqual   = 1 << 3
+)
+
+// addRPM reads the files and requirements from an rpm's main header, without
+// decompressing the payload.
+func (c *packageContents) addRPM(r io.Reader) error {
+	h, err := readRPMMainHeader(r)
+	if err != nil {
+		return err
+	}
+
+	baseNames, _ := h.strings(rpmTagBaseNames)
+	dirNames, _ := h.strings(rpmTagDirNames)
+	dirIndexes, _ := h.int32s(rpmTagDirIndexes)
+	sizes, _ := h.int32s(rpmTagFileSizes)
+	modes, _ := h.int16s(rpmTagFileModes)
+	digests, _ := h.strings(rpmTagFileDigests)
+	if len(dirIndexes) != len(baseNames) || len(sizes) != len(baseNames) || len(modes) != len(baseNames) {
+		return errors.New("inconsistent file list")
+	}
+	for i, name := range baseNames {
+		if modes[i]&0o170000 != 0o100000 || int(dirIndexes[i]) >= len(dirNames) {
+			continue
+		}
+		file := packageFile{Size: int64(sizes[i]), Mode: int64(modes[i] & 0o7777)}
+		if i < len(digests) {
+			file.Digest = digests[i]
+		}
+		c.Files[dirNames[dirIndexes[i]]+name] = file
+	}
+
+	names, _ := h.strings(rpmTagRequireName)
+	versions, _ := h.strings(rpmTagRequireVersion)
+	flags, _ := h.int32s(rpmTagRequireFlags)
+	for i, name := range names {
+		// rpmlib() requirements describe the package format, not the software.
+		if strings.HasPrefix(name, "rpmlib(") {
+			continue
+		}
+		if i < len(versions) && i < len(flags) && versions[i] != "" {
+			name += " " + rpmSenseOperator(flags[i]) + " " + versions[i]
+		}
+		c.Depends = append(c.Depends, name)
+	}
+	return nil
+}
+
+// rpmSenseOperator returns the comparison of an rpm dependency's sense flags.
+func rpmSenseOperator(flags uint32) string {
+	operator := ""
+	if flags&rpmSenseLess != 0 {
+		operator += "<"
+	}
+	if flags&rpmSenseGreater != 0 {
+		operator += ">"
+	}
+	if flags&rpmSenseEqual != 0 || operator == "" {
+		operator += "="
+	}
+	return operator
+}
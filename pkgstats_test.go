@@ -0,0 +1,126 @@
+package main
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blakesmith/ar"
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// debMember returns the name of the first member of the deb pkg whose name starts with
+// prefix, such as "data.tar", and a reader of its raw content. The package stays open
+// until the test ends.
+func debMember(t *testing.T, pkg, prefix string) (string, io.Reader) {
+	t.Helper()
+
+	f, err := os.Open(pkg)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", pkg, err)
+	}
+	t.Cleanup(func() { f.Close() })
+	reader := ar.NewReader(f)
+	for {
+		header, err := reader.Next()
+		if err != nil {
+			t.Fatalf("no %s member in %s: %v", prefix, pkg, err)
+		}
+		if name := strings.TrimSuffix(header.Name, "/"); strings.HasPrefix(name, prefix) {
+			return name, reader
+		}
+	}
+}
+
+// readDebMember returns a reader of the decompressed tarball of the deb pkg whose name
+// starts with prefix, such as "data.tar" or "control.tar".
+func readDebMember(t *testing.T, pkg, prefix string) *tar.Reader {
+	t.Helper()
+
+	name, member := debMember(t, pkg, prefix)
+	data, err := decompress(member, name)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", name, err)
+	}
+	t.Cleanup(func() { data.Close() })
+	return tar.NewReader(data)
+}
+
+// TestExecutePackageStats tests reporting the installed size and file count of every
+// package, including debs with other data compressions.
+func TestExecutePackageStats(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		compression string
+	}{
+		{"default", ""},
+		{"xz", "xz"},
+		{"zstd", "zstd"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			configPath := writeEmbeddedTestConfig(t, dir, "amd64")
+			if tt.compression != "" {
+				f, err := os.OpenFile(configPath, os.O_APPEND|os.O_WRONLY, 0)
+				if err != nil {
+					t.Fatalf("failed to open config: %v", err)
+				}
+				_, err = f.WriteString("deb:\n  compression: " + tt.compression + "\n")
+				f.Close()
+				if err != nil {
+					t.Fatalf("failed to write config: %v", err)
+				}
+			}
+
+			p := &LinuxPkgPlugin{}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook: plugin.HookPostPublish,
+				Config: map[string]any{
+					"working_dir": dir,
+					"formats":     []any{"deb", "rpm", "apk", "archlinux", "ipk"},
+				},
+				Context: plugin.ReleaseContext{Version: "1.2.3"},
+			})
+			if err != nil || !resp.Success {
+				t.Fatalf("expected success, got %v, %+v", err, resp)
+			}
+
+			binary, err := os.Stat(filepath.Join(dir, "myapp"))
+			if err != nil {
+				t.Fatalf("failed to stat binary: %v", err)
+			}
+			for _, artifact := range resp.Outputs["artifacts"].([]map[string]any) {
+				if artifact["file_count"] != 1 || artifact["installed_size"] != binary.Size() {
+					t.Errorf("%s: expected 1 file of %d bytes, got %v files of %v bytes",
+						artifact["format"], binary.Size(), artifact["file_count"], artifact["installed_size"])
+				}
+			}
+		})
+	}
+}
+
+// TestReadPackageStatsUnsupported tests that unreadable packages report an error.
+func TestReadPackageStatsUnsupported(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "test.deb")
+	if err := os.WriteFile(path, []byte("package"), 0644); err != nil {
+		t.Fatalf("failed to write package: %v", err)
+	}
+	if _, err := readPackageStats(path, "deb"); err == nil {
+		t.Error("expected an invalid deb to fail")
+	}
+	if _, err := readPackageStats(path, "sh"); err == nil {
+		t.Error("expected sh packages to be unsupported")
+	}
+}
@@ -3,24 +3,48 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"io"
+	"maps"
+	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
-	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
 	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
 )
 
+// pluginVersion is the version reported by GetInfo and recorded in provenance.
+const pluginVersion = "2.0.0"
+
 // Allowed package formats for security validation.
 var allowedFormats = map[string]bool{
-	"deb": true,
-	"rpm": true,
-	"apk": true,
+	"deb":       true,
+	"rpm":       true,
+	"apk":       true,
+	"archlinux": true,
+	"ipk":       true,
+	"sh":        true,
+	"tar":       true,
+}
+
+// packageExtensions maps package formats to the file extension nfpm, or fpm for the
+// formats only it builds, produces.
+var packageExtensions = map[string]string{
+	"deb":       ".deb",
+	"rpm":       ".rpm",
+	"apk":       ".apk",
+	"archlinux": ".pkg.tar.zst",
+	"ipk":       ".ipk",
+	"sh":        ".sh",
+	"tar":       ".tar",
 }
 
 // Allowed target architectures for security validation.
@@ -29,32 +53,37 @@ var allowedArchitectures = map[string]bool{
 	"386":     true,
 	"arm64":   true,
 	"arm":     true,
+	"arm/v5":  true,
+	"arm/v6":  true,
+	"arm/v7":  true,
 	"ppc64le": true,
 	"s390x":   true,
 	"riscv64": true,
 }
 
-// formatNamePattern validates package format names.
-var formatNamePattern = regexp.MustCompile(`^[a-z]+$`)
-
-// CommandExecutor abstracts command execution for testability.
-type CommandExecutor interface {
-	Run(ctx context.Context, name string, args ...string) ([]byte, error)
+// Allowed packaging tools.
+var allowedPackagers = map[string]bool{
+	"nfpm":      true,
+	"nfpm-cli":  true,
+	"fpm":       true,
+	"container": true,
+	"native":    true,
 }
 
-// RealCommandExecutor executes real shell commands.
-type RealCommandExecutor struct{}
-
-// Run executes a command and returns combined output.
-func (e *RealCommandExecutor) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
-	cmd := exec.CommandContext(ctx, name, args...)
-	return cmd.CombinedOutput()
-}
+// formatNamePattern validates package format names.
+var formatNamePattern = regexp.MustCompile(`^[a-z]+$`)
 
 // LinuxPkgPlugin implements the Linux package building plugin.
 type LinuxPkgPlugin struct {
 	// cmdExecutor is used for executing shell commands. If nil, uses RealCommandExecutor.
 	cmdExecutor CommandExecutor
+	// lookPath locates host binaries. If nil, uses exec.LookPath.
+	lookPath func(file string) (string, error)
+	// httpClient is used for HTTP uploads. If nil, uses http.DefaultClient.
+	httpClient *http.Client
+	// logOutput receives tool output as it is written. If nil, uses os.Stderr, which the
+	// plugin host forwards to its log.
+	logOutput io.Writer
 }
 
 // getExecutor returns the command executor, defaulting to RealCommandExecutor.
@@ -65,63 +94,256 @@ func (p *LinuxPkgPlugin) getExecutor() CommandExecutor {
 	return &RealCommandExecutor{}
 }
 
+// getLogOutput returns the log output, defaulting to os.Stderr.
+func (p *LinuxPkgPlugin) getLogOutput() io.Writer {
+	if p.logOutput != nil {
+		return p.logOutput
+	}
+	return os.Stderr
+}
+
+// getHTTPClient returns the HTTP client, defaulting to http.DefaultClient.
+func (p *LinuxPkgPlugin) getHTTPClient() *http.Client {
+	if p.httpClient != nil {
+		return p.httpClient
+	}
+	return http.DefaultClient
+}
+
 // Config represents the LinuxPkg plugin configuration.
 type Config struct {
-	// ConfigPath is the path to the nfpm.yaml configuration file.
+	// WorkingDir is the directory relative paths are resolved against, in plugin options
+	// and in the nfpm config. Empty uses the process working directory.
+	WorkingDir string
+	// ConfigPath is the path to the nfpm configuration file (YAML, JSON, or TOML).
 	ConfigPath string
-	// Formats is the list of package formats to build (deb, rpm, apk).
+	// Formats is the list of package formats to build (deb, rpm, apk, archlinux, ipk).
 	Formats []string
+	// FormatConfigs holds per-format overrides, keyed by format. Nil when formats is a list.
+	FormatConfigs map[string]*FormatConfig
+	// Distros lists distributions to build per-distro packages for. Formats no distribution
+	// uses are built once without one.
+	Distros []*DistroConfig
+	// Distro is the distribution a per-distro build targets. Only set on the configuration
+	// of a single build.
+	Distro *DistroConfig
+	// Overrides patch the dependency lists of packages per format or distribution.
+	Overrides map[string]DependencyOverride
 	// OutputDir is the directory where packages will be written.
 	OutputDir string
-	// Packager is the tool to use for packaging (nfpm or native).
+	// OutputMode is the octal mode of the packages and other files written to the output
+	// directories.
+	OutputMode string
+	// Umask masks the modes of the files and directories written to the output
+	// directories, unless OutputMode sets the files'.
+	Umask string
+	// Packager is the packaging backend: nfpm (embedded library), nfpm-cli (nfpm binary),
+	// fpm (fpm binary), or container (nfpm in a docker or podman container).
 	Packager string
 	// Target is the target architecture for the packages.
 	Target string
+	// Targets lists architectures to build in one run; when set it takes precedence over Target.
+	Targets []string
+	// Release is the package release (deb revision, rpm Release), a Go template over the
+	// release context such as "{{.RunNumber}}". Empty keeps the nfpm config's release.
+	Release string
+	// FilenameTemplate names packages, e.g. "{name}_{version}_{arch}.{format}". Empty uses nfpm's names.
+	FilenameTemplate string
+	// Epoch is the package epoch for deb, rpm, ipk, and archlinux. 0 keeps the nfpm config's epoch.
+	Epoch int
+	// NormalizeVersion rewrites semver prereleases into each format's native version syntax.
+	NormalizeVersion bool
+	// TemplateConfig renders the nfpm config and overlays as Go templates with the release context.
+	TemplateConfig bool
+	// Scripts maps nfpm maintainer scripts (postinstall, ...) to Go templates rendered with
+	// the release context.
+	Scripts map[string]string
+	// Changelog generates changelogs for deb and rpm packages from the release notes.
+	Changelog *ChangelogConfig
+	// VerifyUnits checks packaged systemd unit files before building.
+	VerifyUnits bool
+	// SystemUser creates a system user and its directories when the package is installed.
+	SystemUser *SystemUserConfig
+	// Manpages are man page sources, roff or Markdown, installed under /usr/share/man.
+	Manpages []string
+	// Desktop packages a desktop entry, AppStream metadata, and icons for GUI applications.
+	Desktop *DesktopConfig
+	// Checks are the checks, such as lintian, rpmlint, and install smoke tests, run on built packages.
+	Checks *ChecksConfig
+	// ConfigOverlays are nfpm config files deep-merged over ConfigPath, in order.
+	ConfigOverlays []string
+	// OverlayListStrategy controls how lists are merged by overlays (replace, append, unique).
+	OverlayListStrategy string
+	// PersistLogs saves the full output of each tool invocation under OutputDir/logs.
+	PersistLogs bool
+	// CompressLogs gzips persisted logs.
+	CompressLogs bool
+	// RespectIgnoreFiles expands content globs itself, excluding paths matched by .gitignore/.nfpmignore.
+	RespectIgnoreFiles bool
+	// MaxTotalSize is the budget for the combined size of all artifacts (e.g. "500MB"). Empty means unlimited.
+	MaxTotalSize string
+	// MaxSize limits the size of each package, per format or for all. Nil means unlimited.
+	MaxSize *MaxSizeConfig
+	// RPMSigning configures signing of RPM packages. Nil disables signing.
+	RPMSigning *RPMSigningConfig
+	// APKKeyPath is the PEM RSA private key used to sign apk packages. Empty disables signing.
+	APKKeyPath string
+	// APKKeyName is the public key name installed under /etc/apk/keys (defaults to <maintainer email>.rsa.pub).
+	APKKeyName string
+	// Checksums lists the algorithms (sha256, sha512) for which a <ALGO>SUMS file is written to OutputDir.
+	Checksums []string
+	// Provenance writes a SLSA provenance statement next to each package.
+	Provenance bool
+	// ProvenanceBuilderID is the builder identity recorded in provenance statements.
+	ProvenanceBuilderID string
+	// Build compiles a Go binary for each target before packaging. Nil disables it.
+	Build *GoBuildConfig
+	// Cosign configures signing every package with cosign. Nil disables it.
+	Cosign *CosignConfig
+	// Publish configures delivering built packages to repositories. Nil disables publishing.
+	Publish *PublishConfig
+	// Concurrency is the number of packages built in parallel. 0 uses one worker per CPU.
+	// Container builds count as two.
+	Concurrency int
+	// Run coordinates this package with the other packages of a monorepo run, set by
+	// executePackages. Nil for a single package.
+	Run *packageRun
+	// FailFast stops starting builds once one has failed. When false, every build runs
+	// and SuccessPolicy decides whether failed builds fail the run.
+	FailFast bool
+	// SuccessPolicy is "any" to succeed when at least one build succeeded, or "all" to
+	// fail when any build failed. It only applies when FailFast is false.
+	SuccessPolicy string
+	// Reproducible builds byte-identical packages from the same commit: the build time is
+	// SOURCE_DATE_EPOCH or the commit date, and every packaged file gets it as mtime.
+	Reproducible bool
+	// VerifyReproducible rebuilds every package in a temporary directory and compares the
+	// digests: "fail" fails the hook when they differ, "warn" only reports it.
+	VerifyReproducible string
+	// SRPM builds a source RPM of every package built as rpm, from a spec generated from
+	// the nfpm config, with rpmbuild.
+	SRPM bool
+	// DebugSymbols splits the debug symbols of packaged ELF files into -dbgsym debs and
+	// -debuginfo rpms.
+	DebugSymbols *DebugSymbolsConfig
+	// Subpackages are packages built from parts of the nfpm config's contents, which
+	// the package leaves out.
+	Subpackages []*SubpackageConfig
+	// Compression sets the compression of deb and rpm packages, as "algorithm[:level]".
+	Compression *CompressionConfig
+	// ConfigFiles are globs of the destinations installed as configuration files that
+	// keep local edits on upgrade.
+	ConfigFiles []string
+	// Ownership sets the owner, group, and mode of the contents matching each glob.
+	Ownership []*OwnershipRule
+	// DebianDocs installs a Debian changelog and machine-readable copyright file into
+	// every deb package.
+	DebianDocs *DebianDocsConfig
+	// DiffAgainst is the directory or https URL, a template like Release, holding the
+	// previous release's packages to compare the built packages with.
+	DiffAgainst string
+	// SkipExisting skips packages a re-run of the release finds already built and
+	// published: their inputs match the build cache and every publish target received
+	// them. Publish targets only receive the packages they are missing.
+	SkipExisting bool
+	// CleanOutput removes artifacts of other versions from the output directories before
+	// building.
+	CleanOutput bool
+	// Cache skips builds whose inputs match a package already in the output directory.
+	Cache bool
+	// MinNfpmVersion is the oldest nfpm release the nfpm-cli packager builds with. Empty
+	// builds with any release.
+	MinNfpmVersion string
+	// NfpmVersion pins the nfpm release the nfpm-cli packager runs, e.g. "v2.41.1". When
+	// the nfpm on PATH is missing or another version, the release is downloaded.
+	NfpmVersion string
+	// NfpmDownloadURL is the base URL of nfpm releases, with a directory per tag.
+	NfpmDownloadURL string
+	// ToolCacheDir keeps downloaded tools between runs. Empty uses the user cache directory.
+	ToolCacheDir string
+	// AllowUnverifiedNfpm runs a downloaded nfpm release whose checksums signature cannot
+	// be verified, because the release is unsigned or cosign is not installed, trusting
+	// its checksums alone.
+	AllowUnverifiedNfpm bool
+	// ToolLockPath is a JSON lock file, relative to the working directory, pinning the
+	// downloaded nfpm and every container image the plugin runs to a digest. Empty pins
+	// nothing beyond the options of each tool.
+	ToolLockPath string
+	// ToolLock is the lock read from ToolLockPath by Execute.
+	ToolLock *ToolLock
+	// NfpmPath is a vendored nfpm binary the nfpm-cli packager runs in place of the one
+	// on PATH, relative to the working directory.
+	NfpmPath string
+	// AllowAbsoluteNfpmPath allows NfpmPath to be an absolute path outside the working
+	// directory.
+	AllowAbsoluteNfpmPath bool
+	// NfpmBinary is the nfpm binary the nfpm-cli packager runs, set by Execute from
+	// NfpmPath or when NfpmVersion is pinned. Empty runs nfpm from PATH.
+	NfpmBinary string
+	// RunOn is the hook packages are built in, pre-publish or post-publish, so a failed
+	// build can block publishing. Empty builds in post-publish.
+	RunOn string
+	// Hooks maps hooks to the actions they run: build, publish, and cleanup. It replaces
+	// RunOn when set.
+	Hooks map[string][]string
+	// CleanupOnError removes the packages built for the release in the on-error hook. It
+	// is off by default, and Hooks replaces it when set.
+	CleanupOnError bool
+	// ReportOnSuccess writes a packaging report of the release in the on-success hook. It
+	// is off by default, and Hooks replaces it when set.
+	ReportOnSuccess bool
+	// Yank also deletes the packages cleanup removes from the GitHub release, S3, and GCS
+	// when they were published there.
+	Yank bool
+	// SkipPrerelease skips prereleases, by release type or version.
+	SkipPrerelease bool
+	// OnlyReleaseTypes limits the plugin to releases of these types: major, minor, patch,
+	// or prerelease. Empty runs for every release.
+	OnlyReleaseTypes []string
+	// OnlyBranches and OnlyTags limit the plugin to releases from branches and tags
+	// matching a glob or a /regular expression/. Empty runs for every branch or tag.
+	OnlyBranches []string
+	OnlyTags     []string
+	// DeferPublish leaves publishing to the publish action, set by Execute when a hook
+	// runs it.
+	DeferPublish bool
+	// Container configures the container packager.
+	Container *ContainerConfig
+	// ContainerRuntime is the runtime binary the container packager runs, set by Execute.
+	ContainerRuntime string
 }
 
 // GetInfo returns plugin metadata.
 func (p *LinuxPkgPlugin) GetInfo() plugin.Info {
 	return plugin.Info{
 		Name:        "linuxpkg",
-		Version:     "2.0.0",
+		Version:     pluginVersion,
 		Description: "Build deb/rpm packages for Linux",
 		Author:      "Relicta Team",
 		Hooks: []plugin.Hook{
+			plugin.HookPrePublish,
 			plugin.HookPostPublish,
+			plugin.HookOnSuccess,
+			plugin.HookOnError,
 		},
-		ConfigSchema: `{
-			"type": "object",
-			"properties": {
-				"config_path": {
-					"type": "string",
-					"description": "Path to nfpm.yaml config file",
-					"default": "nfpm.yaml"
-				},
-				"formats": {
-					"type": "array",
-					"items": {"type": "string", "enum": ["deb", "rpm", "apk"]},
-					"description": "Package formats to build",
-					"default": ["deb", "rpm"]
-				},
-				"output_dir": {
-					"type": "string",
-					"description": "Output directory for packages",
-					"default": "dist"
-				},
-				"packager": {
-					"type": "string",
-					"enum": ["nfpm", "native"],
-					"description": "Tool to use for packaging",
-					"default": "nfpm"
-				},
-				"target": {
-					"type": "string",
-					"description": "Target architecture",
-					"default": "current"
-				}
-			}
-		}`,
+		ConfigSchema: withCapabilities(configSchema, p.capabilities()),
+	}
+}
+
+// runOnHooks are the hooks run_on can select to build packages in.
+var runOnHooks = map[string]plugin.Hook{
+	"pre-publish":  plugin.HookPrePublish,
+	"post-publish": plugin.HookPostPublish,
+}
+
+// parseRunOn returns the hook run_on selects. Underscores may stand in for dashes, as
+// in pre_publish.
+func parseRunOn(runOn string) (plugin.Hook, error) {
+	hook, ok := runOnHooks[strings.ReplaceAll(runOn, "_", "-")]
+	if !ok {
+		return "", fmt.Errorf("unsupported hook: %s (allowed: %s)", runOn, strings.Join(sortedKeys(runOnHooks), ", "))
 	}
+	return hook, nil
 }
 
 // validatePath validates a file path to prevent path traversal attacks.
@@ -157,24 +379,22 @@ func validateFormat(format string) error {
 	}
 
 	if !allowedFormats[format] {
-		return fmt.Errorf("unsupported format: %s (allowed: deb, rpm, apk)", format)
+		return fmt.Errorf("unsupported format: %s (allowed: %s)", format, strings.Join(sortedKeys(allowedFormats), ", "))
 	}
 
 	return nil
 }
 
-// validateArchitecture validates that a target architecture is allowed.
+// validateArchitecture validates that a target architecture, or an alias of one, is
+// allowed.
 func validateArchitecture(arch string) error {
 	if arch == "" || arch == "current" {
 		return nil
 	}
 
-	if !allowedArchitectures[arch] {
-		allowed := make([]string, 0, len(allowedArchitectures))
-		for k := range allowedArchitectures {
-			allowed = append(allowed, k)
-		}
-		return fmt.Errorf("unsupported architecture: %s (allowed: %s)", arch, strings.Join(allowed, ", "))
+	if !allowedArchitectures[canonicalArchitecture(arch)] {
+		return fmt.Errorf("unsupported architecture: %s (allowed: %s; aliases: %s)", arch,
+			strings.Join(sortedKeys(allowedArchitectures), ", "), strings.Join(sortedKeys(architectureAliases), ", "))
 	}
 
 	return nil
@@ -197,17 +417,110 @@ func validateConfigExists(configPath string) error {
 
 // Execute runs the plugin for a given hook.
 func (p *LinuxPkgPlugin) Execute(ctx context.Context, req plugin.ExecuteRequest) (*plugin.ExecuteResponse, error) {
-	cfg := p.parseConfig(req.Config)
+	raw, err := expandConfigEnv(req.Config)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid config: %v", err),
+		}, nil
+	}
+
+	specs, err := parsePackages(raw)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid packages: %v", err),
+		}, nil
+	}
+	if specs != nil {
+		return p.executePackages(ctx, specs, req)
+	}
+	return p.executeConfig(ctx, raw, req, nil)
+}
+
+// executeConfig runs the actions of the hook with raw, an expanded config of one package,
+// as part of run when it is one of several packages.
+func (p *LinuxPkgPlugin) executeConfig(ctx context.Context, raw map[string]any, req plugin.ExecuteRequest, run *packageRun) (*plugin.ExecuteResponse, error) {
+	cfg := p.parseConfig(raw)
+	cfg.Run = run
+
+	if err := validateReleaseTypes(cfg.OnlyReleaseTypes); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid only_release_types: %v", err),
+		}, nil
+	}
+
+	if err := validateRefPatterns(cfg.OnlyBranches); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid only_branches: %v", err),
+		}, nil
+	}
+
+	if err := validateRefPatterns(cfg.OnlyTags); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid only_tags: %v", err),
+		}, nil
+	}
+
+	var actions map[plugin.Hook][]string
+	if cfg.Hooks == nil {
+		runOn, err := parseRunOn(cmp.Or(cfg.RunOn, "post-publish"))
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid run_on: %v", err),
+			}, nil
+		}
+		actions = map[plugin.Hook][]string{runOn: {"build"}}
+		if cfg.ReportOnSuccess {
+			actions[plugin.HookOnSuccess] = []string{"report"}
+		}
+		if cfg.CleanupOnError {
+			actions[plugin.HookOnError] = []string{"cleanup"}
+		}
+	} else {
+		var err error
+		if actions, err = resolveHookActions(cfg.Hooks, cfg.Publish != nil); err == nil && cfg.RunOn != "" {
+			err = fmt.Errorf("hooks cannot be combined with run_on")
+		}
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid hooks: %v", err),
+			}, nil
+		}
+		for _, hookActions := range actions {
+			if slices.Contains(hookActions, "publish") {
+				cfg.DeferPublish = true
+			}
+		}
+	}
 
-	switch req.Hook {
-	case plugin.HookPostPublish:
-		return p.buildPackages(ctx, cfg, req.Context, req.DryRun)
-	default:
+	if len(actions[req.Hook]) == 0 {
 		return &plugin.ExecuteResponse{
 			Success: true,
 			Message: fmt.Sprintf("Hook %s not handled", req.Hook),
 		}, nil
 	}
+
+	if reason := skipReason(cfg, req.Context); reason != "" {
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: "Skipped: " + reason,
+			Outputs: map[string]any{
+				"skipped":     true,
+				"skip_reason": reason,
+			},
+		}, nil
+	}
+	// Only builds overlap with other packages; the build action waits its turn itself.
+	if !slices.Contains(actions[req.Hook], "build") && !cfg.Run.awaitTurn() {
+		return packageRunStopped(), nil
+	}
+	return p.runHookActions(ctx, cfg, actions[req.Hook], req)
 }
 
 // buildPackages builds Linux packages using nfpm.
@@ -227,184 +540,1834 @@ func (p *LinuxPkgPlugin) buildPackages(ctx context.Context, cfg *Config, release
 		}, nil
 	}
 
-	// Validate formats.
-	for _, format := range cfg.Formats {
-		if err := validateFormat(format); err != nil {
+	for _, overlay := range cfg.ConfigOverlays {
+		if err := validatePath(overlay); err != nil {
 			return &plugin.ExecuteResponse{
 				Success: false,
-				Error:   fmt.Sprintf("invalid format: %v", err),
+				Error:   fmt.Sprintf("invalid config_overlays: %v", err),
 			}, nil
 		}
 	}
 
-	// Validate target architecture.
-	if err := validateArchitecture(cfg.Target); err != nil {
+	if err := validateManpages(cfg.Manpages); err != nil {
 		return &plugin.ExecuteResponse{
 			Success: false,
-			Error:   fmt.Sprintf("invalid target: %v", err),
+			Error:   fmt.Sprintf("invalid manpages: %v", err),
 		}, nil
 	}
 
-	// Resolve target architecture.
-	targetArch := cfg.Target
-	if targetArch == "" || targetArch == "current" {
-		targetArch = runtime.GOARCH
+	for _, name := range sortedKeys(cfg.Scripts) {
+		if !maintainerScripts[name] {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid scripts: unknown script %q", name),
+			}, nil
+		}
+		if err := validatePath(cfg.Scripts[name]); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid scripts: %s: %v", name, err),
+			}, nil
+		}
 	}
 
-	// Handle dry run.
-	if dryRun {
+	if err := validateListStrategy(cfg.OverlayListStrategy); err != nil {
 		return &plugin.ExecuteResponse{
-			Success: true,
-			Message: fmt.Sprintf("Would build %d package(s) using %s", len(cfg.Formats), cfg.Packager),
-			Outputs: map[string]any{
-				"config_path": cfg.ConfigPath,
-				"formats":     cfg.Formats,
-				"output_dir":  cfg.OutputDir,
-				"packager":    cfg.Packager,
-				"target":      targetArch,
-				"version":     releaseCtx.Version,
-			},
+			Success: false,
+			Error:   fmt.Sprintf("invalid overlay_list_strategy: %v", err),
 		}, nil
 	}
 
-	// Validate config file exists (only for actual execution).
-	if err := validateConfigExists(cfg.ConfigPath); err != nil {
+	maxTotalSize, err := parseSize(cfg.MaxTotalSize)
+	if err != nil {
 		return &plugin.ExecuteResponse{
 			Success: false,
-			Error:   err.Error(),
+			Error:   fmt.Sprintf("invalid max_total_size: %v", err),
 		}, nil
 	}
 
-	// Create output directory if it doesn't exist.
-	if err := os.MkdirAll(cfg.OutputDir, 0755); err != nil {
+	if cfg.MaxSize != nil {
+		if err := cfg.MaxSize.validate(); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid max_size: %v", err),
+			}, nil
+		}
+	}
+
+	if cfg.RPMSigning != nil {
+		if err := cfg.RPMSigning.validate(); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid rpm_signing: %v", err),
+			}, nil
+		}
+	}
+
+	if err := validatePath(cfg.APKKeyPath); err != nil {
 		return &plugin.ExecuteResponse{
 			Success: false,
-			Error:   fmt.Sprintf("failed to create output directory: %v", err),
+			Error:   fmt.Sprintf("invalid apk_key_path: %v", err),
 		}, nil
 	}
 
-	// Build packages for each format.
-	builtPackages := make([]string, 0, len(cfg.Formats))
-	executor := p.getExecutor()
+	if err := validateAPKKeyName(cfg.APKKeyName); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid apk_key_name: %v", err),
+		}, nil
+	}
 
-	for _, format := range cfg.Formats {
-		output, err := p.buildPackage(ctx, executor, cfg, format, targetArch)
-		if err != nil {
+	if cfg.Build != nil {
+		if err := cfg.Build.validate(); err != nil {
 			return &plugin.ExecuteResponse{
 				Success: false,
-				Error:   fmt.Sprintf("failed to build %s package: %v\nOutput: %s", format, err, string(output)),
+				Error:   fmt.Sprintf("invalid build: %v", err),
 			}, nil
 		}
+	}
 
-		// Parse the output to get the package filename.
-		packagePath := p.parsePackagePath(output, cfg.OutputDir, format)
-		if packagePath != "" {
-			builtPackages = append(builtPackages, packagePath)
-		} else {
-			// Fallback: construct expected package name.
-			builtPackages = append(builtPackages, filepath.Join(cfg.OutputDir, fmt.Sprintf("package.%s", format)))
+	if cfg.Cosign != nil {
+		if err := cfg.Cosign.validate(); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid cosign: %v", err),
+			}, nil
 		}
 	}
 
-	return &plugin.ExecuteResponse{
-		Success: true,
-		Message: fmt.Sprintf("Built %d Linux package(s)", len(builtPackages)),
-		Outputs: map[string]any{
-			"packages":   builtPackages,
-			"formats":    cfg.Formats,
-			"output_dir": cfg.OutputDir,
-			"target":     targetArch,
-			"version":    releaseCtx.Version,
-		},
-	}, nil
-}
+	if cfg.Publish != nil {
+		if err := cfg.Publish.validate(); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid publish: %v", err),
+			}, nil
+		}
+	}
 
-// buildPackage builds a single package using nfpm.
-func (p *LinuxPkgPlugin) buildPackage(ctx context.Context, executor CommandExecutor, cfg *Config, format, targetArch string) ([]byte, error) {
-	args := []string{
-		"package",
-		"--config", cfg.ConfigPath,
-		"--packager", format,
-		"--target", cfg.OutputDir + "/",
+	if err := cfg.Container.validate(); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid container: %v", err),
+		}, nil
 	}
 
-	return executor.Run(ctx, "nfpm", args...)
-}
+	if err := validateConcurrency(cfg.Concurrency); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid concurrency: %v", err),
+		}, nil
+	}
 
-// parsePackagePath attempts to parse the package path from nfpm output.
-func (p *LinuxPkgPlugin) parsePackagePath(output []byte, outputDir, format string) string {
-	// nfpm typically outputs: "created package: <path>"
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "created package:") {
-			parts := strings.SplitN(line, ":", 2)
-			if len(parts) == 2 {
-				return strings.TrimSpace(parts[1])
-			}
-		}
-		// Also check for "using" pattern from some nfpm versions.
-		if strings.Contains(line, "."+format) && strings.Contains(line, outputDir) {
-			return line
+	if err := validateNfpmVersion(cfg.NfpmVersion, cfg.Packager); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid nfpm_version: %v", err),
+		}, nil
+	}
+
+	if err := validateNfpmPath(cfg.NfpmPath, cfg.AllowAbsoluteNfpmPath, cfg.Packager, cfg.NfpmVersion); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid nfpm_path: %v", err),
+		}, nil
+	}
+
+	if err := validateNfpmDownloadURL(cfg.NfpmDownloadURL); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid nfpm_download_url: %v", err),
+		}, nil
+	}
+
+	if err := validateAllowUnverifiedNfpm(cfg.AllowUnverifiedNfpm, cfg.NfpmVersion); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid allow_unverified_nfpm: %v", err),
+		}, nil
+	}
+
+	if err := validatePath(cfg.ToolLockPath); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid tool_lock: %v", err),
+		}, nil
+	}
+
+	if cfg.MinNfpmVersion != "" {
+		if err := validateMinNfpmVersion(cfg.MinNfpmVersion); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid min_nfpm_version: %v", err),
+			}, nil
 		}
 	}
-	return ""
-}
 
-// parseConfig parses the raw configuration into a Config struct.
-func (p *LinuxPkgPlugin) parseConfig(raw map[string]any) *Config {
-	parser := helpers.NewConfigParser(raw)
+	if err := validateSuccessPolicy(cfg.SuccessPolicy); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid success_policy: %v", err),
+		}, nil
+	}
 
-	// Parse formats with default.
-	formats := parser.GetStringSlice("formats", []string{"deb", "rpm"})
-	if len(formats) == 0 {
-		formats = []string{"deb", "rpm"}
+	if err := validateSRPM(cfg.SRPM, cfg.Formats); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid srpm: %v", err),
+		}, nil
 	}
 
-	return &Config{
-		ConfigPath: parser.GetString("config_path", "", "nfpm.yaml"),
-		Formats:    formats,
-		OutputDir:  parser.GetString("output_dir", "", "dist"),
-		Packager:   parser.GetString("packager", "", "nfpm"),
-		Target:     parser.GetString("target", "", "current"),
+	if cfg.DebugSymbols != nil {
+		if err := cfg.DebugSymbols.validate(cfg.Formats, cfg.FilenameTemplate); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid debug_symbols: %v", err),
+			}, nil
+		}
 	}
-}
 
-// Validate validates the plugin configuration.
-func (p *LinuxPkgPlugin) Validate(_ context.Context, config map[string]any) (*plugin.ValidateResponse, error) {
-	vb := helpers.NewValidationBuilder()
-	parser := helpers.NewConfigParser(config)
+	if err := validateSubpackages(cfg.Subpackages, cfg.FilenameTemplate); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid subpackages: %v", err),
+		}, nil
+	}
 
-	// Validate config_path.
-	configPath := parser.GetString("config_path", "", "nfpm.yaml")
-	if err := validatePath(configPath); err != nil {
-		vb.AddError("config_path", err.Error())
+	if err := validateConfigFiles(cfg.ConfigFiles); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid config_files: %v", err),
+		}, nil
 	}
 
-	// Validate output_dir.
-	outputDir := parser.GetString("output_dir", "", "dist")
-	if err := validatePath(outputDir); err != nil {
-		vb.AddError("output_dir", err.Error())
+	if err := validateOwnership(cfg.Ownership, cfg.Packager, cfg.Formats); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid ownership: %v", err),
+		}, nil
 	}
 
-	// Validate formats.
-	formats := parser.GetStringSlice("formats", []string{"deb", "rpm"})
-	for _, format := range formats {
-		if err := validateFormat(format); err != nil {
-			vb.AddError("formats", err.Error())
+	if cfg.DebianDocs != nil {
+		if err := cfg.DebianDocs.validate(cfg.Formats); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid debian_docs: %v", err),
+			}, nil
 		}
 	}
 
-	// Validate target architecture.
-	target := parser.GetString("target", "", "current")
-	if err := validateArchitecture(target); err != nil {
-		vb.AddError("target", err.Error())
+	if err := validateDiffAgainst(cfg.DiffAgainst); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid diff_against: %v", err),
+		}, nil
 	}
 
-	// Validate packager.
-	packager := parser.GetString("packager", "", "nfpm")
-	if packager != "nfpm" && packager != "native" {
-		vb.AddError("packager", "packager must be 'nfpm' or 'native'")
+	if err := validateVerifyReproducible(cfg.VerifyReproducible); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid verify_reproducible: %v", err),
+		}, nil
+	}
+
+	if err := validateFilenameTemplate(cfg.FilenameTemplate); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid filename_template: %v", err),
+		}, nil
+	}
+
+	if err := validateEpoch(cfg.Epoch); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid epoch: %v", err),
+		}, nil
+	}
+
+	for _, algorithm := range cfg.Checksums {
+		if err := validateChecksumAlgorithm(algorithm); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid checksums: %v", err),
+			}, nil
+		}
+	}
+
+	if err := validateOutputModes(cfg.OutputMode, cfg.Umask); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	// Validate formats.
+	for _, format := range cfg.Formats {
+		if err := validateFormat(format); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid format: %v", err),
+			}, nil
+		}
+		if err := validatePackagerFormat(cfg.Packager, format); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid format: %v", err),
+			}, nil
+		}
+		formatCfg := cfg.forFormat(format)
+		if err := validatePath(formatCfg.ConfigPath); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid formats: %s: invalid config_path: %v", format, err),
+			}, nil
+		}
+		if err := validatePath(formatCfg.OutputDir); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid formats: %s: invalid output_dir: %v", format, err),
+			}, nil
+		}
+	}
+
+	if cfg.SystemUser != nil {
+		if err := cfg.SystemUser.validate(); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid system_user: %v", err),
+			}, nil
+		}
+	}
+
+	if cfg.Desktop != nil {
+		if err := cfg.Desktop.validate(); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid desktop: %v", err),
+			}, nil
+		}
+	}
+
+	if cfg.Checks != nil {
+		if err := cfg.Checks.validate(); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid checks: %v", err),
+			}, nil
+		}
+	}
+
+	if cfg.Changelog != nil {
+		if err := cfg.Changelog.validate(); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid changelog: %v", err),
+			}, nil
+		}
+	}
+
+	if err := validateDistros(cfg.Distros); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid distros: %v", err),
+		}, nil
+	}
+	if err := validateCompressions(cfg.Compression, cfg.Distros, cfg.Packager); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid compression: %v", err),
+		}, nil
+	}
+	units := buildUnits(cfg)
+
+	for _, name := range sortedKeys(cfg.Overrides) {
+		if err := validateOverrideTarget(name, cfg.Distros); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid overrides: %v", err),
+			}, nil
+		}
+	}
+
+	// Validate and resolve target architectures.
+	targets, err := resolveTargets(cfg)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid target: %v", err),
+		}, nil
+	}
+	archs := targetArchs(targets)
+
+	if err := validateFilenameMatrix(cfg.FilenameTemplate, len(cfg.Formats), len(targets), armVariants(targets)); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   fmt.Sprintf("invalid filename_template: %v", err),
+		}, nil
+	}
+
+	buildTime := time.Now()
+	if cfg.Reproducible {
+		if buildTime, err = p.sourceDate(ctx, cfg, releaseCtx); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("reproducible: %v", err),
+			}, nil
+		}
+	}
+	templateData := newNfpmTemplateData(releaseCtx, buildTime)
+	if cfg.Reproducible {
+		templateData.SourceDateEpoch = strconv.FormatInt(buildTime.Unix(), 10)
+	}
+	if cfg.Release != "" {
+		if templateData.Release, err = renderPackageRelease(cfg.Release, templateData); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid release: %v", err),
+			}, nil
+		}
+	}
+
+	ldflags := ""
+	if cfg.Build != nil {
+		if ldflags, err = cfg.Build.renderLdflags(templateData); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid build: invalid ldflags: %v", err),
+			}, nil
+		}
+	}
+
+	// Resolve relative paths against the working directory from here on.
+	if cfg.WorkingDir != "" {
+		if cfg.WorkingDir, err = resolveWorkingDir(cfg.WorkingDir); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid working_dir: %v", err),
+			}, nil
+		}
+		cfg.applyWorkingDir()
+	}
+
+	// Pin the downloaded nfpm and every container image to the locked digests.
+	if cfg.ToolLockPath != "" {
+		if cfg.ToolLock, err = loadToolLock(cfg.ToolLockPath); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid tool_lock: %v", err),
+			}, nil
+		}
+		if cfg.Packager == "container" {
+			image, err := cfg.ToolLock.pinImage(cfg.Container.imageRef())
+			if err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   fmt.Sprintf("invalid tool_lock: %v", err),
+				}, nil
+			}
+			cfg.Container.Image, cfg.Container.Digest = image, ""
+		}
+		if cfg.Checks != nil && cfg.Checks.Install != nil {
+			cfg.Checks.Install.Lock = cfg.ToolLock
+		}
+	}
+
+	if cfg.Packager == "nfpm-cli" && cfg.NfpmPath != "" {
+		if cfg.NfpmBinary, err = checkNfpmPath(cfg.NfpmPath); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid nfpm_path: %v", err),
+			}, nil
+		}
+	}
+
+	outputDirs := []string{cfg.OutputDir}
+	for _, unit := range units {
+		outputDirs = append(outputDirs, cfg.forUnit(unit).OutputDir)
+	}
+
+	// Handle dry run.
+	if dryRun {
+		extensions := make(map[string]string, len(cfg.Formats))
+		for _, format := range cfg.Formats {
+			extensions[format] = packageExtensions[format]
+		}
+
+		outputs := map[string]any{
+			"config_path":        cfg.ConfigPath,
+			"config_overlays":    cfg.ConfigOverlays,
+			"formats":            cfg.Formats,
+			"package_extensions": extensions,
+			"output_dir":         cfg.OutputDir,
+			"packager":           cfg.Packager,
+			"target":             archs[0],
+			"targets":            archs,
+			"version":            releaseCtx.Version,
+		}
+		if cfg.Release != "" {
+			outputs["release"] = templateData.Release
+		}
+		if cfg.Build != nil {
+			binaries := make([]string, len(targets))
+			for i, target := range targets {
+				binaries[i] = cfg.Build.binaryPath(cfg.OutputDir, target)
+			}
+			outputs["build"] = map[string]any{
+				"main":     cfg.Build.Main,
+				"ldflags":  ldflags,
+				"binaries": binaries,
+			}
+		}
+		if len(cfg.Distros) > 0 {
+			distros := make([]map[string]any, 0, len(cfg.Distros))
+			for _, distro := range cfg.Distros {
+				distros = append(distros, map[string]any{
+					"name":       distro.Name,
+					"tag":        distro.Tag,
+					"formats":    distro.Formats,
+					"output_dir": cfg.forUnit(buildUnit{Format: distro.Formats[0], Distro: distro}).OutputDir,
+				})
+			}
+			outputs["distros"] = distros
+		}
+		if cfg.CleanOutput {
+			stale, err := cfg.staleArtifacts(releaseCtx.Version, outputDirs)
+			if err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   fmt.Sprintf("failed to clean output_dir: %v", err),
+				}, nil
+			}
+			outputs["stale_files"] = stale
+		}
+		outputs["commands"] = dryRunCommands(cfg, units, targets, ldflags, templateData.env(), releaseCtx)
+
+		// Render the nfpm configs once they exist, so template and override mistakes show
+		// up before the release.
+		configsExist := true
+		for _, format := range cfg.Formats {
+			if validateConfigExists(cfg.forFormat(format).ConfigPath) != nil {
+				configsExist = false
+			}
+		}
+		if configsExist {
+			previews, err := previewNfpmConfigs(ctx, cfg, units, targets, templateData, templateData.env())
+			if err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   err.Error(),
+				}, nil
+			}
+			outputs["nfpm_configs"] = previews
+		}
+		if cfg.FormatConfigs != nil {
+			formatConfigs := make(map[string]any, len(cfg.Formats))
+			for _, format := range cfg.Formats {
+				formatCfg := cfg.forFormat(format)
+				formatConfigs[format] = map[string]any{
+					"config_path": formatCfg.ConfigPath,
+					"output_dir":  formatCfg.OutputDir,
+				}
+			}
+			outputs["format_configs"] = formatConfigs
+		}
+
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: fmt.Sprintf("Would build %d package(s) using %s (%s)",
+				len(units)*len(targets), cfg.Packager, matrixSummary(len(cfg.Formats), len(targets))),
+			Outputs: outputs,
+		}, nil
+	}
+
+	started := time.Now()
+
+	// Validate config files exist (only for actual execution).
+	for _, format := range cfg.Formats {
+		if err := validateConfigExists(cfg.forFormat(format).ConfigPath); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+	}
+
+	for _, overlay := range cfg.ConfigOverlays {
+		if err := validateConfigExists(overlay); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid config_overlays: %v", err),
+			}, nil
+		}
+	}
+
+	for _, manpage := range cfg.Manpages {
+		if err := validateConfigExists(manpage); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid manpages: %v", err),
+			}, nil
+		}
+	}
+
+	if cfg.Checks != nil && cfg.Checks.Rpmlint != nil && cfg.Checks.Rpmlint.Rpmlintrc != "" {
+		if err := validateConfigExists(cfg.Checks.Rpmlint.Rpmlintrc); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid checks: rpmlint: %v", err),
+			}, nil
+		}
+	}
+
+	if cfg.DebianDocs != nil && cfg.DebianDocs.LicenseFile != "" {
+		if err := validateConfigExists(cfg.DebianDocs.LicenseFile); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid debian_docs: %v", err),
+			}, nil
+		}
+	}
+
+	if cfg.Desktop != nil {
+		for _, file := range cfg.Desktop.files() {
+			if err := validateConfigExists(file); err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   fmt.Sprintf("invalid desktop: %v", err),
+				}, nil
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(cfg.Scripts) {
+		if err := validateConfigExists(cfg.Scripts[name]); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid scripts: %s: %v", name, err),
+			}, nil
+		}
+	}
+
+	for _, distro := range cfg.Distros {
+		for _, overlay := range distro.ConfigOverlays {
+			if err := validateConfigExists(overlay); err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   fmt.Sprintf("invalid distros: %s: %v", distro.Name, err),
+				}, nil
+			}
+		}
+	}
+
+	apkFingerprint := ""
+	if cfg.APKKeyPath != "" {
+		if err := checkAPKKey(cfg.APKKeyPath); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid apk_key_path: %v", err),
+			}, nil
+		}
+		if apkFingerprint, err = apkKeyFingerprint(cfg.APKKeyPath); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("invalid apk_key_path: %v", err),
+			}, nil
+		}
+	}
+
+	// Remove artifacts left over from other versions.
+	var cleaned []string
+	if cfg.CleanOutput {
+		stale, err := cfg.staleArtifacts(releaseCtx.Version, outputDirs)
+		if err == nil {
+			cleaned, err = removeFiles(stale)
+		}
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to clean output_dir: %v", err),
+			}, nil
+		}
+	}
+
+	// Create output directories if they don't exist.
+	for _, dir := range outputDirs {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to create output directory: %v", err),
+			}, nil
+		}
+	}
+
+	// Build every format, per distribution where configured, for every target architecture.
+	builds := len(units) * len(targets)
+	builtPackages := make([]string, 0, builds)
+	artifacts := make([]map[string]any, 0, builds)
+	logs := make([]string, 0, builds)
+	executor := p.getExecutor()
+
+	if cfg.Packager == "nfpm-cli" && cfg.NfpmVersion != "" {
+		if cfg.NfpmBinary, err = p.ensureNfpm(ctx, executor, cfg); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to get nfpm %s: %v", cfg.NfpmVersion, err),
+			}, nil
+		}
+	}
+
+	if cfg.Packager == "container" {
+		if cfg.ContainerRuntime, err = p.resolveRuntime(cfg.Container); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+	}
+
+	// Old nfpm releases silently ignore config keys they do not know, so refuse them.
+	if cfg.Packager == "nfpm-cli" && cfg.MinNfpmVersion != "" {
+		if err := checkNfpmVersion(ctx, executor, cfg.nfpmBinary(), cfg.MinNfpmVersion); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+	}
+
+	var provenance *provenanceContext
+	provenanceFiles := make([]string, 0)
+	signatures := make([]string, 0)
+	if cfg.Provenance {
+		configDigest, err := fileDigest(cfg.ConfigPath, "sha256")
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+		provenance = &provenanceContext{
+			BuilderID:    cfg.ProvenanceBuilderID,
+			Packager:     cfg.Packager,
+			NfpmVersion:  p.nfpmVersion(ctx, executor, cfg),
+			ConfigPath:   cfg.ConfigPath,
+			ConfigDigest: configDigest,
+			Release:      releaseCtx,
+			StartedOn:    time.Now().UTC(),
+		}
+	}
+
+	// Prepared nfpm configs keyed by source config, distribution, and the arch written into
+	// them ("" keeps the config's arch), and finalized configs keyed by prepared config,
+	// format, and distribution.
+	nfpmConfigs := make(map[string]string)
+	var cleanups []func()
+	defer func() {
+		for _, cleanup := range cleanups {
+			cleanup()
+		}
+	}()
+
+	releaseEnv := templateData.env()
+	fetcher := newRemoteFetcher(p.getHTTPClient())
+	cleanups = append(cleanups, fetcher.cleanup)
+
+	jobs := make([]buildJob, 0, builds)
+	for _, target := range targets {
+		// Compile the binary for this target first; nfpm configs reference it as ${BINARY}.
+		targetEnv := releaseEnv
+		if cfg.Build != nil {
+			binary, output, err := buildGoBinary(ctx, executor, cfg.Build, cfg.WorkingDir, cfg.OutputDir, target, ldflags)
+			if err != nil {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   fmt.Sprintf("failed to build Go binary for %s: %v\nOutput: %s", target.Arch, err, string(output)),
+				}, nil
+			}
+			targetEnv = append(slices.Clip(releaseEnv), goBinaryEnv+"="+binary)
+		}
+
+		// The embedded backend overrides the arch directly; nfpm-cli needs it in the config.
+		configArch := ""
+		if target.Override && !usesEmbeddedNfpm(cfg.Packager) {
+			configArch = target.nfpmArch()
+		}
+
+		for _, unit := range units {
+			format, unitCfg := unit.Format, cfg.forUnit(unit)
+			key := unitCfg.ConfigPath + "\x00" + unitCfg.distroName() + "\x00" + configArch
+			nfpmConfigPath, ok := nfpmConfigs[key]
+			if !ok {
+				// Resolve overlays and convert JSON/TOML configs into a form nfpm can read.
+				path, cleanup, err := prepareNfpmConfig(unitCfg, configArch, templateData)
+				if err != nil {
+					return &plugin.ExecuteResponse{
+						Success: false,
+						Error:   err.Error(),
+					}, nil
+				}
+				cleanups = append(cleanups, cleanup)
+				nfpmConfigs[key] = path
+				nfpmConfigPath = path
+			}
+
+			key = nfpmConfigPath + "\x00" + format + "\x00" + unitCfg.distroName()
+			finalPath, ok := nfpmConfigs[key]
+			if !ok {
+				// Normalize the version, tag the release, patch dependencies, and download
+				// remote contents for this format and distribution.
+				path, cleanup, err := finalizeNfpmConfig(ctx, nfpmConfigPath, format, unitCfg, templateData, envLookup(targetEnv, os.Getenv), fetcher)
+				if err != nil {
+					return &plugin.ExecuteResponse{
+						Success: false,
+						Error:   err.Error(),
+					}, nil
+				}
+				cleanups = append(cleanups, cleanup)
+				nfpmConfigs[key] = path
+				finalPath = path
+			}
+
+			formatJobs := []buildJob{{Format: format, Target: target, Config: unitCfg, ConfigPath: finalPath, Env: targetEnv, Version: releaseCtx.Version}}
+			if len(unitCfg.Subpackages) > 0 {
+				// Move the contents of subpackages into packages of their own.
+				split, cleanup, err := splitSubpackages(formatJobs[0], unitCfg.Subpackages)
+				if err != nil {
+					return &plugin.ExecuteResponse{
+						Success: false,
+						Error:   fmt.Sprintf("failed to split subpackages: %v", err),
+					}, nil
+				}
+				cleanups = append(cleanups, cleanup)
+				formatJobs = split
+			}
+			for _, job := range formatJobs {
+				split := []buildJob{job}
+				if unitCfg.DebugSymbols != nil && debugPackageSuffixes[format] != "" {
+					// Strip the packaged ELF files and build their debug symbols as a
+					// companion package.
+					var cleanup func()
+					var err error
+					split, cleanup, err = p.splitDebugSymbols(ctx, executor, unitCfg.DebugSymbols, job)
+					if err != nil {
+						return &plugin.ExecuteResponse{
+							Success: false,
+							Error:   fmt.Sprintf("failed to split debug symbols: %v", err),
+						}, nil
+					}
+					cleanups = append(cleanups, cleanup)
+				}
+				for _, job := range split {
+					if unitCfg.DebianDocs != nil && format == "deb" {
+						// Every deb, including split ones, gets a copyright file of its own.
+						withCopyright, cleanup, err := addDebianCopyright(job, unitCfg.DebianDocs, templateData)
+						if err != nil {
+							return &plugin.ExecuteResponse{
+								Success: false,
+								Error:   fmt.Sprintf("failed to add copyright file: %v", err),
+							}, nil
+						}
+						cleanups = append(cleanups, cleanup)
+						job = withCopyright
+					}
+					jobs = append(jobs, job)
+				}
+			}
+		}
+	}
+
+	// Check packaged systemd units before building anything.
+	if cfg.VerifyUnits {
+		if err := verifySystemdUnits(jobs); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+			}, nil
+		}
+	}
+
+	var cache *buildCache
+	if cfg.Cache || cfg.SkipExisting {
+		cache = loadBuildCache(cfg, releaseCtx.Version, releaseCtx.CommitSHA)
+	}
+
+	cached, failed := 0, 0
+	outcomes := p.runBuildJobs(ctx, executor, cfg, jobs, provenance, cache)
+	if !cfg.Run.awaitTurn() {
+		return packageRunStopped(), nil
+	}
+	for i, outcome := range outcomes {
+		if outcome == nil {
+			continue
+		}
+		if outcome.Log != "" {
+			logs = append(logs, outcome.Log)
+		}
+		if outcome.Err != nil {
+			if !cfg.FailFast {
+				failed++
+				continue
+			}
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   outcome.Err.Error(),
+				Outputs: partialOutputs(jobs, outcomes),
+			}, nil
+		}
+
+		artifact := outcome.Artifact
+		builtPackages = append(builtPackages, artifact["path"].(string))
+		if path, ok := artifact["provenance"].(string); ok {
+			provenanceFiles = append(provenanceFiles, path)
+		}
+		if path, ok := artifact["signature"].(string); ok {
+			signatures = append(signatures, path)
+		}
+		if artifact["cached"] == true {
+			cached++
+		}
+		if cache != nil {
+			cache.record(jobs[i], outcome.Inputs, artifact)
+		}
+		artifacts = append(artifacts, artifact)
+	}
+
+	// Without fail_fast, the run goes on with the packages that were built unless the
+	// success policy says otherwise.
+	if failed > 0 && (len(artifacts) == 0 || cfg.SuccessPolicy == "all") {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   buildFailureSummary(jobs, outcomes, failed),
+			Outputs: partialOutputs(jobs, outcomes),
+		}, nil
+	}
+
+	// Later failures still report what was built.
+	partial := func(outputs map[string]any) map[string]any {
+		result := partialOutputs(jobs, outcomes)
+		maps.Copy(result, outputs)
+		return result
+	}
+
+	if cache != nil {
+		if err := cache.save(); err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+				Outputs: partialOutputs(jobs, outcomes),
+			}, nil
+		}
+	}
+
+	if cfg.SRPM {
+		srpms, err := p.buildSRPMs(ctx, executor, jobs, outcomes)
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to build source RPM: %v", err),
+				Outputs: partialOutputs(jobs, outcomes),
+			}, nil
+		}
+		for _, artifact := range srpms {
+			builtPackages = append(builtPackages, artifact["path"].(string))
+			artifacts = append(artifacts, artifact)
+		}
+	}
+
+	var reproducibility []map[string]any
+	var reproducibilityWarnings []string
+	if cfg.VerifyReproducible != "" {
+		var differing []string
+		reproducibility, differing, err = p.verifyReproducible(ctx, executor, jobs, outcomes)
+		if err == nil && len(differing) > 0 {
+			err = fmt.Errorf("%d package(s) are not reproducible: %s", len(differing), strings.Join(differing, ", "))
+			if cfg.VerifyReproducible == "warn" {
+				reproducibilityWarnings = append(reproducibilityWarnings, err.Error())
+				err = nil
+			}
+		}
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   err.Error(),
+				Outputs: partial(map[string]any{
+					"reproducibility": reproducibility,
+				}),
+			}, nil
+		}
+	}
+
+	checkResults, checkWarnings, err := p.runChecks(ctx, executor, cfg.Checks, cfg.Formats, artifacts)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+			Outputs: partial(map[string]any{
+				"checks": checkResults,
+			}),
+		}, nil
+	}
+	checkWarnings = append(reproducibilityWarnings, checkWarnings...)
+
+	totalSize, err := totalArtifactSize(builtPackages)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+			Outputs: partialOutputs(jobs, outcomes),
+		}, nil
+	}
+
+	if maxTotalSize > 0 && totalSize > maxTotalSize {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error: fmt.Sprintf("total artifact size %s exceeds max_total_size %s",
+				formatSize(totalSize), formatSize(maxTotalSize)),
+			Outputs: partial(map[string]any{
+				"total_size": totalSize,
+			}),
+		}, nil
+	}
+
+	if cfg.MaxSize != nil {
+		if oversized := cfg.MaxSize.oversized(artifacts); len(oversized) > 0 {
+			problem := fmt.Sprintf("%d package(s) exceed max_size: %s", len(oversized), strings.Join(oversized, "; "))
+			if cfg.MaxSize.OnViolation == "fail" {
+				return &plugin.ExecuteResponse{
+					Success: false,
+					Error:   problem,
+					Outputs: partial(map[string]any{
+						"total_size": totalSize,
+					}),
+				}, nil
+			}
+			checkWarnings = append([]string{problem}, checkWarnings...)
+		}
+	}
+
+	checksumFiles, err := writeChecksumFiles(cfg.OutputDir, builtPackages, cfg.Checksums)
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+			Outputs: partialOutputs(jobs, outcomes),
+		}, nil
+	}
+
+	// Comparing with the previous release only informs reviewers, so it never fails the
+	// build.
+	var diffs []packageDiff
+	if cfg.DiffAgainst != "" && releaseCtx.PreviousVersion != "" {
+		location, err := renderDiffAgainst(cfg.DiffAgainst, cfg.WorkingDir, templateData)
+		if err == nil {
+			diffs, err = p.diffPackages(ctx, location, artifacts)
+		}
+		if err != nil {
+			checkWarnings = append(checkWarnings, fmt.Sprintf("failed to diff against the previous release: %v", err))
+		}
+	}
+
+	// Later hooks publish or remove what this build recorded.
+	record := releaseRecord{
+		Version:         releaseCtx.Version,
+		Artifacts:       artifacts,
+		ChecksumFiles:   checksumFiles,
+		Diff:            diffs,
+		Builds:          buildResults(jobs, outcomes),
+		BuildDurationMS: time.Since(started).Milliseconds(),
+	}
+	skipped := 0
+	if cfg.SkipExisting {
+		record.Published, record.Delivered = previousDeliveries(cfg.OutputDir, releaseCtx.Version)
+		skipped = markSkipped(artifacts, cfg.Publish, record.Delivered)
+	}
+	if err := writeReleaseRecord(cfg.OutputDir, record); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+			Outputs: partialOutputs(jobs, outcomes),
+		}, nil
+	}
+
+	// Set the modes of everything written to the output directories. Files rewritten
+	// later keep them.
+	emitted := slices.Concat(checksumFiles, logs, []string{filepath.Join(cfg.OutputDir, releaseRecordName)})
+	for _, artifact := range artifacts {
+		for _, key := range []string{"path", "provenance", "signature", "certificate", "bundle"} {
+			if path, _ := artifact[key].(string); path != "" {
+				emitted = append(emitted, path)
+			}
+		}
+	}
+	if err := cfg.applyOutputModes(outputDirs, emitted); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+			Outputs: partialOutputs(jobs, outcomes),
+		}, nil
+	}
+
+	published := make(map[string]any)
+	if cfg.Publish != nil && !cfg.DeferPublish {
+		publishExecutor := executor
+		var publishLog *loggingExecutor
+		if cfg.PersistLogs {
+			publishLog = &loggingExecutor{executor: executor}
+			publishExecutor = publishLog
+		}
+		publishStarted := time.Now()
+		if cfg.SkipExisting {
+			published, err = p.publishNew(ctx, publishExecutor, cfg.Publish, artifacts, checksumFiles, releaseCtx, record.Published, record.Delivered)
+		} else {
+			published, err = p.publishPackages(ctx, publishExecutor, cfg.Publish, artifacts, checksumFiles, releaseCtx)
+		}
+		// Record what was uploaded, even by a failed publish, so cleanup can yank it.
+		record.Published = published
+		record.PublishDurationMS = time.Since(publishStarted).Milliseconds()
+		if recordErr := writeReleaseRecord(cfg.OutputDir, record); err == nil {
+			err = recordErr
+		}
+		if publishLog != nil {
+			logPath, logErr := writeToolLog(cfg.OutputDir, publishLogName, publishLog.output(), cfg.CompressLogs)
+			if logErr == nil {
+				logs = append(logs, logPath)
+				logErr = cfg.applyOutputModes(nil, []string{logPath})
+			}
+			if err == nil {
+				err = logErr
+			}
+		}
+		if err != nil {
+			return &plugin.ExecuteResponse{
+				Success: false,
+				Error:   fmt.Sprintf("failed to publish packages: %v", err),
+				Outputs: partial(map[string]any{
+					"checksum_files": checksumFiles,
+					"published":      published,
+					"logs":           logs,
+				}),
+			}, nil
+		}
+	}
+
+	outputs := map[string]any{
+		"packages":       builtPackages,
+		"artifacts":      artifacts,
+		"total_size":     totalSize,
+		"checksum_files": checksumFiles,
+		"provenance":     provenanceFiles,
+		"signatures":     signatures,
+		"published":      published,
+		"checks":         checkResults,
+		"logs":           logs,
+		"builds":         buildResults(jobs, outcomes),
+		"formats":        cfg.Formats,
+		"output_dir":     cfg.OutputDir,
+		"target":         archs[0],
+		"targets":        archs,
+		"version":        releaseCtx.Version,
+	}
+	if apkFingerprint != "" {
+		outputs["apk_key_fingerprint"] = apkFingerprint
+	}
+	if cfg.CleanOutput {
+		outputs["cleaned"] = cleaned
+	}
+	if cfg.VerifyReproducible != "" {
+		outputs["reproducibility"] = reproducibility
+	}
+	if cfg.DiffAgainst != "" {
+		outputs["package_diff"] = diffs
+	}
+
+	message := fmt.Sprintf("Built %d Linux package(s) (%s)",
+		len(builtPackages), matrixSummary(len(cfg.Formats), len(targets)))
+	if len(cfg.Distros) > 0 {
+		message += fmt.Sprintf(" for %d distribution(s)", len(cfg.Distros))
+	}
+	if cached > 0 {
+		message += fmt.Sprintf("; %d reused from cache", cached)
+	}
+	if skipped > 0 {
+		message += fmt.Sprintf("; %d skipped as already published", skipped)
+	}
+	if len(cleaned) > 0 {
+		message += fmt.Sprintf("; removed %d stale file(s)", len(cleaned))
+	}
+	if failed > 0 {
+		message += fmt.Sprintf("; %d build(s) failed", failed)
+	}
+	if failed := gemfuryFailures(published); failed > 0 {
+		message += fmt.Sprintf("; %d Gemfury upload(s) failed", failed)
+	}
+	// Check findings come last since they may list findings on further lines.
+	for _, warning := range checkWarnings {
+		message += "; " + warning
+	}
+
+	return &plugin.ExecuteResponse{
+		Success: true,
+		Message: message,
+		Outputs: outputs,
+	}, nil
+}
+
+// runBuild builds a single package with the configured packager and applies the deb
+// compression level nfpm cannot.
+func (p *LinuxPkgPlugin) runBuild(ctx context.Context, executor CommandExecutor, cfg *Config, configPath, format string, target buildTarget, env []string, log io.Writer) (*packageResult, []byte, error) {
+	result, output, err := p.runPackager(ctx, executor, cfg, configPath, format, target, env, log)
+	if err != nil {
+		return result, output, err
+	}
+	// nfpm has no deb compression levels, so the data tarball is recompressed.
+	if setting := cfg.Compression.setting(format); format == "deb" && strings.Contains(setting, ":") {
+		if err := recompressDeb(result.Path, setting); err != nil {
+			return nil, output, err
+		}
+	}
+	return result, output, nil
+}
+
+// runPackager builds a single package with the configured backend: the embedded nfpm
+// library for packager "nfpm", fpm for packager "fpm", nfpm in a container for packager
+// "container", or the nfpm binary otherwise.
+func (p *LinuxPkgPlugin) runPackager(ctx context.Context, executor CommandExecutor, cfg *Config, configPath, format string, target buildTarget, env []string, log io.Writer) (*packageResult, []byte, error) {
+	filenameTemplate := cfg.filenameTemplate()
+	// Only an explicit target overrides the arch declared in the nfpm config.
+	arch := ""
+	if target.Override {
+		arch = target.nfpmArch()
+	}
+	if cfg.Packager == "fpm" {
+		return p.buildPackageFpm(ctx, executor, cfg, configPath, format, arch, env, log)
+	}
+	if usesEmbeddedNfpm(cfg.Packager) {
+		return buildPackageEmbedded(ctx, configPath, format, arch, cfg.OutputDir, filenameTemplate, envLookup(env, signingEnv(cfg)))
+	}
+
+	packageTarget, err := nfpmTarget(cfg, configPath, format, "", target, env)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var output []byte
+	if cfg.Packager == "container" {
+		output, err = p.buildPackageContainer(ctx, executor, cfg, configPath, format, packageTarget, env, log)
+	} else {
+		output, err = p.buildPackage(ctx, executor, cfg.nfpmBinary(), configPath, format, packageTarget, env, log)
+	}
+	if err != nil {
+		return nil, output, err
+	}
+
+	// Parse the output to get the package filename.
+	packagePath := p.parsePackagePath(output, cfg.OutputDir, format)
+	if packagePath == "" && packageTarget != cfg.OutputDir+"/" {
+		packagePath = packageTarget
+	}
+	if packagePath == "" {
+		// Fallback: construct expected package name.
+		packagePath = filepath.Join(cfg.OutputDir, "package"+packageExtensions[format])
+	}
+
+	return &packageResult{Path: packagePath}, output, nil
+}
+
+// nfpmTarget returns the --target of an nfpm build: the output directory, with a trailing
+// slash, or the package file. nfpm names the package itself unless it is given a file.
+// Its names do not tell ARM variants apart, so variant targets are always named here.
+// A non-empty arch overrides the arch of the config.
+func nfpmTarget(cfg *Config, configPath, format, arch string, target buildTarget, env []string) (string, error) {
+	filenameTemplate := cfg.filenameTemplate()
+	if filenameTemplate == "" && target.variant() == "" {
+		return cfg.OutputDir + "/", nil
+	}
+	info, packager, err := resolvePackageInfo(configPath, format, arch, envLookup(env, signingEnv(cfg)))
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cfg.OutputDir, packageFilename(filenameTemplate, format, packager, info)), nil
+}
+
+// nfpmPackageArgs returns the nfpm arguments that build a package of format from
+// configPath into target.
+func nfpmPackageArgs(configPath, format, target string) []string {
+	return []string{
+		"package",
+		"--config", configPath,
+		"--packager", format,
+		"--target", target,
+	}
+}
+
+// buildPackage builds a single package by executing the nfpm binary with env added to
+// its environment. target is the output directory, with a trailing slash, or the
+// package file. nfpm's output is streamed to log as it runs. A failed build returns a
+// *CommandError along with nfpm's output.
+func (p *LinuxPkgPlugin) buildPackage(ctx context.Context, executor CommandExecutor, binary, configPath, format, target string, env []string, log io.Writer) ([]byte, error) {
+	spec := ExecSpec{
+		Name:   binary,
+		Args:   nfpmPackageArgs(configPath, format, target),
+		Env:    env,
+		Output: log,
+	}
+	return runBuildCommand(ctx, executor, spec)
+}
+
+// runBuildCommand runs the command that builds a package and returns its combined
+// output. A failure returns a *CommandError along with the output.
+func runBuildCommand(ctx context.Context, executor CommandExecutor, spec ExecSpec) ([]byte, error) {
+	result, err := executor.Exec(ctx, spec)
+	var output []byte
+	if result != nil {
+		output = result.Combined
+	}
+	if err != nil {
+		return output, newCommandError(spec, result, err)
+	}
+	return output, nil
+}
+
+// nfpmBinary returns the nfpm binary the nfpm-cli packager runs.
+func (cfg *Config) nfpmBinary() string {
+	if cfg.NfpmBinary != "" {
+		return cfg.NfpmBinary
+	}
+	return "nfpm"
+}
+
+// parsePackagePath attempts to parse the package path from nfpm output.
+func (p *LinuxPkgPlugin) parsePackagePath(output []byte, outputDir, format string) string {
+	// nfpm typically outputs: "created package: <path>"
+	lines := strings.Split(string(output), "\n")
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "created package:") {
+			parts := strings.SplitN(line, ":", 2)
+			if len(parts) == 2 {
+				return strings.TrimSpace(parts[1])
+			}
+		}
+		// Also check for "using" pattern from some nfpm versions.
+		if strings.Contains(line, packageExtensions[format]) && strings.Contains(line, outputDir) {
+			return line
+		}
+	}
+	return ""
+}
+
+// parseConfig parses the raw configuration into a Config struct.
+func (p *LinuxPkgPlugin) parseConfig(raw map[string]any) *Config {
+	parser := helpers.NewConfigParser(raw)
+
+	// Parse formats with default.
+	formats, formatConfigs := parseFormats(raw)
+
+	return &Config{
+		WorkingDir:    parser.GetString("working_dir", "", ""),
+		ConfigPath:    parser.GetString("config_path", "", "nfpm.yaml"),
+		Formats:       formats,
+		FormatConfigs: formatConfigs,
+		Distros:       parseDistros(raw),
+		Overrides:     parseOverrides(raw),
+		OutputDir:     parser.GetString("output_dir", "", "dist"),
+		OutputMode:    parser.GetString("output_mode", "", ""),
+		Umask:         parser.GetString("umask", "", ""),
+		Packager:      parser.GetString("packager", "", "nfpm"),
+		Target:        parser.GetString("target", "", "current"),
+
+		Targets:               parser.GetStringSlice("targets", nil),
+		Release:               parser.GetString("release", "", parser.GetString("revision", "", "")),
+		FilenameTemplate:      parser.GetString("filename_template", "", ""),
+		Epoch:                 parser.GetInt("epoch", 0),
+		NormalizeVersion:      parser.GetBool("normalize_version", true),
+		TemplateConfig:        parser.GetBool("template_config", false),
+		Scripts:               parseScripts(raw),
+		Changelog:             parseChangelog(raw),
+		VerifyUnits:           parser.GetBool("verify_units", true),
+		SystemUser:            parseSystemUser(raw),
+		Manpages:              parser.GetStringSlice("manpages", nil),
+		Desktop:               parseDesktop(raw),
+		Checks:                parseChecks(raw),
+		ConfigOverlays:        parser.GetStringSlice("config_overlays", nil),
+		OverlayListStrategy:   parser.GetString("overlay_list_strategy", "", "replace"),
+		PersistLogs:           parser.GetBool("persist_logs", false),
+		CompressLogs:          parser.GetBool("compress_logs", false),
+		RespectIgnoreFiles:    parser.GetBool("respect_ignore_files", false),
+		MaxTotalSize:          sizeOption(raw, "max_total_size"),
+		MaxSize:               parseMaxSize(raw),
+		RPMSigning:            parseRPMSigning(raw),
+		APKKeyPath:            parser.GetString("apk_key_path", "", ""),
+		APKKeyName:            parser.GetString("apk_key_name", "", ""),
+		Checksums:             parser.GetStringSlice("checksums", []string{"sha256"}),
+		Provenance:            parser.GetBool("provenance", false),
+		ProvenanceBuilderID:   parser.GetString("provenance_builder_id", "", defaultBuilderID),
+		Build:                 parseGoBuild(raw),
+		Cosign:                parseCosign(raw),
+		Publish:               parsePublish(raw),
+		Concurrency:           parser.GetInt("concurrency", 0),
+		FailFast:              parser.GetBool("fail_fast", true),
+		SuccessPolicy:         parser.GetString("success_policy", "", "any"),
+		Cache:                 parser.GetBool("cache", false),
+		CleanOutput:           parser.GetBool("clean_output", false),
+		SkipExisting:          parser.GetBool("skip_existing", false),
+		Reproducible:          parser.GetBool("reproducible", false),
+		SRPM:                  parser.GetBool("srpm", false),
+		DebugSymbols:          parseDebugSymbols(raw),
+		Subpackages:           parseSubpackages(raw),
+		Compression:           parseCompression(raw),
+		ConfigFiles:           parser.GetStringSlice("config_files", nil),
+		Ownership:             parseOwnership(raw),
+		DebianDocs:            parseDebianDocs(raw),
+		VerifyReproducible:    parseVerifyReproducible(raw),
+		DiffAgainst:           parser.GetString("diff_against", "", ""),
+		MinNfpmVersion:        parser.GetString("min_nfpm_version", "", ""),
+		NfpmVersion:           parser.GetString("nfpm_version", "", ""),
+		NfpmDownloadURL:       parser.GetString("nfpm_download_url", "", defaultNfpmDownloadURL),
+		ToolCacheDir:          parser.GetString("tool_cache_dir", "", ""),
+		AllowUnverifiedNfpm:   parser.GetBool("allow_unverified_nfpm", false),
+		ToolLockPath:          parser.GetString("tool_lock", "", ""),
+		NfpmPath:              parser.GetString("nfpm_path", "", ""),
+		AllowAbsoluteNfpmPath: parser.GetBool("allow_absolute_nfpm_path", false),
+		Container:             parseContainer(raw),
+		RunOn:                 parser.GetString("run_on", "", ""),
+		Hooks:                 parseHooks(raw),
+		CleanupOnError:        parser.GetBool("cleanup_on_error", false),
+		ReportOnSuccess:       parser.GetBool("report_on_success", false),
+		Yank:                  parser.GetBool("yank", false),
+		SkipPrerelease:        parser.GetBool("skip_prerelease", false),
+		OnlyReleaseTypes:      parser.GetStringSlice("only_release_types", nil),
+		OnlyBranches:          parser.GetStringSlice("only_branches", nil),
+		OnlyTags:              parser.GetStringSlice("only_tags", nil),
+	}
+}
+
+// Validate validates the plugin configuration.
+func (p *LinuxPkgPlugin) Validate(ctx context.Context, config map[string]any) (*plugin.ValidateResponse, error) {
+	vb := helpers.NewValidationBuilder()
+
+	// Expand environment variable references before validating the values they produce.
+	if expanded, err := expandConfigEnv(config); err != nil {
+		vb.AddError("strict_env", err.Error())
+	} else {
+		config = expanded
+	}
+	parser := helpers.NewConfigParser(config)
+
+	// Validate working_dir.
+	if value, ok := config["working_dir"]; ok && value != nil {
+		if dir, ok := value.(string); !ok || dir == "" {
+			vb.AddError("working_dir", "working_dir must be a non-empty string")
+		}
+	}
+
+	// Validate config_path.
+	configPath := parser.GetString("config_path", "", "nfpm.yaml")
+	if err := validatePath(configPath); err != nil {
+		vb.AddError("config_path", err.Error())
+	}
+
+	// Validate output_dir.
+	outputDir := parser.GetString("output_dir", "", "dist")
+	if err := validatePath(outputDir); err != nil {
+		vb.AddError("output_dir", err.Error())
+	}
+
+	// Validate packages.
+	if specs, err := parsePackages(config); err != nil {
+		vb.AddError("packages", err.Error())
+	} else {
+		for _, spec := range specs {
+			packageParser := helpers.NewConfigParser(spec.Config)
+			for _, key := range []string{"config_path", "output_dir"} {
+				if err := validatePath(packageParser.GetString(key, "", "")); err != nil {
+					vb.AddError("packages", fmt.Sprintf("package %s: invalid %s: %v", spec.Name, key, err))
+				}
+			}
+			formats, _ := parseFormats(spec.Config)
+			for _, format := range formats {
+				if err := validateFormat(format); err != nil {
+					vb.AddError("packages", fmt.Sprintf("package %s: %v", spec.Name, err))
+				}
+			}
+		}
+	}
+
+	// Validate config_overlays.
+	for _, overlay := range parser.GetStringSlice("config_overlays", nil) {
+		if err := validatePath(overlay); err != nil {
+			vb.AddError("config_overlays", err.Error())
+		}
+	}
+
+	// Validate scripts.
+	if err := validateScripts(config); err != nil {
+		vb.AddError("scripts", err.Error())
+	}
+
+	// Validate changelog.
+	if err := validateChangelog(config); err != nil {
+		vb.AddError("changelog", err.Error())
+	}
+
+	// Validate manpages.
+	if err := validateManpages(parser.GetStringSlice("manpages", nil)); err != nil {
+		vb.AddError("manpages", err.Error())
+	}
+
+	// Validate system_user.
+	if user := parseSystemUser(config); user != nil {
+		if err := user.validate(); err != nil {
+			vb.AddError("system_user", err.Error())
+		}
+	} else if parser.Has("system_user") {
+		vb.AddError("system_user", "system_user must be an object")
+	}
+
+	// Validate checks.
+	if err := validateChecks(config); err != nil {
+		vb.AddError("checks", err.Error())
+	}
+
+	// Validate desktop.
+	if desktop := parseDesktop(config); desktop != nil {
+		if err := desktop.validate(); err != nil {
+			vb.AddError("desktop", err.Error())
+		}
+	} else if parser.Has("desktop") {
+		vb.AddError("desktop", "desktop must be an object")
+	}
+
+	// Validate overlay_list_strategy.
+	if err := validateListStrategy(parser.GetString("overlay_list_strategy", "", "replace")); err != nil {
+		vb.AddError("overlay_list_strategy", err.Error())
+	}
+
+	// Validate max_total_size.
+	if _, err := parseSize(sizeOption(config, "max_total_size")); err != nil {
+		vb.AddError("max_total_size", err.Error())
+	}
+
+	// Validate max_size.
+	if maxSize := parseMaxSize(config); maxSize != nil {
+		if err := maxSize.validate(); err != nil {
+			vb.AddError("max_size", err.Error())
+		}
+	}
+
+	// Validate run_on.
+	if _, err := parseRunOn(parser.GetString("run_on", "", "post-publish")); err != nil {
+		vb.AddError("run_on", err.Error())
+	}
+
+	// Validate only_release_types.
+	if err := validateReleaseTypes(parser.GetStringSlice("only_release_types", nil)); err != nil {
+		vb.AddError("only_release_types", err.Error())
+	}
+
+	// Validate only_branches and only_tags.
+	if err := validateRefPatterns(parser.GetStringSlice("only_branches", nil)); err != nil {
+		vb.AddError("only_branches", err.Error())
+	}
+	if err := validateRefPatterns(parser.GetStringSlice("only_tags", nil)); err != nil {
+		vb.AddError("only_tags", err.Error())
+	}
+
+	// Validate hooks.
+	if parser.Has("hooks") {
+		if parser.GetMap("hooks") == nil {
+			vb.AddError("hooks", "hooks must be an object mapping hooks to actions")
+		} else if _, err := resolveHookActions(parseHooks(config), parser.GetMap("publish") != nil); err != nil {
+			vb.AddError("hooks", err.Error())
+		} else if parser.Has("run_on") {
+			vb.AddError("hooks", "hooks cannot be combined with run_on")
+		}
+	}
+
+	// Validate container.
+	if err := parseContainer(config).validate(); err != nil {
+		vb.AddError("container", err.Error())
+	} else if parser.Has("container") && parser.GetMap("container") == nil {
+		vb.AddError("container", "container must be an object")
+	}
+
+	// Validate concurrency.
+	if err := validateConcurrency(parser.GetInt("concurrency", 0)); err != nil {
+		vb.AddError("concurrency", err.Error())
+	}
+
+	// Validate nfpm_version, nfpm_path, nfpm_download_url, and allow_unverified_nfpm.
+	if err := validateNfpmVersion(parser.GetString("nfpm_version", "", ""), parser.GetString("packager", "", "nfpm")); err != nil {
+		vb.AddError("nfpm_version", err.Error())
+	}
+	nfpmPath := parser.GetString("nfpm_path", "", "")
+	nfpmPathErr := validateNfpmPath(nfpmPath, parser.GetBool("allow_absolute_nfpm_path", false),
+		parser.GetString("packager", "", "nfpm"), parser.GetString("nfpm_version", "", ""))
+	if nfpmPathErr != nil {
+		vb.AddError("nfpm_path", nfpmPathErr.Error())
+	}
+	if err := validateNfpmDownloadURL(parser.GetString("nfpm_download_url", "", defaultNfpmDownloadURL)); err != nil {
+		vb.AddError("nfpm_download_url", err.Error())
+	}
+	if err := validateAllowUnverifiedNfpm(parser.GetBool("allow_unverified_nfpm", false), parser.GetString("nfpm_version", "", "")); err != nil {
+		vb.AddError("allow_unverified_nfpm", err.Error())
+	}
+	if toolLock := parser.GetString("tool_lock", "", ""); toolLock != "" {
+		if err := validateToolLock(parser.GetString("working_dir", "", ""), toolLock); err != nil {
+			vb.AddError("tool_lock", err.Error())
+		}
+	}
+	minNfpm := parser.GetString("min_nfpm_version", "", "")
+	if minNfpm == "" {
+		minNfpm = minNfpmVersion
+	} else if err := validateMinNfpmVersion(minNfpm); err != nil {
+		vb.AddError("min_nfpm_version", err.Error())
+		minNfpm = minNfpmVersion
+	}
+
+	// Validate success_policy.
+	if err := validateSuccessPolicy(parser.GetString("success_policy", "", "any")); err != nil {
+		vb.AddError("success_policy", err.Error())
+	}
+
+	// Validate diff_against.
+	if err := validateDiffAgainst(parser.GetString("diff_against", "", "")); err != nil {
+		vb.AddError("diff_against", err.Error())
+	}
+
+	// Validate verify_reproducible.
+	if err := validateVerifyReproducible(parseVerifyReproducible(config)); err != nil {
+		vb.AddError("verify_reproducible", err.Error())
+	}
+
+	// Validate distros.
+	if err := validateDistrosObject(config); err != nil {
+		vb.AddError("distros", err.Error())
+	} else if err := validateDistros(parseDistros(config)); err != nil {
+		vb.AddError("distros", err.Error())
+	}
+
+	// Validate compression.
+	if err := validateCompressionObject(config); err != nil {
+		vb.AddError("compression", err.Error())
+	} else if err := validateCompressions(parseCompression(config), parseDistros(config), parser.GetString("packager", "", "nfpm")); err != nil {
+		vb.AddError("compression", err.Error())
+	}
+
+	// Validate overrides.
+	if err := validateOverrides(config, parseDistros(config)); err != nil {
+		vb.AddError("overrides", err.Error())
+	}
+
+	// Validate filename_template.
+	if err := validateFilenameTemplate(parser.GetString("filename_template", "", "")); err != nil {
+		vb.AddError("filename_template", err.Error())
+	}
+
+	// Validate epoch.
+	if err := validateEpoch(parser.GetInt("epoch", 0)); err != nil {
+		vb.AddError("epoch", err.Error())
+	}
+
+	// Validate rpm_signing.
+	if signing := parseRPMSigning(config); signing != nil {
+		if err := signing.validate(); err != nil {
+			vb.AddError("rpm_signing", err.Error())
+		}
+	} else if parser.Has("rpm_signing") {
+		vb.AddError("rpm_signing", "rpm_signing must be an object")
+	}
+
+	// Validate apk_key_path and apk_key_name.
+	if apkKeyPath := parser.GetString("apk_key_path", "", ""); apkKeyPath != "" {
+		if err := validateAPKKey(parser.GetString("working_dir", "", ""), apkKeyPath); err != nil {
+			vb.AddError("apk_key_path", err.Error())
+		}
+	}
+	if err := validateAPKKeyName(parser.GetString("apk_key_name", "", "")); err != nil {
+		vb.AddError("apk_key_name", err.Error())
+	}
+
+	// Validate build.
+	if build := parseGoBuild(config); build != nil {
+		if err := build.validate(); err != nil {
+			vb.AddError("build", err.Error())
+		}
+	} else if parser.Has("build") {
+		vb.AddError("build", "build must be an object")
+	}
+
+	// Validate cosign.
+	if cosign := parseCosign(config); cosign != nil {
+		if err := cosign.validate(); err != nil {
+			vb.AddError("cosign", err.Error())
+		}
+	} else if parser.Has("cosign") {
+		vb.AddError("cosign", "cosign must be an object")
+	}
+
+	// Validate publish.
+	if publish := parsePublish(config); publish != nil {
+		if err := publish.validate(); err != nil {
+			vb.AddError("publish", err.Error())
+		}
+	} else if parser.Has("publish") {
+		vb.AddError("publish", "publish must be an object")
+	}
+
+	// Validate checksums.
+	for _, algorithm := range parser.GetStringSlice("checksums", nil) {
+		if err := validateChecksumAlgorithm(algorithm); err != nil {
+			vb.AddError("checksums", err.Error())
+		}
+	}
+
+	// Validate output_mode and umask.
+	if err := validateOutputModes(parser.GetString("output_mode", "", ""), ""); err != nil {
+		vb.AddError("output_mode", err.Error())
+	}
+	if err := validateOutputModes("", parser.GetString("umask", "", "")); err != nil {
+		vb.AddError("umask", err.Error())
+	}
+
+	// Validate formats.
+	formats, _ := parseFormats(config)
+	for _, format := range formats {
+		if err := validateFormat(format); err != nil {
+			vb.AddError("formats", err.Error())
+		} else if err := validatePackagerFormat(parser.GetString("packager", "", "nfpm"), format); err != nil {
+			vb.AddError("formats", err.Error())
+		}
+	}
+	if err := validateFormatsObject(config); err != nil {
+		vb.AddError("formats", err.Error())
+	}
+
+	// Validate srpm.
+	if err := validateSRPM(parser.GetBool("srpm", false), formats); err != nil {
+		vb.AddError("srpm", err.Error())
+	}
+
+	// Validate debug_symbols.
+	if err := validateDebugSymbols(config, formats, parser.GetString("filename_template", "", "")); err != nil {
+		vb.AddError("debug_symbols", err.Error())
+	}
+
+	// Validate subpackages.
+	if err := validateSubpackageEntries(config); err != nil {
+		vb.AddError("subpackages", err.Error())
+	} else if err := validateSubpackages(parseSubpackages(config), parser.GetString("filename_template", "", "")); err != nil {
+		vb.AddError("subpackages", err.Error())
+	}
+
+	// Validate config_files.
+	if err := validateConfigFiles(parser.GetStringSlice("config_files", nil)); err != nil {
+		vb.AddError("config_files", err.Error())
+	}
+
+	// Validate ownership.
+	if err := validateOwnershipObject(config); err != nil {
+		vb.AddError("ownership", err.Error())
+	} else if err := validateOwnership(parseOwnership(config), parser.GetString("packager", "", "nfpm"), formats); err != nil {
+		vb.AddError("ownership", err.Error())
+	}
+
+	// Validate debian_docs.
+	if err := validateDebianDocs(config, formats); err != nil {
+		vb.AddError("debian_docs", err.Error())
+	}
+
+	// Validate release template.
+	if err := validateReleaseTemplate(parser.GetString("release", "", parser.GetString("revision", "", ""))); err != nil {
+		vb.AddError("release", err.Error())
+	}
+
+	// Validate target architecture.
+	target := parser.GetString("target", "", "current")
+	if err := validateArchitecture(target); err != nil {
+		vb.AddError("target", err.Error())
+	}
+	for _, arch := range parser.GetStringSlice("targets", nil) {
+		if err := validateArchitecture(arch); err != nil {
+			vb.AddError("targets", err.Error())
+		}
+	}
+
+	// Validate packager.
+	packager := parser.GetString("packager", "", "nfpm")
+	if !allowedPackagers[packager] {
+		vb.AddError("packager", "packager must be one of: "+strings.Join(sortedKeys(allowedPackagers), ", "))
+	}
+
+	// Check the binary the nfpm-cli packager runs, unless a pinned release is downloaded
+	// when missing or nfpm_path is invalid. Outside strict mode a problem is only logged,
+	// as the release may run on a host other than the one validating.
+	strict := parser.GetBool("strict", false)
+	if packager == "nfpm-cli" && !parser.Has("nfpm_version") && nfpmPathErr == nil {
+		if nfpmPath != "" {
+			nfpmPath = inWorkingDir(parser.GetString("working_dir", "", ""), nfpmPath)
+		}
+		if err := p.checkNfpmBinary(ctx, nfpmPath, minNfpm); err != nil {
+			if strict {
+				vb.AddError("packager", err.Error())
+			} else {
+				fmt.Fprintf(p.getLogOutput(), "warning: %v\n", err)
+			}
+		}
+	}
+
+	if packager == "container" {
+		if _, err := p.resolveRuntime(parseContainer(config)); err != nil {
+			if strict {
+				vb.AddError("packager", err.Error())
+			} else {
+				fmt.Fprintf(p.getLogOutput(), "warning: %v\n", err)
+			}
+		}
+	}
+	if packager == "fpm" {
+		if err := p.checkFpmBinary(); err != nil {
+			if strict {
+				vb.AddError("packager", err.Error())
+			} else {
+				fmt.Fprintf(p.getLogOutput(), "warning: %v\n", err)
+			}
+		}
+	}
+
+	// Validate the shape of the config against the schema. The checks above explain
+	// their problems better, so the schema only reports options they found no fault in.
+	if errs, err := schemaErrors(config); err != nil {
+		vb.AddError("config", err.Error())
+	} else {
+		reported := make(map[string]bool)
+		for _, e := range vb.Build().Errors {
+			reported[e.Field] = true
+		}
+		for _, e := range errs {
+			if !reported[e.Field] {
+				vb.AddError(e.Field, e.Message)
+			}
+		}
+	}
+
+	// In strict mode, and when the nfpm config is to be checked, problems that would
+	// otherwise only surface during the release fail validation.
+	checkNfpm := parser.GetBool("check_nfpm_config", false)
+	if strict || checkNfpm {
+		if cfg, err := p.resolvedConfig(config); err != nil {
+			vb.AddError("working_dir", err.Error())
+		} else {
+			var problems []plugin.ValidationError
+			if strict {
+				problems = append(problems, p.strictProblems(config, cfg)...)
+			}
+			if checkNfpm {
+				problems = append(problems, checkNfpmConfigs(cfg)...)
+			}
+			for _, problem := range problems {
+				vb.AddError(problem.Field, problem.Message)
+			}
+		}
 	}
 
 	return vb.Build(), nil
@@ -4,10 +4,13 @@ package main
 import (
 	"context"
 	"errors"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
@@ -15,25 +18,52 @@ import (
 
 // MockCommandExecutor is a mock implementation of CommandExecutor for testing.
 type MockCommandExecutor struct {
-	// RunFunc is called when Run is invoked. If nil, returns default success.
+	// RunFunc is called when a command is executed. If nil, returns default success. Its
+	// output is reported as both stdout and combined output.
 	RunFunc func(ctx context.Context, name string, args ...string) ([]byte, error)
-	// Calls records all calls made to Run.
+	// ExecFunc, when set, is called instead of RunFunc with the full ExecSpec.
+	ExecFunc func(ctx context.Context, spec ExecSpec) (*ExecResult, error)
+	// Calls records all commands executed.
 	Calls []MockCall
+
+	mu sync.Mutex
 }
 
 // MockCall records a single call to the executor.
 type MockCall struct {
 	Name string
 	Args []string
+	// Env holds the extra environment passed to the command.
+	Env []string
+	// Dir holds the directory the command was run in.
+	Dir string
+	// Stdin holds everything fed to the command's standard input.
+	Stdin string
 }
 
-// Run implements CommandExecutor.
-func (m *MockCommandExecutor) Run(ctx context.Context, name string, args ...string) ([]byte, error) {
-	m.Calls = append(m.Calls, MockCall{Name: name, Args: args})
+// Exec implements CommandExecutor.
+func (m *MockCommandExecutor) Exec(ctx context.Context, spec ExecSpec) (*ExecResult, error) {
+	call := MockCall{Name: spec.Name, Args: spec.Args, Env: spec.Env, Dir: spec.Dir}
+	if spec.Stdin != nil {
+		stdin, err := io.ReadAll(spec.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		call.Stdin = string(stdin)
+	}
+	m.mu.Lock()
+	m.Calls = append(m.Calls, call)
+	m.mu.Unlock()
+
+	if m.ExecFunc != nil {
+		return m.ExecFunc(ctx, spec)
+	}
+	output := []byte("created package: dist/myapp-1.0.0.deb")
+	var err error
 	if m.RunFunc != nil {
-		return m.RunFunc(ctx, name, args...)
+		output, err = m.RunFunc(ctx, spec.Name, spec.Args...)
 	}
-	return []byte("created package: dist/myapp-1.0.0.deb"), nil
+	return &ExecResult{Stdout: output, Combined: output}, err
 }
 
 // TestGetInfo verifies plugin metadata.
@@ -80,14 +110,11 @@ func TestGetInfo(t *testing.T) {
 	}
 
 	// Verify hooks.
-	t.Run("hooks contains PostPublish", func(t *testing.T) {
+	t.Run("hooks contains the hooks actions run in", func(t *testing.T) {
 		t.Parallel()
-		if len(info.Hooks) != 1 {
-			t.Errorf("expected 1 hook, got %d", len(info.Hooks))
-			return
-		}
-		if info.Hooks[0] != plugin.HookPostPublish {
-			t.Errorf("expected hook %q, got %q", plugin.HookPostPublish, info.Hooks[0])
+		expected := []plugin.Hook{plugin.HookPrePublish, plugin.HookPostPublish, plugin.HookOnSuccess, plugin.HookOnError}
+		if !reflect.DeepEqual(info.Hooks, expected) {
+			t.Errorf("expected hooks %v, got %v", expected, info.Hooks)
 		}
 	})
 
@@ -110,6 +137,27 @@ func TestGetInfo(t *testing.T) {
 	})
 }
 
+// expectFieldError validates config and checks that field has an error containing
+// expectError, or no error when expectError is empty.
+func expectFieldError(t *testing.T, config map[string]any, field, expectError string) {
+	t.Helper()
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Validate(context.Background(), config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var found string
+	for _, e := range resp.Errors {
+		if e.Field == field {
+			found = e.Message
+		}
+	}
+	if (expectError == "") != (found == "") || !strings.Contains(found, expectError) {
+		t.Errorf("expected %s error %q, got %v", field, expectError, resp.Errors)
+	}
+}
+
 // TestValidate tests configuration validation.
 func TestValidate(t *testing.T) {
 	t.Parallel()
@@ -149,6 +197,14 @@ func TestValidate(t *testing.T) {
 			expectValid: true,
 			expectErrs:  0,
 		},
+		{
+			name: "valid ipk alongside deb and rpm",
+			config: map[string]any{
+				"formats": []string{"deb", "rpm", "ipk"},
+			},
+			expectValid: true,
+			expectErrs:  0,
+		},
 		{
 			name: "invalid format",
 			config: map[string]any{
@@ -578,8 +634,9 @@ func TestExecuteDryRun(t *testing.T) {
 }
 
 // TestExecuteWithMockExecutor tests actual execution with mock.
-// Note: These tests cannot run in parallel due to chdir usage.
 func TestExecuteWithMockExecutor(t *testing.T) {
+	t.Parallel()
+
 	tests := []struct {
 		name          string
 		configPath    string
@@ -680,21 +737,12 @@ func TestExecuteWithMockExecutor(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			// Create a temporary directory and change to it.
+			t.Parallel()
+
 			tmpDir := t.TempDir()
-			oldWd, err := os.Getwd()
-			if err != nil {
-				t.Fatalf("failed to get working directory: %v", err)
-			}
-			if err := os.Chdir(tmpDir); err != nil {
-				t.Fatalf("failed to change to temp directory: %v", err)
-			}
-			t.Cleanup(func() {
-				_ = os.Chdir(oldWd)
-			})
 
 			// Create the config file.
-			if err := os.WriteFile(tc.configPath, []byte("name: test\nversion: 1.0.0"), 0644); err != nil {
+			if err := os.WriteFile(filepath.Join(tmpDir, tc.configPath), []byte("name: test\nversion: 1.0.0"), 0644); err != nil {
 				t.Fatalf("failed to create test config: %v", err)
 			}
 
@@ -705,9 +753,11 @@ func TestExecuteWithMockExecutor(t *testing.T) {
 				Hook:   plugin.HookPostPublish,
 				DryRun: false,
 				Config: map[string]any{
+					"working_dir": tmpDir,
 					"config_path": tc.configPath,
 					"formats":     tc.formats,
 					"output_dir":  tc.outputDir,
+					"packager":    "nfpm-cli",
 				},
 				Context: plugin.ReleaseContext{
 					Version:         "1.0.0",
@@ -778,7 +828,7 @@ func TestExecuteValidationErrors(t *testing.T) {
 		{
 			name: "invalid architecture",
 			config: map[string]any{
-				"target": "x86_64", // Should be amd64.
+				"target": "sparc64",
 			},
 			expectError: "invalid target",
 		},
@@ -870,8 +920,6 @@ func TestExecuteUnhandledHook(t *testing.T) {
 		plugin.HookPreApprove,
 		plugin.HookPostApprove,
 		plugin.HookPrePublish,
-		plugin.HookOnSuccess,
-		plugin.HookOnError,
 	}
 
 	for _, hook := range unhandledHooks {
@@ -1001,6 +1049,16 @@ func TestValidateFormatFunction(t *testing.T) {
 			format:    "apk",
 			expectErr: false,
 		},
+		{
+			name:      "valid archlinux",
+			format:    "archlinux",
+			expectErr: false,
+		},
+		{
+			name:      "valid ipk",
+			format:    "ipk",
+			expectErr: false,
+		},
 		{
 			name:      "empty format",
 			format:    "",
@@ -1083,13 +1141,18 @@ func TestValidateArchitectureFunction(t *testing.T) {
 			expectErr: false,
 		},
 		{
-			name:      "invalid x86_64",
+			name:      "alias x86_64",
 			arch:      "x86_64",
-			expectErr: true,
+			expectErr: false,
 		},
 		{
-			name:      "invalid aarch64",
-			arch:      "aarch64",
+			name:      "alias armhf",
+			arch:      "armhf",
+			expectErr: false,
+		},
+		{
+			name:      "invalid arm/v8",
+			arch:      "arm/v8",
 			expectErr: true,
 		},
 		{
@@ -1141,6 +1204,27 @@ func TestParsePackagePath(t *testing.T) {
 			format:     "rpm",
 			expectPath: "dist/myapp-1.0.0.rpm",
 		},
+		{
+			name:       "archlinux package path",
+			output:     "using archlinux packager...\ncreated package: dist/myapp-1.0.0-1-x86_64.pkg.tar.zst",
+			outputDir:  "dist",
+			format:     "archlinux",
+			expectPath: "dist/myapp-1.0.0-1-x86_64.pkg.tar.zst",
+		},
+		{
+			name:       "archlinux path without prefix",
+			output:     "dist/myapp-1.0.0-1-x86_64.pkg.tar.zst",
+			outputDir:  "dist",
+			format:     "archlinux",
+			expectPath: "dist/myapp-1.0.0-1-x86_64.pkg.tar.zst",
+		},
+		{
+			name:       "ipk package path",
+			output:     "created package: dist/myapp_1.0.0_mipsel_24kc.ipk",
+			outputDir:  "dist",
+			format:     "ipk",
+			expectPath: "dist/myapp_1.0.0_mipsel_24kc.ipk",
+		},
 		{
 			name:       "no match returns empty",
 			output:     "some other output",
@@ -1233,23 +1317,12 @@ func TestDryRunResolvesCurrentArchitecture(t *testing.T) {
 }
 
 // TestExecuteCreatesOutputDirectory tests that the plugin creates the output directory.
-// Note: This test cannot run in parallel due to chdir usage.
 func TestExecuteCreatesOutputDirectory(t *testing.T) {
-	// Create a temporary directory and change to it.
-	tmpDir := t.TempDir()
-	oldWd, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("failed to get working directory: %v", err)
-	}
-	if err := os.Chdir(tmpDir); err != nil {
-		t.Fatalf("failed to change to temp directory: %v", err)
-	}
-	t.Cleanup(func() {
-		_ = os.Chdir(oldWd)
-	})
+	t.Parallel()
 
+	tmpDir := t.TempDir()
 	configPath := "nfpm.yaml"
-	if err := os.WriteFile(configPath, []byte("name: test\nversion: 1.0.0"), 0644); err != nil {
+	if err := os.WriteFile(filepath.Join(tmpDir, configPath), []byte("name: test\nversion: 1.0.0"), 0644); err != nil {
 		t.Fatalf("failed to create test config: %v", err)
 	}
 
@@ -1266,9 +1339,11 @@ func TestExecuteCreatesOutputDirectory(t *testing.T) {
 		Hook:   plugin.HookPostPublish,
 		DryRun: false,
 		Config: map[string]any{
+			"working_dir": tmpDir,
 			"config_path": configPath,
 			"formats":     []string{"deb"},
 			"output_dir":  outputDir,
+			"packager":    "nfpm-cli",
 		},
 		Context: plugin.ReleaseContext{
 			Version: "1.0.0",
@@ -1286,7 +1361,7 @@ func TestExecuteCreatesOutputDirectory(t *testing.T) {
 	}
 
 	// Verify the output directory was created.
-	if _, err := os.Stat(outputDir); os.IsNotExist(err) {
+	if _, err := os.Stat(filepath.Join(tmpDir, outputDir)); os.IsNotExist(err) {
 		t.Error("expected output directory to be created")
 	}
 }
@@ -1337,23 +1412,12 @@ func TestValidateConfigExists(t *testing.T) {
 }
 
 // TestCommandArgsFormat tests that the nfpm command is built correctly.
-// Note: This test cannot run in parallel due to chdir usage.
 func TestCommandArgsFormat(t *testing.T) {
-	// Create a temporary directory and change to it.
-	tmpDir := t.TempDir()
-	oldWd, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("failed to get working directory: %v", err)
-	}
-	if err := os.Chdir(tmpDir); err != nil {
-		t.Fatalf("failed to change to temp directory: %v", err)
-	}
-	t.Cleanup(func() {
-		_ = os.Chdir(oldWd)
-	})
+	t.Parallel()
 
+	tmpDir := t.TempDir()
 	configPath := "nfpm.yaml"
-	if err := os.WriteFile(configPath, []byte("name: test\nversion: 1.0.0"), 0644); err != nil {
+	if err := os.WriteFile(filepath.Join(tmpDir, configPath), []byte("name: test\nversion: 1.0.0"), 0644); err != nil {
 		t.Fatalf("failed to create test config: %v", err)
 	}
 	outputDir := "dist"
@@ -1371,9 +1435,11 @@ func TestCommandArgsFormat(t *testing.T) {
 		Hook:   plugin.HookPostPublish,
 		DryRun: false,
 		Config: map[string]any{
+			"working_dir": tmpDir,
 			"config_path": configPath,
 			"formats":     []string{"deb"},
 			"output_dir":  outputDir,
+			"packager":    "nfpm-cli",
 		},
 		Context: plugin.ReleaseContext{
 			Version: "1.0.0",
@@ -1390,12 +1456,13 @@ func TestCommandArgsFormat(t *testing.T) {
 		t.Fatalf("expected success, got failure: %s", resp.Error)
 	}
 
-	// Verify the args structure.
+	// Verify the args structure. The config is staged with its paths resolved against
+	// working_dir, so only its file name is stable.
 	expectedArgs := []string{
 		"package",
 		"--config", configPath,
 		"--packager", "deb",
-		"--target", outputDir + "/",
+		"--target", filepath.Join(tmpDir, outputDir) + "/",
 	}
 
 	if len(capturedArgs) != len(expectedArgs) {
@@ -1403,8 +1470,105 @@ func TestCommandArgsFormat(t *testing.T) {
 	}
 
 	for i, expected := range expectedArgs {
-		if i < len(capturedArgs) && capturedArgs[i] != expected {
+		if i >= len(capturedArgs) {
+			break
+		}
+		got := capturedArgs[i]
+		if i == 2 {
+			got = filepath.Base(got)
+		}
+		if got != expected {
 			t.Errorf("arg[%d]: expected %q, got %q", i, expected, capturedArgs[i])
 		}
 	}
 }
+
+// TestDryRunArchlinuxFormat tests dry run reporting for archlinux packages.
+func TestDryRunArchlinuxFormat(t *testing.T) {
+	t.Parallel()
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:   plugin.HookPostPublish,
+		DryRun: true,
+		Config: map[string]any{
+			"formats": []string{"deb", "archlinux"},
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	extensions, ok := resp.Outputs["package_extensions"].(map[string]string)
+	if !ok {
+		t.Fatalf("expected package_extensions output, got %T", resp.Outputs["package_extensions"])
+	}
+	if extensions["archlinux"] != ".pkg.tar.zst" {
+		t.Errorf("expected archlinux extension .pkg.tar.zst, got %q", extensions["archlinux"])
+	}
+	if extensions["deb"] != ".deb" {
+		t.Errorf("expected deb extension .deb, got %q", extensions["deb"])
+	}
+}
+
+// TestExecuteRunOn tests that run_on selects the hook packages are built in.
+func TestExecuteRunOn(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		runOn       any
+		hook        plugin.Hook
+		expectBuild bool
+		expectError string
+	}{
+		{"default post-publish", nil, plugin.HookPostPublish, true, ""},
+		{"default skips pre-publish", nil, plugin.HookPrePublish, false, ""},
+		{"pre-publish", "pre-publish", plugin.HookPrePublish, true, ""},
+		{"pre_publish", "pre_publish", plugin.HookPrePublish, true, ""},
+		{"pre-publish skips post-publish", "pre-publish", plugin.HookPostPublish, false, ""},
+		{"invalid", "on-success", plugin.HookPostPublish, false, "invalid run_on: unsupported hook: on-success"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: test\nversion: 1.0.0"), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+			config := map[string]any{"working_dir": dir, "formats": []string{"deb"}, "packager": "nfpm-cli"}
+			if tt.runOn != nil {
+				config["run_on"] = tt.runOn
+			}
+			mock := &MockCommandExecutor{}
+			p := &LinuxPkgPlugin{cmdExecutor: mock}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook:    tt.hook,
+				Config:  config,
+				Context: plugin.ReleaseContext{Version: "1.0.0"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tt.expectError != "" {
+				if resp.Success || !strings.HasPrefix(resp.Error, tt.expectError) {
+					t.Errorf("expected error starting with %q, got %+v", tt.expectError, resp)
+				}
+				return
+			}
+			if !resp.Success {
+				t.Fatalf("expected success, got failure: %s", resp.Error)
+			}
+			if built := len(mock.Calls) > 0; built != tt.expectBuild {
+				t.Errorf("expected build %v, got calls %v and message %q", tt.expectBuild, mock.Calls, resp.Message)
+			}
+		})
+	}
+}
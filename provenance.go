@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+const (
+	// inTotoStatementType is the in-toto attestation statement type.
+	inTotoStatementType = "https://in-toto.io/Statement/v1"
+	// slsaProvenanceType is the SLSA provenance predicate type.
+	slsaProvenanceType = "https://slsa.dev/provenance/v1"
+	// provenanceBuildType identifies how this plugin builds packages.
+	provenanceBuildType = "https://github.com/relicta-tech/plugin-linuxpkg/build@v1"
+	// defaultBuilderID is the builder identity recorded when none is configured.
+	defaultBuilderID = "https://github.com/relicta-tech/plugin-linuxpkg"
+	// provenanceExtension is appended to a package path to name its provenance file.
+	provenanceExtension = ".intoto.json"
+	// nfpmModulePath is the module path of the embedded nfpm library.
+	nfpmModulePath = "github.com/goreleaser/nfpm/v2"
+)
+
+// provenanceStatement is an in-toto statement carrying a SLSA v1 provenance predicate.
+type provenanceStatement struct {
+	Type          string              `json:"_type"`
+	Subject       []provenanceSubject `json:"subject"`
+	PredicateType string              `json:"predicateType"`
+	Predicate     provenancePredicate `json:"predicate"`
+}
+
+// provenanceSubject identifies an artifact by name and digest.
+type provenanceSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// provenancePredicate is the SLSA v1 provenance predicate.
+type provenancePredicate struct {
+	BuildDefinition provenanceBuildDefinition `json:"buildDefinition"`
+	RunDetails      provenanceRunDetails      `json:"runDetails"`
+}
+
+// provenanceBuildDefinition describes the inputs of a build.
+type provenanceBuildDefinition struct {
+	BuildType            string               `json:"buildType"`
+	ExternalParameters   map[string]any       `json:"externalParameters"`
+	InternalParameters   map[string]any       `json:"internalParameters,omitempty"`
+	ResolvedDependencies []provenanceResource `json:"resolvedDependencies,omitempty"`
+}
+
+// provenanceResource is a resolved build dependency.
+type provenanceResource struct {
+	URI    string            `json:"uri,omitempty"`
+	Name   string            `json:"name,omitempty"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+// provenanceRunDetails describes the builder and timing of a build.
+type provenanceRunDetails struct {
+	Builder  provenanceBuilder  `json:"builder"`
+	Metadata provenanceMetadata `json:"metadata"`
+}
+
+// provenanceBuilder identifies the entity that ran the build.
+type provenanceBuilder struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+// provenanceMetadata records when the build ran.
+type provenanceMetadata struct {
+	StartedOn  time.Time `json:"startedOn"`
+	FinishedOn time.Time `json:"finishedOn"`
+}
+
+// provenanceContext holds the per-run facts shared by every provenance statement.
+type provenanceContext struct {
+	BuilderID    string
+	Packager     string
+	NfpmVersion  string
+	ConfigPath   string
+	ConfigDigest string
+	Release      plugin.ReleaseContext
+	StartedOn    time.Time
+}
+
+// embeddedNfpmVersion returns the version of the nfpm library compiled into the plugin.
+func embeddedNfpmVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == nfpmModulePath {
+			return dep.Version
+		}
+	}
+	return ""
+}
+
+// nfpmVersion returns the version of the nfpm backend in use, or "" if it cannot be determined.
+func (p *LinuxPkgPlugin) nfpmVersion(ctx context.Context, executor CommandExecutor, cfg *Config) string {
+	if usesEmbeddedNfpm(cfg.Packager) {
+		return embeddedNfpmVersion()
+	}
+	if cfg.Packager == "fpm" || cfg.Packager == "container" {
+		return ""
+	}
+
+	output, err := runCommand(ctx, executor, cfg.nfpmBinary(), "--version")
+	if err != nil {
+		return ""
+	}
+	return parseNfpmVersion(output)
+}
+
+// parseNfpmVersion extracts the version from `nfpm --version` output, which is either a
+// bare version or a banner with a "GitVersion:" line.
+func parseNfpmVersion(output []byte) string {
+	first := ""
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if version, ok := strings.CutPrefix(line, "GitVersion:"); ok {
+			return strings.TrimSpace(version)
+		}
+		if first == "" && line != "" {
+			first = line
+		}
+	}
+	if len(strings.Fields(first)) == 1 {
+		return first
+	}
+	return ""
+}
+
+// newProvenanceStatement builds the provenance statement for one package.
+func newProvenanceStatement(pc *provenanceContext, path, format, arch, digest string) provenanceStatement {
+	dependencies := []provenanceResource{{
+		Name:   filepath.ToSlash(pc.ConfigPath),
+		Digest: map[string]string{"sha256": pc.ConfigDigest},
+	}}
+	if pc.Release.CommitSHA != "" {
+		dependencies = append([]provenanceResource{{
+			URI:    "git+" + pc.Release.RepositoryURL,
+			Digest: map[string]string{"gitCommit": pc.Release.CommitSHA},
+		}}, dependencies...)
+	}
+
+	builderVersion := map[string]string{"linuxpkg": pluginVersion}
+	if pc.NfpmVersion != "" {
+		builderVersion["nfpm"] = pc.NfpmVersion
+	}
+
+	return provenanceStatement{
+		Type: inTotoStatementType,
+		Subject: []provenanceSubject{{
+			Name:   filepath.Base(path),
+			Digest: map[string]string{"sha256": digest},
+		}},
+		PredicateType: slsaProvenanceType,
+		Predicate: provenancePredicate{
+			BuildDefinition: provenanceBuildDefinition{
+				BuildType: provenanceBuildType,
+				ExternalParameters: map[string]any{
+					"config_path": filepath.ToSlash(pc.ConfigPath),
+					"format":      format,
+					"arch":        arch,
+					"version":     pc.Release.Version,
+					"tag":         pc.Release.TagName,
+				},
+				InternalParameters: map[string]any{
+					"packager": pc.Packager,
+				},
+				ResolvedDependencies: dependencies,
+			},
+			RunDetails: provenanceRunDetails{
+				Builder: provenanceBuilder{
+					ID:      pc.BuilderID,
+					Version: builderVersion,
+				},
+				Metadata: provenanceMetadata{
+					StartedOn:  pc.StartedOn,
+					FinishedOn: time.Now().UTC(),
+				},
+			},
+		},
+	}
+}
+
+// writeProvenance writes the provenance statement for a package next to it and returns its path.
+func writeProvenance(pc *provenanceContext, path, format, arch, digest string) (string, error) {
+	data, err := json.MarshalIndent(newProvenanceStatement(pc, path, format, arch, digest), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode provenance: %w", err)
+	}
+
+	provenancePath := path + provenanceExtension
+	if err := os.WriteFile(provenancePath, append(data, '\n'), 0644); err != nil {
+		return "", fmt.Errorf("failed to write provenance: %w", err)
+	}
+	return provenancePath, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestParseNfpmVersion tests extracting the version from nfpm --version output.
+func TestParseNfpmVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		output   string
+		expected string
+	}{
+		{name: "bare version", output: "2.41.1\n", expected: "2.41.1"},
+		{name: "banner", output: "  nfpm: a simple deb and rpm packager\nGitVersion:    v2.41.1\nGitCommit:     abc123\n", expected: "v2.41.1"},
+		{name: "unrecognized", output: "command not found: nfpm\n", expected: ""},
+		{name: "empty", output: "", expected: ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := parseNfpmVersion([]byte(tc.output)); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestNfpmVersionFromCLI tests that the nfpm binary is asked for its version.
+func TestNfpmVersionFromCLI(t *testing.T) {
+	t.Parallel()
+
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("GitVersion: v2.40.0\n"), nil
+		},
+	}
+
+	p := &LinuxPkgPlugin{}
+	if got := p.nfpmVersion(context.Background(), mock, &Config{Packager: "nfpm-cli"}); got != "v2.40.0" {
+		t.Errorf("expected v2.40.0, got %q", got)
+	}
+	if len(mock.Calls) != 1 || mock.Calls[0].Name != "nfpm" || mock.Calls[0].Args[0] != "--version" {
+		t.Errorf("unexpected calls: %v", mock.Calls)
+	}
+}
+
+// TestExecuteWritesProvenance tests that each package gets a provenance statement.
+func TestExecuteWritesProvenance(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir":           dir,
+			"formats":               []any{"deb"},
+			"provenance":            true,
+			"provenance_builder_id": "https://ci.example.com/builder",
+		},
+		Context: plugin.ReleaseContext{
+			Version:       "1.2.3",
+			TagName:       "v1.2.3",
+			RepositoryURL: "https://github.com/example/myapp",
+			CommitSHA:     "0123456789abcdef",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	files := resp.Outputs["provenance"].([]string)
+	artifacts := resp.Outputs["artifacts"].([]map[string]any)
+	if len(files) != 1 || artifacts[0]["provenance"] != files[0] {
+		t.Fatalf("expected provenance to be listed in outputs, got %v and %v", files, artifacts)
+	}
+	if files[0] != artifacts[0]["path"].(string)+".intoto.json" {
+		t.Errorf("expected provenance next to the package, got %s", files[0])
+	}
+
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("failed to read provenance: %v", err)
+	}
+	var statement provenanceStatement
+	if err := json.Unmarshal(data, &statement); err != nil {
+		t.Fatalf("provenance is not valid JSON: %v", err)
+	}
+
+	if statement.Type != inTotoStatementType || statement.PredicateType != slsaProvenanceType {
+		t.Errorf("unexpected statement types: %s, %s", statement.Type, statement.PredicateType)
+	}
+	if statement.Subject[0].Digest["sha256"] != artifacts[0]["sha256"] {
+		t.Errorf("expected subject digest %v, got %v", artifacts[0]["sha256"], statement.Subject[0].Digest)
+	}
+
+	runDetails := statement.Predicate.RunDetails
+	if runDetails.Builder.ID != "https://ci.example.com/builder" {
+		t.Errorf("unexpected builder id %q", runDetails.Builder.ID)
+	}
+	if runDetails.Builder.Version["linuxpkg"] != pluginVersion {
+		t.Errorf("expected plugin version in builder, got %v", runDetails.Builder.Version)
+	}
+
+	configDigest, err := fileDigest(filepath.Join(dir, "nfpm.yaml"), "sha256")
+	if err != nil {
+		t.Fatalf("failed to hash config: %v", err)
+	}
+	dependencies := statement.Predicate.BuildDefinition.ResolvedDependencies
+	if len(dependencies) != 2 {
+		t.Fatalf("expected source and config dependencies, got %v", dependencies)
+	}
+	if dependencies[0].Digest["gitCommit"] != "0123456789abcdef" {
+		t.Errorf("expected commit SHA, got %v", dependencies[0])
+	}
+	if dependencies[1].Digest["sha256"] != configDigest {
+		t.Errorf("expected config digest %s, got %v", configDigest, dependencies[1])
+	}
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// supportedPublishTargets are the publish destinations reported in capabilities.
+var supportedPublishTargets = []string{"apk", "apt", "copr", "gcs", "gemfury", "github", "gitlab", "oras", "s3", "yum"}
+
+// PublishConfig configures where built packages are delivered after the build.
+type PublishConfig struct {
+	// APT pushes debs into an APT repository managed by reprepro or aptly.
+	APT *APTPublishConfig
+	// YUM copies RPMs into a yum/dnf repository and regenerates its metadata.
+	YUM *YUMPublishConfig
+	// APK copies apk packages into an Alpine repository and regenerates APKINDEX.tar.gz.
+	APK *APKPublishConfig
+	// Gemfury uploads debs and rpms to Gemfury's push API.
+	Gemfury *GemfuryPublishConfig
+	// COPR submits a Fedora COPR build from an SRPM or the release tag.
+	COPR *COPRPublishConfig
+	// GitHub uploads packages and checksum files as assets of the GitHub release.
+	GitHub *GitHubPublishConfig
+	// GitLab uploads packages to the GitLab generic package registry.
+	GitLab *GitLabPublishConfig
+	// ORAS pushes packages and their signatures to an OCI registry.
+	ORAS *ORASPublishConfig
+	// S3 uploads packages and checksum files to an S3 bucket.
+	S3 *S3PublishConfig
+	// GCS uploads packages and checksum files to a Google Cloud Storage bucket.
+	GCS *GCSPublishConfig
+}
+
+// parsePublish parses the publish block. It returns nil when nothing is published.
+func parsePublish(raw map[string]any) *PublishConfig {
+	parser := helpers.NewConfigParser(raw)
+	block := parser.GetMap("publish")
+	if block == nil {
+		return nil
+	}
+
+	return &PublishConfig{
+		APT:     parseAPTPublish(block),
+		YUM:     parseYUMPublish(block),
+		APK:     parseAPKPublish(block, parser.GetString("apk_key_path", "", "")),
+		Gemfury: parseGemfuryPublish(block),
+		COPR:    parseCOPRPublish(block),
+		GitHub:  parseGitHubPublish(block),
+		GitLab:  parseGitLabPublish(block),
+		ORAS:    parseORASPublish(block),
+		S3:      parseS3Publish(block),
+		GCS:     parseGCSPublish(block),
+	}
+}
+
+// validate checks every configured publisher.
+func (c *PublishConfig) validate() error {
+	if c.APT != nil {
+		if err := c.APT.validate(); err != nil {
+			return fmt.Errorf("apt: %w", err)
+		}
+	}
+	if c.YUM != nil {
+		if err := c.YUM.validate(); err != nil {
+			return fmt.Errorf("yum: %w", err)
+		}
+	}
+	if c.APK != nil {
+		if err := c.APK.validate(); err != nil {
+			return fmt.Errorf("apk: %w", err)
+		}
+	}
+	if c.Gemfury != nil {
+		if err := c.Gemfury.validate(); err != nil {
+			return fmt.Errorf("gemfury: %w", err)
+		}
+	}
+	if c.COPR != nil {
+		if err := c.COPR.validate(); err != nil {
+			return fmt.Errorf("copr: %w", err)
+		}
+	}
+	if c.GitHub != nil {
+		if err := c.GitHub.validate(); err != nil {
+			return fmt.Errorf("github: %w", err)
+		}
+	}
+	if c.GitLab != nil {
+		if err := c.GitLab.validate(); err != nil {
+			return fmt.Errorf("gitlab: %w", err)
+		}
+	}
+	if c.ORAS != nil {
+		if err := c.ORAS.validate(); err != nil {
+			return fmt.Errorf("oras: %w", err)
+		}
+	}
+	if c.S3 != nil {
+		if err := c.S3.validate(); err != nil {
+			return fmt.Errorf("s3: %w", err)
+		}
+	}
+	if c.GCS != nil {
+		if err := c.GCS.validate(); err != nil {
+			return fmt.Errorf("gcs: %w", err)
+		}
+	}
+	return nil
+}
+
+// artifactsOfFormat returns the paths of built artifacts with the given format.
+func artifactsOfFormat(artifacts []map[string]any, format string) []string {
+	var paths []string
+	for _, artifact := range artifacts {
+		if artifact["format"] == format {
+			paths = append(paths, artifact["path"].(string))
+		}
+	}
+	return paths
+}
+
+// artifactPaths returns the paths of all built artifacts.
+func artifactPaths(artifacts []map[string]any) []string {
+	paths := make([]string, 0, len(artifacts))
+	for _, artifact := range artifacts {
+		paths = append(paths, artifact["path"].(string))
+	}
+	return paths
+}
+
+// publishedEntries returns the entries of a list in publish results, which is
+// []map[string]any as published and []any once read back from a release record.
+func publishedEntries(list any) []map[string]any {
+	switch list := list.(type) {
+	case []map[string]any:
+		return list
+	case []any:
+		entries := make([]map[string]any, 0, len(list))
+		for _, item := range list {
+			if entry, ok := item.(map[string]any); ok {
+				entries = append(entries, entry)
+			}
+		}
+		return entries
+	}
+	return nil
+}
+
+// publishedValues returns the key field of each entry of a list in publish results.
+func publishedValues(list any, key string) []string {
+	var values []string
+	for _, entry := range publishedEntries(list) {
+		if value, ok := entry[key].(string); ok && value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// yankablePublishTargets are the publish targets yankPublished deletes packages from.
+var yankablePublishTargets = []string{"gcs", "github", "s3"}
+
+// yankPublished deletes what publishPackages uploaded, as recorded in published, from
+// the targets in yankablePublishTargets. Packages pushed into repositories and registries
+// stay where they are. It returns what was deleted per target.
+func (p *LinuxPkgPlugin) yankPublished(ctx context.Context, executor CommandExecutor, publish *PublishConfig, published map[string]any) (map[string]any, error) {
+	results := make(map[string]any)
+
+	if result, ok := published["github"].(map[string]any); ok && publish.GitHub != nil {
+		yanked, err := p.yankGitHub(ctx, publish.GitHub, result)
+		results["github"] = yanked
+		if err != nil {
+			return results, fmt.Errorf("github: %w", err)
+		}
+	}
+
+	if result, ok := published["s3"].(map[string]any); ok && publish.S3 != nil {
+		yanked, err := p.yankS3(ctx, executor, publish.S3, result)
+		results["s3"] = yanked
+		if err != nil {
+			return results, fmt.Errorf("s3: %w", err)
+		}
+	}
+
+	if result, ok := published["gcs"].(map[string]any); ok && publish.GCS != nil {
+		yanked, err := p.yankGCS(ctx, publish.GCS, result)
+		results["gcs"] = yanked
+		if err != nil {
+			return results, fmt.Errorf("gcs: %w", err)
+		}
+	}
+
+	return results, nil
+}
+
+// expandPublishTemplate replaces {name} (repository name), {version}, and {tag} in an
+// upload path template with values from the release context.
+func expandPublishTemplate(template string, release plugin.ReleaseContext) string {
+	return strings.NewReplacer(
+		"{name}", release.RepositoryName,
+		"{version}", release.Version,
+		"{tag}", release.TagName,
+	).Replace(template)
+}
+
+// publishPackages runs every configured publisher and returns their results keyed by
+// publisher name. It stops at the first publisher that fails; individual Gemfury uploads
+// that fail are reported in its result instead.
+func (p *LinuxPkgPlugin) publishPackages(ctx context.Context, executor CommandExecutor, publish *PublishConfig, artifacts []map[string]any, checksumFiles []string, release plugin.ReleaseContext) (map[string]any, error) {
+	results := make(map[string]any)
+
+	if publish.APT != nil {
+		debs := artifactsOfFormat(artifacts, "deb")
+		if len(debs) > 0 {
+			result, err := p.publishAPT(ctx, executor, publish.APT, debs)
+			if err != nil {
+				return results, fmt.Errorf("apt: %w", err)
+			}
+			results["apt"] = result
+		}
+	}
+
+	if publish.YUM != nil {
+		rpms := artifactsOfFormat(artifacts, "rpm")
+		if len(rpms) > 0 {
+			result, err := p.publishYUM(ctx, executor, publish.YUM, rpms)
+			if err != nil {
+				return results, fmt.Errorf("yum: %w", err)
+			}
+			results["yum"] = result
+		}
+	}
+
+	if publish.APK != nil && len(artifactsOfFormat(artifacts, "apk")) > 0 {
+		result, err := p.publishAPK(ctx, executor, publish.APK, artifacts)
+		if err != nil {
+			return results, fmt.Errorf("apk: %w", err)
+		}
+		results["apk"] = result
+	}
+
+	if publish.Gemfury != nil && len(artifactsOfFormat(artifacts, "deb"))+len(artifactsOfFormat(artifacts, "rpm")) > 0 {
+		result, err := p.publishGemfury(ctx, publish.Gemfury, artifacts)
+		if err != nil {
+			return results, fmt.Errorf("gemfury: %w", err)
+		}
+		results["gemfury"] = result
+	}
+
+	if publish.COPR != nil {
+		result, err := p.publishCOPR(ctx, executor, publish.COPR, release)
+		if err != nil {
+			return results, fmt.Errorf("copr: %w", err)
+		}
+		results["copr"] = result
+	}
+
+	if publish.GitHub != nil && len(artifacts) > 0 {
+		files := artifactPaths(artifacts)
+		if publish.GitHub.Checksums {
+			files = append(files, checksumFiles...)
+		}
+		result, err := p.publishGitHub(ctx, publish.GitHub, files, release)
+		if err != nil {
+			return results, fmt.Errorf("github: %w", err)
+		}
+		results["github"] = result
+	}
+
+	if publish.GitLab != nil && len(artifacts) > 0 {
+		result, err := p.publishGitLab(ctx, publish.GitLab, append(artifactPaths(artifacts), checksumFiles...), release)
+		if err != nil {
+			return results, fmt.Errorf("gitlab: %w", err)
+		}
+		results["gitlab"] = result
+	}
+
+	if publish.ORAS != nil && len(artifacts) > 0 {
+		result, err := p.publishORAS(ctx, executor, publish.ORAS, artifacts, release.Version)
+		if err != nil {
+			return results, fmt.Errorf("oras: %w", err)
+		}
+		results["oras"] = result
+	}
+
+	if publish.S3 != nil && len(artifacts) > 0 {
+		prefix := expandPublishTemplate(publish.S3.Prefix, release)
+		if err := validateObjectPrefix(prefix); err != nil {
+			return results, fmt.Errorf("s3: %w", err)
+		}
+		result, err := p.publishS3(ctx, executor, publish.S3, append(artifactPaths(artifacts), checksumFiles...), prefix)
+		if err != nil {
+			return results, fmt.Errorf("s3: %w", err)
+		}
+		results["s3"] = result
+	}
+
+	if publish.GCS != nil && len(artifacts) > 0 {
+		prefix := expandPublishTemplate(publish.GCS.Prefix, release)
+		if err := validateObjectPrefix(prefix); err != nil {
+			return results, fmt.Errorf("gcs: %w", err)
+		}
+		result, err := p.publishGCS(ctx, publish.GCS, append(artifactPaths(artifacts), checksumFiles...), prefix)
+		if err != nil {
+			return results, fmt.Errorf("gcs: %w", err)
+		}
+		results["gcs"] = result
+	}
+
+	return results, nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// sha256Pattern matches a hex-encoded SHA-256 digest.
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// isRemoteSource reports whether a contents src is a URL to download.
+func isRemoteSource(src string) bool {
+	return strings.HasPrefix(src, "https://") || strings.HasPrefix(src, "http://")
+}
+
+// remoteFetcher downloads remote contents sources into a staging directory, once per
+// URL, verifying each against its expected SHA-256 digest.
+type remoteFetcher struct {
+	client *http.Client
+	// dir is created on the first download.
+	dir string
+	// fetched maps URLs to downloaded files.
+	fetched map[string]string
+}
+
+// newRemoteFetcher returns a fetcher that downloads with client.
+func newRemoteFetcher(client *http.Client) *remoteFetcher {
+	return &remoteFetcher{client: client, fetched: make(map[string]string)}
+}
+
+// cleanup removes everything the fetcher downloaded.
+func (f *remoteFetcher) cleanup() {
+	if f.dir != "" {
+		_ = os.RemoveAll(f.dir)
+	}
+}
+
+// resolveRemoteContents downloads the remote sources in doc's contents, and in the
+// contents of nfpm's per-format overrides, and points the entries at the downloaded
+// files. Each remote entry must carry the expected sha256, which is removed since nfpm
+// does not know it. Sources of entries with expand set are resolved with getenv first.
+// It reports whether doc changed.
+func (f *remoteFetcher) resolveRemoteContents(ctx context.Context, doc map[string]any, getenv func(string) string) (bool, error) {
+	lists := [][]any{contentEntries(doc)}
+	if overrides, ok := doc["overrides"].(map[string]any); ok {
+		for _, format := range sortedKeys(overrides) {
+			if override, ok := overrides[format].(map[string]any); ok {
+				lists = append(lists, contentEntries(override))
+			}
+		}
+	}
+
+	changed := false
+	for _, entries := range lists {
+		for _, raw := range entries {
+			entry, ok := raw.(map[string]any)
+			if !ok {
+				continue
+			}
+			src, _ := entry["src"].(string)
+			if expand, _ := entry["expand"].(bool); expand {
+				src = os.Expand(src, getenv)
+			}
+			if !isRemoteSource(src) {
+				if _, ok := entry["sha256"]; ok {
+					return false, fmt.Errorf("contents entry %s: sha256 is only supported for https sources", src)
+				}
+				continue
+			}
+
+			digest, _ := entry["sha256"].(string)
+			local, err := f.fetch(ctx, src, strings.ToLower(digest))
+			if err != nil {
+				return false, err
+			}
+			entry["src"] = local
+			delete(entry, "sha256")
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// fetch downloads rawURL and verifies it against digest, returning the local path.
+// Repeated URLs are downloaded once.
+func (f *remoteFetcher) fetch(ctx context.Context, rawURL, digest string) (string, error) {
+	if local, ok := f.fetched[rawURL]; ok {
+		return local, nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("remote content %s: only https URLs are supported", rawURL)
+	}
+	if !sha256Pattern.MatchString(digest) {
+		return "", fmt.Errorf("remote content %s: sha256 must be a hex-encoded SHA-256 digest", rawURL)
+	}
+	name := path.Base(u.Path)
+	if name == "." || name == "/" {
+		name = "download"
+	}
+
+	if f.dir == "" {
+		if f.dir, err = os.MkdirTemp("", "linuxpkg-remote-"); err != nil {
+			return "", fmt.Errorf("failed to create download directory: %w", err)
+		}
+	}
+	// Each download gets its own directory so files keep their names.
+	dir := filepath.Join(f.dir, digest[:16])
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create download directory: %w", err)
+	}
+	local := filepath.Join(dir, name)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("remote content %s: %w", rawURL, err)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download %s: %s", rawURL, resp.Status)
+	}
+
+	file, err := os.OpenFile(local, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", local, err)
+	}
+	h := sha256.New()
+	_, copyErr := io.Copy(io.MultiWriter(file, h), resp.Body)
+	if err := file.Close(); copyErr == nil {
+		copyErr = err
+	}
+	if copyErr != nil {
+		return "", fmt.Errorf("failed to download %s: %w", rawURL, copyErr)
+	}
+
+	if got := hex.EncodeToString(h.Sum(nil)); got != digest {
+		_ = os.Remove(local)
+		return "", fmt.Errorf("checksum mismatch for %s: expected sha256 %s, got %s", rawURL, digest, got)
+	}
+
+	f.fetched[rawURL] = local
+	return local, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// newRemoteContentServer serves body over TLS and counts the requests it receives.
+func newRemoteContentServer(t *testing.T, body string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// sha256Hex returns the hex SHA-256 digest of s.
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// TestResolveRemoteContents tests downloading and verifying remote contents sources.
+func TestResolveRemoteContents(t *testing.T) {
+	t.Parallel()
+
+	server, requests := newRemoteContentServer(t, "binary")
+	digest := sha256Hex("binary")
+
+	tests := []struct {
+		name        string
+		entry       map[string]any
+		expectError string
+	}{
+		{"download", map[string]any{"src": server.URL + "/v1/myapp", "dst": "/usr/bin/myapp", "sha256": digest}, ""},
+		{"expanded", map[string]any{"src": server.URL + "/v${VERSION}/myapp", "dst": "/usr/bin/myapp", "sha256": strings.ToUpper(digest), "expand": true}, ""},
+		{"missing digest", map[string]any{"src": server.URL + "/v1/myapp", "dst": "/usr/bin/myapp"}, "sha256 must be"},
+		{"wrong digest", map[string]any{"src": server.URL + "/v2/myapp", "dst": "/usr/bin/myapp", "sha256": sha256Hex("other")}, "checksum mismatch"},
+		{"not found", map[string]any{"src": server.URL + "/missing", "dst": "/usr/bin/myapp", "sha256": digest}, "404"},
+		{"plain http", map[string]any{"src": "http://example.com/myapp", "dst": "/usr/bin/myapp", "sha256": digest}, "only https"},
+		{"local digest", map[string]any{"src": "bin/myapp", "dst": "/usr/bin/myapp", "sha256": digest}, "only supported for https"},
+	}
+
+	getenv := func(key string) string {
+		if key == "VERSION" {
+			return "1"
+		}
+		return ""
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			fetcher := newRemoteFetcher(server.Client())
+			defer fetcher.cleanup()
+
+			doc := map[string]any{"contents": []any{tt.entry}}
+			changed, err := fetcher.resolveRemoteContents(context.Background(), doc, getenv)
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Errorf("expected error containing %q, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			src := tt.entry["src"].(string)
+			if !changed || !strings.HasSuffix(src, "/myapp") || strings.HasPrefix(src, "https://") || tt.entry["sha256"] != nil {
+				t.Fatalf("expected the entry to point at the download without sha256, got %v", tt.entry)
+			}
+			content, err := os.ReadFile(src)
+			if err != nil || string(content) != "binary" {
+				t.Errorf("expected the downloaded file, got %q, %v", content, err)
+			}
+		})
+	}
+
+	// Repeated URLs are downloaded once.
+	fetcher := newRemoteFetcher(server.Client())
+	defer fetcher.cleanup()
+	before := requests.Load()
+	for i := 0; i < 2; i++ {
+		doc := map[string]any{"contents": []any{map[string]any{"src": server.URL + "/v3/myapp", "dst": "/usr/bin/myapp", "sha256": digest}}}
+		if _, err := fetcher.resolveRemoteContents(context.Background(), doc, getenv); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if got := requests.Load() - before; got != 1 {
+		t.Errorf("expected one download, got %d", got)
+	}
+}
+
+// TestExecuteRemoteContents tests packaging a downloaded binary with the embedded library.
+func TestExecuteRemoteContents(t *testing.T) {
+	server, _ := newRemoteContentServer(t, "#!/bin/sh\necho myapp\n")
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	config := "name: myapp\nversion: 1.2.3\narch: amd64\ncontents:\n" +
+		"  - src: " + server.URL + "/releases/myapp\n" +
+		"    dst: /usr/bin/myapp\n" +
+		"    sha256: " + sha256Hex("#!/bin/sh\necho myapp\n") + "\n" +
+		"    file_info:\n      mode: 0755\n"
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	p := &LinuxPkgPlugin{httpClient: server.Client()}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"working_dir": dir, "formats": []string{"deb", "rpm"}},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+	if packages := resp.Outputs["packages"].([]string); len(packages) != 2 {
+		t.Errorf("expected 2 packages, got %v", packages)
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// reportJSONName and reportMarkdownName are the packaging report files written to the
+// output directory.
+const (
+	reportJSONName     = "linuxpkg-report.json"
+	reportMarkdownName = "linuxpkg-report.md"
+)
+
+// packagingReport summarizes what a release packaged and where it was published, for
+// release notes and audit systems.
+type packagingReport struct {
+	Version           string           `json:"version"`
+	Packages          []reportPackage  `json:"packages"`
+	TotalSize         int64            `json:"total_size"`
+	ChecksumFiles     []string         `json:"checksum_files,omitempty"`
+	BuildDurationMS   int64            `json:"build_duration_ms"`
+	PublishDurationMS int64            `json:"publish_duration_ms,omitempty"`
+	Builds            []map[string]any `json:"builds,omitempty"`
+	Published         map[string]any   `json:"published,omitempty"`
+	Diff              []packageDiff    `json:"diff,omitempty"`
+}
+
+// reportPackage describes one package in a packaging report.
+type reportPackage struct {
+	Name          string   `json:"name"`
+	Path          string   `json:"path"`
+	Format        string   `json:"format"`
+	Arch          string   `json:"arch"`
+	Distro        string   `json:"distro,omitempty"`
+	Size          int64    `json:"size"`
+	InstalledSize int64    `json:"installed_size,omitempty"`
+	FileCount     int64    `json:"file_count,omitempty"`
+	SHA256        string   `json:"sha256"`
+	Signed        bool     `json:"signed,omitempty"`
+	Signature     string   `json:"signature,omitempty"`
+	Certificate   string   `json:"certificate,omitempty"`
+	Provenance    string   `json:"provenance,omitempty"`
+	DurationMS    int64    `json:"duration_ms,omitempty"`
+	URLs          []string `json:"urls,omitempty"`
+}
+
+// recordInt returns a number from a release record, which holds int64 values as built
+// and float64 values once read back.
+func recordInt(value any) int64 {
+	switch value := value.(type) {
+	case int64:
+		return value
+	case int:
+		return int64(value)
+	case float64:
+		return int64(value)
+	}
+	return 0
+}
+
+// publishedURLs maps file names to the URLs publish targets report for them, from the
+// name and url of entries in the target results.
+func publishedURLs(published map[string]any) map[string][]string {
+	urls := make(map[string][]string)
+	for _, target := range sortedKeys(published) {
+		result, ok := published[target].(map[string]any)
+		if !ok {
+			continue
+		}
+		for _, key := range sortedKeys(result) {
+			for _, entry := range publishedEntries(result[key]) {
+				name, _ := entry["name"].(string)
+				link, _ := entry["url"].(string)
+				if name != "" && link != "" {
+					urls[name] = append(urls[name], link)
+				}
+			}
+		}
+	}
+	return urls
+}
+
+// newPackagingReport builds the packaging report of a release record.
+func newPackagingReport(record *releaseRecord) *packagingReport {
+	durations := make(map[string]int64, len(record.Builds))
+	for _, build := range record.Builds {
+		if path, ok := build["package"].(string); ok {
+			durations[path] = recordInt(build["duration_ms"])
+		}
+	}
+	urls := publishedURLs(record.Published)
+
+	report := &packagingReport{
+		Version:           record.Version,
+		Packages:          make([]reportPackage, 0, len(record.Artifacts)),
+		ChecksumFiles:     record.ChecksumFiles,
+		BuildDurationMS:   record.BuildDurationMS,
+		PublishDurationMS: record.PublishDurationMS,
+		Builds:            record.Builds,
+		Published:         record.Published,
+		Diff:              record.Diff,
+	}
+	for _, artifact := range record.Artifacts {
+		pkg := reportPackage{
+			Size:          recordInt(artifact["size"]),
+			InstalledSize: recordInt(artifact["installed_size"]),
+			FileCount:     recordInt(artifact["file_count"]),
+		}
+		pkg.Path, _ = artifact["path"].(string)
+		pkg.Name = filepath.Base(pkg.Path)
+		pkg.Format, _ = artifact["format"].(string)
+		pkg.Arch, _ = artifact["arch"].(string)
+		pkg.Distro, _ = artifact["distro"].(string)
+		pkg.SHA256, _ = artifact["sha256"].(string)
+		pkg.Signed, _ = artifact["signed"].(bool)
+		pkg.Signature, _ = artifact["signature"].(string)
+		pkg.Certificate, _ = artifact["certificate"].(string)
+		pkg.Provenance, _ = artifact["provenance"].(string)
+		pkg.DurationMS = durations[pkg.Path]
+		pkg.URLs = urls[pkg.Name]
+		report.Packages = append(report.Packages, pkg)
+		report.TotalSize += pkg.Size
+	}
+	return report
+}
+
+// markdown renders the report for release notes.
+func (r *packagingReport) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Linux packages for %s\n\n", r.Version)
+
+	fmt.Fprintf(&b, "Built %d package(s), %s in total, in %s", len(r.Packages), formatSize(r.TotalSize), reportDuration(r.BuildDurationMS))
+	if r.PublishDurationMS > 0 {
+		fmt.Fprintf(&b, "; published in %s", reportDuration(r.PublishDurationMS))
+	}
+	b.WriteString(".\n\n")
+
+	b.WriteString("| Package | Format | Arch | Size | SHA-256 | Signed |\n")
+	b.WriteString("|---------|--------|------|------|---------|--------|\n")
+	for _, pkg := range r.Packages {
+		signed := "no"
+		if pkg.Signed || pkg.Signature != "" {
+			signed = "yes"
+		}
+		arch := pkg.Arch
+		if pkg.Distro != "" {
+			arch += " (" + pkg.Distro + ")"
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s | `%s` | %s |\n", pkg.Name, pkg.Format, arch, formatSize(pkg.Size), pkg.SHA256, signed)
+	}
+
+	if len(r.Diff) > 0 {
+		b.WriteString("\n## Changes since the previous release\n\n")
+		for _, diff := range r.Diff {
+			fmt.Fprintf(&b, "- `%s`: %s\n", filepath.Base(diff.Package), diff.summary())
+			for _, change := range []struct {
+				label string
+				items []string
+			}{
+				{"Added", diff.FilesAdded},
+				{"Removed", diff.FilesRemoved},
+				{"Changed", diff.FilesChanged},
+				{"New dependency", diff.DependsAdded},
+				{"Dropped dependency", diff.DependsRemoved},
+			} {
+				for i, item := range change.items {
+					if i == maxReportItems {
+						fmt.Fprintf(&b, "  - %s: %d more\n", change.label, len(change.items)-i)
+						break
+					}
+					fmt.Fprintf(&b, "  - %s: `%s`\n", change.label, item)
+				}
+			}
+		}
+	}
+
+	if len(r.ChecksumFiles) > 0 {
+		b.WriteString("\n## Checksum files\n\n")
+		for _, file := range r.ChecksumFiles {
+			fmt.Fprintf(&b, "- `%s`\n", filepath.Base(file))
+		}
+	}
+
+	var attestations, downloads []string
+	for _, pkg := range r.Packages {
+		var files []string
+		for _, file := range []string{pkg.Signature, pkg.Certificate, pkg.Provenance} {
+			if file != "" {
+				files = append(files, "`"+filepath.Base(file)+"`")
+			}
+		}
+		if len(files) > 0 {
+			attestations = append(attestations, fmt.Sprintf("- `%s`: %s", pkg.Name, strings.Join(files, ", ")))
+		}
+		for _, url := range pkg.URLs {
+			downloads = append(downloads, fmt.Sprintf("- `%s`: %s", pkg.Name, url))
+		}
+	}
+	if len(attestations) > 0 {
+		b.WriteString("\n## Signatures and provenance\n\n")
+		b.WriteString(strings.Join(attestations, "\n") + "\n")
+	}
+	if len(downloads) > 0 {
+		sort.Strings(downloads)
+		b.WriteString("\n## Downloads\n\n")
+		b.WriteString(strings.Join(downloads, "\n") + "\n")
+	}
+	return b.String()
+}
+
+// maxReportItems is how many files or dependencies of each kind of change the Markdown
+// report lists per package; the JSON report has them all.
+const maxReportItems = 20
+
+// reportDuration formats a duration in milliseconds for the report.
+func reportDuration(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).String()
+}
+
+// writeReport writes the JSON and Markdown report to outputDir and returns their paths.
+func writeReport(outputDir string, report *packagingReport) ([]string, error) {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode packaging report: %w", err)
+	}
+	jsonPath := filepath.Join(outputDir, reportJSONName)
+	if err := os.WriteFile(jsonPath, append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write packaging report: %w", err)
+	}
+	markdownPath := filepath.Join(outputDir, reportMarkdownName)
+	if err := os.WriteFile(markdownPath, []byte(report.markdown()), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write packaging report: %w", err)
+	}
+	return []string{jsonPath, markdownPath}, nil
+}
+
+// reportRecorded writes the packaging report of the packages an earlier build recorded
+// for the release. Without a record for the release there is nothing to report.
+func reportRecorded(cfg *Config, releaseCtx plugin.ReleaseContext, dryRun bool) (*plugin.ExecuteResponse, error) {
+	if err := resolveRecordDir(cfg); err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	record, err := readReleaseRecord(cfg.OutputDir)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && record.Version != releaseCtx.Version) {
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: fmt.Sprintf("No packages recorded for %s", releaseCtx.Version),
+		}, nil
+	}
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	report := newPackagingReport(record)
+	if dryRun {
+		return &plugin.ExecuteResponse{
+			Success: true,
+			Message: fmt.Sprintf("Would write a packaging report for %d package(s)", len(report.Packages)),
+			Outputs: map[string]any{
+				"report":  report,
+				"version": releaseCtx.Version,
+			},
+		}, nil
+	}
+
+	files, err := writeReport(cfg.OutputDir, report)
+	if err == nil {
+		err = cfg.applyOutputModes(nil, files)
+	}
+	if err != nil {
+		return &plugin.ExecuteResponse{
+			Success: false,
+			Error:   err.Error(),
+		}, nil
+	}
+
+	return &plugin.ExecuteResponse{
+		Success: true,
+		Message: fmt.Sprintf("Wrote packaging report for %d package(s) to %s", len(report.Packages), files[1]),
+		Outputs: map[string]any{
+			"report":       report,
+			"report_files": files,
+			"version":      releaseCtx.Version,
+		},
+	}, nil
+}
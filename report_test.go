@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestNewPackagingReport tests summarizing a release record read back from disk.
+func TestNewPackagingReport(t *testing.T) {
+	t.Parallel()
+
+	record := releaseRecord{
+		Version: "1.0.0",
+		Artifacts: []map[string]any{
+			{"path": "dist/myapp.deb", "format": "deb", "arch": "amd64", "sha256": "abc", "size": int64(2048)},
+			{"path": "dist/myapp.rpm", "format": "rpm", "arch": "amd64", "sha256": "def", "size": int64(1024), "signed": true, "signature": "dist/myapp.rpm.sig"},
+		},
+		ChecksumFiles:     []string{"dist/checksums.txt"},
+		Builds:            []map[string]any{{"format": "deb", "package": "dist/myapp.deb", "duration_ms": int64(1500)}},
+		BuildDurationMS:   2000,
+		PublishDurationMS: 500,
+		Published: map[string]any{
+			"github": map[string]any{"assets": []map[string]any{{"name": "myapp.deb", "url": "https://example.com/myapp.deb"}}},
+		},
+		Diff: []packageDiff{{
+			Package:            "dist/myapp.deb",
+			Format:             "deb",
+			Arch:               "amd64",
+			Previous:           "previous/myapp.deb",
+			FilesAdded:         []string{"/usr/share/doc/myapp/README"},
+			DependsRemoved:     []string{"libc6"},
+			SizeDelta:          -2048,
+			InstalledSizeDelta: 6,
+		}},
+	}
+	// Read the record back as the on-success hook does.
+	data, err := json.Marshal(record)
+	if err != nil {
+		t.Fatalf("failed to encode record: %v", err)
+	}
+	var decoded releaseRecord
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to decode record: %v", err)
+	}
+
+	report := newPackagingReport(&decoded)
+	if report.TotalSize != 3072 {
+		t.Errorf("expected a total size of 3072, got %d", report.TotalSize)
+	}
+	deb, rpm := report.Packages[0], report.Packages[1]
+	if deb.DurationMS != 1500 || !reflect.DeepEqual(deb.URLs, []string{"https://example.com/myapp.deb"}) {
+		t.Errorf("expected the deb duration and download URL, got %+v", deb)
+	}
+	if !rpm.Signed || rpm.Signature != "dist/myapp.rpm.sig" {
+		t.Errorf("expected the rpm signature, got %+v", rpm)
+	}
+
+	markdown := report.markdown()
+	for _, want := range []string{
+		"# Linux packages for 1.0.0",
+		"Built 2 package(s), 3.0 KiB in total, in 2s; published in 500ms.",
+		"| `myapp.rpm` | rpm | amd64 | 1.0 KiB | `def` | yes |",
+		"- `checksums.txt`",
+		"- `myapp.rpm`: `myapp.rpm.sig`",
+		"- `myapp.deb`: https://example.com/myapp.deb",
+		"- `myapp.deb`: 1 file(s) added, 0 removed, 0 changed; 0 dependency(ies) added, 1 removed; size -2.0 KiB, installed size +6 B",
+		"  - Added: `/usr/share/doc/myapp/README`",
+		"  - Dropped dependency: `libc6`",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("expected the report to contain %q, got:\n%s", want, markdown)
+		}
+	}
+}
+
+// TestExecuteOnSuccessReport tests that the on-success hook writes the packaging report
+// of the release when report_on_success is set.
+func TestExecuteOnSuccessReport(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		config       map[string]any
+		expectReport bool
+	}{
+		{"enabled", map[string]any{"report_on_success": true}, true},
+		{"default", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			writeEmbeddedTestConfig(t, dir, "amd64")
+			config := map[string]any{"working_dir": dir, "formats": []any{"deb", "rpm"}}
+			for key, value := range tt.config {
+				config[key] = value
+			}
+
+			p := &LinuxPkgPlugin{}
+			for _, hook := range []plugin.Hook{plugin.HookPostPublish, plugin.HookOnSuccess} {
+				resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+					Hook:    hook,
+					Config:  config,
+					Context: plugin.ReleaseContext{Version: "1.0.0"},
+				})
+				if err != nil || !resp.Success {
+					t.Fatalf("expected %s to succeed, got %v, %+v", hook, err, resp)
+				}
+				if hook != plugin.HookOnSuccess {
+					continue
+				}
+
+				report, ok := resp.Outputs["report"].(*packagingReport)
+				if ok != tt.expectReport {
+					t.Fatalf("expected report %v, got %+v", tt.expectReport, resp)
+				}
+				if ok && len(report.Packages) != 2 {
+					t.Errorf("expected two packages in the report, got %+v", report.Packages)
+				}
+			}
+
+			data, err := os.ReadFile(filepath.Join(dir, "dist", reportMarkdownName))
+			if !tt.expectReport {
+				if !os.IsNotExist(err) {
+					t.Errorf("expected no report, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected a report: %v", err)
+			}
+			if !strings.Contains(string(data), "Built 2 package(s)") {
+				t.Errorf("unexpected report:\n%s", data)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "dist", reportJSONName)); err != nil {
+				t.Errorf("expected a JSON report: %v", err)
+			}
+		})
+	}
+}
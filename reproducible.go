@@ -0,0 +1,49 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// sourceDateEpochEnv is the variable reproducible builds take their timestamp from.
+const sourceDateEpochEnv = "SOURCE_DATE_EPOCH"
+
+// parseSourceDateEpoch parses a SOURCE_DATE_EPOCH value, in seconds since the Unix epoch.
+func parseSourceDateEpoch(value string) (time.Time, error) {
+	seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || seconds < 0 {
+		return time.Time{}, fmt.Errorf("invalid %s: %q", sourceDateEpochEnv, value)
+	}
+	return time.Unix(seconds, 0).UTC(), nil
+}
+
+// sourceDate returns the timestamp of a reproducible build: SOURCE_DATE_EPOCH from the
+// release environment or the process environment, or else the committer date of the
+// release commit, or of its tag without a commit, read with git in the working directory.
+func (p *LinuxPkgPlugin) sourceDate(ctx context.Context, cfg *Config, release plugin.ReleaseContext) (time.Time, error) {
+	if value := cmp.Or(release.Environment[sourceDateEpochEnv], os.Getenv(sourceDateEpochEnv)); value != "" {
+		return parseSourceDateEpoch(value)
+	}
+
+	ref := cmp.Or(release.CommitSHA, release.TagName, "HEAD")
+	result, err := p.getExecutor().Exec(ctx, ExecSpec{
+		Name: "git",
+		Args: []string{"log", "-1", "--format=%ct", ref},
+		Dir:  cfg.WorkingDir,
+	})
+	if err != nil {
+		output := ""
+		if result != nil {
+			output = strings.TrimSpace(string(result.Combined))
+		}
+		return time.Time{}, fmt.Errorf("failed to get the commit date of %s: %w: %s", ref, err, output)
+	}
+	return parseSourceDateEpoch(string(result.Stdout))
+}
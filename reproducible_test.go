@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestSourceDate tests taking the build time from SOURCE_DATE_EPOCH or the commit date.
+// Note: This test cannot run in parallel due to t.Setenv usage.
+func TestSourceDate(t *testing.T) {
+	t.Setenv(sourceDateEpochEnv, "")
+
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("1700000000\n"), nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	cfg := &Config{WorkingDir: "/src"}
+
+	date, err := p.sourceDate(context.Background(), cfg, plugin.ReleaseContext{CommitSHA: "abc123"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !date.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("unexpected date: %v", date)
+	}
+	expectedArgs := []string{"log", "-1", "--format=%ct", "abc123"}
+	if len(mock.Calls) != 1 || mock.Calls[0].Name != "git" || !reflect.DeepEqual(mock.Calls[0].Args, expectedArgs) || mock.Calls[0].Dir != "/src" {
+		t.Errorf("expected git %v in /src, got %+v", expectedArgs, mock.Calls)
+	}
+
+	date, err = p.sourceDate(context.Background(), cfg, plugin.ReleaseContext{
+		CommitSHA:   "abc123",
+		Environment: map[string]string{sourceDateEpochEnv: "1600000000"},
+	})
+	if err != nil || !date.Equal(time.Unix(1600000000, 0)) {
+		t.Errorf("expected the release environment to win, got %v, %v", date, err)
+	}
+	if len(mock.Calls) != 1 {
+		t.Errorf("expected git not to run again, got %+v", mock.Calls)
+	}
+
+	if _, err := p.sourceDate(context.Background(), cfg, plugin.ReleaseContext{
+		Environment: map[string]string{sourceDateEpochEnv: "yesterday"},
+	}); err == nil {
+		t.Error("expected an invalid SOURCE_DATE_EPOCH to fail")
+	}
+}
+
+// TestExecuteReproducible tests that two builds of the same release are byte-identical,
+// even when the packaged files change mtime in between.
+func TestExecuteReproducible(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+
+	p := &LinuxPkgPlugin{}
+	digests := make([]map[string]any, 0, 2)
+	for i, outputDir := range []string{"first", "second"} {
+		// Touch the packaged binary so only the pinned mtime can make the builds match.
+		touched := time.Now().Add(time.Duration(i) * time.Hour)
+		if err := os.Chtimes(filepath.Join(dir, "myapp"), touched, touched); err != nil {
+			t.Fatalf("failed to touch binary: %v", err)
+		}
+
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook: plugin.HookPostPublish,
+			Config: map[string]any{
+				"working_dir":  dir,
+				"output_dir":   outputDir,
+				"formats":      []any{"deb", "rpm", "apk", "archlinux"},
+				"reproducible": true,
+			},
+			Context: plugin.ReleaseContext{
+				Version:     "1.0.0",
+				Environment: map[string]string{sourceDateEpochEnv: "1700000000"},
+			},
+		})
+		if err != nil || !resp.Success {
+			t.Fatalf("expected build %d to succeed, got %v, %+v", i, err, resp)
+		}
+
+		byFormat := make(map[string]any)
+		for _, artifact := range resp.Outputs["artifacts"].([]map[string]any) {
+			byFormat[artifact["format"].(string)] = artifact["sha256"]
+		}
+		digests = append(digests, byFormat)
+	}
+
+	if !reflect.DeepEqual(digests[0], digests[1]) {
+		t.Errorf("expected identical packages, got %v and %v", digests[0], digests[1])
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// publishOrder lists the publish targets in the order publishPackages runs them.
+var publishOrder = []string{"apt", "yum", "apk", "gemfury", "copr", "github", "gitlab", "oras", "s3", "gcs"}
+
+// targets returns the configured publish targets in publish order.
+func (c *PublishConfig) targets() []string {
+	var targets []string
+	for _, target := range publishOrder {
+		if c.only(target) != nil {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// only returns a copy of c that publishes to target alone, or nil when target is not
+// configured.
+func (c *PublishConfig) only(target string) *PublishConfig {
+	single := &PublishConfig{}
+	switch {
+	case target == "apt" && c.APT != nil:
+		single.APT = c.APT
+	case target == "yum" && c.YUM != nil:
+		single.YUM = c.YUM
+	case target == "apk" && c.APK != nil:
+		single.APK = c.APK
+	case target == "gemfury" && c.Gemfury != nil:
+		single.Gemfury = c.Gemfury
+	case target == "copr" && c.COPR != nil:
+		single.COPR = c.COPR
+	case target == "github" && c.GitHub != nil:
+		single.GitHub = c.GitHub
+	case target == "gitlab" && c.GitLab != nil:
+		single.GitLab = c.GitLab
+	case target == "oras" && c.ORAS != nil:
+		single.ORAS = c.ORAS
+	case target == "s3" && c.S3 != nil:
+		single.S3 = c.S3
+	case target == "gcs" && c.GCS != nil:
+		single.GCS = c.GCS
+	default:
+		return nil
+	}
+	return single
+}
+
+// isDelivered reports whether every publish target received the artifact, by digest.
+func isDelivered(artifact map[string]any, targets []string, delivered map[string][]string) bool {
+	digest, _ := artifact["sha256"].(string)
+	for _, target := range targets {
+		if !slices.Contains(delivered[target], digest) {
+			return false
+		}
+	}
+	return digest != ""
+}
+
+// markSkipped marks the artifacts reused from the build cache that every publish target
+// already received as skipped, and returns how many it marked.
+func markSkipped(artifacts []map[string]any, publish *PublishConfig, delivered map[string][]string) int {
+	var targets []string
+	if publish != nil {
+		targets = publish.targets()
+	}
+	skipped := 0
+	for _, artifact := range artifacts {
+		if artifact["cached"] == true && isDelivered(artifact, targets, delivered) {
+			artifact["skipped"] = true
+			skipped++
+		}
+	}
+	return skipped
+}
+
+// publishNew publishes to each target the artifacts it has not received yet, according
+// to delivered, which maps targets to the digests of the packages they received and is
+// updated as they receive more. A target that already received every artifact keeps
+// its result from previous. Like publishPackages, it stops at the first target that
+// fails.
+func (p *LinuxPkgPlugin) publishNew(ctx context.Context, executor CommandExecutor, publish *PublishConfig, artifacts []map[string]any, checksumFiles []string, release plugin.ReleaseContext, previous map[string]any, delivered map[string][]string) (map[string]any, error) {
+	results := make(map[string]any)
+	for _, target := range publish.targets() {
+		var pending []map[string]any
+		for _, artifact := range artifacts {
+			if !isDelivered(artifact, []string{target}, delivered) {
+				pending = append(pending, artifact)
+			}
+		}
+		if result, ok := previous[target]; ok && len(pending) == 0 {
+			results[target] = result
+			continue
+		}
+
+		result, err := p.publishPackages(ctx, executor, publish.only(target), pending, checksumFiles, release)
+		maps.Copy(results, result)
+		if err != nil {
+			return results, err
+		}
+		if _, ok := result[target]; ok {
+			for _, artifact := range pending {
+				if digest, _ := artifact["sha256"].(string); digest != "" && !slices.Contains(delivered[target], digest) {
+					delivered[target] = append(delivered[target], digest)
+				}
+			}
+		}
+	}
+	return results, nil
+}
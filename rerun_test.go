@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestExecuteSkipExisting tests that re-running a release only uploads what a publish
+// target is missing and skips packages already built and published.
+func TestExecuteSkipExisting(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+
+	failing := true
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if failing {
+				return []byte("access denied"), errors.New("exit status 1")
+			}
+			return nil, nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	req := plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir":   dir,
+			"formats":       []any{"deb", "rpm"},
+			"skip_existing": true,
+			"publish":       map[string]any{"s3": map[string]any{"bucket": "pkgs"}},
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0", RepositoryName: "myapp"},
+	}
+	// uploads runs the release and returns the packages it uploaded.
+	uploads := func() ([]string, *plugin.ExecuteResponse) {
+		t.Helper()
+		mock.Calls = nil
+		resp, err := p.Execute(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var uploaded []string
+		for _, call := range mock.Calls {
+			for _, arg := range call.Args {
+				if strings.HasSuffix(arg, ".deb") || strings.HasSuffix(arg, ".rpm") {
+					uploaded = append(uploaded, arg)
+					break
+				}
+			}
+		}
+		return uploaded, resp
+	}
+
+	// The first upload fails, so nothing was delivered.
+	if _, resp := uploads(); resp.Success {
+		t.Fatalf("expected the publish to fail, got %+v", resp)
+	}
+
+	failing = false
+	uploaded, resp := uploads()
+	if !resp.Success {
+		t.Fatalf("expected success, got %s", resp.Error)
+	}
+	if len(uploaded) != 2 {
+		t.Errorf("expected both packages to be uploaded, got %v", uploaded)
+	}
+	for _, artifact := range resp.Outputs["artifacts"].([]map[string]any) {
+		if artifact["cached"] != true || artifact["skipped"] == true {
+			t.Errorf("expected a reused package that is not skipped, got %v", artifact)
+		}
+	}
+
+	uploaded, resp = uploads()
+	if !resp.Success {
+		t.Fatalf("expected success, got %s", resp.Error)
+	}
+	if len(uploaded) != 0 {
+		t.Errorf("expected nothing to be uploaded again, got %v", uploaded)
+	}
+	for _, artifact := range resp.Outputs["artifacts"].([]map[string]any) {
+		if artifact["skipped"] != true {
+			t.Errorf("expected a skipped package, got %v", artifact)
+		}
+	}
+	if !strings.Contains(resp.Message, "2 skipped as already published") {
+		t.Errorf("unexpected message: %s", resp.Message)
+	}
+	if _, ok := resp.Outputs["published"].(map[string]any)["s3"]; !ok {
+		t.Errorf("expected the earlier s3 result, got %v", resp.Outputs["published"])
+	}
+}
+
+// TestPublishConfigTargets tests listing publish targets in publish order.
+func TestPublishConfigTargets(t *testing.T) {
+	t.Parallel()
+
+	publish := &PublishConfig{GCS: &GCSPublishConfig{}, APT: &APTPublishConfig{}, GitHub: &GitHubPublishConfig{}}
+	targets := publish.targets()
+	if strings.Join(targets, ",") != "apt,github,gcs" {
+		t.Errorf("unexpected targets: %v", targets)
+	}
+	if single := publish.only("github"); single == nil || single.GitHub == nil || single.APT != nil {
+		t.Errorf("unexpected github config: %+v", single)
+	}
+	if publish.only("yum") != nil {
+		t.Error("expected no yum config")
+	}
+}
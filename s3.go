@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// defaultObjectPrefix is the object key prefix used when none is configured.
+const defaultObjectPrefix = "{name}/{version}/"
+
+// allowedS3SSE are the supported server-side encryption modes.
+var allowedS3SSE = map[string]bool{
+	"":        true,
+	"AES256":  true,
+	"aws:kms": true,
+}
+
+// s3BucketPattern validates S3 bucket names.
+var s3BucketPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// s3RegionPattern validates AWS region names.
+var s3RegionPattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// S3PublishConfig configures uploading packages and checksum files to S3 with the AWS CLI.
+type S3PublishConfig struct {
+	// Bucket is the destination bucket.
+	Bucket string
+	// Prefix is the object key prefix; see expandPublishTemplate for placeholders.
+	Prefix string
+	// Region is the bucket region. Empty uses the AWS CLI configuration.
+	Region string
+	// SSE is the server-side encryption mode: "AES256" or "aws:kms".
+	SSE string
+	// SSEKMSKeyID is the KMS key for "aws:kms" encryption. Empty uses the AWS managed key.
+	SSEKMSKeyID string
+	// EndpointURL targets an S3-compatible service instead of AWS.
+	EndpointURL string
+	// AccessKeyIDEnv and SecretAccessKeyEnv name environment variables holding static
+	// credentials. When empty, the AWS credential chain is used.
+	AccessKeyIDEnv     string
+	SecretAccessKeyEnv string
+}
+
+// parseS3Publish parses the publish.s3 block. It returns nil when it is not set.
+func parseS3Publish(publish map[string]any) *S3PublishConfig {
+	block := helpers.NewConfigParser(publish).GetMap("s3")
+	if block == nil {
+		return nil
+	}
+
+	parser := helpers.NewConfigParser(block)
+	return &S3PublishConfig{
+		Bucket:             parser.GetString("bucket", "", ""),
+		Prefix:             parser.GetString("prefix", "", defaultObjectPrefix),
+		Region:             parser.GetString("region", "", ""),
+		SSE:                parser.GetString("sse", "", ""),
+		SSEKMSKeyID:        parser.GetString("sse_kms_key_id", "", ""),
+		EndpointURL:        parser.GetString("endpoint_url", "", ""),
+		AccessKeyIDEnv:     parser.GetString("access_key_id_env", "", ""),
+		SecretAccessKeyEnv: parser.GetString("secret_access_key_env", "", ""),
+	}
+}
+
+// validate checks the S3 publishing settings.
+func (c *S3PublishConfig) validate() error {
+	if !s3BucketPattern.MatchString(c.Bucket) {
+		return fmt.Errorf("invalid bucket: %q", c.Bucket)
+	}
+	if err := validateObjectPrefix(c.Prefix); err != nil {
+		return err
+	}
+	if c.Region != "" && !s3RegionPattern.MatchString(c.Region) {
+		return fmt.Errorf("invalid region: %q", c.Region)
+	}
+	if !allowedS3SSE[c.SSE] {
+		return fmt.Errorf("invalid sse: %s (allowed: AES256, aws:kms)", c.SSE)
+	}
+	if c.SSEKMSKeyID != "" && c.SSE != "aws:kms" {
+		return fmt.Errorf("sse_kms_key_id requires sse: aws:kms")
+	}
+	if strings.HasPrefix(c.SSEKMSKeyID, "-") {
+		return fmt.Errorf("sse_kms_key_id cannot start with '-'")
+	}
+	if c.EndpointURL != "" {
+		u, err := url.Parse(c.EndpointURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("endpoint_url must be an http(s) URL: %s", c.EndpointURL)
+		}
+	}
+	if (c.AccessKeyIDEnv == "") != (c.SecretAccessKeyEnv == "") {
+		return fmt.Errorf("access_key_id_env and secret_access_key_env must be set together")
+	}
+	return nil
+}
+
+// validateObjectPrefix rejects object prefixes that escape the bucket root.
+func validateObjectPrefix(prefix string) error {
+	if strings.HasPrefix(prefix, "/") || strings.HasPrefix(prefix, "-") {
+		return fmt.Errorf("prefix cannot start with '/' or '-'")
+	}
+	for _, part := range strings.Split(prefix, "/") {
+		if part == ".." {
+			return fmt.Errorf("prefix cannot contain '..'")
+		}
+	}
+	return nil
+}
+
+// credentialsEnv returns the static credentials as AWS CLI environment variables, or nil
+// to use the credential chain.
+func (c *S3PublishConfig) credentialsEnv() ([]string, error) {
+	if c.AccessKeyIDEnv == "" {
+		return nil, nil
+	}
+	accessKeyID, secretAccessKey := os.Getenv(c.AccessKeyIDEnv), os.Getenv(c.SecretAccessKeyEnv)
+	if accessKeyID == "" || secretAccessKey == "" {
+		return nil, fmt.Errorf("static credentials not set: %s or %s is empty", c.AccessKeyIDEnv, c.SecretAccessKeyEnv)
+	}
+	return []string{"AWS_ACCESS_KEY_ID=" + accessKeyID, "AWS_SECRET_ACCESS_KEY=" + secretAccessKey}, nil
+}
+
+// objectURL returns the HTTPS URL of an object.
+func (c *S3PublishConfig) objectURL(key string) string {
+	escaped := (&url.URL{Path: key}).EscapedPath()
+	switch {
+	case c.EndpointURL != "":
+		return strings.TrimSuffix(c.EndpointURL, "/") + "/" + c.Bucket + "/" + escaped
+	case c.Region != "":
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", c.Bucket, c.Region, escaped)
+	default:
+		return fmt.Sprintf("https://%s.s3.amazonaws.com/%s", c.Bucket, escaped)
+	}
+}
+
+// publishS3 uploads every file with `aws s3 cp` and returns the object URLs.
+func (p *LinuxPkgPlugin) publishS3(ctx context.Context, executor CommandExecutor, c *S3PublishConfig, files []string, prefix string) (map[string]any, error) {
+	env, err := c.credentialsEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make([]map[string]any, 0, len(files))
+	for _, file := range files {
+		key := path.Join(prefix, filepath.Base(file))
+		uri := "s3://" + c.Bucket + "/" + key
+
+		args := []string{"s3", "cp", "--only-show-errors"}
+		if c.Region != "" {
+			args = append(args, "--region", c.Region)
+		}
+		if c.EndpointURL != "" {
+			args = append(args, "--endpoint-url", c.EndpointURL)
+		}
+		if c.SSE != "" {
+			args = append(args, "--sse", c.SSE)
+		}
+		if c.SSEKMSKeyID != "" {
+			args = append(args, "--sse-kms-key-id", c.SSEKMSKeyID)
+		}
+		args = append(args, file, uri)
+
+		output, err := runWithEnv(ctx, executor, env, "aws", args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upload %s: %w\nOutput: %s", file, err, string(output))
+		}
+		objects = append(objects, map[string]any{
+			"name": filepath.Base(file),
+			"uri":  uri,
+			"url":  c.objectURL(key),
+		})
+	}
+
+	return map[string]any{
+		"bucket":  c.Bucket,
+		"prefix":  prefix,
+		"objects": objects,
+	}, nil
+}
+
+// yankS3 deletes the objects a publish uploaded, as recorded in published, with
+// `aws s3 rm`.
+func (p *LinuxPkgPlugin) yankS3(ctx context.Context, executor CommandExecutor, c *S3PublishConfig, published map[string]any) ([]string, error) {
+	env, err := c.credentialsEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	var yanked []string
+	for _, uri := range publishedValues(published["objects"], "uri") {
+		args := []string{"s3", "rm", "--only-show-errors"}
+		if c.Region != "" {
+			args = append(args, "--region", c.Region)
+		}
+		if c.EndpointURL != "" {
+			args = append(args, "--endpoint-url", c.EndpointURL)
+		}
+		args = append(args, uri)
+
+		output, err := runWithEnv(ctx, executor, env, "aws", args...)
+		if err != nil {
+			return yanked, fmt.Errorf("failed to delete %s: %w\nOutput: %s", uri, err, string(output))
+		}
+		yanked = append(yanked, uri)
+	}
+	return yanked, nil
+}
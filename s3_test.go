@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestValidatePublishS3 tests validation of the publish.s3 block.
+func TestValidatePublishS3(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		s3          map[string]any
+		expectValid bool
+	}{
+		{name: "bucket", s3: map[string]any{"bucket": "my-packages"}, expectValid: true},
+		{name: "kms", s3: map[string]any{"bucket": "my-packages", "sse": "aws:kms", "sse_kms_key_id": "alias/packages"}, expectValid: true},
+		{name: "missing bucket", s3: map[string]any{}, expectValid: false},
+		{name: "invalid sse", s3: map[string]any{"bucket": "my-packages", "sse": "none"}, expectValid: false},
+		{name: "kms key without kms", s3: map[string]any{"bucket": "my-packages", "sse_kms_key_id": "alias/packages"}, expectValid: false},
+		{name: "prefix traversal", s3: map[string]any{"bucket": "my-packages", "prefix": "../other/"}, expectValid: false},
+		{name: "half static keys", s3: map[string]any{"bucket": "my-packages", "access_key_id_env": "KEY"}, expectValid: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &LinuxPkgPlugin{}
+			resp, err := p.Validate(context.Background(), map[string]any{"publish": map[string]any{"s3": tc.s3}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Valid != tc.expectValid {
+				t.Errorf("expected valid=%v, got %v: %v", tc.expectValid, resp.Valid, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestExpandPublishTemplate tests prefix placeholders.
+func TestExpandPublishTemplate(t *testing.T) {
+	t.Parallel()
+
+	release := plugin.ReleaseContext{RepositoryName: "myapp", Version: "1.2.3", TagName: "v1.2.3"}
+	if got := expandPublishTemplate("releases/{name}/{tag}/{version}/", release); got != "releases/myapp/v1.2.3/1.2.3/" {
+		t.Errorf("unexpected expansion: %q", got)
+	}
+}
+
+// TestPublishS3 tests uploads with the credential chain and with static keys.
+// Note: This test cannot run in parallel due to t.Setenv usage.
+func TestPublishS3(t *testing.T) {
+	t.Setenv("TEST_S3_KEY", "AKIDEXAMPLE")
+	t.Setenv("TEST_S3_SECRET", "secret")
+
+	tests := []struct {
+		name      string
+		cfg       *S3PublishConfig
+		expectCmd string
+		expectURL string
+		expectEnv []string
+	}{
+		{
+			name:      "credential chain",
+			cfg:       &S3PublishConfig{Bucket: "my-packages", Region: "eu-west-1", SSE: "aws:kms", SSEKMSKeyID: "alias/packages"},
+			expectCmd: "aws s3 cp --only-show-errors --region eu-west-1 --sse aws:kms --sse-kms-key-id alias/packages dist/myapp_1.0.0_amd64.deb s3://my-packages/myapp/1.0.0/myapp_1.0.0_amd64.deb",
+			expectURL: "https://my-packages.s3.eu-west-1.amazonaws.com/myapp/1.0.0/myapp_1.0.0_amd64.deb",
+		},
+		{
+			name:      "static keys",
+			cfg:       &S3PublishConfig{Bucket: "my-packages", EndpointURL: "https://minio.example.com", AccessKeyIDEnv: "TEST_S3_KEY", SecretAccessKeyEnv: "TEST_S3_SECRET"},
+			expectCmd: "aws s3 cp --only-show-errors --endpoint-url https://minio.example.com dist/myapp_1.0.0_amd64.deb s3://my-packages/myapp/1.0.0/myapp_1.0.0_amd64.deb",
+			expectURL: "https://minio.example.com/my-packages/myapp/1.0.0/myapp_1.0.0_amd64.deb",
+			expectEnv: []string{"AWS_ACCESS_KEY_ID=AKIDEXAMPLE", "AWS_SECRET_ACCESS_KEY=secret"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &MockCommandExecutor{
+				RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+					return nil, nil
+				},
+			}
+
+			p := &LinuxPkgPlugin{}
+			files := []string{"dist/myapp_1.0.0_amd64.deb", "dist/SHA256SUMS"}
+			result, err := p.publishS3(context.Background(), mock, tc.cfg, files, "myapp/1.0.0/")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(mock.Calls) != 2 {
+				t.Fatalf("expected 2 uploads, got %v", mock.Calls)
+			}
+			call := mock.Calls[0]
+			if got := call.Name + " " + strings.Join(call.Args, " "); got != tc.expectCmd {
+				t.Errorf("expected %q, got %q", tc.expectCmd, got)
+			}
+			if strings.Join(call.Env, ",") != strings.Join(tc.expectEnv, ",") {
+				t.Errorf("expected env %v, got %v", tc.expectEnv, call.Env)
+			}
+
+			objects := result["objects"].([]map[string]any)
+			if objects[0]["url"] != tc.expectURL {
+				t.Errorf("expected url %q, got %v", tc.expectURL, objects[0]["url"])
+			}
+			if objects[1]["uri"] != "s3://my-packages/myapp/1.0.0/SHA256SUMS" {
+				t.Errorf("unexpected uri: %v", objects[1]["uri"])
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// configSchema is the JSON schema advertised for the plugin configuration and enforced
+// by Validate.
+//
+//go:embed schema.json
+var configSchema string
+
+// compiledConfigSchema compiles configSchema once.
+var compiledConfigSchema = sync.OnceValues(func() (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("schema.json", strings.NewReader(configSchema)); err != nil {
+		return nil, err
+	}
+	return compiler.Compile("schema.json")
+})
+
+// schemaErrors validates config against configSchema. Each error is reported under the
+// top-level option it belongs to, with the path of the offending value in the message,
+// e.g. "formats.deb.config_path: expected string, but got number".
+func schemaErrors(config map[string]any) ([]plugin.ValidationError, error) {
+	schema, err := compiledConfigSchema()
+	if err != nil {
+		return nil, fmt.Errorf("invalid config schema: %w", err)
+	}
+
+	if config == nil {
+		config = map[string]any{}
+	}
+
+	// Round-trip through JSON so Go slices, maps, and numbers become the JSON values the
+	// schema describes.
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("config is not valid JSON: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var instance any
+	if err := decoder.Decode(&instance); err != nil {
+		return nil, fmt.Errorf("config is not valid JSON: %w", err)
+	}
+
+	err = schema.Validate(instance)
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil, err
+	}
+
+	var result []plugin.ValidationError
+	seen := make(map[string]bool)
+	for _, leaf := range schemaLeafErrors(validationErr) {
+		field, path := schemaErrorPath(leaf.InstanceLocation)
+		message := path + ": " + leaf.Message
+		if seen[message] {
+			continue
+		}
+		seen[message] = true
+		result = append(result, plugin.ValidationError{Field: field, Message: message})
+	}
+	return result, nil
+}
+
+// schemaLeafErrors returns the errors at the bottom of a validation error tree, which
+// name the keyword that failed rather than the schemas containing it. When a value
+// matches the type of one oneOf or anyOf branch, only that branch's errors are kept;
+// when it matches none, the branches' type errors are merged into one.
+func schemaLeafErrors(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+
+	causes := err.Causes
+	if strings.HasSuffix(err.KeywordLocation, "/oneOf") || strings.HasSuffix(err.KeywordLocation, "/anyOf") {
+		var matched []*jsonschema.ValidationError
+		var expected []string
+		for _, cause := range causes {
+			if want, ok := schemaTypeMismatch(cause, err.InstanceLocation); ok {
+				expected = append(expected, want)
+			} else {
+				matched = append(matched, cause)
+			}
+		}
+		if len(matched) == 0 {
+			_, got, _ := strings.Cut(schemaLeafErrors(causes[0])[0].Message, ", but got ")
+			return []*jsonschema.ValidationError{{
+				KeywordLocation:  err.KeywordLocation,
+				InstanceLocation: err.InstanceLocation,
+				Message:          fmt.Sprintf("expected %s, but got %s", strings.Join(expected, " or "), got),
+			}}
+		}
+		causes = matched
+	}
+
+	var leaves []*jsonschema.ValidationError
+	for _, cause := range causes {
+		leaves = append(leaves, schemaLeafErrors(cause)...)
+	}
+	return leaves
+}
+
+// schemaTypeMismatch reports whether a oneOf or anyOf branch failed only because the
+// value at location has a different type, and returns the type the branch expected.
+func schemaTypeMismatch(branch *jsonschema.ValidationError, location string) (string, bool) {
+	cause := branch
+	for len(cause.Causes) == 1 {
+		cause = cause.Causes[0]
+	}
+	if len(cause.Causes) != 0 || cause.InstanceLocation != location || !strings.HasSuffix(cause.KeywordLocation, "/type") {
+		return "", false
+	}
+	want, _, ok := strings.Cut(strings.TrimPrefix(cause.Message, "expected "), ", but got ")
+	return want, ok
+}
+
+// schemaErrorPath converts a JSON pointer into the top-level option it is under and a
+// readable path, e.g. "/publish/apt/repo" into "publish" and "publish.apt.repo", and
+// "/targets/1" into "targets" and "targets[1]". The root is reported as "config".
+func schemaErrorPath(pointer string) (field, path string) {
+	if pointer == "" {
+		return "config", "config"
+	}
+	segments := strings.Split(strings.TrimPrefix(pointer, "/"), "/")
+	var b strings.Builder
+	for i, segment := range segments {
+		segment = strings.NewReplacer("~1", "/", "~0", "~").Replace(segment)
+		if i > 0 && isArrayIndex(segment) {
+			b.WriteString("[" + segment + "]")
+			continue
+		}
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(segment)
+	}
+	return strings.NewReplacer("~1", "/", "~0", "~").Replace(segments[0]), b.String()
+}
+
+// isArrayIndex reports whether a JSON pointer segment is an array index.
+func isArrayIndex(segment string) bool {
+	if segment == "" {
+		return false
+	}
+	for _, r := range segment {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
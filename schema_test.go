@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestSchemaErrors tests that config values are checked against the JSON schema and
+// reported under their option with the path to the value.
+func TestSchemaErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		config map[string]any
+		errors []plugin.ValidationError
+	}{
+		{"valid", map[string]any{
+			"formats":     []string{"deb", "rpm"},
+			"targets":     []any{"amd64", "arm64"},
+			"concurrency": 2,
+			"epoch":       "1",
+			"checks":      map[string]any{"lintian": map[string]any{"severity": "warning"}},
+		}, nil},
+		{"formats string", map[string]any{"formats": "deb"}, []plugin.ValidationError{
+			{Field: "formats", Message: "formats: expected array or object, but got string"},
+		}},
+		{"array item", map[string]any{"targets": []any{"amd64", 64}}, []plugin.ValidationError{
+			{Field: "targets", Message: "targets[1]: expected string, but got number"},
+		}},
+		{"nested value", map[string]any{"publish": map[string]any{"apt": map[string]any{"repo": true}}}, []plugin.ValidationError{
+			{Field: "publish", Message: "publish.apt.repo: expected string, but got boolean"},
+		}},
+		{"matching branch", map[string]any{"checks": map[string]any{"lintian": map[string]any{"ignore": "binary-without-manpage"}}}, []plugin.ValidationError{
+			{Field: "checks", Message: "checks.lintian.ignore: expected array, but got string"},
+		}},
+		{"integer", map[string]any{"concurrency": 1.5}, []plugin.ValidationError{
+			{Field: "concurrency", Message: "concurrency: expected integer, but got number"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			errs, err := schemaErrors(tt.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(errs, tt.errors) {
+				t.Errorf("expected %v, got %v", tt.errors, errs)
+			}
+		})
+	}
+}
+
+// TestValidateSchema tests that Validate reports schema errors only for options the
+// plugin's own checks accepted.
+func TestValidateSchema(t *testing.T) {
+	t.Parallel()
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Validate(context.Background(), map[string]any{
+		"formats":  "deb",
+		"packager": "rpmbuild",
+		"cache":    "yes",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fields := make(map[string]int)
+	for _, e := range resp.Errors {
+		fields[e.Field]++
+	}
+	expected := map[string]int{"formats": 1, "packager": 1, "cache": 1}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("expected one error per option, got %v", resp.Errors)
+	}
+}
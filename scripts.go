@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// maintainerScripts are the nfpm scripts the scripts option may set.
+var maintainerScripts = map[string]bool{
+	"preinstall":  true,
+	"postinstall": true,
+	"preremove":   true,
+	"postremove":  true,
+}
+
+// parseScripts reads the scripts option, which maps nfpm script names to template files.
+func parseScripts(raw map[string]any) map[string]string {
+	block := helpers.NewConfigParser(raw).GetMap("scripts")
+	if block == nil {
+		return nil
+	}
+
+	scripts := make(map[string]string, len(block))
+	for name, value := range block {
+		if path, ok := value.(string); ok && path != "" {
+			scripts[name] = path
+		}
+	}
+	return scripts
+}
+
+// validateScripts checks script names, paths, and template syntax. Templates are only
+// parsed when the files exist in the working directory, so a missing file is reported
+// when building.
+func validateScripts(raw map[string]any) error {
+	value, ok := raw["scripts"]
+	if !ok || value == nil {
+		return nil
+	}
+	block, ok := value.(map[string]any)
+	if !ok {
+		return fmt.Errorf("scripts must be an object mapping script names to files")
+	}
+	workingDir, _ := raw["working_dir"].(string)
+
+	for _, name := range sortedKeys(block) {
+		if !maintainerScripts[name] {
+			return fmt.Errorf("unknown script %q (allowed: %s)", name, strings.Join(sortedKeys(maintainerScripts), ", "))
+		}
+		path, ok := block[name].(string)
+		if !ok || path == "" {
+			return fmt.Errorf("%s must be a file path", name)
+		}
+		if err := validatePath(path); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if content, err := os.ReadFile(inWorkingDir(workingDir, path)); err == nil {
+			if _, err := template.New(filepath.Base(path)).Parse(string(content)); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// renderScripts renders each script template with data into dir and points the matching
+// nfpm script in doc at the rendered file.
+func renderScripts(doc map[string]any, scripts map[string]string, data *nfpmTemplateData, dir string) error {
+	docScripts, _ := doc["scripts"].(map[string]any)
+	if docScripts == nil {
+		docScripts = make(map[string]any, len(scripts))
+	}
+
+	for _, name := range sortedKeys(scripts) {
+		path := scripts[name]
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s script: %w", name, err)
+		}
+		tmpl, err := template.New(filepath.Base(path)).Option("missingkey=error").Parse(string(content))
+		if err != nil {
+			return fmt.Errorf("failed to parse %s script template %s: %w", name, path, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return fmt.Errorf("failed to render %s script template %s: %w", name, path, err)
+		}
+
+		rendered := filepath.Join(dir, name+".sh")
+		if err := os.WriteFile(rendered, buf.Bytes(), 0755); err != nil {
+			return fmt.Errorf("failed to write rendered %s script: %w", name, err)
+		}
+		docScripts[name] = rendered
+	}
+
+	doc["scripts"] = docScripts
+	return nil
+}
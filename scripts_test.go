@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+	"gopkg.in/yaml.v3"
+)
+
+// TestValidateScripts tests scripts validation.
+func TestValidateScripts(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "broken.sh"), []byte("echo {{.Version"), 0644); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		scripts     any
+		expectError string
+	}{
+		{"valid", map[string]any{"postinstall": "postinstall.sh", "preremove": "preremove.sh"}, ""},
+		{"unknown script", map[string]any{"posttrans": "posttrans.sh"}, "unknown script"},
+		{"not a path", map[string]any{"postinstall": 1}, "must be a file path"},
+		{"traversal", map[string]any{"postinstall": "../postinstall.sh"}, "postinstall"},
+		{"bad template", map[string]any{"postinstall": "broken.sh"}, "unclosed action"},
+		{"not an object", "postinstall.sh", "must be an object"},
+	}
+
+	p := &LinuxPkgPlugin{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := p.Validate(context.Background(), map[string]any{"working_dir": dir, "scripts": tt.scripts})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectError == "" {
+				if !resp.Valid {
+					t.Errorf("expected valid config, got errors: %v", resp.Errors)
+				}
+				return
+			}
+			if resp.Valid || len(resp.Errors) == 0 || resp.Errors[0].Field != "scripts" ||
+				!strings.Contains(resp.Errors[0].Message, tt.expectError) {
+				t.Errorf("expected scripts error containing %q, got %v", tt.expectError, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestExecuteScripts tests that maintainer script templates are rendered for nfpm.
+func TestExecuteScripts(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	config := "name: myapp\nversion: 1.2.3\nscripts:\n  preremove: preremove.sh\n"
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "postinstall.sh.tmpl"), []byte("#!/bin/sh\necho myapp {{.Version}} ({{.ShortCommit}})\n"), 0644); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+
+	var rendered map[string]any
+	var postinstall string
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			content, err := os.ReadFile(args[2])
+			if err != nil {
+				return nil, err
+			}
+			if err := yaml.Unmarshal(content, &rendered); err != nil {
+				return nil, err
+			}
+			scripts := rendered["scripts"].(map[string]any)
+			script, err := os.ReadFile(scripts["postinstall"].(string))
+			if err != nil {
+				return nil, err
+			}
+			postinstall = string(script)
+			return []byte("created package: " + args[len(args)-1] + "myapp.deb"), nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb"},
+			"packager":    "nfpm-cli",
+			"scripts":     map[string]any{"postinstall": "postinstall.sh.tmpl"},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3", CommitSHA: "0123456789abcdef"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	if postinstall != "#!/bin/sh\necho myapp 1.2.3 (0123456)\n" {
+		t.Errorf("expected the rendered postinstall script, got %q", postinstall)
+	}
+	if scripts := rendered["scripts"].(map[string]any); scripts["preremove"] != filepath.Join(dir, "preremove.sh") {
+		t.Errorf("expected the config's preremove script to be kept, got %v", scripts)
+	}
+}
+
+// TestExecuteScriptsMissing tests that a missing script template fails the build.
+func TestExecuteScriptsMissing(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: myapp\nversion: 1.2.3\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	mock := &MockCommandExecutor{}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"working_dir": dir, "formats": []string{"deb"}, "packager": "nfpm-cli", "scripts": map[string]any{"postinstall": "postinstall.sh"}},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "invalid scripts: postinstall") {
+		t.Errorf("expected a missing script error, got %+v", resp)
+	}
+	if len(mock.Calls) != 0 {
+		t.Errorf("expected nfpm not to run, got %v", mock.Calls)
+	}
+}
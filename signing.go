@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// Allowed RPM signing methods.
+var allowedRPMSigningMethods = map[string]bool{
+	"nfpm":    true,
+	"rpmsign": true,
+}
+
+// supportedSigners are the package signing mechanisms reported in capabilities.
+var supportedSigners = []string{"apk", "cosign", "rpm"}
+
+// defaultRPMPassphraseEnv is the environment variable nfpm reads the RPM key passphrase from.
+const defaultRPMPassphraseEnv = "NFPM_RPM_PASSPHRASE"
+
+// RPMSigningConfig configures signing of built RPM packages.
+type RPMSigningConfig struct {
+	// Method is how packages are signed: nfpm (signature embedded at build time) or rpmsign.
+	Method string
+	// KeyFile is the armored GPG private key used by the nfpm method.
+	KeyFile string
+	// KeyID selects the GPG key; required by rpmsign, optional for nfpm.
+	KeyID string
+	// PassphraseEnv names the environment variable holding the key passphrase.
+	PassphraseEnv string
+	// Verify runs `rpm --checksig` on every signed package and fails the build if it is not signed.
+	Verify bool
+}
+
+// parseRPMSigning parses the rpm_signing block. It returns nil when signing is not configured.
+func parseRPMSigning(raw map[string]any) *RPMSigningConfig {
+	block := helpers.NewConfigParser(raw).GetMap("rpm_signing")
+	if block == nil {
+		return nil
+	}
+
+	parser := helpers.NewConfigParser(block)
+	return &RPMSigningConfig{
+		Method:        parser.GetString("method", "", "nfpm"),
+		KeyFile:       parser.GetString("key_file", "", ""),
+		KeyID:         parser.GetString("key_id", "", ""),
+		PassphraseEnv: parser.GetString("passphrase_env", "", defaultRPMPassphraseEnv),
+		Verify:        parser.GetBool("verify", true),
+	}
+}
+
+// validate checks that the signing settings are complete for the selected method.
+func (s *RPMSigningConfig) validate() error {
+	if !allowedRPMSigningMethods[s.Method] {
+		return fmt.Errorf("unsupported signing method: %s (allowed: %s)", s.Method, strings.Join(sortedKeys(allowedRPMSigningMethods), ", "))
+	}
+	if err := validatePath(s.KeyFile); err != nil {
+		return fmt.Errorf("invalid key_file: %w", err)
+	}
+
+	switch s.Method {
+	case "nfpm":
+		if s.KeyFile == "" {
+			return fmt.Errorf("key_file is required for the nfpm signing method")
+		}
+	case "rpmsign":
+		if s.KeyID == "" {
+			return fmt.Errorf("key_id is required for the rpmsign signing method")
+		}
+	}
+
+	return nil
+}
+
+// applyRPMSigning writes the nfpm rpm signature settings into an nfpm config document.
+func applyRPMSigning(doc map[string]any, s *RPMSigningConfig) {
+	if s == nil || s.Method != "nfpm" {
+		return
+	}
+
+	rpm, _ := doc["rpm"].(map[string]any)
+	if rpm == nil {
+		rpm = make(map[string]any)
+	}
+	signature, _ := rpm["signature"].(map[string]any)
+	if signature == nil {
+		signature = make(map[string]any)
+	}
+
+	signature["key_file"] = s.KeyFile
+	if s.KeyID != "" {
+		signature["key_id"] = s.KeyID
+	}
+	rpm["signature"] = signature
+	doc["rpm"] = rpm
+}
+
+// signingEnv returns the environment lookup used by the embedded nfpm backend, mapping
+// the configured passphrase variable onto the name nfpm reads.
+func signingEnv(cfg *Config) func(string) string {
+	return func(key string) string {
+		if key == defaultRPMPassphraseEnv && cfg.RPMSigning != nil {
+			return os.Getenv(cfg.RPMSigning.PassphraseEnv)
+		}
+		return os.Getenv(key)
+	}
+}
+
+// rpmsignArgs returns the rpmsign arguments that sign path, reading the key passphrase
+// from passphraseFile when set.
+func (s *RPMSigningConfig) rpmsignArgs(passphraseFile, path string) []string {
+	args := []string{"--addsign", "--define", "_gpg_name " + s.KeyID}
+	if passphraseFile != "" {
+		args = append(args, "--define",
+			"_gpg_sign_cmd_extra_args --batch --pinentry-mode loopback --passphrase-file "+passphraseFile)
+	}
+	return append(args, path)
+}
+
+// signRPM signs a built RPM with rpmsign when that method is selected and then verifies
+// the signature. The combined tool output is returned for logging.
+func (p *LinuxPkgPlugin) signRPM(ctx context.Context, executor CommandExecutor, s *RPMSigningConfig, path string) ([]byte, error) {
+	var output []byte
+
+	if s.Method == "rpmsign" {
+		passphraseFile := ""
+		if passphrase := os.Getenv(s.PassphraseEnv); passphrase != "" {
+			passphraseDir, err := os.MkdirTemp("", "linuxpkg-sign-")
+			if err != nil {
+				return nil, fmt.Errorf("failed to create signing directory: %w", err)
+			}
+			defer os.RemoveAll(passphraseDir)
+
+			passphraseFile = filepath.Join(passphraseDir, "passphrase")
+			if err := os.WriteFile(passphraseFile, []byte(passphrase), 0600); err != nil {
+				return nil, fmt.Errorf("failed to write passphrase file: %w", err)
+			}
+		}
+
+		out, err := runCommand(ctx, executor, "rpmsign", s.rpmsignArgs(passphraseFile, path)...)
+		output = append(output, out...)
+		if err != nil {
+			return output, fmt.Errorf("failed to sign %s: %w", path, err)
+		}
+	}
+
+	if !s.Verify {
+		return output, nil
+	}
+
+	out, err := runCommand(ctx, executor, "rpm", "--checksig", path)
+	output = append(output, out...)
+	if err != nil || !hasRPMSignature(out, path) {
+		return output, fmt.Errorf("signature verification failed for %s", path)
+	}
+
+	return output, nil
+}
+
+// hasRPMSignature reports whether `rpm --checksig` output shows a valid signature.
+// Unsigned packages only report digests; the package path is ignored when matching.
+func hasRPMSignature(output []byte, path string) bool {
+	result := strings.ToLower(strings.ReplaceAll(string(output), path, ""))
+	if strings.Contains(result, "not ok") {
+		return false
+	}
+	return strings.Contains(result, "signatures ok") || strings.Contains(result, "pgp") || strings.Contains(result, "gpg")
+}
+
+// validateAPKKey checks that the APK signing key is a safe path to an existing PEM file
+// in workingDir.
+func validateAPKKey(workingDir, path string) error {
+	if err := validatePath(path); err != nil {
+		return err
+	}
+	return checkAPKKey(inWorkingDir(workingDir, path))
+}
+
+// checkAPKKey checks that the APK signing key at path is an existing PEM file.
+func checkAPKKey(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("key file does not exist: %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read key file: %w", err)
+	}
+	if block, _ := pem.Decode(data); block == nil {
+		return fmt.Errorf("key file is not PEM encoded: %s", path)
+	}
+
+	return nil
+}
+
+// validateAPKKeyName checks that an APK key name is a bare file name.
+func validateAPKKeyName(name string) error {
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return fmt.Errorf("key name must be a file name, not a path: %s", name)
+	}
+	return nil
+}
+
+// applyAPKSigning writes the nfpm apk signature settings into an nfpm config document.
+func applyAPKSigning(doc map[string]any, cfg *Config) {
+	if cfg.APKKeyPath == "" {
+		return
+	}
+
+	apk, _ := doc["apk"].(map[string]any)
+	if apk == nil {
+		apk = make(map[string]any)
+	}
+	signature, _ := apk["signature"].(map[string]any)
+	if signature == nil {
+		signature = make(map[string]any)
+	}
+
+	signature["key_file"] = cfg.APKKeyPath
+	if cfg.APKKeyName != "" {
+		signature["key_name"] = cfg.APKKeyName
+	}
+	apk["signature"] = signature
+	doc["apk"] = apk
+}
+
+// apkKeyFingerprint returns the SHA-256 fingerprint of the public half of a PEM encoded
+// RSA private key. Encrypted keys cannot be fingerprinted and yield an empty string.
+func apkKeyFingerprint(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return "", fmt.Errorf("key file is not PEM encoded: %s", path)
+	}
+	if block.Type == "ENCRYPTED PRIVATE KEY" || strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED") {
+		return "", nil
+	}
+
+	var key any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to parse key file: %w", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return "", fmt.Errorf("unsupported key type in %s", path)
+	}
+	der, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return "", fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	sum := sha256.Sum256(der)
+	return "SHA256:" + hex.EncodeToString(sum[:]), nil
+}
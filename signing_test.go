@@ -0,0 +1,486 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// writeTestSigningKey writes an armored GPG private key, encrypted when passphrase is set.
+func writeTestSigningKey(t *testing.T, path, passphrase string) {
+	t.Helper()
+
+	config := &packet.Config{Algorithm: packet.PubKeyAlgoEdDSA}
+	entity, err := openpgp.NewEntity("Relicta Test", "", "test@example.com", config)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	var sb strings.Builder
+	w, err := armor.Encode(&sb, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatalf("failed to armor key: %v", err)
+	}
+	if passphrase != "" {
+		if err := entity.EncryptPrivateKeys([]byte(passphrase), config); err != nil {
+			t.Fatalf("failed to encrypt key: %v", err)
+		}
+		err = entity.SerializePrivateWithoutSigning(w, config)
+	} else {
+		err = entity.SerializePrivate(w, config)
+	}
+	if err != nil {
+		t.Fatalf("failed to serialize key: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to armor key: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(sb.String()), 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+}
+
+// writeTestAPKKey writes a PEM encoded RSA private key suitable for apk signing.
+func writeTestAPKKey(t *testing.T, path string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+}
+
+// TestParseRPMSigning tests parsing of the rpm_signing block and its defaults.
+func TestParseRPMSigning(t *testing.T) {
+	t.Parallel()
+
+	if got := parseRPMSigning(map[string]any{}); got != nil {
+		t.Errorf("expected nil when rpm_signing is not set, got %+v", got)
+	}
+
+	got := parseRPMSigning(map[string]any{
+		"rpm_signing": map[string]any{"key_file": "keys/rpm.asc"},
+	})
+	expected := &RPMSigningConfig{
+		Method:        "nfpm",
+		KeyFile:       "keys/rpm.asc",
+		PassphraseEnv: defaultRPMPassphraseEnv,
+		Verify:        true,
+	}
+	if got == nil || *got != *expected {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+// TestValidateRPMSigning tests validation of the rpm_signing block.
+func TestValidateRPMSigning(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		signing     any
+		expectValid bool
+	}{
+		{
+			name:        "nfpm with key file",
+			signing:     map[string]any{"key_file": "keys/rpm.asc"},
+			expectValid: true,
+		},
+		{
+			name:        "rpmsign with key id",
+			signing:     map[string]any{"method": "rpmsign", "key_id": "ABCD1234"},
+			expectValid: true,
+		},
+		{
+			name:        "nfpm without key file",
+			signing:     map[string]any{"key_id": "ABCD1234"},
+			expectValid: false,
+		},
+		{
+			name:        "rpmsign without key id",
+			signing:     map[string]any{"method": "rpmsign"},
+			expectValid: false,
+		},
+		{
+			name:        "unknown method",
+			signing:     map[string]any{"method": "osslsigncode", "key_file": "keys/rpm.asc"},
+			expectValid: false,
+		},
+		{
+			name:        "key file path traversal",
+			signing:     map[string]any{"key_file": "../rpm.asc"},
+			expectValid: false,
+		},
+		{
+			name:        "not an object",
+			signing:     "keys/rpm.asc",
+			expectValid: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &LinuxPkgPlugin{}
+			resp, err := p.Validate(context.Background(), map[string]any{"rpm_signing": tc.signing})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Valid != tc.expectValid {
+				t.Errorf("expected valid=%v, got %v: %v", tc.expectValid, resp.Valid, resp.Errors)
+			}
+			if !tc.expectValid && (len(resp.Errors) == 0 || resp.Errors[0].Field != "rpm_signing") {
+				t.Errorf("expected error on field rpm_signing, got %v", resp.Errors)
+			}
+		})
+	}
+}
+
+// TestApplyRPMSigning tests that nfpm signature settings are merged into the config.
+func TestApplyRPMSigning(t *testing.T) {
+	t.Parallel()
+
+	doc := map[string]any{
+		"rpm": map[string]any{"compression": "zstd"},
+	}
+	applyRPMSigning(doc, &RPMSigningConfig{Method: "nfpm", KeyFile: "keys/rpm.asc", KeyID: "ABCD1234"})
+
+	rpm := doc["rpm"].(map[string]any)
+	if rpm["compression"] != "zstd" {
+		t.Errorf("expected existing rpm settings to be kept, got %v", rpm)
+	}
+	signature := rpm["signature"].(map[string]any)
+	if signature["key_file"] != "keys/rpm.asc" || signature["key_id"] != "ABCD1234" {
+		t.Errorf("unexpected signature settings: %v", signature)
+	}
+
+	untouched := map[string]any{}
+	applyRPMSigning(untouched, &RPMSigningConfig{Method: "rpmsign", KeyID: "ABCD1234"})
+	if len(untouched) != 0 {
+		t.Errorf("expected rpmsign method to leave the config alone, got %v", untouched)
+	}
+}
+
+// TestHasRPMSignature tests interpretation of rpm --checksig output.
+func TestHasRPMSignature(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		output   string
+		expected bool
+	}{
+		{output: "dist/myapp.rpm: digests signatures OK", expected: true},
+		{output: "dist/myapp.rpm: rsa sha1 (md5) pgp md5 OK", expected: true},
+		{output: "dist/myapp.rpm: digests OK", expected: false},
+		{output: "dist/myapp.rpm: digests SIGNATURES NOT OK", expected: false},
+		{output: "dist/gpg/myapp.rpm: digests OK", expected: false},
+	}
+
+	for _, tc := range tests {
+		path := strings.SplitN(tc.output, ":", 2)[0]
+		if got := hasRPMSignature([]byte(tc.output), path); got != tc.expected {
+			t.Errorf("%q: expected %v, got %v", tc.output, tc.expected, got)
+		}
+	}
+}
+
+// TestSignRPMWithRpmsign tests the rpmsign invocation and passphrase handling.
+// Note: This test cannot run in parallel due to t.Setenv usage.
+func TestSignRPMWithRpmsign(t *testing.T) {
+	t.Setenv("TEST_RPM_PASSPHRASE", "s3cret")
+
+	var passphrase string
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			for _, arg := range args {
+				if file, ok := strings.CutPrefix(arg, "_gpg_sign_cmd_extra_args --batch --pinentry-mode loopback --passphrase-file "); ok {
+					data, err := os.ReadFile(file)
+					if err != nil {
+						return nil, err
+					}
+					passphrase = string(data)
+				}
+			}
+			if name == "rpm" {
+				return []byte("dist/myapp.rpm: digests signatures OK\n"), nil
+			}
+			return nil, nil
+		},
+	}
+
+	p := &LinuxPkgPlugin{}
+	signing := &RPMSigningConfig{Method: "rpmsign", KeyID: "ABCD1234", PassphraseEnv: "TEST_RPM_PASSPHRASE", Verify: true}
+	if _, err := p.signRPM(context.Background(), mock, signing, "dist/myapp.rpm"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(mock.Calls) != 2 {
+		t.Fatalf("expected sign and verify calls, got %v", mock.Calls)
+	}
+	sign := mock.Calls[0]
+	if sign.Name != "rpmsign" || sign.Args[0] != "--addsign" || sign.Args[2] != "_gpg_name ABCD1234" {
+		t.Errorf("unexpected sign call: %v", sign)
+	}
+	if sign.Args[len(sign.Args)-1] != "dist/myapp.rpm" {
+		t.Errorf("expected package path last, got %v", sign.Args)
+	}
+	if passphrase != "s3cret" {
+		t.Errorf("expected passphrase file to contain the passphrase, got %q", passphrase)
+	}
+	verify := mock.Calls[1]
+	if verify.Name != "rpm" || strings.Join(verify.Args, " ") != "--checksig dist/myapp.rpm" {
+		t.Errorf("unexpected verify call: %v", verify)
+	}
+}
+
+// TestSignRPMVerificationFailure tests that unsigned packages fail verification.
+func TestSignRPMVerificationFailure(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		output string
+		err    error
+	}{
+		{name: "unsigned", output: "dist/myapp.rpm: digests OK"},
+		{name: "checksig error", output: "dist/myapp.rpm: digests SIGNATURES NOT OK", err: errors.New("exit status 1")},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mock := &MockCommandExecutor{
+				RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+					return []byte(tc.output), tc.err
+				},
+			}
+
+			p := &LinuxPkgPlugin{}
+			signing := &RPMSigningConfig{Method: "nfpm", KeyFile: "keys/rpm.asc", Verify: true}
+			_, err := p.signRPM(context.Background(), mock, signing, "dist/myapp.rpm")
+			if err == nil || !strings.Contains(err.Error(), "signature verification failed") {
+				t.Errorf("expected verification error, got %v", err)
+			}
+		})
+	}
+}
+
+// TestExecuteSignsRPMEmbedded tests signing an RPM with the embedded backend using an
+// encrypted key whose passphrase comes from a custom environment variable.
+// Note: This test cannot run in parallel due to t.Setenv usage.
+func TestExecuteSignsRPMEmbedded(t *testing.T) {
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+	writeTestSigningKey(t, filepath.Join(dir, "rpm.asc"), "s3cret")
+	t.Setenv("TEST_RPM_PASSPHRASE", "s3cret")
+
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte(args[len(args)-1] + ": digests signatures OK\n"), nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []any{"rpm", "deb"},
+			"rpm_signing": map[string]any{
+				"key_file":       "rpm.asc",
+				"passphrase_env": "TEST_RPM_PASSPHRASE",
+			},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	if len(mock.Calls) != 1 || mock.Calls[0].Name != "rpm" {
+		t.Fatalf("expected only the rpm package to be verified, got %v", mock.Calls)
+	}
+
+	artifacts := resp.Outputs["artifacts"].([]map[string]any)
+	for _, artifact := range artifacts {
+		signed := artifact["signed"] == true
+		if signed != (artifact["format"] == "rpm") {
+			t.Errorf("unexpected signed flag on %v", artifact)
+		}
+	}
+
+	t.Run("wrong passphrase fails the build", func(t *testing.T) {
+		t.Setenv("TEST_RPM_PASSPHRASE", "wrong")
+
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook: plugin.HookPostPublish,
+			Config: map[string]any{
+				"working_dir": dir,
+				"formats":     []any{"rpm"},
+				"rpm_signing": map[string]any{
+					"key_file":       "rpm.asc",
+					"passphrase_env": "TEST_RPM_PASSPHRASE",
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Success {
+			t.Error("expected failure with the wrong passphrase")
+		}
+	})
+}
+
+// TestValidateAPKKey tests that Validate checks the apk signing key.
+func TestValidateAPKKey(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeTestAPKKey(t, filepath.Join(dir, "apk.rsa"))
+	if err := os.WriteFile(filepath.Join(dir, "not-a-key.txt"), []byte("hello"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		config   map[string]any
+		errField string
+	}{
+		{name: "valid key", config: map[string]any{"apk_key_path": "apk.rsa", "apk_key_name": "team@example.com.rsa.pub"}},
+		{name: "missing key", config: map[string]any{"apk_key_path": "missing.rsa"}, errField: "apk_key_path"},
+		{name: "not pem", config: map[string]any{"apk_key_path": "not-a-key.txt"}, errField: "apk_key_path"},
+		{name: "path traversal", config: map[string]any{"apk_key_path": "../apk.rsa"}, errField: "apk_key_path"},
+		{name: "key name with path", config: map[string]any{"apk_key_name": "keys/team.rsa.pub"}, errField: "apk_key_name"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.config["working_dir"] = dir
+			p := &LinuxPkgPlugin{}
+			resp, err := p.Validate(context.Background(), tc.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Valid != (tc.errField == "") {
+				t.Errorf("expected valid=%v, got %v: %v", tc.errField == "", resp.Valid, resp.Errors)
+			}
+			if tc.errField != "" && (len(resp.Errors) == 0 || resp.Errors[0].Field != tc.errField) {
+				t.Errorf("expected error on field %q, got %v", tc.errField, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestAPKKeyFingerprint tests fingerprinting of apk signing keys.
+func TestAPKKeyFingerprint(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "apk.rsa")
+	writeTestAPKKey(t, keyPath)
+
+	fingerprint, err := apkKeyFingerprint(keyPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(fingerprint, "SHA256:") || len(fingerprint) != len("SHA256:")+64 {
+		t.Errorf("unexpected fingerprint %q", fingerprint)
+	}
+
+	again, err := apkKeyFingerprint(keyPath)
+	if err != nil || again != fingerprint {
+		t.Errorf("expected a stable fingerprint, got %q (%v)", again, err)
+	}
+
+	encrypted := filepath.Join(dir, "encrypted.rsa")
+	data := pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED PRIVATE KEY", Bytes: []byte("opaque")})
+	if err := os.WriteFile(encrypted, data, 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	if fingerprint, err := apkKeyFingerprint(encrypted); err != nil || fingerprint != "" {
+		t.Errorf("expected no fingerprint for encrypted key, got %q (%v)", fingerprint, err)
+	}
+}
+
+// TestExecuteSignsAPKEmbedded tests signing an apk with the embedded backend.
+func TestExecuteSignsAPKEmbedded(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+	writeTestAPKKey(t, filepath.Join(dir, "apk.rsa"))
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir":  dir,
+			"formats":      []any{"apk"},
+			"apk_key_path": "apk.rsa",
+			"apk_key_name": "team@example.com.rsa.pub",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	expected, err := apkKeyFingerprint(filepath.Join(dir, "apk.rsa"))
+	if err != nil {
+		t.Fatalf("failed to fingerprint key: %v", err)
+	}
+	if resp.Outputs["apk_key_fingerprint"] != expected {
+		t.Errorf("expected fingerprint %q, got %v", expected, resp.Outputs["apk_key_fingerprint"])
+	}
+
+	artifacts := resp.Outputs["artifacts"].([]map[string]any)
+	if len(artifacts) != 1 || artifacts[0]["signed"] != true {
+		t.Fatalf("expected one signed artifact, got %v", artifacts)
+	}
+
+	// The signature is the first gzip stream of the apk.
+	f, err := os.Open(artifacts[0]["path"].(string))
+	if err != nil {
+		t.Fatalf("failed to open package: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("failed to read package: %v", err)
+	}
+	gz.Multistream(false)
+	header, err := tar.NewReader(gz).Next()
+	if err != nil {
+		t.Fatalf("failed to read signature entry: %v", err)
+	}
+	if header.Name != ".SIGN.RSA.team@example.com.rsa.pub" {
+		t.Errorf("expected an abuild signature entry, got %q", header.Name)
+	}
+}
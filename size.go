@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// sizePattern matches human-readable sizes such as "512", "50MB", or "1.5 GiB".
+var sizePattern = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([a-zA-Z]*)$`)
+
+// sizeUnits maps size suffixes to their byte multipliers. Decimal suffixes use
+// powers of 1000 and binary suffixes (KiB, MiB, ...) use powers of 1024.
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"k":   1 << 10,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gib": 1 << 30,
+	"t":   1 << 40,
+	"tib": 1 << 40,
+}
+
+// parseSize parses a human-readable size into bytes. An empty string means no limit and returns 0.
+func parseSize(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+
+	m := sizePattern.FindStringSubmatch(value)
+	if m == nil {
+		return 0, fmt.Errorf("invalid size %q: expected a number with an optional unit (e.g. 50MB, 1GiB)", value)
+	}
+
+	multiplier, ok := sizeUnits[strings.ToLower(m[2])]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q", value, m[2])
+	}
+
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", value, err)
+	}
+
+	return int64(n * multiplier), nil
+}
+
+// formatSize renders a byte count in binary units for messages.
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// sizeOption reads a size setting that may be given as a string ("50MB") or a plain byte count.
+func sizeOption(raw map[string]any, key string) string {
+	switch v := raw[key].(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// totalArtifactSize sums the sizes of the given files. Files that do not exist are skipped.
+func totalArtifactSize(paths []string) (int64, error) {
+	var total int64
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to stat artifact %s: %w", path, err)
+		}
+		total += info.Size()
+	}
+	return total, nil
+}
+
+// MaxSizeConfig limits the size of each built package.
+type MaxSizeConfig struct {
+	// Default is the limit of formats without one of their own. Empty means unlimited.
+	Default string
+	// Formats holds per-format limits, keyed by format.
+	Formats map[string]string
+	// OnViolation is what an oversized package does: fail the hook or only warn.
+	OnViolation string
+}
+
+// parseMaxSize parses max_size, given as a size for every package or an object with a
+// default, per-format sizes, and on_violation. It returns nil when it is not set.
+func parseMaxSize(raw map[string]any) *MaxSizeConfig {
+	block, ok := raw["max_size"].(map[string]any)
+	if !ok {
+		if limit := sizeOption(raw, "max_size"); limit != "" {
+			return &MaxSizeConfig{Default: limit, OnViolation: "fail"}
+		}
+		return nil
+	}
+
+	limits := &MaxSizeConfig{
+		Default:     sizeOption(block, "default"),
+		Formats:     make(map[string]string),
+		OnViolation: helpers.NewConfigParser(block).GetString("on_violation", "", "fail"),
+	}
+	for key := range block {
+		if key != "default" && key != "on_violation" {
+			limits.Formats[key] = sizeOption(block, key)
+		}
+	}
+	return limits
+}
+
+// validate checks the sizes, formats, and on_violation of max_size.
+func (c *MaxSizeConfig) validate() error {
+	if _, err := parseSize(c.Default); err != nil {
+		return err
+	}
+	for _, format := range sortedKeys(c.Formats) {
+		if _, ok := packageExtensions[format]; !ok {
+			return fmt.Errorf("unsupported format: %s", format)
+		}
+		if c.Formats[format] == "" {
+			return fmt.Errorf("%s: size cannot be empty", format)
+		}
+		if _, err := parseSize(c.Formats[format]); err != nil {
+			return fmt.Errorf("%s: %w", format, err)
+		}
+	}
+	if !allowedCheckActions[c.OnViolation] {
+		return fmt.Errorf("invalid on_violation %q (allowed: fail, warn)", c.OnViolation)
+	}
+	return nil
+}
+
+// limit returns the size limit of a format in bytes, 0 for unlimited.
+func (c *MaxSizeConfig) limit(format string) int64 {
+	value, ok := c.Formats[format]
+	if !ok {
+		value = c.Default
+	}
+	limit, _ := parseSize(value)
+	return limit
+}
+
+// oversized describes the artifacts larger than the limit of their format.
+func (c *MaxSizeConfig) oversized(artifacts []map[string]any) []string {
+	var problems []string
+	for _, artifact := range artifacts {
+		format, _ := artifact["format"].(string)
+		size, _ := artifact["size"].(int64)
+		if limit := c.limit(format); limit > 0 && size > limit {
+			path, _ := artifact["path"].(string)
+			problems = append(problems, fmt.Sprintf("%s is %s, over the %s limit of %s",
+				filepath.Base(path), formatSize(size), format, formatSize(limit)))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestParseSize tests parsing of human-readable sizes.
+func TestParseSize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input     string
+		expected  int64
+		expectErr bool
+	}{
+		{input: "", expected: 0},
+		{input: "1024", expected: 1024},
+		{input: "50MB", expected: 50_000_000},
+		{input: "50 mb", expected: 50_000_000},
+		{input: "1.5GB", expected: 1_500_000_000},
+		{input: "2GiB", expected: 2 << 30},
+		{input: "512K", expected: 512 << 10},
+		{input: "10XB", expectErr: true},
+		{input: "-5MB", expectErr: true},
+		{input: "lots", expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseSize(tc.input)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error for %q, got %d", tc.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.expected {
+				t.Errorf("expected %d, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestFormatSize tests rendering of byte counts.
+func TestFormatSize(t *testing.T) {
+	t.Parallel()
+
+	tests := map[int64]string{
+		512:           "512 B",
+		2048:          "2.0 KiB",
+		5 << 20:       "5.0 MiB",
+		3<<30 + 1<<29: "3.5 GiB",
+	}
+
+	for input, expected := range tests {
+		if got := formatSize(input); got != expected {
+			t.Errorf("formatSize(%d): expected %q, got %q", input, expected, got)
+		}
+	}
+}
+
+// TestSizeOption tests reading size settings given as strings or numbers.
+func TestSizeOption(t *testing.T) {
+	t.Parallel()
+
+	raw := map[string]any{
+		"as_string": "50MB",
+		"as_int":    1024,
+		"as_float":  float64(2048),
+		"as_bool":   true,
+	}
+
+	tests := map[string]string{
+		"as_string": "50MB",
+		"as_int":    "1024",
+		"as_float":  "2048",
+		"as_bool":   "",
+		"missing":   "",
+	}
+
+	for key, expected := range tests {
+		if got := sizeOption(raw, key); got != expected {
+			t.Errorf("%s: expected %q, got %q", key, expected, got)
+		}
+	}
+}
+
+// TestExecuteTotalSizeBudget tests reporting and enforcement of max_total_size.
+func TestExecuteTotalSizeBudget(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		maxTotalSize  any
+		expectSuccess bool
+	}{
+		{name: "no budget", maxTotalSize: nil, expectSuccess: true},
+		{name: "within budget", maxTotalSize: "1KiB", expectSuccess: true},
+		{name: "exceeds budget", maxTotalSize: 150, expectSuccess: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: test\nversion: 1.0.0"), 0644); err != nil {
+				t.Fatalf("failed to create test config: %v", err)
+			}
+
+			mock := &MockCommandExecutor{
+				RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+					format := args[4]
+					path := filepath.Join(dir, "dist", "test."+format)
+					if err := os.WriteFile(path, make([]byte, 100), 0644); err != nil {
+						return nil, err
+					}
+					return []byte("created package: " + path), nil
+				},
+			}
+			p := &LinuxPkgPlugin{cmdExecutor: mock}
+
+			config := map[string]any{"working_dir": dir, "formats": []string{"deb", "rpm"}, "packager": "nfpm-cli"}
+			if tc.maxTotalSize != nil {
+				config["max_total_size"] = tc.maxTotalSize
+			}
+
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook:    plugin.HookPostPublish,
+				Config:  config,
+				Context: plugin.ReleaseContext{Version: "1.0.0"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if resp.Success != tc.expectSuccess {
+				t.Fatalf("expected success=%v, got %v: %s", tc.expectSuccess, resp.Success, resp.Error)
+			}
+			if total, ok := resp.Outputs["total_size"].(int64); !ok || total != 200 {
+				t.Errorf("expected total_size 200, got %v", resp.Outputs["total_size"])
+			}
+			if !tc.expectSuccess && !strings.Contains(resp.Error, "exceeds max_total_size") {
+				t.Errorf("expected budget error, got %q", resp.Error)
+			}
+		})
+	}
+}
+
+// TestValidateMaxTotalSize tests validation of max_total_size.
+func TestValidateMaxTotalSize(t *testing.T) {
+	t.Parallel()
+
+	p := &LinuxPkgPlugin{}
+
+	resp, err := p.Validate(context.Background(), map[string]any{"max_total_size": "2GiB"})
+	if err != nil || !resp.Valid {
+		t.Errorf("expected valid config, got %v %v", resp, err)
+	}
+
+	resp, err = p.Validate(context.Background(), map[string]any{"max_total_size": "huge"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Valid || resp.Errors[0].Field != "max_total_size" {
+		t.Errorf("expected max_total_size error, got %v", resp.Errors)
+	}
+}
+
+// TestExecuteMaxSize tests enforcement of per-package max_size limits.
+func TestExecuteMaxSize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		maxSize       any
+		expectSuccess bool
+		expectText    string
+	}{
+		{name: "within limit", maxSize: "1KiB", expectSuccess: true, expectText: "Built 2 Linux package(s)"},
+		{name: "global limit", maxSize: 50, expectSuccess: false, expectText: "2 package(s) exceed max_size"},
+		{name: "format limit", maxSize: map[string]any{"default": "1KiB", "rpm": 50}, expectSuccess: false, expectText: "test.rpm is 100 B, over the rpm limit of 50 B"},
+		{name: "warn", maxSize: map[string]any{"deb": 50, "on_violation": "warn"}, expectSuccess: true, expectText: "1 package(s) exceed max_size"},
+		{name: "unknown format", maxSize: map[string]any{"msi": 50}, expectSuccess: false, expectText: "invalid max_size: unsupported format: msi"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: test\nversion: 1.0.0"), 0644); err != nil {
+				t.Fatalf("failed to create test config: %v", err)
+			}
+
+			mock := &MockCommandExecutor{
+				RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+					format := args[4]
+					path := filepath.Join(dir, "dist", "test."+format)
+					if err := os.WriteFile(path, make([]byte, 100), 0644); err != nil {
+						return nil, err
+					}
+					return []byte("created package: " + path), nil
+				},
+			}
+			p := &LinuxPkgPlugin{cmdExecutor: mock}
+
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook: plugin.HookPostPublish,
+				Config: map[string]any{
+					"working_dir": dir,
+					"formats":     []string{"deb", "rpm"},
+					"packager":    "nfpm-cli",
+					"max_size":    tc.maxSize,
+				},
+				Context: plugin.ReleaseContext{Version: "1.0.0"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Success != tc.expectSuccess || !strings.Contains(resp.Message+resp.Error, tc.expectText) {
+				t.Errorf("expected success=%v with %q, got %+v", tc.expectSuccess, tc.expectText, resp)
+			}
+		})
+	}
+}
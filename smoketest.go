@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// Allowed container runtimes for install smoke tests.
+var allowedContainerRuntimes = map[string]bool{
+	"docker": true,
+	"podman": true,
+}
+
+// installImageFormats maps container image names, without registry or tag, to the
+// package format their package manager installs.
+var installImageFormats = map[string]string{
+	"debian":      "deb",
+	"ubuntu":      "deb",
+	"fedora":      "rpm",
+	"centos":      "rpm",
+	"rockylinux":  "rpm",
+	"almalinux":   "rpm",
+	"amazonlinux": "rpm",
+	"oraclelinux": "rpm",
+	"leap":        "rpm",
+	"tumbleweed":  "rpm",
+	"alpine":      "apk",
+	"archlinux":   "archlinux",
+}
+
+// defaultInstallImages are the images packages of each format are installed in when no
+// images are configured.
+var defaultInstallImages = map[string][]string{
+	"deb":       {"debian:stable-slim", "ubuntu:latest"},
+	"rpm":       {"fedora:latest"},
+	"apk":       {"alpine:latest"},
+	"archlinux": {"archlinux:latest"},
+}
+
+// installImagePattern matches container image references.
+var installImagePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._/:@-]*$`)
+
+// installPackageDir is where packages are mounted inside smoke test containers.
+const installPackageDir = "/tmp/linuxpkg"
+
+// InstallCheckConfig configures the install smoke test.
+type InstallCheckConfig struct {
+	// Runtime is the container runtime, docker or podman. Empty uses whichever is installed.
+	Runtime string
+	// Images are the container images packages are installed in. Empty uses
+	// defaultInstallImages for the built formats.
+	Images []string
+	// OnFailure is what a failed installation does: fail the hook or only warn.
+	OnFailure string
+	// Lock pins Images to their locked digests, set by Execute from tool_lock.
+	Lock *ToolLock
+}
+
+// parseInstallCheck parses the install check, given as true or an object.
+func parseInstallCheck(raw map[string]any) *InstallCheckConfig {
+	if enabled, ok := raw["install"].(bool); ok {
+		if !enabled {
+			return nil
+		}
+		return &InstallCheckConfig{OnFailure: "fail"}
+	}
+	block := helpers.NewConfigParser(raw).GetMap("install")
+	if block == nil {
+		return nil
+	}
+
+	parser := helpers.NewConfigParser(block)
+	return &InstallCheckConfig{
+		Runtime:   parser.GetString("runtime", "", ""),
+		Images:    parser.GetStringSlice("images", nil),
+		OnFailure: parser.GetString("on_failure", "", "fail"),
+	}
+}
+
+// validate checks the install check settings.
+func (c *InstallCheckConfig) validate() error {
+	if c.Runtime != "" && !allowedContainerRuntimes[c.Runtime] {
+		return fmt.Errorf("invalid runtime %q (allowed: docker, podman)", c.Runtime)
+	}
+	if !allowedCheckActions[c.OnFailure] {
+		return fmt.Errorf("invalid on_failure %q (allowed: fail, warn)", c.OnFailure)
+	}
+	for _, image := range c.Images {
+		if !installImagePattern.MatchString(image) {
+			return fmt.Errorf("invalid image %q", image)
+		}
+		if installImageFormat(image) == "" {
+			return fmt.Errorf("unsupported image %q: cannot tell its package format (supported: %s)",
+				image, strings.Join(sortedKeys(installImageFormats), ", "))
+		}
+	}
+	return nil
+}
+
+// installImageFormat returns the package format an image installs, or "" if unknown. The
+// registry, tag, and digest are ignored: "docker.io/library/debian:bookworm" is "deb".
+func installImageFormat(image string) string {
+	name, _, _ := strings.Cut(image, "@")
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	return installImageFormats[path.Base(name)]
+}
+
+// installImages returns the images packages of the built formats are installed in.
+func (c *InstallCheckConfig) installImages(formats []string) []string {
+	if len(c.Images) > 0 {
+		return c.Images
+	}
+	var images []string
+	for _, format := range formats {
+		images = append(images, defaultInstallImages[format]...)
+	}
+	return images
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// installCommand returns the shell command installing file with the package manager of
+// format, including the dependencies the package declares.
+func installCommand(format, file string) string {
+	file = shellQuote(file)
+	switch format {
+	case "deb":
+		return "apt-get update -qq && apt-get install -y -qq " + file
+	case "rpm":
+		return fmt.Sprintf("if command -v dnf >/dev/null 2>&1; then dnf install -y --nogpgcheck %[1]s; "+
+			"elif command -v zypper >/dev/null 2>&1; then zypper --non-interactive --no-gpg-checks install %[1]s; "+
+			"else yum install -y --nogpgcheck %[1]s; fi", file)
+	case "apk":
+		return "apk add --no-cache --allow-untrusted " + file
+	case "archlinux":
+		return "pacman -Sy --noconfirm && pacman -U --noconfirm " + file
+	}
+	return ""
+}
+
+// containerRuntime returns the configured runtime, or the first of docker and podman
+// found on PATH. user names what needs the runtime in the error.
+func (p *LinuxPkgPlugin) containerRuntime(runtime, user string) (string, error) {
+	if runtime != "" {
+		return runtime, nil
+	}
+	lookPath := p.getLookPath()
+	for _, runtime := range []string{"docker", "podman"} {
+		if _, err := lookPath(runtime); err == nil {
+			return runtime, nil
+		}
+	}
+	return "", fmt.Errorf("%s needs docker or podman", user)
+}
+
+// lastLine returns the last non-empty line of output.
+func lastLine(output []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// runInstallCheck installs every built package in each image for its format and returns
+// one result per installation. Packages for a known architecture are run on the matching
+// container platform.
+func (p *LinuxPkgPlugin) runInstallCheck(ctx context.Context, executor CommandExecutor, c *InstallCheckConfig, formats []string, artifacts []map[string]any) ([]map[string]any, error) {
+	runtime, err := p.containerRuntime(c.Runtime, "install check")
+	if err != nil {
+		return nil, err
+	}
+
+	var results []map[string]any
+	for _, image := range c.installImages(formats) {
+		format := installImageFormat(image)
+		ref, err := c.Lock.pinImage(image)
+		if err != nil {
+			return nil, err
+		}
+		for _, artifact := range artifacts {
+			if artifact["format"] != format {
+				continue
+			}
+			pkg := artifact["path"].(string)
+			abs, err := filepath.Abs(pkg)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve %s: %w", pkg, err)
+			}
+			file := installPackageDir + "/" + filepath.Base(pkg)
+
+			args := []string{"run", "--rm", "-e", "DEBIAN_FRONTEND=noninteractive", "-v", abs + ":" + file + ":ro"}
+			if arch, _ := artifact["arch"].(string); allowedArchitectures[arch] {
+				args = append(args, "--platform", "linux/"+arch)
+			}
+			args = append(args, ref, "sh", "-c", installCommand(format, file))
+
+			out, err := runCommand(ctx, executor, runtime, args...)
+			result := map[string]any{
+				"image":   image,
+				"package": pkg,
+				"format":  format,
+				"success": err == nil,
+			}
+			if err != nil {
+				result["error"] = lastLine(out)
+				if result["error"] == "" {
+					result["error"] = err.Error()
+				}
+			}
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
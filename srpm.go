@@ -0,0 +1,376 @@
+package main
+
+import (
+	"archive/tar"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/goreleaser/nfpm/v2"
+	"github.com/goreleaser/nfpm/v2/files"
+)
+
+// srpmWrotePattern matches the source RPM rpmbuild reports writing.
+var srpmWrotePattern = regexp.MustCompile(`(?m)^Wrote:\s*(\S+\.src\.rpm)\s*$`)
+
+// validateSRPM checks that source RPMs are only requested along with rpm packages.
+func validateSRPM(enabled bool, formats []string) error {
+	if enabled && !slices.Contains(formats, "rpm") {
+		return fmt.Errorf("requires the rpm format")
+	}
+	return nil
+}
+
+// srpmTarget is the rpm built for one architecture, as the source RPM repackages it.
+type srpmTarget struct {
+	// Arch is the rpm name of the architecture, such as x86_64.
+	Arch string
+	Info *nfpm.Info
+}
+
+// srpmGroups groups the rpm jobs that built a package by the package they built: jobs
+// sharing an output directory and distribution differ only in their target. Groups are
+// in job order.
+func srpmGroups(jobs []buildJob, outcomes []*buildOutcome) [][]buildJob {
+	var groups [][]buildJob
+	index := make(map[string]int)
+	for i, job := range jobs {
+		if job.Format != "rpm" || job.Subpackage != "" || job.Debug || outcomes[i] == nil || outcomes[i].Artifact == nil {
+			continue
+		}
+		key := job.Config.OutputDir + "\x00" + job.Config.distroName()
+		if n, ok := index[key]; ok {
+			groups[n] = append(groups[n], job)
+			continue
+		}
+		index[key] = len(groups)
+		groups = append(groups, []buildJob{job})
+	}
+	return groups
+}
+
+// resolveSRPMTarget resolves the rpm contents of a job the way nfpm packages them.
+func resolveSRPMTarget(job buildJob) (srpmTarget, error) {
+	arch := ""
+	if job.Target.Override {
+		arch = job.Target.nfpmArch()
+	}
+	info, packager, err := resolvePackageInfo(job.ConfigPath, "rpm", arch, envLookup(job.Env, os.Getenv))
+	if err != nil {
+		return srpmTarget{}, err
+	}
+	if err := nfpm.PrepareForPackager(info, "rpm"); err != nil {
+		return srpmTarget{}, fmt.Errorf("failed to prepare contents: %w", err)
+	}
+	meta := expectedPackageMetadata("rpm", info, packager)
+	return srpmTarget{Arch: meta.Arch, Info: info}, nil
+}
+
+// srpmVersion returns the rpm Version of info, without its epoch or release.
+func srpmVersion(info *nfpm.Info) string {
+	version := info.Version
+	if info.Prerelease != "" {
+		version += "~" + strings.ReplaceAll(info.Prerelease, "-", "_")
+	}
+	if info.VersionMetadata != "" {
+		version += "+" + info.VersionMetadata
+	}
+	return version
+}
+
+// isSRPMSource reports whether a content is a file the source tarball carries.
+func isSRPMSource(content *files.Content) bool {
+	switch content.Type {
+	case files.TypeFile, files.TypeConfig, files.TypeConfigNoReplace, files.TypeConfigMissingOK,
+		files.TypeRPMDoc, files.TypeRPMLicence, files.TypeRPMLicense, files.TypeRPMReadme:
+		return true
+	}
+	return false
+}
+
+// writeSRPMSources writes the source tarball: the packaged files of every target, under
+// a directory named after its architecture.
+func writeSRPMSources(path string, targets []srpmTarget) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create source tarball: %w", err)
+	}
+	defer f.Close()
+	zw := gzip.NewWriter(f)
+	tw := tar.NewWriter(zw)
+
+	for _, target := range targets {
+		for _, content := range target.Info.Contents {
+			if !isSRPMSource(content) {
+				continue
+			}
+			if err := addSRPMSource(tw, target.Arch+content.Destination, content.Source); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write source tarball: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to write source tarball: %w", err)
+	}
+	return f.Close()
+}
+
+// addSRPMSource adds the file at source to the tarball as name.
+func addSRPMSource(tw *tar.Writer, name, source string) error {
+	src, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", source, err)
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", source, err)
+	}
+	header := &tar.Header{Name: name, Mode: 0o644, Size: info.Size(), ModTime: info.ModTime(), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write source tarball: %w", err)
+	}
+	if _, err := io.Copy(tw, src); err != nil {
+		return fmt.Errorf("failed to write source tarball: %w", err)
+	}
+	return nil
+}
+
+// specQuote quotes a path for the %files section of a spec.
+func specQuote(path string) string {
+	return `"` + strings.ReplaceAll(path, `%`, `%%`) + `"`
+}
+
+// specScript returns the content of a maintainer script for a spec section, or "" when
+// there is none.
+func specScript(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read script %s: %w", path, err)
+	}
+	return strings.ReplaceAll(strings.TrimRight(string(content), "\n"), "%", "%%"), nil
+}
+
+// srpmSpec renders a spec that installs the packaged files of every target, as the
+// binary rpms do. Metadata, dependencies, and scripts come from the first target.
+func srpmSpec(targets []srpmTarget, source string) (string, error) {
+	info := targets[0].Info
+	var b strings.Builder
+	b.WriteString("# Generated by plugin-linuxpkg from the nfpm config.\n")
+	b.WriteString("%global debug_package %{nil}\n")
+	b.WriteString("%global __os_install_post %{nil}\n\n")
+
+	summary := info.RPM.Summary
+	if summary == "" {
+		summary, _, _ = strings.Cut(strings.TrimSpace(info.Description), "\n")
+	}
+	license := info.License
+	if license == "" {
+		license = "Unspecified"
+	}
+	fields := [][2]string{
+		{"Name", info.Name},
+		{"Epoch", info.Epoch},
+		{"Version", srpmVersion(info)},
+		{"Release", info.Release},
+		{"Summary", summary},
+		{"License", license},
+		{"URL", info.Homepage},
+		{"Vendor", info.Vendor},
+		{"Packager", cmp.Or(info.RPM.Packager, info.Maintainer)},
+		{"Group", info.RPM.Group},
+		{"Source0", source},
+	}
+	for _, field := range fields {
+		if field[1] != "" {
+			fmt.Fprintf(&b, "%s: %s\n", field[0], field[1])
+		}
+	}
+
+	archs := make([]string, 0, len(targets))
+	for _, target := range targets {
+		archs = append(archs, target.Arch)
+	}
+	if len(archs) == 1 && archs[0] == "noarch" {
+		b.WriteString("BuildArch: noarch\n")
+	} else {
+		fmt.Fprintf(&b, "ExclusiveArch: %s\n", strings.Join(archs, " "))
+	}
+	for _, deps := range []struct {
+		tag   string
+		names []string
+	}{
+		{"Requires", info.Depends},
+		{"Recommends", info.Recommends},
+		{"Suggests", info.Suggests},
+		{"Provides", info.Provides},
+		{"Conflicts", info.Conflicts},
+		{"Obsoletes", info.Replaces},
+	} {
+		for _, name := range deps.names {
+			fmt.Fprintf(&b, "%s: %s\n", deps.tag, name)
+		}
+	}
+
+	fmt.Fprintf(&b, "\n%%description\n%s\n", strings.TrimSpace(info.Description))
+	b.WriteString("\n%prep\n%setup -q -c\n\n%build\n\n%install\n")
+	for _, target := range targets {
+		fmt.Fprintf(&b, "%%ifarch %s\n", target.Arch)
+		for _, content := range target.Info.Contents {
+			dest := shellQuote("%{buildroot}" + strings.TrimSuffix(content.Destination, "/"))
+			switch {
+			case isSRPMSource(content):
+				fmt.Fprintf(&b, "install -D -m %04o %s %s\n", content.Mode().Perm(), shellQuote(target.Arch+content.Destination), dest)
+			case content.Type == files.TypeDir:
+				fmt.Fprintf(&b, "install -d -m %04o %s\n", content.Mode().Perm(), dest)
+			case content.Type == files.TypeSymlink:
+				fmt.Fprintf(&b, "mkdir -p %s\nln -sf %s %s\n", shellQuote(filepath.Dir("%{buildroot}"+content.Destination)), shellQuote(content.Source), dest)
+			}
+		}
+		b.WriteString("%endif\n")
+	}
+
+	for _, script := range []struct {
+		section string
+		path    string
+	}{
+		{"pretrans", info.RPM.Scripts.PreTrans},
+		{"pre", info.Scripts.PreInstall},
+		{"post", info.Scripts.PostInstall},
+		{"preun", info.Scripts.PreRemove},
+		{"postun", info.Scripts.PostRemove},
+		{"posttrans", info.RPM.Scripts.PostTrans},
+	} {
+		content, err := specScript(script.path)
+		if err != nil {
+			return "", err
+		}
+		if content != "" {
+			fmt.Fprintf(&b, "\n%%%s\n%s\n", script.section, content)
+		}
+	}
+
+	b.WriteString("\n%files\n")
+	for _, target := range targets {
+		fmt.Fprintf(&b, "%%ifarch %s\n", target.Arch)
+		for _, content := range target.Info.Contents {
+			if content.Type == files.TypeImplicitDir {
+				continue
+			}
+			owner, group := cmp.Or(content.FileInfo.Owner, "root"), cmp.Or(content.FileInfo.Group, "root")
+			attr := fmt.Sprintf("%%attr(%04o,%s,%s) ", content.Mode().Perm(), owner, group)
+			if content.Type == files.TypeSymlink {
+				attr = fmt.Sprintf("%%attr(-,%s,%s) ", owner, group)
+			}
+			prefix := map[string]string{
+				files.TypeDir:             "%dir ",
+				files.TypeConfig:          "%config ",
+				files.TypeConfigNoReplace: "%config(noreplace) ",
+				files.TypeConfigMissingOK: "%config(missingok) ",
+				files.TypeRPMGhost:        "%ghost ",
+				files.TypeRPMDoc:          "%doc ",
+				files.TypeRPMLicence:      "%license ",
+				files.TypeRPMLicense:      "%license ",
+				files.TypeRPMReadme:       "%doc ",
+			}[content.Type]
+			fmt.Fprintf(&b, "%s%s%s\n", prefix, attr, specQuote(content.Destination))
+		}
+		b.WriteString("%endif\n")
+	}
+	return b.String(), nil
+}
+
+// buildSRPM writes the spec and source tarball of a group of rpm jobs and builds the
+// source RPM into the group's output directory with rpmbuild.
+func (p *LinuxPkgPlugin) buildSRPM(ctx context.Context, executor CommandExecutor, group []buildJob) (string, []byte, error) {
+	targets := make([]srpmTarget, 0, len(group))
+	for _, job := range group {
+		target, err := resolveSRPMTarget(job)
+		if err != nil {
+			return "", nil, err
+		}
+		targets = append(targets, target)
+	}
+	info := targets[0].Info
+
+	topdir, err := os.MkdirTemp("", "linuxpkg-srpm-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create rpmbuild directory: %w", err)
+	}
+	defer os.RemoveAll(topdir)
+	for _, dir := range []string{"SOURCES", "SPECS"} {
+		if err := os.MkdirAll(filepath.Join(topdir, dir), 0755); err != nil {
+			return "", nil, fmt.Errorf("failed to create rpmbuild directory: %w", err)
+		}
+	}
+
+	source := fmt.Sprintf("%s-%s.tar.gz", info.Name, srpmVersion(info))
+	if err := writeSRPMSources(filepath.Join(topdir, "SOURCES", source), targets); err != nil {
+		return "", nil, err
+	}
+	spec, err := srpmSpec(targets, source)
+	if err != nil {
+		return "", nil, err
+	}
+	specPath := filepath.Join(topdir, "SPECS", info.Name+".spec")
+	if err := os.WriteFile(specPath, []byte(spec), 0644); err != nil {
+		return "", nil, fmt.Errorf("failed to write spec: %w", err)
+	}
+
+	outputDir := group[0].Config.OutputDir
+	output, err := runCommand(ctx, executor, "rpmbuild", "-bs",
+		"--define", "_topdir "+topdir,
+		"--define", "_srcrpmdir "+outputDir,
+		specPath)
+	if err != nil {
+		return "", output, fmt.Errorf("rpmbuild failed for %s: %w\nOutput: %s", info.Name, err, string(output))
+	}
+	match := srpmWrotePattern.FindSubmatch(output)
+	if match == nil {
+		return "", output, fmt.Errorf("could not find the source RPM in rpmbuild output: %s", strings.TrimSpace(string(output)))
+	}
+	return string(match[1]), output, nil
+}
+
+// buildSRPMs builds a source RPM for every package built as rpm and returns them as
+// artifacts with the arch "src".
+func (p *LinuxPkgPlugin) buildSRPMs(ctx context.Context, executor CommandExecutor, jobs []buildJob, outcomes []*buildOutcome) ([]map[string]any, error) {
+	var artifacts []map[string]any
+	for _, group := range srpmGroups(jobs, outcomes) {
+		path, _, err := p.buildSRPM(ctx, executor, group)
+		if err != nil {
+			return artifacts, err
+		}
+		digest, size, err := artifactDigest(path)
+		if err != nil {
+			return artifacts, err
+		}
+		artifact := map[string]any{
+			"path":   path,
+			"format": "rpm",
+			"arch":   "src",
+			"sha256": digest,
+			"size":   size,
+			"source": true,
+		}
+		if distro := group[0].Config.distroName(); distro != "" {
+			artifact["distro"] = distro
+		}
+		artifacts = append(artifacts, artifact)
+	}
+	return artifacts, nil
+}
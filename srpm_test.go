@@ -0,0 +1,131 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestExecuteSRPM tests building a source RPM that repackages the rpm of every target.
+func TestExecuteSRPM(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "")
+
+	var spec string
+	var sources []string
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			if name != "rpmbuild" || args[0] != "-bs" {
+				return nil, errors.New("unexpected command " + name)
+			}
+			specPath := args[len(args)-1]
+			content, err := os.ReadFile(specPath)
+			if err != nil {
+				return nil, err
+			}
+			spec = string(content)
+
+			f, err := os.Open(filepath.Join(filepath.Dir(filepath.Dir(specPath)), "SOURCES", "myapp-1.2.3.tar.gz"))
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			zr, err := gzip.NewReader(f)
+			if err != nil {
+				return nil, err
+			}
+			tr := tar.NewReader(zr)
+			for {
+				header, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return nil, err
+				}
+				sources = append(sources, header.Name)
+			}
+
+			srcrpmdir := strings.TrimPrefix(args[4], "_srcrpmdir ")
+			path := filepath.Join(srcrpmdir, "myapp-1.2.3-1.src.rpm")
+			if err := os.WriteFile(path, []byte("srpm"), 0644); err != nil {
+				return nil, err
+			}
+			return []byte("Wrote: " + path + "\n"), nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []any{"deb", "rpm"},
+			"targets":     []any{"amd64", "arm64"},
+			"srpm":        true,
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected success, got %v, %+v", err, resp)
+	}
+	if len(mock.Calls) != 1 {
+		t.Fatalf("expected a single rpmbuild run, got %+v", mock.Calls)
+	}
+
+	artifacts := resp.Outputs["artifacts"].([]map[string]any)
+	srpm := artifacts[len(artifacts)-1]
+	if srpm["arch"] != "src" || srpm["format"] != "rpm" || srpm["source"] != true ||
+		srpm["path"] != filepath.Join(dir, "dist", "myapp-1.2.3-1.src.rpm") {
+		t.Errorf("unexpected source RPM artifact: %v", srpm)
+	}
+	if !slices.Contains(resp.Outputs["packages"].([]string), srpm["path"].(string)) {
+		t.Errorf("expected the source RPM in packages, got %v", resp.Outputs["packages"])
+	}
+
+	for _, want := range []string{
+		"Name: myapp\n",
+		"Version: 1.2.3\n",
+		"Release: 1\n",
+		"Source0: myapp-1.2.3.tar.gz\n",
+		"ExclusiveArch: x86_64 aarch64\n",
+		"%ifarch aarch64\ninstall -D -m 0755 'aarch64/usr/bin/myapp' '%{buildroot}/usr/bin/myapp'\n",
+		"%attr(0755,root,root) \"/usr/bin/myapp\"\n",
+	} {
+		if !strings.Contains(spec, want) {
+			t.Errorf("expected the spec to contain %q, got:\n%s", want, spec)
+		}
+	}
+	if !slices.Equal(sources, []string{"x86_64/usr/bin/myapp", "aarch64/usr/bin/myapp"}) {
+		t.Errorf("unexpected sources: %v", sources)
+	}
+}
+
+// TestValidateSRPM tests that source RPMs require the rpm format.
+func TestValidateSRPM(t *testing.T) {
+	t.Parallel()
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Validate(context.Background(), map[string]any{"formats": []any{"deb"}, "srpm": true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Valid || resp.Errors[0].Field != "srpm" {
+		t.Errorf("expected an srpm error, got %v", resp.Errors)
+	}
+
+	resp, err = p.Validate(context.Background(), map[string]any{"formats": []any{"rpm"}, "srpm": true})
+	if err != nil || !resp.Valid {
+		t.Errorf("expected valid config, got %v, %v", resp, err)
+	}
+}
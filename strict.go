@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// deprecatedKeys maps option names that are still accepted to the options replacing them.
+var deprecatedKeys = map[string]string{
+	"revision": "release",
+}
+
+// strictProblems returns the problems strict mode reports during validation, which
+// otherwise only surface once a release runs: unknown and deprecated keys, and nfpm
+// configs that do not exist yet. cfg is config parsed with paths resolved against the
+// working directory.
+func (p *LinuxPkgPlugin) strictProblems(config map[string]any, cfg *Config) []plugin.ValidationError {
+	var problems []plugin.ValidationError
+	add := func(field, format string, args ...any) {
+		problems = append(problems, plugin.ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	known := schemaProperties(configSchema)
+	for _, key := range sortedKeys(config) {
+		if replacement, ok := deprecatedKeys[key]; ok {
+			add(key, "%s is deprecated, use %s instead", key, replacement)
+		} else if !slices.Contains(known, key) {
+			add(key, "unknown option: %s", key)
+		}
+	}
+
+	checked := make(map[string]bool)
+	for _, format := range cfg.Formats {
+		configPath := cfg.forFormat(format).ConfigPath
+		if checked[configPath] {
+			continue
+		}
+		checked[configPath] = true
+		field := "config_path"
+		if configPath != cfg.ConfigPath {
+			field = "formats"
+		}
+		if err := validateConfigExists(configPath); err != nil {
+			add(field, "%v", err)
+		}
+	}
+
+	return problems
+}
+
+// schemaProperties returns the top-level property names of a JSON schema document.
+func schemaProperties(schema string) []string {
+	var doc struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal([]byte(schema), &doc); err != nil {
+		return nil
+	}
+	return sortedKeys(doc.Properties)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestValidateStrict tests that strict mode turns problems found at release time into
+// validation errors.
+func TestValidateStrict(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: myapp\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		config map[string]any
+		fields []string
+	}{
+		{"valid", map[string]any{"working_dir": dir, "formats": []string{"deb"}}, nil},
+		{"unknown key", map[string]any{"working_dir": dir, "format": "deb"}, []string{"format"}},
+		{"deprecated key", map[string]any{"working_dir": dir, "revision": "2"}, []string{"revision"}},
+		{"missing config", map[string]any{"working_dir": dir, "config_path": "missing.yaml"}, []string{"config_path"}},
+		{"missing format config", map[string]any{
+			"working_dir": dir,
+			"formats":     map[string]any{"deb": nil, "rpm": map[string]any{"config_path": "rpm.yaml"}},
+		}, []string{"formats"}},
+		{"missing nfpm binary", map[string]any{"working_dir": dir, "packager": "nfpm-cli"}, []string{"packager"}},
+		{"missing working dir", map[string]any{"working_dir": filepath.Join(dir, "missing")}, []string{"working_dir"}},
+	}
+
+	p := &LinuxPkgPlugin{
+		lookPath:  func(string) (string, error) { return "", errors.New("not found") },
+		logOutput: io.Discard,
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			lenient, err := p.Validate(context.Background(), tt.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !lenient.Valid {
+				t.Errorf("expected valid without strict, got errors=%v", lenient.Errors)
+			}
+
+			config := map[string]any{"strict": true}
+			for key, value := range tt.config {
+				config[key] = value
+			}
+			resp, err := p.Validate(context.Background(), config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(resp.Errors) != len(tt.fields) {
+				t.Fatalf("expected errors for %v, got %v", tt.fields, resp.Errors)
+			}
+			for i, field := range tt.fields {
+				if resp.Errors[i].Field != field {
+					t.Errorf("expected an error for %s, got %v", field, resp.Errors[i])
+				}
+			}
+		})
+	}
+}
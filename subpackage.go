@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// subpackageOptions are the options of an entry of subpackages.
+var subpackageOptions = []string{"name", "contents", "description", "depends"}
+
+// companionPackageKeys are the nfpm config keys a package built next to another one
+// shares with it.
+var companionPackageKeys = []string{
+	"arch", "platform", "version", "version_schema", "epoch", "release", "prerelease",
+	"version_metadata", "maintainer", "vendor", "homepage", "license", "mtime", "changelog",
+}
+
+// SubpackageConfig is a package built from part of the contents of the nfpm config.
+type SubpackageConfig struct {
+	// Name is the package name, such as "myapp-doc".
+	Name string
+	// Contents are globs of the destinations the subpackage takes from the package.
+	Contents []string
+	// Description defaults to the package's.
+	Description string
+	// Depends are the dependencies of the subpackage.
+	Depends []string
+}
+
+// parseSubpackages reads the subpackages option, a list of objects.
+func parseSubpackages(raw map[string]any) []*SubpackageConfig {
+	entries, _ := raw["subpackages"].([]any)
+	subpackages := make([]*SubpackageConfig, 0, len(entries))
+	for _, value := range entries {
+		entry, ok := value.(map[string]any)
+		if !ok {
+			continue
+		}
+		parser := helpers.NewConfigParser(entry)
+		subpackages = append(subpackages, &SubpackageConfig{
+			Name:        parser.GetString("name", "", ""),
+			Contents:    parser.GetStringSlice("contents", nil),
+			Description: parser.GetString("description", "", ""),
+			Depends:     parser.GetStringSlice("depends", nil),
+		})
+	}
+	return subpackages
+}
+
+// validateSubpackageEntries checks that subpackages is a list of objects with only the
+// supported options.
+func validateSubpackageEntries(raw map[string]any) error {
+	value, ok := raw["subpackages"]
+	if !ok || value == nil {
+		return nil
+	}
+	entries, ok := value.([]any)
+	if !ok {
+		return fmt.Errorf("subpackages must be a list")
+	}
+	for i, value := range entries {
+		entry, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("subpackages[%d] must be an object", i)
+		}
+		for _, key := range sortedKeys(entry) {
+			if !slices.Contains(subpackageOptions, key) {
+				return fmt.Errorf("subpackages[%d]: unsupported option: %s (allowed: %s)", i, key, strings.Join(subpackageOptions, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// validateSubpackages checks every subpackage, rejects duplicate names, and requires a
+// filename template to tell the packages apart.
+func validateSubpackages(subpackages []*SubpackageConfig, filenameTemplate string) error {
+	seen := make(map[string]bool, len(subpackages))
+	for _, subpackage := range subpackages {
+		if err := subpackage.validate(); err != nil {
+			return err
+		}
+		if seen[subpackage.Name] {
+			return fmt.Errorf("duplicate subpackage %q", subpackage.Name)
+		}
+		seen[subpackage.Name] = true
+	}
+	if len(subpackages) > 0 && filenameTemplate != "" && !strings.Contains(filenameTemplate, "{name}") {
+		return fmt.Errorf("filename_template must contain {name} to tell subpackages apart")
+	}
+	return nil
+}
+
+// validate checks a subpackage's name and content globs.
+func (s *SubpackageConfig) validate() error {
+	if !packageNamePattern.MatchString(s.Name) {
+		return fmt.Errorf("name must be letters, digits, dots, dashes, and underscores, got %q", s.Name)
+	}
+	if len(s.Contents) == 0 {
+		return fmt.Errorf("%s: contents must list at least one destination glob", s.Name)
+	}
+	for _, glob := range s.Contents {
+		if !strings.HasPrefix(glob, "/") {
+			return fmt.Errorf("%s: contents glob must be an absolute destination: %s", s.Name, glob)
+		}
+		if _, err := regexp.Compile(globToRegexp(glob)); err != nil {
+			return fmt.Errorf("%s: invalid contents glob %q: %w", s.Name, glob, err)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the subpackage takes the content installed at dst.
+func (s *SubpackageConfig) matches(dst string) bool {
+	dst = path.Clean("/" + dst)
+	for _, glob := range s.Contents {
+		if regexp.MustCompile(globToRegexp(glob)).MatchString(dst) {
+			return true
+		}
+	}
+	return false
+}
+
+// companionPackage returns the nfpm config of a package built for format next to doc's
+// package, named name and installing contents. It shares the version, architecture,
+// maintainer, and the signing and compression settings of format.
+func companionPackage(doc map[string]any, format, name, description string, contents []any) map[string]any {
+	companion := map[string]any{
+		"name":        name,
+		"description": description,
+		"contents":    contents,
+	}
+	for _, key := range companionPackageKeys {
+		if value, ok := doc[key]; ok {
+			companion[key] = value
+		}
+	}
+	block := make(map[string]any)
+	if original, ok := doc[format].(map[string]any); ok {
+		for _, key := range []string{"signature", "compression"} {
+			if value, ok := original[key]; ok {
+				block[key] = value
+			}
+		}
+	}
+	companion[format] = block
+	return companion
+}
+
+// splitSubpackages moves the contents each subpackage matches, by destination, out of
+// the job's package and returns the job building what is left, followed by a job per
+// subpackage that matched any. Contents go to the first subpackage matching them, and
+// entries with a globbed source move as a whole. The returned cleanup function removes
+// the staged configs.
+func splitSubpackages(job buildJob, subpackages []*SubpackageConfig) ([]buildJob, func(), error) {
+	noop := func() {}
+	doc, err := loadNfpmConfig(job.ConfigPath)
+	if err != nil {
+		return nil, noop, err
+	}
+	getenv := envLookup(job.Env, os.Getenv)
+
+	claimed := make([][]any, len(subpackages))
+	split := func(entries []any) []any {
+		kept := make([]any, 0, len(entries))
+	entries:
+		for _, raw := range entries {
+			entry, ok := raw.(map[string]any)
+			if !ok {
+				kept = append(kept, raw)
+				continue
+			}
+			dst, _ := entry["dst"].(string)
+			if expand, _ := entry["expand"].(bool); expand {
+				dst = os.Expand(dst, getenv)
+			}
+			for i, subpackage := range subpackages {
+				if dst != "" && subpackage.matches(dst) {
+					claimed[i] = append(claimed[i], entry)
+					continue entries
+				}
+			}
+			kept = append(kept, entry)
+		}
+		return kept
+	}
+	if entries := contentEntries(doc); entries != nil {
+		doc["contents"] = split(entries)
+	}
+	if overrides, ok := doc["overrides"].(map[string]any); ok {
+		if override, ok := overrides[job.Format].(map[string]any); ok {
+			if entries := contentEntries(override); entries != nil {
+				override["contents"] = split(entries)
+			}
+		}
+	}
+	if !slices.ContainsFunc(claimed, func(contents []any) bool { return len(contents) > 0 }) {
+		return []buildJob{job}, noop, nil
+	}
+
+	var cleanups []func()
+	cleanup := func() {
+		for _, cleanup := range cleanups {
+			cleanup()
+		}
+	}
+	stage := func(doc map[string]any, subpackage string) (buildJob, error) {
+		staged, stagedCleanup, err := stageNfpmConfig(doc)
+		if err != nil {
+			return buildJob{}, err
+		}
+		cleanups = append(cleanups, stagedCleanup)
+		split := job
+		split.ConfigPath = staged
+		split.Subpackage = subpackage
+		return split, nil
+	}
+
+	mainJob, err := stage(doc, "")
+	if err != nil {
+		cleanup()
+		return nil, noop, err
+	}
+	jobs := []buildJob{mainJob}
+	for i, subpackage := range subpackages {
+		if len(claimed[i]) == 0 {
+			continue
+		}
+		description := subpackage.Description
+		if description == "" {
+			description, _ = doc["description"].(string)
+		}
+		subDoc := companionPackage(doc, job.Format, subpackage.Name, description, claimed[i])
+		if len(subpackage.Depends) > 0 {
+			subDoc["depends"] = subpackage.Depends
+		}
+		subJob, err := stage(subDoc, subpackage.Name)
+		if err != nil {
+			cleanup()
+			return nil, noop, err
+		}
+		jobs = append(jobs, subJob)
+	}
+	return jobs, cleanup, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestExecuteSubpackages tests building subpackages from the contents of one nfpm config.
+func TestExecuteSubpackages(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"myapp":      "#!/bin/sh\necho hello\n",
+		"README":     "docs\n",
+		"myapp.conf": "key=value\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	config := "name: myapp\n" +
+		"version: 1.2.3\n" +
+		"arch: amd64\n" +
+		"maintainer: Relicta Team <team@example.com>\n" +
+		"description: test package\n" +
+		"contents:\n" +
+		"  - src: myapp\n" +
+		"    dst: /usr/bin/myapp\n" +
+		"  - src: README\n" +
+		"    dst: /usr/share/doc/myapp/README\n" +
+		"  - src: myapp.conf\n" +
+		"    dst: /etc/myapp/myapp.conf\n" +
+		"    type: config|noreplace\n"
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	p := &LinuxPkgPlugin{cmdExecutor: &MockCommandExecutor{}}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []any{"deb", "rpm"},
+			"subpackages": []any{
+				map[string]any{"name": "myapp-doc", "contents": []any{"/usr/share/doc/**"}, "description": "myapp documentation"},
+				map[string]any{"name": "myapp-config", "contents": []any{"/etc/**"}, "depends": []any{"myapp"}},
+				map[string]any{"name": "myapp-extras", "contents": []any{"/opt/**"}},
+			},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected success, got %v, %+v", err, resp)
+	}
+
+	expected := map[string]struct {
+		file    string
+		depends []string
+	}{
+		"":             {"/usr/bin/myapp", nil},
+		"myapp-doc":    {"/usr/share/doc/myapp/README", nil},
+		"myapp-config": {"/etc/myapp/myapp.conf", []string{"myapp"}},
+	}
+	artifacts := resp.Outputs["artifacts"].([]map[string]any)
+	if len(artifacts) != 2*len(expected) {
+		t.Fatalf("expected %d artifacts, got %v", 2*len(expected), artifacts)
+	}
+	for _, artifact := range artifacts {
+		subpackage, _ := artifact["subpackage"].(string)
+		want, ok := expected[subpackage]
+		if !ok {
+			t.Fatalf("unexpected subpackage %q", subpackage)
+		}
+		path, format := artifact["path"].(string), artifact["format"].(string)
+		meta, err := readPackageMetadata(path, format)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", path, err)
+		}
+		if name := cmp.Or(subpackage, "myapp"); meta.Name != name {
+			t.Errorf("expected package %s, got %s", name, meta.Name)
+		}
+		contents, err := readPackageContents(path, format, false)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", path, err)
+		}
+		if len(contents.Files) != 1 {
+			t.Errorf("expected only %s in %s, got %v", want.file, path, contents.Files)
+		}
+		if _, ok := contents.Files[want.file]; !ok {
+			t.Errorf("expected %s in %s, got %v", want.file, path, contents.Files)
+		}
+		if !slices.Equal(contents.Depends, want.depends) {
+			t.Errorf("expected %s to depend on %v, got %v", path, want.depends, contents.Depends)
+		}
+	}
+}
+
+// TestValidateSubpackages tests the subpackages option.
+func TestValidateSubpackages(t *testing.T) {
+	t.Parallel()
+
+	doc := map[string]any{"name": "myapp-doc", "contents": []any{"/usr/share/doc/**"}}
+	tests := []struct {
+		name        string
+		config      map[string]any
+		expectError string
+	}{
+		{"valid", map[string]any{"subpackages": []any{doc}}, ""},
+		{"not a list", map[string]any{"subpackages": "myapp-doc"}, "must be a list"},
+		{"not an object", map[string]any{"subpackages": []any{"myapp-doc"}}, "must be an object"},
+		{"unknown option", map[string]any{"subpackages": []any{map[string]any{"name": "myapp-doc", "contents": []any{"/usr/**"}, "arch": "all"}}}, "unsupported option: arch"},
+		{"invalid name", map[string]any{"subpackages": []any{map[string]any{"name": "my app", "contents": []any{"/usr/**"}}}}, "name must be"},
+		{"no contents", map[string]any{"subpackages": []any{map[string]any{"name": "myapp-doc"}}}, "contents must list"},
+		{"relative glob", map[string]any{"subpackages": []any{map[string]any{"name": "myapp-doc", "contents": []any{"usr/**"}}}}, "absolute destination"},
+		{"duplicate", map[string]any{"subpackages": []any{doc, doc}}, "duplicate subpackage"},
+		{"filename without name", map[string]any{"subpackages": []any{doc}, "filename_template": "pkg_{version}.{ext}"}, "must contain {name}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			expectFieldError(t, tt.config, "subpackages", tt.expectError)
+		})
+	}
+}
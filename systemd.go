@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// systemdUnitSections maps systemd unit file suffixes to their type-specific section.
+// Targets have none.
+var systemdUnitSections = map[string]string{
+	".service":   "Service",
+	".socket":    "Socket",
+	".timer":     "Timer",
+	".mount":     "Mount",
+	".automount": "Automount",
+	".swap":      "Swap",
+	".path":      "Path",
+	".slice":     "Slice",
+	".scope":     "Scope",
+	".target":    "",
+}
+
+// systemdServiceTypes are the values Type= accepts in a service.
+var systemdServiceTypes = map[string]bool{
+	"simple":        true,
+	"exec":          true,
+	"forking":       true,
+	"oneshot":       true,
+	"dbus":          true,
+	"notify":        true,
+	"notify-reload": true,
+	"idle":          true,
+}
+
+// systemdTimerTriggers are the timer settings that make a timer elapse.
+var systemdTimerTriggers = []string{
+	"OnActiveSec",
+	"OnBootSec",
+	"OnStartupSec",
+	"OnUnitActiveSec",
+	"OnUnitInactiveSec",
+	"OnCalendar",
+	"OnClockChange",
+	"OnTimezoneChange",
+}
+
+// systemdSocketListeners are the socket settings that give a socket something to listen on.
+var systemdSocketListeners = []string{
+	"ListenStream",
+	"ListenDatagram",
+	"ListenSequentialPacket",
+	"ListenFIFO",
+	"ListenSpecial",
+	"ListenNetlink",
+	"ListenMessageQueue",
+	"ListenUSBFunction",
+}
+
+// systemdUnitType returns the unit file suffix of name, or "" when it is not a unit file.
+func systemdUnitType(name string) string {
+	ext := path.Ext(name)
+	if _, ok := systemdUnitSections[ext]; ok {
+		return ext
+	}
+	return ""
+}
+
+// systemdSetting is an assignment in a unit file.
+type systemdSetting struct {
+	Section, Key, Value string
+	Line                int
+}
+
+// parseSystemdUnit reads the settings of a unit file, reporting syntax errors as
+// "file:line: message".
+func parseSystemdUnit(name string, content []byte) ([]systemdSetting, []string) {
+	var settings []systemdSetting
+	var errs []string
+	section := ""
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		start := lineNo
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		// A trailing backslash continues the value on the next line.
+		for strings.HasSuffix(line, "\\") && scanner.Scan() {
+			lineNo++
+			line = strings.TrimSuffix(line, "\\") + " " + strings.TrimSpace(scanner.Text())
+		}
+
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") || len(line) < 3 {
+				errs = append(errs, fmt.Sprintf("%s:%d: invalid section header %q", name, start, line))
+				continue
+			}
+			section = line[1 : len(line)-1]
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Sprintf("%s:%d: expected KEY=value, got %q", name, start, line))
+		case key == "":
+			errs = append(errs, fmt.Sprintf("%s:%d: missing key before '='", name, start))
+		case section == "":
+			errs = append(errs, fmt.Sprintf("%s:%d: %s= is outside of any section", name, start, key))
+		default:
+			settings = append(settings, systemdSetting{Section: section, Key: key, Value: strings.TrimSpace(value), Line: start})
+		}
+	}
+	return settings, errs
+}
+
+// verifySystemdUnit checks a unit file of unitType (".service", ...): its syntax, its
+// sections, and the settings its type cannot work without. Errors are reported as
+// "file:line: message".
+func verifySystemdUnit(name, unitType string, content []byte) []string {
+	settings, errs := parseSystemdUnit(name, content)
+
+	typeSection := systemdUnitSections[unitType]
+	reported := make(map[string]bool)
+	values := make(map[string][]systemdSetting)
+	for _, s := range settings {
+		known := s.Section == "Unit" || s.Section == "Install" || (typeSection != "" && s.Section == typeSection) ||
+			strings.HasPrefix(s.Section, "X-")
+		if !known {
+			if !reported[s.Section] {
+				errs = append(errs, fmt.Sprintf("%s:%d: unknown section [%s] in a %s unit", name, s.Line, s.Section, strings.TrimPrefix(unitType, ".")))
+				reported[s.Section] = true
+			}
+			continue
+		}
+		if s.Section != typeSection {
+			continue
+		}
+		if s.Value == "" {
+			// An empty assignment resets the setting.
+			delete(values, s.Key)
+			continue
+		}
+		values[s.Key] = append(values[s.Key], s)
+	}
+
+	switch unitType {
+	case ".service":
+		serviceType := "simple"
+		if set := values["Type"]; len(set) > 0 {
+			last := set[len(set)-1]
+			if !systemdServiceTypes[last.Value] {
+				errs = append(errs, fmt.Sprintf("%s:%d: invalid Type=%s", name, last.Line, last.Value))
+			}
+			serviceType = last.Value
+		}
+		if len(values["ExecStart"]) == 0 && len(values["ExecStop"]) == 0 && len(values["SuccessAction"]) == 0 {
+			errs = append(errs, fmt.Sprintf("%s: service has no ExecStart=, ExecStop=, or SuccessAction=", name))
+		}
+		if starts := values["ExecStart"]; len(starts) > 1 && serviceType != "oneshot" {
+			errs = append(errs, fmt.Sprintf("%s:%d: more than one ExecStart= is only allowed with Type=oneshot", name, starts[1].Line))
+		}
+	case ".timer":
+		if !hasAnySetting(values, systemdTimerTriggers) {
+			errs = append(errs, fmt.Sprintf("%s: timer has no OnCalendar= or other On*= trigger", name))
+		}
+	case ".socket":
+		if !hasAnySetting(values, systemdSocketListeners) {
+			errs = append(errs, fmt.Sprintf("%s: socket has no Listen*= setting", name))
+		}
+	}
+	return errs
+}
+
+// hasAnySetting reports whether any of keys is set in values.
+func hasAnySetting(values map[string][]systemdSetting, keys []string) bool {
+	for _, key := range keys {
+		if len(values[key]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// systemdUnitFiles returns the local unit files an nfpm config packages, mapped to their
+// unit type: contents whose source or, for single files, destination has a unit file
+// suffix. Globs and directories are expanded, and sources of entries with expand set are
+// resolved with getenv. Missing files are skipped; nfpm reports them when it builds.
+func systemdUnitFiles(doc map[string]any, getenv func(string) string) (map[string]string, error) {
+	units := make(map[string]string)
+	for _, raw := range contentEntries(doc) {
+		entry, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		src, _ := entry["src"].(string)
+		dst, _ := entry["dst"].(string)
+		entryType, _ := entry["type"].(string)
+		if src == "" || isRemoteSource(src) || !globbedContentTypes[entryType] {
+			continue
+		}
+		if expand, _ := entry["expand"].(bool); expand {
+			src = os.Expand(src, getenv)
+		}
+
+		matches := []string{src}
+		if hasGlobMeta(src) {
+			globbed, _, err := globFiles(src, nil)
+			if err != nil {
+				return nil, err
+			}
+			matches = globbed
+		}
+		for _, match := range matches {
+			err := filepath.WalkDir(match, func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if d.IsDir() {
+					return nil
+				}
+				if unitType := systemdUnitType(p); unitType != "" {
+					units[p] = unitType
+				} else if unitType := systemdUnitType(dst); p == src && unitType != "" {
+					units[p] = unitType
+				}
+				return nil
+			})
+			if err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to read %s: %w", match, err)
+			}
+		}
+	}
+	return units, nil
+}
+
+// verifySystemdUnits checks the unit files the nfpm configs of jobs package. All problems
+// are reported together, each unit file once.
+func verifySystemdUnits(jobs []buildJob) error {
+	var errs []string
+	checkedConfigs := make(map[string]bool)
+	checkedUnits := make(map[string]bool)
+	for _, job := range jobs {
+		if checkedConfigs[job.ConfigPath] {
+			continue
+		}
+		checkedConfigs[job.ConfigPath] = true
+
+		doc, err := loadNfpmConfig(job.ConfigPath)
+		if err != nil {
+			return err
+		}
+		units, err := systemdUnitFiles(doc, envLookup(job.Env, os.Getenv))
+		if err != nil {
+			return err
+		}
+		for _, unit := range sortedKeys(units) {
+			if checkedUnits[unit] {
+				continue
+			}
+			checkedUnits[unit] = true
+
+			content, err := os.ReadFile(unit)
+			if err != nil {
+				return fmt.Errorf("failed to read systemd unit %s: %w", unit, err)
+			}
+			errs = append(errs, verifySystemdUnit(unit, units[unit], content)...)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid systemd units:\n  %s", strings.Join(errs, "\n  "))
+	}
+	return nil
+}
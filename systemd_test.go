@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestVerifySystemdUnit tests checking unit files.
+func TestVerifySystemdUnit(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		unitType string
+		content  string
+		expected []string
+	}{
+		{
+			name:     "valid service",
+			unitType: ".service",
+			content: "# myapp\n[Unit]\nDescription=My app\n\n[Service]\nType=notify\nExecStart=/usr/bin/myapp \\\n  --config /etc/myapp.yaml\nX-Custom=1\n\n" +
+				"[Install]\nWantedBy=multi-user.target\n\n[X-Vendor]\nKey=value\n",
+		},
+		{
+			name:     "oneshot with several commands",
+			unitType: ".service",
+			content:  "[Service]\nType=oneshot\nExecStart=/usr/bin/myapp migrate\nExecStart=/usr/bin/myapp warm\n",
+		},
+		{
+			name:     "syntax errors",
+			unitType: ".service",
+			content:  "Description=outside\n[Unit\n[Service]\nExecStart /usr/bin/myapp\n=value\nExecStart=/usr/bin/myapp\n",
+			expected: []string{
+				"myapp.service:1: Description= is outside of any section",
+				`myapp.service:2: invalid section header "[Unit"`,
+				`myapp.service:4: expected KEY=value, got "ExecStart /usr/bin/myapp"`,
+				"myapp.service:5: missing key before '='",
+			},
+		},
+		{
+			name:     "service without commands",
+			unitType: ".service",
+			content:  "[Service]\nType=simple\nExecStart=/usr/bin/myapp\nExecStart=\n",
+			expected: []string{"myapp.service: service has no ExecStart=, ExecStop=, or SuccessAction="},
+		},
+		{
+			name:     "service errors",
+			unitType: ".service",
+			content:  "[Unit]\nDescription=My app\n[Service]\nType=daemon\nExecStart=/usr/bin/myapp\nExecStart=/usr/bin/other\n[Timer]\nOnCalendar=daily\n[Timer]\nOnBootSec=5m\n",
+			expected: []string{
+				"myapp.service:8: unknown section [Timer] in a service unit",
+				"myapp.service:4: invalid Type=daemon",
+				"myapp.service:6: more than one ExecStart= is only allowed with Type=oneshot",
+			},
+		},
+		{
+			name:     "timer without trigger",
+			unitType: ".timer",
+			content:  "[Timer]\nPersistent=true\n",
+			expected: []string{"myapp.timer: timer has no OnCalendar= or other On*= trigger"},
+		},
+		{
+			name:     "valid timer",
+			unitType: ".timer",
+			content:  "[Timer]\nOnCalendar=daily\n[Install]\nWantedBy=timers.target\n",
+		},
+		{
+			name:     "socket without listener",
+			unitType: ".socket",
+			content:  "[Socket]\nAccept=no\n",
+			expected: []string{"myapp.socket: socket has no Listen*= setting"},
+		},
+		{
+			name:     "target",
+			unitType: ".target",
+			content:  "[Unit]\nDescription=My app services\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := verifySystemdUnit("myapp"+tt.unitType, tt.unitType, []byte(tt.content)); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestSystemdUnitFiles tests finding the unit files an nfpm config packages.
+func TestSystemdUnitFiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	for _, file := range []string{"systemd/myapp.service", "systemd/myapp.timer", "systemd/README.md", "init/unit", "bin/myapp"} {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", file, err)
+		}
+	}
+
+	doc := map[string]any{"contents": []any{
+		map[string]any{"src": "systemd/*", "dst": "/usr/lib/systemd/system/"},
+		map[string]any{"src": "${UNIT}", "dst": "/usr/lib/systemd/system/myapp-worker.service", "expand": true},
+		map[string]any{"src": "bin/myapp", "dst": "/usr/bin/myapp"},
+		map[string]any{"src": "/usr/lib/systemd/system/myapp.service", "dst": "/etc/systemd/system/myapp.service", "type": "symlink"},
+		map[string]any{"src": "missing.service", "dst": "/usr/lib/systemd/system/missing.service"},
+	}}
+	rebaseNfpmPaths(doc, dir)
+	getenv := func(key string) string {
+		if key == "UNIT" {
+			return filepath.Join(dir, "init", "unit")
+		}
+		return ""
+	}
+
+	units, err := systemdUnitFiles(doc, getenv)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]string{
+		filepath.Join(dir, "systemd", "myapp.service"): ".service",
+		filepath.Join(dir, "systemd", "myapp.timer"):   ".timer",
+		filepath.Join(dir, "init", "unit"):             ".service",
+	}
+	if !reflect.DeepEqual(units, expected) {
+		t.Errorf("expected %v, got %v", expected, units)
+	}
+}
+
+// TestExecuteVerifyUnits tests that broken units stop the release before packaging.
+func TestExecuteVerifyUnits(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	config := "name: myapp\nversion: 1.2.3\ncontents:\n  - src: myapp.service\n    dst: /usr/lib/systemd/system/myapp.service\n"
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "myapp.service"), []byte("[Unit]\nDescription=My app\n[Service]\nExecstart /usr/bin/myapp\n"), 0644); err != nil {
+		t.Fatalf("failed to write unit: %v", err)
+	}
+
+	for _, verify := range []bool{true, false} {
+		mock := &MockCommandExecutor{}
+		p := &LinuxPkgPlugin{cmdExecutor: mock}
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  map[string]any{"working_dir": dir, "formats": []string{"deb", "rpm"}, "packager": "nfpm-cli", "verify_units": verify},
+			Context: plugin.ReleaseContext{Version: "1.2.3"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !verify {
+			if len(mock.Calls) != 2 {
+				t.Errorf("expected packages to be built without verify_units, got %v", mock.Calls)
+			}
+			continue
+		}
+		unit := filepath.Join(dir, "myapp.service")
+		expected := "invalid systemd units:\n" +
+			"  " + unit + ":4: expected KEY=value, got \"Execstart /usr/bin/myapp\"\n" +
+			"  " + unit + ": service has no ExecStart=, ExecStop=, or SuccessAction="
+		if resp.Success || resp.Error != expected {
+			t.Errorf("expected error:\n%s\ngot: %+v", expected, resp)
+		}
+		if len(mock.Calls) != 0 {
+			t.Errorf("expected nfpm not to run, got %v", mock.Calls)
+		}
+	}
+}
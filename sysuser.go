@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// systemUserNamePattern matches user names every distribution accepts.
+var systemUserNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_-]{0,30}$`)
+
+// systemUserDirPattern matches absolute directories that need no quoting in sysusers.d,
+// tmpfiles.d, or shell scripts.
+var systemUserDirPattern = regexp.MustCompile(`^/[A-Za-z0-9._/+-]*$`)
+
+// SystemUserConfig creates the system user a package runs as, and the directories it owns.
+type SystemUserConfig struct {
+	// Name is the user and group name.
+	Name string
+	// Home is the user's home directory. Defaults to /var/lib/<name>.
+	Home string
+	// Dirs are extra directories owned by the user, such as /var/log/<name>.
+	Dirs []string
+}
+
+// parseSystemUser parses the system_user block. It returns nil when no user is configured.
+func parseSystemUser(raw map[string]any) *SystemUserConfig {
+	block := helpers.NewConfigParser(raw).GetMap("system_user")
+	if block == nil {
+		return nil
+	}
+
+	parser := helpers.NewConfigParser(block)
+	user := &SystemUserConfig{
+		Name: parser.GetString("name", "", ""),
+		Home: parser.GetString("home", "", ""),
+		Dirs: parser.GetStringSlice("dirs", nil),
+	}
+	if user.Home == "" && user.Name != "" {
+		user.Home = "/var/lib/" + user.Name
+	}
+	return user
+}
+
+// validate checks the system user settings.
+func (u *SystemUserConfig) validate() error {
+	if !systemUserNamePattern.MatchString(u.Name) {
+		return fmt.Errorf("invalid name %q: use up to 32 lowercase letters, digits, '_' and '-'", u.Name)
+	}
+	for _, dir := range append([]string{u.Home}, u.Dirs...) {
+		if !systemUserDirPattern.MatchString(dir) || path.Clean(dir) != dir || dir == "/" {
+			return fmt.Errorf("invalid directory %q: use a clean absolute path", dir)
+		}
+	}
+	return nil
+}
+
+// ownedDirs returns the home directory followed by the other directories, without duplicates.
+func (u *SystemUserConfig) ownedDirs() []string {
+	dirs := []string{u.Home}
+	for _, dir := range u.Dirs {
+		if !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// sysusersConfig returns the sysusers.d fragment creating the user and its group.
+func (u *SystemUserConfig) sysusersConfig() string {
+	return fmt.Sprintf("u %s - \"%s system user\" %s -\n", u.Name, u.Name, u.Home)
+}
+
+// tmpfilesConfig returns the tmpfiles.d fragment creating the user's directories.
+func (u *SystemUserConfig) tmpfilesConfig() string {
+	var b strings.Builder
+	for _, dir := range u.ownedDirs() {
+		fmt.Fprintf(&b, "d %s 0750 %s %s -\n", dir, u.Name, u.Name)
+	}
+	return b.String()
+}
+
+// postinstallScript returns the postinstall snippet applying the fragments with
+// systemd-sysusers and systemd-tmpfiles, falling back to useradd or busybox adduser and
+// mkdir on systems without them.
+func (u *SystemUserConfig) postinstallScript() string {
+	dirs := strings.Join(u.ownedDirs(), " ")
+	return fmt.Sprintf(`if command -v systemd-sysusers >/dev/null 2>&1; then
+	systemd-sysusers %[1]s.conf
+elif command -v useradd >/dev/null 2>&1; then
+	getent group %[1]s >/dev/null || groupadd --system %[1]s
+	getent passwd %[1]s >/dev/null || useradd --system --gid %[1]s --home-dir %[2]s --no-create-home \
+		--shell /sbin/nologin --comment "%[1]s system user" %[1]s
+else
+	getent group %[1]s >/dev/null || addgroup -S %[1]s
+	getent passwd %[1]s >/dev/null || adduser -S -D -H -h %[2]s -s /sbin/nologin -G %[1]s -g "%[1]s system user" %[1]s
+fi
+if command -v systemd-tmpfiles >/dev/null 2>&1; then
+	systemd-tmpfiles --create %[1]s.conf
+else
+	mkdir -p %[3]s
+	chown %[1]s:%[1]s %[3]s
+	chmod 0750 %[3]s
+fi
+`, u.Name, u.Home, dirs)
+}
+
+// addSystemUser writes the sysusers.d and tmpfiles.d fragments for user into dir, adds them
+// to doc's contents, and runs the postinstall snippet before doc's own postinstall script.
+func addSystemUser(doc map[string]any, user *SystemUserConfig, dir string) error {
+	fragments := []struct {
+		name, dst, content string
+	}{
+		{"sysusers.conf", "/usr/lib/sysusers.d/" + user.Name + ".conf", user.sysusersConfig()},
+		{"tmpfiles.conf", "/usr/lib/tmpfiles.d/" + user.Name + ".conf", user.tmpfilesConfig()},
+	}
+	contents := contentEntries(doc)
+	for _, fragment := range fragments {
+		src := filepath.Join(dir, fragment.name)
+		if err := os.WriteFile(src, []byte(fragment.content), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", fragment.name, err)
+		}
+		contents = append(contents, map[string]any{
+			"src":       src,
+			"dst":       fragment.dst,
+			"file_info": map[string]any{"mode": 0644},
+		})
+	}
+	doc["contents"] = contents
+
+	docScripts, _ := doc["scripts"].(map[string]any)
+	if docScripts == nil {
+		docScripts = make(map[string]any, 1)
+	}
+	shebang, body := "#!/bin/sh", ""
+	if existing, ok := docScripts["postinstall"].(string); ok && existing != "" {
+		content, err := os.ReadFile(existing)
+		if err != nil {
+			return fmt.Errorf("failed to read postinstall script: %w", err)
+		}
+		// The package's own script keeps its interpreter and runs after the user exists.
+		body = string(content)
+		if first, rest, _ := strings.Cut(body, "\n"); strings.HasPrefix(first, "#!") {
+			shebang, body = first, rest
+		}
+	}
+
+	script := shebang + "\n" + user.postinstallScript() + body
+	postinstall := filepath.Join(dir, "postinstall-system-user.sh")
+	if err := os.WriteFile(postinstall, []byte(script), 0755); err != nil {
+		return fmt.Errorf("failed to write postinstall script: %w", err)
+	}
+	docScripts["postinstall"] = postinstall
+	doc["scripts"] = docScripts
+	return nil
+}
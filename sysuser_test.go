@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+	"gopkg.in/yaml.v3"
+)
+
+// TestValidateSystemUser tests system_user validation.
+func TestValidateSystemUser(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		user        any
+		expectError string
+	}{
+		{"valid", map[string]any{"name": "myapp", "dirs": []any{"/var/log/myapp"}}, ""},
+		{"custom home", map[string]any{"name": "myapp", "home": "/srv/myapp"}, ""},
+		{"missing name", map[string]any{"home": "/srv/myapp"}, "invalid name"},
+		{"bad name", map[string]any{"name": "MyApp"}, "invalid name"},
+		{"relative dir", map[string]any{"name": "myapp", "dirs": []any{"var/log/myapp"}}, "invalid directory"},
+		{"unclean dir", map[string]any{"name": "myapp", "home": "/var/lib/../myapp"}, "invalid directory"},
+		{"root dir", map[string]any{"name": "myapp", "dirs": []any{"/"}}, "invalid directory"},
+		{"dir with space", map[string]any{"name": "myapp", "dirs": []any{"/var/lib/my app"}}, "invalid directory"},
+		{"not an object", "myapp", "must be an object"},
+	}
+
+	p := &LinuxPkgPlugin{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			resp, err := p.Validate(context.Background(), map[string]any{"system_user": tt.user})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectError == "" {
+				if !resp.Valid {
+					t.Errorf("expected valid config, got errors: %v", resp.Errors)
+				}
+				return
+			}
+			if resp.Valid || len(resp.Errors) == 0 || resp.Errors[0].Field != "system_user" ||
+				!strings.Contains(resp.Errors[0].Message, tt.expectError) {
+				t.Errorf("expected system_user error containing %q, got %v", tt.expectError, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestSystemUserFragments tests the generated sysusers.d and tmpfiles.d fragments.
+func TestSystemUserFragments(t *testing.T) {
+	t.Parallel()
+
+	user := parseSystemUser(map[string]any{"system_user": map[string]any{"name": "myapp", "dirs": []any{"/var/log/myapp", "/var/lib/myapp"}}})
+	if user.Home != "/var/lib/myapp" {
+		t.Errorf("expected the default home, got %q", user.Home)
+	}
+	if got, expected := user.sysusersConfig(), "u myapp - \"myapp system user\" /var/lib/myapp -\n"; got != expected {
+		t.Errorf("expected sysusers.d fragment %q, got %q", expected, got)
+	}
+	if got, expected := user.tmpfilesConfig(), "d /var/lib/myapp 0750 myapp myapp -\nd /var/log/myapp 0750 myapp myapp -\n"; got != expected {
+		t.Errorf("expected tmpfiles.d fragment %q, got %q", expected, got)
+	}
+}
+
+// TestAddSystemUser tests adding the fragments and the postinstall fallback to a config.
+func TestAddSystemUser(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "postinstall.sh")
+	if err := os.WriteFile(existing, []byte("#!/bin/bash\nsystemctl daemon-reload\n"), 0755); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+
+	user := &SystemUserConfig{Name: "myapp", Home: "/var/lib/myapp"}
+	tests := []struct {
+		name    string
+		scripts map[string]any
+		shebang string
+		suffix  string
+	}{
+		{"no script", nil, "#!/bin/sh\n", "fi\n"},
+		{"existing script", map[string]any{"postinstall": existing, "preremove": "preremove.sh"}, "#!/bin/bash\n", "fi\nsystemctl daemon-reload\n"},
+	}
+
+	for _, tt := range tests {
+		doc := map[string]any{"contents": []any{map[string]any{"src": "myapp", "dst": "/usr/bin/myapp"}}}
+		if tt.scripts != nil {
+			doc["scripts"] = tt.scripts
+		}
+		assets := t.TempDir()
+		if err := addSystemUser(doc, user, assets); err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+
+		var dsts []string
+		for _, entry := range contentEntries(doc) {
+			dsts = append(dsts, entry.(map[string]any)["dst"].(string))
+		}
+		expected := []string{"/usr/bin/myapp", "/usr/lib/sysusers.d/myapp.conf", "/usr/lib/tmpfiles.d/myapp.conf"}
+		if !reflect.DeepEqual(dsts, expected) {
+			t.Errorf("%s: expected contents %v, got %v", tt.name, expected, dsts)
+		}
+
+		scripts := doc["scripts"].(map[string]any)
+		script, err := os.ReadFile(scripts["postinstall"].(string))
+		if err != nil {
+			t.Fatalf("%s: failed to read postinstall: %v", tt.name, err)
+		}
+		if !strings.HasPrefix(string(script), tt.shebang+"if command -v systemd-sysusers") || !strings.HasSuffix(string(script), tt.suffix) ||
+			!strings.Contains(string(script), "useradd --system --gid myapp --home-dir /var/lib/myapp") {
+			t.Errorf("%s: unexpected postinstall script:\n%s", tt.name, script)
+		}
+		if tt.scripts != nil && scripts["preremove"] != "preremove.sh" {
+			t.Errorf("%s: expected other scripts to be kept, got %v", tt.name, scripts)
+		}
+	}
+}
+
+// TestExecuteSystemUser tests that the system user files reach nfpm.
+func TestExecuteSystemUser(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: myapp\nversion: 1.2.3\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	var tmpfiles string
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			content, err := os.ReadFile(args[2])
+			if err != nil {
+				return nil, err
+			}
+			var doc map[string]any
+			if err := yaml.Unmarshal(content, &doc); err != nil {
+				return nil, err
+			}
+			for _, raw := range contentEntries(doc) {
+				entry := raw.(map[string]any)
+				if entry["dst"] == "/usr/lib/tmpfiles.d/myapp.conf" {
+					fragment, err := os.ReadFile(entry["src"].(string))
+					if err != nil {
+						return nil, err
+					}
+					tmpfiles = string(fragment)
+				}
+			}
+			return []byte("created package: " + args[len(args)-1] + "myapp.deb"), nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb"},
+			"packager":    "nfpm-cli",
+			"system_user": map[string]any{"name": "myapp", "dirs": []string{"/var/log/myapp"}},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+	if expected := "d /var/lib/myapp 0750 myapp myapp -\nd /var/log/myapp 0750 myapp myapp -\n"; tmpfiles != expected {
+		t.Errorf("expected tmpfiles.d fragment %q, got %q", expected, tmpfiles)
+	}
+}
+
+// TestExecuteSystemUserEmbedded tests building packages with a system user with the
+// embedded library.
+func TestExecuteSystemUserEmbedded(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"working_dir": dir, "formats": []string{"deb", "rpm", "apk", "archlinux"}, "system_user": map[string]any{"name": "myapp"}},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+	if packages := resp.Outputs["packages"].([]string); len(packages) != 4 {
+		t.Errorf("expected 4 packages, got %v", packages)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// buildTarget is a resolved target architecture for a build.
+type buildTarget struct {
+	// Arch is the resolved architecture name.
+	Arch string
+	// Override reports whether Arch replaces the arch declared in the nfpm config.
+	// It is false for "current", which defers to the config and falls back to the host.
+	Override bool
+}
+
+// architectureAliases maps the architecture names of Debian, rpm, and uname to the
+// Go-style names targets use. Debian's armhf is ARMv7 and armel ARMv5.
+var architectureAliases = map[string]string{
+	"x86_64":  "amd64",
+	"i386":    "386",
+	"i686":    "386",
+	"aarch64": "arm64",
+	"armhf":   "arm/v7",
+	"armel":   "arm/v5",
+	"ppc64el": "ppc64le",
+}
+
+// canonicalArchitecture returns the Go-style name of arch, which may be an alias.
+func canonicalArchitecture(arch string) string {
+	if canonical, ok := architectureAliases[arch]; ok {
+		return canonical
+	}
+	return arch
+}
+
+// nfpmArch returns the architecture name nfpm understands: GOARCH, with the ARM
+// variant folded in as GOARM does ("arm/v7" becomes "arm7").
+func (t buildTarget) nfpmArch() string {
+	if base, variant, ok := strings.Cut(t.Arch, "/v"); ok {
+		return base + variant
+	}
+	return t.Arch
+}
+
+// variant returns the ARM variant of the target, such as "v7", or "".
+func (t buildTarget) variant() string {
+	return armVariant(t.nfpmArch())
+}
+
+// fileName returns the target name for use in file names ("armv7" for "arm/v7").
+func (t buildTarget) fileName() string {
+	return strings.ReplaceAll(t.Arch, "/", "")
+}
+
+// armVariantPattern matches nfpm's ARM architectures with a GOARM variant.
+var armVariantPattern = regexp.MustCompile(`^arm([5-7])$`)
+
+// armVariant returns the variant of an nfpm ARM architecture ("v7" for "arm7"), or "".
+func armVariant(nfpmArch string) string {
+	if m := armVariantPattern.FindStringSubmatch(nfpmArch); m != nil {
+		return "v" + m[1]
+	}
+	return ""
+}
+
+// resolveTargets returns the deduplicated target architectures to build, using
+// Targets when set and Target otherwise. Aliases resolve to their Go-style names.
+func resolveTargets(cfg *Config) ([]buildTarget, error) {
+	requested := cfg.Targets
+	if len(requested) == 0 {
+		requested = []string{cfg.Target}
+	}
+
+	targets := make([]buildTarget, 0, len(requested))
+	seen := make(map[string]bool, len(requested))
+	for _, arch := range requested {
+		if err := validateArchitecture(arch); err != nil {
+			return nil, err
+		}
+
+		target := buildTarget{Arch: canonicalArchitecture(arch), Override: true}
+		if arch == "" || arch == "current" {
+			target = buildTarget{Arch: runtime.GOARCH}
+		}
+
+		if seen[target.Arch] {
+			continue
+		}
+		seen[target.Arch] = true
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
+
+// targetArchs returns the architecture names of the given targets.
+func targetArchs(targets []buildTarget) []string {
+	archs := make([]string, len(targets))
+	for i, target := range targets {
+		archs[i] = target.Arch
+	}
+	return archs
+}
+
+// armVariants counts the distinct ARM variants among targets.
+func armVariants(targets []buildTarget) int {
+	count := 0
+	for _, target := range targets {
+		if target.variant() != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// matrixSummary describes the size of a format x architecture build matrix.
+func matrixSummary(formats, archs int) string {
+	return fmt.Sprintf("%d format(s) x %d architecture(s)", formats, archs)
+}
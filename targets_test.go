@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestResolveTargets tests resolution of target and targets settings.
+func TestResolveTargets(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		cfg       *Config
+		expected  []buildTarget
+		expectErr bool
+	}{
+		{
+			name:     "current target defers to config",
+			cfg:      &Config{Target: "current"},
+			expected: []buildTarget{{Arch: runtime.GOARCH}},
+		},
+		{
+			name:     "explicit target overrides config",
+			cfg:      &Config{Target: "arm64"},
+			expected: []buildTarget{{Arch: "arm64", Override: true}},
+		},
+		{
+			name:     "targets take precedence over target",
+			cfg:      &Config{Target: "386", Targets: []string{"amd64", "arm64"}},
+			expected: []buildTarget{{Arch: "amd64", Override: true}, {Arch: "arm64", Override: true}},
+		},
+		{
+			name:     "duplicates are removed",
+			cfg:      &Config{Targets: []string{"arm64", "arm64", "riscv64"}},
+			expected: []buildTarget{{Arch: "arm64", Override: true}, {Arch: "riscv64", Override: true}},
+		},
+		{
+			name:     "arm variants",
+			cfg:      &Config{Targets: []string{"arm/v6", "arm/v7"}},
+			expected: []buildTarget{{Arch: "arm/v6", Override: true}, {Arch: "arm/v7", Override: true}},
+		},
+		{
+			name:     "aliases",
+			cfg:      &Config{Targets: []string{"amd64", "x86_64", "armhf", "i386"}},
+			expected: []buildTarget{{Arch: "amd64", Override: true}, {Arch: "arm/v7", Override: true}, {Arch: "386", Override: true}},
+		},
+		{
+			name:      "invalid architecture",
+			cfg:       &Config{Targets: []string{"amd64", "sparc64"}},
+			expectErr: true,
+		},
+		{
+			name:      "invalid arm variant",
+			cfg:       &Config{Targets: []string{"arm/v8"}},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := resolveTargets(tc.cfg)
+			if tc.expectErr {
+				if err == nil {
+					t.Errorf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+// TestDryRunMatrix tests dry run reporting for multiple targets.
+func TestDryRunMatrix(t *testing.T) {
+	t.Parallel()
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:   plugin.HookPostPublish,
+		DryRun: true,
+		Config: map[string]any{
+			"formats": []string{"deb", "rpm", "apk"},
+			"targets": []string{"amd64", "arm64"},
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.Contains(resp.Message, "Would build 6 package(s)") || !strings.Contains(resp.Message, "3 format(s) x 2 architecture(s)") {
+		t.Errorf("unexpected message: %q", resp.Message)
+	}
+	if !reflect.DeepEqual(resp.Outputs["targets"], []string{"amd64", "arm64"}) {
+		t.Errorf("unexpected targets output: %v", resp.Outputs["targets"])
+	}
+}
+
+// TestExecuteMatrixWithCLI tests that nfpm-cli receives a config with each target arch.
+func TestExecuteMatrixWithCLI(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: test\nversion: 1.0.0\narch: amd64\n"), 0644); err != nil {
+		t.Fatalf("failed to create test config: %v", err)
+	}
+
+	var built []string
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			doc, err := loadNfpmConfig(args[2])
+			if err != nil {
+				return nil, err
+			}
+			format := args[4]
+			path := filepath.Join(dir, "dist", "test_") + doc["arch"].(string) + packageExtensions[format]
+			built = append(built, path)
+			return []byte("created package: " + path), nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb", "rpm"},
+			"targets":     []string{"arm64", "riscv64"},
+			"packager":    "nfpm-cli",
+		},
+		Context: plugin.ReleaseContext{Version: "1.0.0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	expected := []string{filepath.Join(dir, "dist", "test_arm64.deb"), filepath.Join(dir, "dist", "test_arm64.rpm"), filepath.Join(dir, "dist", "test_riscv64.deb"), filepath.Join(dir, "dist", "test_riscv64.rpm")}
+	if !reflect.DeepEqual(built, expected) {
+		t.Errorf("expected builds %v, got %v", expected, built)
+	}
+
+	if !strings.Contains(resp.Message, "Built 4 Linux package(s) (2 format(s) x 2 architecture(s))") {
+		t.Errorf("unexpected message: %q", resp.Message)
+	}
+
+	artifacts, ok := resp.Outputs["artifacts"].([]map[string]any)
+	if !ok || len(artifacts) != 4 {
+		t.Fatalf("expected 4 artifacts, got %v", resp.Outputs["artifacts"])
+	}
+	last := artifacts[3]
+	if last["path"] != filepath.Join(dir, "dist", "test_riscv64.rpm") || last["format"] != "rpm" || last["arch"] != "riscv64" {
+		t.Errorf("unexpected artifact: %v", last)
+	}
+}
+
+// TestExecuteMatrixEmbedded tests a multi-arch build with the embedded backend.
+func TestExecuteMatrixEmbedded(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "")
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb"},
+			"targets":     []string{"amd64", "arm64", "s390x"},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	artifacts := resp.Outputs["artifacts"].([]map[string]any)
+	if len(artifacts) != 3 {
+		t.Fatalf("expected 3 artifacts, got %v", artifacts)
+	}
+	for i, arch := range []string{"amd64", "arm64", "s390x"} {
+		if artifacts[i]["arch"] != arch {
+			t.Errorf("artifact %d: expected arch %s, got %v", i, arch, artifacts[i]["arch"])
+		}
+		if _, err := os.Stat(artifacts[i]["path"].(string)); err != nil {
+			t.Errorf("expected package %v to exist: %v", artifacts[i]["path"], err)
+		}
+	}
+}
+
+// TestBuildTargetARMVariant tests mapping ARM variant targets to nfpm architectures.
+func TestBuildTargetARMVariant(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		arch     string
+		nfpmArch string
+		variant  string
+		fileName string
+	}{
+		{"arm64", "arm64", "", "arm64"},
+		{"arm", "arm", "", "arm"},
+		{"arm/v5", "arm5", "v5", "armv5"},
+		{"arm/v7", "arm7", "v7", "armv7"},
+	}
+
+	for _, tt := range tests {
+		target := buildTarget{Arch: tt.arch, Override: true}
+		if got := target.nfpmArch(); got != tt.nfpmArch {
+			t.Errorf("%s: expected nfpm arch %s, got %s", tt.arch, tt.nfpmArch, got)
+		}
+		if got := target.variant(); got != tt.variant {
+			t.Errorf("%s: expected variant %q, got %q", tt.arch, tt.variant, got)
+		}
+		if got := target.fileName(); got != tt.fileName {
+			t.Errorf("%s: expected file name %s, got %s", tt.arch, tt.fileName, got)
+		}
+	}
+}
+
+// TestExecuteARMVariants tests building arm/v6 and arm/v7 packages side by side.
+func TestExecuteARMVariants(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "")
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb", "rpm"},
+			"targets":     []string{"arm/v6", "arm/v7"},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	expected := []string{
+		filepath.Join(dir, "dist", "myapp_1.2.3_armhf-v6.deb"),
+		filepath.Join(dir, "dist", "myapp-1.2.3-1.armv6hl.rpm"),
+		filepath.Join(dir, "dist", "myapp_1.2.3_armhf-v7.deb"),
+		filepath.Join(dir, "dist", "myapp-1.2.3-1.armv7hl.rpm"),
+	}
+	if !reflect.DeepEqual(resp.Outputs["packages"], expected) {
+		t.Errorf("expected packages %v, got %v", expected, resp.Outputs["packages"])
+	}
+	if !reflect.DeepEqual(resp.Outputs["targets"], []string{"arm/v6", "arm/v7"}) {
+		t.Errorf("expected targets arm/v6 and arm/v7, got %v", resp.Outputs["targets"])
+	}
+}
+
+// TestValidateTargets tests validation of the targets list.
+func TestValidateTargets(t *testing.T) {
+	t.Parallel()
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Validate(context.Background(), map[string]any{
+		"targets": []any{"amd64", "sparc64"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Valid || len(resp.Errors) != 1 || resp.Errors[0].Field != "targets" {
+		t.Errorf("expected a single targets error, got %v", resp.Errors)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// nfpmTemplateData is the data available to templated nfpm configs. The release context
+// fields are promoted, so templates use {{.Version}}, {{.TagName}}, {{.CommitSHA}}, and so on.
+type nfpmTemplateData struct {
+	plugin.ReleaseContext
+	// ShortCommit is the first seven characters of CommitSHA.
+	ShortCommit string
+	// Date is the build time in RFC 3339 format (UTC).
+	Date string
+	// RunNumber is the CI run or build number, empty outside CI.
+	RunNumber string
+	// Release is the package release (deb revision, rpm Release).
+	Release string
+	// SourceDateEpoch is the build time in seconds for reproducible builds, empty otherwise.
+	SourceDateEpoch string
+}
+
+// runNumberEnv are the variables CI systems put their run or build number in.
+var runNumberEnv = []string{
+	"GITHUB_RUN_NUMBER",
+	"CI_PIPELINE_IID",
+	"BUILDKITE_BUILD_NUMBER",
+	"CIRCLE_BUILD_NUM",
+	"BUILD_NUMBER",
+}
+
+// packageReleasePattern matches package releases every format accepts.
+var packageReleasePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.+~_]*$`)
+
+// newNfpmTemplateData returns the template data for a release.
+func newNfpmTemplateData(release plugin.ReleaseContext, now time.Time) *nfpmTemplateData {
+	shortCommit := release.CommitSHA
+	if len(shortCommit) > 7 {
+		shortCommit = shortCommit[:7]
+	}
+	return &nfpmTemplateData{
+		ReleaseContext: release,
+		ShortCommit:    shortCommit,
+		Date:           now.UTC().Format(time.RFC3339),
+		RunNumber:      runNumber(release.Environment),
+		Release:        defaultPackageRelease,
+	}
+}
+
+// runNumber returns the CI run number from the release environment or the process
+// environment.
+func runNumber(environment map[string]string) string {
+	for _, key := range runNumberEnv {
+		if value := environment[key]; value != "" {
+			return value
+		}
+		if value := os.Getenv(key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// validateReleaseTemplate checks the syntax of the release option.
+func validateReleaseTemplate(release string) error {
+	if _, err := template.New("release").Parse(release); err != nil {
+		return err
+	}
+	return nil
+}
+
+// renderPackageRelease renders the release option with the template data. The result
+// must be a release every format accepts: no '-' or whitespace.
+func renderPackageRelease(release string, data *nfpmTemplateData) (string, error) {
+	tmpl, err := template.New("release").Option("missingkey=error").Parse(release)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	rendered := strings.TrimSpace(buf.String())
+	if !packageReleasePattern.MatchString(rendered) {
+		return "", fmt.Errorf("%q renders to %q, which is not a valid package release (letters, digits, '.', '+', '~', '_')", release, rendered)
+	}
+	return rendered, nil
+}
+
+// executeNfpmTemplate renders an nfpm config file's contents as a Go text/template.
+// Referencing an unknown field is an error rather than an empty value.
+func executeNfpmTemplate(path string, content []byte, data *nfpmTemplateData) ([]byte, error) {
+	tmpl, err := template.New(filepath.Base(path)).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse nfpm config template %s: %w", path, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render nfpm config template %s: %w", path, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// defaultPackageRelease is the package release (revision) used when none is configured.
+const defaultPackageRelease = "1"
+
+// env returns the release as the environment variables nfpm configs conventionally
+// reference: VERSION, RELEASE, COMMIT, and DATE, and SOURCE_DATE_EPOCH for reproducible
+// builds. Empty values are left out so they do not mask variables set in the environment.
+func (d *nfpmTemplateData) env() []string {
+	vars := [][2]string{
+		{"VERSION", d.Version},
+		{"RELEASE", d.Release},
+		{"COMMIT", d.CommitSHA},
+		{"DATE", d.Date},
+		{sourceDateEpochEnv, d.SourceDateEpoch},
+	}
+
+	env := make([]string, 0, len(vars))
+	for _, v := range vars {
+		if v[1] != "" {
+			env = append(env, v[0]+"="+v[1])
+		}
+	}
+	return env
+}
+
+// envLookup returns a lookup that resolves variables from env, a list of KEY=value
+// pairs, before falling back to getenv.
+func envLookup(env []string, getenv func(string) string) func(string) string {
+	values := make(map[string]string, len(env))
+	for _, kv := range env {
+		key, value, _ := strings.Cut(kv, "=")
+		values[key] = value
+	}
+	return func(key string) string {
+		if value, ok := values[key]; ok {
+			return value
+		}
+		return getenv(key)
+	}
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestExecuteNfpmTemplate tests rendering nfpm configs with the release context.
+func TestExecuteNfpmTemplate(t *testing.T) {
+	t.Parallel()
+
+	data := newNfpmTemplateData(plugin.ReleaseContext{
+		Version:       "1.2.3",
+		TagName:       "v1.2.3",
+		CommitSHA:     "0123456789abcdef",
+		RepositoryURL: "https://github.com/example/myapp",
+	}, time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60)))
+
+	tests := []struct {
+		name        string
+		template    string
+		expected    string
+		expectError string
+	}{
+		{
+			name:     "release fields",
+			template: "version: {{.Version}}\nhomepage: {{.RepositoryURL}}\n",
+			expected: "version: 1.2.3\nhomepage: https://github.com/example/myapp\n",
+		},
+		{
+			name:     "commit and date",
+			template: "release: {{.ShortCommit}}\ndescription: built from {{.TagName}} at {{.Date}}\n",
+			expected: "release: 0123456\ndescription: built from v1.2.3 at 2024-05-01T10:00:00Z\n",
+		},
+		{
+			name:     "no actions",
+			template: "name: myapp\n",
+			expected: "name: myapp\n",
+		},
+		{
+			name:        "unknown field",
+			template:    "version: {{.Revision}}\n",
+			expectError: "failed to render nfpm config template",
+		},
+		{
+			name:        "syntax error",
+			template:    "version: {{.Version\n",
+			expectError: "failed to parse nfpm config template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := executeNfpmTemplate("nfpm.yaml", []byte(tt.template), data)
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Fatalf("expected error containing %q, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, string(got))
+			}
+		})
+	}
+}
+
+// TestPrepareNfpmConfigTemplate tests that templates are only rendered when enabled.
+func TestPrepareNfpmConfigTemplate(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nfpm.yaml")
+	if err := os.WriteFile(path, []byte("name: myapp\nversion: \"{{.Version}}\"\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	overlay := filepath.Join(dir, "overlay.yaml")
+	if err := os.WriteFile(overlay, []byte("release: \"{{.ShortCommit}}\"\n"), 0644); err != nil {
+		t.Fatalf("failed to write overlay: %v", err)
+	}
+	data := newNfpmTemplateData(plugin.ReleaseContext{Version: "2.0.0", CommitSHA: "abcdef0123"}, time.Now())
+
+	got, cleanup, err := prepareNfpmConfig(&Config{
+		ConfigPath:          path,
+		ConfigOverlays:      []string{overlay},
+		OverlayListStrategy: "replace",
+		TemplateConfig:      true,
+	}, "", data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cleanup()
+
+	doc, err := loadNfpmConfig(got)
+	if err != nil {
+		t.Fatalf("failed to read rendered config: %v", err)
+	}
+	if doc["version"] != "2.0.0" || doc["release"] != "abcdef0" {
+		t.Errorf("expected version 2.0.0 and release abcdef0, got %v and %v", doc["version"], doc["release"])
+	}
+
+	untemplated, cleanup, err := prepareNfpmConfig(&Config{ConfigPath: path, OverlayListStrategy: "replace"}, "", data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cleanup()
+	if untemplated != path {
+		t.Errorf("expected the config to be used in place without template_config, got %s", untemplated)
+	}
+}
+
+// TestExecuteTemplateConfig tests building a package whose version comes from the release.
+func TestExecuteTemplateConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	configPath := writeEmbeddedTestConfig(t, dir, "amd64")
+
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	templated := strings.Replace(string(content), "version: 1.2.3", "version: \"{{.Version}}\"", 1)
+	if err := os.WriteFile(configPath, []byte(templated), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"working_dir": dir, "formats": []string{"deb"}, "template_config": true},
+		Context: plugin.ReleaseContext{Version: "4.5.6", TagName: "v4.5.6"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	packages := resp.Outputs["packages"].([]string)
+	if len(packages) != 1 || !strings.Contains(filepath.Base(packages[0]), "4.5.6") {
+		t.Errorf("expected a package for version 4.5.6, got %v", packages)
+	}
+}
+
+// TestNfpmTemplateDataEnv tests the release variables passed to nfpm.
+func TestNfpmTemplateDataEnv(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	data := newNfpmTemplateData(plugin.ReleaseContext{Version: "1.2.3", CommitSHA: "0123456789abcdef"}, now)
+	expected := []string{"VERSION=1.2.3", "RELEASE=1", "COMMIT=0123456789abcdef", "DATE=2024-05-01T10:00:00Z"}
+	if got := data.env(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	// Unknown values are left to the environment.
+	data = newNfpmTemplateData(plugin.ReleaseContext{Version: "1.2.3"}, now)
+	expected = []string{"VERSION=1.2.3", "RELEASE=1", "DATE=2024-05-01T10:00:00Z"}
+	if got := data.env(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	lookup := envLookup(expected, func(key string) string { return "env-" + key })
+	if lookup("VERSION") != "1.2.3" || lookup("COMMIT") != "env-COMMIT" {
+		t.Errorf("expected release values to take precedence, got VERSION=%s COMMIT=%s", lookup("VERSION"), lookup("COMMIT"))
+	}
+}
+
+// TestExecuteReleaseEnvCLI tests that nfpm-cli runs with the release variables.
+func TestExecuteReleaseEnvCLI(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: myapp\nversion: ${VERSION}\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	mock := &MockCommandExecutor{}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"working_dir": dir, "formats": []string{"deb"}, "packager": "nfpm-cli"},
+		Context: plugin.ReleaseContext{Version: "2.3.4", CommitSHA: "abc123"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	if len(mock.Calls) != 1 || mock.Calls[0].Name != "nfpm" {
+		t.Fatalf("expected one nfpm call, got %+v", mock.Calls)
+	}
+	env := strings.Join(mock.Calls[0].Env, " ")
+	for _, want := range []string{"VERSION=2.3.4", "RELEASE=1", "COMMIT=abc123", "DATE="} {
+		if !strings.Contains(env, want) {
+			t.Errorf("expected %s in nfpm environment, got %v", want, mock.Calls[0].Env)
+		}
+	}
+}
+
+// TestExecuteReleaseEnvEmbedded tests that ${VERSION} in the nfpm config resolves to the
+// release version with the embedded packager.
+// Note: This test cannot run in parallel due to t.Setenv usage.
+func TestExecuteReleaseEnvEmbedded(t *testing.T) {
+	t.Setenv("VERSION", "0.0.0")
+	dir := t.TempDir()
+	configPath := writeEmbeddedTestConfig(t, dir, "amd64")
+
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	if err := os.WriteFile(configPath, []byte(strings.Replace(string(content), "version: 1.2.3", "version: ${VERSION}", 1)), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"working_dir": dir, "formats": []string{"deb"}},
+		Context: plugin.ReleaseContext{Version: "7.8.9"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	packages := resp.Outputs["packages"].([]string)
+	if len(packages) != 1 || !strings.Contains(filepath.Base(packages[0]), "7.8.9") {
+		t.Errorf("expected a package for version 7.8.9, got %v", packages)
+	}
+}
+
+// TestRenderPackageRelease tests rendering the release option.
+// Note: This test cannot run in parallel due to t.Setenv usage.
+func TestRenderPackageRelease(t *testing.T) {
+	for _, key := range runNumberEnv {
+		t.Setenv(key, "")
+	}
+	t.Setenv("BUILD_NUMBER", "17")
+
+	data := newNfpmTemplateData(plugin.ReleaseContext{
+		Version:     "1.2.3",
+		CommitSHA:   "0123456789abcdef",
+		Environment: map[string]string{"GITHUB_RUN_NUMBER": "42"},
+	}, time.Now())
+	if data.RunNumber != "42" {
+		t.Errorf("expected the release environment to take precedence, got run number %q", data.RunNumber)
+	}
+	if got := newNfpmTemplateData(plugin.ReleaseContext{}, time.Now()).RunNumber; got != "17" {
+		t.Errorf("expected run number 17 from BUILD_NUMBER, got %q", got)
+	}
+
+	tests := []struct {
+		name        string
+		release     string
+		expected    string
+		expectError string
+	}{
+		{name: "literal", release: "3", expected: "3"},
+		{name: "run number", release: "{{.RunNumber}}", expected: "42"},
+		{name: "composite", release: "{{.RunNumber}}.git{{.ShortCommit}}", expected: "42.git0123456"},
+		{name: "dash", release: "{{.RunNumber}}-1", expectError: "not a valid package release"},
+		{name: "empty", release: "{{.Branch}}", expectError: "not a valid package release"},
+		{name: "unknown field", release: "{{.Run}}", expectError: "can't evaluate field Run"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderPackageRelease(tt.release, data)
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Fatalf("expected error containing %q, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestExecutePackageRelease tests that the configured release reaches deb and rpm packages.
+// Note: This test cannot run in parallel due to t.Setenv usage.
+func TestExecutePackageRelease(t *testing.T) {
+	t.Setenv("GITHUB_RUN_NUMBER", "42")
+	dir := t.TempDir()
+	writeEmbeddedTestConfig(t, dir, "amd64")
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"working_dir": dir, "formats": []string{"deb", "rpm"}, "revision": "{{.RunNumber}}"},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	packages := resp.Outputs["packages"].([]string)
+	if len(packages) != 2 || !strings.Contains(packages[0], "1.2.3-42") || !strings.Contains(packages[1], "1.2.3-42") {
+		t.Errorf("expected packages with release 42, got %v", packages)
+	}
+
+	resp, err = p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"working_dir": dir, "release": "{{.Branch}}"},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+		DryRun:  true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Success || !strings.Contains(resp.Error, "invalid release") {
+		t.Errorf("expected an invalid release error, got success=%v error=%s", resp.Success, resp.Error)
+	}
+}
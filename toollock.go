@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ToolLock pins every tool the plugin fetches to a digest, so builds run the same
+// helpers on every host and a tampered download or retagged image fails the run.
+type ToolLock struct {
+	// Nfpm maps "<version>/<os>_<arch>", e.g. "v2.41.1/linux_amd64", to the SHA-256
+	// digest of the nfpm release archive downloaded for nfpm_version.
+	Nfpm map[string]string `json:"nfpm"`
+	// Images maps container image references, as configured and without a digest, to
+	// their "sha256:<hex>" digests.
+	Images map[string]string `json:"images"`
+}
+
+// loadToolLock reads and checks the lock file at path.
+func loadToolLock(path string) (*ToolLock, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var lock ToolLock
+	if err := json.Unmarshal(data, &lock); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for _, key := range sortedKeys(lock.Nfpm) {
+		if !sha256Pattern.MatchString(lock.Nfpm[key]) {
+			return nil, fmt.Errorf("%s: invalid sha256 for nfpm %s: %q", path, key, lock.Nfpm[key])
+		}
+	}
+	for _, image := range sortedKeys(lock.Images) {
+		if strings.Contains(image, "@") {
+			return nil, fmt.Errorf("%s: image %s must be listed without its digest", path, image)
+		}
+		if !imageDigestPattern.MatchString(lock.Images[image]) {
+			return nil, fmt.Errorf("%s: invalid digest for image %s: %q", path, image, lock.Images[image])
+		}
+	}
+	return &lock, nil
+}
+
+// nfpmDigest returns the locked digest of the nfpm release archive for version on
+// platform, given as "<os>_<arch>".
+func (l *ToolLock) nfpmDigest(version, platform string) (string, error) {
+	key := version + "/" + platform
+	digest, ok := l.Nfpm[key]
+	if !ok {
+		return "", fmt.Errorf("tool_lock has no digest for nfpm %s", key)
+	}
+	return digest, nil
+}
+
+// pinImage returns image pinned to its locked digest. A nil lock returns image
+// unchanged. An image already pinned by digest must match the lock when it lists the
+// image; an unpinned image the lock does not list is an error.
+func (l *ToolLock) pinImage(image string) (string, error) {
+	if l == nil {
+		return image, nil
+	}
+	name, digest, pinned := strings.Cut(image, "@")
+	locked, ok := l.Images[name]
+	switch {
+	case pinned && ok && digest != locked:
+		return "", fmt.Errorf("image %s is pinned to %s, but tool_lock lists %s", name, digest, locked)
+	case pinned:
+		return image, nil
+	case !ok:
+		return "", fmt.Errorf("tool_lock has no digest for image %s", name)
+	}
+	return name + "@" + locked, nil
+}
+
+// validateToolLock checks that the lock file is a safe path to a valid lock in
+// workingDir.
+func validateToolLock(workingDir, path string) error {
+	if err := validatePath(path); err != nil {
+		return err
+	}
+	_, err := loadToolLock(inWorkingDir(workingDir, path))
+	return err
+}
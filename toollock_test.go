@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestLoadToolLock tests reading and checking lock files.
+func TestLoadToolLock(t *testing.T) {
+	t.Parallel()
+
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	tests := []struct {
+		name        string
+		content     string
+		expectError string
+	}{
+		{"valid", `{"nfpm": {"v2.41.1/linux_amd64": "` + strings.Repeat("a", 64) + `"}, "images": {"debian:stable-slim": "` + digest + `"}}`, ""},
+		{"empty", `{}`, ""},
+		{"not json", `nfpm: {}`, "failed to parse"},
+		{"bad nfpm digest", `{"nfpm": {"v2.41.1/linux_amd64": "sha256:abc"}}`, "invalid sha256 for nfpm v2.41.1/linux_amd64"},
+		{"image with digest", `{"images": {"debian@` + digest + `": "` + digest + `"}}`, "must be listed without its digest"},
+		{"bad image digest", `{"images": {"debian:stable-slim": "latest"}}`, "invalid digest for image debian:stable-slim"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "tools.lock.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write lock: %v", err)
+			}
+			_, err := loadToolLock(path)
+			if tt.expectError == "" && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.expectError != "" && (err == nil || !strings.Contains(err.Error(), tt.expectError)) {
+				t.Errorf("expected error containing %q, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+// TestToolLockPinImage tests pinning images to their locked digests.
+func TestToolLockPinImage(t *testing.T) {
+	t.Parallel()
+
+	locked := "sha256:" + strings.Repeat("ab", 32)
+	other := "sha256:" + strings.Repeat("cd", 32)
+	lock := &ToolLock{Images: map[string]string{"goreleaser/nfpm:v2.41.1": locked}}
+
+	tests := []struct {
+		name        string
+		lock        *ToolLock
+		image       string
+		expected    string
+		expectError string
+	}{
+		{"no lock", nil, "fedora:latest", "fedora:latest", ""},
+		{"locked", lock, "goreleaser/nfpm:v2.41.1", "goreleaser/nfpm:v2.41.1@" + locked, ""},
+		{"pinned as locked", lock, "goreleaser/nfpm:v2.41.1@" + locked, "goreleaser/nfpm:v2.41.1@" + locked, ""},
+		{"pinned elsewhere", lock, "goreleaser/nfpm:v2.41.1@" + other, "", "but tool_lock lists " + locked},
+		{"pinned and not listed", lock, "fedora:latest@" + other, "fedora:latest@" + other, ""},
+		{"not listed", lock, "fedora:latest", "", "tool_lock has no digest for image fedora:latest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			image, err := tt.lock.pinImage(tt.image)
+			if tt.expectError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+					t.Errorf("expected error containing %q, got %v", tt.expectError, err)
+				}
+				return
+			}
+			if err != nil || image != tt.expected {
+				t.Errorf("expected %s, got %s (%v)", tt.expected, image, err)
+			}
+		})
+	}
+}
+
+// TestEnsureNfpmToolLock tests that a downloaded or cached nfpm must match the archive
+// digest tool_lock pins for the host platform.
+func TestEnsureNfpmToolLock(t *testing.T) {
+	t.Parallel()
+
+	server, _ := nfpmReleaseServer(t, "v2.41.1", "", true)
+	resp, err := server.Client().Get(server.URL + "/v2.41.1/checksums.txt")
+	if err != nil {
+		t.Fatalf("failed to get checksums: %v", err)
+	}
+	checksums, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	asset, _ := nfpmAssetName("v2.41.1", runtime.GOOS, runtime.GOARCH)
+	digest, err := checksumFor(checksums, asset)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	platform := runtime.GOOS + "_" + runtime.GOARCH
+
+	tests := []struct {
+		name        string
+		locked      map[string]string
+		expectError string
+	}{
+		{"locked", map[string]string{"v2.41.1/" + platform: digest}, ""},
+		{"other digest", map[string]string{"v2.41.1/" + platform: strings.Repeat("a", 64)}, "but tool_lock pins " + strings.Repeat("a", 64)},
+		{"not listed", map[string]string{"v2.40.0/" + platform: digest}, "tool_lock has no digest for nfpm v2.41.1/" + platform},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &LinuxPkgPlugin{lookPath: cosignOnly, httpClient: server.Client()}
+			cacheDir := t.TempDir()
+			_, err := p.ensureNfpm(context.Background(), &MockCommandExecutor{}, &Config{
+				NfpmVersion:     "v2.41.1",
+				NfpmDownloadURL: server.URL,
+				ToolCacheDir:    cacheDir,
+				ToolLock:        &ToolLock{Nfpm: tt.locked},
+			})
+			if tt.expectError == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectError) {
+				t.Errorf("expected error containing %q, got %v", tt.expectError, err)
+			}
+			if entries, _ := os.ReadDir(cacheDir); len(entries) != 0 {
+				t.Errorf("expected nothing cached, got %v", entries)
+			}
+		})
+	}
+
+	// A binary cached from another archive is not run once the lock changes.
+	p := &LinuxPkgPlugin{lookPath: cosignOnly, httpClient: server.Client()}
+	cfg := &Config{NfpmVersion: "v2.41.1", NfpmDownloadURL: server.URL, ToolCacheDir: t.TempDir()}
+	if _, err := p.ensureNfpm(context.Background(), &MockCommandExecutor{}, cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.ToolLock = &ToolLock{Nfpm: map[string]string{"v2.41.1/" + platform: strings.Repeat("a", 64)}}
+	if _, err := p.ensureNfpm(context.Background(), &MockCommandExecutor{}, cfg); err == nil || !strings.Contains(err.Error(), "was extracted from an archive with sha256 "+digest) {
+		t.Errorf("expected the cached binary to be refused, got %v", err)
+	}
+}
+
+// TestExecuteToolLockContainer tests that the container packager runs the image pinned
+// by tool_lock, and refuses an image the lock does not list.
+func TestExecuteToolLockContainer(t *testing.T) {
+	t.Parallel()
+
+	locked := "sha256:" + strings.Repeat("ab", 32)
+	tests := []struct {
+		name        string
+		images      string
+		expectError string
+	}{
+		{"locked", `{"goreleaser/nfpm": "` + locked + `"}`, ""},
+		{"not listed", `{}`, "invalid tool_lock: tool_lock has no digest for image goreleaser/nfpm"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			writeEmbeddedTestConfig(t, dir, "amd64")
+			if err := os.WriteFile(filepath.Join(dir, "tools.lock.json"), []byte(`{"images": `+tt.images+`}`), 0644); err != nil {
+				t.Fatalf("failed to write lock: %v", err)
+			}
+
+			mock := &MockCommandExecutor{
+				RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+					return []byte("created package: " + args[len(args)-1] + "myapp.deb"), nil
+				},
+			}
+			p := &LinuxPkgPlugin{
+				cmdExecutor: mock,
+				lookPath: func(file string) (string, error) {
+					if file == "docker" {
+						return "/usr/bin/docker", nil
+					}
+					return "", errors.New("not found")
+				},
+			}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook: plugin.HookPostPublish,
+				Config: map[string]any{
+					"working_dir": dir,
+					"formats":     []string{"deb"},
+					"packager":    "container",
+					"tool_lock":   "tools.lock.json",
+				},
+				Context: plugin.ReleaseContext{Version: "1.2.3"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectError != "" {
+				if resp.Success || resp.Error != tt.expectError {
+					t.Errorf("expected error %q, got %+v", tt.expectError, resp)
+				}
+				return
+			}
+			if !resp.Success {
+				t.Fatalf("expected success, got failure: %s", resp.Error)
+			}
+			if len(mock.Calls) != 1 || !slices.Contains(mock.Calls[0].Args, "goreleaser/nfpm@"+locked) {
+				t.Errorf("expected the locked image to run, got %v", mock.Calls)
+			}
+		})
+	}
+}
+
+// TestExecuteToolLockInstallCheck tests that install smoke tests run the images pinned
+// by tool_lock, and refuse an image the lock does not list.
+func TestExecuteToolLockInstallCheck(t *testing.T) {
+	t.Parallel()
+
+	debian := "sha256:" + strings.Repeat("ab", 32)
+	ubuntu := "sha256:" + strings.Repeat("cd", 32)
+	tests := []struct {
+		name        string
+		images      string
+		expected    []string
+		expectError string
+	}{
+		{"locked", `{"debian:stable-slim": "` + debian + `", "ubuntu:latest": "` + ubuntu + `"}`, []string{"debian:stable-slim@" + debian, "ubuntu:latest@" + ubuntu}, ""},
+		{"not listed", `{"debian:stable-slim": "` + debian + `"}`, nil, "tool_lock has no digest for image ubuntu:latest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			writeEmbeddedTestConfig(t, dir, "amd64")
+			if err := os.WriteFile(filepath.Join(dir, "tools.lock.json"), []byte(`{"images": `+tt.images+`}`), 0644); err != nil {
+				t.Fatalf("failed to write lock: %v", err)
+			}
+
+			var images []string
+			mock := &MockCommandExecutor{
+				RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+					if name == "docker" {
+						images = append(images, args[len(args)-4])
+						return []byte("Setting up myapp (1.2.3) ...\n"), nil
+					}
+					return []byte("created package: " + args[len(args)-1] + "myapp.deb"), nil
+				},
+			}
+			p := &LinuxPkgPlugin{
+				cmdExecutor: mock,
+				lookPath: func(file string) (string, error) {
+					if file == "docker" {
+						return "/usr/bin/docker", nil
+					}
+					return "", errors.New("not found")
+				},
+			}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook: plugin.HookPostPublish,
+				Config: map[string]any{
+					"working_dir": dir,
+					"formats":     []string{"deb"},
+					"packager":    "nfpm-cli",
+					"tool_lock":   "tools.lock.json",
+					"checks":      map[string]any{"install": true},
+				},
+				Context: plugin.ReleaseContext{Version: "1.2.3"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.expectError != "" {
+				if resp.Success || !strings.Contains(resp.Error, tt.expectError) {
+					t.Errorf("expected error containing %q, got %+v", tt.expectError, resp)
+				}
+				return
+			}
+			if !resp.Success {
+				t.Fatalf("expected success, got failure: %s", resp.Error)
+			}
+			if !slices.Equal(images, tt.expected) {
+				t.Errorf("expected images %v, got %v", tt.expected, images)
+			}
+		})
+	}
+}
+
+// TestValidateToolLock tests validating the tool_lock path and contents.
+func TestValidateToolLock(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "tools.lock.json"), []byte(`{"images": {}}`), 0644); err != nil {
+		t.Fatalf("failed to write lock: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"images": []}`), 0644); err != nil {
+		t.Fatalf("failed to write lock: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		lock        string
+		expectError string
+	}{
+		{"valid", "tools.lock.json", ""},
+		{"missing", "missing.json", "failed to read"},
+		{"invalid", "broken.json", "failed to parse"},
+		{"absolute", "/etc/tools.lock.json", "absolute paths are not allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			expectFieldError(t, map[string]any{"working_dir": dir, "tool_lock": tt.lock}, "tool_lock", tt.expectError)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// parseVerifyReproducible parses verify_reproducible: true fails the hook when a rebuild
+// differs, "warn" only reports it, and false or unset disables the check.
+func parseVerifyReproducible(raw map[string]any) string {
+	switch value := raw["verify_reproducible"].(type) {
+	case bool:
+		if value {
+			return "fail"
+		}
+	case string:
+		return value
+	}
+	return ""
+}
+
+// validateVerifyReproducible checks the action of verify_reproducible.
+func validateVerifyReproducible(action string) error {
+	if action != "" && !allowedCheckActions[action] {
+		return fmt.Errorf("invalid value %q (allowed: true, false, fail, warn)", action)
+	}
+	return nil
+}
+
+// verifyReproducible builds every built package a second time in a temporary directory
+// and compares the digests. Packages signed after the build carry a timestamped
+// signature and are skipped. It returns a result per package and the paths of those
+// whose rebuild differs.
+func (p *LinuxPkgPlugin) verifyReproducible(ctx context.Context, executor CommandExecutor, jobs []buildJob, outcomes []*buildOutcome) ([]map[string]any, []string, error) {
+	tmp, err := os.MkdirTemp("", "linuxpkg-verify-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create rebuild directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	var results []map[string]any
+	var differing []string
+	for i, job := range jobs {
+		if outcomes[i] == nil || outcomes[i].Artifact == nil {
+			continue
+		}
+		artifact := outcomes[i].Artifact
+		path, _ := artifact["path"].(string)
+		result := map[string]any{
+			"package": path,
+			"format":  job.Format,
+			"arch":    artifact["arch"],
+			"sha256":  artifact["sha256"],
+		}
+		if artifact["signed"] == true {
+			result["skipped"] = "signed"
+			results = append(results, result)
+			continue
+		}
+
+		rebuildCfg := *job.Config
+		rebuildCfg.OutputDir = filepath.Join(tmp, strconv.Itoa(i))
+		if err := os.MkdirAll(rebuildCfg.OutputDir, 0755); err != nil {
+			return results, differing, fmt.Errorf("failed to create rebuild directory: %w", err)
+		}
+		rebuilt, output, err := p.runBuild(ctx, executor, &rebuildCfg, job.ConfigPath, job.Format, job.Target, job.Env, io.Discard)
+		if err != nil {
+			return results, differing, fmt.Errorf("failed to rebuild %s: %w\nOutput: %s", path, err, string(output))
+		}
+		digest, _, err := artifactDigest(rebuilt.Path)
+		if err != nil {
+			return results, differing, err
+		}
+
+		result["rebuilt_sha256"] = digest
+		result["reproducible"] = digest == artifact["sha256"]
+		if digest != artifact["sha256"] {
+			differing = append(differing, path)
+		}
+		results = append(results, result)
+	}
+	return results, differing, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestExecuteVerifyReproducible tests rebuilding packages to compare their digests.
+func TestExecuteVerifyReproducible(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		verify        any
+		deterministic bool
+		expectSuccess bool
+		expectText    string
+	}{
+		{"identical", true, true, true, "Built 1 Linux package(s)"},
+		{"differing", true, false, false, "1 package(s) are not reproducible"},
+		{"differing warn", "warn", false, true, "1 package(s) are not reproducible"},
+		{"invalid", "maybe", true, false, "invalid verify_reproducible"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: test\nversion: 1.0.0"), 0644); err != nil {
+				t.Fatalf("failed to create test config: %v", err)
+			}
+
+			// The mock nfpm writes the package to its target, with the build count in it
+			// unless it is deterministic.
+			var builds atomic.Int32
+			mock := &MockCommandExecutor{
+				RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+					target := args[slices.Index(args, "--target")+1]
+					path := filepath.Join(target, "test.deb")
+					content := "package"
+					if !tt.deterministic {
+						content = fmt.Sprintf("package %d", builds.Add(1))
+					}
+					if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+						return nil, err
+					}
+					return []byte("created package: " + path), nil
+				},
+			}
+			p := &LinuxPkgPlugin{cmdExecutor: mock}
+			resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+				Hook: plugin.HookPostPublish,
+				Config: map[string]any{
+					"working_dir":         dir,
+					"formats":             []any{"deb"},
+					"target":              "amd64",
+					"packager":            "nfpm-cli",
+					"verify_reproducible": tt.verify,
+				},
+				Context: plugin.ReleaseContext{Version: "1.0.0"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Success != tt.expectSuccess || !strings.Contains(resp.Message+resp.Error, tt.expectText) {
+				t.Fatalf("expected success %v with %q, got %+v", tt.expectSuccess, tt.expectText, resp)
+			}
+			if tt.name == "invalid" {
+				return
+			}
+
+			results := resp.Outputs["reproducibility"].([]map[string]any)
+			if len(results) != 1 || results[0]["reproducible"] != tt.deterministic {
+				t.Errorf("expected reproducible %v, got %v", tt.deterministic, results)
+			}
+			if len(mock.Calls) != 2 {
+				t.Errorf("expected a build and a rebuild, got %d call(s)", len(mock.Calls))
+			}
+		})
+	}
+}
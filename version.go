@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// apkPrereleaseSuffixes are the apk version suffixes that sort before a release.
+var apkPrereleaseSuffixes = map[string]bool{
+	"alpha": true,
+	"beta":  true,
+	"pre":   true,
+	"rc":    true,
+}
+
+// apkSuffixPattern splits a prerelease identifier like "rc1" into its name and number.
+var apkSuffixPattern = regexp.MustCompile(`^([a-z]+)(\d*)$`)
+
+// nativeVersion converts a semantic version into the version string a package format
+// sorts correctly, so prereleases install before the final release:
+//
+//	deb, ipk    1.2.0-rc.1+build.5  ->  1.2.0~rc.1+build.5
+//	rpm         1.2.0-rc.1+build-5  ->  1.2.0~rc.1+build_5
+//	apk         1.2.0-rc.1+build.5  ->  1.2.0_rc1
+//	archlinux   1.2.0-rc.1+build.5  ->  1.2.0rc.1
+//
+// It reports false when version is not a semantic version.
+func nativeVersion(format, version string) (string, bool) {
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return "", false
+	}
+
+	base := fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor(), v.Patch())
+	pre, meta := v.Prerelease(), v.Metadata()
+	switch format {
+	case "rpm":
+		// rpm reserves '-' for separating the version from the release.
+		if pre != "" {
+			base += "~" + strings.ReplaceAll(pre, "-", "_")
+		}
+		if meta != "" {
+			base += "+" + strings.ReplaceAll(meta, "-", "_")
+		}
+	case "apk":
+		// apk only accepts known suffixes followed by digits, and no build metadata.
+		if pre != "" {
+			base += "_" + apkSuffix(pre)
+		}
+	case "archlinux":
+		// Arch package versions cannot contain '-' or '+'; a letter suffix sorts first.
+		base += strings.ReplaceAll(pre, "-", "_")
+	default:
+		if pre != "" {
+			base += "~" + pre
+		}
+		if meta != "" {
+			base += "+" + meta
+		}
+	}
+	return base, true
+}
+
+// apkSuffix maps a semver prerelease to an apk suffix: "rc.1" and "rc1" become "rc1",
+// and names apk does not know, such as "dev.3", become "pre3".
+func apkSuffix(prerelease string) string {
+	parts := strings.Split(strings.ToLower(prerelease), ".")
+	name, number := parts[0], ""
+	if m := apkSuffixPattern.FindStringSubmatch(name); m != nil {
+		name, number = m[1], m[2]
+	}
+	if number == "" && len(parts) > 1 && isDigits(parts[1]) {
+		number = parts[1]
+	}
+	if !apkPrereleaseSuffixes[name] {
+		name = "pre"
+	}
+	return name + number
+}
+
+// isDigits reports whether s is a non-empty string of ASCII digits.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// maxEpoch is the largest epoch rpm can store.
+const maxEpoch = 1<<32 - 1
+
+// validateEpoch checks the configured package epoch.
+func validateEpoch(epoch int) error {
+	if epoch < 0 || epoch > maxEpoch {
+		return fmt.Errorf("epoch must be between 0 and %d, got %d", maxEpoch, epoch)
+	}
+	return nil
+}
+
+// normalizeVersion rewrites the version in doc into the native version for format.
+// Environment references in the version are resolved with getenv first, as nfpm would.
+// Configs with a plain release version, a non-semver version, or version_schema "none"
+// are left alone. It reports whether doc changed.
+func normalizeVersion(doc map[string]any, format string, getenv func(string) string) bool {
+	version, _ := doc["version"].(string)
+	if schema, _ := doc["version_schema"].(string); schema == "none" || version == "" {
+		return false
+	}
+	v, err := semver.NewVersion(os.Expand(version, getenv))
+	if err != nil {
+		return false
+	}
+	// Explicit prerelease and version_metadata fields apply when the version has none,
+	// matching nfpm.
+	pre, meta := v.Prerelease(), v.Metadata()
+	if pre == "" {
+		pre, _ = doc["prerelease"].(string)
+	}
+	if meta == "" {
+		meta, _ = doc["version_metadata"].(string)
+	}
+	if pre == "" && meta == "" {
+		return false
+	}
+
+	version = fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor(), v.Patch())
+	if pre != "" {
+		version += "-" + pre
+	}
+	if meta != "" {
+		version += "+" + meta
+	}
+	native, ok := nativeVersion(format, version)
+	if !ok {
+		return false
+	}
+
+	doc["version"] = native
+	doc["version_schema"] = "none"
+	delete(doc, "prerelease")
+	delete(doc, "version_metadata")
+	return true
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestNativeVersion tests the per-format version rules.
+func TestNativeVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		format   string
+		version  string
+		expected string
+	}{
+		{"deb", "1.2.0", "1.2.0"},
+		{"deb", "v1.2.0-rc.1", "1.2.0~rc.1"},
+		{"deb", "1.2.0-rc.1+build.5", "1.2.0~rc.1+build.5"},
+		{"ipk", "1.2.0-beta.2", "1.2.0~beta.2"},
+		{"rpm", "1.2.0-rc.1", "1.2.0~rc.1"},
+		{"rpm", "1.2.0-pre-release+build-5", "1.2.0~pre_release+build_5"},
+		{"apk", "1.2.0-rc.1", "1.2.0_rc1"},
+		{"apk", "1.2.0-beta3", "1.2.0_beta3"},
+		{"apk", "1.2.0-alpha", "1.2.0_alpha"},
+		{"apk", "1.2.0-dev.3+build.5", "1.2.0_pre3"},
+		{"archlinux", "1.2.0-rc.1+build.5", "1.2.0rc.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format+"/"+tt.version, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := nativeVersion(tt.format, tt.version)
+			if !ok {
+				t.Fatalf("expected %s to parse", tt.version)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	if _, ok := nativeVersion("deb", "nightly"); ok {
+		t.Error("expected a non-semver version to be rejected")
+	}
+}
+
+// TestFinalizeNfpmConfigVersion tests rewriting the version in an nfpm config.
+func TestFinalizeNfpmConfigVersion(t *testing.T) {
+	t.Parallel()
+
+	getenv := func(key string) string {
+		if key == "VERSION" {
+			return "2.0.0-rc.2"
+		}
+		return ""
+	}
+
+	tests := []struct {
+		name     string
+		config   string
+		format   string
+		expected string // empty when the config is used as-is
+	}{
+		{"release version", "version: 1.2.0\n", "deb", ""},
+		{"non-semver version", "version: nightly\n", "deb", ""},
+		{"schema none", "version: 1.2.0-rc.1\nversion_schema: none\n", "deb", ""},
+		{"prerelease", "version: 1.2.0-rc.1\n", "apk", "1.2.0_rc1"},
+		{"prerelease field", "version: 1.2.0\nprerelease: beta.1\n", "rpm", "1.2.0~beta.1"},
+		{"environment", "version: ${VERSION}\n", "deb", "2.0.0~rc.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "nfpm.yaml")
+			if err := os.WriteFile(path, []byte("name: myapp\n"+tt.config), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			got, cleanup, err := finalizeNfpmConfig(context.Background(), path, tt.format, &Config{NormalizeVersion: true}, nil, getenv, newRemoteFetcher(nil))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer cleanup()
+
+			if tt.expected == "" {
+				if got != path {
+					t.Errorf("expected the config to be used as-is, got %s", got)
+				}
+				return
+			}
+			doc, err := loadNfpmConfig(got)
+			if err != nil {
+				t.Fatalf("failed to read normalized config: %v", err)
+			}
+			if doc["version"] != tt.expected || doc["version_schema"] != "none" || doc["prerelease"] != nil {
+				t.Errorf("expected version %s with schema none, got %v", tt.expected, doc)
+			}
+		})
+	}
+}
+
+// TestExecuteNormalizeVersion tests that prerelease packages get native versions.
+func TestExecuteNormalizeVersion(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	configPath := writeEmbeddedTestConfig(t, dir, "amd64")
+
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	if err := os.WriteFile(configPath, []byte(strings.Replace(string(content), "version: 1.2.3", "version: ${VERSION}", 1)), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	build := func(normalize bool) []string {
+		t.Helper()
+		p := &LinuxPkgPlugin{}
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  map[string]any{"working_dir": dir, "formats": []string{"apk", "deb"}, "normalize_version": normalize},
+			Context: plugin.ReleaseContext{Version: "1.2.3-rc.1"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !resp.Success {
+			t.Fatalf("expected success, got failure: %s", resp.Error)
+		}
+		return resp.Outputs["packages"].([]string)
+	}
+
+	packages := build(true)
+	if len(packages) != 2 || !strings.Contains(packages[0], "1.2.3_rc1") || !strings.Contains(packages[1], "1.2.3~rc.1") {
+		t.Errorf("expected native prerelease versions, got %v", packages)
+	}
+
+	// Without normalization nfpm's own semver handling applies.
+	packages = build(false)
+	if len(packages) != 2 || !strings.Contains(packages[0], "1.2.3_rc.1") {
+		t.Errorf("expected nfpm's apk version, got %v", packages)
+	}
+}
+
+// TestValidateEpoch tests epoch validation.
+func TestValidateEpoch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		epoch       any
+		expectValid bool
+	}{
+		{"unset", nil, true},
+		{"positive", 2, true},
+		{"string", "3", true},
+		{"negative", -1, false},
+		{"too large", int64(1) << 32, false},
+	}
+
+	p := &LinuxPkgPlugin{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config := map[string]any{}
+			if tt.epoch != nil {
+				config["epoch"] = tt.epoch
+			}
+			resp, err := p.Validate(context.Background(), config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Valid != tt.expectValid {
+				t.Errorf("expected valid=%v, got valid=%v, errors=%v", tt.expectValid, resp.Valid, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestExecuteEpoch tests that the configured epoch replaces the nfpm config's.
+func TestExecuteEpoch(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: myapp\nversion: 1.2.3\nepoch: \"1\"\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	var epochs []any
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			doc, err := loadNfpmConfig(args[2])
+			if err != nil {
+				return nil, err
+			}
+			epochs = append(epochs, doc["epoch"])
+			return []byte("created package: dist/myapp" + packageExtensions[args[4]]), nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook:    plugin.HookPostPublish,
+		Config:  map[string]any{"working_dir": dir, "formats": []string{"deb", "rpm"}, "packager": "nfpm-cli", "epoch": 2},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+	if len(epochs) != 2 || epochs[0] != "2" || epochs[1] != "2" {
+		t.Errorf("expected epoch 2 for both formats, got %v", epochs)
+	}
+}
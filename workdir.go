@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// nfpmFormatBlocks are the nfpm config sections holding format-specific settings.
+var nfpmFormatBlocks = []string{"deb", "rpm", "apk", "archlinux", "ipk"}
+
+// resolveWorkingDir returns working_dir as an absolute path after checking that it is
+// an existing directory.
+func resolveWorkingDir(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("not a directory: %s", dir)
+	}
+	return abs, nil
+}
+
+// resolvedConfig parses raw the way Execute does and resolves its paths against the
+// working directory, for checks that look at files during validation.
+func (p *LinuxPkgPlugin) resolvedConfig(raw map[string]any) (*Config, error) {
+	cfg := p.parseConfig(raw)
+	if cfg.WorkingDir != "" {
+		dir, err := resolveWorkingDir(cfg.WorkingDir)
+		if err != nil {
+			return nil, err
+		}
+		cfg.WorkingDir = dir
+		cfg.applyWorkingDir()
+	}
+	return cfg, nil
+}
+
+// inWorkingDir resolves a relative path against dir. Empty and absolute paths, and all
+// paths when dir is empty, are returned unchanged.
+func inWorkingDir(dir, path string) string {
+	if dir == "" || path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// inWorkingDirAll resolves each of paths against dir.
+func inWorkingDirAll(dir string, paths []string) []string {
+	if dir == "" || len(paths) == 0 {
+		return paths
+	}
+	resolved := make([]string, len(paths))
+	for i, path := range paths {
+		resolved[i] = inWorkingDir(dir, path)
+	}
+	return resolved
+}
+
+// applyWorkingDir resolves every file and directory option against cfg.WorkingDir, which
+// must already be absolute. Options are validated as relative paths first, so they
+// cannot escape the working directory.
+func (cfg *Config) applyWorkingDir() {
+	dir := cfg.WorkingDir
+	if dir == "" {
+		return
+	}
+
+	cfg.ConfigPath = inWorkingDir(dir, cfg.ConfigPath)
+	cfg.OutputDir = inWorkingDir(dir, cfg.OutputDir)
+	cfg.ConfigOverlays = inWorkingDirAll(dir, cfg.ConfigOverlays)
+	cfg.Manpages = inWorkingDirAll(dir, cfg.Manpages)
+	cfg.APKKeyPath = inWorkingDir(dir, cfg.APKKeyPath)
+	cfg.NfpmPath = inWorkingDir(dir, cfg.NfpmPath)
+	cfg.ToolLockPath = inWorkingDir(dir, cfg.ToolLockPath)
+	for name, script := range cfg.Scripts {
+		cfg.Scripts[name] = inWorkingDir(dir, script)
+	}
+	for _, formatCfg := range cfg.FormatConfigs {
+		if formatCfg != nil {
+			formatCfg.ConfigPath = inWorkingDir(dir, formatCfg.ConfigPath)
+			formatCfg.OutputDir = inWorkingDir(dir, formatCfg.OutputDir)
+		}
+	}
+	for _, distro := range cfg.Distros {
+		distro.ConfigOverlays = inWorkingDirAll(dir, distro.ConfigOverlays)
+	}
+	if cfg.Desktop != nil {
+		cfg.Desktop.DesktopFile = inWorkingDir(dir, cfg.Desktop.DesktopFile)
+		cfg.Desktop.Metainfo = inWorkingDir(dir, cfg.Desktop.Metainfo)
+		cfg.Desktop.Icons = inWorkingDirAll(dir, cfg.Desktop.Icons)
+	}
+	if cfg.DebianDocs != nil {
+		cfg.DebianDocs.LicenseFile = inWorkingDir(dir, cfg.DebianDocs.LicenseFile)
+	}
+	if cfg.Checks != nil && cfg.Checks.Rpmlint != nil {
+		cfg.Checks.Rpmlint.Rpmlintrc = inWorkingDir(dir, cfg.Checks.Rpmlint.Rpmlintrc)
+	}
+	if cfg.RPMSigning != nil {
+		cfg.RPMSigning.KeyFile = inWorkingDir(dir, cfg.RPMSigning.KeyFile)
+	}
+	if cfg.Cosign != nil && !isKeyReference(cfg.Cosign.Key) {
+		cfg.Cosign.Key = inWorkingDir(dir, cfg.Cosign.Key)
+	}
+	if cfg.Publish != nil {
+		if cfg.Publish.APT != nil && cfg.Publish.APT.Tool == "reprepro" {
+			cfg.Publish.APT.Repo = inWorkingDir(dir, cfg.Publish.APT.Repo)
+		}
+		if cfg.Publish.YUM != nil {
+			cfg.Publish.YUM.Repo = inWorkingDir(dir, cfg.Publish.YUM.Repo)
+		}
+		if cfg.Publish.APK != nil {
+			cfg.Publish.APK.Repo = inWorkingDir(dir, cfg.Publish.APK.Repo)
+			cfg.Publish.APK.KeyPath = inWorkingDir(dir, cfg.Publish.APK.KeyPath)
+		}
+		if cfg.Publish.COPR != nil {
+			cfg.Publish.COPR.SRPM = inWorkingDir(dir, cfg.Publish.COPR.SRPM)
+		}
+	}
+}
+
+// rebaseNfpmPaths resolves the local files an nfpm config references against dir:
+// contents sources, scripts, the changelog, and signing keys, including those in format
+// sections and overrides. Symlink targets, remote sources, and sources starting with an
+// environment reference, such as ${BINARY}, are left alone.
+func rebaseNfpmPaths(doc map[string]any, dir string) {
+	rebaseNfpmSection(doc, dir)
+	if changelog, ok := doc["changelog"].(string); ok {
+		doc["changelog"] = inWorkingDir(dir, changelog)
+	}
+	for _, format := range nfpmFormatBlocks {
+		if block, ok := doc[format].(map[string]any); ok {
+			rebaseNfpmSection(block, dir)
+			if signature, ok := block["signature"].(map[string]any); ok {
+				if keyFile, ok := signature["key_file"].(string); ok {
+					signature["key_file"] = inWorkingDir(dir, keyFile)
+				}
+			}
+		}
+	}
+	if overrides, ok := doc["overrides"].(map[string]any); ok {
+		for _, override := range overrides {
+			if block, ok := override.(map[string]any); ok {
+				rebaseNfpmSection(block, dir)
+			}
+		}
+	}
+}
+
+// rebaseNfpmSection resolves the contents sources and scripts of one section of an nfpm
+// config against dir.
+func rebaseNfpmSection(section map[string]any, dir string) {
+	for _, raw := range contentEntries(section) {
+		entry, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		src, _ := entry["src"].(string)
+		entryType, _ := entry["type"].(string)
+		if src == "" || entryType == "symlink" || isRemoteSource(src) || strings.HasPrefix(src, "$") {
+			continue
+		}
+		entry["src"] = filepath.ToSlash(inWorkingDir(dir, src))
+	}
+	if scripts, ok := section["scripts"].(map[string]any); ok {
+		for name, script := range scripts {
+			if path, ok := script.(string); ok {
+				scripts[name] = inWorkingDir(dir, path)
+			}
+		}
+	}
+}
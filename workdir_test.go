@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/plugin"
+)
+
+// TestInWorkingDir tests resolving paths against the working directory.
+func TestInWorkingDir(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		dir      string
+		path     string
+		expected string
+	}{
+		{"/work", "nfpm.yaml", "/work/nfpm.yaml"},
+		{"/work", "./build/../dist", "/work/dist"},
+		{"/work", "/etc/key.asc", "/etc/key.asc"},
+		{"/work", "", ""},
+		{"", "nfpm.yaml", "nfpm.yaml"},
+	}
+	for _, tt := range tests {
+		if got := inWorkingDir(tt.dir, tt.path); got != tt.expected {
+			t.Errorf("inWorkingDir(%q, %q): expected %q, got %q", tt.dir, tt.path, tt.expected, got)
+		}
+	}
+}
+
+// TestRebaseNfpmPaths tests that the files an nfpm config references are resolved
+// against the working directory.
+func TestRebaseNfpmPaths(t *testing.T) {
+	t.Parallel()
+
+	doc := map[string]any{
+		"changelog": "changelog.yml",
+		"contents": []any{
+			map[string]any{"src": "build/myapp", "dst": "/usr/bin/myapp"},
+			map[string]any{"src": "share/*.md", "dst": "/usr/share/doc/myapp"},
+			map[string]any{"src": "/usr/bin/myapp", "dst": "/usr/local/bin/myapp", "type": "symlink"},
+			map[string]any{"src": "https://example.com/LICENSE", "dst": "/usr/share/licenses/myapp/LICENSE"},
+			map[string]any{"src": "/etc/myapp.conf", "dst": "/etc/myapp.conf"},
+			map[string]any{"src": "${BINARY}", "dst": "/usr/bin/myapp", "expand": true},
+			map[string]any{"dst": "/var/lib/myapp", "type": "dir"},
+		},
+		"scripts": map[string]any{"postinstall": "scripts/postinstall.sh"},
+		"deb": map[string]any{
+			"scripts":   map[string]any{"rules": "debian/rules"},
+			"signature": map[string]any{"key_file": "keys/deb.asc"},
+		},
+		"overrides": map[string]any{
+			"rpm": map[string]any{
+				"contents": []any{map[string]any{"src": "rpm/myapp.service", "dst": "/usr/lib/systemd/system/myapp.service"}},
+			},
+		},
+	}
+	rebaseNfpmPaths(doc, "/work")
+
+	expected := map[string]any{
+		"changelog": "/work/changelog.yml",
+		"contents": []any{
+			map[string]any{"src": "/work/build/myapp", "dst": "/usr/bin/myapp"},
+			map[string]any{"src": "/work/share/*.md", "dst": "/usr/share/doc/myapp"},
+			map[string]any{"src": "/usr/bin/myapp", "dst": "/usr/local/bin/myapp", "type": "symlink"},
+			map[string]any{"src": "https://example.com/LICENSE", "dst": "/usr/share/licenses/myapp/LICENSE"},
+			map[string]any{"src": "/etc/myapp.conf", "dst": "/etc/myapp.conf"},
+			map[string]any{"src": "${BINARY}", "dst": "/usr/bin/myapp", "expand": true},
+			map[string]any{"dst": "/var/lib/myapp", "type": "dir"},
+		},
+		"scripts": map[string]any{"postinstall": "/work/scripts/postinstall.sh"},
+		"deb": map[string]any{
+			"scripts":   map[string]any{"rules": "/work/debian/rules"},
+			"signature": map[string]any{"key_file": "/work/keys/deb.asc"},
+		},
+		"overrides": map[string]any{
+			"rpm": map[string]any{
+				"contents": []any{map[string]any{"src": "/work/rpm/myapp.service", "dst": "/usr/lib/systemd/system/myapp.service"}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(doc, expected) {
+		t.Errorf("expected %v, got %v", expected, doc)
+	}
+}
+
+// TestExecuteWorkingDir tests building from a working directory other than the process
+// working directory.
+func TestExecuteWorkingDir(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"packaging/nfpm.yaml": "name: myapp\nversion: 1.2.3\nmaintainer: Relicta Team <team@example.com>\n" +
+			"contents:\n  - src: build/*\n    dst: /usr/bin\n",
+		"packaging/postinstall.sh": "#!/bin/sh\necho {{.Version}}\n",
+		"build/myapp":              "binary",
+		"build/myapp.tmp":          "scratch",
+		".gitignore":               "*.tmp\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	p := &LinuxPkgPlugin{}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir":          dir,
+			"config_path":          "packaging/nfpm.yaml",
+			"formats":              []string{"deb"},
+			"target":               "amd64",
+			"scripts":              map[string]any{"postinstall": "packaging/postinstall.sh"},
+			"respect_ignore_files": true,
+			"cache":                true,
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	expected := filepath.Join(dir, "dist", "myapp_1.2.3_amd64.deb")
+	if packages := resp.Outputs["packages"].([]string); !reflect.DeepEqual(packages, []string{expected}) {
+		t.Fatalf("expected %s, got %v", expected, packages)
+	}
+	if resp.Outputs["output_dir"] != filepath.Join(dir, "dist") {
+		t.Errorf("expected output_dir in the working directory, got %v", resp.Outputs["output_dir"])
+	}
+	packaged := debDataFiles(t, expected)
+	if !packaged["./usr/bin/myapp"] {
+		t.Errorf("expected build/myapp to be packaged, got %v", packaged)
+	}
+	if packaged["./usr/bin/myapp.tmp"] {
+		t.Errorf("expected ignored build/myapp.tmp to be skipped, got %v", packaged)
+	}
+}
+
+// TestExecuteWorkingDirGoBuild tests that the Go build runs in the working directory.
+func TestExecuteWorkingDirGoBuild(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "nfpm.yaml"), []byte("name: myapp\nversion: 1.2.3\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte("created package: " + args[len(args)-1] + "myapp.deb"), nil
+		},
+	}
+	p := &LinuxPkgPlugin{cmdExecutor: mock}
+	resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+		Hook: plugin.HookPostPublish,
+		Config: map[string]any{
+			"working_dir": dir,
+			"formats":     []string{"deb"},
+			"packager":    "nfpm-cli",
+			"target":      "amd64",
+			"build":       map[string]any{"main": "./cmd/myapp"},
+		},
+		Context: plugin.ReleaseContext{Version: "1.2.3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Success {
+		t.Fatalf("expected success, got failure: %s", resp.Error)
+	}
+
+	binary := filepath.Join(dir, "dist", "bin", "amd64", "myapp")
+	expectedArgs := []string{"-C", dir, "build", "-trimpath", "-o", binary, "./cmd/myapp"}
+	if mock.Calls[0].Name != "go" || !reflect.DeepEqual(mock.Calls[0].Args, expectedArgs) {
+		t.Errorf("expected go %v, got %s %v", expectedArgs, mock.Calls[0].Name, mock.Calls[0].Args)
+	}
+	if target := mock.Calls[1].Args[len(mock.Calls[1].Args)-1]; target != filepath.Join(dir, "dist")+"/" {
+		t.Errorf("expected nfpm to write into the working directory, got %s", target)
+	}
+}
+
+// TestExecuteWorkingDirInvalid tests that a missing working directory is reported.
+func TestExecuteWorkingDirInvalid(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	p := &LinuxPkgPlugin{cmdExecutor: &MockCommandExecutor{}}
+	for _, workingDir := range []string{filepath.Join(dir, "missing"), file} {
+		resp, err := p.Execute(context.Background(), plugin.ExecuteRequest{
+			Hook:    plugin.HookPostPublish,
+			Config:  map[string]any{"working_dir": workingDir},
+			Context: plugin.ReleaseContext{Version: "1.2.3"},
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Success || !strings.Contains(resp.Error, "invalid working_dir") {
+			t.Errorf("%s: expected an invalid working_dir error, got %+v", workingDir, resp)
+		}
+	}
+
+	resp, err := p.Validate(context.Background(), map[string]any{"working_dir": 42})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Valid || len(resp.Errors) == 0 || resp.Errors[0].Field != "working_dir" {
+		t.Errorf("expected a working_dir error, got %v", resp.Errors)
+	}
+}
+
+// debDataFiles returns the names of the entries in a deb's data tarball.
+func debDataFiles(t *testing.T, pkg string) map[string]bool {
+	t.Helper()
+
+	files := make(map[string]bool)
+	tr := readDebMember(t, pkg, "data.tar")
+	for {
+		entry, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatalf("failed to read the data tarball of %s: %v", pkg, err)
+		}
+		files[entry.Name] = true
+	}
+}
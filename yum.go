@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/relicta-tech/relicta-plugin-sdk/helpers"
+)
+
+// YUMPublishConfig configures publishing RPMs to a yum/dnf repository directory.
+type YUMPublishConfig struct {
+	// Repo is the repository directory the RPMs are copied into.
+	Repo string
+	// GPGKey signs repodata/repomd.xml with a detached armored signature. Empty skips signing.
+	GPGKey string
+}
+
+// parseYUMPublish parses the publish.yum block. It returns nil when it is not set.
+func parseYUMPublish(publish map[string]any) *YUMPublishConfig {
+	block := helpers.NewConfigParser(publish).GetMap("yum")
+	if block == nil {
+		return nil
+	}
+
+	parser := helpers.NewConfigParser(block)
+	return &YUMPublishConfig{
+		Repo:   parser.GetString("repo", "", ""),
+		GPGKey: parser.GetString("gpg_key", "", ""),
+	}
+}
+
+// validate checks the yum publishing settings.
+func (c *YUMPublishConfig) validate() error {
+	if c.Repo == "" {
+		return fmt.Errorf("repo is required")
+	}
+	if err := validatePath(c.Repo); err != nil {
+		return fmt.Errorf("invalid repo: %w", err)
+	}
+	if strings.HasPrefix(c.GPGKey, "-") {
+		return fmt.Errorf("gpg_key cannot start with '-'")
+	}
+	return nil
+}
+
+// publishYUM copies RPMs into the repository, regenerates its metadata with createrepo_c,
+// and signs repomd.xml when a key is configured.
+func (p *LinuxPkgPlugin) publishYUM(ctx context.Context, executor CommandExecutor, c *YUMPublishConfig, rpms []string) (map[string]any, error) {
+	if err := os.MkdirAll(c.Repo, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create repository directory: %w", err)
+	}
+
+	copied := make([]string, 0, len(rpms))
+	for _, rpm := range rpms {
+		dst := filepath.Join(c.Repo, filepath.Base(rpm))
+		if err := copyFile(rpm, dst); err != nil {
+			return nil, err
+		}
+		copied = append(copied, dst)
+	}
+
+	output, err := runCommand(ctx, executor, "createrepo_c", "--update", c.Repo)
+	if err != nil {
+		return nil, fmt.Errorf("createrepo_c failed: %w\nOutput: %s", err, string(output))
+	}
+
+	repomd := filepath.Join(c.Repo, "repodata", "repomd.xml")
+	result := map[string]any{
+		"repository": c.Repo,
+		"packages":   copied,
+		"metadata":   repomd,
+		"signed":     c.GPGKey != "",
+	}
+
+	if c.GPGKey != "" {
+		signature := repomd + ".asc"
+		output, err := runCommand(ctx, executor, "gpg", c.signArgs(repomd)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign repomd.xml: %w\nOutput: %s", err, string(output))
+		}
+		result["signature"] = signature
+	}
+
+	return result, nil
+}
+
+// signArgs returns the gpg arguments that sign repomd with an armored detached signature.
+func (c *YUMPublishConfig) signArgs(repomd string) []string {
+	return []string{"--batch", "--yes", "--armor", "--detach-sign", "--local-user", c.GPGKey, "--output", repomd + ".asc", repomd}
+}
+
+// copyFile copies a file, replacing dst if it exists.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestValidatePublishYUM tests validation of the publish.yum block.
+func TestValidatePublishYUM(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		yum         map[string]any
+		expectValid bool
+	}{
+		{name: "repo", yum: map[string]any{"repo": "repo/el9"}, expectValid: true},
+		{name: "signed repo", yum: map[string]any{"repo": "repo/el9", "gpg_key": "ABCD1234"}, expectValid: true},
+		{name: "missing repo", yum: map[string]any{}, expectValid: false},
+		{name: "absolute repo", yum: map[string]any{"repo": "/srv/yum"}, expectValid: false},
+		{name: "option injection", yum: map[string]any{"repo": "repo", "gpg_key": "--homedir=/tmp"}, expectValid: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := &LinuxPkgPlugin{}
+			resp, err := p.Validate(context.Background(), map[string]any{"publish": map[string]any{"yum": tc.yum}})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Valid != tc.expectValid {
+				t.Errorf("expected valid=%v, got %v: %v", tc.expectValid, resp.Valid, resp.Errors)
+			}
+		})
+	}
+}
+
+// TestPublishYUM tests copying RPMs, running createrepo_c, and signing repomd.xml.
+func TestPublishYUM(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	rpm := filepath.Join(dir, "myapp-1.0.0-1.x86_64.rpm")
+	if err := os.WriteFile(rpm, []byte("rpm"), 0644); err != nil {
+		t.Fatalf("failed to write rpm: %v", err)
+	}
+	repo := filepath.Join(dir, "repo")
+
+	mock := &MockCommandExecutor{
+		RunFunc: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return nil, nil
+		},
+	}
+
+	p := &LinuxPkgPlugin{}
+	result, err := p.publishYUM(context.Background(), mock, &YUMPublishConfig{Repo: repo, GPGKey: "ABCD1234"}, []string{rpm})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	copied := filepath.Join(repo, "myapp-1.0.0-1.x86_64.rpm")
+	if data, err := os.ReadFile(copied); err != nil || string(data) != "rpm" {
+		t.Errorf("expected rpm to be copied into the repo: %v", err)
+	}
+
+	repomd := filepath.Join(repo, "repodata", "repomd.xml")
+	expected := []string{
+		"createrepo_c --update " + repo,
+		"gpg --batch --yes --armor --detach-sign --local-user ABCD1234 --output " + repomd + ".asc " + repomd,
+	}
+	if len(mock.Calls) != len(expected) {
+		t.Fatalf("expected %d calls, got %v", len(expected), mock.Calls)
+	}
+	for i, call := range mock.Calls {
+		if got := call.Name + " " + strings.Join(call.Args, " "); got != expected[i] {
+			t.Errorf("call %d: expected %q, got %q", i, expected[i], got)
+		}
+	}
+
+	if result["metadata"] != repomd || result["signature"] != repomd+".asc" {
+		t.Errorf("unexpected result: %v", result)
+	}
+}